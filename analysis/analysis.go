@@ -0,0 +1,53 @@
+/*
+The analysis package measures properties of audio, such as the correlation
+between the channels of a stereo recording. Measurements work on frames of
+floating point samples between -1 and 1, one value per channel, as decoded by
+wav.WavReader.ReadFrames, and most have a convenience function that reads them
+straight from a WavReader.
+*/
+package analysis
+
+import (
+	"context"
+	"io"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/wav"
+)
+
+// blockFrames is the number of frames decoded at a time by forEachFrame.
+const blockFrames = 4096
+
+/*
+forEachFrame decodes every remaining frame of reader and passes it to process,
+reporting to progress after each block and returning ctx's error if it is done
+first. The frame slice is reused between calls, so process must copy it to keep
+it.
+*/
+func forEachFrame(ctx context.Context, reader *wav.WavReader,
+	progress audio.ProgressFunc, process func(frame []float64)) error {
+	frames := make([][]float64, blockFrames)
+	for i := range frames {
+		frames[i] = make([]float64, reader.Fmt.NumChannels)
+	}
+	total := reader.RemainingFrames()
+	var done int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := reader.ReadFrames(frames)
+		for _, frame := range frames[:n] {
+			process(frame)
+		}
+		if n > 0 {
+			done += int64(n)
+			progress.Report(done, total)
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
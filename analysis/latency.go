@@ -0,0 +1,194 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"time"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/dsp"
+)
+
+const (
+	RecordingLengthError = "recording of %v frames is shorter than the %v frame chirp"
+	NoChirpError         = "chirp not found in the recording; best correlation was %.2f"
+	// chirpFade is the length of the fades at either end of the chirp,
+	// which keep it from clicking.
+	chirpFade = 5 * time.Millisecond
+	// minCorrelation is the normalised correlation below which a recording
+	// is taken not to hold the chirp.
+	minCorrelation = 0.2
+)
+
+/*
+LatencyOptions controls the chirp of Ping and Chirp. Duration, which defaults
+to 100 ms, is its length, and it sweeps exponentially from Low to High Hz,
+defaulting to 100 Hz and 10 kHz, with High kept below 0.45 of the sample rate.
+Gain is its peak level in dBFS. Longer and wider chirps are found more reliably
+in a noisy recording.
+*/
+type LatencyOptions struct {
+	Duration time.Duration
+	Low      float64
+	High     float64
+	Gain     audio.Decibel
+}
+
+/*
+DefaultLatencyOptions returns LatencyOptions for a 100 ms chirp from 100 Hz to
+10 kHz with a peak of -6 dBFS, which leaves headroom for a device that
+overshoots.
+*/
+func DefaultLatencyOptions() LatencyOptions {
+	return LatencyOptions{
+		Duration: 100 * time.Millisecond, Low: 100, High: 10000, Gain: -6}
+}
+
+/*
+Latency is the result of MeasureLatency. Frames is the delay of the chirp in
+the recording in sample frames, interpolated between them, and Time the same
+delay as a duration. Confidence is the normalised correlation of the chirp
+with the recording at that delay, from 0 to 1.
+*/
+type Latency struct {
+	Frames     float64
+	Time       time.Duration
+	Confidence float64
+}
+
+/*
+Chirp returns the mono test signal of Ping at the given sample rate: an
+exponential sine sweep, faded in and out over 5 ms. A sweep correlates with
+itself at one delay only, so it is located precisely even through the
+filtering and noise of a real device.
+*/
+func Chirp(options LatencyOptions, sampleRate float64) []float64 {
+	if options.Duration == 0 {
+		options.Duration = 100 * time.Millisecond
+	}
+	if options.Low == 0 {
+		options.Low = 100
+	}
+	if options.High == 0 {
+		options.High = 10000
+	}
+	options.High = math.Min(options.High, 0.45*sampleRate)
+	length := options.Duration.Seconds()
+	chirp := make([]float64, int(length*sampleRate))
+	fade := chirpFade.Seconds() * sampleRate
+	gain := options.Gain.Linear()
+	sweep := math.Log(options.High / options.Low)
+	for i := range chirp {
+		t := float64(i) / sampleRate
+		phase := 2 * math.Pi * options.Low * length / sweep *
+			(math.Exp(t/length*sweep) - 1)
+		level := gain
+		if edge := math.Min(float64(i), float64(len(chirp)-1-i)); edge < fade {
+			level *= 0.5 - 0.5*math.Cos(math.Pi*edge/fade)
+		}
+		chirp[i] = level * math.Sin(phase)
+	}
+	return chirp
+}
+
+/*
+MeasureLatency returns the delay of played in recorded, mono signals at the
+given sample rate, by finding the peak of their cross-correlation. The
+recording is expected to start when playback does, so only delays from 0 are
+searched, and a chirp with its polarity inverted is found as well. A non-nil
+error is returned if recorded is shorter than played or the correlation never
+rises above 0.2.
+*/
+func MeasureLatency(
+	played, recorded []float64, sampleRate float64) (Latency, error) {
+	if len(recorded) < len(played) {
+		return Latency{}, fmt.Errorf(
+			RecordingLengthError, len(recorded), len(played))
+	}
+	size := 1
+	for size < len(played)+len(recorded) {
+		size *= 2
+	}
+	chirp := make([]complex128, size)
+	for i, value := range played {
+		chirp[i] = complex(value, 0)
+	}
+	correlation := make([]complex128, size)
+	for i, value := range recorded {
+		correlation[i] = complex(value, 0)
+	}
+	dsp.FFT(chirp)
+	dsp.FFT(correlation)
+	for i := range correlation {
+		correlation[i] *= cmplx.Conj(chirp[i])
+	}
+	dsp.InverseFFT(correlation)
+
+	// The correlation at each delay is normalised by the energy of the
+	// chirp and of the part of the recording it overlaps.
+	var chirpEnergy float64
+	for _, value := range played {
+		chirpEnergy += value * value
+	}
+	energies := make([]float64, len(recorded)+1)
+	for i, value := range recorded {
+		energies[i+1] = energies[i] + value*value
+	}
+	best, delay := 0.0, 0
+	for lag := 0; lag <= len(recorded)-len(played); lag++ {
+		energy := chirpEnergy * (energies[lag+len(played)] - energies[lag])
+		if energy == 0 {
+			continue
+		}
+		score := math.Abs(real(correlation[lag])) / math.Sqrt(energy)
+		if score > best {
+			best, delay = score, lag
+		}
+	}
+	if best < minCorrelation {
+		return Latency{}, fmt.Errorf(NoChirpError, best)
+	}
+
+	// The peak is refined with a parabola through it and its neighbours.
+	frames := float64(delay)
+	if delay > 0 && delay < len(recorded)-len(played) {
+		before := math.Abs(real(correlation[delay-1]))
+		peak := math.Abs(real(correlation[delay]))
+		after := math.Abs(real(correlation[delay+1]))
+		if curve := before - 2*peak + after; curve < 0 {
+			frames += 0.5 * (before - after) / curve
+		}
+	}
+	return Latency{
+		Frames:     frames,
+		Time:       time.Duration(frames / sampleRate * float64(time.Second)),
+		Confidence: math.Min(best, 1),
+	}, nil
+}
+
+/*
+Loopback plays chirp on an output device and returns what an input device
+recorded over the same time, starting as playback starts and lasting long
+enough to hold the chirp after the round trip. Both are mono at the sample
+rate given to Ping. It adapts Ping to whatever playback and capture backend a
+program uses, with the output looped back to the input by a cable or through
+the air.
+*/
+type Loopback func(ctx context.Context, chirp []float64) ([]float64, error)
+
+/*
+Ping measures the round trip latency of loopback at the given sample rate. It
+plays a Chirp through loopback and returns the delay that MeasureLatency finds
+in the recording, or loopback's error if it fails.
+*/
+func Ping(ctx context.Context, loopback Loopback,
+	options LatencyOptions, sampleRate float64) (Latency, error) {
+	chirp := Chirp(options, sampleRate)
+	recorded, err := loopback(ctx, chirp)
+	if err != nil {
+		return Latency{}, err
+	}
+	return MeasureLatency(chirp, recorded, sampleRate)
+}
@@ -0,0 +1,101 @@
+package analysis_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"regexp"
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio/analysis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChirp(t *testing.T) {
+	chirp := Chirp(DefaultLatencyOptions(), 48000)
+	assert.Len(t, chirp, 4800)
+	// It fades in from silence and stays below its gain.
+	assert.Equal(t, 0.0, chirp[0])
+	assert.InDelta(t, 0.0, chirp[len(chirp)-1], 1e-3)
+	assert.InDelta(t, 0.5, peak(chirp), 0.01)
+	chirp = Chirp(LatencyOptions{Gain: -20}, 48000)
+	assert.InDelta(t, 0.1, peak(chirp), 0.002)
+	assert.Equal(t, chirp, Chirp(LatencyOptions{
+		Duration: 100 * time.Millisecond, Low: 100, High: 10000, Gain: -20},
+		48000))
+	// A gain of 0 dB is full scale.
+	assert.InDelta(t, 1, peak(Chirp(LatencyOptions{}, 48000)), 0.01)
+	assert.Len(t, Chirp(LatencyOptions{Duration: time.Second}, 8000), 8000)
+}
+
+// peak returns the largest magnitude of values.
+func peak(values []float64) float64 {
+	var peak float64
+	for _, value := range values {
+		peak = math.Max(peak, math.Abs(value))
+	}
+	return peak
+}
+
+func TestPing(t *testing.T) {
+	// The loopback inverts and quietens the chirp, delays it by 1234
+	// frames and adds noise, as a real interface might.
+	random := rand.New(rand.NewSource(1))
+	loopback := func(ctx context.Context, chirp []float64) ([]float64, error) {
+		recorded := make([]float64, 12000)
+		for i := range recorded {
+			recorded[i] = 0.05 * random.NormFloat64()
+			if i >= 1234 && i-1234 < len(chirp) {
+				recorded[i] -= 0.3 * chirp[i-1234]
+			}
+		}
+		return recorded, nil
+	}
+	latency, err := Ping(
+		context.Background(), loopback, DefaultLatencyOptions(), 48000)
+	assert.Nil(t, err)
+	assert.InDelta(t, 1234, latency.Frames, 0.1)
+	assert.InDelta(t, 25.708*float64(time.Millisecond),
+		float64(latency.Time), float64(time.Microsecond))
+	assert.True(t, latency.Confidence > 0.8)
+
+	failure := errors.New("device unplugged")
+	_, err = Ping(context.Background(),
+		func(context.Context, []float64) ([]float64, error) {
+			return nil, failure
+		}, DefaultLatencyOptions(), 48000)
+	assert.Equal(t, failure, err)
+}
+
+func TestMeasureLatencyFractional(t *testing.T) {
+	// A delay between frames is interpolated.
+	chirp := Chirp(LatencyOptions{Low: 50, High: 2000}, 8000)
+	recorded := make([]float64, 2000)
+	for i := 1; i < len(chirp); i++ {
+		recorded[i+100] = (chirp[i] + chirp[i-1]) / 2
+	}
+	latency, err := MeasureLatency(chirp, recorded, 8000)
+	assert.Nil(t, err)
+	assert.InDelta(t, 100.5, latency.Frames, 0.1)
+}
+
+func TestMeasureLatencyErrors(t *testing.T) {
+	chirp := Chirp(LatencyOptions{}, 8000)
+	_, err := MeasureLatency(chirp, make([]float64, 100), 8000)
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"recording of 100 frames is shorter").FindString(err.Error()))
+
+	random := rand.New(rand.NewSource(1))
+	noise := make([]float64, 4000)
+	for i := range noise {
+		noise[i] = random.NormFloat64()
+	}
+	_, err = MeasureLatency(chirp, noise, 8000)
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"chirp not found").FindString(err.Error()))
+	_, err = MeasureLatency(chirp, make([]float64, 4000), 8000)
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"chirp not found").FindString(err.Error()))
+}
@@ -0,0 +1,371 @@
+package analysis
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/dsp"
+	"github.com/husafan/audio/wav"
+)
+
+const (
+	// The following values are the gates of BS.1770 and EBU Tech 3342: an
+	// absolute gate in LUFS and relative gates in LU.
+	absoluteGate   = -70
+	integratedGate = -10
+	rangeGate      = -20
+
+	// Loudness is measured in steps of a tenth of a second. Momentary
+	// loudness covers 4 steps and short-term loudness 30.
+	stepsPerSecond = 10
+	momentarySteps = 4
+	shortTermSteps = 30
+
+	// The loudness range spans these percentiles of short-term loudness.
+	rangeLow  = 0.10
+	rangeHigh = 0.95
+
+	// loudnessOffset converts K-weighted power in decibels to LUFS.
+	loudnessOffset = -0.691
+
+	// The LFE channel of a 5.1 file is ignored, and the two surround
+	// channels following it are weighted by surroundWeight.
+	surroundChannels = 6
+	lfeChannel       = 3
+	surroundWeight   = 1.41
+)
+
+/*
+kWeighting returns the two filters of the K-weighting curve for the given
+sample rate: a high shelf modelling the acoustic effect of the head, followed by
+a high pass filter. The coefficients are derived from the analog prototypes of
+the 48 kHz filters in BS.1770, so any sample rate can be measured.
+*/
+func kWeighting(sampleRate float64) [2]dsp.Biquad {
+	k := math.Tan(math.Pi * 1681.974450955533 / sampleRate)
+	q := 0.7071752369554196
+	vh := math.Pow(10, 3.999843853973347/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	shelf := dsp.NewBiquad(vh+vb*k/q+k*k, 2*(k*k-vh), vh-vb*k/q+k*k,
+		1+k/q+k*k, 2*(k*k-1), 1-k/q+k*k)
+	k = math.Tan(math.Pi * 38.13547087602444 / sampleRate)
+	q = 0.5003270373238773
+	// BS.1770 leaves the numerator of the high pass unnormalised.
+	a0 := 1 + k/q + k*k
+	highPass := dsp.NewBiquad(a0, -2*a0, a0, a0, 2*(k*k-1), 1-k/q+k*k)
+	return [2]dsp.Biquad{shelf, highPass}
+}
+
+/*
+LoudnessMeter measures loudness as defined by ITU-R BS.1770 and EBU R 128. Each
+channel is K-weighted and its power summed, with the surround channels of a 5.1
+file weighted by 1.41 and its LFE channel ignored. Loudness is measured over
+100 ms steps, from which the momentary (400 ms), short-term (3 s), integrated
+and loudness range values are taken. Loudness values are in LUFS and loudness
+ranges in LU, with silence reported as -Inf.
+*/
+type LoudnessMeter struct {
+	filters    [][2]dsp.Biquad
+	weights    []float64
+	stepFrames int
+	frames     int
+	power      float64
+	steps      []float64
+	stepCount  int
+	momentary  []float64
+	shortTerm  []float64
+}
+
+/*
+NewLoudnessMeter returns a LoudnessMeter for frames of the given channels at the
+given sample rate.
+*/
+func NewLoudnessMeter(channels int, sampleRate uint32) *LoudnessMeter {
+	m := &LoudnessMeter{
+		filters:    make([][2]dsp.Biquad, channels),
+		weights:    make([]float64, channels),
+		stepFrames: int(math.Round(float64(sampleRate) / stepsPerSecond)),
+		steps:      make([]float64, shortTermSteps),
+	}
+	for channel := range m.filters {
+		m.filters[channel] = kWeighting(float64(sampleRate))
+		m.weights[channel] = 1
+		if channels == surroundChannels && channel == lfeChannel {
+			m.weights[channel] = 0
+		} else if channels == surroundChannels && channel > lfeChannel {
+			m.weights[channel] = surroundWeight
+		}
+	}
+	if m.stepFrames < 1 {
+		m.stepFrames = 1
+	}
+	return m
+}
+
+// Add adds a frame, holding a value for each of the meter's channels.
+func (m *LoudnessMeter) Add(frame []float64) {
+	for channel := range m.filters {
+		filters := &m.filters[channel]
+		value := filters[1].Filter(filters[0].Filter(frame[channel]))
+		m.power += m.weights[channel] * value * value
+	}
+	if m.frames++; m.frames < m.stepFrames {
+		return
+	}
+	m.steps[m.stepCount%shortTermSteps] = m.power / float64(m.stepFrames)
+	m.stepCount++
+	m.frames, m.power = 0, 0
+	if m.stepCount >= momentarySteps {
+		m.momentary = append(m.momentary, m.stepPower(momentarySteps))
+	}
+	if m.stepCount >= shortTermSteps {
+		m.shortTerm = append(m.shortTerm, m.stepPower(shortTermSteps))
+	}
+}
+
+// stepPower returns the mean power of the last count steps.
+func (m *LoudnessMeter) stepPower(count int) float64 {
+	var sum float64
+	for i := 1; i <= count; i++ {
+		sum += m.steps[(m.stepCount-i)%shortTermSteps]
+	}
+	return sum / float64(count)
+}
+
+/*
+Momentary returns the loudness of the last 400 ms, or -Inf until that much has
+been added.
+*/
+func (m *LoudnessMeter) Momentary() float64 {
+	if m.stepCount < momentarySteps {
+		return math.Inf(-1)
+	}
+	return loudness(m.stepPower(momentarySteps))
+}
+
+/*
+ShortTerm returns the loudness of the last 3 s, or -Inf until that much has been
+added.
+*/
+func (m *LoudnessMeter) ShortTerm() float64 {
+	if m.stepCount < shortTermSteps {
+		return math.Inf(-1)
+	}
+	return loudness(m.stepPower(shortTermSteps))
+}
+
+/*
+Integrated returns the gated loudness of everything added so far. Blocks of 400
+ms quieter than -70 LUFS, or 10 LU below the loudness of the remaining blocks,
+are left out so that pauses do not lower the result.
+*/
+func (m *LoudnessMeter) Integrated() float64 {
+	gated := gate(m.momentary, integratedGate)
+	var sum float64
+	for _, power := range gated {
+		sum += power
+	}
+	if len(gated) == 0 {
+		return math.Inf(-1)
+	}
+	return loudness(sum / float64(len(gated)))
+}
+
+/*
+Range returns the loudness range (LRA) of everything added so far, as defined by
+EBU Tech 3342: the spread between the 10th and 95th percentiles of the
+short-term loudness, ignoring values quieter than -70 LUFS or 20 LU below the
+loudness of the remaining values. It is 0 until 3 s have been added.
+*/
+func (m *LoudnessMeter) Range() float64 {
+	gated := gate(m.shortTerm, rangeGate)
+	if len(gated) == 0 {
+		return 0
+	}
+	values := make([]float64, len(gated))
+	for i, power := range gated {
+		values[i] = loudness(power)
+	}
+	sort.Float64s(values)
+	percentile := func(fraction float64) float64 {
+		return values[int(math.Round(float64(len(values)-1)*fraction))]
+	}
+	return percentile(rangeHigh) - percentile(rangeLow)
+}
+
+/*
+gate returns the block powers louder than the absolute gate and than the
+loudness of those blocks plus relative, in LU.
+*/
+func gate(powers []float64, relative float64) []float64 {
+	var absolute []float64
+	var sum float64
+	for _, power := range powers {
+		if loudness(power) > absoluteGate {
+			absolute = append(absolute, power)
+			sum += power
+		}
+	}
+	if len(absolute) == 0 {
+		return nil
+	}
+	threshold := loudness(sum/float64(len(absolute))) + relative
+	var gated []float64
+	for _, power := range absolute {
+		if loudness(power) > threshold {
+			gated = append(gated, power)
+		}
+	}
+	return gated
+}
+
+// loudness converts a K-weighted power to LUFS.
+func loudness(power float64) float64 {
+	return loudnessOffset + 10*math.Log10(power)
+}
+
+/*
+LoudnessPoint is an entry of a loudness timeline. Time is the end of the
+measured audio, measured from the first frame.
+*/
+type LoudnessPoint struct {
+	Time      time.Duration
+	Momentary float64
+	ShortTerm float64
+}
+
+/*
+LoudnessOptions controls MeasureLoudness. When TimelineInterval is positive, a
+LoudnessPoint is recorded every TimelineInterval, rounded to the 100 ms steps
+the meter measures in. Progress, if not nil, is called as
+MeasureLoudnessContext reads the file.
+*/
+type LoudnessOptions struct {
+	TimelineInterval time.Duration
+	Progress         audio.ProgressFunc
+}
+
+/*
+Loudness is the result of MeasureLoudness. MaxMomentary and MaxShortTerm are the
+largest momentary and short-term loudness values.
+*/
+type Loudness struct {
+	Integrated   float64
+	Range        float64
+	MaxMomentary float64
+	MaxShortTerm float64
+	Timeline     []LoudnessPoint
+}
+
+/*
+MeasureLoudness reads every remaining frame of a WAV file and measures its
+loudness, recording a timeline of momentary and short-term loudness values if
+requested.
+*/
+func MeasureLoudness(
+	reader *wav.WavReader, options LoudnessOptions) (*Loudness, error) {
+	return MeasureLoudnessContext(context.Background(), reader, options)
+}
+
+/*
+MeasureLoudnessContext is MeasureLoudness, stopping once ctx is done. The
+loudness of the frames read until then is returned with ctx's error.
+*/
+func MeasureLoudnessContext(ctx context.Context, reader *wav.WavReader,
+	options LoudnessOptions) (*Loudness, error) {
+	rate := int64(reader.Fmt.SampleRate)
+	meter := NewLoudnessMeter(int(reader.Fmt.NumChannels), reader.Fmt.SampleRate)
+	result := &Loudness{
+		MaxMomentary: math.Inf(-1),
+		MaxShortTerm: math.Inf(-1),
+	}
+	interval := int(options.TimelineInterval * stepsPerSecond / time.Second)
+	if options.TimelineInterval > 0 && interval < 1 {
+		interval = 1
+	}
+	var frames int64
+	err := forEachFrame(ctx, reader, options.Progress, func(frame []float64) {
+		meter.Add(frame)
+		frames++
+		if meter.frames != 0 {
+			return
+		}
+		result.MaxMomentary = math.Max(result.MaxMomentary, meter.Momentary())
+		result.MaxShortTerm = math.Max(result.MaxShortTerm, meter.ShortTerm())
+		if interval > 0 && meter.stepCount%interval == 0 {
+			result.Timeline = append(result.Timeline, LoudnessPoint{
+				Time:      time.Duration(frames * int64(time.Second) / rate),
+				Momentary: meter.Momentary(),
+				ShortTerm: meter.ShortTerm(),
+			})
+		}
+	})
+	if err != nil && ctx.Err() == nil {
+		return nil, err
+	}
+	result.Integrated = meter.Integrated()
+	result.Range = meter.Range()
+	return result, err
+}
+
+/*
+WriteCSV writes the timeline as CSV, with a header row followed by a row of
+time in seconds, momentary and short-term loudness for each point. Values that
+have not been measured yet are written as -Inf.
+*/
+func (l *Loudness) WriteCSV(writer io.Writer) error {
+	output := csv.NewWriter(writer)
+	output.Write([]string{"time", "momentary", "short_term"})
+	for _, point := range l.Timeline {
+		output.Write([]string{
+			strconv.FormatFloat(point.Time.Seconds(), 'f', -1, 64),
+			strconv.FormatFloat(point.Momentary, 'f', 2, 64),
+			strconv.FormatFloat(point.ShortTerm, 'f', 2, 64),
+		})
+	}
+	output.Flush()
+	return output.Error()
+}
+
+/*
+WriteJSON writes the measurement as a JSON object, with times in seconds.
+Values that have not been measured, which are -Inf, are written as null.
+*/
+func (l *Loudness) WriteJSON(writer io.Writer) error {
+	type point struct {
+		Time      float64  `json:"time"`
+		Momentary *float64 `json:"momentary"`
+		ShortTerm *float64 `json:"short_term"`
+	}
+	measured := func(value float64) *float64 {
+		if math.IsInf(value, 0) || math.IsNaN(value) {
+			return nil
+		}
+		return &value
+	}
+	output := struct {
+		Integrated   *float64 `json:"integrated"`
+		Range        float64  `json:"range"`
+		MaxMomentary *float64 `json:"max_momentary"`
+		MaxShortTerm *float64 `json:"max_short_term"`
+		Timeline     []point  `json:"timeline"`
+	}{
+		Integrated:   measured(l.Integrated),
+		Range:        l.Range,
+		MaxMomentary: measured(l.MaxMomentary),
+		MaxShortTerm: measured(l.MaxShortTerm),
+		Timeline:     make([]point, len(l.Timeline)),
+	}
+	for i, current := range l.Timeline {
+		output.Timeline[i] = point{current.Time.Seconds(),
+			measured(current.Momentary), measured(current.ShortTerm)}
+	}
+	return json.NewEncoder(writer).Encode(output)
+}
@@ -0,0 +1,106 @@
+package analysis_test
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio/analysis"
+	"github.com/stretchr/testify/assert"
+)
+
+// addSine adds a 1 kHz sine of the given amplitude to every channel of meter.
+func addSine(meter *LoudnessMeter, channels int, rate, seconds, amplitude float64) {
+	frame := make([]float64, channels)
+	for i := 0; i < int(rate*seconds); i++ {
+		for channel := range frame {
+			frame[channel] = amplitude * math.Sin(2*math.Pi*1000*float64(i)/rate)
+		}
+		meter.Add(frame)
+	}
+}
+
+func TestLoudnessMeter(t *testing.T) {
+	// A full scale 1 kHz sine in one channel measures -3.01 LUFS.
+	meter := NewLoudnessMeter(1, 48000)
+	assert.True(t, math.IsInf(meter.Integrated(), -1))
+	assert.True(t, math.IsInf(meter.Momentary(), -1))
+	addSine(meter, 1, 48000, 5, 1)
+	assert.InDelta(t, -3.01, meter.Integrated(), 0.05)
+	assert.InDelta(t, -3.01, meter.Momentary(), 0.05)
+	assert.InDelta(t, -3.01, meter.ShortTerm(), 0.05)
+	assert.InDelta(t, 0, meter.Range(), 0.05)
+
+	// Two channels add their power, while the LFE of a 5.1 file is ignored.
+	stereo := NewLoudnessMeter(2, 48000)
+	addSine(stereo, 2, 48000, 1, 1)
+	assert.InDelta(t, 0, stereo.Integrated(), 0.05)
+	surround := NewLoudnessMeter(6, 48000)
+	frame := make([]float64, 6)
+	for i := 0; i < 48000; i++ {
+		frame[3] = math.Sin(2 * math.Pi * 1000 * float64(i) / 48000)
+		surround.Add(frame)
+	}
+	assert.True(t, math.IsInf(surround.Integrated(), -1))
+}
+
+func TestLoudnessMeterGating(t *testing.T) {
+	// Silence is gated out of the integrated loudness, which would otherwise
+	// drop by 3 LU. Only the blocks that overlap the end of the tone count.
+	meter := NewLoudnessMeter(1, 48000)
+	addSine(meter, 1, 48000, 3, 1)
+	addSine(meter, 1, 48000, 3, 0)
+	assert.InDelta(t, -3.01, meter.Integrated(), 0.3)
+
+	// The range spans the loud and quiet halves.
+	meter = NewLoudnessMeter(1, 48000)
+	addSine(meter, 1, 48000, 10, 0.1)
+	addSine(meter, 1, 48000, 10, 0.1/math.Sqrt(10))
+	assert.InDelta(t, 10, meter.Range(), 0.5)
+}
+
+func TestMeasureLoudness(t *testing.T) {
+	const rate = 8000
+	frames := make([][]float64, 4*rate)
+	for i := range frames {
+		frames[i] = []float64{0.5 * math.Sin(2*math.Pi*1000*float64(i)/rate)}
+	}
+	reader := newReader(t, 1, frames...)
+	reader.Fmt.SampleRate = rate
+	loudness, err := MeasureLoudness(reader, LoudnessOptions{
+		TimelineInterval: time.Second})
+	assert.Nil(t, err)
+	assert.InDelta(t, -9.03, loudness.Integrated, 0.3)
+	assert.InDelta(t, loudness.Integrated, loudness.MaxShortTerm, 0.1)
+	assert.Equal(t, 4, len(loudness.Timeline))
+	assert.Equal(t, 3*time.Second, loudness.Timeline[2].Time)
+	assert.True(t, math.IsInf(loudness.Timeline[1].ShortTerm, -1))
+	assert.InDelta(t, -9.03, loudness.Timeline[3].ShortTerm, 0.3)
+
+	output := new(bytes.Buffer)
+	assert.Nil(t, loudness.WriteCSV(output))
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	assert.Equal(t, 5, len(lines))
+	assert.Equal(t, "time,momentary,short_term", lines[0])
+	assert.NotEqual(t, "", regexp.MustCompile(
+		`^1,-9\.\d\d,-Inf$`).FindString(lines[1]))
+
+	output.Reset()
+	assert.Nil(t, loudness.WriteJSON(output))
+	assert.NotEqual(t, "", regexp.MustCompile(
+		`"timeline":\[\{"time":1,"momentary":-9\.\d+,"short_term":null\}`).
+		FindString(output.String()))
+}
+
+func TestMeasureLoudnessContext(t *testing.T) {
+	reader := newReader(t, 1, []float64{0.5})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	loudness, err := MeasureLoudnessContext(ctx, reader, LoudnessOptions{})
+	assert.Equal(t, context.Canceled, err)
+	assert.True(t, math.IsInf(loudness.Integrated, -1))
+}
@@ -0,0 +1,193 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"time"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/dsp"
+	"github.com/husafan/audio/wav"
+)
+
+const (
+	// fluxStepsPerSecond is the number of spectral flux values per second.
+	fluxStepsPerSecond = 100
+	// fluxWindowDuration is the shortest window Onsets takes spectra of. The
+	// window is rounded up to a power of two frames.
+	fluxWindowDuration = 20 * time.Millisecond
+	// peakReach and meanReach are the number of steps either side of a flux
+	// value that it must be the largest of, and that its threshold is
+	// averaged over.
+	peakReach = 3
+	meanReach = 10
+)
+
+/*
+OnsetOptions controls Onsets. Threshold is how far, as a fraction of the
+largest spectral flux in the file, a peak must rise above the mean flux around
+it, and defaults to 0.1. Lower values find quieter onsets. MinInterval, which
+defaults to 50 ms, is the shortest time between onsets, so ringing and flams
+are not reported twice. Progress, if not nil, is called as OnsetsContext reads
+the file.
+*/
+type OnsetOptions struct {
+	Threshold   float64
+	MinInterval time.Duration
+	Progress    audio.ProgressFunc
+}
+
+/*
+Onset is a transient found by Onsets. Frame is its position in sample frames
+from the first frame and Time the same position as a duration. Strength is its
+spectral flux relative to the largest in the file, from 0 to 1.
+*/
+type Onset struct {
+	Frame    int64
+	Time     time.Duration
+	Strength float64
+}
+
+/*
+Onsets reads every remaining frame of a WAV file and returns its percussive
+onsets in order. The channels are mixed to mono and Hann windowed spectra are
+taken every 10 ms. The spectral flux of each step sums the rises in log
+magnitude of every frequency bin since the previous step, so it jumps when new
+energy appears across the spectrum, as at drum hits and plucked notes, while
+ignoring steady tones and decays. Onsets are the peaks of the flux that pass an
+adaptive threshold.
+*/
+func Onsets(reader *wav.WavReader, options OnsetOptions) ([]Onset, error) {
+	return OnsetsContext(context.Background(), reader, options)
+}
+
+/*
+OnsetsContext is Onsets, stopping once ctx is done. Nothing is detected from a
+partial read, so only ctx's error is returned.
+*/
+func OnsetsContext(ctx context.Context, reader *wav.WavReader,
+	options OnsetOptions) ([]Onset, error) {
+	if options.Threshold == 0 {
+		options.Threshold = 0.1
+	}
+	if options.MinInterval == 0 {
+		options.MinInterval = 50 * time.Millisecond
+	}
+	rate := int64(reader.Fmt.SampleRate)
+	hop := int(rate) / fluxStepsPerSecond
+	if hop < 1 {
+		hop = 1
+	}
+	size := 1
+	for time.Duration(size)*time.Second < fluxWindowDuration*time.Duration(rate) {
+		size *= 2
+	}
+	hann := make([]float64, size)
+	for i := range hann {
+		hann[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(size))
+	}
+
+	// Windows are centred on their step, so the first is half silence.
+	window := make([]float64, size/2, size+hop)
+	spectrum := make([]complex128, size)
+	previous := make([]float64, size/2+1)
+	magnitudes := make([]float64, size/2+1)
+	var flux []float64
+	err := forEachFrame(ctx, reader, options.Progress, func(frame []float64) {
+		var mono float64
+		for _, value := range frame {
+			mono += value
+		}
+		window = append(window, mono/float64(len(frame)))
+		if len(window) < size {
+			return
+		}
+		for i, value := range window[:size] {
+			spectrum[i] = complex(value*hann[i], 0)
+		}
+		dsp.FFT(spectrum)
+		var sum float64
+		for bin := range magnitudes {
+			magnitudes[bin] = math.Log1p(100 * cmplx.Abs(spectrum[bin]))
+			if rise := magnitudes[bin] - previous[bin]; rise > 0 {
+				sum += rise
+			}
+		}
+		previous, magnitudes = magnitudes, previous
+		flux = append(flux, sum)
+		window = window[:copy(window, window[hop:])]
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pickOnsets(flux, hop, rate, options), nil
+}
+
+/*
+pickOnsets returns the onsets at peaks of flux, measured every hop frames at
+the given sample rate. A peak is the largest value within peakReach steps and
+exceeds the mean within meanReach steps by the threshold.
+*/
+func pickOnsets(
+	flux []float64, hop int, rate int64, options OnsetOptions) []Onset {
+	var largest float64
+	for _, value := range flux {
+		largest = math.Max(largest, value)
+	}
+	if largest == 0 {
+		return nil
+	}
+	var onsets []Onset
+	for i, value := range flux {
+		peak := value > 0
+		var sum float64
+		var count int
+		for j := i - meanReach; j <= i+meanReach; j++ {
+			if j < 0 || j >= len(flux) {
+				continue
+			}
+			if j >= i-peakReach && j <= i+peakReach && flux[j] > value {
+				peak = false
+			}
+			sum += flux[j]
+			count++
+		}
+		if !peak || value-sum/float64(count) < options.Threshold*largest {
+			continue
+		}
+		onset := Onset{Frame: int64(i * hop), Strength: value / largest}
+		onset.Time = time.Duration(onset.Frame * int64(time.Second) / rate)
+		if last := len(onsets) - 1; last >= 0 &&
+			onset.Time-onsets[last].Time < options.MinInterval {
+			if onset.Strength > onsets[last].Strength {
+				onsets[last] = onset
+			}
+			continue
+		}
+		onsets = append(onsets, onset)
+	}
+	return onsets
+}
+
+/*
+OnsetCuePoints returns a cue point for each onset, labeled with its number
+counted from 1, to be added to a WavWriter with AddCuePoint. Each cue point
+but the last is a region reaching the next onset, so the regions slice the
+file into hits.
+*/
+func OnsetCuePoints(onsets []Onset) []wav.CuePoint {
+	cues := make([]wav.CuePoint, len(onsets))
+	for i, onset := range onsets {
+		cues[i] = wav.CuePoint{
+			Id:       uint32(i + 1),
+			Position: uint32(onset.Frame),
+			Label:    fmt.Sprintf("Onset %v", i+1),
+		}
+		if i+1 < len(onsets) {
+			cues[i].Length = uint32(onsets[i+1].Frame - onset.Frame)
+		}
+	}
+	return cues
+}
@@ -0,0 +1,55 @@
+package analysis_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio/analysis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnsets(t *testing.T) {
+	hits := []time.Duration{
+		100 * time.Millisecond,
+		430 * time.Millisecond,
+		700 * time.Millisecond,
+		720 * time.Millisecond,
+		1100 * time.Millisecond,
+	}
+	reader := newClickReader(t, 1.5, hits...)
+	onsets, err := Onsets(reader, OnsetOptions{})
+	assert.Nil(t, err)
+	// The hit 20 ms after another is within MinInterval.
+	expected := []time.Duration{hits[0], hits[1], hits[2], hits[4]}
+	assert.Equal(t, len(expected), len(onsets))
+	for i, onset := range onsets {
+		assert.InDelta(t, expected[i].Seconds(), onset.Time.Seconds(), 0.015)
+		assert.Equal(t, onset.Frame*int64(time.Second)/8000,
+			int64(onset.Time))
+		assert.True(t, onset.Strength > 0 && onset.Strength <= 1)
+	}
+
+	cues := OnsetCuePoints(onsets)
+	assert.Equal(t, len(onsets), len(cues))
+	assert.Equal(t, "Onset 2", cues[1].Label)
+	assert.Equal(t, uint32(onsets[1].Frame), cues[1].Position)
+	assert.Equal(t, uint32(onsets[2].Frame-onsets[1].Frame), cues[1].Length)
+	assert.Equal(t, uint32(0), cues[len(cues)-1].Length)
+}
+
+func TestOnsetsIgnoreSteadyTone(t *testing.T) {
+	samples := make([]float64, 8000)
+	for i := range samples {
+		samples[i] = 0.5 * math.Sin(2*math.Pi*440*float64(i)/8000)
+	}
+	onsets, err := Onsets(newMonoReader(t, 8000, samples), OnsetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(onsets))
+	assert.InDelta(t, 0, onsets[0].Time.Seconds(), 0.015)
+
+	onsets, err = Onsets(
+		newMonoReader(t, 8000, make([]float64, 8000)), OnsetOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, onsets)
+}
@@ -0,0 +1,113 @@
+package analysis
+
+import (
+	"context"
+	"math"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/dsp"
+	"github.com/husafan/audio/wav"
+)
+
+/*
+TruePeakMeter measures the true peak level of each channel, following the
+method of ITU-R BS.1770 annex 2: the signal is oversampled four times by a
+dsp.TruePeak and the largest absolute value is kept. Unlike the sample peak, the true peak includes
+overs between samples, which clip the reconstruction filters of DACs and lossy
+encoders even when no sample reaches full scale. Levels are linear, so 1 is full
+scale.
+*/
+type TruePeakMeter struct {
+	detectors   []dsp.TruePeak
+	samplePeaks []float64
+	truePeaks   []float64
+}
+
+// NewTruePeakMeter returns a TruePeakMeter for frames of the given channels.
+func NewTruePeakMeter(channels int) *TruePeakMeter {
+	return &TruePeakMeter{
+		detectors:   make([]dsp.TruePeak, channels),
+		samplePeaks: make([]float64, channels),
+		truePeaks:   make([]float64, channels),
+	}
+}
+
+/*
+Add adds a frame, holding a value for each of the meter's channels, to the
+measurement. Interpolated values trail the input by half the filter length.
+*/
+func (m *TruePeakMeter) Add(frame []float64) {
+	for channel := range m.detectors {
+		level := math.Abs(frame[channel])
+		m.samplePeaks[channel] = math.Max(m.samplePeaks[channel], level)
+		m.truePeaks[channel] = math.Max(m.truePeaks[channel], math.Max(
+			level, m.detectors[channel].Next(frame[channel])))
+	}
+}
+
+// SamplePeaks returns the largest absolute sample value of each channel.
+func (m *TruePeakMeter) SamplePeaks() []float64 {
+	return append([]float64{}, m.samplePeaks...)
+}
+
+// TruePeaks returns the true peak level of each channel.
+func (m *TruePeakMeter) TruePeaks() []float64 {
+	return append([]float64{}, m.truePeaks...)
+}
+
+// TruePeak returns the largest true peak level of any channel.
+func (m *TruePeakMeter) TruePeak() float64 {
+	var peak float64
+	for _, level := range m.truePeaks {
+		peak = math.Max(peak, level)
+	}
+	return peak
+}
+
+// Reset clears the measurement so the meter can be reused.
+func (m *TruePeakMeter) Reset() {
+	for channel := range m.detectors {
+		m.detectors[channel] = dsp.TruePeak{}
+		m.samplePeaks[channel] = 0
+		m.truePeaks[channel] = 0
+	}
+}
+
+/*
+Peaks is the result of MeasurePeaks. Sample and True hold the sample peak and
+true peak level of each channel.
+*/
+type Peaks struct {
+	Sample []float64
+	True   []float64
+}
+
+/*
+PeakOptions controls MeasurePeaksContext. Progress, if not nil, is called as it
+reads the file.
+*/
+type PeakOptions struct {
+	Progress audio.ProgressFunc
+}
+
+/*
+MeasurePeaks reads every remaining frame of a WAV file and measures the sample
+and true peak level of each channel.
+*/
+func MeasurePeaks(reader *wav.WavReader) (*Peaks, error) {
+	return MeasurePeaksContext(context.Background(), reader, PeakOptions{})
+}
+
+/*
+MeasurePeaksContext is MeasurePeaks, stopping once ctx is done. The peaks of the
+frames read until then are returned with ctx's error.
+*/
+func MeasurePeaksContext(ctx context.Context,
+	reader *wav.WavReader, options PeakOptions) (*Peaks, error) {
+	meter := NewTruePeakMeter(int(reader.Fmt.NumChannels))
+	err := forEachFrame(ctx, reader, options.Progress, meter.Add)
+	if err != nil && ctx.Err() == nil {
+		return nil, err
+	}
+	return &Peaks{Sample: meter.SamplePeaks(), True: meter.TruePeaks()}, err
+}
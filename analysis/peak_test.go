@@ -0,0 +1,68 @@
+package analysis_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/husafan/audio"
+	. "github.com/husafan/audio/analysis"
+	"github.com/stretchr/testify/assert"
+)
+
+// quarterRateSine returns a sine at a quarter of the sample rate whose peaks
+// fall exactly halfway between samples.
+func quarterRateSine(frames int, amplitude float64) [][]float64 {
+	sine := make([][]float64, frames)
+	for i := range sine {
+		sine[i] = []float64{
+			amplitude * math.Sin(math.Pi*float64(i)/2+math.Pi/4)}
+	}
+	return sine
+}
+
+func TestTruePeakMeter(t *testing.T) {
+	meter := NewTruePeakMeter(1)
+	for _, frame := range quarterRateSine(100, 1) {
+		meter.Add(frame)
+	}
+	assert.InDelta(t, math.Sqrt2/2, meter.SamplePeaks()[0], 1e-9)
+	assert.InDelta(t, 1, meter.TruePeak(), 0.02)
+
+	meter.Reset()
+	assert.Equal(t, []float64{0}, meter.TruePeaks())
+	meter.Add([]float64{0.5})
+	assert.Equal(t, []float64{0.5}, meter.SamplePeaks())
+	assert.True(t, meter.TruePeak() >= 0.5)
+}
+
+func TestTruePeakMeterStep(t *testing.T) {
+	// A step from silence rings slightly past its level between samples.
+	meter := NewTruePeakMeter(2)
+	for i := 0; i < 50; i++ {
+		meter.Add([]float64{0.5, -0.25})
+	}
+	assert.Equal(t, []float64{0.5, 0.25}, meter.SamplePeaks())
+	peaks := meter.TruePeaks()
+	assert.True(t, peaks[0] > 0.5 && peaks[0] < 0.6)
+	assert.InDelta(t, peaks[0]/2, peaks[1], 1e-9)
+}
+
+func TestMeasurePeaks(t *testing.T) {
+	reader := newReader(t, 1, quarterRateSine(100, 0.5)...)
+	peaks, err := MeasurePeaks(reader)
+	assert.Nil(t, err)
+	assert.InDelta(t, math.Sqrt2/4, peaks.Sample[0], 1e-3)
+	assert.InDelta(t, 0.5, peaks.True[0], 0.02)
+	assert.InDelta(t, -6.02,
+		float64(audio.LinearToDecibel(peaks.True[0])), 0.2)
+}
+
+func TestMeasurePeaksContext(t *testing.T) {
+	reader := newReader(t, 1, quarterRateSine(100, 0.5)...)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	peaks, err := MeasurePeaksContext(ctx, reader, PeakOptions{})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, []float64{0}, peaks.Sample)
+}
@@ -0,0 +1,139 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/wav"
+)
+
+const StereoChannelsError = "stereo analysis requires 2 channels; found %v"
+
+/*
+CorrelationMeter measures the correlation between the left and right channels
+of stereo frames, as shown by a phase correlation meter. The coefficient ranges
+from 1 for identical channels, through 0 for unrelated channels, to -1 for
+channels that are inverted copies of each other and cancel when summed to mono.
+The zero value is ready to use.
+*/
+type CorrelationMeter struct {
+	leftRight  float64
+	leftLeft   float64
+	rightRight float64
+}
+
+/*
+Add adds a frame to the measurement. The first two values of the frame are
+taken as the left and right channels.
+*/
+func (c *CorrelationMeter) Add(frame []float64) {
+	left, right := frame[0], frame[1]
+	c.leftRight += left * right
+	c.leftLeft += left * left
+	c.rightRight += right * right
+}
+
+/*
+Coefficient returns the correlation of the frames added so far. It is 0 when
+either channel has been silent.
+*/
+func (c *CorrelationMeter) Coefficient() float64 {
+	if c.leftLeft == 0 || c.rightRight == 0 {
+		return 0
+	}
+	return c.leftRight / math.Sqrt(c.leftLeft*c.rightRight)
+}
+
+// Reset clears the measurement so the meter can be reused.
+func (c *CorrelationMeter) Reset() {
+	*c = CorrelationMeter{}
+}
+
+/*
+Point is a point on a goniometer, or vectorscope, display. Y measures the mid
+signal, the sum of the channels, and X the side signal, their difference, both
+scaled by 1/√2. A mono signal draws a vertical line, a signal in the left
+channel only leans to the left and channels in opposite phase draw a
+horizontal line.
+*/
+type Point struct {
+	X float64
+	Y float64
+}
+
+// GoniometerPoint returns the goniometer point of a stereo frame.
+func GoniometerPoint(frame []float64) Point {
+	left, right := frame[0], frame[1]
+	return Point{X: (right - left) / math.Sqrt2, Y: (left + right) / math.Sqrt2}
+}
+
+/*
+StereoOptions controls AnalyzeStereo. Window is the number of frames in each
+entry of StereoAnalysis.Windows, and PointStep the number of frames between
+exported goniometer points. Either is skipped when not positive. Progress, if
+not nil, is called as AnalyzeStereoContext reads the file.
+*/
+type StereoOptions struct {
+	Window    int
+	PointStep int
+	Progress  audio.ProgressFunc
+}
+
+/*
+StereoAnalysis is the result of AnalyzeStereo. Correlation covers the whole
+file, while Windows holds the correlation of each consecutive window, the last
+of which may be shorter, so brief mono compatibility problems are not hidden by
+the overall figure. Points holds goniometer points for drawing a Lissajous
+figure.
+*/
+type StereoAnalysis struct {
+	Correlation float64
+	Windows     []float64
+	Points      []Point
+}
+
+/*
+AnalyzeStereo reads every remaining frame of a stereo WAV file and measures the
+correlation between its channels. A non-nil error is returned for files that do
+not have two channels or cannot be decoded.
+*/
+func AnalyzeStereo(
+	reader *wav.WavReader, options StereoOptions) (*StereoAnalysis, error) {
+	return AnalyzeStereoContext(context.Background(), reader, options)
+}
+
+/*
+AnalyzeStereoContext is AnalyzeStereo, stopping once ctx is done. The analysis
+of the frames read until then is returned with ctx's error.
+*/
+func AnalyzeStereoContext(ctx context.Context, reader *wav.WavReader,
+	options StereoOptions) (*StereoAnalysis, error) {
+	if channels := reader.Fmt.NumChannels; channels != 2 {
+		return nil, fmt.Errorf(StereoChannelsError, channels)
+	}
+	analysis := new(StereoAnalysis)
+	var overall, window CorrelationMeter
+	var frames int
+	err := forEachFrame(ctx, reader, options.Progress, func(frame []float64) {
+		overall.Add(frame)
+		window.Add(frame)
+		if options.PointStep > 0 && frames%options.PointStep == 0 {
+			analysis.Points = append(analysis.Points, GoniometerPoint(frame))
+		}
+		frames++
+		if options.Window > 0 && frames%options.Window == 0 {
+			analysis.Windows = append(analysis.Windows, window.Coefficient())
+			window.Reset()
+		}
+	})
+	if err != nil && ctx.Err() == nil {
+		return nil, err
+	}
+	if options.Window > 0 && frames%options.Window != 0 {
+		analysis.Windows = append(analysis.Windows, window.Coefficient())
+	}
+	analysis.Correlation = overall.Coefficient()
+	return analysis, err
+}
@@ -0,0 +1,142 @@
+package analysis_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/analysis"
+	"github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// bufferWriterAt is an io.WriterAt backed by a growing byte slice.
+type bufferWriterAt struct {
+	data []byte
+}
+
+func (b *bufferWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	if end := int(off) + len(p); end > len(b.data) {
+		b.data = append(b.data, make([]byte, end-len(b.data))...)
+	}
+	return copy(b.data[off:], p), nil
+}
+
+/*
+newReader returns a WavReader for a 16 bit file with the given number of
+channels, holding frames of samples between -1 and 1.
+*/
+func newReader(t *testing.T, channels uint16, frames ...[]float64) *wav.WavReader {
+	fmtChunk := wav.NewDefaultFmtChunk()
+	fmtChunk.NumChannels = channels
+	fmtChunk.BlockAlign = channels * 2
+	output := new(bufferWriterAt)
+	writer, err := wav.NewWavWriter(output, fmtChunk)
+	assert.Nil(t, err)
+	for _, frame := range frames {
+		sample := make(wav.Sample, len(frame))
+		for i, value := range frame {
+			sample[i] = make([]byte, 2)
+			binary.LittleEndian.PutUint16(
+				sample[i], uint16(int16(value*math.MaxInt16)))
+		}
+		assert.Nil(t, writer.AddSample(sample))
+	}
+	reader, err := wav.NewWavReader(bytes.NewReader(output.data))
+	assert.Nil(t, err)
+	return reader
+}
+
+func TestCorrelationMeter(t *testing.T) {
+	var meter CorrelationMeter
+	assert.Equal(t, 0.0, meter.Coefficient())
+	meter.Add([]float64{0.5, 0.5})
+	meter.Add([]float64{-0.25, -0.25})
+	assert.InDelta(t, 1, meter.Coefficient(), 1e-9)
+
+	meter.Reset()
+	meter.Add([]float64{0.5, -0.5})
+	meter.Add([]float64{-0.25, 0.25})
+	assert.InDelta(t, -1, meter.Coefficient(), 1e-9)
+
+	meter.Reset()
+	meter.Add([]float64{1, 0})
+	meter.Add([]float64{0, 1})
+	assert.InDelta(t, 0, meter.Coefficient(), 1e-9)
+
+	meter.Reset()
+	meter.Add([]float64{0.5, 0})
+	assert.Equal(t, 0.0, meter.Coefficient())
+}
+
+func TestGoniometerPoint(t *testing.T) {
+	mono := GoniometerPoint([]float64{0.5, 0.5})
+	assert.InDelta(t, 0, mono.X, 1e-9)
+	assert.InDelta(t, math.Sqrt2/2, mono.Y, 1e-9)
+
+	inverted := GoniometerPoint([]float64{0.5, -0.5})
+	assert.InDelta(t, -math.Sqrt2/2, inverted.X, 1e-9)
+	assert.InDelta(t, 0, inverted.Y, 1e-9)
+
+	left := GoniometerPoint([]float64{1, 0})
+	assert.True(t, left.X < 0)
+	assert.True(t, left.Y > 0)
+}
+
+func TestAnalyzeStereo(t *testing.T) {
+	reader := newReader(t, 2,
+		[]float64{0.5, 0.5}, []float64{-0.5, -0.5},
+		[]float64{0.5, -0.5}, []float64{-0.5, 0.5},
+		[]float64{0.25, 0.25})
+	analysis, err := AnalyzeStereo(reader, StereoOptions{Window: 2, PointStep: 2})
+	assert.Nil(t, err)
+	assert.InDelta(t, 1.0/17, analysis.Correlation, 1e-3)
+	assert.Equal(t, 3, len(analysis.Windows))
+	assert.InDelta(t, 1, analysis.Windows[0], 1e-3)
+	assert.InDelta(t, -1, analysis.Windows[1], 1e-3)
+	assert.InDelta(t, 1, analysis.Windows[2], 1e-3)
+	assert.Equal(t, 3, len(analysis.Points))
+	assert.InDelta(t, 0, analysis.Points[0].X, 1e-3)
+	assert.InDelta(t, -math.Sqrt2/2, analysis.Points[1].X, 1e-3)
+}
+
+func TestAnalyzeStereoWithoutOptions(t *testing.T) {
+	reader := newReader(t, 2, []float64{0.5, 0.5}, []float64{0.25, 0.25})
+	analysis, err := AnalyzeStereo(reader, StereoOptions{})
+	assert.Nil(t, err)
+	assert.InDelta(t, 1, analysis.Correlation, 1e-3)
+	assert.Nil(t, analysis.Windows)
+	assert.Nil(t, analysis.Points)
+}
+
+func TestAnalyzeStereoWrongChannels(t *testing.T) {
+	reader := newReader(t, 1, []float64{0.5})
+	_, err := AnalyzeStereo(reader, StereoOptions{})
+	assert.NotNil(t, err)
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"requires 2 channels; found 1").FindString(err.Error()))
+}
+
+func TestAnalyzeStereoContext(t *testing.T) {
+	reader := newReader(t, 2, []float64{0.5, 0.5})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	analysis, err := AnalyzeStereoContext(ctx, reader, StereoOptions{Window: 1})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 0.0, analysis.Correlation)
+	assert.Nil(t, analysis.Windows)
+}
+
+func TestAnalyzeStereoProgress(t *testing.T) {
+	reader := newReader(t, 2, []float64{0.5, 0.5}, []float64{0.5, 0.5})
+	var reports [][2]int64
+	_, err := AnalyzeStereoContext(context.Background(), reader,
+		StereoOptions{Progress: func(done, total int64) {
+			reports = append(reports, [2]int64{done, total})
+		}})
+	assert.Nil(t, err)
+	assert.Equal(t, [][2]int64{{2, 2}}, reports)
+}
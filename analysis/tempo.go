@@ -0,0 +1,291 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/midi"
+	"github.com/husafan/audio/midi/gm"
+	"github.com/husafan/audio/wav"
+)
+
+const TempoRangeError = "tempo range must satisfy 0 < MinBPM < MaxBPM; found %v-%v"
+
+const (
+	// envelopeRate is the number of onset strength values per second.
+	envelopeRate = 100
+	// preferredBPM is the centre of the tempo prior used to resolve octave
+	// errors, where half and double the tempo fit the onsets equally well.
+	preferredBPM = 120
+	// clickKey is the General MIDI percussion key of exported beat clicks, the
+	// Hi Wood Block.
+	clickKey = 76
+)
+
+/*
+TempoOptions controls EstimateTempo. The estimate is limited to tempos between
+MinBPM and MaxBPM, which default to 60 and 180 when both are zero. Progress, if
+not nil, is called as EstimateTempoContext reads the file.
+*/
+type TempoOptions struct {
+	MinBPM   float64
+	MaxBPM   float64
+	Progress audio.ProgressFunc
+}
+
+/*
+Tempo is the result of EstimateTempo. BPM is the estimated tempo in beats per
+minute and Beats holds the time of each beat, measured from the first frame.
+Beats follow a grid at the estimated tempo, each moved to the strongest onset
+near it, so they track small drifts in the performance. SampleRate is the rate
+of the analysed file.
+*/
+type Tempo struct {
+	BPM        float64
+	Beats      []time.Duration
+	SampleRate uint32
+}
+
+/*
+EstimateTempo reads every remaining frame of a WAV file and estimates its tempo
+and beat positions. The channels are mixed to mono and an onset strength
+envelope is built from rises in energy over 10 ms steps. The autocorrelation
+of the envelope, weighted towards 120 BPM, gives the beat period, and the
+phase is the offset of the period grid that lines up with the most onset
+strength. A Tempo with no beats and a BPM of 0 is returned for files without
+onsets.
+*/
+func EstimateTempo(
+	reader *wav.WavReader, options TempoOptions) (*Tempo, error) {
+	return EstimateTempoContext(context.Background(), reader, options)
+}
+
+/*
+EstimateTempoContext is EstimateTempo, stopping once ctx is done. Nothing is
+estimated from a partial read, so only ctx's error is returned.
+*/
+func EstimateTempoContext(ctx context.Context, reader *wav.WavReader,
+	options TempoOptions) (*Tempo, error) {
+	if options.MinBPM == 0 && options.MaxBPM == 0 {
+		options.MinBPM, options.MaxBPM = 60, 180
+	}
+	if options.MinBPM <= 0 || options.MaxBPM <= options.MinBPM {
+		return nil, fmt.Errorf(
+			TempoRangeError, options.MinBPM, options.MaxBPM)
+	}
+	rate := reader.Fmt.SampleRate
+	hop := int(rate) / envelopeRate
+	if hop < 1 {
+		hop = 1
+	}
+	var envelope []float64
+	var energy, previous float64
+	var count int
+	err := forEachFrame(ctx, reader, options.Progress, func(frame []float64) {
+		var mono float64
+		for _, value := range frame {
+			mono += value
+		}
+		mono /= float64(len(frame))
+		energy += mono * mono
+		if count++; count < hop {
+			return
+		}
+		level := math.Log(energy/float64(hop) + 1e-10)
+		if len(envelope) == 0 {
+			envelope = append(envelope, 0)
+		} else {
+			envelope = append(envelope, math.Max(0, level-previous))
+		}
+		previous, energy, count = level, 0, 0
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tempo := &Tempo{SampleRate: rate}
+	hopRate := float64(rate) / float64(hop)
+	period := beatPeriod(envelope, hopRate, options)
+	if period == 0 {
+		return tempo, nil
+	}
+	tempo.BPM = 60 * hopRate / period
+	for _, index := range beatIndices(envelope, period) {
+		tempo.Beats = append(tempo.Beats, time.Duration(
+			int64(index)*int64(hop)*int64(time.Second)/int64(rate)))
+	}
+	return tempo, nil
+}
+
+/*
+beatPeriod returns the beat period, in envelope values, that best explains the
+onset strength envelope, or 0 if the envelope has no onsets. The peak of the
+weighted autocorrelation is refined by parabolic interpolation.
+*/
+func beatPeriod(
+	envelope []float64, hopRate float64, options TempoOptions) float64 {
+	var mean float64
+	for _, value := range envelope {
+		mean += value
+	}
+	if mean == 0 {
+		return 0
+	}
+	mean /= float64(len(envelope))
+	centred := make([]float64, len(envelope))
+	for i, value := range envelope {
+		centred[i] = value - mean
+	}
+	autocorrelation := func(lag int) float64 {
+		if lag <= 0 || lag >= len(centred) {
+			return 0
+		}
+		var sum float64
+		for i := lag; i < len(centred); i++ {
+			sum += centred[i] * centred[i-lag]
+		}
+		return sum / float64(len(centred)-lag)
+	}
+
+	minLag := int(math.Floor(60 * hopRate / options.MaxBPM))
+	maxLag := int(math.Ceil(60 * hopRate / options.MinBPM))
+	if minLag < 1 {
+		minLag = 1
+	}
+	best, bestScore := 0, 0.0
+	for lag := minLag; lag <= maxLag && lag < len(centred); lag++ {
+		bpm := 60 * hopRate / float64(lag)
+		prior := math.Exp(-0.5 * math.Pow(math.Log2(bpm/preferredBPM), 2))
+		if score := autocorrelation(lag) * prior; score > bestScore {
+			best, bestScore = lag, score
+		}
+	}
+	if best == 0 {
+		return 0
+	}
+	period := float64(best)
+	before, at, after :=
+		autocorrelation(best-1), autocorrelation(best), autocorrelation(best+1)
+	if curve := before - 2*at + after; curve < 0 {
+		period += 0.5 * (before - after) / curve
+	}
+	return period
+}
+
+/*
+beatIndices places a grid with the given period over the envelope at the phase
+that collects the most onset strength, then moves each grid point to the
+strongest onset within an eighth of a period of it.
+*/
+func beatIndices(envelope []float64, period float64) []int {
+	bestPhase, bestSum := 0, -1.0
+	for phase := 0; float64(phase) < period; phase++ {
+		var sum float64
+		for position := float64(phase); ; position += period {
+			index := int(math.Round(position))
+			if index >= len(envelope) {
+				break
+			}
+			sum += envelope[index]
+		}
+		if sum > bestSum {
+			bestPhase, bestSum = phase, sum
+		}
+	}
+
+	reach := int(period / 8)
+	var indices []int
+	for position := float64(bestPhase); ; position += period {
+		centre := int(math.Round(position))
+		if centre >= len(envelope) {
+			break
+		}
+		index := centre
+		for i := centre - reach; i <= centre+reach; i++ {
+			if i >= 0 && i < len(envelope) && envelope[i] > envelope[index] {
+				index = i
+			}
+		}
+		if len(indices) == 0 || index > indices[len(indices)-1] {
+			indices = append(indices, index)
+		}
+	}
+	return indices
+}
+
+/*
+CuePoints returns a cue point for each beat, labeled with its number counted
+from 1, to be added to a WavWriter with AddCuePoint. Positions are measured in
+frames at the analysed file's sample rate.
+*/
+func (t *Tempo) CuePoints() []wav.CuePoint {
+	cues := make([]wav.CuePoint, len(t.Beats))
+	for i, beat := range t.Beats {
+		cues[i] = wav.CuePoint{
+			Id:       uint32(i + 1),
+			Position: uint32(beat * time.Duration(t.SampleRate) / time.Second),
+			Label:    fmt.Sprintf("Beat %v", i+1),
+		}
+	}
+	return cues
+}
+
+/*
+ClickTrack returns a single track format 0 Midi with a click at each beat,
+played as a General MIDI Hi Wood Block on the percussion channel. The track's
+tempo is the estimated BPM, so beats that follow the grid fall on quarter
+notes of the given division.
+*/
+func (t *Tempo) ClickTrack(division uint16) *midi.Midi {
+	var events []midi.TrackEvent
+	var ticks []uint64
+	add := func(tick uint64, data ...byte) {
+		events = append(events, midi.TrackEvent{Data: data})
+		ticks = append(ticks, tick)
+	}
+	if t.BPM > 0 {
+		micros := uint32(math.Round(60e6 / t.BPM))
+		add(0, midi.MetaEvent, midi.MetaSetTempo, 3,
+			byte(micros>>16), byte(micros>>8), byte(micros))
+	}
+	tickOf := func(beat time.Duration) uint64 {
+		return uint64(math.Round(
+			beat.Seconds() * t.BPM / 60 * float64(division)))
+	}
+	var end uint64
+	for i, beat := range t.Beats {
+		start := tickOf(beat)
+		end = start + uint64(division/4)
+		if i+1 < len(t.Beats) {
+			if next := tickOf(t.Beats[i+1]); next < end {
+				end = next
+			}
+		}
+		add(start, midi.NoteOnEvent|gm.PercussionChannel, clickKey, 100)
+		add(end, midi.NoteOffEvent|gm.PercussionChannel, clickKey, 0)
+	}
+	add(end, midi.MetaEvent, midi.MetaEndOfTrack)
+	return newTrackMidi(division, events, ticks)
+}
+
+/*
+newTrackMidi returns a single track format 0 Midi holding events, placed at the
+given absolute ticks in order.
+*/
+func newTrackMidi(
+	division uint16, events []midi.TrackEvent, ticks []uint64) *midi.Midi {
+	var last uint64
+	for i := range events {
+		events[i].DeltaTime = int(ticks[i] - last)
+		last = ticks[i]
+	}
+	m := &midi.Midi{
+		HeaderChunk: &midi.HeaderChunk{Format: 0, Ntrks: 1, Division: division},
+		TrackChunks: []midi.TrackChunk{{TrackEvents: events}},
+	}
+	m.Reindex()
+	return m
+}
@@ -0,0 +1,108 @@
+package analysis_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"regexp"
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio/analysis"
+	"github.com/husafan/audio/midi"
+	"github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+newMonoReader returns a WavReader for a 16 bit mono file at the given sample
+rate, holding samples between -1 and 1.
+*/
+func newMonoReader(
+	t *testing.T, rate uint32, samples []float64) *wav.WavReader {
+	fmtChunk := wav.NewDefaultFmtChunk()
+	fmtChunk.NumChannels = 1
+	fmtChunk.SampleRate = rate
+	fmtChunk.ByteRate = rate * 2
+	fmtChunk.BlockAlign = 2
+	output := new(bufferWriterAt)
+	writer, err := wav.NewWavWriter(output, fmtChunk)
+	assert.Nil(t, err)
+	for _, value := range samples {
+		sample := wav.Sample{make([]byte, 2)}
+		binary.LittleEndian.PutUint16(
+			sample[0], uint16(int16(value*math.MaxInt16)))
+		assert.Nil(t, writer.AddSample(sample))
+	}
+	reader, err := wav.NewWavReader(bytes.NewReader(output.data))
+	assert.Nil(t, err)
+	return reader
+}
+
+/*
+newClickReader returns a mono WavReader at 8 kHz holding seconds of audio, with
+a short decaying burst at each of the given times.
+*/
+func newClickReader(
+	t *testing.T, seconds float64, clicks ...time.Duration) *wav.WavReader {
+	const rate = 8000
+	samples := make([]float64, int(seconds*rate))
+	for _, click := range clicks {
+		start := int(click * rate / time.Second)
+		for i := 0; i < 400 && start+i < len(samples); i++ {
+			decay := math.Exp(-float64(i) / 80)
+			tone := math.Sin(2 * math.Pi * 1000 * float64(i) / rate)
+			samples[start+i] = 0.8 * decay * tone
+		}
+	}
+	return newMonoReader(t, rate, samples)
+}
+
+func TestEstimateTempo(t *testing.T) {
+	var clicks []time.Duration
+	for beat := 0; beat < 16; beat++ {
+		clicks = append(clicks,
+			250*time.Millisecond+time.Duration(beat)*500*time.Millisecond)
+	}
+	tempo, err := EstimateTempo(newClickReader(t, 8, clicks...), TempoOptions{})
+	assert.Nil(t, err)
+	assert.InDelta(t, 120, tempo.BPM, 1)
+	assert.Equal(t, len(clicks), len(tempo.Beats))
+	for i, beat := range tempo.Beats {
+		assert.InDelta(t, clicks[i].Seconds(), beat.Seconds(), 0.02)
+	}
+
+	cues := tempo.CuePoints()
+	assert.Equal(t, len(clicks), len(cues))
+	assert.Equal(t, uint32(1), cues[0].Id)
+	assert.Equal(t, "Beat 1", cues[0].Label)
+	assert.InDelta(t, 2000, float64(cues[0].Position), 160)
+
+	clickTrack := tempo.ClickTrack(96)
+	data, err := clickTrack.MarshalBinary()
+	assert.Nil(t, err)
+	parsed := new(midi.Midi)
+	assert.Nil(t, parsed.UnmarshalBinary(data))
+	notes := parsed.Notes()
+	assert.Equal(t, len(clicks), len(notes))
+	for i, note := range notes {
+		assert.Equal(t, uint8(9), note.Channel)
+		assert.InDelta(t, tempo.Beats[i].Seconds(), note.StartTime.Seconds(), 0.01)
+	}
+}
+
+func TestEstimateTempoSilence(t *testing.T) {
+	tempo, err := EstimateTempo(newClickReader(t, 1), TempoOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0.0, tempo.BPM)
+	assert.Empty(t, tempo.Beats)
+	assert.Empty(t, tempo.ClickTrack(96).Notes())
+}
+
+func TestEstimateTempoRange(t *testing.T) {
+	_, err := EstimateTempo(
+		newClickReader(t, 1), TempoOptions{MinBPM: 120, MaxBPM: 60})
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("0 < MinBPM < MaxBPM; found 120-60")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
@@ -0,0 +1,151 @@
+package analysis
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/dsp"
+	"github.com/husafan/audio/midi"
+	"github.com/husafan/audio/wav"
+)
+
+const (
+	// pitchWindowsPerSecond is the number of windows a second is divided into
+	// for pitch detection. Windows of 40 ms find fundamentals down to 50 Hz.
+	pitchWindowsPerSecond = 25
+	// pitchStepsPerSecond is the number of pitch estimates per second.
+	pitchStepsPerSecond = 100
+	// transcriptionDivision is the division of transcribed files, which use
+	// the default tempo of 120 BPM, so a tick lasts just over a millisecond.
+	transcriptionDivision = 480
+)
+
+/*
+TranscribeOptions controls Transcribe. Windows quieter than Silence, which
+defaults to -50 dBFS, are treated as rests, and notes shorter than MinDuration,
+which defaults to 50 ms, are dropped as glitches. Progress, if not nil, is
+called as TranscribeContext reads the file.
+*/
+type TranscribeOptions struct {
+	Silence     audio.Decibel
+	MinDuration time.Duration
+	Progress    audio.ProgressFunc
+}
+
+// pitchStep is the pitch detected in a step of Transcribe.
+type pitchStep struct {
+	key   int
+	level float64
+}
+
+/*
+Transcribe reads every remaining frame of a WAV file holding a single melodic
+line, such as a voice or a solo instrument, and returns it as a single track
+format 0 Midi on channel 0. The channels are mixed to mono and dsp.DetectPitch
+runs over 40 ms windows every 10 ms. Each pitch is rounded to the nearest key,
+and runs of windows on the same key become notes whose velocity follows their
+loudest window. Chords cannot be transcribed.
+*/
+func Transcribe(
+	reader *wav.WavReader, options TranscribeOptions) (*midi.Midi, error) {
+	return TranscribeContext(context.Background(), reader, options)
+}
+
+/*
+TranscribeContext is Transcribe, stopping once ctx is done. Nothing is
+transcribed from a partial read, so only ctx's error is returned.
+*/
+func TranscribeContext(ctx context.Context, reader *wav.WavReader,
+	options TranscribeOptions) (*midi.Midi, error) {
+	if options.Silence == 0 {
+		options.Silence = -50
+	}
+	if options.MinDuration == 0 {
+		options.MinDuration = 50 * time.Millisecond
+	}
+	rate := int(reader.Fmt.SampleRate)
+	size := rate / pitchWindowsPerSecond
+	hop := rate / pitchStepsPerSecond
+	if hop < 1 {
+		hop = 1
+	}
+	silence := options.Silence.Linear()
+	var steps []pitchStep
+	window := make([]float64, 0, size+hop)
+	err := forEachFrame(ctx, reader, options.Progress, func(frame []float64) {
+		var mono float64
+		for _, value := range frame {
+			mono += value
+		}
+		window = append(window, mono/float64(len(frame)))
+		if len(window) < size {
+			return
+		}
+		var power float64
+		for _, value := range window {
+			power += value * value
+		}
+		step := pitchStep{key: -1, level: math.Sqrt(power / float64(size))}
+		if step.level > silence {
+			pitch := dsp.DetectPitch(window, float64(rate))
+			if key := frequencyKey(pitch.Frequency); key >= 0 {
+				step.key = key
+			}
+		}
+		steps = append(steps, step)
+		window = window[:copy(window, window[hop:])]
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A step's pitch describes the middle of its window.
+	offset := time.Duration(size-hop) * time.Second / time.Duration(2*rate)
+	stepTime := func(step int) time.Duration {
+		return offset + time.Duration(step*hop)*time.Second/time.Duration(rate)
+	}
+	var events []midi.TrackEvent
+	var ticks []uint64
+	add := func(at time.Duration, data ...byte) {
+		events = append(events, midi.TrackEvent{Data: data})
+		ticks = append(ticks, uint64(at*2*transcriptionDivision/time.Second))
+	}
+	var end time.Duration
+	for first := 0; first < len(steps); {
+		last, level := first+1, steps[first].level
+		for last < len(steps) && steps[last].key == steps[first].key {
+			level = math.Max(level, steps[last].level)
+			last++
+		}
+		key := steps[first].key
+		start := stepTime(first)
+		if key >= 0 && stepTime(last)-start >= options.MinDuration {
+			velocity := math.Round(
+				127 * (1 + float64(audio.LinearToDecibel(level))/60))
+			velocity = math.Min(127, math.Max(1, velocity))
+			end = stepTime(last)
+			add(start, midi.NoteOnEvent, byte(key), byte(velocity))
+			add(end, midi.NoteOffEvent, byte(key), 0)
+		}
+		first = last
+	}
+	add(end, midi.MetaEvent, midi.MetaEndOfTrack)
+	return newTrackMidi(transcriptionDivision, events, ticks), nil
+}
+
+/*
+frequencyKey returns the MIDI key nearest to frequency Hz, with A4 at 440 Hz as
+key 69, or -1 if there is none.
+*/
+func frequencyKey(frequency float64) int {
+	if frequency <= 0 {
+		return -1
+	}
+	key := int(math.Round(69 + 12*math.Log2(frequency/440)))
+	if key < 0 || key > 127 {
+		return -1
+	}
+	return key
+}
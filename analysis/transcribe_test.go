@@ -0,0 +1,63 @@
+package analysis_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio/analysis"
+	"github.com/stretchr/testify/assert"
+)
+
+// melodyNote is a note of the melody synthesized by TestTranscribe.
+type melodyNote struct {
+	key       uint8
+	amplitude float64
+	duration  time.Duration
+}
+
+func TestTranscribe(t *testing.T) {
+	const rate = 8000
+	melody := []melodyNote{
+		{69, 0.5, 300 * time.Millisecond},
+		{0, 0, 100 * time.Millisecond},
+		{72, 0.1, 300 * time.Millisecond},
+		{76, 0.5, 20 * time.Millisecond},
+		{64, 0.5, 300 * time.Millisecond},
+	}
+	var samples []float64
+	for _, note := range melody {
+		frequency := 440 * math.Pow(2, (float64(note.key)-69)/12)
+		count := int(note.duration * rate / time.Second)
+		for i := 0; i < count; i++ {
+			phase := 2 * math.Pi * frequency * float64(i) / rate
+			samples = append(samples, note.amplitude*
+				(0.8*math.Sin(phase)+0.2*math.Sin(2*phase)))
+		}
+	}
+
+	transcription, err := Transcribe(
+		newMonoReader(t, rate, samples), TranscribeOptions{})
+	assert.Nil(t, err)
+	notes := transcription.Notes()
+	// The 20 ms note is too short to keep.
+	assert.Equal(t, 3, len(notes))
+	starts := []float64{0, 0.4, 0.72}
+	for i, key := range []uint8{69, 72, 64} {
+		assert.Equal(t, key, notes[i].Key)
+		assert.Equal(t, uint8(0), notes[i].Channel)
+		assert.InDelta(t, starts[i], notes[i].StartTime.Seconds(), 0.03)
+		assert.InDelta(t, 0.3, notes[i].Duration.Seconds(), 0.05)
+	}
+	assert.True(t, notes[0].Velocity > notes[1].Velocity)
+}
+
+func TestTranscribeSilence(t *testing.T) {
+	transcription, err := Transcribe(
+		newMonoReader(t, 8000, make([]float64, 4000)), TranscribeOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, transcription.Notes())
+	data, err := transcription.MarshalBinary()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, data)
+}
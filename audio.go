@@ -0,0 +1,94 @@
+/*
+The audio package provides functionality shared by the format packages, such as
+probing a stream to find out what it holds. Formats register themselves when
+their package is imported, in the same way as image formats in the standard
+library, so a program imports the formats it wants to recognize:
+
+	import (
+		"github.com/husafan/audio"
+		_ "github.com/husafan/audio/midi"
+		_ "github.com/husafan/audio/wav"
+	)
+*/
+package audio
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrFormat is returned by Probe when no registered format recognizes a stream.
+var ErrFormat = errors.New("audio: unknown format")
+
+/*
+Info is a format independent summary of an audio stream, in the spirit of
+ffprobe. Fields that do not apply to a format, such as the sample rate of a MIDI
+file, are left zero. Metadata is keyed by lower case names such as "title" and
+"artist".
+*/
+type Info struct {
+	Format     string
+	Codec      string
+	SampleRate uint32
+	Channels   int
+	BitDepth   int
+	Duration   time.Duration
+	Metadata   map[string]string
+}
+
+// format is a registered format and the function that probes it.
+type format struct {
+	name  string
+	magic string
+	probe func(io.Reader) (*Info, error)
+}
+
+var (
+	formatsMutex sync.Mutex
+	formats      []format
+)
+
+/*
+RegisterFormat registers a format for use by Probe. name is the name of the
+format, such as "wav". magic is the prefix that identifies the format, in which
+each "?" matches any byte. probe is called with a reader positioned at the
+start of the stream once its prefix matches. RegisterFormat is typically called
+from the init function of a format package.
+*/
+func RegisterFormat(
+	name, magic string, probe func(io.Reader) (*Info, error)) {
+	formatsMutex.Lock()
+	defer formatsMutex.Unlock()
+	formats = append(formats, format{name, magic, probe})
+}
+
+/*
+Probe identifies the format of the stream in reader and returns a summary of it.
+ErrFormat is returned if no registered format matches the start of the stream.
+*/
+func Probe(reader io.Reader) (*Info, error) {
+	buffered := bufio.NewReader(reader)
+	formatsMutex.Lock()
+	registered := append([]format{}, formats...)
+	formatsMutex.Unlock()
+	for _, f := range registered {
+		prefix, err := buffered.Peek(len(f.magic))
+		if err == nil && matchMagic(f.magic, prefix) {
+			return f.probe(buffered)
+		}
+	}
+	return nil, ErrFormat
+}
+
+// matchMagic reports whether prefix matches magic, where "?" matches any byte.
+func matchMagic(magic string, prefix []byte) bool {
+	for i := range magic {
+		if magic[i] != '?' && magic[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,38 @@
+package audio_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/husafan/audio"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	RegisterFormat("test", "TE?T", func(reader io.Reader) (*Info, error) {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		return &Info{Format: "test", Codec: string(data)}, nil
+	})
+}
+
+func TestProbeMatchesMagic(t *testing.T) {
+	info, err := Probe(strings.NewReader("TEXT and more"))
+	assert.Nil(t, err)
+	assert.Equal(t, "test", info.Format)
+	// The probe function sees the stream from its start.
+	assert.Equal(t, "TEXT and more", info.Codec)
+}
+
+func TestProbeUnknownFormat(t *testing.T) {
+	info, err := Probe(strings.NewReader("OGGS"))
+	assert.Nil(t, info)
+	assert.Equal(t, ErrFormat, err)
+
+	info, err = Probe(strings.NewReader("TE"))
+	assert.Nil(t, info)
+	assert.Equal(t, ErrFormat, err)
+}
@@ -0,0 +1,57 @@
+package audio
+
+import "time"
+
+/*
+Format describes uncompressed audio as the packages exchange it in memory:
+SampleRate frames per second, each holding one value per channel.
+*/
+type Format struct {
+	SampleRate uint32
+	Channels   int
+}
+
+/*
+Frame holds one value per channel for a single instant, between -1 and 1 at full
+scale. Channels are in the order they are stored in a file, such as left before
+right.
+*/
+type Frame []float64
+
+/*
+Buffer holds frames of audio in memory, as exchanged by the wav and dsp
+packages. Data is interleaved, so frame i is held by
+Data[i*Channels : (i+1)*Channels].
+*/
+type Buffer struct {
+	Format Format
+	Data   []float64
+}
+
+// NewBuffer returns a silent Buffer of the given number of frames.
+func NewBuffer(format Format, frames int) *Buffer {
+	return &Buffer{Format: format, Data: make([]float64, frames*format.Channels)}
+}
+
+// Frames returns the number of whole frames held by the Buffer.
+func (b *Buffer) Frames() int {
+	if b.Format.Channels <= 0 {
+		return 0
+	}
+	return len(b.Data) / b.Format.Channels
+}
+
+// Frame returns frame i of the Buffer, which shares its memory.
+func (b *Buffer) Frame(i int) Frame {
+	channels := b.Format.Channels
+	return Frame(b.Data[i*channels : (i+1)*channels : (i+1)*channels])
+}
+
+// Duration returns the time the frames of the Buffer take to play.
+func (b *Buffer) Duration() time.Duration {
+	if b.Format.SampleRate == 0 {
+		return 0
+	}
+	return time.Duration(b.Frames()) * time.Second /
+		time.Duration(b.Format.SampleRate)
+}
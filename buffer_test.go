@@ -0,0 +1,24 @@
+package audio_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuffer(t *testing.T) {
+	buffer := NewBuffer(Format{SampleRate: 4, Channels: 2}, 3)
+	assert.Equal(t, 3, buffer.Frames())
+	assert.Equal(t, 750*time.Millisecond, buffer.Duration())
+
+	// Frames share the memory of the buffer.
+	frame := buffer.Frame(1)
+	frame[0], frame[1] = 0.5, -0.5
+	assert.Equal(t, []float64{0, 0, 0.5, -0.5, 0, 0}, buffer.Data)
+	assert.Equal(t, Frame{0.5, -0.5}, buffer.Frame(1))
+
+	assert.Equal(t, 0, new(Buffer).Frames())
+	assert.Equal(t, time.Duration(0), new(Buffer).Duration())
+}
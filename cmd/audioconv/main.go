@@ -0,0 +1,404 @@
+/*
+audioconv converts WAV and headerless PCM files between sample formats,
+channel counts and sample rates.
+
+Usage:
+
+	audioconv [-bits 16] [-float] [-channels 2] [-rate 48000]
+		[-quality polyphase] [-raw s16le:8000:1] input.wav output.wav
+
+-bits and -float choose the sample format of the output, which defaults to that
+of the input. Integer samples are clipped to full scale. -channels remixes the
+audio: mono is copied to every output channel, and any number of channels can
+be averaged down to mono. -rate resamples the audio with the dsp package at the
+-quality given, linear, polyphase or sinc, trading speed for accuracy.
+Resampling reads the whole file into memory. LIST INFO metadata is copied to
+the output.
+
+Files named .raw or .pcm hold headerless PCM, described by -raw as
+encoding:rate:channels, such as s16le:44100:2 or mulaw:8000:1, which a raw
+input needs. A raw output is written with the encoding of -raw when it is
+given, and otherwise as little endian integers, unsigned for 8 bits, or floats,
+as -bits and -float choose. Its rate and channels come from the input, -rate
+and -channels as for WAV.
+
+Formats other than WAV and raw PCM are not supported yet, as the library has
+no AIFF support.
+*/
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/dsp"
+	"github.com/husafan/audio/raw"
+	"github.com/husafan/audio/wav"
+)
+
+const (
+	BitsError     = "cannot write %v bit %s samples"
+	ChannelsError = "cannot remix %v channels to %v"
+	OutputError   = "cannot write %q files; only .wav, .raw and .pcm are supported"
+	QualityError  = "unknown quality %q; use linear, polyphase or sinc"
+	RawError      = "%s holds raw PCM; describe it with -raw encoding:rate:channels"
+)
+
+// qualities maps the names accepted by -quality to resampling tiers.
+var qualities = map[string]dsp.ResampleQuality{
+	"linear":    dsp.ResampleLinear,
+	"polyphase": dsp.ResamplePolyphase,
+	"sinc":      dsp.ResampleSinc,
+}
+
+// blockFrames is the number of frames converted at a time.
+const blockFrames = 4096
+
+// options holds the command line flags.
+type options struct {
+	bits     int
+	float    bool
+	channels int
+	rate     uint
+	quality  string
+	raw      string
+}
+
+/*
+source is the decoded input, read by a wav.WavReader or a raw.Reader, and sink
+the encoded output, which only appears at its path once finalized.
+*/
+type (
+	source interface {
+		ReadBuffer(buffer *audio.Buffer) (int, error)
+		ReadAll() (*audio.Buffer, error)
+	}
+	sink interface {
+		WriteBuffer(buffer *audio.Buffer) error
+		Finalize() error
+		Abort() error
+	}
+)
+
+func main() {
+	var opts options
+	flag.IntVar(&opts.bits, "bits", 0, "bits per sample of the output")
+	flag.BoolVar(&opts.float, "float", false, "write IEEE float samples")
+	flag.IntVar(&opts.channels, "channels", 0, "channels of the output")
+	flag.UintVar(&opts.rate, "rate", 0, "sample rate of the output in Hz")
+	flag.StringVar(&opts.quality, "quality", "polyphase",
+		"resampling quality: linear, polyphase or sinc")
+	flag.StringVar(&opts.raw, "raw", "",
+		"format of .raw and .pcm files as encoding:rate:channels")
+	flag.Parse()
+	if flag.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "usage: audioconv [flags] input output")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	if err := convert(flag.Arg(0), flag.Arg(1), opts); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// convert writes the file at input to output as described by opts.
+func convert(input, output string, opts options) error {
+	var rawFormat *raw.Format
+	if opts.raw != "" {
+		format, err := raw.ParseFormat(opts.raw)
+		if err != nil {
+			return err
+		}
+		rawFormat = &format
+	}
+	if ext := strings.ToLower(filepath.Ext(output)); ext != ".wav" &&
+		!isRaw(output) {
+		return fmt.Errorf(OutputError, ext)
+	}
+	file, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var reader source
+	var inputFmt *wav.FmtChunk
+	var metadata wav.Metadata
+	if isRaw(input) {
+		if rawFormat == nil {
+			return fmt.Errorf(RawError, filepath.Base(input))
+		}
+		rawReader, err := raw.NewReader(bufio.NewReader(file), *rawFormat)
+		if err != nil {
+			return err
+		}
+		reader, inputFmt = rawReader, rawFmtChunk(*rawFormat)
+	} else {
+		wavReader, err := wav.NewWavReader(file)
+		if err != nil {
+			return err
+		}
+		reader, inputFmt = wavReader, wavReader.Fmt
+		metadata = wavReader.Metadata
+	}
+	fmtChunk, err := outputFormat(inputFmt, opts)
+	if err != nil {
+		return err
+	}
+	from, to := int(inputFmt.NumChannels), int(fmtChunk.NumChannels)
+	if from != to && from != 1 && to != 1 {
+		return fmt.Errorf(ChannelsError, from, to)
+	}
+
+	var writer sink
+	if isRaw(output) {
+		writer, err = newRawSink(output, outputRawFormat(fmtChunk, rawFormat))
+	} else {
+		var writerOptions []wav.WriterOption
+		if len(metadata) > 0 {
+			writerOptions = append(writerOptions, wav.WithMetadata(metadata))
+		}
+		writerOptions = append(writerOptions, wav.WithBufferSize(64<<10))
+		writer, err = wav.NewAtomicWavWriter(
+			output, fmtChunk, writerOptions...)
+	}
+	if err != nil {
+		return err
+	}
+	if fmtChunk.SampleRate != inputFmt.SampleRate {
+		out, err := resample(
+			reader, fmtChunk.Format(), qualities[opts.quality])
+		if err == nil {
+			err = writer.WriteBuffer(out)
+		}
+		if err != nil {
+			writer.Abort()
+			return err
+		}
+		return writer.Finalize()
+	}
+	in := audio.NewBuffer(inputFmt.Format(), blockFrames)
+	out := audio.NewBuffer(fmtChunk.Format(), blockFrames)
+	for {
+		n, err := reader.ReadBuffer(in)
+		// Raw input that ends partway through a frame loses that frame,
+		// as it does when read whole for resampling.
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		if err != nil && err != io.EOF {
+			writer.Abort()
+			return err
+		}
+		out.Data = out.Data[:n*to]
+		for i := 0; i < n; i++ {
+			remix(in.Frame(i), out.Frame(i))
+		}
+		if writeErr := writer.WriteBuffer(out); writeErr != nil {
+			writer.Abort()
+			return writeErr
+		}
+		if err == io.EOF {
+			return writer.Finalize()
+		}
+	}
+}
+
+/*
+outputFormat returns the fmt chunk of the output: that of input, changed as
+opts asks.
+*/
+func outputFormat(input *wav.FmtChunk, opts options) (*wav.FmtChunk, error) {
+	f := wav.NewDefaultFmtChunk()
+	f.SampleRate = input.SampleRate
+	f.NumChannels = input.NumChannels
+	f.AudioFormat = input.AudioFormat
+	f.BitsPerSample = input.BitsPerSample
+	if opts.rate > 0 {
+		if _, ok := qualities[opts.quality]; !ok {
+			return nil, fmt.Errorf(QualityError, opts.quality)
+		}
+		f.SampleRate = uint32(opts.rate)
+	}
+	if opts.channels < 0 {
+		return nil, fmt.Errorf(ChannelsError, input.NumChannels, opts.channels)
+	} else if opts.channels > 0 {
+		f.NumChannels = uint16(opts.channels)
+	}
+	if opts.float {
+		f.AudioFormat = wav.FormatIEEEFloat
+		if opts.bits == 0 && f.BitsPerSample != 64 {
+			f.BitsPerSample = 32
+		}
+	} else if opts.bits > 0 && f.AudioFormat == wav.FormatIEEEFloat {
+		f.AudioFormat = wav.FormatPCM
+	}
+	if opts.bits > 0 {
+		f.BitsPerSample = uint16(opts.bits)
+	}
+	switch bits := f.BitsPerSample; {
+	case f.AudioFormat == wav.FormatPCM && bits%8 == 0 && bits >= 8 &&
+		bits <= 32:
+	case f.AudioFormat == wav.FormatIEEEFloat && (bits == 32 || bits == 64):
+	default:
+		kind := "integer"
+		if f.AudioFormat == wav.FormatIEEEFloat {
+			kind = "float"
+		}
+		return nil, fmt.Errorf(BitsError, bits, kind)
+	}
+	f.BlockAlign = f.NumChannels * f.BitsPerSample / 8
+	f.ByteRate = f.SampleRate * uint32(f.BlockAlign)
+	return f, nil
+}
+
+/*
+resample returns the rest of reader remixed and resampled to format at the
+given quality.
+*/
+func resample(reader source, format audio.Format,
+	quality dsp.ResampleQuality) (*audio.Buffer, error) {
+	in, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	remixed := audio.NewBuffer(
+		audio.Format{SampleRate: in.Format.SampleRate,
+			Channels: format.Channels}, in.Frames())
+	for i := 0; i < in.Frames(); i++ {
+		remix(in.Frame(i), remixed.Frame(i))
+	}
+	return dsp.Resample(remixed, format.SampleRate,
+		dsp.ResampleSettings{Quality: quality})
+}
+
+// isRaw reports whether the file at path holds headerless PCM.
+func isRaw(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".raw" || ext == ".pcm"
+}
+
+/*
+rawFmtChunk returns the fmt chunk of a WAV file holding the samples of format,
+so that raw input is converted as a WAV file would be. G.711 samples expand to
+16 bit PCM.
+*/
+func rawFmtChunk(format raw.Format) *wav.FmtChunk {
+	f := wav.NewDefaultFmtChunk()
+	f.SampleRate = format.SampleRate
+	f.NumChannels = uint16(format.Channels)
+	f.AudioFormat = wav.FormatPCM
+	f.BitsPerSample = uint16(format.BitDepth)
+	switch format.Encoding {
+	case raw.Float:
+		f.AudioFormat = wav.FormatIEEEFloat
+	case raw.MuLaw, raw.ALaw:
+		f.BitsPerSample = 16
+	}
+	f.BlockAlign = f.NumChannels * f.BitsPerSample / 8
+	f.ByteRate = f.SampleRate * uint32(f.BlockAlign)
+	return f
+}
+
+/*
+outputRawFormat returns the format of a raw output of the samples described by
+f: the encoding of rawFormat, if given, and otherwise that of f.
+*/
+func outputRawFormat(f *wav.FmtChunk, rawFormat *raw.Format) raw.Format {
+	format := raw.Format{
+		SampleRate: f.SampleRate,
+		Channels:   int(f.NumChannels),
+		BitDepth:   int(f.BitsPerSample),
+	}
+	switch {
+	case rawFormat != nil:
+		format.Encoding = rawFormat.Encoding
+		format.BitDepth = rawFormat.BitDepth
+		format.BigEndian = rawFormat.BigEndian
+	case f.AudioFormat == wav.FormatIEEEFloat:
+		format.Encoding = raw.Float
+	case f.BitsPerSample == 8:
+		format.Encoding = raw.Unsigned
+	}
+	return format
+}
+
+/*
+rawSink writes raw PCM to a temporary file alongside path, and moves it into
+place when finalized, as wav.AtomicWavWriter does for WAV files.
+*/
+type rawSink struct {
+	*raw.Writer
+	buffered *bufio.Writer
+	file     *os.File
+	path     string
+}
+
+// newRawSink returns a rawSink that will save raw PCM in format to path.
+func newRawSink(path string, format raw.Format) (*rawSink, error) {
+	file, err := os.CreateTemp(
+		filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	buffered := bufio.NewWriterSize(file, 64<<10)
+	writer, err := raw.NewWriter(buffered, format)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return &rawSink{
+		Writer: writer, buffered: buffered, file: file, path: path}, nil
+}
+
+// Finalize flushes the temporary file and renames it to the destination.
+func (s *rawSink) Finalize() error {
+	err := s.buffered.Flush()
+	if err == nil {
+		err = s.file.Chmod(0644)
+	}
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(s.file.Name(), s.path)
+	}
+	if err != nil {
+		os.Remove(s.file.Name())
+	}
+	return err
+}
+
+// Abort discards the temporary file without touching the destination.
+func (s *rawSink) Abort() error {
+	s.file.Close()
+	return os.Remove(s.file.Name())
+}
+
+/*
+remix fills out from in: channels are copied when the counts match, mono is
+copied to every channel and anything else is averaged down to mono.
+*/
+func remix(in, out []float64) {
+	switch {
+	case len(in) == len(out):
+		copy(out, in)
+	case len(in) == 1:
+		for channel := range out {
+			out[channel] = in[0]
+		}
+	default:
+		var sum float64
+		for _, value := range in {
+			sum += value
+		}
+		out[0] = sum / float64(len(in))
+	}
+}
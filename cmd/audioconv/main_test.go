@@ -0,0 +1,159 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeInput writes a 16 bit stereo file with a title to a temporary directory.
+func writeInput(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "input.wav")
+	writer, err := wav.NewAtomicWavWriter(path, nil,
+		wav.WithMetadata(wav.Metadata{wav.InfoTitle: "A title"}))
+	assert.Nil(t, err)
+	assert.Nil(t, writer.AddSample(wav.Sample{{0x00, 0x40}, {0x00, 0x20}}))
+	assert.Nil(t, writer.AddSample(wav.Sample{{0x00, 0xC0}, {0x00, 0xC0}}))
+	assert.Nil(t, writer.Finalize())
+	return path
+}
+
+// readOutput returns the fmt chunk, metadata and samples of the file at path.
+func readOutput(t *testing.T, path string) (*wav.WavReader, []float64) {
+	file, err := os.Open(path)
+	assert.Nil(t, err)
+	defer file.Close()
+	reader, err := wav.NewWavReader(file)
+	assert.Nil(t, err)
+	buffer := audio.NewBuffer(reader.Fmt.Format(), 10)
+	n, _ := reader.ReadBuffer(buffer)
+	return reader, buffer.Data[:n*buffer.Format.Channels]
+}
+
+func TestConvert(t *testing.T) {
+	input := writeInput(t)
+	for _, test := range []struct {
+		opts        options
+		audioFormat uint16
+		bits        uint16
+		values      []float64
+	}{
+		{options{bits: 24, channels: 1}, wav.FormatPCM, 24,
+			[]float64{0.375, -0.5}},
+		{options{float: true}, wav.FormatIEEEFloat, 32,
+			[]float64{0.5, 0.25, -0.5, -0.5}},
+		{options{bits: 8}, wav.FormatPCM, 8,
+			[]float64{0.5, 0.25, -0.5, -0.5}},
+	} {
+		output := filepath.Join(t.TempDir(), "output.wav")
+		assert.Nil(t, convert(input, output, test.opts))
+		reader, values := readOutput(t, output)
+		assert.Equal(t, test.audioFormat, reader.Fmt.AudioFormat)
+		assert.Equal(t, test.bits, reader.Fmt.BitsPerSample)
+		assert.Equal(t, reader.Fmt.NumChannels*test.bits/8,
+			reader.Fmt.BlockAlign)
+		assert.Equal(t, "A title", reader.Metadata[wav.InfoTitle])
+		assert.Equal(t, test.values, values)
+	}
+}
+
+func TestConvertMonoToStereo(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "mono.wav")
+	assert.Nil(t, convert(writeInput(t), input, options{channels: 1}))
+	output := filepath.Join(t.TempDir(), "stereo.wav")
+	assert.Nil(t, convert(input, output, options{channels: 2}))
+	_, values := readOutput(t, output)
+	assert.Equal(t, []float64{0.375, 0.375, -0.5, -0.5}, values)
+}
+
+func TestConvertRate(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "output.wav")
+	assert.Nil(t, convert(writeInput(t), output,
+		options{rate: 22050, quality: "linear", channels: 1}))
+	reader, values := readOutput(t, output)
+	assert.Equal(t, uint32(22050), reader.Fmt.SampleRate)
+	assert.Equal(t, uint32(44100), reader.Fmt.ByteRate)
+	assert.Equal(t, "A title", reader.Metadata[wav.InfoTitle])
+	assert.Equal(t, []float64{0.375}, values)
+}
+
+func TestConvertErrors(t *testing.T) {
+	input := writeInput(t)
+	dir := t.TempDir()
+	for _, test := range []struct {
+		output string
+		opts   options
+		err    string
+	}{
+		{"output.aiff", options{}, `cannot write ".aiff" files`},
+		{"output.wav", options{bits: 12}, "cannot write 12 bit integer"},
+		{"output.wav", options{bits: 16, float: true},
+			"cannot write 16 bit float"},
+		{"output.wav", options{channels: 3}, "cannot remix 2 channels to 3"},
+		{"output.wav", options{rate: 8000, quality: "cubic"},
+			`unknown quality "cubic"`},
+	} {
+		err := convert(input, filepath.Join(dir, test.output), test.opts)
+		assert.NotEqual(t, "",
+			regexp.MustCompile(test.err).FindString(err.Error()))
+	}
+	// Failed conversions leave nothing behind.
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(entries))
+}
+
+func TestConvertRaw(t *testing.T) {
+	dir := t.TempDir()
+	// WAV to raw follows the input's sample format unless -raw is given.
+	output := filepath.Join(dir, "output.raw")
+	assert.Nil(t, convert(writeInput(t), output, options{}))
+	data, err := os.ReadFile(output)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x00, 0x40, 0x00, 0x20, 0x00, 0xC0, 0x00, 0xC0},
+		data)
+	output = filepath.Join(dir, "output.pcm")
+	assert.Nil(t, convert(writeInput(t), output,
+		options{channels: 1, raw: "s16be:8000:2"}))
+	data, err = os.ReadFile(output)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x30, 0x00, 0xC0, 0x00}, data)
+	output = filepath.Join(dir, "output8.raw")
+	assert.Nil(t, convert(writeInput(t), output, options{bits: 8}))
+	data, err = os.ReadFile(output)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0xC0, 0xA0, 0x40, 0x40}, data)
+
+	// Raw to WAV, expanding G.711 to 16 bits.
+	input := filepath.Join(dir, "call.raw")
+	assert.Nil(t, os.WriteFile(input, []byte{0xFF, 0x80, 0x00, 0x7F}, 0644))
+	output = filepath.Join(dir, "call.wav")
+	assert.Nil(t, convert(input, output,
+		options{channels: 2, raw: "alaw:8000:1"}))
+	reader, values := readOutput(t, output)
+	assert.Equal(t, uint32(8000), reader.Fmt.SampleRate)
+	assert.Equal(t, uint16(16), reader.Fmt.BitsPerSample)
+	assert.Equal(t, []float64{0.02587890625, 0.02587890625,
+		0.16796875, 0.16796875, -0.16796875, -0.16796875,
+		-0.02587890625, -0.02587890625}, values)
+	// A partial frame at the end is dropped.
+	input = filepath.Join(dir, "short.pcm")
+	assert.Nil(t, os.WriteFile(input, []byte{0x00, 0x40, 0x00}, 0644))
+	assert.Nil(t, convert(input, output, options{raw: "s16le:8000:1"}))
+	_, values = readOutput(t, output)
+	assert.Equal(t, []float64{0.5}, values)
+
+	input = filepath.Join(dir, "call.raw")
+	err = convert(input, filepath.Join(dir, "missing.wav"), options{})
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"call.raw holds raw PCM; describe it with -raw").FindString(err.Error()))
+	err = convert(input, filepath.Join(dir, "missing.wav"),
+		options{raw: "s16:8000:1"})
+	assert.NotEqual(t, "", regexp.MustCompile(
+		`unknown encoding "s16"`).FindString(err.Error()))
+}
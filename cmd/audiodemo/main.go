@@ -0,0 +1,246 @@
+/*
+audiodemo is a tour of the library, and a target for testing its packages
+together. It generates a tone, runs it through a chain of effects and saves it
+as tone.wav. It then writes a short MIDI jingle to jingle.mid, reads it back and
+renders it with the synth package to jingle.wav. Finally it measures the
+peaks of both WAV files.
+
+Usage:
+
+	audiodemo [-seconds 2] [-frequency 440] [-effects compress,deess] [-dir .]
+
+The effects are applied in the order given. They are compress, deess,
+multiband, gate, limit, delay, chorus and flanger.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/analysis"
+	"github.com/husafan/audio/dsp"
+	"github.com/husafan/audio/midi"
+	"github.com/husafan/audio/synth"
+	"github.com/husafan/audio/wav"
+)
+
+const (
+	EffectError = "unknown effect %q; use compress, deess, multiband, gate, limit, delay, chorus or flanger"
+)
+
+// options holds the command line flags.
+type options struct {
+	seconds   float64
+	frequency float64
+	effects   string
+	dir       string
+}
+
+func main() {
+	var opts options
+	flag.Float64Var(&opts.seconds, "seconds", 2, "length of the tone")
+	flag.Float64Var(&opts.frequency, "frequency", 440, "frequency of the tone")
+	flag.StringVar(&opts.effects, "effects", "compress,deess",
+		"comma separated effects to apply to the tone")
+	flag.StringVar(&opts.dir, "dir", ".", "directory to write files to")
+	flag.Parse()
+	if err := run(opts, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run writes the demo's files and reports on them to out.
+func run(opts options, out io.Writer) error {
+	format := wav.NewDefaultFmtChunk().Format()
+	tone := generateTone(format, opts.frequency, opts.seconds)
+	chain, err := newChain(opts.effects, format)
+	if err != nil {
+		return err
+	}
+	dsp.ProcessBuffer(chain, tone)
+	tonePath := filepath.Join(opts.dir, "tone.wav")
+	if err := writeWav(tonePath, tone); err != nil {
+		return err
+	}
+
+	data, err := newJingle().MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(
+		filepath.Join(opts.dir, "jingle.mid"), data, 0644); err != nil {
+		return err
+	}
+	jingle, err := midi.ParseMidi(data, nil)
+	if err != nil {
+		return err
+	}
+	rendered, err := synth.Render(jingle, synth.RenderOptions{
+		Settings: synth.DefaultSettings(), Format: format})
+	if err != nil {
+		return err
+	}
+	jinglePath := filepath.Join(opts.dir, "jingle.wav")
+	if err := writeWav(jinglePath, rendered); err != nil {
+		return err
+	}
+
+	for _, path := range []string{tonePath, jinglePath} {
+		if err := report(path, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+generateTone returns seconds of a sine wave at frequency Hz and half scale,
+faded in and out over 10 ms to avoid clicks.
+*/
+func generateTone(
+	format audio.Format, frequency, seconds float64) *audio.Buffer {
+	rate := float64(format.SampleRate)
+	buffer := audio.NewBuffer(format, int(seconds*rate))
+	fade := int(rate / 100)
+	for i := 0; i < buffer.Frames(); i++ {
+		value := 0.5 * math.Sin(2*math.Pi*frequency*float64(i)/rate)
+		if i < fade {
+			value *= float64(i) / float64(fade)
+		} else if edge := buffer.Frames() - 1 - i; edge < fade {
+			value *= float64(edge) / float64(fade)
+		}
+		for channel := range buffer.Frame(i) {
+			buffer.Frame(i)[channel] = value
+		}
+	}
+	return buffer
+}
+
+// chain runs processors one after another.
+type chain []dsp.Processor
+
+func (c chain) Process(frame []float64) {
+	for _, processor := range c {
+		processor.Process(frame)
+	}
+}
+
+// newChain returns the named effects, separated by commas, as a chain.
+func newChain(effects string, format audio.Format) (chain, error) {
+	var processors chain
+	rate := float64(format.SampleRate)
+	compressor := dsp.CompressorSettings{
+		Threshold: -18, Ratio: 4, Attack: 5, Release: 50, Makeup: 6}
+	for _, name := range strings.Split(effects, ",") {
+		var processor dsp.Processor
+		var err error
+		switch strings.TrimSpace(name) {
+		case "":
+			continue
+		case "compress":
+			processor, err = dsp.NewCompressor(compressor, rate)
+		case "deess":
+			processor, err = dsp.NewDeEsser(dsp.DeEsserSettings{
+				Frequency: 6000, Threshold: -30, Ratio: 4, Attack: 1,
+				Release: 20}, format.Channels, rate)
+		case "multiband":
+			processor, err = dsp.NewMultibandCompressor(dsp.MultibandSettings{
+				Crossovers: []float64{200, 2000},
+				Bands: []dsp.CompressorSettings{
+					compressor, compressor, compressor},
+			}, format.Channels, rate)
+		case "gate":
+			processor = dsp.NewGate(dsp.GateSettings{
+				Threshold: -50, Range: -40, Attack: 1, Hold: 20,
+				Release: 50}, rate)
+		case "limit":
+			processor = dsp.NewLimiter(dsp.LimiterSettings{
+				Ceiling: -1, Lookahead: 5, Release: 50}, format.Channels, rate)
+		case "delay":
+			processor, err = dsp.NewDelay(dsp.DelaySettings{
+				Time: 250, Feedback: 0.4, Mix: 0.3}, format.Channels, rate)
+		case "chorus":
+			processor, err = dsp.NewChorus(
+				dsp.ModulationSettings{}, format.Channels, rate)
+		case "flanger":
+			processor, err = dsp.NewFlanger(
+				dsp.ModulationSettings{Feedback: 0.5}, format.Channels, rate)
+		default:
+			return nil, fmt.Errorf(EffectError, name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		processors = append(processors, processor)
+	}
+	return processors, nil
+}
+
+// writeWav saves buffer as a 16 bit WAV file at path.
+func writeWav(path string, buffer *audio.Buffer) error {
+	writer, err := wav.NewAtomicWavWriter(
+		path, wav.NewDefaultFmtChunk(), wav.WithBufferSize(64<<10))
+	if err != nil {
+		return err
+	}
+	if err := writer.WriteBuffer(buffer); err != nil {
+		writer.Abort()
+		return err
+	}
+	return writer.Finalize()
+}
+
+/*
+newJingle returns a format 0 MIDI file that plays an arpeggio of a C major
+chord at 120 beats per minute, one eighth note per key.
+*/
+func newJingle() *midi.Midi {
+	const division, eighth = 480, 240
+	track := midi.TrackChunk{Chunk: &midi.Chunk{}}
+	for _, key := range []byte{60, 64, 67, 72, 67, 64, 60} {
+		track.TrackEvents = append(track.TrackEvents,
+			midi.TrackEvent{Data: []byte{midi.NoteOnEvent, key, 100}},
+			midi.TrackEvent{DeltaTime: eighth,
+				Data: []byte{midi.NoteOffEvent, key, 0}})
+	}
+	track.TrackEvents = append(track.TrackEvents, midi.TrackEvent{
+		Data: []byte{midi.MetaEvent, midi.MetaEndOfTrack}})
+	return &midi.Midi{
+		HeaderChunk: &midi.HeaderChunk{Chunk: &midi.Chunk{},
+			Division: division},
+		TrackChunks: []midi.TrackChunk{track},
+	}
+}
+
+// report writes the length and true peak of the WAV file at path to out.
+func report(path string, out io.Writer) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader, err := wav.NewWavReader(file)
+	if err != nil {
+		return err
+	}
+	frames := reader.RemainingFrames()
+	peaks, err := analysis.MeasurePeaks(reader)
+	if err != nil {
+		return err
+	}
+	var peak float64
+	for _, value := range peaks.True {
+		peak = math.Max(peak, value)
+	}
+	_, err = fmt.Fprintf(out, "%s: %v frames, true peak %v\n",
+		filepath.Base(path), frames, audio.LinearToDecibel(peak))
+	return err
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+	effects := "compress,deess,multiband,gate,limit,delay,chorus,flanger"
+	assert.Nil(t, run(options{seconds: 0.5, frequency: 1000,
+		effects: effects, dir: dir}, &out))
+	for _, name := range []string{"tone.wav", "jingle.mid", "jingle.wav"} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		assert.Nil(t, err)
+	}
+	// Seven eighth notes at 120 beats per minute last 1.75 seconds, and the
+	// last one releases over another 50 ms.
+	assert.Regexp(t, "tone.wav: 22050 frames, true peak -?[0-9.]+ dB\n"+
+		"jingle.wav: 79380 frames, true peak -?[0-9.]+ dB\n", out.String())
+}
+
+func TestRunUnknownEffect(t *testing.T) {
+	err := run(options{seconds: 0.1, frequency: 440, effects: "reverb",
+		dir: t.TempDir()}, &bytes.Buffer{})
+	assert.NotEqual(t, "", regexp.MustCompile(
+		`unknown effect "reverb"`).FindString(err.Error()))
+}
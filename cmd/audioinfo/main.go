@@ -0,0 +1,181 @@
+/*
+audioinfo prints what the audio package knows about audio files: their format,
+duration and metadata, as reported by audio.Probe, and the layout of the chunks
+of RIFF WAV and MIDI files.
+
+Usage:
+
+	audioinfo [-json] file...
+
+With -json, a JSON array with one object per file is printed instead.
+*/
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/husafan/audio"
+	_ "github.com/husafan/audio/midi"
+	_ "github.com/husafan/audio/wav"
+)
+
+// report describes a single file.
+type report struct {
+	Path       string            `json:"path"`
+	Format     string            `json:"format"`
+	Codec      string            `json:"codec"`
+	SampleRate uint32            `json:"sample_rate,omitempty"`
+	Channels   int               `json:"channels,omitempty"`
+	BitDepth   int               `json:"bit_depth,omitempty"`
+	Duration   float64           `json:"duration"`
+	Metadata   map[string]string `json:"metadata"`
+	Chunks     []chunk           `json:"chunks,omitempty"`
+}
+
+// chunk is a chunk of a file, with the offset of its header.
+type chunk struct {
+	Id     string `json:"id"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+}
+
+func main() {
+	asJSON := flag.Bool("json", false, "print JSON instead of text")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: audioinfo [-json] file...")
+		os.Exit(2)
+	}
+	if err := run(flag.Args(), *asJSON, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run reports on the files at paths to out.
+func run(paths []string, asJSON bool, out io.Writer) error {
+	reports := make([]*report, len(paths))
+	for i, path := range paths {
+		var err error
+		if reports[i], err = inspect(path); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(reports)
+	}
+	for _, r := range reports {
+		if err := r.write(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inspect probes the file at path and reads its chunk layout.
+func inspect(path string) (*report, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := audio.Probe(file)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	r := &report{
+		Path:       path,
+		Format:     info.Format,
+		Codec:      info.Codec,
+		SampleRate: info.SampleRate,
+		Channels:   info.Channels,
+		BitDepth:   info.BitDepth,
+		Duration:   info.Duration.Seconds(),
+		Metadata:   info.Metadata,
+	}
+	magic := make([]byte, 4)
+	if _, err := file.ReadAt(magic, 0); err != nil {
+		return nil, err
+	}
+	switch string(magic) {
+	case "RIFF", "RF64":
+		// The RIFF header is followed by the WAVE form type.
+		r.Chunks, err = readChunks(file, 12, stat.Size(), binary.LittleEndian)
+	case "MThd":
+		r.Chunks, err = readChunks(file, 0, stat.Size(), binary.BigEndian)
+	}
+	return r, err
+}
+
+/*
+readChunks lists the chunks from offset to the end of a file of the given size,
+each headed by a 4 character ID and a 32 bit size in order. RIFF chunks of odd
+size are followed by a pad byte. A chunk that runs past the end of the file,
+such as the data chunk of an RF64 file, is listed up to the end and ends the
+list.
+*/
+func readChunks(reader io.ReaderAt, offset, size int64,
+	order binary.ByteOrder) ([]chunk, error) {
+	var chunks []chunk
+	header := make([]byte, 8)
+	for offset+8 <= size {
+		if _, err := reader.ReadAt(header, offset); err != nil {
+			return nil, err
+		}
+		c := chunk{Id: string(header[:4]), Offset: offset,
+			Size: int64(order.Uint32(header[4:]))}
+		if c.Size > size-offset-8 {
+			c.Size = size - offset - 8
+		}
+		chunks = append(chunks, c)
+		offset += 8 + c.Size
+		if order == binary.LittleEndian {
+			offset += c.Size % 2
+		}
+	}
+	return chunks, nil
+}
+
+// write prints the report as text.
+func (r *report) write(out io.Writer) error {
+	fmt.Fprintf(out, "%s\n", r.Path)
+	fmt.Fprintf(out, "  format:      %s (%s)\n", r.Format, r.Codec)
+	if r.SampleRate > 0 {
+		fmt.Fprintf(out, "  sample rate: %v Hz\n", r.SampleRate)
+		fmt.Fprintf(out, "  channels:    %v\n", r.Channels)
+		fmt.Fprintf(out, "  bit depth:   %v\n", r.BitDepth)
+	}
+	fmt.Fprintf(out, "  duration:    %.3fs\n", r.Duration)
+	if len(r.Metadata) > 0 {
+		fmt.Fprintf(out, "  metadata:\n")
+		names := make([]string, 0, len(r.Metadata))
+		for name := range r.Metadata {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(out, "    %s: %s\n", name, r.Metadata[name])
+		}
+	}
+	if len(r.Chunks) > 0 {
+		fmt.Fprintf(out, "  chunks:\n")
+		for _, c := range r.Chunks {
+			fmt.Fprintf(out, "    %q at byte %v, %v bytes\n",
+				c.Id, c.Offset, c.Size)
+		}
+	}
+	_, err := fmt.Fprintln(out)
+	return err
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/husafan/audio/midi"
+	"github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeFiles writes a WAV and a MIDI file to a temporary directory.
+func writeFiles(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	wavPath := filepath.Join(dir, "test.wav")
+	writer, err := wav.NewAtomicWavWriter(wavPath, nil,
+		wav.WithMetadata(wav.Metadata{wav.InfoTitle: "A title"}))
+	assert.Nil(t, err)
+	assert.Nil(t, writer.AddSample(wav.Sample{{1, 2}, {3, 4}}))
+	assert.Nil(t, writer.Finalize())
+
+	track := midi.TrackChunk{Chunk: &midi.Chunk{},
+		TrackEvents: []midi.TrackEvent{
+			{Data: []byte{midi.NoteOnEvent, 60, 100}},
+			{DeltaTime: 960, Data: []byte{midi.NoteOffEvent, 60, 0}},
+			{Data: []byte{midi.MetaEvent, midi.MetaEndOfTrack}},
+		}}
+	data, err := (&midi.Midi{
+		HeaderChunk: &midi.HeaderChunk{Chunk: &midi.Chunk{}, Division: 480},
+		TrackChunks: []midi.TrackChunk{track},
+	}).MarshalBinary()
+	assert.Nil(t, err)
+	midiPath := filepath.Join(dir, "test.mid")
+	assert.Nil(t, os.WriteFile(midiPath, data, 0644))
+	return wavPath, midiPath
+}
+
+func TestRun(t *testing.T) {
+	wavPath, midiPath := writeFiles(t)
+	var out bytes.Buffer
+	assert.Nil(t, run([]string{wavPath, midiPath}, false, &out))
+	assert.Equal(t, wavPath+`
+  format:      wav (pcm)
+  sample rate: 44100 Hz
+  channels:    2
+  bit depth:   16
+  duration:    0.000s
+  metadata:
+    title: A title
+  chunks:
+    "fmt " at byte 12, 16 bytes
+    "LIST" at byte 36, 20 bytes
+    "data" at byte 64, 4 bytes
+
+`+midiPath+`
+  format:      midi (smf)
+  duration:    1.000s
+  chunks:
+    "MThd" at byte 0, 6 bytes
+    "MTrk" at byte 14, 13 bytes
+
+`, out.String())
+}
+
+func TestRunJSON(t *testing.T) {
+	wavPath, midiPath := writeFiles(t)
+	var out bytes.Buffer
+	assert.Nil(t, run([]string{wavPath, midiPath}, true, &out))
+	var reports []report
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &reports))
+	assert.Equal(t, 2, len(reports))
+	assert.Equal(t, "wav", reports[0].Format)
+	assert.Equal(t, uint32(44100), reports[0].SampleRate)
+	assert.Equal(t, "A title", reports[0].Metadata["title"])
+	assert.Equal(t, 3, len(reports[0].Chunks))
+	assert.Equal(t, "midi", reports[1].Format)
+	assert.Equal(t, 1.0, reports[1].Duration)
+}
+
+func TestRunUnknownFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.txt")
+	assert.Nil(t, os.WriteFile(path, []byte("not audio"), 0644))
+	err := run([]string{path}, false, &bytes.Buffer{})
+	assert.Equal(t, path+": audio: unknown format", err.Error())
+}
@@ -0,0 +1,133 @@
+/*
+midi2wav renders standard MIDI files to WAV with the synth package, running
+them through the whole library from MIDI parsing to WAV writing.
+
+Usage:
+
+	midi2wav [-rate 44100] [-bits 16] [-float] [-channels 2] [-gain -12]
+		input.mid output.wav
+
+-rate and -channels choose the format of the output, mono or stereo, and -bits
+and -float its samples, 16 bit integers by default, which are clipped to full
+scale. -gain is the level in dB of a note at full velocity before the volume
+and expression of its channel. The default leaves room for chords, and can be
+raised for sparse files or lowered if dense ones clip. The output lasts until
+the end of the file's longest track and the release of its last notes.
+
+SF2 sound banks are not supported yet, as the library has no SoundFont
+support: notes are played by the synth package's own waveforms and drum kit.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/midi"
+	"github.com/husafan/audio/synth"
+	"github.com/husafan/audio/wav"
+)
+
+const (
+	BitsError     = "cannot write %v bit %s samples"
+	ChannelsError = "cannot render %v channels; use 1 or 2"
+	RateError     = "cannot render at a sample rate of %v Hz"
+)
+
+// options holds the command line flags.
+type options struct {
+	rate     uint
+	bits     int
+	float    bool
+	channels int
+	gain     float64
+}
+
+func main() {
+	var opts options
+	flag.UintVar(&opts.rate, "rate", 44100, "sample rate of the output in Hz")
+	flag.IntVar(&opts.bits, "bits", 0, "bits per sample of the output")
+	flag.BoolVar(&opts.float, "float", false, "write IEEE float samples")
+	flag.IntVar(&opts.channels, "channels", 2, "channels of the output")
+	flag.Float64Var(&opts.gain, "gain", float64(synth.DefaultSettings().Gain),
+		"level in dB of a note at full velocity")
+	flag.Parse()
+	if flag.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "usage: midi2wav [flags] input.mid output.wav")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	if err := render(flag.Arg(0), flag.Arg(1), opts); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// render plays the MIDI file at input and saves it to output as opts asks.
+func render(input, output string, opts options) error {
+	fmtChunk, err := outputFormat(opts)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+	m, err := midi.ParseMidi(data, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", input, err)
+	}
+	settings := synth.DefaultSettings()
+	settings.Gain = audio.Decibel(opts.gain)
+	buffer, err := synth.Render(m, synth.RenderOptions{
+		Settings: settings, Format: fmtChunk.Format()})
+	if err != nil {
+		return fmt.Errorf("%s: %w", input, err)
+	}
+	writer, err := wav.NewAtomicWavWriter(
+		output, fmtChunk, wav.WithBufferSize(64<<10))
+	if err != nil {
+		return err
+	}
+	if err := writer.WriteBuffer(buffer); err != nil {
+		writer.Abort()
+		return err
+	}
+	return writer.Finalize()
+}
+
+// outputFormat returns the fmt chunk of the output described by opts.
+func outputFormat(opts options) (*wav.FmtChunk, error) {
+	if opts.rate == 0 {
+		return nil, fmt.Errorf(RateError, opts.rate)
+	}
+	if opts.channels != 1 && opts.channels != 2 {
+		return nil, fmt.Errorf(ChannelsError, opts.channels)
+	}
+	f := wav.NewDefaultFmtChunk()
+	f.SampleRate = uint32(opts.rate)
+	f.NumChannels = uint16(opts.channels)
+	if opts.float {
+		f.AudioFormat = wav.FormatIEEEFloat
+		f.BitsPerSample = 32
+	}
+	if opts.bits > 0 {
+		f.BitsPerSample = uint16(opts.bits)
+	}
+	switch bits := f.BitsPerSample; {
+	case f.AudioFormat == wav.FormatPCM && bits%8 == 0 && bits >= 8 &&
+		bits <= 32:
+	case f.AudioFormat == wav.FormatIEEEFloat && (bits == 32 || bits == 64):
+	default:
+		kind := "integer"
+		if f.AudioFormat == wav.FormatIEEEFloat {
+			kind = "float"
+		}
+		return nil, fmt.Errorf(BitsError, bits, kind)
+	}
+	f.BlockAlign = f.NumChannels * f.BitsPerSample / 8
+	f.ByteRate = f.SampleRate * uint32(f.BlockAlign)
+	return f, nil
+}
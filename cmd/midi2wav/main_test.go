@@ -0,0 +1,112 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/midi"
+	"github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeScale writes four quarter notes at 120 beats per minute to a MIDI file.
+func writeScale(t *testing.T) string {
+	track := midi.TrackChunk{Chunk: &midi.Chunk{}}
+	for _, key := range []byte{60, 62, 64, 65} {
+		track.TrackEvents = append(track.TrackEvents,
+			midi.TrackEvent{Data: []byte{midi.NoteOnEvent, key, 127}},
+			midi.TrackEvent{DeltaTime: 480,
+				Data: []byte{midi.NoteOffEvent, key, 0}})
+	}
+	track.TrackEvents = append(track.TrackEvents, midi.TrackEvent{
+		Data: []byte{midi.MetaEvent, midi.MetaEndOfTrack}})
+	data, err := (&midi.Midi{
+		HeaderChunk: &midi.HeaderChunk{Chunk: &midi.Chunk{}, Division: 480},
+		TrackChunks: []midi.TrackChunk{track},
+	}).MarshalBinary()
+	assert.Nil(t, err)
+	path := filepath.Join(t.TempDir(), "scale.mid")
+	assert.Nil(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+// readOutput returns the fmt chunk and samples of the file at path.
+func readOutput(t *testing.T, path string) (*wav.FmtChunk, *audio.Buffer) {
+	file, err := os.Open(path)
+	assert.Nil(t, err)
+	defer file.Close()
+	reader, err := wav.NewWavReader(file)
+	assert.Nil(t, err)
+	buffer, err := reader.ReadAll()
+	assert.Nil(t, err)
+	return reader.Fmt, buffer
+}
+
+// peak returns the largest magnitude in buffer.
+func peak(buffer *audio.Buffer) float64 {
+	var peak float64
+	for _, value := range buffer.Data {
+		peak = math.Max(peak, math.Abs(value))
+	}
+	return peak
+}
+
+func TestRender(t *testing.T) {
+	input := writeScale(t)
+	output := filepath.Join(t.TempDir(), "scale.wav")
+	assert.Nil(t, render(input, output, options{
+		rate: 44100, channels: 2, gain: -12}))
+	f, buffer := readOutput(t, output)
+	assert.Equal(t, wav.FormatPCM, f.AudioFormat)
+	assert.Equal(t, uint16(16), f.BitsPerSample)
+	assert.Equal(t, audio.Format{SampleRate: 44100, Channels: 2},
+		buffer.Format)
+	// Two seconds of notes, and the release of the last one over 50 ms.
+	assert.Equal(t, 90405, buffer.Frames())
+	loud := peak(buffer)
+	assert.NotEqual(t, 0.0, loud)
+
+	// Gain, rate, channels and the sample format all come from the flags.
+	assert.Nil(t, render(input, output, options{
+		rate: 8000, bits: 64, float: true, channels: 1, gain: -18}))
+	f, buffer = readOutput(t, output)
+	assert.Equal(t, wav.FormatIEEEFloat, f.AudioFormat)
+	assert.Equal(t, uint16(64), f.BitsPerSample)
+	assert.Equal(t, audio.Format{SampleRate: 8000, Channels: 1},
+		buffer.Format)
+	assert.InDelta(t, 16400, buffer.Frames(), 1)
+	// Mono is also spared the 3 dB a centred pan takes from each side.
+	assert.InDelta(t, audio.Decibel(-6).Linear()*math.Sqrt2,
+		peak(buffer)/loud, 0.05)
+}
+
+func TestRenderErrors(t *testing.T) {
+	input := writeScale(t)
+	output := filepath.Join(t.TempDir(), "scale.wav")
+	for _, test := range []struct {
+		opts  options
+		error string
+	}{
+		{options{rate: 0, channels: 2}, "sample rate of 0 Hz"},
+		{options{rate: 44100, channels: 6}, "cannot render 6 channels"},
+		{options{rate: 44100, channels: 2, bits: 12}, "12 bit integer"},
+		{options{rate: 44100, channels: 2, bits: 16, float: true},
+			"16 bit float"},
+	} {
+		err := render(input, output, test.opts)
+		assert.NotEqual(t, "", regexp.MustCompile(
+			test.error).FindString(err.Error()))
+	}
+
+	// A file that is not MIDI is named in the error, and nothing is written.
+	assert.Nil(t, os.WriteFile(input, []byte("not MIDI"), 0644))
+	err := render(input, output, options{rate: 44100, channels: 2})
+	assert.NotEqual(t, "", regexp.MustCompile(
+		regexp.QuoteMeta(input)).FindString(err.Error()))
+	_, err = os.Stat(output)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,60 @@
+/*
+midijson converts standard MIDI files to JSON and back, so they can be diffed,
+edited by hand and regenerated. The format is described by midi.MarshalJSON.
+
+Usage:
+
+	midijson input.mid output.json
+	midijson input.json output.mid
+
+The direction is chosen by the extension of the input: .json files are
+converted to MIDI, and anything else to JSON.
+*/
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/husafan/audio/midi"
+)
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Fprintln(os.Stderr, "usage: midijson input output")
+		os.Exit(2)
+	}
+	if err := convert(os.Args[1], os.Args[2]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// convert reads the file at input and writes it to output in the other format.
+func convert(input, output string) error {
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+	m := new(midi.Midi)
+	if strings.ToLower(filepath.Ext(input)) == ".json" {
+		if err := json.Unmarshal(data, m); err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+		data, err = m.MarshalBinary()
+	} else {
+		if m, err = midi.ParseMidi(data, nil); err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+		if data, err = json.MarshalIndent(m, "", "  "); err == nil {
+			data = append(data, '\n')
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(output, data, 0644)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvert(t *testing.T) {
+	dir := t.TempDir()
+	track := midi.TrackChunk{Chunk: &midi.Chunk{},
+		TrackEvents: []midi.TrackEvent{
+			{Data: []byte{midi.NoteOnEvent, 60, 100}},
+			{DeltaTime: 96, Data: []byte{midi.NoteOffEvent, 60, 0}},
+			{Data: []byte{midi.MetaEvent, midi.MetaEndOfTrack}},
+		}}
+	original, err := (&midi.Midi{
+		HeaderChunk: &midi.HeaderChunk{Chunk: &midi.Chunk{}, Division: 96},
+		TrackChunks: []midi.TrackChunk{track},
+	}).MarshalBinary()
+	assert.Nil(t, err)
+	input := filepath.Join(dir, "input.mid")
+	assert.Nil(t, os.WriteFile(input, original, 0644))
+
+	jsonPath := filepath.Join(dir, "output.json")
+	assert.Nil(t, convert(input, jsonPath))
+	text, err := os.ReadFile(jsonPath)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "",
+		regexp.MustCompile(`"type": "note_on"`).FindString(string(text)))
+
+	output := filepath.Join(dir, "output.mid")
+	assert.Nil(t, convert(jsonPath, output))
+	data, err := os.ReadFile(output)
+	assert.Nil(t, err)
+	assert.Equal(t, original, data)
+}
+
+func TestConvertInvalidJSON(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "input.json")
+	assert.Nil(t, os.WriteFile(input,
+		[]byte(`{"tracks": [[{"type": "wobble"}]]}`), 0644))
+	err := convert(input, filepath.Join(t.TempDir(), "output.mid"))
+	assert.NotEqual(t, "", regexp.MustCompile(
+		`input.json: track 0, event 0: unknown event type "wobble"`).
+		FindString(err.Error()))
+}
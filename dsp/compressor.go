@@ -0,0 +1,107 @@
+package dsp
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/husafan/audio"
+)
+
+const (
+	RatioError = "compressor ratio of %v must be at least 1"
+
+	// silenceLevel is the level in dBFS given to digital silence.
+	silenceLevel = -120
+)
+
+/*
+CompressorSettings configures a Compressor. Threshold is the level in dBFS above
+which the gain is reduced, and Ratio the number of decibels the input must rise
+for the output to rise by one. Attack and Release are the time constants, in
+milliseconds, with which the gain reduction follows the level, and Makeup is a
+gain in decibels applied afterwards.
+*/
+type CompressorSettings struct {
+	Threshold audio.Decibel `json:"threshold"`
+	Ratio     float64       `json:"ratio"`
+	Attack    float64       `json:"attack"`
+	Release   float64       `json:"release"`
+	Makeup    audio.Decibel `json:"makeup"`
+}
+
+/*
+Compressor is a feed forward compressor. The channels are linked, so the gain
+follows the loudest channel and the stereo image does not shift.
+*/
+type Compressor struct {
+	settings      CompressorSettings
+	attack        float64
+	release       float64
+	gainReduction audio.Decibel
+}
+
+/*
+NewCompressor returns a Compressor for audio at the given sample rate. A non-nil
+error is returned if the ratio is less than 1.
+*/
+func NewCompressor(
+	settings CompressorSettings, sampleRate float64) (*Compressor, error) {
+	if settings.Ratio < 1 {
+		return nil, fmt.Errorf(RatioError, settings.Ratio)
+	}
+	return &Compressor{
+		settings: settings,
+		attack:   smoothing(settings.Attack, sampleRate),
+		release:  smoothing(settings.Release, sampleRate),
+	}, nil
+}
+
+/*
+smoothing returns the coefficient of a one pole filter with a time constant of
+milliseconds at the given sample rate.
+*/
+func smoothing(milliseconds, sampleRate float64) float64 {
+	if milliseconds <= 0 {
+		return 0
+	}
+	return math.Exp(-1000 / (milliseconds * sampleRate))
+}
+
+// Process compresses a frame.
+func (c *Compressor) Process(frame []float64) {
+	var level float64
+	for _, value := range frame {
+		level = math.Max(level, math.Abs(value))
+	}
+	gain := c.gain(level)
+	for i := range frame {
+		frame[i] *= gain
+	}
+}
+
+/*
+gain updates the gain reduction for the given linear detector level and returns
+the linear gain to apply.
+*/
+func (c *Compressor) gain(level float64) float64 {
+	decibels := audio.Decibel(silenceLevel)
+	if level > 0 && audio.LinearToDecibel(level) > decibels {
+		decibels = audio.LinearToDecibel(level)
+	}
+	var target audio.Decibel
+	if over := decibels - c.settings.Threshold; over > 0 {
+		target = audio.Decibel(float64(over) * (1 - 1/c.settings.Ratio))
+	}
+	coefficient := c.release
+	if target > c.gainReduction {
+		coefficient = c.attack
+	}
+	c.gainReduction = audio.Decibel(coefficient*float64(c.gainReduction) +
+		(1-coefficient)*float64(target))
+	return (c.settings.Makeup - c.gainReduction).Linear()
+}
+
+// GainReduction returns the current gain reduction.
+func (c *Compressor) GainReduction() audio.Decibel {
+	return c.gainReduction
+}
@@ -0,0 +1,55 @@
+package dsp_test
+
+import (
+	"math"
+	"regexp"
+	"testing"
+
+	"github.com/husafan/audio"
+	. "github.com/husafan/audio/dsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressor(t *testing.T) {
+	compressor, err := NewCompressor(
+		CompressorSettings{Threshold: -20, Ratio: 4}, 48000)
+	assert.Nil(t, err)
+	// Without attack or release the gain follows the level immediately.
+	frame := []float64{1, -0.5}
+	compressor.Process(frame)
+	assert.InDelta(t, 15, float64(compressor.GainReduction()), 1e-9)
+	assert.InDelta(t, audio.Decibel(-15).Linear(), frame[0], 1e-9)
+	assert.InDelta(t, -audio.Decibel(-15).Linear()/2, frame[1], 1e-9)
+
+	frame = []float64{0.05, 0.05}
+	compressor.Process(frame)
+	assert.Equal(t, audio.Decibel(0), compressor.GainReduction())
+	assert.Equal(t, []float64{0.05, 0.05}, frame)
+}
+
+func TestCompressorTimeConstants(t *testing.T) {
+	compressor, err := NewCompressor(CompressorSettings{
+		Threshold: -20, Ratio: 2, Attack: 1, Release: 100, Makeup: 6}, 1000)
+	assert.Nil(t, err)
+	// After one time constant the gain reduction reaches 1 - 1/e of its target.
+	compressor.Process([]float64{1})
+	assert.InDelta(t, 10*(1-1/math.E), float64(compressor.GainReduction()), 1e-9)
+	for i := 0; i < 20; i++ {
+		compressor.Process([]float64{1})
+	}
+	assert.InDelta(t, 10, float64(compressor.GainReduction()), 1e-6)
+	frame := []float64{1}
+	compressor.Process(frame)
+	assert.InDelta(t, audio.Decibel(-4).Linear(), frame[0], 1e-6)
+
+	// Release is much slower.
+	compressor.Process([]float64{0})
+	assert.InDelta(t, 10*math.Exp(-0.01), float64(compressor.GainReduction()), 1e-6)
+}
+
+func TestCompressorRatio(t *testing.T) {
+	_, err := NewCompressor(CompressorSettings{Ratio: 0.5}, 48000)
+	assert.NotNil(t, err)
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"ratio of 0.5 must be at least 1").FindString(err.Error()))
+}
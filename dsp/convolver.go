@@ -0,0 +1,169 @@
+package dsp
+
+import (
+	"fmt"
+
+	"github.com/husafan/audio"
+)
+
+const (
+	ImpulseChannelsError = "impulse response has %v channels; expected 1 or %v"
+
+	// defaultBlockSize is the partition size of a Convolver when none is given.
+	defaultBlockSize = 512
+)
+
+/*
+ConvolverSettings configures a Convolver. Mix is the proportion of convolved
+signal in the output, with 0 meaning 1 so that cabinet simulation, which wants
+no dry signal, needs no setting. BlockSize is the number of frames in each
+partition of the impulse response and sets the latency. It must be a power of
+two and defaults to 512.
+*/
+type ConvolverSettings struct {
+	Mix       float64 `json:"mix"`
+	BlockSize int     `json:"block_size"`
+}
+
+/*
+Convolver convolves audio with an impulse response, such as a recording of a
+room for reverb or of a speaker cabinet, using uniformly partitioned overlap-add
+FFT convolution. The impulse response is split into blocks whose spectra are
+multiplied with those of recent input blocks, so long responses cost little
+more than short ones and the latency is a single block, whatever the length of
+the response. Process returns frames Latency frames after it is given them.
+*/
+type Convolver struct {
+	size     int
+	mix      float64
+	channels []convolverChannel
+	position int
+}
+
+// convolverChannel holds the state of a single channel of a Convolver.
+type convolverChannel struct {
+	partitions [][]complex128
+	history    [][]complex128
+	newest     int
+	input      []float64
+	dry        []float64
+	output     []float64
+	overlap    []float64
+	sum        []complex128
+}
+
+/*
+NewConvolver returns a Convolver for the given channels with the impulse
+response in impulse, which is applied as it is, without normalisation. A mono
+impulse response is applied to every channel, while one with a channel per
+channel of audio is applied to each in turn. The sample rate of impulse should
+match that of the audio. A non-nil error is returned for other channel counts
+or a BlockSize that is not a power of two.
+*/
+func NewConvolver(impulse *audio.Buffer,
+	channels int, settings ConvolverSettings) (*Convolver, error) {
+	if impulse.Format.Channels != 1 && impulse.Format.Channels != channels {
+		return nil, fmt.Errorf(
+			ImpulseChannelsError, impulse.Format.Channels, channels)
+	}
+	size := settings.BlockSize
+	if size == 0 {
+		size = defaultBlockSize
+	}
+	if size < 0 || size&(size-1) != 0 {
+		return nil, fmt.Errorf(FFTSizeError, size)
+	}
+	c := &Convolver{
+		size:     size,
+		mix:      settings.Mix,
+		channels: make([]convolverChannel, channels),
+	}
+	if c.mix == 0 {
+		c.mix = 1
+	}
+	count := (impulse.Frames() + size - 1) / size
+	if count == 0 {
+		count = 1
+	}
+	for channel := range c.channels {
+		source := channel
+		if impulse.Format.Channels == 1 {
+			source = 0
+		}
+		state := &c.channels[channel]
+		state.partitions = make([][]complex128, count)
+		state.history = make([][]complex128, count)
+		for k := range state.partitions {
+			partition := make([]complex128, 2*size)
+			for i := 0; i < size && k*size+i < impulse.Frames(); i++ {
+				partition[i] = complex(impulse.Frame(k*size + i)[source], 0)
+			}
+			FFT(partition)
+			state.partitions[k] = partition
+			state.history[k] = make([]complex128, 2*size)
+		}
+		state.input = make([]float64, size)
+		state.dry = make([]float64, size)
+		state.output = make([]float64, size)
+		state.overlap = make([]float64, size)
+		state.sum = make([]complex128, 2*size)
+	}
+	return c, nil
+}
+
+// Latency returns the number of frames by which the Convolver delays audio.
+func (c *Convolver) Latency() int {
+	return c.size
+}
+
+/*
+Process convolves a frame, replacing it with the frame Latency frames earlier.
+*/
+func (c *Convolver) Process(frame []float64) {
+	for channel := range c.channels {
+		state := &c.channels[channel]
+		value := frame[channel]
+		frame[channel] = c.mix*state.output[c.position] +
+			(1-c.mix)*state.dry[c.position]
+		state.input[c.position] = value
+	}
+	if c.position++; c.position < c.size {
+		return
+	}
+	c.position = 0
+	for channel := range c.channels {
+		c.channels[channel].convolve()
+	}
+}
+
+/*
+convolve adds the spectrum of the input block to the channel's history and
+computes the next output block from the history and the impulse partitions.
+*/
+func (s *convolverChannel) convolve() {
+	s.newest = (s.newest + len(s.history) - 1) % len(s.history)
+	block := s.history[s.newest]
+	for i := range block {
+		block[i] = 0
+	}
+	for i, value := range s.input {
+		block[i] = complex(value, 0)
+	}
+	FFT(block)
+
+	for i := range s.sum {
+		s.sum[i] = 0
+	}
+	for k, partition := range s.partitions {
+		history := s.history[(s.newest+k)%len(s.history)]
+		for i := range s.sum {
+			s.sum[i] += history[i] * partition[i]
+		}
+	}
+	InverseFFT(s.sum)
+	for i := range s.output {
+		s.output[i] = real(s.sum[i]) + s.overlap[i]
+		s.overlap[i] = real(s.sum[len(s.output)+i])
+	}
+	s.input, s.dry = s.dry, s.input
+}
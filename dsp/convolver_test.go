@@ -0,0 +1,89 @@
+package dsp_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/husafan/audio"
+	. "github.com/husafan/audio/dsp"
+	"github.com/stretchr/testify/assert"
+)
+
+// directConvolution returns input convolved with impulse, truncated to input.
+func directConvolution(input, impulse []float64) []float64 {
+	output := make([]float64, len(input))
+	for i := range output {
+		for j, value := range impulse {
+			if i >= j {
+				output[i] += input[i-j] * value
+			}
+		}
+	}
+	return output
+}
+
+func TestConvolver(t *testing.T) {
+	impulse := make([]float64, 37)
+	for i := range impulse {
+		impulse[i] = 1 / float64(i+1)
+	}
+	input := make([]float64, 200)
+	for i := range input {
+		input[i] = float64(i%7)/7 - 0.4
+	}
+	convolver, err := NewConvolver(&audio.Buffer{
+		Format: audio.Format{SampleRate: 1000, Channels: 1},
+		Data:   impulse,
+	}, 2, ConvolverSettings{BlockSize: 8})
+	assert.Nil(t, err)
+	assert.Equal(t, 8, convolver.Latency())
+
+	// A mono impulse response applies to both channels.
+	expected := directConvolution(input, impulse)
+	for i, value := range input {
+		frame := []float64{value, -value}
+		convolver.Process(frame)
+		if i < 8 {
+			assert.Equal(t, []float64{0, 0}, frame)
+			continue
+		}
+		assert.InDelta(t, expected[i-8], frame[0], 1e-9)
+		assert.InDelta(t, -expected[i-8], frame[1], 1e-9)
+	}
+}
+
+func TestConvolverStereoMix(t *testing.T) {
+	impulse := &audio.Buffer{
+		Format: audio.Format{SampleRate: 1000, Channels: 2},
+		Data:   []float64{0, 1, 0.5, 0, 0, 0.25},
+	}
+	convolver, err := NewConvolver(
+		impulse, 2, ConvolverSettings{Mix: 0.5, BlockSize: 2})
+	assert.Nil(t, err)
+	var left, right []float64
+	for _, value := range []float64{1, 0, 0, 0, 0, 0, 0, 0} {
+		frame := []float64{value, value}
+		convolver.Process(frame)
+		left = append(left, frame[0])
+		right = append(right, frame[1])
+	}
+	// Each channel is half dry and half convolved with its own response.
+	assert.InDeltaSlice(t, []float64{0, 0, 0.5, 0.25, 0, 0, 0, 0}, left, 1e-9)
+	assert.InDeltaSlice(t, []float64{0, 0, 1, 0, 0.125, 0, 0, 0}, right, 1e-9)
+}
+
+func TestConvolverSettings(t *testing.T) {
+	mono := audio.NewBuffer(audio.Format{SampleRate: 1000, Channels: 1}, 4)
+	convolver, err := NewConvolver(mono, 1, ConvolverSettings{})
+	assert.Nil(t, err)
+	assert.Equal(t, 512, convolver.Latency())
+
+	_, err = NewConvolver(mono, 1, ConvolverSettings{BlockSize: 100})
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"power of two; found 100").FindString(err.Error()))
+
+	stereo := audio.NewBuffer(audio.Format{SampleRate: 1000, Channels: 2}, 4)
+	_, err = NewConvolver(stereo, 3, ConvolverSettings{})
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"has 2 channels; expected 1 or 3").FindString(err.Error()))
+}
@@ -0,0 +1,86 @@
+package dsp
+
+import (
+	"fmt"
+	"math"
+)
+
+const CrossoverError = "crossover frequencies must rise from above 0 to below %v Hz; found %v"
+
+/*
+Crossover splits audio into frequency bands with fourth order Linkwitz-Riley
+filters, each made of two Butterworth filters in series. The lower bands are
+passed through all pass filters matching the phase shift of the higher
+crossovers, so the bands sum back to a signal with a flat frequency response.
+*/
+type Crossover struct {
+	// lows and highs hold the two sections of each crossover for each
+	// channel, and phases the all pass filters of each band.
+	lows   [][][2]Biquad
+	highs  [][][2]Biquad
+	phases [][][]Biquad
+}
+
+/*
+NewCrossover returns a Crossover that splits the given channels at each of the
+given frequencies, which must rise and lie below the Nyquist frequency. There is
+one more band than there are frequencies.
+*/
+func NewCrossover(
+	frequencies []float64, channels int, sampleRate float64) (*Crossover, error) {
+	previous := 0.0
+	for _, frequency := range frequencies {
+		if frequency <= previous || frequency >= sampleRate/2 {
+			return nil, fmt.Errorf(CrossoverError, sampleRate/2, frequencies)
+		}
+		previous = frequency
+	}
+	c := &Crossover{
+		lows:   make([][][2]Biquad, channels),
+		highs:  make([][][2]Biquad, channels),
+		phases: make([][][]Biquad, channels),
+	}
+	q := 1 / math.Sqrt2
+	for channel := 0; channel < channels; channel++ {
+		c.phases[channel] = make([][]Biquad, len(frequencies))
+		for band, frequency := range frequencies {
+			low := lowPass(frequency, q, sampleRate)
+			high := highPass(frequency, q, sampleRate)
+			c.lows[channel] = append(c.lows[channel], [2]Biquad{low, low})
+			c.highs[channel] = append(c.highs[channel], [2]Biquad{high, high})
+			for _, higher := range frequencies[band+1:] {
+				c.phases[channel][band] = append(c.phases[channel][band],
+					allPass(higher, q, sampleRate))
+			}
+		}
+	}
+	return c, nil
+}
+
+// Bands returns the number of bands the Crossover splits audio into.
+func (c *Crossover) Bands() int {
+	if len(c.lows) == 0 {
+		return 0
+	}
+	return len(c.lows[0]) + 1
+}
+
+/*
+Split splits a frame into bands, from the lowest to the highest. Each entry of
+bands must hold a value for each channel.
+*/
+func (c *Crossover) Split(frame []float64, bands [][]float64) {
+	for channel := range c.lows {
+		rest := frame[channel]
+		for band := range c.lows[channel] {
+			low, high := &c.lows[channel][band], &c.highs[channel][band]
+			value := low[1].Filter(low[0].Filter(rest))
+			for i := range c.phases[channel][band] {
+				value = c.phases[channel][band][i].Filter(value)
+			}
+			bands[band][channel] = value
+			rest = high[1].Filter(high[0].Filter(rest))
+		}
+		bands[len(c.lows[channel])][channel] = rest
+	}
+}
@@ -0,0 +1,74 @@
+package dsp_test
+
+import (
+	"math"
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/dsp"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+bandLevels splits a one second sine of the given frequency at 48 kHz and returns
+the amplitude of each band, and of the bands summed, over its last half. The
+amplitudes are measured from the RMS level, as a sampled sine may never reach its
+peak.
+*/
+func bandLevels(t *testing.T, crossover *Crossover, frequency float64) ([]float64, float64) {
+	bands := make([][]float64, crossover.Bands())
+	for i := range bands {
+		bands[i] = make([]float64, 1)
+	}
+	levels := make([]float64, len(bands))
+	var sum float64
+	for i := 0; i < 48000; i++ {
+		crossover.Split([]float64{
+			math.Sin(2 * math.Pi * frequency * float64(i) / 48000)}, bands)
+		if i < 24000 {
+			continue
+		}
+		var total float64
+		for band := range bands {
+			levels[band] += bands[band][0] * bands[band][0]
+			total += bands[band][0]
+		}
+		sum += total * total
+	}
+	for band := range levels {
+		levels[band] = math.Sqrt(levels[band] / 12000)
+	}
+	return levels, math.Sqrt(sum / 12000)
+}
+
+func TestCrossover(t *testing.T) {
+	for _, frequency := range []float64{50, 500, 1000, 4000, 10000} {
+		crossover, err := NewCrossover([]float64{200, 2000, 8000}, 1, 48000)
+		assert.Nil(t, err)
+		assert.Equal(t, 4, crossover.Bands())
+		_, sum := bandLevels(t, crossover, frequency)
+		assert.InDelta(t, 1, sum, 0.01)
+	}
+
+	crossover, err := NewCrossover([]float64{200, 2000}, 1, 48000)
+	assert.Nil(t, err)
+	levels, _ := bandLevels(t, crossover, 50)
+	assert.True(t, levels[0] > 0.99)
+	assert.True(t, levels[1] < 0.1)
+	assert.True(t, levels[2] < 0.01)
+	levels, _ = bandLevels(t, crossover, 200)
+	assert.InDelta(t, 0.5, levels[0], 0.01)
+	assert.InDelta(t, 0.5, levels[1], 0.01)
+}
+
+func TestCrossoverErrors(t *testing.T) {
+	for _, frequencies := range [][]float64{
+		{2000, 200}, {0, 200}, {200, 24000},
+	} {
+		_, err := NewCrossover(frequencies, 2, 48000)
+		assert.NotNil(t, err)
+		assert.NotEqual(t, "", regexp.MustCompile(
+			"must rise from above 0 to below 24000 Hz").
+			FindString(err.Error()))
+	}
+}
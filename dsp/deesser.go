@@ -0,0 +1,112 @@
+package dsp
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/husafan/audio"
+)
+
+const (
+	FrequencyError = "frequency of %v Hz must be above 0 and below %v Hz"
+
+	// defaultQ is the Q of the detection band when none is given.
+	defaultQ = 1
+)
+
+/*
+DeEsserSettings configures a DeEsser. Frequency is the centre of the detection
+band in Hz, typically between 4 and 10 kHz for sibilance, and Q its width, with
+0 meaning 1. The remaining fields are those of CompressorSettings, applied to
+the level in the detection band. When Split is set, only the detection band is
+turned down, leaving the rest of the voice untouched. Otherwise the whole signal
+is.
+*/
+type DeEsserSettings struct {
+	Frequency float64       `json:"frequency"`
+	Q         float64       `json:"q"`
+	Threshold audio.Decibel `json:"threshold"`
+	Ratio     float64       `json:"ratio"`
+	Attack    float64       `json:"attack"`
+	Release   float64       `json:"release"`
+	Split     bool          `json:"split"`
+}
+
+/*
+DeEsser is a compressor that only responds to a band of frequencies, to tame
+harsh "s" and "sh" sounds in voice recordings. Like Compressor, its channels are
+linked.
+*/
+type DeEsser struct {
+	compressor *Compressor
+	filters    []Biquad
+	band       []float64
+	split      bool
+}
+
+/*
+NewDeEsser returns a DeEsser for the given channels at the given sample rate. A
+non-nil error is returned if the frequency is not below the Nyquist frequency or
+the ratio is less than 1.
+*/
+func NewDeEsser(
+	settings DeEsserSettings, channels int, sampleRate float64) (*DeEsser, error) {
+	if settings.Frequency <= 0 || settings.Frequency >= sampleRate/2 {
+		return nil, fmt.Errorf(FrequencyError, settings.Frequency, sampleRate/2)
+	}
+	compressor, err := NewCompressor(CompressorSettings{
+		Threshold: settings.Threshold,
+		Ratio:     settings.Ratio,
+		Attack:    settings.Attack,
+		Release:   settings.Release,
+	}, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+	q := settings.Q
+	if q <= 0 {
+		q = defaultQ
+	}
+	d := &DeEsser{
+		compressor: compressor,
+		filters:    make([]Biquad, channels),
+		band:       make([]float64, channels),
+		split:      settings.Split,
+	}
+	for channel := range d.filters {
+		d.filters[channel] = bandPass(settings.Frequency, q, sampleRate)
+	}
+	return d, nil
+}
+
+/*
+bandPass returns a second order band pass filter centred on frequency Hz with a
+gain of 1 at its centre. Subtracting its output from its input leaves exactly
+the matching notch filter.
+*/
+func bandPass(frequency, q, sampleRate float64) Biquad {
+	cos, alpha := cookbook(frequency, q, sampleRate)
+	return NewBiquad(alpha, 0, -alpha, 1+alpha, -2*cos, 1-alpha)
+}
+
+// Process de-esses a frame.
+func (d *DeEsser) Process(frame []float64) {
+	var level float64
+	for channel := range d.filters {
+		d.band[channel] = d.filters[channel].Filter(frame[channel])
+		level = math.Max(level, math.Abs(d.band[channel]))
+	}
+	gain := d.compressor.gain(level)
+	for channel := range d.filters {
+		if d.split {
+			frame[channel] += d.band[channel] * (gain - 1)
+		} else {
+			frame[channel] *= gain
+		}
+	}
+}
+
+// GainReduction returns the current gain reduction.
+func (d *DeEsser) GainReduction() audio.Decibel {
+	return d.compressor.GainReduction()
+}
@@ -0,0 +1,65 @@
+package dsp_test
+
+import (
+	"math"
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/dsp"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+deEss passes a one second sine of the given frequency at 48 kHz through
+processor and returns its amplitude over the last half, measured from its RMS
+level.
+*/
+func deEss(processor Processor, frequency float64) float64 {
+	var sum float64
+	for i := 0; i < 48000; i++ {
+		frame := []float64{0.5 * math.Sin(2*math.Pi*frequency*float64(i)/48000)}
+		processor.Process(frame)
+		if i >= 24000 {
+			sum += frame[0] * frame[0]
+		}
+	}
+	return math.Sqrt(sum / 12000)
+}
+
+func TestDeEsser(t *testing.T) {
+	settings := DeEsserSettings{
+		Frequency: 6000, Q: 2, Threshold: -30, Ratio: 10, Attack: 1, Release: 50}
+	deEsser, err := NewDeEsser(settings, 1, 48000)
+	assert.Nil(t, err)
+	assert.True(t, deEss(deEsser, 6000) < 0.1)
+	assert.True(t, deEsser.GainReduction() > 20)
+
+	// Low frequencies do not trigger it.
+	deEsser, err = NewDeEsser(settings, 1, 48000)
+	assert.Nil(t, err)
+	assert.InDelta(t, 0.5, deEss(deEsser, 200), 0.01)
+	assert.True(t, deEsser.GainReduction() < 1)
+}
+
+func TestDeEsserSplit(t *testing.T) {
+	settings := DeEsserSettings{
+		Frequency: 6000, Threshold: -60, Ratio: 100, Split: true}
+	deEsser, err := NewDeEsser(settings, 1, 48000)
+	assert.Nil(t, err)
+	// Even while it is turning sibilance right down, the rest of the signal
+	// passes.
+	assert.True(t, deEss(deEsser, 6000) < 0.05)
+	assert.True(t, deEsser.GainReduction() > 20)
+	assert.InDelta(t, 0.5, deEss(deEsser, 100), 0.01)
+}
+
+func TestDeEsserErrors(t *testing.T) {
+	_, err := NewDeEsser(DeEsserSettings{Frequency: 30000, Ratio: 2}, 1, 48000)
+	assert.NotNil(t, err)
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"frequency of 30000 Hz must be above 0 and below 24000 Hz").
+		FindString(err.Error()))
+
+	_, err = NewDeEsser(DeEsserSettings{Frequency: 6000}, 1, 48000)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,210 @@
+package dsp
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	DelayTimeError = "delay time of %v ms must not be negative"
+	FeedbackError  = "feedback of %v must be between -1 and 1"
+)
+
+/*
+delayLine is a circular buffer of past values that can be read at a fractional
+number of frames ago, with linear interpolation between neighbouring values.
+*/
+type delayLine struct {
+	values   []float64
+	position int
+}
+
+// newDelayLine returns a delayLine that can be read up to frames frames ago.
+func newDelayLine(frames float64) delayLine {
+	return delayLine{values: make([]float64, int(math.Ceil(frames))+2)}
+}
+
+/*
+read returns the value written delay frames before the next write, so a delay
+of 1 is the last value written. Delays are clamped to the length of the line.
+*/
+func (d *delayLine) read(delay float64) float64 {
+	delay = math.Max(1, math.Min(float64(len(d.values)-1), delay))
+	whole := int(delay)
+	fraction := delay - float64(whole)
+	size := len(d.values)
+	newer := d.values[(d.position-whole+size)%size]
+	older := d.values[(d.position-whole-1+size)%size]
+	return newer + fraction*(older-newer)
+}
+
+// write adds value to the line, replacing its oldest value.
+func (d *delayLine) write(value float64) {
+	d.values[d.position] = value
+	d.position = (d.position + 1) % len(d.values)
+}
+
+/*
+DelaySettings configures a Delay. Time is the delay in milliseconds, which may
+be fractional. Feedback is the proportion of the delayed signal fed back into
+the line, giving repeating echoes that fade as long as it is between -1 and 1,
+and invert each time when it is negative. Mix is the proportion of delayed
+signal in the output.
+*/
+type DelaySettings struct {
+	Time     float64 `json:"time"`
+	Feedback float64 `json:"feedback"`
+	Mix      float64 `json:"mix"`
+}
+
+// Delay is an echo effect with a delay line per channel.
+type Delay struct {
+	delay    float64
+	feedback float64
+	mix      float64
+	lines    []delayLine
+}
+
+/*
+NewDelay returns a Delay for the given channels at the given sample rate. A
+non-nil error is returned if the time is negative or the feedback would make the
+echoes grow.
+*/
+func NewDelay(
+	settings DelaySettings, channels int, sampleRate float64) (*Delay, error) {
+	if settings.Time < 0 {
+		return nil, fmt.Errorf(DelayTimeError, settings.Time)
+	}
+	if math.Abs(settings.Feedback) >= 1 {
+		return nil, fmt.Errorf(FeedbackError, settings.Feedback)
+	}
+	d := &Delay{
+		delay:    settings.Time * sampleRate / 1000,
+		feedback: settings.Feedback,
+		mix:      settings.Mix,
+		lines:    make([]delayLine, channels),
+	}
+	for i := range d.lines {
+		d.lines[i] = newDelayLine(d.delay)
+	}
+	return d, nil
+}
+
+// Process delays a frame.
+func (d *Delay) Process(frame []float64) {
+	for channel, value := range frame[:len(d.lines)] {
+		line := &d.lines[channel]
+		delayed := value
+		if d.delay > 0 {
+			delayed = line.read(d.delay)
+		}
+		line.write(value + d.feedback*delayed)
+		frame[channel] = (1-d.mix)*value + d.mix*delayed
+	}
+}
+
+/*
+ModulationSettings configures a ModulatedDelay. The delay in milliseconds swings
+by Depth either side of Delay, following a sine at Rate Hz, with each channel's
+sine a quarter cycle behind the previous one to widen the stereo image.
+Feedback and Mix are as for DelaySettings. Zero values of Delay, Depth, Rate and
+Mix take the defaults of the constructor.
+*/
+type ModulationSettings struct {
+	Delay    float64 `json:"delay"`
+	Depth    float64 `json:"depth"`
+	Rate     float64 `json:"rate"`
+	Feedback float64 `json:"feedback"`
+	Mix      float64 `json:"mix"`
+}
+
+/*
+ModulatedDelay mixes audio with a copy through a delay whose length is swept by
+a low frequency oscillator. The changing delay shifts the pitch of the copy
+slightly, so with delays around 20 ms it sounds like several voices playing
+together, a chorus, and with delays of a few milliseconds the moving comb
+filter notches give the sweep of a flanger.
+*/
+type ModulatedDelay struct {
+	delay    float64
+	depth    float64
+	step     float64
+	phase    float64
+	feedback float64
+	mix      float64
+	lines    []delayLine
+}
+
+/*
+NewChorus returns a ModulatedDelay for the given channels at the given sample
+rate with the defaults of a chorus: a 20 ms delay swept by 5 ms at 0.8 Hz, mixed
+half and half with the input. A non-nil error is returned for feedback that
+would grow or a delay that would become negative.
+*/
+func NewChorus(settings ModulationSettings,
+	channels int, sampleRate float64) (*ModulatedDelay, error) {
+	return newModulatedDelay(settings,
+		ModulationSettings{Delay: 20, Depth: 5, Rate: 0.8, Mix: 0.5},
+		channels, sampleRate)
+}
+
+/*
+NewFlanger returns a ModulatedDelay for the given channels at the given sample
+rate with the defaults of a flanger: a 2.5 ms delay swept by 2 ms at 0.25 Hz,
+mixed half and half with the input. Feedback deepens the notches.
+*/
+func NewFlanger(settings ModulationSettings,
+	channels int, sampleRate float64) (*ModulatedDelay, error) {
+	return newModulatedDelay(settings,
+		ModulationSettings{Delay: 2.5, Depth: 2, Rate: 0.25, Mix: 0.5},
+		channels, sampleRate)
+}
+
+// newModulatedDelay fills in the zero fields of settings from defaults.
+func newModulatedDelay(settings, defaults ModulationSettings,
+	channels int, sampleRate float64) (*ModulatedDelay, error) {
+	if settings.Delay == 0 {
+		settings.Delay = defaults.Delay
+	}
+	if settings.Depth == 0 {
+		settings.Depth = defaults.Depth
+	}
+	if settings.Rate == 0 {
+		settings.Rate = defaults.Rate
+	}
+	if settings.Mix == 0 {
+		settings.Mix = defaults.Mix
+	}
+	if settings.Delay-math.Abs(settings.Depth) < 0 {
+		return nil, fmt.Errorf(
+			DelayTimeError, settings.Delay-math.Abs(settings.Depth))
+	}
+	if math.Abs(settings.Feedback) >= 1 {
+		return nil, fmt.Errorf(FeedbackError, settings.Feedback)
+	}
+	m := &ModulatedDelay{
+		delay:    settings.Delay * sampleRate / 1000,
+		depth:    settings.Depth * sampleRate / 1000,
+		step:     settings.Rate / sampleRate,
+		feedback: settings.Feedback,
+		mix:      settings.Mix,
+		lines:    make([]delayLine, channels),
+	}
+	for i := range m.lines {
+		m.lines[i] = newDelayLine(m.delay + math.Abs(m.depth))
+	}
+	return m, nil
+}
+
+// Process modulates a frame.
+func (m *ModulatedDelay) Process(frame []float64) {
+	for channel, value := range frame[:len(m.lines)] {
+		line := &m.lines[channel]
+		phase := m.phase - float64(channel)/4
+		delayed := line.read(m.delay + m.depth*math.Sin(2*math.Pi*phase))
+		line.write(value + m.feedback*delayed)
+		frame[channel] = (1-m.mix)*value + m.mix*delayed
+	}
+	m.phase += m.step
+	m.phase -= math.Floor(m.phase)
+}
@@ -0,0 +1,86 @@
+package dsp_test
+
+import (
+	"math"
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/dsp"
+	"github.com/stretchr/testify/assert"
+)
+
+// impulseResponse returns the first count outputs of processor for an
+// impulse on a single channel.
+func impulseResponse(processor Processor, count int) []float64 {
+	var output []float64
+	for i := 0; i < count; i++ {
+		frame := []float64{0}
+		if i == 0 {
+			frame[0] = 1
+		}
+		processor.Process(frame)
+		output = append(output, frame[0])
+	}
+	return output
+}
+
+func TestDelay(t *testing.T) {
+	delay, err := NewDelay(
+		DelaySettings{Time: 3, Feedback: 0.5, Mix: 0.5}, 1, 1000)
+	assert.Nil(t, err)
+	assert.InDeltaSlice(t,
+		[]float64{0.5, 0, 0, 0.5, 0, 0, 0.25, 0, 0, 0.125},
+		impulseResponse(delay, 10), 1e-12)
+}
+
+func TestDelayFractional(t *testing.T) {
+	delay, err := NewDelay(DelaySettings{Time: 1.5, Mix: 1}, 1, 1000)
+	assert.Nil(t, err)
+	assert.InDeltaSlice(t, []float64{0, 0.5, 0.5, 0},
+		impulseResponse(delay, 4), 1e-12)
+}
+
+func TestDelaySettings(t *testing.T) {
+	_, err := NewDelay(DelaySettings{Time: -1}, 1, 1000)
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"delay time of -1 ms must not be negative").FindString(err.Error()))
+	_, err = NewDelay(DelaySettings{Time: 1, Feedback: -1}, 1, 1000)
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"feedback of -1 must be between -1 and 1").FindString(err.Error()))
+	_, err = NewChorus(ModulationSettings{Delay: 1, Depth: 2}, 1, 1000)
+	assert.NotNil(t, err)
+	_, err = NewFlanger(ModulationSettings{Feedback: 1.5}, 1, 1000)
+	assert.NotNil(t, err)
+}
+
+func TestChorus(t *testing.T) {
+	chorus, err := NewChorus(
+		ModulationSettings{Delay: 10, Depth: 5, Rate: 0.01, Mix: 1}, 2, 1000)
+	assert.Nil(t, err)
+	// The sweep barely moves in 20 ms. It starts at a delay of 10 ms on the
+	// left channel and, a quarter cycle behind, 5 ms on the right.
+	var left, right []float64
+	for i := 0; i < 20; i++ {
+		frame := []float64{0, 0}
+		if i == 0 {
+			frame = []float64{1, 1}
+		}
+		chorus.Process(frame)
+		left = append(left, frame[0])
+		right = append(right, frame[1])
+	}
+	assert.InDelta(t, 1, left[10]+left[11], 1e-3)
+	assert.True(t, left[10] > 0.9)
+	assert.InDelta(t, 1, right[4]+right[5]+right[6], 1e-3)
+	assert.True(t, right[5] > 0.9)
+}
+
+func TestFlangerDefaults(t *testing.T) {
+	flanger, err := NewFlanger(ModulationSettings{Feedback: 0.7}, 1, 48000)
+	assert.Nil(t, err)
+	output := impulseResponse(flanger, 48000)
+	assert.Equal(t, 0.5, output[0])
+	for _, value := range output {
+		assert.True(t, math.Abs(value) <= 1)
+	}
+}
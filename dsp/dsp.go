@@ -0,0 +1,84 @@
+/*
+The dsp package processes audio, with effects such as compressors. Processors
+work on frames of floating point samples between -1 and 1, one value per
+channel, as decoded by wav.WavReader.ReadFrames, and change them in place.
+ProcessBuffer runs a Processor over a whole audio.Buffer.
+*/
+package dsp
+
+import (
+	"math"
+
+	"github.com/husafan/audio"
+)
+
+/*
+Processor is an effect that processes audio one frame at a time. Process changes
+the frame in place and may keep state between calls, so a Processor must only be
+used for a single stream.
+*/
+type Processor interface {
+	Process(frame []float64)
+}
+
+// ProcessBuffer runs processor over every frame of buffer in turn.
+func ProcessBuffer(processor Processor, buffer *audio.Buffer) {
+	for i := 0; i < buffer.Frames(); i++ {
+		processor.Process(buffer.Frame(i))
+	}
+}
+
+/*
+Biquad is a second order IIR filter of a single channel, in transposed direct
+form II. It keeps its state between values, so each channel needs its own. The
+zero value filters everything out; NewBiquad gives it coefficients.
+*/
+type Biquad struct {
+	b0, b1, b2 float64
+	a1, a2     float64
+	z1, z2     float64
+}
+
+/*
+NewBiquad returns the filter with the given coefficients, normalised by a0,
+giving the transfer function (b0 + b1/z + b2/z^2) / (a0 + a1/z + a2/z^2). The
+filters of this package follow Robert Bristow-Johnson's Audio EQ Cookbook.
+*/
+func NewBiquad(b0, b1, b2, a0, a1, a2 float64) Biquad {
+	return Biquad{b0: b0 / a0, b1: b1 / a0, b2: b2 / a0,
+		a1: a1 / a0, a2: a2 / a0}
+}
+
+/*
+lowPass, highPass and allPass return second order filters with a cutoff of
+frequency Hz and the given Q at the given sample rate.
+*/
+func lowPass(frequency, q, sampleRate float64) Biquad {
+	cos, alpha := cookbook(frequency, q, sampleRate)
+	return NewBiquad((1-cos)/2, 1-cos, (1-cos)/2, 1+alpha, -2*cos, 1-alpha)
+}
+
+func highPass(frequency, q, sampleRate float64) Biquad {
+	cos, alpha := cookbook(frequency, q, sampleRate)
+	return NewBiquad((1+cos)/2, -(1 + cos), (1+cos)/2,
+		1+alpha, -2*cos, 1-alpha)
+}
+
+func allPass(frequency, q, sampleRate float64) Biquad {
+	cos, alpha := cookbook(frequency, q, sampleRate)
+	return NewBiquad(1-alpha, -2*cos, 1+alpha, 1+alpha, -2*cos, 1-alpha)
+}
+
+// cookbook returns the cosine of the angular frequency and the alpha term.
+func cookbook(frequency, q, sampleRate float64) (float64, float64) {
+	omega := 2 * math.Pi * frequency / sampleRate
+	return math.Cos(omega), math.Sin(omega) / (2 * q)
+}
+
+// Filter filters a single value.
+func (b *Biquad) Filter(value float64) float64 {
+	result := b.b0*value + b.z1
+	b.z1 = b.b1*value - b.a1*result + b.z2
+	b.z2 = b.b2*value - b.a2*result
+	return result
+}
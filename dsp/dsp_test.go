@@ -0,0 +1,31 @@
+package dsp_test
+
+import (
+	"testing"
+
+	"github.com/husafan/audio"
+	. "github.com/husafan/audio/dsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessBuffer(t *testing.T) {
+	buffer := &audio.Buffer{
+		Format: audio.Format{SampleRate: 1000, Channels: 2},
+		Data:   []float64{0.1, 0.2, 0.3, 0.4},
+	}
+	ProcessBuffer(scale(-2), buffer)
+	assert.Equal(t, []float64{-0.2, -0.4, -0.6, -0.8}, buffer.Data)
+}
+
+func TestBiquad(t *testing.T) {
+	// An impulse gives the feedforward taps, then decays by the feedback.
+	filter := NewBiquad(2, 1, 0, 2, -1, 0)
+	var response []float64
+	for _, value := range []float64{1, 0, 0, 0} {
+		response = append(response, filter.Filter(value))
+	}
+	assert.Equal(t, []float64{1, 1, 0.5, 0.25}, response)
+
+	var zero Biquad
+	assert.Equal(t, 0.0, zero.Filter(1))
+}
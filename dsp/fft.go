@@ -0,0 +1,60 @@
+package dsp
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+const FFTSizeError = "FFT size must be a power of two; found %v"
+
+/*
+FFT replaces data with its discrete Fourier transform, computed in place with
+the iterative radix 2 Cooley-Tukey algorithm. A non-nil error is returned, and
+data left unchanged, if its length is not a power of two.
+*/
+func FFT(data []complex128) error {
+	return transform(data, -1)
+}
+
+/*
+InverseFFT replaces data with its inverse discrete Fourier transform, scaled by
+1/len(data) so that it undoes FFT.
+*/
+func InverseFFT(data []complex128) error {
+	if err := transform(data, 1); err != nil {
+		return err
+	}
+	scale := complex(1/float64(len(data)), 0)
+	for i := range data {
+		data[i] *= scale
+	}
+	return nil
+}
+
+// transform runs the FFT with twiddle factors turning in the given direction.
+func transform(data []complex128, direction float64) error {
+	size := len(data)
+	if size == 0 || size&(size-1) != 0 {
+		return fmt.Errorf(FFTSizeError, size)
+	}
+	shift := 64 - bits.TrailingZeros(uint(size))
+	for i := range data {
+		if j := int(bits.Reverse64(uint64(i)) >> uint(shift)); i < j {
+			data[i], data[j] = data[j], data[i]
+		}
+	}
+	for width := 2; width <= size; width *= 2 {
+		step := cmplx.Rect(1, direction*2*math.Pi/float64(width))
+		for start := 0; start < size; start += width {
+			twiddle := complex(1, 0)
+			for k := 0; k < width/2; k++ {
+				even, odd := data[start+k], twiddle*data[start+k+width/2]
+				data[start+k], data[start+k+width/2] = even+odd, even-odd
+				twiddle *= step
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package dsp_test
+
+import (
+	"math"
+	"math/cmplx"
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/dsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFFT(t *testing.T) {
+	// A cosine at bin 3 puts half its amplitude in bins 3 and 13.
+	data := make([]complex128, 16)
+	for i := range data {
+		data[i] = complex(math.Cos(2*math.Pi*3*float64(i)/16), 0)
+	}
+	original := append([]complex128{}, data...)
+	assert.Nil(t, FFT(data))
+	for i, value := range data {
+		expected := 0.0
+		if i == 3 || i == 13 {
+			expected = 8
+		}
+		assert.InDelta(t, expected, cmplx.Abs(value), 1e-9)
+	}
+
+	assert.Nil(t, InverseFFT(data))
+	for i := range data {
+		assert.InDelta(t, real(original[i]), real(data[i]), 1e-9)
+		assert.InDelta(t, 0, imag(data[i]), 1e-9)
+	}
+
+	single := []complex128{2}
+	assert.Nil(t, FFT(single))
+	assert.Equal(t, []complex128{2}, single)
+}
+
+func TestFFTSize(t *testing.T) {
+	data := []complex128{1, 2, 3}
+	err := FFT(data)
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("power of two; found 3")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+	assert.Equal(t, []complex128{1, 2, 3}, data)
+	assert.NotNil(t, InverseFFT(nil))
+}
@@ -0,0 +1,86 @@
+package dsp
+
+import (
+	"math"
+
+	"github.com/husafan/audio"
+)
+
+/*
+GateSettings configures a Gate. The gate opens when the level rises above
+Threshold in dBFS and closes once it has stayed below for Hold milliseconds.
+Range is the gain in decibels of the closed gate, such as -40 to turn noise
+down rather than remove it, with 0 meaning silence. Attack and Release are the
+time constants in milliseconds with which the gate opens and closes.
+*/
+type GateSettings struct {
+	Threshold audio.Decibel `json:"threshold"`
+	Range     audio.Decibel `json:"range"`
+	Attack    float64       `json:"attack"`
+	Hold      float64       `json:"hold"`
+	Release   float64       `json:"release"`
+}
+
+/*
+Gate is a noise gate, which silences hiss, hum and spill between the phrases of
+a recording. Like Compressor, its channels are linked.
+*/
+type Gate struct {
+	threshold float64
+	closed    float64
+	attack    float64
+	release   float64
+	hold      int
+	remaining int
+	open      bool
+	gain      float64
+}
+
+// NewGate returns a Gate for audio at the given sample rate.
+func NewGate(settings GateSettings, sampleRate float64) *Gate {
+	g := &Gate{
+		threshold: settings.Threshold.Linear(),
+		attack:    smoothing(settings.Attack, sampleRate),
+		release:   smoothing(settings.Release, sampleRate),
+		hold:      int(settings.Hold * sampleRate / 1000),
+	}
+	if settings.Range != 0 {
+		g.closed = settings.Range.Linear()
+	}
+	g.gain = g.closed
+	return g
+}
+
+// Process gates a frame.
+func (g *Gate) Process(frame []float64) {
+	var level float64
+	for _, value := range frame {
+		level = math.Max(level, math.Abs(value))
+	}
+	switch {
+	case level > g.threshold:
+		g.remaining, g.open = g.hold, true
+	case g.remaining > 0:
+		g.remaining--
+	default:
+		g.open = false
+	}
+	target, coefficient := g.closed, g.release
+	if g.open {
+		target, coefficient = 1, g.attack
+	}
+	g.gain = coefficient*g.gain + (1-coefficient)*target
+	for i := range frame {
+		frame[i] *= g.gain
+	}
+}
+
+// Open reports whether the gate is open, including while it holds.
+func (g *Gate) Open() bool {
+	return g.open
+}
+
+// Gain returns the gain applied to the last frame.
+func (g *Gate) Gain() audio.Decibel {
+	return audio.LinearToDecibel(g.gain)
+}
@@ -0,0 +1,49 @@
+package dsp_test
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/husafan/audio/dsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGate(t *testing.T) {
+	gate := NewGate(GateSettings{Threshold: -20, Range: -40, Hold: 2}, 1000)
+	assert.False(t, gate.Open())
+	frame := []float64{0.01, -0.01}
+	gate.Process(frame)
+	assert.InDeltaSlice(t, []float64{0.0001, -0.0001}, frame, 1e-12)
+	assert.InDelta(t, -40, float64(gate.Gain()), 1e-9)
+
+	frame = []float64{0.5, 0.01}
+	gate.Process(frame)
+	assert.True(t, gate.Open())
+	assert.Equal(t, []float64{0.5, 0.01}, frame)
+
+	// The gate holds open for two frames before closing.
+	for i := 0; i < 2; i++ {
+		frame = []float64{0.01}
+		gate.Process(frame)
+		assert.True(t, gate.Open())
+		assert.Equal(t, []float64{0.01}, frame)
+	}
+	gate.Process([]float64{0.01})
+	assert.False(t, gate.Open())
+}
+
+func TestGateTimeConstants(t *testing.T) {
+	gate := NewGate(GateSettings{Threshold: -20, Attack: 1, Release: 10}, 1000)
+	frame := []float64{0.01}
+	gate.Process(frame)
+	assert.Equal(t, []float64{0}, frame)
+
+	// After one time constant the gain covers 1 - 1/e of the distance.
+	gate.Process([]float64{1})
+	assert.InDelta(t, 1-1/math.E, gate.Gain().Linear(), 1e-9)
+	for i := 0; i < 20; i++ {
+		gate.Process([]float64{1})
+	}
+	gate.Process([]float64{0})
+	assert.InDelta(t, math.Exp(-0.1), gate.Gain().Linear(), 1e-6)
+}
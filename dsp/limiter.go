@@ -0,0 +1,154 @@
+package dsp
+
+import (
+	"math"
+
+	"github.com/husafan/audio"
+)
+
+/*
+LimiterSettings configures a Limiter. Ceiling is the level in dBFS no output
+sample exceeds, such as -1 to leave room for the overs of lossy encoders.
+Lookahead is the time in milliseconds over which the gain ramps down ahead of a
+peak, and Release the time constant in milliseconds with which it recovers.
+When TruePeak is set, peaks are found by a TruePeak for each channel, so that
+overs between samples are held near the ceiling as well.
+*/
+type LimiterSettings struct {
+	Ceiling   audio.Decibel `json:"ceiling"`
+	Lookahead float64       `json:"lookahead"`
+	Release   float64       `json:"release"`
+	TruePeak  bool          `json:"true_peak"`
+}
+
+/*
+Limiter is a brickwall peak limiter that guarantees no sample leaves louder
+than its ceiling, for the end of a mix or before writing integer samples that
+would otherwise clip. Each frame's required gain is held over the lookahead by
+a sliding minimum, which keeps only the gains that could still become the
+minimum in a ring the length of the lookahead, and averaged over the same
+length by a running sum, so the gain ramps down smoothly and reaches the
+required value exactly as the peak leaves, at amortised constant cost per frame
+and without allocating. To see peaks coming, the audio is delayed by Latency
+frames, so Process returns earlier frames than it is given. Like Compressor,
+its channels are linked.
+*/
+type Limiter struct {
+	ceiling  float64
+	release  float64
+	peaks    []TruePeak
+	minima   []requirement
+	first    int
+	count    int
+	frame    int
+	held     []float64
+	sum      float64
+	position int
+	delay    [][]float64
+	delayed  int
+	recovery float64
+	gain     float64
+}
+
+// requirement is the gain a frame requires, in the Limiter's sliding minimum.
+type requirement struct {
+	frame int
+	gain  float64
+}
+
+/*
+NewLimiter returns a Limiter for the given channels at the given sample rate.
+*/
+func NewLimiter(
+	settings LimiterSettings, channels int, sampleRate float64) *Limiter {
+	length := int(settings.Lookahead*sampleRate/1000) + 1
+	latency := length - 1
+	var peaks []TruePeak
+	if settings.TruePeak {
+		peaks = make([]TruePeak, channels)
+		latency += peaks[0].Latency()
+	}
+	l := &Limiter{
+		ceiling:  settings.Ceiling.Linear(),
+		release:  smoothing(settings.Release, sampleRate),
+		peaks:    peaks,
+		minima:   make([]requirement, length),
+		held:     make([]float64, length),
+		delay:    make([][]float64, latency),
+		sum:      float64(length),
+		recovery: 1,
+		gain:     1,
+	}
+	for i := range l.held {
+		l.held[i] = 1
+	}
+	for i := range l.delay {
+		l.delay[i] = make([]float64, channels)
+	}
+	return l
+}
+
+// Latency returns the number of frames by which the Limiter delays audio.
+func (l *Limiter) Latency() int {
+	return len(l.delay)
+}
+
+// Process limits a frame, replacing it with the frame Latency frames earlier.
+func (l *Limiter) Process(frame []float64) {
+	var peak float64
+	for i, value := range frame {
+		if l.peaks != nil {
+			peak = math.Max(peak, l.peaks[i].Next(value))
+		} else {
+			peak = math.Max(peak, math.Abs(value))
+		}
+	}
+	required := 1.0
+	if peak > l.ceiling {
+		required = l.ceiling / peak
+	}
+	// The oldest gain leaves once it falls out of the lookahead, and gains no
+	// lower than this frame's can never be the minimum again.
+	size := len(l.minima)
+	if l.count > 0 && l.minima[l.first].frame <= l.frame-size {
+		l.first, l.count = (l.first+1)%size, l.count-1
+	}
+	for l.count > 0 && l.minima[(l.first+l.count-1)%size].gain >= required {
+		l.count--
+	}
+	l.minima[(l.first+l.count)%size] = requirement{l.frame, required}
+	l.count++
+	l.frame++
+	held := l.minima[l.first].gain
+	// The gain recovers at the release rate but falls at once, so the held
+	// gain never rises above what the lookahead window requires.
+	l.recovery = math.Min(held, l.release*l.recovery+(1-l.release)*held)
+	l.sum += l.recovery - l.held[l.position]
+	l.held[l.position] = l.recovery
+	l.position = (l.position + 1) % len(l.held)
+	// The running sum is recomputed once per window so that rounding errors
+	// cannot accumulate.
+	if l.position == 0 {
+		l.sum = 0
+		for _, value := range l.held {
+			l.sum += value
+		}
+	}
+	l.gain = l.sum / float64(len(l.held))
+
+	if len(l.delay) > 0 {
+		oldest := l.delay[l.delayed]
+		for i, value := range frame {
+			frame[i], oldest[i] = oldest[i], value
+		}
+		l.delayed = (l.delayed + 1) % len(l.delay)
+	}
+	for i, value := range frame {
+		frame[i] = math.Max(-l.ceiling, math.Min(l.ceiling, value*l.gain))
+	}
+}
+
+// GainReduction returns the gain reduction applied to the last frame.
+func (l *Limiter) GainReduction() audio.Decibel {
+	return -audio.LinearToDecibel(l.gain)
+}
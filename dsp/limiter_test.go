@@ -0,0 +1,104 @@
+package dsp_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/husafan/audio"
+	. "github.com/husafan/audio/dsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	limiter := NewLimiter(
+		LimiterSettings{Ceiling: -6, Lookahead: 5, Release: 10}, 2, 1000)
+	assert.Equal(t, 5, limiter.Latency())
+	ceiling := audio.Decibel(-6).Linear()
+
+	input := make([][]float64, 60)
+	for i := range input {
+		input[i] = []float64{0.25, -0.25}
+	}
+	input[20] = []float64{1, -0.5}
+	var output [][]float64
+	for _, frame := range input {
+		frame = append([]float64{}, frame...)
+		limiter.Process(frame)
+		output = append(output, frame)
+	}
+	for i, frame := range output {
+		for _, value := range frame {
+			assert.True(t, math.Abs(value) <= ceiling+1e-12)
+		}
+		if i < 5 {
+			assert.Equal(t, []float64{0, 0}, frame)
+		}
+	}
+	// The peak arrives exactly at the ceiling, after gain ramping down over
+	// the lookahead rather than clipping.
+	assert.InDelta(t, ceiling, output[25][0], 1e-9)
+	assert.InDelta(t, -ceiling/2, output[25][1], 1e-9)
+	assert.True(t, output[22][0] < 0.25 && output[22][0] > output[24][0])
+	// Quiet audio passes unchanged, well before and after the peak.
+	assert.Equal(t, []float64{0.25, -0.25}, output[10])
+	assert.InDelta(t, 0.25, output[59][0], 0.01)
+}
+
+func TestLimiterWithoutLookahead(t *testing.T) {
+	limiter := NewLimiter(LimiterSettings{}, 1, 48000)
+	assert.Equal(t, 0, limiter.Latency())
+	frame := []float64{2}
+	limiter.Process(frame)
+	assert.InDelta(t, 1, frame[0], 1e-9)
+	assert.InDelta(t, 6.02, float64(limiter.GainReduction()), 0.01)
+	frame = []float64{-0.5}
+	limiter.Process(frame)
+	assert.Equal(t, []float64{-0.5}, frame)
+	assert.Equal(t, audio.Decibel(0), limiter.GainReduction())
+}
+
+func TestLimiterTruePeak(t *testing.T) {
+	// The samples of the sine stay below the ceiling, but its true peak
+	// does not.
+	ceiling := audio.Decibel(-1).Linear()
+	for _, truePeak := range []bool{false, true} {
+		limiter := NewLimiter(LimiterSettings{
+			Ceiling: -1, Lookahead: 5, Release: 10, TruePeak: truePeak},
+			1, 1000)
+		var meter TruePeak
+		var level float64
+		for i := 0; i < 400; i++ {
+			frame := []float64{math.Sin(math.Pi/2*float64(i) + math.Pi/4)}
+			limiter.Process(frame)
+			level = math.Max(level, meter.Next(frame[0]))
+		}
+		if truePeak {
+			assert.Equal(t, 11, limiter.Latency())
+			assert.InDelta(t, ceiling, level, 0.01)
+		} else {
+			assert.Equal(t, 5, limiter.Latency())
+			assert.InDelta(t, 1, level, 0.02)
+		}
+	}
+}
+
+func TestLimiterAllocations(t *testing.T) {
+	limiter := NewLimiter(
+		LimiterSettings{Ceiling: -1, Lookahead: 5, TruePeak: true}, 2, 48000)
+	frame := []float64{0, 0}
+	// A falling level keeps the whole lookahead in the sliding minimum, so
+	// its oldest gain leaves on every frame. AllocsPerRun rounds down its
+	// average, so each frame is counted alone to catch occasional
+	// allocations.
+	var i int
+	var allocations float64
+	for run := 0; run < 2000; run++ {
+		allocations += testing.AllocsPerRun(1, func() {
+			level := 2 - float64(i%1000)/1000
+			frame[0], frame[1] = level, -level
+			i++
+			limiter.Process(frame)
+		})
+	}
+	assert.Equal(t, 0.0, allocations)
+}
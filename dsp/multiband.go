@@ -0,0 +1,98 @@
+package dsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/husafan/audio"
+)
+
+const BandCountError = "%v crossover frequencies need %v bands; found %v"
+
+/*
+MultibandSettings configures a MultibandCompressor, and is stored as JSON in
+presets. Crossovers are the frequencies in Hz between the bands, and Bands the
+settings of the compressor of each band, from the lowest to the highest.
+*/
+type MultibandSettings struct {
+	Crossovers []float64            `json:"crossovers"`
+	Bands      []CompressorSettings `json:"bands"`
+}
+
+/*
+ReadPreset reads MultibandSettings stored as JSON by WritePreset.
+*/
+func ReadPreset(reader io.Reader) (MultibandSettings, error) {
+	var settings MultibandSettings
+	err := json.NewDecoder(reader).Decode(&settings)
+	return settings, err
+}
+
+// WritePreset writes the settings to writer as indented JSON.
+func (s MultibandSettings) WritePreset(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+/*
+MultibandCompressor splits audio into bands with a Crossover, compresses each
+band with its own Compressor and sums the bands back together, so a loud bass
+line does not pump the rest of the mix.
+*/
+type MultibandCompressor struct {
+	crossover   *Crossover
+	compressors []*Compressor
+	bands       [][]float64
+}
+
+/*
+NewMultibandCompressor returns a MultibandCompressor for the given channels at
+the given sample rate. A non-nil error is returned if the settings do not have
+one more band than crossover frequencies, or either are invalid.
+*/
+func NewMultibandCompressor(settings MultibandSettings,
+	channels int, sampleRate float64) (*MultibandCompressor, error) {
+	if bands := len(settings.Crossovers) + 1; len(settings.Bands) != bands {
+		return nil, fmt.Errorf(BandCountError,
+			len(settings.Crossovers), bands, len(settings.Bands))
+	}
+	crossover, err := NewCrossover(settings.Crossovers, channels, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+	m := &MultibandCompressor{crossover: crossover}
+	for _, band := range settings.Bands {
+		compressor, err := NewCompressor(band, sampleRate)
+		if err != nil {
+			return nil, err
+		}
+		m.compressors = append(m.compressors, compressor)
+		m.bands = append(m.bands, make([]float64, channels))
+	}
+	return m, nil
+}
+
+// Process compresses a frame.
+func (m *MultibandCompressor) Process(frame []float64) {
+	m.crossover.Split(frame, m.bands)
+	for channel := range frame {
+		frame[channel] = 0
+	}
+	for band, compressor := range m.compressors {
+		compressor.Process(m.bands[band])
+		for channel, value := range m.bands[band] {
+			frame[channel] += value
+		}
+	}
+}
+
+// GainReductions returns the current gain reduction of each band.
+func (m *MultibandCompressor) GainReductions() []audio.Decibel {
+	reductions := make([]audio.Decibel, len(m.compressors))
+	for band, compressor := range m.compressors {
+		reductions[band] = compressor.GainReduction()
+	}
+	return reductions
+}
@@ -0,0 +1,94 @@
+package dsp_test
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"testing"
+
+	"github.com/husafan/audio"
+	. "github.com/husafan/audio/dsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultibandCompressor(t *testing.T) {
+	settings := MultibandSettings{
+		Crossovers: []float64{200, 2000},
+		Bands: []CompressorSettings{
+			{Threshold: -20, Ratio: 4}, {Ratio: 1}, {Ratio: 1},
+		},
+	}
+	compressor, err := NewMultibandCompressor(settings, 2, 48000)
+	assert.Nil(t, err)
+
+	// Only the band holding the loud bass is compressed.
+	var peak float64
+	for i := 0; i < 48000; i++ {
+		value := math.Sin(2 * math.Pi * 50 * float64(i) / 48000)
+		frame := []float64{value, value}
+		compressor.Process(frame)
+		if i >= 24000 {
+			peak = math.Max(peak, frame[0])
+		}
+	}
+	reductions := compressor.GainReductions()
+	assert.True(t, reductions[0] > 10)
+	assert.Equal(t, audio.Decibel(0), reductions[2])
+	assert.True(t, peak < 0.5)
+}
+
+func TestMultibandCompressorTransparent(t *testing.T) {
+	compressor, err := NewMultibandCompressor(MultibandSettings{
+		Crossovers: []float64{1000},
+		Bands:      []CompressorSettings{{Ratio: 1}, {Ratio: 1}},
+	}, 1, 48000)
+	assert.Nil(t, err)
+	var peak float64
+	for i := 0; i < 48000; i++ {
+		frame := []float64{math.Sin(2 * math.Pi * 1000 * float64(i) / 48000)}
+		compressor.Process(frame)
+		if i >= 24000 {
+			peak = math.Max(peak, math.Abs(frame[0]))
+		}
+	}
+	assert.InDelta(t, 1, peak, 0.01)
+}
+
+func TestMultibandCompressorErrors(t *testing.T) {
+	_, err := NewMultibandCompressor(MultibandSettings{
+		Crossovers: []float64{1000},
+		Bands:      []CompressorSettings{{Ratio: 1}},
+	}, 1, 48000)
+	assert.NotNil(t, err)
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"1 crossover frequencies need 2 bands; found 1").
+		FindString(err.Error()))
+
+	_, err = NewMultibandCompressor(MultibandSettings{
+		Crossovers: []float64{1000},
+		Bands:      []CompressorSettings{{Ratio: 1}, {Ratio: 0}},
+	}, 1, 48000)
+	assert.NotNil(t, err)
+}
+
+func TestPreset(t *testing.T) {
+	settings := MultibandSettings{
+		Crossovers: []float64{120, 2500, 9000},
+		Bands: []CompressorSettings{
+			{Threshold: -18, Ratio: 3, Attack: 30, Release: 200},
+			{Threshold: -20, Ratio: 2, Attack: 10, Release: 150},
+			{Threshold: -22, Ratio: 2, Attack: 5, Release: 100, Makeup: 1},
+			{Threshold: -24, Ratio: 1.5, Attack: 2, Release: 80},
+		},
+	}
+	buffer := new(bytes.Buffer)
+	assert.Nil(t, settings.WritePreset(buffer))
+	assert.NotEqual(t, "", regexp.MustCompile(
+		`"crossovers": \[\s*120,`).FindString(buffer.String()))
+	read, err := ReadPreset(buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, settings, read)
+
+	_, err = ReadPreset(bytes.NewBufferString("{"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,74 @@
+package dsp
+
+import "math"
+
+// yinThreshold is the largest normalised difference accepted as a period.
+const yinThreshold = 0.15
+
+/*
+Pitch is the result of DetectPitch. Frequency is the fundamental frequency in
+Hz, or 0 when the window has no clear period, and Confidence ranges from 0 to 1
+for how closely the window repeats at that period.
+*/
+type Pitch struct {
+	Frequency  float64
+	Confidence float64
+}
+
+/*
+DetectPitch estimates the fundamental frequency of a window of mono samples at
+the given sample rate, with the YIN algorithm of de Cheveigné and Kawahara. The
+difference between the window and delayed copies of itself is normalised by its
+running mean, and the first delay whose difference falls below 0.15 is taken as
+the period, refined by parabolic interpolation. Periods up to half the window
+are found, so the window must hold two periods of the lowest frequency wanted.
+*/
+func DetectPitch(window []float64, sampleRate float64) Pitch {
+	half := len(window) / 2
+	if half < 3 {
+		return Pitch{}
+	}
+	// normalised[tau] is the cumulative mean normalised difference of YIN.
+	normalised := make([]float64, half)
+	normalised[0] = 1
+	var total float64
+	for tau := 1; tau < half; tau++ {
+		var difference float64
+		for j := 0; j < half; j++ {
+			delta := window[j] - window[j+tau]
+			difference += delta * delta
+		}
+		total += difference
+		if total == 0 {
+			normalised[tau] = 1
+		} else {
+			normalised[tau] = difference * float64(tau) / total
+		}
+	}
+
+	period := 0
+	for tau := 2; tau < half; tau++ {
+		if normalised[tau] < yinThreshold {
+			for tau+1 < half && normalised[tau+1] < normalised[tau] {
+				tau++
+			}
+			period = tau
+			break
+		}
+	}
+	if period == 0 {
+		return Pitch{}
+	}
+	refined := float64(period)
+	if period+1 < half {
+		before, at, after :=
+			normalised[period-1], normalised[period], normalised[period+1]
+		if curve := before - 2*at + after; curve > 0 {
+			refined += 0.5 * (before - after) / curve
+		}
+	}
+	return Pitch{
+		Frequency:  sampleRate / refined,
+		Confidence: math.Max(0, 1-normalised[period]),
+	}
+}
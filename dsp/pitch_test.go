@@ -0,0 +1,42 @@
+package dsp_test
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/husafan/audio/dsp"
+	"github.com/stretchr/testify/assert"
+)
+
+// tone returns count samples of a sine at frequency Hz with two harmonics.
+func tone(frequency, sampleRate float64, count int) []float64 {
+	samples := make([]float64, count)
+	for i := range samples {
+		phase := 2 * math.Pi * frequency * float64(i) / sampleRate
+		samples[i] = 0.5*math.Sin(phase) + 0.2*math.Sin(2*phase) +
+			0.1*math.Sin(3*phase)
+	}
+	return samples
+}
+
+func TestDetectPitch(t *testing.T) {
+	for _, frequency := range []float64{82.41, 220, 440, 1046.5} {
+		pitch := DetectPitch(tone(frequency, 44100, 2048), 44100)
+		assert.InDelta(t, frequency, pitch.Frequency, frequency*0.005)
+		assert.True(t, pitch.Confidence > 0.9)
+	}
+}
+
+func TestDetectPitchWithoutPeriod(t *testing.T) {
+	assert.Equal(t, Pitch{}, DetectPitch(make([]float64, 1024), 44100))
+	assert.Equal(t, Pitch{}, DetectPitch([]float64{0.5, -0.5}, 44100))
+
+	// White noise does not repeat.
+	noise := make([]float64, 2048)
+	seed := uint32(1)
+	for i := range noise {
+		seed = seed*1664525 + 1013904223
+		noise[i] = float64(int32(seed)) / math.MaxInt32
+	}
+	assert.Equal(t, 0.0, DetectPitch(noise, 44100).Frequency)
+}
@@ -0,0 +1,190 @@
+package dsp_test
+
+import (
+	"context"
+	"math"
+	"regexp"
+	"testing"
+
+	"github.com/husafan/audio"
+	. "github.com/husafan/audio/dsp"
+	"github.com/stretchr/testify/assert"
+)
+
+// sineBuffer returns a mono buffer of a second of a half scale sine.
+func sineBuffer(frequency float64, rate uint32) *audio.Buffer {
+	buffer := audio.NewBuffer(audio.Format{SampleRate: rate, Channels: 1},
+		int(rate))
+	for i := range buffer.Data {
+		buffer.Data[i] = 0.5 *
+			math.Sin(2*math.Pi*frequency*float64(i)/float64(rate))
+	}
+	return buffer
+}
+
+// halfScale is the level of the sines of sineBuffer.
+var halfScale = audio.LinearToDecibel(0.5)
+
+// measure returns the Tone at frequency in the middle half of buffer.
+func measure(buffer *audio.Buffer, frequency float64) Tone {
+	return MeasureTone(buffer.Data[buffer.Frames()/4:buffer.Frames()*3/4],
+		float64(buffer.Format.SampleRate), frequency)
+}
+
+func TestResampleQuality(t *testing.T) {
+	for _, test := range []struct {
+		settings  ResampleSettings
+		frequency float64
+		thdn      audio.Decibel
+		level     audio.Decibel
+	}{
+		{ResampleSettings{Quality: ResampleLinear}, 1000, -60, 0},
+		{ResampleSettings{Quality: ResampleLinear}, 10000, -20, -1.5},
+		{ResampleSettings{Quality: ResamplePolyphase}, 1000, -95, 0},
+		{ResampleSettings{Quality: ResamplePolyphase}, 16000, -95, 0},
+		{ResampleSettings{Quality: ResampleSinc}, 1000, -100, 0},
+		{ResampleSettings{Quality: ResampleSinc}, 18000, -100, 0},
+	} {
+		output, err := Resample(sineBuffer(test.frequency, 44100), 48000,
+			test.settings)
+		assert.Nil(t, err)
+		assert.Equal(t, 48000, output.Frames())
+		tone := measure(output, test.frequency)
+		assert.Less(t, tone.THDN(), test.thdn, "%v at %v Hz",
+			test.settings.Quality, test.frequency)
+		assert.InDelta(t, float64(test.level), float64(tone.Level-halfScale),
+			0.1, "%v at %v Hz", test.settings.Quality, test.frequency)
+	}
+}
+
+func TestResampleAliasing(t *testing.T) {
+	for _, test := range []struct {
+		quality ResampleQuality
+		level   audio.Decibel
+	}{
+		{ResampleLinear, -10},
+		{ResamplePolyphase, -85},
+		{ResampleSinc, -85},
+	} {
+		// A 23 kHz tone has no place below 22.05 kHz.
+		output, err := Resample(sineBuffer(23000, 48000), 44100,
+			ResampleSettings{Quality: test.quality})
+		assert.Nil(t, err)
+		assert.Equal(t, 44100, output.Frames())
+		level := MeasureLevel(output.Data[11025:33075]) - halfScale
+		if test.quality == ResampleLinear {
+			assert.Greater(t, level, test.level)
+		} else {
+			assert.Less(t, level, test.level, "quality %v", test.quality)
+		}
+	}
+}
+
+func TestResampleMinimumTaps(t *testing.T) {
+	// The fewest taps still pass the low frequencies at their level, and
+	// filter out a tone above the new Nyquist frequency.
+	settings := ResampleSettings{Quality: ResampleSinc, Taps: 16}
+	for _, rate := range []uint32{48000, 32000} {
+		output, err := Resample(sineBuffer(1000, 44100), rate, settings)
+		assert.Nil(t, err)
+		tone := measure(output, 1000)
+		assert.Less(t, tone.THDN(), audio.Decibel(-100), "%v Hz", rate)
+		assert.InDelta(t, float64(halfScale), float64(tone.Level), 0.1,
+			"%v Hz", rate)
+	}
+	output, err := Resample(sineBuffer(23000, 48000), 32000, settings)
+	assert.Nil(t, err)
+	assert.Less(t, MeasureLevel(output.Data[8000:24000])-halfScale,
+		audio.Decibel(-80))
+}
+
+func TestResampleChannels(t *testing.T) {
+	buffer := audio.NewBuffer(audio.Format{SampleRate: 8000, Channels: 2}, 80)
+	for i := 0; i < buffer.Frames(); i++ {
+		buffer.Frame(i)[0], buffer.Frame(i)[1] = 0.5, -0.25
+	}
+	for _, quality := range []ResampleQuality{
+		ResampleLinear, ResamplePolyphase, ResampleSinc} {
+		output, err := Resample(buffer, 22050, ResampleSettings{
+			Quality: quality, Taps: 16})
+		assert.Nil(t, err)
+		assert.Equal(t, audio.Format{SampleRate: 22050, Channels: 2},
+			output.Format)
+		assert.Equal(t, 221, output.Frames())
+		// Channels stay apart, and a constant keeps its level away from the
+		// ends.
+		assert.InDelta(t, 0.5, output.Frame(110)[0], 1e-3)
+		assert.InDelta(t, -0.25, output.Frame(110)[1], 1e-3)
+	}
+
+	// Resampling to the same rate copies the buffer.
+	output, err := Resample(buffer, 8000, ResampleSettings{})
+	assert.Nil(t, err)
+	assert.Equal(t, buffer, output)
+	output.Data[0] = 0
+	assert.Equal(t, 0.5, buffer.Data[0])
+}
+
+func TestResampleContext(t *testing.T) {
+	buffer := sineBuffer(1000, 44100)
+	var reports [][2]int64
+	settings := ResampleSettings{Progress: func(done, total int64) {
+		reports = append(reports, [2]int64{done, total})
+	}}
+	output, err := ResampleContext(context.Background(), buffer, 11025,
+		settings)
+	assert.Nil(t, err)
+	assert.Equal(t, [][2]int64{{4096, 11025}, {8192, 11025},
+		{11025, 11025}}, reports)
+	expected, _ := Resample(buffer, 11025, ResampleSettings{})
+	assert.Equal(t, expected, output)
+
+	// A cancelled conversion stops with the frames it has written.
+	ctx, cancel := context.WithCancel(context.Background())
+	reports = nil
+	settings.Progress = func(done, total int64) {
+		reports = append(reports, [2]int64{done, total})
+		cancel()
+	}
+	output, err = ResampleContext(ctx, buffer, 11025, settings)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, [][2]int64{{4096, 11025}}, reports)
+	assert.Equal(t, expected.Data[:4096], output.Data[:4096])
+	assert.Equal(t, make([]float64, 11025-4096), output.Data[4096:])
+}
+
+// benchmarkResample converts a second of 44.1 kHz audio to 48 kHz.
+func benchmarkResample(b *testing.B, quality ResampleQuality) {
+	buffer := sineBuffer(1000, 44100)
+	for i := 0; i < b.N; i++ {
+		Resample(buffer, 48000, ResampleSettings{Quality: quality})
+	}
+}
+
+func BenchmarkResampleLinear(b *testing.B) {
+	benchmarkResample(b, ResampleLinear)
+}
+
+func BenchmarkResamplePolyphase(b *testing.B) {
+	benchmarkResample(b, ResamplePolyphase)
+}
+
+func BenchmarkResampleSinc(b *testing.B) {
+	benchmarkResample(b, ResampleSinc)
+}
+
+func TestResampleErrors(t *testing.T) {
+	buffer := sineBuffer(1000, 8000)
+	_, err := Resample(buffer, 0, ResampleSettings{})
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"sample rate of 0 Hz").FindString(err.Error()))
+	for _, taps := range []int{2, 4, 14, 17, 2048} {
+		_, err = Resample(buffer, 16000, ResampleSettings{
+			Quality: ResampleSinc, Taps: taps})
+		assert.NotEqual(t, "", regexp.MustCompile(
+			"even number of taps").FindString(err.Error()))
+	}
+	// The taps only matter to ResampleSinc.
+	_, err = Resample(buffer, 16000, ResampleSettings{Taps: 7})
+	assert.Nil(t, err)
+}
@@ -0,0 +1,66 @@
+package dsp
+
+import "math"
+
+// settledDistance is the distance from its target at which a Smoother snaps.
+const settledDistance = 1e-6
+
+/*
+Smoother ramps a parameter towards its target with a one pole filter, so that
+changing a gain, pan or filter cutoff while audio plays does not cause zipper
+noise. After one time constant the value has covered 1 - 1/e of the distance to
+its target. Once within a millionth of the target it snaps to it, so Settled can
+be used to skip per frame work.
+*/
+type Smoother struct {
+	coefficient float64
+	value       float64
+	target      float64
+}
+
+/*
+NewSmoother returns a Smoother resting at value, with a time constant of
+milliseconds at the given sample rate. A time constant of 0 or less makes every
+change take effect on the next frame.
+*/
+func NewSmoother(value, milliseconds, sampleRate float64) *Smoother {
+	return &Smoother{
+		coefficient: smoothing(milliseconds, sampleRate),
+		value:       value,
+		target:      value,
+	}
+}
+
+// SetTarget sets the value the Smoother ramps towards.
+func (s *Smoother) SetTarget(target float64) {
+	s.target = target
+}
+
+// Target returns the value the Smoother is ramping towards.
+func (s *Smoother) Target() float64 {
+	return s.target
+}
+
+// Reset moves the Smoother to rest at value without ramping.
+func (s *Smoother) Reset(value float64) {
+	s.value, s.target = value, value
+}
+
+// Next advances the Smoother by one frame and returns its new value.
+func (s *Smoother) Next() float64 {
+	s.value = s.coefficient*s.value + (1-s.coefficient)*s.target
+	if math.Abs(s.target-s.value) < settledDistance {
+		s.value = s.target
+	}
+	return s.value
+}
+
+// Value returns the current value without advancing the Smoother.
+func (s *Smoother) Value() float64 {
+	return s.value
+}
+
+// Settled reports whether the Smoother has reached its target.
+func (s *Smoother) Settled() bool {
+	return s.value == s.target
+}
@@ -0,0 +1,46 @@
+package dsp_test
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/husafan/audio/dsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSmoother(t *testing.T) {
+	smoother := NewSmoother(1, 10, 1000)
+	assert.True(t, smoother.Settled())
+	assert.Equal(t, 1.0, smoother.Next())
+
+	// After one time constant the value covers 1 - 1/e of the distance.
+	smoother.SetTarget(0)
+	assert.Equal(t, 0.0, smoother.Target())
+	assert.False(t, smoother.Settled())
+	previous := smoother.Value()
+	for i := 0; i < 10; i++ {
+		value := smoother.Next()
+		assert.True(t, value < previous)
+		previous = value
+	}
+	assert.InDelta(t, 1/math.E, smoother.Value(), 1e-9)
+
+	// The value eventually snaps to its target.
+	for i := 0; i < 1000 && !smoother.Settled(); i++ {
+		smoother.Next()
+	}
+	assert.True(t, smoother.Settled())
+	assert.Equal(t, 0.0, smoother.Value())
+
+	smoother.SetTarget(5)
+	smoother.Reset(2)
+	assert.Equal(t, 2.0, smoother.Value())
+	assert.Equal(t, 2.0, smoother.Target())
+}
+
+func TestSmootherWithoutRamp(t *testing.T) {
+	smoother := NewSmoother(0, 0, 48000)
+	smoother.SetTarget(0.5)
+	assert.Equal(t, 0.5, smoother.Next())
+	assert.True(t, smoother.Settled())
+}
@@ -0,0 +1,127 @@
+package dsp
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/husafan/audio"
+)
+
+const (
+	StretchRatioError = "stretch ratio of %v must be positive"
+
+	// stretchWindow is the length in milliseconds of the segments TimeStretch
+	// overlaps, long enough to hold a period of low voices and instruments.
+	stretchWindow = 40
+)
+
+/*
+TimeStretch returns a copy of buffer whose duration is ratio times that of the
+original, without changing its pitch, so a ratio of 2 plays half as fast. It
+uses WSOLA, waveform similarity overlap-add: Hann windowed segments are taken
+from the input at the stretched rate and overlapped at half their length, and
+each segment is moved by up to a quarter of its length to the position where
+it best continues the previous one, so periodic waveforms stay in phase. The
+same position is used for every channel, keeping the stereo image. Transients
+may be smeared or repeated at large ratios. A non-nil error is returned if
+ratio is not positive.
+*/
+func TimeStretch(buffer *audio.Buffer, ratio float64) (*audio.Buffer, error) {
+	if !(ratio > 0) {
+		return nil, fmt.Errorf(StretchRatioError, ratio)
+	}
+	frames := buffer.Frames()
+	length := int(math.Round(float64(frames) * ratio))
+	output := audio.NewBuffer(buffer.Format, length)
+	size := 2 * int(float64(buffer.Format.SampleRate)*stretchWindow/2000)
+	if size < 4 {
+		size = 4
+	}
+	hop := size / 2
+	tolerance := size / 4
+	window := make([]float64, size)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(size))
+	}
+	mono := make([]float64, frames)
+	for i := range mono {
+		for _, value := range buffer.Frame(i) {
+			mono[i] += value
+		}
+	}
+	// sample returns the value at frame i, or 0 beyond either end.
+	sample := func(values []float64, i int) float64 {
+		if i < 0 || i >= len(values) {
+			return 0
+		}
+		return values[i]
+	}
+
+	previous := -hop
+	for start := -hop; start < length; start += hop {
+		nominal := int(math.Round(float64(start) / ratio))
+		position := nominal
+		if start > -hop {
+			// The natural continuation of the previous segment is where
+			// the next segment would start without stretching.
+			continuation := previous + hop
+			best := math.Inf(-1)
+			for offset := -tolerance; offset <= tolerance; offset++ {
+				var correlation float64
+				for i := 0; i < hop; i++ {
+					correlation += sample(mono, continuation+i) *
+						sample(mono, nominal+offset+i)
+				}
+				if correlation > best {
+					best, position = correlation, nominal+offset
+				}
+			}
+		}
+		for i := 0; i < size; i++ {
+			target := start + i
+			if target < 0 || target >= length {
+				continue
+			}
+			source := position + i
+			if source < 0 || source >= frames {
+				continue
+			}
+			outputFrame := output.Frame(target)
+			for channel, value := range buffer.Frame(source) {
+				outputFrame[channel] += window[i] * value
+			}
+		}
+		previous = position
+	}
+	return output, nil
+}
+
+/*
+PitchShift returns a copy of buffer transposed by the given number of
+semitones, which may be fractional or negative, without changing its duration.
+It stretches the buffer with TimeStretch and then resamples it back to its
+original length with linear interpolation, which is fast but lets some aliasing
+through when shifting up.
+*/
+func PitchShift(buffer *audio.Buffer, semitones float64) *audio.Buffer {
+	factor := math.Pow(2, semitones/12)
+	stretched, _ := TimeStretch(buffer, factor)
+	frames := buffer.Frames()
+	output := audio.NewBuffer(buffer.Format, frames)
+	last := stretched.Frames() - 1
+	for i := 0; i < frames && last >= 0; i++ {
+		position := float64(i) * factor
+		whole := int(position)
+		if whole >= last {
+			copy(output.Frame(i), stretched.Frame(last))
+			continue
+		}
+		fraction := position - float64(whole)
+		before, after := stretched.Frame(whole), stretched.Frame(whole+1)
+		for channel := range output.Frame(i) {
+			output.Frame(i)[channel] =
+				before[channel] + fraction*(after[channel]-before[channel])
+		}
+	}
+	return output
+}
@@ -0,0 +1,76 @@
+package dsp_test
+
+import (
+	"math"
+	"regexp"
+	"testing"
+
+	"github.com/husafan/audio"
+	. "github.com/husafan/audio/dsp"
+	"github.com/stretchr/testify/assert"
+)
+
+// toneBuffer returns a stereo buffer of seconds of tone at 8 kHz, with the
+// right channel at half the level of the left.
+func toneBuffer(frequency, seconds float64) *audio.Buffer {
+	samples := tone(frequency, 8000, int(seconds*8000))
+	buffer := audio.NewBuffer(
+		audio.Format{SampleRate: 8000, Channels: 2}, len(samples))
+	for i, value := range samples {
+		buffer.Frame(i)[0], buffer.Frame(i)[1] = value, value/2
+	}
+	return buffer
+}
+
+// centrePitch detects the pitch of the left channel in the middle of buffer.
+func centrePitch(buffer *audio.Buffer) Pitch {
+	window := make([]float64, 512)
+	middle := buffer.Frames()/2 - 256
+	for i := range window {
+		window[i] = buffer.Frame(middle + i)[0]
+	}
+	return DetectPitch(window, float64(buffer.Format.SampleRate))
+}
+
+// rms returns the root mean square of a channel of buffer.
+func rms(buffer *audio.Buffer, channel int) float64 {
+	var sum float64
+	for i := 0; i < buffer.Frames(); i++ {
+		sum += buffer.Frame(i)[channel] * buffer.Frame(i)[channel]
+	}
+	return math.Sqrt(sum / float64(buffer.Frames()))
+}
+
+func TestTimeStretch(t *testing.T) {
+	original := toneBuffer(220, 1)
+	for _, ratio := range []float64{0.5, 1, 1.5, 2} {
+		stretched, err := TimeStretch(original, ratio)
+		assert.Nil(t, err)
+		assert.Equal(t, int(8000*ratio), stretched.Frames())
+		assert.Equal(t, original.Format, stretched.Format)
+		assert.InDelta(t, 220, centrePitch(stretched).Frequency, 2)
+		assert.InDelta(t, rms(original, 0), rms(stretched, 0), 0.05)
+		assert.InDelta(t, rms(stretched, 0)/2, rms(stretched, 1), 1e-9)
+	}
+}
+
+func TestTimeStretchRatio(t *testing.T) {
+	_, err := TimeStretch(toneBuffer(220, 0.1), 0)
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"stretch ratio of 0 must be positive").FindString(err.Error()))
+	_, err = TimeStretch(toneBuffer(220, 0.1), math.NaN())
+	assert.NotNil(t, err)
+}
+
+func TestPitchShift(t *testing.T) {
+	original := toneBuffer(220, 1)
+	for semitones, expected := range map[float64]float64{
+		12: 440, -12: 110, 7: 220 * math.Pow(2, 7.0/12)} {
+		shifted := PitchShift(original, semitones)
+		assert.Equal(t, original.Frames(), shifted.Frames())
+		assert.InDelta(t, expected, centrePitch(shifted).Frequency,
+			expected*0.01)
+	}
+	empty := audio.NewBuffer(audio.Format{SampleRate: 8000, Channels: 1}, 0)
+	assert.Equal(t, 0, PitchShift(empty, 3).Frames())
+}
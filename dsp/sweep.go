@@ -0,0 +1,135 @@
+package dsp
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/husafan/audio"
+)
+
+const (
+	PassbandError = "cannot measure a passband of %v Hz below a Nyquist frequency of %v Hz"
+
+	// sweepStart is the frequency of the lowest tone of the MeasureResample
+	// sweep, and sweepSteps the number of tones per octave above it.
+	sweepStart = 20
+	sweepSteps = 3
+	// aliasTones is the number of tones MeasureResample plays above its
+	// passband's mirror image when the rate falls.
+	aliasTones = 16
+	// sweepLevel is the peak level of the tones of MeasureResample, and
+	// sweepDivisor the fraction of a second each one lasts.
+	sweepLevel   = 0.5
+	sweepDivisor = 10
+)
+
+/*
+ResampleMeasurement is the result of MeasureResample. Ripple is the spread
+between the highest and lowest levels of the tones in the passband, and THDN
+the worst THD+N of any of them. Aliasing is the level of the loudest alias
+that lands in the passband, relative to the tone it came from, or Silence when
+the input holds no frequencies whose aliases could.
+*/
+type ResampleMeasurement struct {
+	Ripple   audio.Decibel `json:"ripple"`
+	THDN     audio.Decibel `json:"thdn"`
+	Aliasing audio.Decibel `json:"aliasing"`
+}
+
+/*
+MeasureResample checks the quality of settings converting audio from one sample
+rate to another, such as a custom number of taps. It resamples a stepped sweep
+of half scale sines, three to the octave from 20 Hz up to passband Hz,
+measuring each with MeasureTone. When the rate falls, sixteen more tones are
+spread from the mirror image of passband about the new Nyquist frequency up to
+the input's, whose aliases land in the passband, and the aliases measured with
+MeasureLevel. Aliases of tones between the Nyquist frequency and the mirror
+image land above passband, where the filters are allowed their transition
+band. Each tone lasts a tenth of a second, and the middle half of it is
+measured, clear of the filter's ringing at the ends. A non-nil error is
+returned if either rate is 0, passband is not below both Nyquist frequencies,
+or Resample rejects settings.
+*/
+func MeasureResample(settings ResampleSettings, from, to uint32,
+	passband float64) (ResampleMeasurement, error) {
+	if from == 0 {
+		return ResampleMeasurement{}, fmt.Errorf(SampleRateError, from)
+	}
+	if to == 0 {
+		return ResampleMeasurement{}, fmt.Errorf(SampleRateError, to)
+	}
+	nyquist := float64(from) / 2
+	if to < from {
+		nyquist = float64(to) / 2
+	}
+	if passband <= 0 || passband >= nyquist {
+		return ResampleMeasurement{}, fmt.Errorf(PassbandError, passband,
+			nyquist)
+	}
+	// Each tone would report its own progress, as if it were the whole job.
+	settings.Progress = nil
+	reference := audio.LinearToDecibel(sweepLevel)
+	measurement := ResampleMeasurement{THDN: audio.Silence,
+		Aliasing: audio.Silence}
+	lowest, highest := audio.Decibel(math.Inf(1)), audio.Silence
+	var frequencies []float64
+	for step := 0.0; ; step++ {
+		frequency := sweepStart * math.Pow(2, step/sweepSteps)
+		if frequency >= passband {
+			break
+		}
+		frequencies = append(frequencies, frequency)
+	}
+	for _, frequency := range append(frequencies, passband) {
+		output, err := resampleTone(settings, from, to, frequency)
+		if err != nil {
+			return ResampleMeasurement{}, err
+		}
+		tone := MeasureTone(output, float64(to), frequency)
+		if tone.Level < lowest {
+			lowest = tone.Level
+		}
+		if tone.Level > highest {
+			highest = tone.Level
+		}
+		if thdn := tone.THDN(); thdn > measurement.THDN {
+			measurement.THDN = thdn
+		}
+	}
+	measurement.Ripple = highest - lowest
+	// The alias of a tone above the new Nyquist frequency is its mirror
+	// image about it.
+	stopband := float64(to) - passband
+	for i := 0; stopband < float64(from)/2 && i < aliasTones; i++ {
+		frequency := stopband +
+			(float64(from)/2-stopband)*float64(i)/aliasTones
+		output, err := resampleTone(settings, from, to, frequency)
+		if err != nil {
+			return ResampleMeasurement{}, err
+		}
+		if alias := MeasureLevel(output) - reference; alias >
+			measurement.Aliasing {
+			measurement.Aliasing = alias
+		}
+	}
+	return measurement, nil
+}
+
+/*
+resampleTone resamples a tenth of a second of a sine of frequency from one rate
+to the other with settings, and returns the middle half of the output.
+*/
+func resampleTone(settings ResampleSettings, from, to uint32,
+	frequency float64) ([]float64, error) {
+	buffer := audio.NewBuffer(audio.Format{SampleRate: from, Channels: 1},
+		int(from)/sweepDivisor)
+	for i := range buffer.Data {
+		buffer.Data[i] = sweepLevel *
+			math.Sin(2*math.Pi*frequency*float64(i)/float64(from))
+	}
+	output, err := Resample(buffer, to, settings)
+	if err != nil {
+		return nil, err
+	}
+	return output.Data[output.Frames()/4 : output.Frames()*3/4], nil
+}
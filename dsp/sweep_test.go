@@ -0,0 +1,64 @@
+package dsp_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/husafan/audio"
+	. "github.com/husafan/audio/dsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeasureResample(t *testing.T) {
+	// The polyphase tier is flat to 16 kHz, while the linear tier droops
+	// and distorts well before.
+	measurement, err := MeasureResample(
+		ResampleSettings{Quality: ResamplePolyphase}, 44100, 48000, 16000)
+	assert.Nil(t, err)
+	assert.Less(t, measurement.Ripple, audio.Decibel(0.1))
+	assert.Less(t, measurement.THDN, audio.Decibel(-95))
+	assert.Equal(t, audio.Silence, measurement.Aliasing)
+	measurement, err = MeasureResample(
+		ResampleSettings{Quality: ResampleLinear}, 44100, 48000, 16000)
+	assert.Nil(t, err)
+	assert.Greater(t, measurement.Ripple, audio.Decibel(3))
+	assert.Greater(t, measurement.THDN, audio.Decibel(-20))
+
+	// Going down from 96 kHz, the tones above 26.1 kHz alias into an 18 kHz
+	// passband, which the filter keeps out and linear interpolation does
+	// not.
+	measurement, err = MeasureResample(
+		ResampleSettings{Quality: ResamplePolyphase}, 96000, 44100, 18000)
+	assert.Nil(t, err)
+	assert.Less(t, measurement.Aliasing, audio.Decibel(-90))
+	measurement, err = MeasureResample(
+		ResampleSettings{Quality: ResampleLinear}, 96000, 44100, 18000)
+	assert.Nil(t, err)
+	assert.Greater(t, measurement.Aliasing, audio.Decibel(-10))
+
+	// Too few taps filter out the top of the passband.
+	measurement, err = MeasureResample(
+		ResampleSettings{Quality: ResampleSinc, Taps: 16}, 48000, 32000, 14000)
+	assert.Nil(t, err)
+	assert.Greater(t, measurement.Ripple, audio.Decibel(10))
+	assert.Less(t, measurement.Aliasing, audio.Decibel(-90))
+}
+
+func TestMeasureResampleErrors(t *testing.T) {
+	settings := ResampleSettings{}
+	_, err := MeasureResample(settings, 0, 44100, 1000)
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"sample rate of 0 Hz").FindString(err.Error()))
+	_, err = MeasureResample(settings, 44100, 0, 1000)
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"sample rate of 0 Hz").FindString(err.Error()))
+	for _, passband := range []float64{0, 16000, 30000} {
+		_, err = MeasureResample(settings, 48000, 32000, passband)
+		assert.NotEqual(t, "", regexp.MustCompile(
+			"Nyquist frequency of 16000 Hz").FindString(err.Error()))
+	}
+	_, err = MeasureResample(ResampleSettings{
+		Quality: ResampleSinc, Taps: 3}, 48000, 32000, 14000)
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"even number of taps").FindString(err.Error()))
+}
@@ -0,0 +1,63 @@
+package dsp
+
+/*
+ABSwitch crossfades between two Processors, A and B, so that processing chains
+can be compared while audio plays without clicks. A nil Processor passes audio
+through unchanged. Both Processors are run on every frame, even when not heard,
+so that their state follows the input and switching back does not click.
+*/
+type ABSwitch struct {
+	a, b Processor
+	// position ramps from 0 when A is heard to 1 when B is heard.
+	position *Smoother
+	scratch  []float64
+}
+
+/*
+NewABSwitch returns an ABSwitch that starts with A selected. Switching
+crossfades with a time constant of milliseconds at the given sample rate.
+*/
+func NewABSwitch(a, b Processor, milliseconds, sampleRate float64) *ABSwitch {
+	return &ABSwitch{
+		a: a, b: b, position: NewSmoother(0, milliseconds, sampleRate)}
+}
+
+/*
+NewBypass returns an ABSwitch that can take processor out of the signal path.
+Selecting B bypasses it.
+*/
+func NewBypass(
+	processor Processor, milliseconds, sampleRate float64) *ABSwitch {
+	return NewABSwitch(processor, nil, milliseconds, sampleRate)
+}
+
+// Select crossfades to B if b is true, and to A otherwise.
+func (s *ABSwitch) Select(b bool) {
+	if b {
+		s.position.SetTarget(1)
+	} else {
+		s.position.SetTarget(0)
+	}
+}
+
+// SelectedB reports whether B is selected, even if the crossfade is not over.
+func (s *ABSwitch) SelectedB() bool {
+	return s.position.Target() == 1
+}
+
+// Process runs both Processors on frame and mixes their outputs.
+func (s *ABSwitch) Process(frame []float64) {
+	s.scratch = append(s.scratch[:0], frame...)
+	if s.a != nil {
+		s.a.Process(frame)
+	}
+	if s.b != nil {
+		s.b.Process(s.scratch)
+	}
+	// The outputs are usually correlated, so a linear crossfade keeps the
+	// level steady.
+	position := s.position.Next()
+	for i := range frame {
+		frame[i] = (1-position)*frame[i] + position*s.scratch[i]
+	}
+}
@@ -0,0 +1,57 @@
+package dsp_test
+
+import (
+	"testing"
+
+	. "github.com/husafan/audio/dsp"
+	"github.com/stretchr/testify/assert"
+)
+
+// scale is a Processor that multiplies every channel by a constant.
+type scale float64
+
+func (s scale) Process(frame []float64) {
+	for i := range frame {
+		frame[i] *= float64(s)
+	}
+}
+
+func TestABSwitch(t *testing.T) {
+	ab := NewABSwitch(scale(2), scale(-1), 0, 1000)
+	assert.False(t, ab.SelectedB())
+	frame := []float64{0.25, -0.5}
+	ab.Process(frame)
+	assert.Equal(t, []float64{0.5, -1}, frame)
+
+	// Without a crossfade the switch is immediate.
+	ab.Select(true)
+	assert.True(t, ab.SelectedB())
+	frame = []float64{0.25, -0.5}
+	ab.Process(frame)
+	assert.Equal(t, []float64{-0.25, 0.5}, frame)
+}
+
+func TestBypassCrossfades(t *testing.T) {
+	bypass := NewBypass(scale(0), 10, 1000)
+	frame := []float64{1}
+	bypass.Process(frame)
+	assert.Equal(t, []float64{0}, frame)
+
+	// The dry signal fades in over the crossfade rather than jumping.
+	bypass.Select(true)
+	previous := 0.0
+	for i := 0; i < 10; i++ {
+		frame := []float64{1}
+		bypass.Process(frame)
+		assert.True(t, frame[0] > previous && frame[0] < 1)
+		previous = frame[0]
+	}
+	for i := 0; i < 1000; i++ {
+		frame = []float64{1}
+		bypass.Process(frame)
+	}
+	assert.Equal(t, []float64{1}, frame)
+
+	bypass.Select(false)
+	assert.False(t, bypass.SelectedB())
+}
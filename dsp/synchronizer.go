@@ -0,0 +1,213 @@
+package dsp
+
+import (
+	"math"
+
+	"github.com/husafan/audio"
+)
+
+const (
+	// The following are used for SynchronizerSettings fields left at 0.
+	defaultSyncLatency  = 20
+	defaultSyncDrift    = 1000
+	defaultSyncResponse = 2000
+	// syncBuffering is the capacity of a Synchronizer's ring in multiples of
+	// its target latency, leaving room for bursts from the writer.
+	syncBuffering = 4
+)
+
+/*
+SynchronizerSettings configures a Synchronizer. Latency is the number of
+milliseconds of audio it aims to hold, 0 meaning 20, which must cover the
+largest blocks either side moves at once. MaxDrift is the largest difference
+between the two clocks it corrects, in parts per million, 0 meaning 1000.
+Response is the time in milliseconds over which it follows a change in the
+difference, 0 meaning 2000: a slower response hides the jitter of block sized
+transfers from the playback rate, and a faster one settles sooner.
+*/
+type SynchronizerSettings struct {
+	Latency  float64 `json:"latency"`
+	MaxDrift float64 `json:"max_drift"`
+	Response float64 `json:"response"`
+}
+
+/*
+Synchronizer passes interleaved frames from one live stream to another whose
+clock runs at a slightly different rate, such as from a capture device to a
+playback device that monitors it, or between the two ends of a loopback
+measurement. The writer and reader may be different goroutines, as for a
+RingBuffer, and neither takes locks or allocates.
+
+The reader resamples by a ratio close to 1, steered by how full the buffer
+between them is: when the writer's clock is faster the buffer fills, and the
+reader consumes a little faster until it drains back to the target latency.
+The ratio moves slowly enough to be inaudible, and the frames are interpolated
+with a cubic that is flat to well beyond 10 kHz at 44.1 kHz. Until the buffer
+first fills to its target, and again after it runs dry, Read plays silence.
+*/
+type Synchronizer struct {
+	ring       *audio.RingBuffer
+	channels   int
+	target     float64
+	maxDrift   float64
+	gain       float64
+	smoothing  float64
+	sampleRate float64
+	// The following belong to the reader. occupancy is the smoothed number
+	// of frames waiting, and history the four frames around the position
+	// being read, which is fraction of the way from the second to the third.
+	occupancy float64
+	ratio     float64
+	history   []float64
+	fraction  float64
+	primed    bool
+	underruns int
+}
+
+/*
+NewSynchronizer returns an empty Synchronizer for frames of the given channels
+at the nominal sample rate of both streams.
+*/
+func NewSynchronizer(settings SynchronizerSettings, channels int,
+	sampleRate float64) *Synchronizer {
+	if settings.Latency <= 0 {
+		settings.Latency = defaultSyncLatency
+	}
+	if settings.MaxDrift <= 0 {
+		settings.MaxDrift = defaultSyncDrift
+	}
+	if settings.Response <= 0 {
+		settings.Response = defaultSyncResponse
+	}
+	target := math.Ceil(settings.Latency * sampleRate / 1000)
+	response := settings.Response / 1000
+	return &Synchronizer{
+		ring: audio.NewRingBuffer(
+			syncBuffering * int(target) * channels),
+		channels: channels,
+		target:   target,
+		maxDrift: settings.MaxDrift * 1e-6,
+		// The occupancy is smoothed with a quarter of the response time, which
+		// with this gain damps the loop critically, so the ratio settles
+		// without overshooting.
+		gain:       1 / response,
+		smoothing:  response / 4 * sampleRate,
+		sampleRate: sampleRate,
+		occupancy:  target,
+		ratio:      1,
+		history:    make([]float64, 4*channels),
+	}
+}
+
+/*
+Write queues as many whole frames of samples as there is room for and returns
+the number of samples queued. Frames that do not fit are the writer's to drop.
+Only one goroutine may write.
+*/
+func (s *Synchronizer) Write(samples []float64) int {
+	room := s.ring.Free() / s.channels * s.channels
+	if len(samples) > room {
+		samples = samples[:room]
+	}
+	return s.ring.Write(samples[:len(samples)/s.channels*s.channels])
+}
+
+/*
+Read fills samples with whole frames resampled from those written, adjusting
+the ratio once for the block. Frames it has none for are silent. Only one
+goroutine may read.
+*/
+func (s *Synchronizer) Read(samples []float64) {
+	frames := len(samples) / s.channels
+	waiting := float64(s.ring.Len() / s.channels)
+	if !s.primed {
+		if waiting < s.target {
+			silence(samples)
+			return
+		}
+		// Three frames are taken into the history, so the first frame read
+		// is the first frame written.
+		s.primed = true
+		s.occupancy = waiting
+		s.fraction = 3
+	}
+	// The occupancy is smoothed over the block, then the ratio set in
+	// proportion to how far it is from the target in seconds.
+	s.occupancy += (waiting - s.occupancy) *
+		(1 - math.Exp(-float64(frames)/s.smoothing))
+	drift := s.gain * (s.occupancy - s.target) / s.sampleRate
+	s.ratio = 1 + math.Max(-s.maxDrift, math.Min(s.maxDrift, drift))
+
+	for i := 0; i < frames; i++ {
+		for s.fraction >= 1 {
+			if !s.advance() {
+				s.primed = false
+				s.underruns++
+				silence(samples[i*s.channels:])
+				return
+			}
+			s.fraction--
+		}
+		s.interpolate(samples[i*s.channels : (i+1)*s.channels])
+		s.fraction += s.ratio
+	}
+}
+
+/*
+advance moves the history on by a frame from the ring, and reports false if
+the ring is empty.
+*/
+func (s *Synchronizer) advance() bool {
+	copy(s.history, s.history[s.channels:])
+	return s.ring.Read(s.history[3*s.channels:]) == s.channels
+}
+
+/*
+interpolate sets frame to the position between the second and third frames of
+the history, with a Catmull-Rom spline through all four.
+*/
+func (s *Synchronizer) interpolate(frame []float64) {
+	t := s.fraction
+	h := s.history
+	c := s.channels
+	for channel := range frame {
+		p0, p1 := h[channel], h[c+channel]
+		p2, p3 := h[2*c+channel], h[3*c+channel]
+		frame[channel] = p1 + 0.5*t*(p2-p0+t*(2*p0-5*p1+4*p2-p3+
+			t*(3*(p1-p2)+p3-p0)))
+	}
+}
+
+// silence sets samples to 0.
+func silence(samples []float64) {
+	for i := range samples {
+		samples[i] = 0
+	}
+}
+
+/*
+Ratio returns the number of written frames the reader consumes for each frame
+it reads. It settles around the ratio of the writer's clock to the reader's,
+wandering by a few hundred parts per million as the block boundaries of the
+two sides slide past each other. Only the reader may call it.
+*/
+func (s *Synchronizer) Ratio() float64 {
+	return s.ratio
+}
+
+/*
+Latency returns the smoothed delay in milliseconds between a frame being
+written and being read. Only the reader may call it.
+*/
+func (s *Synchronizer) Latency() float64 {
+	return (s.occupancy + 2 - s.fraction) * 1000 / s.sampleRate
+}
+
+/*
+Underruns returns the number of times the reader has run out of frames and
+played silence while it waited for the target latency to build up again. Only
+the reader may call it.
+*/
+func (s *Synchronizer) Underruns() int {
+	return s.underruns
+}
@@ -0,0 +1,92 @@
+package dsp_test
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/husafan/audio/dsp"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+drift streams seconds of a stereo sine through synchronizer, written in
+blocks of 320 frames by a clock running ratio times as fast as the reader's,
+which reads blocks of 256, and returns the output.
+*/
+func drift(synchronizer *Synchronizer, ratio, seconds float64) []float64 {
+	const rate = 48000
+	var output, block []float64
+	written := 0
+	for read := 0; float64(read) < seconds*rate; read += 256 {
+		for float64(written) < float64(read+256)*ratio {
+			block = block[:0]
+			for i := written; i < written+320; i++ {
+				value := 0.5 * math.Sin(2*math.Pi*500*float64(i)/rate)
+				block = append(block, value, -value)
+			}
+			synchronizer.Write(block)
+			written += 320
+		}
+		frames := make([]float64, 512)
+		synchronizer.Read(frames)
+		output = append(output, frames...)
+	}
+	return output
+}
+
+// smooth reports whether the left channel of output never jumps further than
+// a 500 Hz sine at 48 kHz can, apart from at its start.
+func smooth(output []float64) bool {
+	start := 2
+	for start < len(output) && output[start] == 0 {
+		start += 2
+	}
+	limit := 0.5 * 2 * math.Pi * 500 / 48000 * 1.01
+	for i := start + 2; i < len(output); i += 2 {
+		if math.Abs(output[i]-output[i-2]) > limit ||
+			output[i+1] != -output[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSynchronizer(t *testing.T) {
+	for _, ratio := range []float64{1.0005, 1, 0.9995} {
+		synchronizer := NewSynchronizer(SynchronizerSettings{}, 2, 48000)
+		// Uncorrected, the drift would move the latency by 30 ms.
+		output := drift(synchronizer, ratio, 60)
+		assert.InDelta(t, ratio, synchronizer.Ratio(), 3e-4)
+		assert.InDelta(t, 20, synchronizer.Latency(), 2)
+		assert.Equal(t, 0, synchronizer.Underruns())
+		assert.True(t, smooth(output))
+		// Nothing is played until the target latency has built up.
+		assert.Equal(t, 0.0, output[0])
+	}
+}
+
+func TestSynchronizerUnderrun(t *testing.T) {
+	synchronizer := NewSynchronizer(SynchronizerSettings{Latency: 10}, 1, 8000)
+	synchronizer.Write([]float64{1, 2, 3, 4})
+	frames := make([]float64, 4)
+	synchronizer.Read(frames)
+	assert.Equal(t, []float64{0, 0, 0, 0}, frames)
+
+	// Once the target latency builds up, frames are read in order. The
+	// interpolation looks two frames ahead, so the last two are held back
+	// when the rest of the block runs out and is silent.
+	ramp := make([]float64, 76)
+	for i := range ramp {
+		ramp[i] = float64(i + 5)
+	}
+	assert.Equal(t, 76, synchronizer.Write(ramp))
+	frames = make([]float64, 100)
+	synchronizer.Read(frames)
+	assert.InDeltaSlice(t, []float64{1, 2, 3}, frames[:3], 1e-9)
+	assert.InDelta(t, 78, frames[77], 1e-9)
+	assert.Equal(t, make([]float64, 22), frames[78:])
+	assert.Equal(t, 1, synchronizer.Underruns())
+
+	// Writes stop when the buffer is full.
+	assert.Equal(t, 512, synchronizer.Write(make([]float64, 1000)))
+}
@@ -0,0 +1,77 @@
+package dsp
+
+import (
+	"math"
+
+	"github.com/husafan/audio"
+)
+
+/*
+Tone is the result of MeasureTone. Level is the peak level of the sine found,
+relative to full scale, and Residual the level of everything else in the
+window, distortion, noise and aliases, as the peak level of a sine of the same
+power.
+*/
+type Tone struct {
+	Level    audio.Decibel
+	Residual audio.Decibel
+}
+
+/*
+THDN returns the total harmonic distortion plus noise of the Tone, the level of
+its Residual relative to that of the sine.
+*/
+func (t Tone) THDN() audio.Decibel {
+	return t.Residual - t.Level
+}
+
+/*
+MeasureTone finds the sine of the given frequency in a window of mono samples
+at the given sample rate by least squares, fitting its amplitude and phase, and
+measures what remains. The window should hold many periods of the frequency,
+and leave out any fade in or out at the ends of a signal, such as the silence a
+filter rings into. A window without a level at the frequency, such as one of
+silence, measures Silence for the Level.
+*/
+func MeasureTone(window []float64, sampleRate, frequency float64) Tone {
+	var ss, sc, cc, ys, yc float64
+	for i, y := range window {
+		s, c := math.Sincos(2 * math.Pi * frequency * float64(i) / sampleRate)
+		ss, sc, cc, ys, yc = ss+s*s, sc+s*c, cc+c*c, ys+y*s, yc+y*c
+	}
+	// a and b are the amplitudes of the sine and cosine at the frequency,
+	// left at 0 when the window is too short, or the frequency too close to
+	// 0 or the Nyquist frequency, to tell them apart.
+	var a, b float64
+	if det := ss*cc - sc*sc; det > 1e-9*float64(len(window)) {
+		a, b = (ys*cc-yc*sc)/det, (yc*ss-ys*sc)/det
+	}
+	residual := make([]float64, len(window))
+	for i, y := range window {
+		s, c := math.Sincos(2 * math.Pi * frequency * float64(i) / sampleRate)
+		residual[i] = y - a*s - b*c
+	}
+	return Tone{
+		Level:    audio.LinearToDecibel(math.Hypot(a, b)),
+		Residual: MeasureLevel(residual),
+	}
+}
+
+/*
+MeasureLevel returns the level of a window of samples as the peak level of a
+sine of the same power, so that a full scale sine measures 0 dB, and an empty
+window or one of silence measures Silence. It is the level MeasureTone gives
+the residual, and suits measuring what leaks through a filter, such as the
+aliases a resampler lets through.
+*/
+func MeasureLevel(window []float64) audio.Decibel {
+	if len(window) == 0 {
+		return audio.Silence
+	}
+	var power float64
+	for _, value := range window {
+		power += value * value
+	}
+	// A sine of peak level 1 has a mean power of 1/2.
+	return audio.LinearToDecibel(math.Sqrt(2 * power / float64(len(window))))
+}
@@ -0,0 +1,51 @@
+package dsp_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/husafan/audio"
+	. "github.com/husafan/audio/dsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeasureTone(t *testing.T) {
+	// A pure sine measures its level, and its residual is negligible.
+	window := make([]float64, 8000)
+	for i := range window {
+		window[i] = 0.25 * math.Sin(2*math.Pi*1000*float64(i)/48000+1)
+	}
+	tone := MeasureTone(window, 48000, 1000)
+	assert.InDelta(t, -12.04, float64(tone.Level), 0.01)
+	assert.Less(t, tone.THDN(), audio.Decibel(-200))
+
+	// A second harmonic a tenth of the level is 20 dB down.
+	for i := range window {
+		window[i] += 0.025 * math.Sin(2*math.Pi*2000*float64(i)/48000)
+	}
+	tone = MeasureTone(window, 48000, 1000)
+	assert.InDelta(t, -12.04, float64(tone.Level), 0.01)
+	assert.InDelta(t, -20, float64(tone.THDN()), 0.01)
+	assert.InDelta(t, -32.04, float64(tone.Residual), 0.01)
+
+	// Silence has no level at all.
+	tone = MeasureTone(make([]float64, 100), 48000, 1000)
+	assert.Equal(t, audio.Silence, tone.Level)
+	assert.Equal(t, audio.Silence, tone.Residual)
+	assert.Equal(t, Tone{audio.Silence, audio.Silence},
+		MeasureTone(nil, 48000, 1000))
+}
+
+func TestMeasureLevel(t *testing.T) {
+	window := make([]float64, 4800)
+	for i := range window {
+		window[i] = 0.5 * math.Sin(2*math.Pi*100*float64(i)/48000)
+	}
+	assert.InDelta(t, -6.02, float64(MeasureLevel(window)), 0.01)
+	// A square wave has the power of a sine 3 dB louder.
+	for i := range window {
+		window[i] = math.Copysign(0.5, window[i])
+	}
+	assert.InDelta(t, -3.01, float64(MeasureLevel(window)), 0.01)
+	assert.Equal(t, audio.Silence, MeasureLevel(nil))
+}
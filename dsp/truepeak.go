@@ -0,0 +1,76 @@
+package dsp
+
+import "math"
+
+const (
+	// oversampling is the factor by which TruePeak oversamples.
+	oversampling = 4
+	// peakTaps is the number of input samples each interpolated value of
+	// TruePeak is computed from.
+	peakTaps = 12
+)
+
+/*
+peakFilter holds the coefficients of the 48 tap interpolation filter used by
+TruePeak, arranged as oversampling phases of peakTaps coefficients. It is a Hann
+windowed sinc low pass filter at the original Nyquist frequency, centred on a
+tap so that phase 0 reproduces the input samples. Each phase is normalised to
+unity gain at DC.
+*/
+var peakFilter = func() [oversampling][peakTaps]float64 {
+	var filter [oversampling][peakTaps]float64
+	const centre = oversampling * peakTaps / 2
+	for phase := range filter {
+		var sum float64
+		for tap := range filter[phase] {
+			t := float64(phase+oversampling*tap-centre) / oversampling
+			value := 1.0
+			if t != 0 {
+				value = math.Sin(math.Pi*t) / (math.Pi * t)
+			}
+			value *= 0.5 * (1 + math.Cos(math.Pi*t/(peakTaps/2)))
+			filter[phase][tap] = value
+			sum += value
+		}
+		for tap := range filter[phase] {
+			filter[phase][tap] /= sum
+		}
+	}
+	return filter
+}()
+
+/*
+TruePeak finds the true peak level of a single channel, following the method
+of ITU-R BS.1770 annex 2: the signal is oversampled four times, so that overs
+between samples, which clip the reconstruction filters of DACs and lossy
+encoders, are seen as well as the samples. It keeps its state between values,
+so each channel needs its own. The zero value is ready to use.
+*/
+type TruePeak struct {
+	history  [peakTaps]float64
+	position int
+}
+
+/*
+Next adds a sample and returns the largest magnitude of the oversampled signal
+from the sample Latency samples earlier up to the one after it.
+*/
+func (p *TruePeak) Next(value float64) float64 {
+	p.position = (p.position + 1) % peakTaps
+	p.history[p.position] = value
+	var peak float64
+	for phase := range peakFilter {
+		var interpolated float64
+		for tap, coefficient := range peakFilter[phase] {
+			interpolated += coefficient *
+				p.history[(p.position-tap+peakTaps)%peakTaps]
+		}
+		peak = math.Max(peak, math.Abs(interpolated))
+	}
+	return peak
+}
+
+// Latency returns the number of samples by which Next trails its input.
+func (p *TruePeak) Latency() int {
+	return peakTaps / 2
+}
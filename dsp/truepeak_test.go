@@ -0,0 +1,31 @@
+package dsp_test
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/husafan/audio/dsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruePeak(t *testing.T) {
+	// A sine at a quarter of the sample rate, sampled 45 degrees from its
+	// peaks, has samples at 0.707 but a true peak of 1.
+	var peak TruePeak
+	assert.Equal(t, 6, peak.Latency())
+	var level float64
+	for i := 0; i < 200; i++ {
+		value := math.Sin(math.Pi/2*float64(i) + math.Pi/4)
+		assert.InDelta(t, math.Sqrt(0.5), math.Abs(value), 1e-9)
+		level = math.Max(level, peak.Next(value))
+	}
+	assert.InDelta(t, 1, level, 0.02)
+
+	// An impulse comes out Latency samples later.
+	peak = TruePeak{}
+	assert.True(t, peak.Next(1) < 1)
+	for i := 1; i < 6; i++ {
+		assert.True(t, peak.Next(0) < 1)
+	}
+	assert.Equal(t, 1.0, peak.Next(0))
+}
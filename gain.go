@@ -0,0 +1,35 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+)
+
+/*
+Decibel is a gain or level in decibels. It is used wherever the packages take a
+gain, so a value can never be mistaken for a linear factor: 0 dB leaves a signal
+unchanged, -6 dB roughly halves it and Silence mutes it. Levels are relative to
+full scale (dBFS).
+*/
+type Decibel float64
+
+// Silence is the gain that mutes a signal, and the level of digital silence.
+var Silence = Decibel(math.Inf(-1))
+
+/*
+LinearToDecibel converts a linear gain or level to decibels. Magnitudes are
+used, so a gain of -0.5 is the same -6.02 dB as 0.5, and 0 is Silence.
+*/
+func LinearToDecibel(linear float64) Decibel {
+	return Decibel(20 * math.Log10(math.Abs(linear)))
+}
+
+// Linear converts the gain to a linear factor, which is 0 for Silence.
+func (d Decibel) Linear() float64 {
+	return math.Pow(10, float64(d)/20)
+}
+
+// String formats the gain with two decimal places, such as "-6.02 dB".
+func (d Decibel) String() string {
+	return fmt.Sprintf("%.2f dB", float64(d))
+}
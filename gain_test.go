@@ -0,0 +1,26 @@
+package audio_test
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/husafan/audio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecibel(t *testing.T) {
+	assert.Equal(t, 1.0, Decibel(0).Linear())
+	assert.InDelta(t, 0.1, Decibel(-20).Linear(), 1e-12)
+	assert.InDelta(t, 2, Decibel(6.0206).Linear(), 1e-4)
+	assert.Equal(t, 0.0, Silence.Linear())
+
+	assert.Equal(t, Decibel(0), LinearToDecibel(1))
+	assert.InDelta(t, -6.0206, float64(LinearToDecibel(0.5)), 1e-4)
+	assert.Equal(t, LinearToDecibel(0.5), LinearToDecibel(-0.5))
+	assert.Equal(t, Silence, LinearToDecibel(0))
+	assert.InDelta(t, 0.25, LinearToDecibel(0.25).Linear(), 1e-12)
+
+	assert.Equal(t, "-6.02 dB", LinearToDecibel(0.5).String())
+	assert.Equal(t, "-Inf dB", Silence.String())
+	assert.True(t, math.IsInf(float64(Silence), -1))
+}
@@ -0,0 +1,454 @@
+/*
+The id3 package parses ID3v2 tags, the metadata format used by MP3 files and
+embedded in the id3 chunk of some WAV files. Versions 2.3 and 2.4 are supported.
+More information on the format can be found here:
+
+ID3v2.3: http://id3.org/id3v2.3.0
+ID3v2.4: http://id3.org/id3v2.4.0-structure
+*/
+package id3
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	FrameSizeError    = "frame %s of %v bytes exceeds the %v bytes remaining in the tag"
+	HeaderError       = "invalid ID3 identifier %q; should be 'ID3'"
+	InflateLimitError = "frame %s inflates to more than %v bytes"
+	InflateSizeError  = "frame %s inflates to %v bytes rather than the %v it declares"
+	SizeError         = "tag of %v bytes exceeds the %v bytes available"
+	VersionError      = "unsupported ID3 version 2.%v; only 2.3 and 2.4 are supported"
+
+	// DefaultMaxFrameSize is the largest a compressed frame may inflate to
+	// unless Options say otherwise.
+	DefaultMaxFrameSize = 16 << 20
+
+	// The following IDs are the frames with accessors on Tag.
+	Album   = "TALB"
+	Artist  = "TPE1"
+	Picture = "APIC"
+	Title   = "TIT2"
+
+	// headerSize is the size of the tag header and of each frame header.
+	headerSize = 10
+
+	// The following flags are set in the tag header.
+	unsynchronisationFlag = 0x80
+	extendedHeaderFlag    = 0x40
+
+	// The following flags are set in v2.3 frame headers.
+	v3CompressionFlag = 0x0080
+	v3EncryptionFlag  = 0x0040
+	v3GroupingFlag    = 0x0020
+
+	// The following flags are set in v2.4 frame headers.
+	v4GroupingFlag          = 0x0040
+	v4CompressionFlag       = 0x0008
+	v4EncryptionFlag        = 0x0004
+	v4UnsynchronisationFlag = 0x0002
+	v4DataLengthFlag        = 0x0001
+)
+
+// The following values are the text encodings of text and picture frames.
+const (
+	EncodingISO88591 byte = iota
+	EncodingUTF16
+	EncodingUTF16BE
+	EncodingUTF8
+)
+
+/*
+Tag is a parsed ID3v2 tag. Version is the major version, 3 or 4. Frames are kept
+in the order they appear in the tag.
+*/
+type Tag struct {
+	Version  byte
+	Revision byte
+	Flags    byte
+	Frames   []Frame
+}
+
+/*
+Frame is a single frame of a Tag. Data holds the frame's contents with any
+unsynchronisation, compression and data length indicator removed. Encrypted
+frames are kept as they were found.
+*/
+type Frame struct {
+	Id    string
+	Flags uint16
+	Data  []byte
+}
+
+/*
+Image is the content of an attached picture (APIC) frame. PictureType is the
+type of picture defined by the ID3 specification, such as 3 for a front cover.
+*/
+type Image struct {
+	MIMEType    string
+	PictureType byte
+	Description string
+	Data        []byte
+}
+
+/*
+Options bounds the tags ReadWithOptions and ParseWithOptions are willing to
+accept. MaxFrameSize limits the size a compressed frame may inflate to, so that
+a few bytes of a crafted tag cannot exhaust memory, and defaults to
+DefaultMaxFrameSize when 0.
+*/
+type Options struct {
+	MaxFrameSize int
+}
+
+/*
+Read reads an ID3v2 tag from the start of reader. Only the bytes of the tag are
+consumed, so reader is left at the audio that follows.
+*/
+func Read(reader io.Reader) (*Tag, error) {
+	return ReadWithOptions(reader, Options{})
+}
+
+// ReadWithOptions reads a tag like Read, enforcing the limits in options.
+func ReadWithOptions(reader io.Reader, options Options) (*Tag, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	// The tag is read as it arrives rather than allocated up front, so a
+	// corrupt size cannot exhaust memory before reader runs out.
+	size := int64(syncsafe(header[6:10]))
+	body, err := io.ReadAll(io.LimitReader(reader, size))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) < size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return ParseWithOptions(append(header, body...), options)
+}
+
+/*
+Parse parses the ID3v2 tag at the start of data. A non-nil error is returned if
+data does not start with a v2.3 or v2.4 tag or the tag is malformed.
+*/
+func Parse(data []byte) (*Tag, error) {
+	return ParseWithOptions(data, Options{})
+}
+
+/*
+ParseWithOptions parses a tag like Parse, enforcing the limits in options. A
+compressed frame that inflates to more than options.MaxFrameSize, or to other
+than the size it declares, is an error.
+*/
+func ParseWithOptions(data []byte, options Options) (*Tag, error) {
+	if options.MaxFrameSize == 0 {
+		options.MaxFrameSize = DefaultMaxFrameSize
+	}
+	if len(data) < headerSize {
+		return nil, fmt.Errorf(SizeError, headerSize, len(data))
+	}
+	if id := string(data[:3]); id != "ID3" {
+		return nil, fmt.Errorf(HeaderError, id)
+	}
+	tag := &Tag{Version: data[3], Revision: data[4], Flags: data[5]}
+	if tag.Version != 3 && tag.Version != 4 {
+		return nil, fmt.Errorf(VersionError, tag.Version)
+	}
+	size := int(syncsafe(data[6:10]))
+	if size > len(data)-headerSize {
+		return nil, fmt.Errorf(SizeError, size, len(data)-headerSize)
+	}
+	body := data[headerSize : headerSize+size]
+	// Version 2.3 unsynchronises the whole tag, while 2.4 marks each frame.
+	if tag.Version == 3 && tag.Flags&unsynchronisationFlag != 0 {
+		body = resynchronise(body)
+	}
+	if tag.Flags&extendedHeaderFlag != 0 {
+		if len(body) < 4 {
+			return nil, fmt.Errorf(SizeError, 4, len(body))
+		}
+		// The v2.3 size excludes itself, while the v2.4 size does not.
+		extended := int(syncsafe(body[:4]))
+		if tag.Version == 3 {
+			extended = 4 + int(binary.BigEndian.Uint32(body[:4]))
+		}
+		if extended > len(body) {
+			return nil, fmt.Errorf(SizeError, extended, len(body))
+		}
+		body = body[extended:]
+	}
+
+	for len(body) >= headerSize && body[0] != 0 {
+		frame, size, err := tag.parseFrame(body, options.MaxFrameSize)
+		if err != nil {
+			return nil, err
+		}
+		tag.Frames = append(tag.Frames, frame)
+		body = body[size:]
+	}
+	return tag, nil
+}
+
+/*
+parseFrame parses the frame at the start of data, returning it along with the
+number of bytes it occupies. A compressed frame may inflate to at most limit
+bytes.
+*/
+func (t *Tag) parseFrame(data []byte, limit int) (Frame, int, error) {
+	frame := Frame{
+		Id:    string(data[:4]),
+		Flags: binary.BigEndian.Uint16(data[8:10]),
+	}
+	size := int(binary.BigEndian.Uint32(data[4:8]))
+	if t.Version == 4 {
+		size = int(syncsafe(data[4:8]))
+	}
+	if size > len(data)-headerSize {
+		return frame, 0, fmt.Errorf(
+			FrameSizeError, frame.Id, size, len(data)-headerSize)
+	}
+	content := data[headerSize : headerSize+size]
+
+	var compressed, encrypted bool
+	// inflated is the decompressed size a frame declares, or -1.
+	inflated := -1
+	if t.Version == 3 {
+		compressed = frame.Flags&v3CompressionFlag != 0
+		encrypted = frame.Flags&v3EncryptionFlag != 0
+		// Compressed frames are preceded by their decompressed size.
+		if compressed && len(content) >= 4 {
+			inflated = int(binary.BigEndian.Uint32(content[:4]))
+		}
+		if compressed {
+			content = skipPrefix(content, 4)
+		}
+		if encrypted {
+			content = skipPrefix(content, 1)
+		}
+		if frame.Flags&v3GroupingFlag != 0 {
+			content = skipPrefix(content, 1)
+		}
+	} else {
+		compressed = frame.Flags&v4CompressionFlag != 0
+		encrypted = frame.Flags&v4EncryptionFlag != 0
+		if frame.Flags&v4GroupingFlag != 0 {
+			content = skipPrefix(content, 1)
+		}
+		if encrypted {
+			content = skipPrefix(content, 1)
+		}
+		if frame.Flags&v4DataLengthFlag != 0 && len(content) >= 4 {
+			inflated = int(syncsafe(content[:4]))
+		}
+		if frame.Flags&v4DataLengthFlag != 0 {
+			content = skipPrefix(content, 4)
+		}
+		if frame.Flags&v4UnsynchronisationFlag != 0 {
+			content = resynchronise(content)
+		}
+	}
+	if compressed && !encrypted {
+		var err error
+		content, err = inflate(frame.Id, content, inflated, limit)
+		if err != nil {
+			return frame, 0, err
+		}
+	}
+	frame.Data = append([]byte{}, content...)
+	return frame, headerSize + size, nil
+}
+
+// Frame returns the first frame with the given ID, or nil if there is none.
+func (t *Tag) Frame(id string) *Frame {
+	for i := range t.Frames {
+		if t.Frames[i].Id == id {
+			return &t.Frames[i]
+		}
+	}
+	return nil
+}
+
+/*
+Text returns the text of the first text information frame with the given ID,
+or an empty string if there is none. The multiple values allowed by v2.4 are
+joined with "/", the separator used by v2.3.
+*/
+func (t *Tag) Text(id string) string {
+	frame := t.Frame(id)
+	if frame == nil || len(frame.Data) == 0 {
+		return ""
+	}
+	return strings.Join(splitText(frame.Data[0], frame.Data[1:]), "/")
+}
+
+// Title returns the title of the recording (TIT2).
+func (t *Tag) Title() string {
+	return t.Text(Title)
+}
+
+// Artist returns the lead artist of the recording (TPE1).
+func (t *Tag) Artist() string {
+	return t.Text(Artist)
+}
+
+// Album returns the album the recording is from (TALB).
+func (t *Tag) Album() string {
+	return t.Text(Album)
+}
+
+/*
+Picture returns the first attached picture (APIC) frame, typically the cover
+art, or nil if there is none or it is malformed.
+*/
+func (t *Tag) Picture() *Image {
+	frame := t.Frame(Picture)
+	if frame == nil || len(frame.Data) < 1 {
+		return nil
+	}
+	encoding, data := frame.Data[0], frame.Data[1:]
+	end := bytes.IndexByte(data, 0)
+	if end < 0 || end+1 >= len(data) {
+		return nil
+	}
+	image := &Image{MIMEType: string(data[:end]), PictureType: data[end+1]}
+	data = data[end+2:]
+	description, size := terminatedText(encoding, data)
+	image.Description = description
+	image.Data = append([]byte{}, data[size:]...)
+	return image
+}
+
+/*
+splitText decodes text in the given encoding and splits it into the values
+separated by NUL characters. A trailing NUL does not add an empty value.
+*/
+func splitText(encoding byte, data []byte) []string {
+	var values []string
+	for len(data) > 0 {
+		value, size := terminatedText(encoding, data)
+		values = append(values, value)
+		data = data[size:]
+	}
+	return values
+}
+
+/*
+terminatedText decodes a NUL terminated string in the given encoding from the
+start of data, returning it along with the number of bytes consumed including
+the terminator. Text without a terminator runs to the end of data.
+*/
+func terminatedText(encoding byte, data []byte) (string, int) {
+	width := 1
+	if encoding == EncodingUTF16 || encoding == EncodingUTF16BE {
+		width = 2
+	}
+	end := len(data) - len(data)%width
+	size := end
+	for i := 0; i+width <= len(data); i += width {
+		if data[i] == 0 && (width == 1 || data[i+1] == 0) {
+			end, size = i, i+width
+			break
+		}
+	}
+	return decodeText(encoding, data[:end]), size
+}
+
+/*
+decodeText decodes data in the given encoding. UTF-16 text uses its byte order
+mark, defaulting to big endian when there is none.
+*/
+func decodeText(encoding byte, data []byte) string {
+	switch encoding {
+	case EncodingISO88591:
+		runes := make([]rune, len(data))
+		for i, value := range data {
+			runes[i] = rune(value)
+		}
+		return string(runes)
+	case EncodingUTF16, EncodingUTF16BE:
+		var order binary.ByteOrder = binary.BigEndian
+		if len(data) >= 2 && data[0] == 0xFF && data[1] == 0xFE {
+			order, data = binary.LittleEndian, data[2:]
+		} else if len(data) >= 2 && data[0] == 0xFE && data[1] == 0xFF {
+			data = data[2:]
+		}
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = order.Uint16(data[2*i:])
+		}
+		return string(utf16.Decode(units))
+	}
+	return string(data)
+}
+
+/*
+syncsafe decodes a syncsafe integer, which stores 7 bits in each byte so that it
+can never be mistaken for an MPEG frame sync.
+*/
+func syncsafe(data []byte) uint32 {
+	var value uint32
+	for _, current := range data {
+		value = value<<7 | uint32(current&0x7F)
+	}
+	return value
+}
+
+/*
+resynchronise reverses unsynchronisation, which inserts a zero byte after every
+0xFF so the tag cannot contain an MPEG frame sync.
+*/
+func resynchronise(data []byte) []byte {
+	result := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		result = append(result, data[i])
+		if data[i] == 0xFF && i+1 < len(data) && data[i+1] == 0 {
+			i++
+		}
+	}
+	return result
+}
+
+// skipPrefix returns data without its first size bytes.
+func skipPrefix(data []byte, size int) []byte {
+	if size > len(data) {
+		return nil
+	}
+	return data[size:]
+}
+
+/*
+inflate decompresses the zlib compressed contents of the frame id, which
+declare their decompressed size, or -1 when they do not. No more than limit
+bytes, or the declared size if smaller, are ever inflated.
+*/
+func inflate(id string, data []byte, size, limit int) ([]byte, error) {
+	if size > limit {
+		return nil, fmt.Errorf(InflateLimitError, id, limit)
+	}
+	if size >= 0 {
+		limit = size
+	}
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	inflated, err := io.ReadAll(io.LimitReader(reader, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(inflated) > limit {
+		return nil, fmt.Errorf(InflateLimitError, id, limit)
+	}
+	if size >= 0 && len(inflated) != size {
+		return nil, fmt.Errorf(InflateSizeError, id, len(inflated), size)
+	}
+	return inflated, nil
+}
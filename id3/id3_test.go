@@ -0,0 +1,200 @@
+package id3_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/id3"
+	"github.com/stretchr/testify/assert"
+)
+
+// syncsafe encodes value as a four byte syncsafe integer.
+func syncsafe(value int) []byte {
+	return []byte{byte(value >> 21 & 0x7F), byte(value >> 14 & 0x7F),
+		byte(value >> 7 & 0x7F), byte(value & 0x7F)}
+}
+
+// frame encodes a frame for a tag of the given major version.
+func frame(version byte, id string, flags uint16, data []byte) []byte {
+	buffer := bytes.NewBufferString(id)
+	if version == 4 {
+		buffer.Write(syncsafe(len(data)))
+	} else {
+		binary.Write(buffer, binary.BigEndian, uint32(len(data)))
+	}
+	binary.Write(buffer, binary.BigEndian, flags)
+	buffer.Write(data)
+	return buffer.Bytes()
+}
+
+// tag encodes a tag holding frames, followed by some padding.
+func tag(version, flags byte, frames ...[]byte) []byte {
+	body := bytes.Join(frames, nil)
+	body = append(body, make([]byte, 6)...)
+	buffer := bytes.NewBufferString("ID3")
+	buffer.Write([]byte{version, 0, flags})
+	buffer.Write(syncsafe(len(body)))
+	buffer.Write(body)
+	return buffer.Bytes()
+}
+
+func TestParseVersion3Text(t *testing.T) {
+	parsed, err := Parse(tag(3, 0,
+		frame(3, Title, 0, []byte("\x00Song\x00")),
+		// UTF-16 with a little endian byte order mark.
+		frame(3, Artist, 0, []byte{1, 0xFF, 0xFE, 'B', 0, 0xE9, 0, 0, 0}),
+		frame(3, Album, 0, []byte("\x00Caf\xe9"))))
+	assert.Nil(t, err)
+	assert.Equal(t, byte(3), parsed.Version)
+	assert.Equal(t, 3, len(parsed.Frames))
+	assert.Equal(t, "Song", parsed.Title())
+	assert.Equal(t, "Bé", parsed.Artist())
+	assert.Equal(t, "Café", parsed.Album())
+	assert.Equal(t, "", parsed.Text("TYER"))
+}
+
+func TestParseVersion4Text(t *testing.T) {
+	parsed, err := Parse(tag(4, 0,
+		frame(4, Artist, 0, []byte("\x03One\x00Two\x00")),
+		frame(4, Title, 0, []byte{2, 0, 'H', 0, 'i'})))
+	assert.Nil(t, err)
+	assert.Equal(t, "One/Two", parsed.Artist())
+	assert.Equal(t, "Hi", parsed.Title())
+}
+
+func TestParsePicture(t *testing.T) {
+	data := append([]byte("\x00image/png\x00\x03Cover\x00"), 0x89, 'P', 'N', 'G')
+	parsed, err := Parse(tag(3, 0, frame(3, Picture, 0, data)))
+	assert.Nil(t, err)
+	assert.Equal(t, &Image{
+		MIMEType:    "image/png",
+		PictureType: 3,
+		Description: "Cover",
+		Data:        []byte{0x89, 'P', 'N', 'G'},
+	}, parsed.Picture())
+}
+
+func TestParseUnsynchronisation(t *testing.T) {
+	// Version 2.3 unsynchronises the whole tag, so the frame takes 6 bytes
+	// although its header gives the 4 it decodes to.
+	parsed, err := Parse(tag(3, 0x80, []byte{'P', 'R', 'I', 'V', 0, 0, 0, 4,
+		0, 0, 0xFF, 0x00, 0xE0, 0xFF, 0x00, 0x00}))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0xFF, 0xE0, 0xFF, 0x00}, parsed.Frame("PRIV").Data)
+
+	// Version 2.4 marks unsynchronised frames, which here also carry a data
+	// length indicator.
+	data := append(syncsafe(2), 0xFF, 0x00, 0xE0)
+	parsed, err = Parse(tag(4, 0, frame(4, "PRIV", 0x0003, data)))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0xFF, 0xE0}, parsed.Frame("PRIV").Data)
+}
+
+func TestParseCompressedFrame(t *testing.T) {
+	compressed := new(bytes.Buffer)
+	writer := zlib.NewWriter(compressed)
+	writer.Write([]byte("\x00Compressed"))
+	writer.Close()
+	data := append([]byte{0, 0, 0, 11}, compressed.Bytes()...)
+
+	parsed, err := Parse(tag(3, 0, frame(3, Title, 0x0080, data)))
+	assert.Nil(t, err)
+	assert.Equal(t, "Compressed", parsed.Title())
+
+	// Version 2.4 declares the size in the data length indicator.
+	data = append(syncsafe(11), compressed.Bytes()...)
+	parsed, err = Parse(tag(4, 0, frame(4, Title, 0x0009, data)))
+	assert.Nil(t, err)
+	assert.Equal(t, "Compressed", parsed.Title())
+}
+
+func TestParseCompressedFrameLimits(t *testing.T) {
+	// A megabyte of zeroes compresses to about a kilobyte.
+	compressed := new(bytes.Buffer)
+	writer := zlib.NewWriter(compressed)
+	writer.Write(make([]byte, 1<<20))
+	writer.Close()
+
+	for _, test := range []struct {
+		size    int
+		max     int
+		message string
+	}{
+		{1 << 20, 1000, "frame PRIV inflates to more than 1000 bytes"},
+		{10, 0, "frame PRIV inflates to more than 10 bytes"},
+		{2 << 20, 0, "inflates to 1048576 bytes rather than the 2097152"},
+	} {
+		data := binary.BigEndian.AppendUint32(nil, uint32(test.size))
+		data = append(data, compressed.Bytes()...)
+		_, err := ParseWithOptions(tag(3, 0, frame(3, "PRIV", 0x0080, data)),
+			Options{MaxFrameSize: test.max})
+		assert.NotEqual(t, "", regexp.MustCompile(
+			test.message).FindString(err.Error()), test.message)
+	}
+	// Without a declared size, the limit still applies.
+	data := append(syncsafe(0), compressed.Bytes()...)
+	_, err := ParseWithOptions(tag(4, 0, frame(4, "PRIV", 0x0008,
+		compressed.Bytes())), Options{MaxFrameSize: 1000})
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"inflates to more than 1000 bytes").FindString(err.Error()))
+	_, err = Parse(tag(4, 0, frame(4, "PRIV", 0x0009, data)))
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"inflates to more than 0 bytes").FindString(err.Error()))
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse([]byte("TAG0000000"))
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("invalid ID3 identifier \"TAG\"")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+
+	_, err = Parse(tag(2, 0))
+	assert.NotNil(t, err)
+	re = regexp.MustCompile("unsupported ID3 version 2.2")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+
+	bad := tag(3, 0, frame(3, Title, 0, []byte("\x00Song")))
+	bad[17] = 100
+	_, err = Parse(bad)
+	assert.NotNil(t, err)
+	re = regexp.MustCompile("frame TIT2 of 100 bytes exceeds the 11 bytes")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
+
+func TestReadStopsAtEndOfTag(t *testing.T) {
+	reader := bytes.NewBuffer(tag(3, 0, frame(3, Title, 0, []byte("\x00A"))))
+	reader.WriteString("audio")
+	parsed, err := Read(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "A", parsed.Title())
+	assert.Equal(t, "audio", reader.String())
+}
+
+/*
+FuzzParse checks that arbitrary data never panics the parser, and that no frame
+it returns grows past both the size of data and the limit it was given.
+*/
+func FuzzParse(f *testing.F) {
+	compressed := new(bytes.Buffer)
+	writer := zlib.NewWriter(compressed)
+	writer.Write(make([]byte, 1<<16))
+	writer.Close()
+	data := append(syncsafe(1<<16), compressed.Bytes()...)
+	f.Add(tag(4, 0, frame(4, "PRIV", 0x0009, data)))
+	f.Add(tag(3, 0x80, frame(3, Title, 0, []byte("\x00Song"))))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		parsed, err := ParseWithOptions(data, Options{MaxFrameSize: 1 << 12})
+		if err != nil {
+			return
+		}
+		for _, frame := range parsed.Frames {
+			if len(frame.Data) > len(data) && len(frame.Data) > 1<<12 {
+				t.Errorf("frame %s holds %v bytes", frame.Id, len(frame.Data))
+			}
+		}
+	})
+}
@@ -0,0 +1,102 @@
+/*
+The kernels package holds the inner loops of real time audio work on 32 bit
+samples: converting between 16 bit integers and floats, applying gain and
+mixing. They work on interleaved slices, so a frame of any number of channels
+is simply consecutive values. On amd64 they run as SSE2 assembly, eight
+samples at a time; elsewhere, or when built with the purego tag, they run as
+plain Go. Both produce identical results.
+*/
+package kernels
+
+import "math"
+
+const (
+	// int16Scale is full scale of a 16 bit sample, as in the wav package.
+	int16Scale = 32768
+	// maxInt16 is the largest 16 bit sample as a float.
+	maxInt16 = 32767
+)
+
+/*
+Int16ToFloat32 converts the 16 bit samples of src to values between -1 and 1
+in dst, dividing by 32768. It converts as many samples as the shorter slice
+holds and returns that number, like copy.
+*/
+func Int16ToFloat32(dst []float32, src []int16) int {
+	n := len(dst)
+	if len(src) < n {
+		n = len(src)
+	}
+	int16ToFloat32(dst[:n], src[:n])
+	return n
+}
+
+/*
+Float32ToInt16 converts the values of src to 16 bit samples in dst,
+multiplying by 32768, clipping to the range of a sample and rounding to the
+nearest integer, ties to even, as the hardware does. NaN converts to -32768.
+It converts as many values as the shorter slice holds and returns that number,
+like copy.
+*/
+func Float32ToInt16(dst []int16, src []float32) int {
+	n := len(dst)
+	if len(src) < n {
+		n = len(src)
+	}
+	float32ToInt16(dst[:n], src[:n])
+	return n
+}
+
+// Scale multiplies every value of dst by gain, as a linear factor.
+func Scale(dst []float32, gain float32) {
+	scale(dst, gain)
+}
+
+/*
+Mix adds the values of src, multiplied by gain, to dst, such as to sum a track
+into a bus. It mixes as many values as the shorter slice holds and returns
+that number, like copy.
+*/
+func Mix(dst, src []float32, gain float32) int {
+	n := len(dst)
+	if len(src) < n {
+		n = len(src)
+	}
+	mix(dst[:n], src[:n], gain)
+	return n
+}
+
+// The following are the plain Go kernels, which the assembly must match.
+
+func int16ToFloat32Go(dst []float32, src []int16) {
+	for i, value := range src {
+		dst[i] = float32(value) * (1.0 / int16Scale)
+	}
+}
+
+func float32ToInt16Go(dst []int16, src []float32) {
+	for i, value := range src {
+		value *= int16Scale
+		// The comparison is false for NaN, which is clipped low.
+		if !(value > -int16Scale) {
+			value = -int16Scale
+		} else if value > maxInt16 {
+			value = maxInt16
+		}
+		dst[i] = int16(math.RoundToEven(float64(value)))
+	}
+}
+
+func scaleGo(dst []float32, gain float32) {
+	for i := range dst {
+		dst[i] *= gain
+	}
+}
+
+func mixGo(dst, src []float32, gain float32) {
+	for i, value := range src {
+		// Converting the product stops it being fused into a multiply
+		// add, which would round differently from the assembly.
+		dst[i] += float32(gain * value)
+	}
+}
@@ -0,0 +1,45 @@
+//go:build amd64 && !purego
+
+package kernels
+
+/*
+The assembly kernels handle eight samples at a time and so are passed slices
+whose length is a multiple of eight, and of equal length. The plain Go kernels
+finish the rest.
+*/
+
+//go:noescape
+func int16ToFloat32SSE2(dst []float32, src []int16)
+
+//go:noescape
+func float32ToInt16SSE2(dst []int16, src []float32)
+
+//go:noescape
+func scaleSSE2(dst []float32, gain float32)
+
+//go:noescape
+func mixSSE2(dst, src []float32, gain float32)
+
+func int16ToFloat32(dst []float32, src []int16) {
+	bulk := len(dst) &^ 7
+	int16ToFloat32SSE2(dst[:bulk], src[:bulk])
+	int16ToFloat32Go(dst[bulk:], src[bulk:])
+}
+
+func float32ToInt16(dst []int16, src []float32) {
+	bulk := len(dst) &^ 7
+	float32ToInt16SSE2(dst[:bulk], src[:bulk])
+	float32ToInt16Go(dst[bulk:], src[bulk:])
+}
+
+func scale(dst []float32, gain float32) {
+	bulk := len(dst) &^ 7
+	scaleSSE2(dst[:bulk], gain)
+	scaleGo(dst[bulk:], gain)
+}
+
+func mix(dst, src []float32, gain float32) {
+	bulk := len(dst) &^ 7
+	mixSSE2(dst[:bulk], src[:bulk], gain)
+	mixGo(dst[bulk:], src[bulk:], gain)
+}
@@ -0,0 +1,134 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// Four copies each of 1/32768, 32768, -32768 and 32767 as float32.
+DATA inverseScale<>+0(SB)/8, $0x3800000038000000
+DATA inverseScale<>+8(SB)/8, $0x3800000038000000
+GLOBL inverseScale<>(SB), RODATA|NOPTR, $16
+DATA fullScale<>+0(SB)/8, $0x4700000047000000
+DATA fullScale<>+8(SB)/8, $0x4700000047000000
+GLOBL fullScale<>(SB), RODATA|NOPTR, $16
+DATA minSample<>+0(SB)/8, $0xc7000000c7000000
+DATA minSample<>+8(SB)/8, $0xc7000000c7000000
+GLOBL minSample<>(SB), RODATA|NOPTR, $16
+DATA maxSample<>+0(SB)/8, $0x46fffe0046fffe00
+DATA maxSample<>+8(SB)/8, $0x46fffe0046fffe00
+GLOBL maxSample<>(SB), RODATA|NOPTR, $16
+
+// func int16ToFloat32SSE2(dst []float32, src []int16)
+TEXT ·int16ToFloat32SSE2(SB), NOSPLIT, $0-48
+	MOVQ dst_base+0(FP), DI
+	MOVQ src_base+24(FP), SI
+	MOVQ dst_len+8(FP), CX
+	SHRQ $3, CX
+	JZ   int16Done
+	MOVUPS inverseScale<>(SB), X7
+
+int16Loop:
+	// Each word is unpacked into both halves of a double word, and the
+	// arithmetic shift leaves it sign extended.
+	MOVOU     (SI), X0
+	MOVO      X0, X1
+	PUNPCKLWL X0, X0
+	PUNPCKHWL X1, X1
+	PSRAL     $16, X0
+	PSRAL     $16, X1
+	CVTPL2PS  X0, X0
+	CVTPL2PS  X1, X1
+	MULPS     X7, X0
+	MULPS     X7, X1
+	MOVUPS    X0, (DI)
+	MOVUPS    X1, 16(DI)
+	ADDQ      $16, SI
+	ADDQ      $32, DI
+	DECQ      CX
+	JNZ       int16Loop
+
+int16Done:
+	RET
+
+// func float32ToInt16SSE2(dst []int16, src []float32)
+TEXT ·float32ToInt16SSE2(SB), NOSPLIT, $0-48
+	MOVQ dst_base+0(FP), DI
+	MOVQ src_base+24(FP), SI
+	MOVQ dst_len+8(FP), CX
+	SHRQ $3, CX
+	JZ   floatDone
+	MOVUPS fullScale<>(SB), X5
+	MOVUPS minSample<>(SB), X6
+	MOVUPS maxSample<>(SB), X7
+
+floatLoop:
+	MOVUPS   (SI), X0
+	MOVUPS   16(SI), X1
+	MULPS    X5, X0
+	MULPS    X5, X1
+	// MAXPS returns its source operand, the minimum, for NaN.
+	MAXPS    X6, X0
+	MAXPS    X6, X1
+	MINPS    X7, X0
+	MINPS    X7, X1
+	CVTPS2PL X0, X0
+	CVTPS2PL X1, X1
+	PACKSSLW X1, X0
+	MOVOU    X0, (DI)
+	ADDQ     $32, SI
+	ADDQ     $16, DI
+	DECQ     CX
+	JNZ      floatLoop
+
+floatDone:
+	RET
+
+// func scaleSSE2(dst []float32, gain float32)
+TEXT ·scaleSSE2(SB), NOSPLIT, $0-28
+	MOVQ   dst_base+0(FP), DI
+	MOVQ   dst_len+8(FP), CX
+	SHRQ   $3, CX
+	JZ     scaleDone
+	MOVSS  gain+24(FP), X7
+	SHUFPS $0, X7, X7
+
+scaleLoop:
+	MOVUPS (DI), X0
+	MOVUPS 16(DI), X1
+	MULPS  X7, X0
+	MULPS  X7, X1
+	MOVUPS X0, (DI)
+	MOVUPS X1, 16(DI)
+	ADDQ   $32, DI
+	DECQ   CX
+	JNZ    scaleLoop
+
+scaleDone:
+	RET
+
+// func mixSSE2(dst, src []float32, gain float32)
+TEXT ·mixSSE2(SB), NOSPLIT, $0-52
+	MOVQ   dst_base+0(FP), DI
+	MOVQ   src_base+24(FP), SI
+	MOVQ   dst_len+8(FP), CX
+	SHRQ   $3, CX
+	JZ     mixDone
+	MOVSS  gain+48(FP), X7
+	SHUFPS $0, X7, X7
+
+mixLoop:
+	MOVUPS (SI), X0
+	MOVUPS 16(SI), X1
+	MULPS  X7, X0
+	MULPS  X7, X1
+	MOVUPS (DI), X2
+	MOVUPS 16(DI), X3
+	ADDPS  X2, X0
+	ADDPS  X3, X1
+	MOVUPS X0, (DI)
+	MOVUPS X1, 16(DI)
+	ADDQ   $32, SI
+	ADDQ   $32, DI
+	DECQ   CX
+	JNZ    mixLoop
+
+mixDone:
+	RET
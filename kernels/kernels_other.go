@@ -0,0 +1,19 @@
+//go:build !amd64 || purego
+
+package kernels
+
+func int16ToFloat32(dst []float32, src []int16) {
+	int16ToFloat32Go(dst, src)
+}
+
+func float32ToInt16(dst []int16, src []float32) {
+	float32ToInt16Go(dst, src)
+}
+
+func scale(dst []float32, gain float32) {
+	scaleGo(dst, gain)
+}
+
+func mix(dst, src []float32, gain float32) {
+	mixGo(dst, src, gain)
+}
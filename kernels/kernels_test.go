@@ -0,0 +1,121 @@
+package kernels_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	. "github.com/husafan/audio/kernels"
+	"github.com/stretchr/testify/assert"
+)
+
+// randomFloats returns n values spread a little beyond full scale.
+func randomFloats(n int) []float32 {
+	random := rand.New(rand.NewSource(1))
+	values := make([]float32, n)
+	for i := range values {
+		values[i] = float32(random.Float64()*2.4 - 1.2)
+	}
+	return values
+}
+
+func TestInt16ToFloat32(t *testing.T) {
+	src := []int16{0, 1, -1, 16384, -16384, 32767, -32768, 100, -100, 12345,
+		-12345, 7}
+	for n := 0; n <= len(src); n++ {
+		dst := make([]float32, n)
+		assert.Equal(t, n, Int16ToFloat32(dst, src))
+		for i := range dst {
+			assert.Equal(t, float32(float64(src[i])/32768), dst[i])
+		}
+	}
+	dst := make([]float32, 20)
+	assert.Equal(t, 12, Int16ToFloat32(dst, src))
+	assert.Equal(t, float32(-1), dst[6])
+	assert.Equal(t, float32(0), dst[12])
+}
+
+func TestFloat32ToInt16(t *testing.T) {
+	src := []float32{0, 0.5, -0.5, 1, -1, 2, -2, 1.5 / 32768, 2.5 / 32768,
+		-0.5 / 32768, float32(math.NaN()), float32(math.Inf(1)),
+		float32(math.Inf(-1)), 32767.4 / 32768, 1e-9}
+	want := []int16{0, 16384, -16384, 32767, -32768, 32767, -32768, 2, 2, 0,
+		-32768, 32767, -32768, 32767, 0}
+	for n := 0; n <= len(src); n++ {
+		dst := make([]int16, n)
+		assert.Equal(t, n, Float32ToInt16(dst, src))
+		assert.Equal(t, want[:n], dst)
+	}
+
+	// Values survive a round trip.
+	samples := make([]int16, 1001)
+	for i := range samples {
+		samples[i] = int16(i*65 - 32768)
+	}
+	floats := make([]float32, len(samples))
+	Int16ToFloat32(floats, samples)
+	back := make([]int16, len(samples))
+	Float32ToInt16(back, floats)
+	assert.Equal(t, samples, back)
+}
+
+func TestScale(t *testing.T) {
+	values := randomFloats(21)
+	want := make([]float32, len(values))
+	for i, value := range values {
+		want[i] = value * 0.3
+	}
+	Scale(values, 0.3)
+	assert.Equal(t, want, values)
+	Scale(nil, 2)
+}
+
+func TestMix(t *testing.T) {
+	src := randomFloats(37)
+	dst := randomFloats(40)
+	want := append([]float32(nil), dst...)
+	for i, value := range src {
+		want[i] += float32(-0.7 * value)
+	}
+	assert.Equal(t, 37, Mix(dst, src, -0.7))
+	assert.Equal(t, want, dst)
+	assert.Equal(t, 0, Mix(nil, src, 1))
+}
+
+// benchmarkSize is a second of stereo at 48 kHz.
+const benchmarkSize = 2 * 48000
+
+func BenchmarkInt16ToFloat32(b *testing.B) {
+	src := make([]int16, benchmarkSize)
+	dst := make([]float32, benchmarkSize)
+	b.SetBytes(benchmarkSize * 2)
+	for i := 0; i < b.N; i++ {
+		Int16ToFloat32(dst, src)
+	}
+}
+
+func BenchmarkFloat32ToInt16(b *testing.B) {
+	src := randomFloats(benchmarkSize)
+	dst := make([]int16, benchmarkSize)
+	b.SetBytes(benchmarkSize * 4)
+	for i := 0; i < b.N; i++ {
+		Float32ToInt16(dst, src)
+	}
+}
+
+func BenchmarkScale(b *testing.B) {
+	dst := randomFloats(benchmarkSize)
+	b.SetBytes(benchmarkSize * 4)
+	for i := 0; i < b.N; i++ {
+		Scale(dst, 1)
+	}
+}
+
+func BenchmarkMix(b *testing.B) {
+	src := randomFloats(benchmarkSize)
+	dst := make([]float32, benchmarkSize)
+	b.SetBytes(benchmarkSize * 4)
+	for i := 0; i < b.N; i++ {
+		Mix(dst, src, 0.5)
+	}
+}
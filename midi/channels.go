@@ -0,0 +1,88 @@
+package midi
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	ChannelRangeError = "channel %v is out of range 0-15"
+
+	// channelCount is the number of channels addressable by a status byte.
+	channelCount = 16
+)
+
+/*
+ChannelUsage returns the number of channel voice messages addressed to each
+channel in the track. Channels the track does not use are absent from the map.
+*/
+func (t *TrackChunk) ChannelUsage() map[uint8]int {
+	usage := make(map[uint8]int)
+	for i := range t.TrackEvents {
+		if event := &t.TrackEvents[i]; event.IsChannelEvent() {
+			usage[event.Channel()]++
+		}
+	}
+	return usage
+}
+
+/*
+Channels returns the channels used by the track's channel voice messages in
+ascending order.
+*/
+func (t *TrackChunk) Channels() []uint8 {
+	var channels []uint8
+	for channel := range t.ChannelUsage() {
+		channels = append(channels, channel)
+	}
+	sort.Slice(channels, func(a, b int) bool {
+		return channels[a] < channels[b]
+	})
+	return channels
+}
+
+/*
+ChannelUsage reports the channels used by each track, indexed by track. See
+TrackChunk.ChannelUsage.
+*/
+func (m *Midi) ChannelUsage() []map[uint8]int {
+	usage := make([]map[uint8]int, len(m.TrackChunks))
+	for i := range m.TrackChunks {
+		usage[i] = m.TrackChunks[i].ChannelUsage()
+	}
+	return usage
+}
+
+/*
+ReassignChannels moves every channel voice message addressed to a channel in
+mapping onto the channel it maps to. The mapping is applied simultaneously, so
+channels may be swapped, and channels absent from mapping are left alone. This
+is typically used before combining files whose parts use the same channels. A
+non-nil error is returned, and nothing changed, if mapping contains a channel
+outside 0-15.
+*/
+func (m *Midi) ReassignChannels(mapping map[uint8]uint8) error {
+	var table [channelCount]uint8
+	for channel := range table {
+		table[channel] = uint8(channel)
+	}
+	for from, to := range mapping {
+		if from >= channelCount {
+			return fmt.Errorf(ChannelRangeError, from)
+		}
+		if to >= channelCount {
+			return fmt.Errorf(ChannelRangeError, to)
+		}
+		table[from] = to
+	}
+	for i := range m.TrackChunks {
+		events := m.TrackChunks[i].TrackEvents
+		for j := range events {
+			if event := &events[j]; event.IsChannelEvent() {
+				event.Data[0] =
+					event.Status()&highOrderMask | table[event.Channel()]
+			}
+		}
+	}
+	return nil
+}
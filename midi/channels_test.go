@@ -0,0 +1,54 @@
+package midi_test
+
+import (
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+// channelMidi returns a Midi with two tracks using channels 0, 1 and 9.
+func channelMidi() *Midi {
+	midi := newTrackMidi(
+		TrackEvent{0, []byte{0xFF, 0x03, 'A'}},
+		TrackEvent{0, []byte{0x90, 60, 100}},
+		TrackEvent{0, []byte{0xC1, 5}},
+		TrackEvent{10, []byte{0x80, 60, 0}},
+	)
+	midi.TrackChunks = append(midi.TrackChunks, TrackChunk{
+		TrackEvents: []TrackEvent{
+			{0, []byte{0x99, 36, 100}},
+			{10, []byte{0x89, 36, 0}},
+		},
+	})
+	return midi
+}
+
+func TestChannelUsage(t *testing.T) {
+	midi := channelMidi()
+	assert.Equal(t, []map[uint8]int{
+		{0: 2, 1: 1},
+		{9: 2},
+	}, midi.ChannelUsage())
+	assert.Equal(t, []uint8{0, 1}, midi.TrackChunks[0].Channels())
+	assert.Nil(t, (&TrackChunk{}).Channels())
+}
+
+func TestReassignChannels(t *testing.T) {
+	midi := channelMidi()
+	assert.Nil(t, midi.ReassignChannels(map[uint8]uint8{0: 1, 1: 0, 9: 15}))
+	assert.Equal(t, []map[uint8]int{
+		{1: 2, 0: 1},
+		{15: 2},
+	}, midi.ChannelUsage())
+	// Status nibbles and meta events are untouched.
+	assert.Equal(t, []byte{0x91, 60, 100}, midi.TrackChunks[0].TrackEvents[1].Data)
+	assert.Equal(t, []byte{0xFF, 0x03, 'A'}, midi.TrackChunks[0].TrackEvents[0].Data)
+
+	err := midi.ReassignChannels(map[uint8]uint8{0: 16})
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("channel 16 is out of range 0-15")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+	assert.Equal(t, []uint8{0, 1}, midi.TrackChunks[0].Channels())
+}
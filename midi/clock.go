@@ -0,0 +1,249 @@
+package midi
+
+import (
+	"io"
+	"time"
+)
+
+const (
+	// The following status bytes are the system messages used to
+	// synchronize devices.
+	SongPositionPointer = 0xF2
+	TimingClock         = 0xF8
+	StartMessage        = 0xFA
+	ContinueMessage     = 0xFB
+	StopMessage         = 0xFC
+
+	// ClocksPerQuarterNote is the rate of Timing Clock messages.
+	ClocksPerQuarterNote = 24
+
+	// clocksPerSixteenth is the number of clocks in each beat counted by a
+	// Song Position Pointer.
+	clocksPerSixteenth = ClocksPerQuarterNote / 4
+
+	// maxSongPosition is the largest 14-bit Song Position Pointer.
+	maxSongPosition = 0x3FFF
+)
+
+/*
+SetClock makes the player a MIDI clock master when enabled. Along with the
+file's events, the handler is then given Timing Clock messages, 24 to the
+quarter note, and the transport messages that keep followers in step: Start or
+a Song Position Pointer and Continue when Play begins, Stop and Continue around
+Pause and Resume, a Song Position Pointer after Seek and Stop when Play
+returns. The Song Position Pointer counts sixteenth notes, so positions between
+them are rounded down. These events have a Track of -1. Clock messages are only
+sent for files whose division counts ticks per quarter note. SetClock should be
+called before Play.
+*/
+func (p *Player) SetClock(enabled bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.clock = enabled
+	p.nextClock = p.clockIndex(p.position)
+}
+
+// clocked returns true if the file's division allows clock messages.
+func (p *Player) clocked() bool {
+	division := p.tempoMap.division
+	return p.clock && division != 0 && division&smpteMask == 0
+}
+
+/*
+upcoming returns the next event or clock message due. Clock messages precede
+events at the same time. The lock must be held.
+*/
+func (p *Player) upcoming() (PlayerEvent, bool) {
+	event := p.events[p.next]
+	if p.clocked() {
+		clock := p.clockEvent(p.nextClock)
+		if clock.Time <= event.Time {
+			return clock, true
+		}
+	}
+	return event, false
+}
+
+/*
+clockEvent returns clock message index, which falls index / 24 quarter notes
+into the file. Its time is interpolated when it falls between ticks.
+*/
+func (p *Player) clockEvent(index uint64) PlayerEvent {
+	scaled := index * uint64(p.tempoMap.division)
+	tick := scaled / ClocksPerQuarterNote
+	start := p.tempoMap.Time(tick)
+	end := p.tempoMap.Time(tick + 1)
+	remainder := time.Duration(scaled % ClocksPerQuarterNote)
+	return PlayerEvent{
+		Track: -1,
+		Tick:  tick,
+		Time:  start + (end-start)*remainder/ClocksPerQuarterNote,
+		Event: TrackEvent{Data: []byte{TimingClock}},
+	}
+}
+
+// clockIndex returns the index of the first clock message at or after d.
+func (p *Player) clockIndex(d time.Duration) uint64 {
+	if !p.clocked() {
+		return 0
+	}
+	index := p.tempoMap.Tick(d) * ClocksPerQuarterNote /
+		uint64(p.tempoMap.division)
+	for p.clockEvent(index).Time < d {
+		index++
+	}
+	return index
+}
+
+/*
+queueStart queues the transport messages that begin playback. The lock must be
+held.
+*/
+func (p *Player) queueStart() {
+	switch {
+	case p.paused:
+		p.queueSongPosition()
+	case p.position == 0:
+		p.queue(StartMessage)
+	default:
+		p.queueSongPosition()
+		p.queue(ContinueMessage)
+	}
+}
+
+// queue queues a transport message for Play to send. The lock must be held.
+func (p *Player) queue(status byte) {
+	p.transport = append(p.transport, p.transportEvent(status))
+}
+
+/*
+queueSongPosition queues a Song Position Pointer for the next clock message.
+The lock must be held.
+*/
+func (p *Player) queueSongPosition() {
+	position := p.nextClock / clocksPerSixteenth
+	if position > maxSongPosition {
+		position = maxSongPosition
+	}
+	event := p.transportEvent(SongPositionPointer)
+	event.Event.Data = append(event.Event.Data,
+		byte(position&sevenBitMask), byte(position>>7))
+	p.transport = append(p.transport, event)
+}
+
+// transportEvent returns a message sent at the current position.
+func (p *Player) transportEvent(status byte) PlayerEvent {
+	now := p.now()
+	return PlayerEvent{
+		Track: -1,
+		Tick:  p.tempoMap.Tick(now),
+		Time:  now,
+		Event: TrackEvent{Data: []byte{status}},
+	}
+}
+
+/*
+ClockFollower slaves a Player to an external MIDI clock master. Start,
+Continue, Stop and Song Position Pointer messages control the player's
+transport, and the spacing of Timing Clock messages sets its tempo scale so the
+file plays at the master's tempo. The tempo is averaged over the last quarter
+note of clocks. Files with an SMPTE division follow the transport only.
+*/
+type ClockFollower struct {
+	player    *Player
+	last      time.Time
+	intervals []time.Duration
+}
+
+/*
+NewClockFollower returns a ClockFollower for player, which is paused until the
+master sends Start or Continue. Play should be running while following.
+*/
+func NewClockFollower(player *Player) *ClockFollower {
+	player.Pause()
+	return &ClockFollower{player: player}
+}
+
+// Handle passes an event received from the master to HandleAt.
+func (f *ClockFollower) Handle(event TrackEvent) {
+	f.HandleAt(event, time.Now())
+}
+
+/*
+HandleAt updates the player for an event the master sent at the given time.
+Events other than clock and transport messages are ignored.
+*/
+func (f *ClockFollower) HandleAt(event TrackEvent, at time.Time) {
+	player := f.player
+	switch event.Status() {
+	case StartMessage:
+		f.reset()
+		player.Seek(0)
+		player.Resume()
+	case ContinueMessage:
+		f.reset()
+		player.Resume()
+	case StopMessage:
+		f.reset()
+		player.Pause()
+	case SongPositionPointer:
+		if len(event.Data) != 3 {
+			return
+		}
+		sixteenths := uint64(event.Data[1]&sevenBitMask) |
+			uint64(event.Data[2]&sevenBitMask)<<7
+		tempoMap := player.tempoMap
+		tick := sixteenths * uint64(tempoMap.division) / 4
+		player.Seek(tempoMap.Time(tick))
+	case TimingClock:
+		f.clock(at)
+	}
+}
+
+// reset forgets the clock spacing measured so far.
+func (f *ClockFollower) reset() {
+	f.last = time.Time{}
+	f.intervals = f.intervals[:0]
+}
+
+// clock measures the spacing of clock messages and sets the tempo scale.
+func (f *ClockFollower) clock(at time.Time) {
+	last := f.last
+	f.last = at
+	if last.IsZero() || !at.After(last) {
+		return
+	}
+	if len(f.intervals) == ClocksPerQuarterNote {
+		f.intervals = f.intervals[1:]
+	}
+	f.intervals = append(f.intervals, at.Sub(last))
+	tempoMap := f.player.tempoMap
+	if tempoMap.division == 0 || tempoMap.division&smpteMask != 0 {
+		return
+	}
+
+	var total time.Duration
+	for _, interval := range f.intervals {
+		total += interval
+	}
+	quarter := total * ClocksPerQuarterNote / time.Duration(len(f.intervals))
+	tempo := tempoMap.Tempo(tempoMap.Tick(f.player.Position()))
+	f.player.SetTempoScale(
+		float64(time.Duration(tempo)*time.Microsecond) / float64(quarter))
+}
+
+/*
+Follow handles every event read from parser, typically the input of a device,
+until the stream ends. It returns nil at io.EOF and any other error as is.
+*/
+func (f *ClockFollower) Follow(parser *StreamParser) error {
+	for {
+		event, err := parser.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		f.Handle(event)
+	}
+}
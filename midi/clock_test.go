@@ -0,0 +1,112 @@
+package midi_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordStatuses returns a Player handler that appends each status to statuses.
+func recordStatuses(statuses *[]byte) func(PlayerEvent) error {
+	return func(event PlayerEvent) error {
+		*statuses = append(*statuses, event.Event.Status())
+		return nil
+	}
+}
+
+func TestPlayerClock(t *testing.T) {
+	var statuses []byte
+	player := NewPlayer(newPlayerMidi(), recordStatuses(&statuses))
+	player.SetClock(true)
+	player.SetTempoScale(100)
+	assert.Nil(t, player.Play(context.Background()))
+
+	// A clock every 1/24 of a quarter note from 0 to 1 second, each before
+	// the events at the same time.
+	assert.Equal(t, 1+49+3+1, len(statuses))
+	assert.Equal(t, []byte{StartMessage, TimingClock, 0x90}, statuses[:3])
+	assert.Equal(t, []byte{TimingClock, 0x91}, statuses[26:28])
+	assert.Equal(t, []byte{TimingClock, 0x80, StopMessage}, statuses[51:])
+	var clocks int
+	for _, status := range statuses {
+		if status == TimingClock {
+			clocks++
+		}
+	}
+	assert.Equal(t, 49, clocks)
+}
+
+func TestPlayerClockSongPosition(t *testing.T) {
+	var events []PlayerEvent
+	player := NewPlayer(newPlayerMidi(), func(event PlayerEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	player.SetClock(true)
+	player.SetTempoScale(100)
+	player.Seek(500 * time.Millisecond)
+	assert.Nil(t, player.Play(context.Background()))
+
+	// Half a second is one quarter note, or four sixteenths.
+	assert.Equal(t, PlayerEvent{
+		Track: -1, Tick: 96, Time: 500 * time.Millisecond,
+		Event: TrackEvent{Data: []byte{SongPositionPointer, 4, 0}},
+	}, events[0])
+	assert.Equal(t, []byte{ContinueMessage}, events[1].Event.Data)
+	assert.Equal(t, []byte{TimingClock}, events[2].Event.Data)
+	assert.Equal(t, 500*time.Millisecond, events[2].Time)
+
+	// Pausing and resuming from the handler sends Stop and Continue.
+	var statuses []byte
+	var paused bool
+	player = NewPlayer(newPlayerMidi(), func(event PlayerEvent) error {
+		statuses = append(statuses, event.Event.Status())
+		if event.Event.Status() == 0x91 && !paused {
+			paused = true
+			player.Pause()
+			player.Resume()
+		}
+		return nil
+	})
+	player.SetClock(true)
+	player.SetTempoScale(100)
+	assert.Nil(t, player.Play(context.Background()))
+	assert.Equal(t, []byte{0x91, StopMessage, ContinueMessage, TimingClock},
+		statuses[27:31])
+}
+
+func TestClockFollower(t *testing.T) {
+	player := NewPlayer(newPlayerMidi(), func(PlayerEvent) error { return nil })
+	follower := NewClockFollower(player)
+	assert.True(t, player.Paused())
+
+	start := time.Now()
+	follower.HandleAt(TrackEvent{Data: []byte{StartMessage}}, start)
+	assert.False(t, player.Paused())
+	// Clocks 10ms apart make a quarter note of 240ms, where the file's is
+	// 500ms.
+	for i := 0; i < 30; i++ {
+		follower.HandleAt(TrackEvent{Data: []byte{TimingClock}},
+			start.Add(time.Duration(i)*10*time.Millisecond))
+	}
+	assert.InDelta(t, 500.0/240, player.TempoScale(), 1e-9)
+
+	follower.HandleAt(TrackEvent{Data: []byte{StopMessage}}, start)
+	assert.True(t, player.Paused())
+	follower.HandleAt(
+		TrackEvent{Data: []byte{SongPositionPointer, 8, 0}}, start)
+	assert.Equal(t, time.Second, player.Position())
+	follower.HandleAt(TrackEvent{Data: []byte{ContinueMessage}}, start)
+	assert.False(t, player.Paused())
+
+	// Following a stream ends with it.
+	follower = NewClockFollower(player)
+	stream := bytes.NewReader([]byte{StartMessage, TimingClock, StopMessage})
+	assert.Nil(t, follower.Follow(NewStreamParser(stream)))
+	assert.True(t, player.Paused())
+	assert.Equal(t, time.Duration(0), player.Position())
+}
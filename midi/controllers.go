@@ -0,0 +1,189 @@
+package midi
+
+import "time"
+
+const (
+	// The following registered parameter numbers are defined by the MIDI
+	// spec. Their value is the 14-bit combination of the RPN MSB and LSB.
+	RPNPitchBendRange = 0x0000
+	RPNFineTuning     = 0x0001
+	RPNCoarseTuning   = 0x0002
+
+	// The first 32 controllers carry the MSB of a 14-bit value whose LSB is
+	// sent on the controller 32 higher.
+	controllerPairs = 32
+)
+
+// ControllerKind identifies what a ControllerEvent's Number refers to.
+type ControllerKind int
+
+const (
+	// Controller14Bit is a controller from 0 to 31 combined with its LSB.
+	Controller14Bit ControllerKind = iota
+	// RegisteredParameter is an RPN set through Data Entry.
+	RegisteredParameter
+	// NonRegisteredParameter is an NRPN set through Data Entry.
+	NonRegisteredParameter
+)
+
+/*
+A ControllerEvent is a 14-bit controller or parameter value assembled from one
+or more Control Change messages. Number is the controller (0-31) for
+Controller14Bit, or the 14-bit parameter number otherwise. Value is the 14-bit
+value, with the MSB in the high 7 bits.
+*/
+type ControllerEvent struct {
+	Channel uint8
+	Kind    ControllerKind
+	Number  uint16
+	Value   uint16
+}
+
+/*
+PitchBendRange returns the pitch bend sensitivity, in semitones, set by an RPN 0
+event: the MSB holds semitones and the LSB cents.
+*/
+func (e ControllerEvent) PitchBendRange() float64 {
+	return float64(e.Value>>7) + float64(e.Value&sevenBitMask)/100
+}
+
+/*
+FineTuning returns the tuning offset, in cents from -100 to just under 100, set
+by an RPN 1 event.
+*/
+func (e ControllerEvent) FineTuning() float64 {
+	return float64(int(e.Value)-pitchWheelCenter) / pitchWheelCenter * 100
+}
+
+// CoarseTuning returns the tuning offset in semitones set by an RPN 2 event.
+func (e ControllerEvent) CoarseTuning() int {
+	return int(e.Value>>7) - 64
+}
+
+/*
+ControllerDecoder pairs the Control Change messages of each channel into
+ControllerEvents. It holds the state a device would: the last MSB of every
+14-bit controller, and the parameter selected by the RPN or NRPN controllers.
+The zero value is ready to use, with no parameter selected.
+*/
+type ControllerDecoder struct {
+	channels [channelCount]controllerState
+}
+
+// controllerState is the Control Change state of one channel.
+type controllerState struct {
+	msb      [controllerPairs]uint8
+	kind     ControllerKind
+	paramMSB uint8
+	paramLSB uint8
+	dataMSB  uint8
+	selected bool
+}
+
+/*
+Decode updates the decoder with message and returns the ControllerEvent it
+completes, if any. The MSB of a 14-bit controller produces an event on its own,
+with an LSB of 0, and a following LSB refines it. Data Entry MSB and LSB
+produce events for the selected parameter; they are ignored when none is
+selected or after the null RPN (127, 127) deselects it.
+*/
+func (d *ControllerDecoder) Decode(
+	message ControlChangeMessage) (ControllerEvent, bool) {
+	state := &d.channels[message.Channel&lowOrderMasl]
+	controller, value := message.Controller, message.Value&sevenBitMask
+	event := ControllerEvent{Channel: message.Channel & lowOrderMasl}
+	isMSB := controller == RPNMSB || controller == NRPNMSB
+	switch {
+	case controller == RPNMSB || controller == RPNLSB:
+		state.selectParameter(RegisteredParameter, isMSB, value)
+	case controller == NRPNMSB || controller == NRPNLSB:
+		state.selectParameter(NonRegisteredParameter, isMSB, value)
+	case controller == DataEntryMSB:
+		state.dataMSB = value
+		return state.parameter(event, uint16(value)<<7)
+	case controller == DataEntryLSB:
+		return state.parameter(event, uint16(state.dataMSB)<<7|uint16(value))
+	case controller < controllerPairs:
+		state.msb[controller] = value
+		event.Kind, event.Number = Controller14Bit, uint16(controller)
+		event.Value = uint16(value) << 7
+		return event, true
+	case controller < 2*controllerPairs:
+		number := controller - controllerPairs
+		event.Kind, event.Number = Controller14Bit, uint16(number)
+		event.Value = uint16(state.msb[number])<<7 | uint16(value)
+		return event, true
+	}
+	return ControllerEvent{}, false
+}
+
+/*
+selectParameter records one half of a parameter number. Switching between
+registered and non-registered parameters starts a new selection, with the other
+half 0.
+*/
+func (s *controllerState) selectParameter(
+	kind ControllerKind, msb bool, value uint8) {
+	if !s.selected || s.kind != kind {
+		s.paramMSB, s.paramLSB = 0, 0
+	}
+	if msb {
+		s.paramMSB = value
+	} else {
+		s.paramLSB = value
+	}
+	s.kind, s.selected, s.dataMSB = kind, true, 0
+	if kind == RegisteredParameter &&
+		s.paramMSB == rpnNull && s.paramLSB == rpnNull {
+		s.selected = false
+	}
+}
+
+// parameter completes event with the selected parameter and value.
+func (s *controllerState) parameter(
+	event ControllerEvent, value uint16) (ControllerEvent, bool) {
+	if !s.selected {
+		return ControllerEvent{}, false
+	}
+	event.Kind = s.kind
+	event.Number = uint16(s.paramMSB)<<7 | uint16(s.paramLSB)
+	event.Value = value
+	return event, true
+}
+
+/*
+A ControllerChange is a ControllerEvent along with where it occurred in the
+file.
+*/
+type ControllerChange struct {
+	ControllerEvent
+	Track int
+	Tick  uint64
+	Time  time.Duration
+}
+
+/*
+ControllerChanges decodes the Control Change messages of m with a
+ControllerDecoder and returns the resulting events in time order. Channel state
+is shared between tracks, as it is on a device.
+*/
+func (m *Midi) ControllerChanges() []ControllerChange {
+	tempoMap := m.TempoMap()
+	var decoder ControllerDecoder
+	var changes []ControllerChange
+	for _, timed := range m.mergedEvents() {
+		message, ok := timed.event.ChannelMessage().(ControlChangeMessage)
+		if !ok {
+			continue
+		}
+		if event, ok := decoder.Decode(message); ok {
+			changes = append(changes, ControllerChange{
+				ControllerEvent: event,
+				Track:           timed.track,
+				Tick:            timed.tick,
+				Time:            tempoMap.Time(timed.tick),
+			})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,97 @@
+package midi_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+// decodeAll feeds messages to decoder and returns the events produced.
+func decodeAll(
+	decoder *ControllerDecoder, messages ...ControlChangeMessage,
+) []ControllerEvent {
+	var events []ControllerEvent
+	for _, message := range messages {
+		if event, ok := decoder.Decode(message); ok {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestControllerDecoder14Bit(t *testing.T) {
+	var decoder ControllerDecoder
+	events := decodeAll(&decoder,
+		ControlChangeMessage{2, 7, 100},
+		ControlChangeMessage{2, 39, 64},
+		// An LSB without an MSB combines with an MSB of 0.
+		ControlChangeMessage{3, 33, 5},
+		// Controllers above 63 are not paired.
+		ControlChangeMessage{2, 64, 127},
+	)
+	assert.Equal(t, []ControllerEvent{
+		{2, Controller14Bit, 7, 100 << 7},
+		{2, Controller14Bit, 7, 100<<7 | 64},
+		{3, Controller14Bit, 1, 5},
+	}, events)
+}
+
+func TestControllerDecoderParameters(t *testing.T) {
+	var decoder ControllerDecoder
+	events := decodeAll(&decoder,
+		// Data entry is ignored until a parameter is selected.
+		ControlChangeMessage{0, DataEntryMSB, 12},
+		ControlChangeMessage{0, RPNMSB, 0},
+		ControlChangeMessage{0, RPNLSB, 0},
+		ControlChangeMessage{0, DataEntryMSB, 12},
+		ControlChangeMessage{0, DataEntryLSB, 50},
+		ControlChangeMessage{0, RPNLSB, RPNFineTuning},
+		ControlChangeMessage{0, DataEntryMSB, 0x60},
+		ControlChangeMessage{0, NRPNMSB, 1},
+		ControlChangeMessage{0, NRPNLSB, 8},
+		ControlChangeMessage{0, DataEntryMSB, 3},
+		// The null RPN deselects the parameter.
+		ControlChangeMessage{0, RPNMSB, 127},
+		ControlChangeMessage{0, RPNLSB, 127},
+		ControlChangeMessage{0, DataEntryMSB, 1},
+	)
+	assert.Equal(t, []ControllerEvent{
+		{0, RegisteredParameter, RPNPitchBendRange, 12 << 7},
+		{0, RegisteredParameter, RPNPitchBendRange, 12<<7 | 50},
+		{0, RegisteredParameter, RPNFineTuning, 0x60 << 7},
+		{0, NonRegisteredParameter, 1<<7 | 8, 3 << 7},
+	}, events)
+	assert.Equal(t, 12.0, events[0].PitchBendRange())
+	assert.Equal(t, 12.5, events[1].PitchBendRange())
+	assert.Equal(t, 50.0, events[2].FineTuning())
+	coarse := ControllerEvent{Value: 62 << 7}
+	assert.Equal(t, -2, coarse.CoarseTuning())
+}
+
+func TestControllerChanges(t *testing.T) {
+	midi := newTrackMidi(
+		TrackEvent{0, []byte{0xB1, 1, 10}},
+		TrackEvent{96, []byte{0x91, 60, 100}},
+		TrackEvent{0, []byte{0xB1, 33, 20}},
+	)
+	events, _ := PitchBendRangeEvents(1, 7, 0)
+	for _, event := range events {
+		midi.TrackChunks[0].InsertAt(192, event)
+	}
+
+	changes := midi.ControllerChanges()
+	// Both halves of the pitch bend range produce an event.
+	assert.Equal(t, 4, len(changes))
+	assert.Equal(t, ControllerChange{
+		ControllerEvent: ControllerEvent{1, Controller14Bit, 1, 10 << 7},
+	}, changes[0])
+	assert.Equal(t, ControllerChange{
+		ControllerEvent: ControllerEvent{1, Controller14Bit, 1, 10<<7 | 20},
+		Tick:            96,
+		Time:            500 * time.Millisecond,
+	}, changes[1])
+	assert.Equal(t, uint64(192), changes[3].Tick)
+	assert.Equal(t, 7.0, changes[3].PitchBendRange())
+}
@@ -0,0 +1,156 @@
+package midi
+
+import "time"
+
+/*
+This file contains helpers for inspecting TrackEvents and for converting between
+the delta-times stored in a track and absolute tick positions.
+*/
+
+// Status returns the status byte of the event, or 0 if the event has no data.
+func (e *TrackEvent) Status() byte {
+	if len(e.Data) == 0 {
+		return 0
+	}
+	return e.Data[0]
+}
+
+/*
+IsChannelEvent returns true when the event is a channel voice message, in which
+case Channel returns the channel it is addressed to.
+*/
+func (e *TrackEvent) IsChannelEvent() bool {
+	status := e.Status()
+	return status >= NoteOffEvent && status < SysExEvent
+}
+
+// Channel returns the channel (0-15) of a channel voice message.
+func (e *TrackEvent) Channel() uint8 {
+	return e.Status() & lowOrderMasl
+}
+
+/*
+IsNoteOn returns true for a Note On event with a non-zero velocity. A Note On
+with a velocity of 0 is treated as a Note Off, as the MIDI spec requires.
+*/
+func (e *TrackEvent) IsNoteOn() bool {
+	return e.Status()&highOrderMask == NoteOnEvent && len(e.Data) > 2 &&
+		e.Data[2] != 0
+}
+
+// IsNoteOff returns true for a Note Off event or a Note On with velocity 0.
+func (e *TrackEvent) IsNoteOff() bool {
+	switch e.Status() & highOrderMask {
+	case NoteOffEvent:
+		return len(e.Data) > 2
+	case NoteOnEvent:
+		return len(e.Data) > 2 && e.Data[2] == 0
+	}
+	return false
+}
+
+// IsMeta returns true if the event is a meta event of the given type.
+func (e *TrackEvent) IsMeta(metaType byte) bool {
+	return e.Status() == MetaEvent && len(e.Data) > 1 && e.Data[1] == metaType
+}
+
+/*
+AbsoluteTicks returns the absolute tick position of every event in the track,
+computed by summing delta-times from the start of the track.
+*/
+func (t *TrackChunk) AbsoluteTicks() []uint64 {
+	ticks := make([]uint64, len(t.TrackEvents))
+	var current uint64
+	for i := range t.TrackEvents {
+		current += uint64(t.TrackEvents[i].DeltaTime)
+		ticks[i] = current
+	}
+	return ticks
+}
+
+/*
+setAbsoluteTicks rewrites the delta-time of every event from a slice of absolute
+tick positions, which must be sorted in ascending order.
+*/
+func (t *TrackChunk) setAbsoluteTicks(ticks []uint64) {
+	var previous uint64
+	for i := range t.TrackEvents {
+		t.TrackEvents[i].DeltaTime = int(ticks[i] - previous)
+		previous = ticks[i]
+	}
+}
+
+/*
+InsertAt inserts event into the track at the absolute position tick and returns
+its index. The event's DeltaTime is ignored: both its delta-time and that of the
+event following it are recomputed so every other event keeps its absolute
+position. The event is placed after any events already at tick, but always
+before the End of Track event, which is moved later if tick is past it.
+*/
+func (t *TrackChunk) InsertAt(tick uint64, event TrackEvent) int {
+	var previous uint64
+	index := 0
+	for ; index < len(t.TrackEvents); index++ {
+		current := &t.TrackEvents[index]
+		next := previous + uint64(current.DeltaTime)
+		if next > tick || current.IsMeta(MetaEndOfTrack) {
+			break
+		}
+		previous = next
+	}
+
+	event.DeltaTime = int(tick - previous)
+	if index < len(t.TrackEvents) {
+		following := &t.TrackEvents[index]
+		if remaining := following.DeltaTime - event.DeltaTime; remaining > 0 {
+			following.DeltaTime = remaining
+		} else {
+			// An End of Track event at or before tick moves to tick.
+			following.DeltaTime = 0
+		}
+	}
+	t.TrackEvents = append(t.TrackEvents, TrackEvent{})
+	copy(t.TrackEvents[index+1:], t.TrackEvents[index:])
+	t.TrackEvents[index] = event
+	return index
+}
+
+// noteKey identifies a sounding note by its channel and key number.
+type noteKey struct {
+	channel, key uint8
+}
+
+/*
+A TimedEvent is a TrackEvent along with the track it belongs to and its absolute
+position in the file, both in ticks and as a time from the tempo map.
+*/
+type TimedEvent struct {
+	Track int
+	Tick  uint64
+	Time  time.Duration
+	Event TrackEvent
+}
+
+/*
+ForEachEvent calls fn with every event of m, merging the tracks in time order.
+Events at the same tick keep the order of their tracks and, within a track,
+their original order. Times come from the file's tempo map, or for a format 2
+file, from the tempo map of each event's own sequence. If fn returns a non-nil
+error, iteration stops and ForEachEvent returns that error. The Data of each
+event is shared with m.
+*/
+func (m *Midi) ForEachEvent(fn func(TimedEvent) error) error {
+	tempoMaps := m.trackTempoMaps()
+	for _, timed := range m.mergedEvents() {
+		err := fn(TimedEvent{
+			Track: timed.track,
+			Tick:  timed.tick,
+			Time:  tempoMaps[timed.track].Time(timed.tick),
+			Event: timed.event,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
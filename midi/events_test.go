@@ -0,0 +1,104 @@
+package midi_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackEventTypes(t *testing.T) {
+	on := TrackEvent{0, []byte{0x93, 60, 100}}
+	assert.True(t, on.IsChannelEvent())
+	assert.True(t, on.IsNoteOn())
+	assert.False(t, on.IsNoteOff())
+	assert.Equal(t, uint8(3), on.Channel())
+
+	// A note on with velocity 0 is a note off.
+	off := TrackEvent{0, []byte{0x93, 60, 0}}
+	assert.False(t, off.IsNoteOn())
+	assert.True(t, off.IsNoteOff())
+
+	meta := TrackEvent{0, []byte{0xFF, 0x2F}}
+	assert.False(t, meta.IsChannelEvent())
+	assert.True(t, meta.IsMeta(MetaEndOfTrack))
+	assert.False(t, meta.IsMeta(MetaSetTempo))
+
+	empty := TrackEvent{}
+	assert.Equal(t, byte(0), empty.Status())
+	assert.False(t, empty.IsNoteOff())
+}
+
+func TestInsertAt(t *testing.T) {
+	track := &TrackChunk{TrackEvents: []TrackEvent{
+		{10, []byte{0x90, 60, 100}},
+		{20, []byte{0x80, 60, 0}},
+		{0, []byte{0xFF, 0x2F}},
+	}}
+
+	// Between the two notes.
+	index := track.InsertAt(15, TrackEvent{99, []byte{0xB0, 7, 100}})
+	assert.Equal(t, 1, index)
+	assert.Equal(t, []uint64{10, 15, 30, 30}, track.AbsoluteTicks())
+
+	// At the start of the track.
+	index = track.InsertAt(0, TrackEvent{Data: []byte{0xC0, 1}})
+	assert.Equal(t, 0, index)
+	assert.Equal(t, []uint64{0, 10, 15, 30, 30}, track.AbsoluteTicks())
+
+	// After events at the same tick but before the end of track.
+	index = track.InsertAt(30, TrackEvent{Data: []byte{0x90, 62, 100}})
+	assert.Equal(t, 4, index)
+	assert.Equal(t, []uint64{0, 10, 15, 30, 30, 30}, track.AbsoluteTicks())
+	assert.True(t, track.TrackEvents[5].IsMeta(MetaEndOfTrack))
+
+	// Past the end of track, which moves with it.
+	index = track.InsertAt(50, TrackEvent{Data: []byte{0x80, 62, 0}})
+	assert.Equal(t, 5, index)
+	assert.Equal(
+		t, []uint64{0, 10, 15, 30, 30, 50, 50}, track.AbsoluteTicks())
+	assert.True(t, track.TrackEvents[6].IsMeta(MetaEndOfTrack))
+
+	// An empty track.
+	empty := &TrackChunk{}
+	assert.Equal(t, 0, empty.InsertAt(7, TrackEvent{Data: []byte{0xC0, 1}}))
+	assert.Equal(t, 7, empty.TrackEvents[0].DeltaTime)
+}
+
+func TestForEachEvent(t *testing.T) {
+	midi := newPlayerMidi()
+	var events []TimedEvent
+	err := midi.ForEachEvent(func(event TimedEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []TimedEvent{
+		{0, 0, 0, TrackEvent{0, []byte{0x90, 60, 100}}},
+		{1, 96, 500 * time.Millisecond, TrackEvent{96, []byte{0x91, 64, 100}}},
+		{0, 192, time.Second, TrackEvent{192, []byte{0x80, 60, 0}}},
+	}, events)
+
+	// Iteration stops at the first error.
+	stop := errors.New("stop")
+	var count int
+	err = midi.ForEachEvent(func(TimedEvent) error {
+		count++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, count)
+
+	// Each sequence of a format 2 file has its own timing.
+	var times []time.Duration
+	newSequenceMidi(2).ForEachEvent(func(event TimedEvent) error {
+		if event.Event.IsNoteOn() {
+			times = append(times, event.Time)
+		}
+		return nil
+	})
+	assert.Equal(t, []time.Duration{time.Second, 500 * time.Millisecond},
+		times)
+}
@@ -0,0 +1,193 @@
+/*
+The gm package names the instruments, percussion sounds and controllers defined
+by General MIDI, so that tools can describe MIDI data to people. Programs and
+keys are numbered from 0, as they are in the data bytes of a MIDI message,
+rather than from 1 as in the published tables.
+*/
+package gm
+
+// PercussionChannel is the channel, counted from 0, reserved for percussion.
+const PercussionChannel = 9
+
+// programNames holds the General MIDI Level 1 instrument of every program.
+var programNames = [128]string{
+	// Piano
+	"Acoustic Grand Piano", "Bright Acoustic Piano", "Electric Grand Piano",
+	"Honky-tonk Piano", "Electric Piano 1", "Electric Piano 2", "Harpsichord",
+	"Clavi",
+	// Chromatic Percussion
+	"Celesta", "Glockenspiel", "Music Box", "Vibraphone", "Marimba",
+	"Xylophone", "Tubular Bells", "Dulcimer",
+	// Organ
+	"Drawbar Organ", "Percussive Organ", "Rock Organ", "Church Organ",
+	"Reed Organ", "Accordion", "Harmonica", "Tango Accordion",
+	// Guitar
+	"Acoustic Guitar (nylon)", "Acoustic Guitar (steel)",
+	"Electric Guitar (jazz)", "Electric Guitar (clean)",
+	"Electric Guitar (muted)", "Overdriven Guitar", "Distortion Guitar",
+	"Guitar Harmonics",
+	// Bass
+	"Acoustic Bass", "Electric Bass (finger)", "Electric Bass (pick)",
+	"Fretless Bass", "Slap Bass 1", "Slap Bass 2", "Synth Bass 1",
+	"Synth Bass 2",
+	// Strings
+	"Violin", "Viola", "Cello", "Contrabass", "Tremolo Strings",
+	"Pizzicato Strings", "Orchestral Harp", "Timpani",
+	// Ensemble
+	"String Ensemble 1", "String Ensemble 2", "Synth Strings 1",
+	"Synth Strings 2", "Choir Aahs", "Voice Oohs", "Synth Voice",
+	"Orchestra Hit",
+	// Brass
+	"Trumpet", "Trombone", "Tuba", "Muted Trumpet", "French Horn",
+	"Brass Section", "Synth Brass 1", "Synth Brass 2",
+	// Reed
+	"Soprano Sax", "Alto Sax", "Tenor Sax", "Baritone Sax", "Oboe",
+	"English Horn", "Bassoon", "Clarinet",
+	// Pipe
+	"Piccolo", "Flute", "Recorder", "Pan Flute", "Blown Bottle",
+	"Shakuhachi", "Whistle", "Ocarina",
+	// Synth Lead
+	"Lead 1 (square)", "Lead 2 (sawtooth)", "Lead 3 (calliope)",
+	"Lead 4 (chiff)", "Lead 5 (charang)", "Lead 6 (voice)",
+	"Lead 7 (fifths)", "Lead 8 (bass + lead)",
+	// Synth Pad
+	"Pad 1 (new age)", "Pad 2 (warm)", "Pad 3 (polysynth)", "Pad 4 (choir)",
+	"Pad 5 (bowed)", "Pad 6 (metallic)", "Pad 7 (halo)", "Pad 8 (sweep)",
+	// Synth Effects
+	"FX 1 (rain)", "FX 2 (soundtrack)", "FX 3 (crystal)",
+	"FX 4 (atmosphere)", "FX 5 (brightness)", "FX 6 (goblins)",
+	"FX 7 (echoes)", "FX 8 (sci-fi)",
+	// Ethnic
+	"Sitar", "Banjo", "Shamisen", "Koto", "Kalimba", "Bag pipe", "Fiddle",
+	"Shanai",
+	// Percussive
+	"Tinkle Bell", "Agogo", "Steel Drums", "Woodblock", "Taiko Drum",
+	"Melodic Tom", "Synth Drum", "Reverse Cymbal",
+	// Sound Effects
+	"Guitar Fret Noise", "Breath Noise", "Seashore", "Bird Tweet",
+	"Telephone Ring", "Helicopter", "Applause", "Gunshot",
+}
+
+// firstPercussionKey is the key of the first sound in percussionNames.
+const firstPercussionKey = 35
+
+/*
+percussionNames holds the General MIDI Level 1 percussion sounds, from key 35 to
+key 81.
+*/
+var percussionNames = [...]string{
+	"Acoustic Bass Drum", "Bass Drum 1", "Side Stick", "Acoustic Snare",
+	"Hand Clap", "Electric Snare", "Low Floor Tom", "Closed Hi Hat",
+	"High Floor Tom", "Pedal Hi-Hat", "Low Tom", "Open Hi-Hat",
+	"Low-Mid Tom", "Hi-Mid Tom", "Crash Cymbal 1", "High Tom",
+	"Ride Cymbal 1", "Chinese Cymbal", "Ride Bell", "Tambourine",
+	"Splash Cymbal", "Cowbell", "Crash Cymbal 2", "Vibraslap",
+	"Ride Cymbal 2", "Hi Bongo", "Low Bongo", "Mute Hi Conga",
+	"Open Hi Conga", "Low Conga", "High Timbale", "Low Timbale",
+	"High Agogo", "Low Agogo", "Cabasa", "Maracas", "Short Whistle",
+	"Long Whistle", "Short Guiro", "Long Guiro", "Claves", "Hi Wood Block",
+	"Low Wood Block", "Mute Cuica", "Open Cuica", "Mute Triangle",
+	"Open Triangle",
+}
+
+/*
+controllerNames holds the controllers named by the MIDI 1.0 spec. Controllers 32
+to 63 are the LSBs of controllers 0 to 31 and are named by ControllerName.
+*/
+var controllerNames = map[byte]string{
+	0:   "Bank Select",
+	1:   "Modulation Wheel",
+	2:   "Breath Controller",
+	4:   "Foot Controller",
+	5:   "Portamento Time",
+	6:   "Data Entry",
+	7:   "Channel Volume",
+	8:   "Balance",
+	10:  "Pan",
+	11:  "Expression Controller",
+	12:  "Effect Control 1",
+	13:  "Effect Control 2",
+	16:  "General Purpose Controller 1",
+	17:  "General Purpose Controller 2",
+	18:  "General Purpose Controller 3",
+	19:  "General Purpose Controller 4",
+	64:  "Damper Pedal (sustain)",
+	65:  "Portamento On/Off",
+	66:  "Sostenuto",
+	67:  "Soft Pedal",
+	68:  "Legato Footswitch",
+	69:  "Hold 2",
+	70:  "Sound Variation",
+	71:  "Timbre/Harmonic Intensity",
+	72:  "Release Time",
+	73:  "Attack Time",
+	74:  "Brightness",
+	75:  "Decay Time",
+	76:  "Vibrato Rate",
+	77:  "Vibrato Depth",
+	78:  "Vibrato Delay",
+	79:  "Sound Controller 10",
+	80:  "General Purpose Controller 5",
+	81:  "General Purpose Controller 6",
+	82:  "General Purpose Controller 7",
+	83:  "General Purpose Controller 8",
+	84:  "Portamento Control",
+	88:  "High Resolution Velocity Prefix",
+	91:  "Effects 1 Depth (reverb)",
+	92:  "Effects 2 Depth (tremolo)",
+	93:  "Effects 3 Depth (chorus)",
+	94:  "Effects 4 Depth (celeste)",
+	95:  "Effects 5 Depth (phaser)",
+	96:  "Data Increment",
+	97:  "Data Decrement",
+	98:  "Non-Registered Parameter Number LSB",
+	99:  "Non-Registered Parameter Number MSB",
+	100: "Registered Parameter Number LSB",
+	101: "Registered Parameter Number MSB",
+	120: "All Sound Off",
+	121: "Reset All Controllers",
+	122: "Local Control On/Off",
+	123: "All Notes Off",
+	124: "Omni Mode Off",
+	125: "Omni Mode On",
+	126: "Mono Mode On",
+	127: "Poly Mode On",
+}
+
+/*
+ProgramName returns the General MIDI instrument selected by program, or "" if
+program is not a valid data byte.
+*/
+func ProgramName(program byte) string {
+	if int(program) >= len(programNames) {
+		return ""
+	}
+	return programNames[program]
+}
+
+/*
+PercussionName returns the General MIDI percussion sound played by key on the
+percussion channel, or "" if no sound is assigned to key.
+*/
+func PercussionName(key byte) string {
+	index := int(key) - firstPercussionKey
+	if index < 0 || index >= len(percussionNames) {
+		return ""
+	}
+	return percussionNames[index]
+}
+
+/*
+ControllerName returns the name of controller cc, or "" if the MIDI spec leaves
+it undefined. The LSBs of controllers 0 to 31 are named after their MSB, as in
+"Channel Volume LSB".
+*/
+func ControllerName(cc byte) string {
+	if cc >= 32 && cc < 64 {
+		if name, ok := controllerNames[cc-32]; ok {
+			return name + " LSB"
+		}
+		return ""
+	}
+	return controllerNames[cc]
+}
@@ -0,0 +1,34 @@
+package gm_test
+
+import (
+	"testing"
+
+	. "github.com/husafan/audio/midi/gm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgramName(t *testing.T) {
+	assert.Equal(t, "Acoustic Grand Piano", ProgramName(0))
+	assert.Equal(t, "Violin", ProgramName(40))
+	assert.Equal(t, "Gunshot", ProgramName(127))
+	assert.Equal(t, "", ProgramName(128))
+}
+
+func TestPercussionName(t *testing.T) {
+	assert.Equal(t, "", PercussionName(34))
+	assert.Equal(t, "Acoustic Bass Drum", PercussionName(35))
+	assert.Equal(t, "Acoustic Snare", PercussionName(38))
+	assert.Equal(t, "Closed Hi Hat", PercussionName(42))
+	assert.Equal(t, "Open Triangle", PercussionName(81))
+	assert.Equal(t, "", PercussionName(82))
+}
+
+func TestControllerName(t *testing.T) {
+	assert.Equal(t, "Channel Volume", ControllerName(7))
+	assert.Equal(t, "Channel Volume LSB", ControllerName(39))
+	assert.Equal(t, "", ControllerName(3))
+	assert.Equal(t, "", ControllerName(35))
+	assert.Equal(t, "Damper Pedal (sustain)", ControllerName(64))
+	assert.Equal(t, "Poly Mode On", ControllerName(127))
+	assert.Equal(t, "", ControllerName(128))
+}
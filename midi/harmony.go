@@ -0,0 +1,351 @@
+package midi
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/husafan/audio/midi/gm"
+)
+
+const (
+	KeyWindowError = "key window must be a positive number of ticks; found %v"
+
+	// minChordClasses is the number of distinct pitch classes that must
+	// sound together to be reported as a chord.
+	minChordClasses = 3
+)
+
+// pitchClassNames names the twelve pitch classes, spelled with sharps.
+var pitchClassNames = [12]string{
+	"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B",
+}
+
+/*
+chordQualities lists the chords recognized by Chords, as the intervals above the
+root that make them up, with the suffix used to name them.
+*/
+var chordQualities = []struct {
+	suffix    string
+	intervals []int
+}{
+	{"", []int{0, 4, 7}},
+	{"m", []int{0, 3, 7}},
+	{"dim", []int{0, 3, 6}},
+	{"aug", []int{0, 4, 8}},
+	{"sus2", []int{0, 2, 7}},
+	{"sus4", []int{0, 5, 7}},
+	{"7", []int{0, 4, 7, 10}},
+	{"maj7", []int{0, 4, 7, 11}},
+	{"m7", []int{0, 3, 7, 10}},
+	{"dim7", []int{0, 3, 6, 9}},
+	{"m7b5", []int{0, 3, 6, 10}},
+}
+
+/*
+The Krumhansl-Kessler key profiles give the perceived fit of each pitch class,
+counted up from the tonic, in a major and a minor key.
+*/
+var (
+	majorProfile = [12]float64{
+		6.35, 2.23, 3.48, 2.33, 4.38, 4.09, 2.52, 5.19, 2.39, 3.66, 2.29, 2.88}
+	minorProfile = [12]float64{
+		6.33, 2.68, 3.52, 5.38, 2.60, 3.53, 2.54, 4.75, 3.98, 2.69, 3.34, 3.17}
+)
+
+/*
+A Chord is a span of time over which the same set of keys sounds together,
+with at least three distinct pitch classes. Keys are sorted from lowest to
+highest. When the pitch classes form a recognized chord, Root is its root pitch
+class (0 for C to 11 for B) and Name its symbol, such as "Am", "G7" or "C/E"
+when the lowest key is not the root; otherwise Name is empty.
+*/
+type Chord struct {
+	StartTick uint64
+	EndTick   uint64
+	StartTime time.Duration
+	Duration  time.Duration
+	Keys      []uint8
+	Root      uint8
+	Name      string
+}
+
+/*
+Chords groups the notes that sound at the same time, across every track, into
+Chords in time order. Notes on the General MIDI percussion channel are ignored,
+as their keys are drum sounds rather than pitches. Spans with fewer than three
+pitch classes, such as single notes and octaves, are not chords.
+*/
+func (m *Midi) Chords() []Chord {
+	type boundary struct {
+		tick  uint64
+		key   uint8
+		delta int
+	}
+	var boundaries []boundary
+	for _, note := range m.Notes() {
+		if note.Channel == gm.PercussionChannel || note.DurationTicks == 0 {
+			continue
+		}
+		boundaries = append(boundaries,
+			boundary{note.StartTick, note.Key, 1},
+			boundary{note.StartTick + note.DurationTicks, note.Key, -1})
+	}
+	sort.SliceStable(boundaries, func(a, b int) bool {
+		return boundaries[a].tick < boundaries[b].tick
+	})
+
+	tempoMap := m.TempoMap()
+	var chords []Chord
+	var sounding [128]int
+	for i := 0; i < len(boundaries); {
+		tick := boundaries[i].tick
+		for ; i < len(boundaries) && boundaries[i].tick == tick; i++ {
+			sounding[boundaries[i].key&sevenBitMask] += boundaries[i].delta
+		}
+		if i == len(boundaries) {
+			break
+		}
+		var keys []uint8
+		var classes uint16
+		for key, count := range sounding {
+			if count > 0 {
+				keys = append(keys, uint8(key))
+				classes |= 1 << (key % 12)
+			}
+		}
+		if countClasses(classes) < minChordClasses {
+			continue
+		}
+		end := boundaries[i].tick
+		if n := len(chords); n > 0 && chords[n-1].EndTick == tick &&
+			equalKeys(chords[n-1].Keys, keys) {
+			chords[n-1].EndTick = end
+			continue
+		}
+		chord := Chord{StartTick: tick, EndTick: end, Keys: keys}
+		chord.Root, chord.Name = nameChord(keys, classes)
+		chords = append(chords, chord)
+	}
+	for i := range chords {
+		chord := &chords[i]
+		chord.StartTime = tempoMap.Time(chord.StartTick)
+		chord.Duration = tempoMap.Time(chord.EndTick) - chord.StartTime
+	}
+	return chords
+}
+
+// countClasses returns the number of pitch classes set in classes.
+func countClasses(classes uint16) int {
+	var count int
+	for ; classes != 0; classes &= classes - 1 {
+		count++
+	}
+	return count
+}
+
+// equalKeys returns true if a and b hold the same keys.
+func equalKeys(a, b []uint8) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+nameChord returns the root and symbol of the chord formed by keys, whose pitch
+classes are set in classes. Roots are tried starting from the lowest key, so
+that symmetric chords, like augmented triads, are named after their bass.
+*/
+func nameChord(keys []uint8, classes uint16) (uint8, string) {
+	bass := int(keys[0] % 12)
+	for offset := 0; offset < 12; offset++ {
+		root := (bass + offset) % 12
+		for _, quality := range chordQualities {
+			var template uint16
+			for _, interval := range quality.intervals {
+				template |= 1 << ((root + interval) % 12)
+			}
+			if template != classes {
+				continue
+			}
+			name := pitchClassNames[root] + quality.suffix
+			if root != bass {
+				name += "/" + pitchClassNames[bass]
+			}
+			return uint8(root), name
+		}
+	}
+	return 0, ""
+}
+
+/*
+A Key is a musical key estimated from the notes of a file. Tonic is its pitch
+class, 0 for C to 11 for B, and Correlation how well the notes fit its
+Krumhansl-Kessler profile, from -1 to 1.
+*/
+type Key struct {
+	Tonic       uint8
+	Minor       bool
+	Correlation float64
+}
+
+// String names the key, as in "C major" or "F# minor".
+func (k Key) String() string {
+	mode := "major"
+	if k.Minor {
+		mode = "minor"
+	}
+	return fmt.Sprintf("%s %s", pitchClassNames[k.Tonic%12], mode)
+}
+
+/*
+EstimateKey estimates the key of m with the Krumhansl-Schmuckler algorithm: the
+total duration of each pitch class is correlated with the major and minor key
+profiles rotated to every tonic, and the best fit is returned. Percussion is
+ignored. It returns false when m has no pitched notes.
+*/
+func (m *Midi) EstimateKey() (Key, bool) {
+	var durations [12]float64
+	for _, note := range m.Notes() {
+		if note.Channel != gm.PercussionChannel {
+			durations[note.Key%12] += float64(note.DurationTicks)
+		}
+	}
+	return estimateKey(durations)
+}
+
+/*
+estimateKey returns the key whose profile best correlates with durations, the
+weight of each pitch class.
+*/
+func estimateKey(durations [12]float64) (Key, bool) {
+	var total float64
+	for _, duration := range durations {
+		total += duration
+	}
+	if total == 0 {
+		return Key{}, false
+	}
+	best := Key{Correlation: math.Inf(-1)}
+	for tonic := 0; tonic < 12; tonic++ {
+		for _, minor := range []bool{false, true} {
+			profile := majorProfile
+			if minor {
+				profile = minorProfile
+			}
+			var rotated [12]float64
+			for i := range rotated {
+				rotated[(tonic+i)%12] = profile[i]
+			}
+			correlation := pearson(durations[:], rotated[:])
+			if correlation > best.Correlation {
+				best = Key{uint8(tonic), minor, correlation}
+			}
+		}
+	}
+	return best, true
+}
+
+// pearson returns the Pearson correlation coefficient of x and y.
+func pearson(x, y []float64) float64 {
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= float64(len(x))
+	meanY /= float64(len(y))
+	var covariance, varianceX, varianceY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		covariance += dx * dy
+		varianceX += dx * dx
+		varianceY += dy * dy
+	}
+	if varianceX == 0 || varianceY == 0 {
+		return 0
+	}
+	return covariance / math.Sqrt(varianceX*varianceY)
+}
+
+/*
+A KeySection is a span of a file estimated to be in a single key.
+*/
+type KeySection struct {
+	StartTick uint64
+	EndTick   uint64
+	StartTime time.Duration
+	EndTime   time.Duration
+	Key       Key
+}
+
+/*
+KeySections divides m into windows of the given number of ticks, estimates the
+key of each with EstimateKey's method, and merges neighboring windows in the
+same key into sections. A window without pitched notes joins the section before
+it. The Correlation of a section is that of its first window. A window of a few
+bars gives stable estimates while still finding modulations.
+*/
+func (m *Midi) KeySections(window uint64) ([]KeySection, error) {
+	if window == 0 {
+		return nil, fmt.Errorf(KeyWindowError, window)
+	}
+	var notes []Note
+	var end uint64
+	for _, note := range m.Notes() {
+		if note.Channel == gm.PercussionChannel {
+			continue
+		}
+		notes = append(notes, note)
+		if finish := note.StartTick + note.DurationTicks; finish > end {
+			end = finish
+		}
+	}
+
+	tempoMap := m.TempoMap()
+	var sections []KeySection
+	for start := uint64(0); start < end; start += window {
+		stop := start + window
+		var durations [12]float64
+		for _, note := range notes {
+			from, to := note.StartTick, note.StartTick+note.DurationTicks
+			if from < start {
+				from = start
+			}
+			if to > stop {
+				to = stop
+			}
+			if to > from {
+				durations[note.Key%12] += float64(to - from)
+			}
+		}
+		key, ok := estimateKey(durations)
+		n := len(sections)
+		if n > 0 && (!ok || sections[n-1].Key.Tonic == key.Tonic &&
+			sections[n-1].Key.Minor == key.Minor) {
+			sections[n-1].EndTick = stop
+			continue
+		}
+		if !ok {
+			continue
+		}
+		sections = append(sections,
+			KeySection{StartTick: start, EndTick: stop, Key: key})
+	}
+	for i := range sections {
+		section := &sections[i]
+		if section.EndTick > end {
+			section.EndTick = end
+		}
+		section.StartTime = tempoMap.Time(section.StartTick)
+		section.EndTime = tempoMap.Time(section.EndTick)
+	}
+	return sections, nil
+}
@@ -0,0 +1,124 @@
+package midi_test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+// newNotesMidi returns a single track file holding a note for each of spans,
+// given as a channel, key, start tick and end tick.
+func newNotesMidi(spans ...[4]uint64) *Midi {
+	midi := newTrackMidi()
+	track := &midi.TrackChunks[0]
+	for _, span := range spans {
+		channel, key := byte(span[0]), byte(span[1])
+		track.InsertAt(span[2], TrackEvent{Data: []byte{0x90 | channel, key, 100}})
+		track.InsertAt(span[3], TrackEvent{Data: []byte{0x80 | channel, key, 0}})
+	}
+	return midi
+}
+
+// chordSpans returns a span for each key, all from start to end on channel 0.
+func chordSpans(start, end uint64, keys ...uint64) [][4]uint64 {
+	var spans [][4]uint64
+	for _, key := range keys {
+		spans = append(spans, [4]uint64{0, key, start, end})
+	}
+	return spans
+}
+
+func TestChords(t *testing.T) {
+	var spans [][4]uint64
+	spans = append(spans, chordSpans(0, 96, 60, 64, 67)...)
+	spans = append(spans, chordSpans(96, 192, 52, 55, 60)...)
+	spans = append(spans, chordSpans(192, 288, 57, 60, 64)...)
+	spans = append(spans, chordSpans(288, 384, 55, 59, 62, 65)...)
+	spans = append(spans, chordSpans(384, 480, 60, 64, 68)...)
+	// Two pitch classes are not a chord.
+	spans = append(spans, chordSpans(480, 576, 48, 60, 64)...)
+	// Drums are ignored.
+	spans = append(spans, [4]uint64{9, 42, 0, 480})
+
+	chords := newNotesMidi(spans...).Chords()
+	var names []string
+	for _, chord := range chords {
+		names = append(names, chord.Name)
+	}
+	assert.Equal(t, []string{"C", "C/E", "Am", "G7", "Caug"}, names)
+	assert.Equal(t, Chord{
+		StartTick: 192, EndTick: 288,
+		StartTime: time.Second, Duration: 500 * time.Millisecond,
+		Keys: []uint8{57, 60, 64}, Root: 9, Name: "Am",
+	}, chords[2])
+	assert.Equal(t, uint8(0), chords[1].Root)
+
+	// A chord held across a new note is split, and unknown chords have no
+	// name.
+	chords = newNotesMidi(append(chordSpans(0, 192, 60, 64, 67),
+		[4]uint64{0, 61, 96, 192})...).Chords()
+	assert.Equal(t, 2, len(chords))
+	assert.Equal(t, uint64(96), chords[0].EndTick)
+	assert.Equal(t, []uint8{60, 61, 64, 67}, chords[1].Keys)
+	assert.Equal(t, "", chords[1].Name)
+}
+
+// scaleSpans returns one beat long notes on the given keys, one after another
+// from start.
+func scaleSpans(start uint64, keys ...uint64) [][4]uint64 {
+	var spans [][4]uint64
+	for i, key := range keys {
+		tick := start + uint64(i)*96
+		spans = append(spans, [4]uint64{0, key, tick, tick + 96})
+	}
+	return spans
+}
+
+func TestEstimateKey(t *testing.T) {
+	_, ok := newTrackMidi().EstimateKey()
+	assert.False(t, ok)
+
+	key, ok := newNotesMidi(
+		scaleSpans(0, 60, 62, 64, 65, 67, 69, 71, 72, 67, 60)...).EstimateKey()
+	assert.True(t, ok)
+	assert.Equal(t, "C major", key.String())
+	assert.True(t, key.Correlation > 0.8)
+
+	key, _ = newNotesMidi(
+		scaleSpans(0, 57, 59, 60, 62, 64, 65, 68, 69, 64, 57)...).EstimateKey()
+	assert.Equal(t, Key{Tonic: 9, Minor: true, Correlation: key.Correlation},
+		key)
+	assert.Equal(t, "A minor", key.String())
+}
+
+func TestKeySections(t *testing.T) {
+	spans := scaleSpans(0, 60, 62, 64, 65, 67, 69, 71, 72)
+	spans = append(spans, scaleSpans(768, 62, 64, 66, 67, 69, 71, 73, 74)...)
+	midi := newNotesMidi(spans...)
+
+	sections, err := midi.KeySections(768)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(sections))
+	assert.Equal(t, "C major", sections[0].Key.String())
+	assert.Equal(t, uint64(0), sections[0].StartTick)
+	assert.Equal(t, uint64(768), sections[0].EndTick)
+	assert.Equal(t, "D major", sections[1].Key.String())
+	assert.Equal(t, 4*time.Second, sections[1].StartTime)
+	assert.Equal(t, 8*time.Second, sections[1].EndTime)
+
+	// Windows in the same key, or with no notes, are merged.
+	spans = scaleSpans(0, 60, 62, 64, 65, 67, 69, 71, 72)
+	spans = append(spans, scaleSpans(1536, 60, 62, 64, 65, 67, 69, 71, 72)...)
+	sections, err = newNotesMidi(spans...).KeySections(768)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(sections))
+	assert.Equal(t, uint64(2304), sections[0].EndTick)
+
+	_, err = midi.KeySections(0)
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("key window must be a positive number of ticks")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
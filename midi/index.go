@@ -0,0 +1,82 @@
+package midi
+
+import (
+	"sort"
+)
+
+/*
+metaIndex maps the names found in Track Name and Marker meta events to where
+they occur, so lookups do not have to rescan every track.
+*/
+type metaIndex struct {
+	trackNames map[string][]int
+	markers    map[string][]uint64
+}
+
+/*
+Reindex rebuilds the track name and marker indexes used by TrackIndices and
+MarkerTicks. Parsing builds the indexes automatically; Reindex only needs to be
+called after tracks or meta events have been modified directly.
+
+A track is indexed by the text of its first Track Name event. Markers from every
+track are indexed by their text, with ticks in ascending order.
+*/
+func (m *Midi) Reindex() {
+	index := &metaIndex{
+		trackNames: make(map[string][]int),
+		markers:    make(map[string][]uint64),
+	}
+	for i := range m.TrackChunks {
+		track := &m.TrackChunks[i]
+		named := false
+		for j, tick := range track.AbsoluteTicks() {
+			event := &track.TrackEvents[j]
+			if !named && event.IsMeta(MetaTrackName) {
+				name := string(event.Data[2:])
+				index.trackNames[name] = append(index.trackNames[name], i)
+				named = true
+			} else if event.IsMeta(MetaMarker) {
+				name := string(event.Data[2:])
+				index.markers[name] = append(index.markers[name], tick)
+			}
+		}
+	}
+	for _, ticks := range index.markers {
+		sort.Slice(ticks, func(a, b int) bool { return ticks[a] < ticks[b] })
+	}
+	m.index = index
+}
+
+/*
+TrackIndices returns the indices of the tracks named name, in ascending order.
+It returns nil if no track has that name.
+*/
+func (m *Midi) TrackIndices(name string) []int {
+	if m.index == nil {
+		m.Reindex()
+	}
+	return m.index.trackNames[name]
+}
+
+/*
+TrackByName returns the index of the first track named name. The boolean is
+false if no track has that name.
+*/
+func (m *Midi) TrackByName(name string) (int, bool) {
+	indices := m.TrackIndices(name)
+	if len(indices) == 0 {
+		return 0, false
+	}
+	return indices[0], true
+}
+
+/*
+MarkerTicks returns the absolute ticks of every Marker event with the text name,
+in ascending order. It returns nil if there is no such marker.
+*/
+func (m *Midi) MarkerTicks(name string) []uint64 {
+	if m.index == nil {
+		m.Reindex()
+	}
+	return m.index.markers[name]
+}
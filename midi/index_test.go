@@ -0,0 +1,65 @@
+package midi_test
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackAndMarkerIndex(t *testing.T) {
+	var buffer bytes.Buffer
+	writeHeader(&buffer, 1, 3, 96)
+	writeTrack(&buffer, []byte{
+		0x00, 0xFF, 0x03, 0x05, 'T', 'e', 'm', 'p', 'o',
+		0x00, 0xFF, 0x06, 0x05, 'V', 'e', 'r', 's', 'e',
+		0x60, 0xFF, 0x06, 0x06, 'C', 'h', 'o', 'r', 'u', 's',
+		0x60, 0xFF, 0x06, 0x05, 'V', 'e', 'r', 's', 'e',
+		0x00, 0xFF, 0x2F, 0x00,
+	})
+	writeTrack(&buffer, []byte{
+		0x00, 0xFF, 0x03, 0x04, 'B', 'a', 's', 's',
+		// A second name event in the same track is not indexed.
+		0x00, 0xFF, 0x03, 0x04, 'L', 'e', 'a', 'd',
+		0x00, 0xFF, 0x2F, 0x00,
+	})
+	writeTrack(&buffer, []byte{
+		0x00, 0xFF, 0x03, 0x04, 'B', 'a', 's', 's',
+		0x00, 0xFF, 0x2F, 0x00,
+	})
+	midi, err := ParseMidi(buffer.Bytes(), nil)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []int{1, 2}, midi.TrackIndices("Bass"))
+	assert.Nil(t, midi.TrackIndices("Lead"))
+	index, ok := midi.TrackByName("Tempo")
+	assert.True(t, ok)
+	assert.Equal(t, 0, index)
+	_, ok = midi.TrackByName("Drums")
+	assert.False(t, ok)
+
+	assert.Equal(t, []uint64{0, 192}, midi.MarkerTicks("Verse"))
+	assert.Equal(t, []uint64{96}, midi.MarkerTicks("Chorus"))
+	assert.Nil(t, midi.MarkerTicks("Bridge"))
+
+	// Merging keeps the markers but leaves a single track.
+	assert.Nil(t, midi.MergeTracks())
+	assert.Equal(t, []int{0}, midi.TrackIndices("Tempo"))
+	assert.Nil(t, midi.TrackIndices("Bass"))
+	assert.Equal(t, []uint64{0, 192}, midi.MarkerTicks("Verse"))
+}
+
+func TestIndexBuiltLazily(t *testing.T) {
+	midi := newTrackMidi(
+		TrackEvent{0, []byte{0xFF, 0x03, 'P', 'i', 'a', 'n', 'o'}},
+		TrackEvent{10, []byte{0xFF, 0x06, 'E', 'n', 'd'}},
+	)
+	assert.Equal(t, []int{0}, midi.TrackIndices("Piano"))
+	assert.Equal(t, []uint64{10}, midi.MarkerTicks("End"))
+
+	midi.TrackChunks[0].TrackEvents[1].Data = []byte{0xFF, 0x06, 'F', 'i', 'n'}
+	midi.Reindex()
+	assert.Nil(t, midi.MarkerTicks("End"))
+	assert.Equal(t, []uint64{10}, midi.MarkerTicks("Fin"))
+}
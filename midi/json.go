@@ -0,0 +1,303 @@
+package midi
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/husafan/audio/midi/gm"
+)
+
+const (
+	JSONEventError = "track %v, event %v: %v"
+	JSONTypeError  = "unknown event type %q"
+	JSONTickError  = "tick %v is before the previous event at tick %v"
+	JSONBendError  = "bend of %v is out of range -8192 to 8191"
+)
+
+/*
+jsonEventTypes holds the names of events in the JSON form of a Midi, keyed by
+status byte with the channel removed.
+*/
+var jsonEventTypes = map[byte]string{
+	NoteOffEvent:          "note_off",
+	NoteOnEvent:           "note_on",
+	PolyphonicKeyPressure: "key_pressure",
+	ControlChange:         "control_change",
+	ProgramChange:         "program_change",
+	ChannelPressure:       "channel_pressure",
+	PitchWheelChange:      "pitch_bend",
+	SysExEvent:            "sysex",
+	SysExEscape:           "sysex_escape",
+	MetaEvent:             "meta",
+}
+
+// jsonMidi is the JSON form of a Midi.
+type jsonMidi struct {
+	Format   uint16        `json:"format"`
+	Division uint16        `json:"division"`
+	Tracks   [][]jsonEvent `json:"tracks"`
+}
+
+/*
+jsonEvent is the JSON form of a TrackEvent. Only the fields that apply to its
+type are set.
+*/
+type jsonEvent struct {
+	Tick       uint64  `json:"tick"`
+	Time       float64 `json:"time"`
+	Type       string  `json:"type"`
+	Channel    *int    `json:"channel,omitempty"`
+	Key        *int    `json:"key,omitempty"`
+	Velocity   *int    `json:"velocity,omitempty"`
+	Controller *int    `json:"controller,omitempty"`
+	Value      *int    `json:"value,omitempty"`
+	Program    *int    `json:"program,omitempty"`
+	Pressure   *int    `json:"pressure,omitempty"`
+	Bend       *int    `json:"bend,omitempty"`
+	MetaType   *int    `json:"meta_type,omitempty"`
+	Text       *string `json:"text,omitempty"`
+	Data       string  `json:"data,omitempty"`
+	Name       string  `json:"name,omitempty"`
+}
+
+/*
+MarshalJSON encodes the Midi as JSON for tools and people to read, diff and
+edit. This method satisfies the json.Marshaler interface. The file holds its
+format and division and, for each track, a list of events with their absolute
+tick, their time in seconds from the tempo map and a type, such as "note_on",
+"control_change" or "meta". Channel events have their channel and data bytes as
+named fields, with pitch bends as a signed value from -8192 to 8191. Meta
+events have their type and either the text of text events or hex encoded data,
+as do system exclusive events. Program and Control Changes, and notes on the
+percussion channel, also carry their General MIDI name, which UnmarshalJSON
+ignores. Events are validated as by MarshalBinary.
+*/
+func (m *Midi) MarshalJSON() ([]byte, error) {
+	if m.HeaderChunk == nil {
+		return nil, ErrMissingHeader
+	}
+	tempoMap := m.TempoMap()
+	file := jsonMidi{Format: m.Format, Division: m.Division,
+		Tracks: make([][]jsonEvent, len(m.TrackChunks))}
+	for i := range m.TrackChunks {
+		track := &m.TrackChunks[i]
+		if err := track.validate(i); err != nil {
+			return nil, err
+		}
+		file.Tracks[i] = make([]jsonEvent, len(track.TrackEvents))
+		for j, tick := range track.AbsoluteTicks() {
+			file.Tracks[i][j] = newJSONEvent(
+				&track.TrackEvents[j], tick, tempoMap.Time(tick))
+		}
+	}
+	return json.Marshal(file)
+}
+
+/*
+UnmarshalJSON decodes JSON written by MarshalJSON into the Midi, replacing its
+contents. This method satisfies the json.Unmarshaler interface. Times are
+ignored in favor of ticks, which must not decrease within a track, and data
+fields left out are taken to be 0. Errors name the track and event at fault.
+*/
+func (m *Midi) UnmarshalJSON(data []byte) error {
+	var file jsonMidi
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	tracks := make([]TrackChunk, len(file.Tracks))
+	for i, events := range file.Tracks {
+		track := &tracks[i]
+		track.TrackEvents = make([]TrackEvent, len(events))
+		var previous uint64
+		for j := range events {
+			event, err := events[j].trackEvent()
+			if err == nil && events[j].Tick < previous {
+				err = fmt.Errorf(JSONTickError, events[j].Tick, previous)
+			}
+			if err != nil {
+				return fmt.Errorf(JSONEventError, i, j, err)
+			}
+			event.DeltaTime = int(events[j].Tick - previous)
+			previous = events[j].Tick
+			track.TrackEvents[j] = event
+		}
+		if err := track.validate(i); err != nil {
+			return err
+		}
+		track.Chunk = &Chunk{Type: trackChunk, Length: track.dataLength()}
+	}
+	m.HeaderChunk = &HeaderChunk{
+		Chunk:    &Chunk{Type: headerChunk, Length: headerDataSize},
+		Format:   file.Format,
+		Ntrks:    uint16(len(tracks)),
+		Division: file.Division,
+	}
+	m.TrackChunks = tracks
+	m.Reindex()
+	return nil
+}
+
+// newJSONEvent returns the JSON form of a validated event.
+func newJSONEvent(
+	event *TrackEvent, tick uint64, time time.Duration) jsonEvent {
+	status := event.Status()
+	result := jsonEvent{Tick: tick, Time: time.Seconds()}
+	switch {
+	case status == MetaEvent:
+		result.Type = jsonEventTypes[status]
+		result.MetaType = jsonInt(int(event.Data[1]))
+		payload := event.Data[2:]
+		// Meta types 1 to 15 are reserved for text events.
+		if event.Data[1] >= 0x01 && event.Data[1] <= 0x0F &&
+			utf8.Valid(payload) {
+			text := string(payload)
+			result.Text = &text
+		} else {
+			result.Data = hex.EncodeToString(payload)
+		}
+	case status == SysExEvent || status == SysExEscape:
+		result.Type = jsonEventTypes[status]
+		result.Data = hex.EncodeToString(event.Data[1:])
+	default:
+		result.Type = jsonEventTypes[status&highOrderMask]
+		result.Channel = jsonInt(int(event.Channel()))
+		values := result.dataFields(status & highOrderMask)
+		for i, value := range event.Data[1:] {
+			*values[i] = jsonInt(int(value))
+		}
+		if status&highOrderMask == PitchWheelChange {
+			result.Bend = jsonInt(*result.Bend +
+				*result.Value<<7 - pitchWheelCenter)
+			result.Value = nil
+		}
+		result.Name = generalMIDIName(event)
+	}
+	return result
+}
+
+/*
+generalMIDIName returns the General MIDI name of the instrument selected by a
+Program Change, the controller set by a Control Change, or the percussion sound
+of a note on the percussion channel. It returns "" for other events.
+*/
+func generalMIDIName(event *TrackEvent) string {
+	switch event.Status() & highOrderMask {
+	case ProgramChange:
+		return gm.ProgramName(event.Data[1])
+	case ControlChange:
+		return gm.ControllerName(event.Data[1])
+	case NoteOffEvent, NoteOnEvent, PolyphonicKeyPressure:
+		if event.Channel() == gm.PercussionChannel {
+			return gm.PercussionName(event.Data[1])
+		}
+	}
+	return ""
+}
+
+/*
+dataFields returns the fields holding the data bytes of channel events of the
+given type, in order. The two bytes of a pitch bend are held in Bend and Value
+until they are combined.
+*/
+func (e *jsonEvent) dataFields(eventType byte) []**int {
+	switch eventType {
+	case NoteOffEvent, NoteOnEvent:
+		return []**int{&e.Key, &e.Velocity}
+	case PolyphonicKeyPressure:
+		return []**int{&e.Key, &e.Pressure}
+	case ControlChange:
+		return []**int{&e.Controller, &e.Value}
+	case ProgramChange:
+		return []**int{&e.Program}
+	case ChannelPressure:
+		return []**int{&e.Pressure}
+	case PitchWheelChange:
+		return []**int{&e.Bend, &e.Value}
+	}
+	return nil
+}
+
+// trackEvent returns the event described by e, without its delta-time.
+func (e *jsonEvent) trackEvent() (TrackEvent, error) {
+	var status byte
+	for candidate, name := range jsonEventTypes {
+		if name == e.Type {
+			status = candidate
+		}
+	}
+	switch status {
+	case 0:
+		return TrackEvent{}, fmt.Errorf(JSONTypeError, e.Type)
+	case MetaEvent:
+		metaType, err := jsonByte("meta type", e.MetaType)
+		if err != nil {
+			return TrackEvent{}, err
+		}
+		payload := []byte{}
+		if e.Text != nil {
+			payload = []byte(*e.Text)
+		} else if payload, err = hex.DecodeString(e.Data); err != nil {
+			return TrackEvent{}, err
+		}
+		return TrackEvent{
+			Data: append([]byte{MetaEvent, metaType}, payload...)}, nil
+	case SysExEvent, SysExEscape:
+		payload, err := hex.DecodeString(e.Data)
+		if err != nil {
+			return TrackEvent{}, err
+		}
+		return TrackEvent{Data: append([]byte{status}, payload...)}, nil
+	}
+
+	channel := 0
+	if e.Channel != nil {
+		channel = *e.Channel
+	}
+	if channel < 0 || channel > 15 {
+		return TrackEvent{}, fmt.Errorf(ChannelRangeError, channel)
+	}
+	event := TrackEvent{Data: []byte{status | byte(channel)}}
+	if status == PitchWheelChange {
+		bend := 0
+		if e.Bend != nil {
+			bend = *e.Bend
+		}
+		if bend < -pitchWheelCenter || bend >= pitchWheelCenter {
+			return TrackEvent{}, fmt.Errorf(JSONBendError, bend)
+		}
+		value := bend + pitchWheelCenter
+		event.Data = append(event.Data, byte(value&sevenBitMask),
+			byte(value>>7))
+		return event, nil
+	}
+	for i, field := range e.dataFields(status) {
+		value, err := jsonByte(dataByteNames[status][i], *field)
+		if err != nil {
+			return TrackEvent{}, err
+		}
+		event.Data = append(event.Data, value)
+	}
+	return event, nil
+}
+
+// jsonInt returns a pointer to value, for the optional fields of a jsonEvent.
+func jsonInt(value int) *int {
+	return &value
+}
+
+/*
+jsonByte returns the data byte held by an optional field, which is 0 when the
+field is missing. name describes the field in errors.
+*/
+func jsonByte(name string, value *int) (byte, error) {
+	if value == nil {
+		return 0, nil
+	}
+	if *value < 0 || *value > sevenBitMask {
+		return 0, fmt.Errorf(DataValueError, name, *value)
+	}
+	return byte(*value), nil
+}
@@ -0,0 +1,104 @@
+package midi_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	var buffer bytes.Buffer
+	writeHeader(&buffer, 1, 1, 480)
+	writeTrack(&buffer, []byte{
+		0x00, 0xFF, 0x03, 0x04, 'L', 'e', 'a', 'd',
+		0x00, 0xFF, 0x51, 0x03, 0x07, 0xA1, 0x20,
+		0x00, 0xF0, 0x03, 0x7E, 0x09, 0xF7,
+		0x00, 0xC1, 0x05,
+		0x00, 0xB1, 0x07, 0x64,
+		0x00, 0xE1, 0x00, 0x50,
+		0x00, 0x91, 0x3C, 0x40,
+		0x83, 0x60, 0x81, 0x3C, 0x00,
+		0x00, 0xA1, 0x3C, 0x10,
+		0x00, 0xD1, 0x20,
+		0x00, 0xFF, 0x2F, 0x00,
+	})
+	midi, err := ParseMidi(buffer.Bytes(), nil)
+	assert.Nil(t, err)
+
+	data, err := json.Marshal(midi)
+	assert.Nil(t, err)
+	parsed := new(Midi)
+	assert.Nil(t, json.Unmarshal(data, parsed))
+	assert.Equal(t, midi.Format, parsed.Format)
+	assert.Equal(t, midi.Division, parsed.Division)
+	assert.Equal(t, midi.Ntrks, parsed.Ntrks)
+	assert.Equal(t, midi.TrackChunks[0].TrackEvents,
+		parsed.TrackChunks[0].TrackEvents)
+	assert.Equal(t, midi.TrackChunks[0].Length, parsed.TrackChunks[0].Length)
+
+	var file struct {
+		Tracks [][]map[string]interface{} `json:"tracks"`
+	}
+	assert.Nil(t, json.Unmarshal(data, &file))
+	events := file.Tracks[0]
+	assert.Equal(t, map[string]interface{}{"tick": 0.0, "time": 0.0,
+		"type": "meta", "meta_type": 3.0, "text": "Lead"}, events[0])
+	assert.Equal(t, map[string]interface{}{"tick": 0.0, "time": 0.0,
+		"type": "sysex", "data": "7e09f7"}, events[2])
+	assert.Equal(t, map[string]interface{}{"tick": 0.0, "time": 0.0,
+		"type": "pitch_bend", "channel": 1.0, "bend": 2048.0}, events[5])
+	// 480 ticks is one beat, half a second at 120 beats per minute.
+	assert.Equal(t, map[string]interface{}{"tick": 480.0, "time": 0.5,
+		"type": "note_off", "channel": 1.0, "key": 60.0, "velocity": 0.0},
+		events[7])
+	// Program and Control Changes carry their General MIDI names.
+	assert.Equal(t, map[string]interface{}{"tick": 0.0, "time": 0.0,
+		"type": "program_change", "channel": 1.0, "program": 5.0,
+		"name": "Electric Piano 2"}, events[3])
+	assert.Equal(t, "Channel Volume", events[4]["name"])
+}
+
+func TestJSONPercussionNames(t *testing.T) {
+	midi := newTrackMidi(
+		TrackEvent{0, []byte{0x99, 38, 100}},
+		TrackEvent{0, []byte{0x90, 38, 100}},
+	)
+	data, err := json.Marshal(midi)
+	assert.Nil(t, err)
+	var file struct {
+		Tracks [][]map[string]interface{} `json:"tracks"`
+	}
+	assert.Nil(t, json.Unmarshal(data, &file))
+	assert.Equal(t, "Acoustic Snare", file.Tracks[0][0]["name"])
+	_, named := file.Tracks[0][1]["name"]
+	assert.False(t, named)
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+	for _, test := range []struct {
+		events string
+		err    string
+	}{
+		{`{"type": "wobble"}`, `track 0, event 1: unknown event type "wobble"`},
+		{`{"tick": 5, "type": "note_on", "key": 200}`,
+			"track 0, event 1: key of 200 is out of range 0-127"},
+		{`{"type": "note_on", "channel": 16}`,
+			"track 0, event 1: channel 16 is out of range 0-15"},
+		{`{"type": "pitch_bend", "bend": 8192}`,
+			"track 0, event 1: bend of 8192 is out of range -8192 to 8191"},
+		{`{"type": "sysex", "data": "zz"}`, "track 0, event 1: .*invalid"},
+		{`{"tick": 1, "type": "program_change"}`,
+			"track 0, event 1: tick 1 is before the previous event at tick 10"},
+	} {
+		data := `{"format": 0, "division": 96, "tracks": [[
+			{"tick": 10, "type": "note_on", "key": 60, "velocity": 1},
+			` + test.events + `]]}`
+		err := new(Midi).UnmarshalJSON([]byte(data))
+		assert.NotEqual(t, "", regexp.MustCompile(test.err).FindString(
+			err.Error()), test.err)
+	}
+}
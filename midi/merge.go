@@ -0,0 +1,111 @@
+package midi
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	MergeFormatError = "cannot merge the independent sequences of a format %v file"
+)
+
+/*
+timedEvent pairs a TrackEvent with the index of the track it came from and its
+absolute position in ticks.
+*/
+type timedEvent struct {
+	track int
+	tick  uint64
+	event TrackEvent
+}
+
+/*
+mergedEvents returns the events of every track in m ordered by absolute tick.
+Events at the same tick keep the order of their tracks, and within a track,
+their original order, so the tempo map in the first track of a format 1 file
+precedes the notes it affects.
+*/
+func (m *Midi) mergedEvents() []timedEvent {
+	var events []timedEvent
+	for index := range m.TrackChunks {
+		track := &m.TrackChunks[index]
+		for i, tick := range track.AbsoluteTicks() {
+			events = append(
+				events, timedEvent{index, tick, track.TrackEvents[i]})
+		}
+	}
+	sort.SliceStable(events, func(a, b int) bool {
+		return events[a].tick < events[b].tick
+	})
+	return events
+}
+
+/*
+MergeTracks converts a format 1 file into a single track format 0 file. Events
+from every track are merged in absolute time order and their delta-times are
+re-encoded, keeping tempo, time signature and all other meta events. The End of
+Track events of the original tracks are replaced by a single one placed at the
+end of the longest track. Format 2 files hold independent sequences that cannot
+be merged, and a non-nil error is returned for them.
+*/
+func (m *Midi) MergeTracks() error {
+	if m.Format == 2 {
+		return fmt.Errorf(MergeFormatError, m.Format)
+	}
+	var last uint64
+	merged := &TrackChunk{Chunk: &Chunk{Type: trackChunk}}
+	var ticks []uint64
+	for _, timed := range m.mergedEvents() {
+		if timed.tick > last {
+			last = timed.tick
+		}
+		if timed.event.IsMeta(MetaEndOfTrack) {
+			continue
+		}
+		merged.TrackEvents = append(merged.TrackEvents, timed.event)
+		ticks = append(ticks, timed.tick)
+	}
+	merged.TrackEvents = append(
+		merged.TrackEvents, TrackEvent{Data: []byte{MetaEvent, MetaEndOfTrack}})
+	ticks = append(ticks, last)
+	merged.setAbsoluteTicks(ticks)
+	merged.Length = merged.dataLength()
+
+	m.TrackChunks = []TrackChunk{*merged}
+	m.Format = 0
+	m.Ntrks = 1
+	m.Reindex()
+	return nil
+}
+
+/*
+dataLength returns the number of bytes the track's events occupy when encoded,
+which is the Length of its chunk. Running status is not assumed.
+*/
+func (t *TrackChunk) dataLength() uint32 {
+	var length int
+	for i := range t.TrackEvents {
+		event := &t.TrackEvents[i]
+		length += variableLengthQuantitySize(uint64(event.DeltaTime))
+		length += len(event.Data)
+		switch status := event.Status(); {
+		case status == MetaEvent && len(event.Data) > 1:
+			length += variableLengthQuantitySize(uint64(len(event.Data) - 2))
+		case status == SysExEvent || status == SysExEscape:
+			length += variableLengthQuantitySize(uint64(len(event.Data) - 1))
+		}
+	}
+	return uint32(length)
+}
+
+/*
+variableLengthQuantitySize returns the number of bytes needed to encode value as
+a variable length quantity.
+*/
+func variableLengthQuantitySize(value uint64) int {
+	size := 1
+	for value >>= 7; value > 0; value >>= 7 {
+		size++
+	}
+	return size
+}
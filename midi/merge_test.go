@@ -0,0 +1,56 @@
+package midi_test
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeTracks(t *testing.T) {
+	var buffer bytes.Buffer
+	writeHeader(&buffer, 1, 2, 96)
+	// A tempo track with a tempo change at tick 0 and tick 96.
+	writeTrack(&buffer, []byte{
+		0x00, 0xFF, 0x51, 0x03, 0x07, 0xA1, 0x20,
+		0x60, 0xFF, 0x51, 0x03, 0x0F, 0x42, 0x40,
+		0x00, 0xFF, 0x2F, 0x00,
+	})
+	// A note track playing from tick 0 to 192.
+	writeTrack(&buffer, []byte{
+		0x00, 0x90, 0x3C, 0x40,
+		0x81, 0x40, 0x80, 0x3C, 0x00,
+		0x00, 0xFF, 0x2F, 0x00,
+	})
+	midi, err := ParseMidi(buffer.Bytes(), nil)
+	assert.Nil(t, err)
+
+	assert.Nil(t, midi.MergeTracks())
+	assert.Equal(t, uint16(0), midi.Format)
+	assert.Equal(t, uint16(1), midi.Ntrks)
+	assert.Equal(t, 1, len(midi.TrackChunks))
+
+	track := midi.TrackChunks[0]
+	assert.Equal(t, []uint64{0, 0, 96, 192, 192}, track.AbsoluteTicks())
+	// The tempo event precedes the note at the same tick.
+	assert.True(t, track.TrackEvents[0].IsMeta(MetaSetTempo))
+	assert.True(t, track.TrackEvents[1].IsNoteOn())
+	assert.True(t, track.TrackEvents[2].IsMeta(MetaSetTempo))
+	assert.True(t, track.TrackEvents[3].IsNoteOff())
+	assert.True(t, track.TrackEvents[4].IsMeta(MetaEndOfTrack))
+	// 2 tempo events of 7 bytes and 3 events of 4 bytes, as the note off
+	// now has a single byte delta-time.
+	assert.Equal(t, uint32(26), track.Length)
+	assert.Equal(t, [4]byte{'M', 'T', 'r', 'k'}, track.Type)
+}
+
+func TestMergeTracksFormat2(t *testing.T) {
+	midi := newTrackMidi()
+	midi.Format = 2
+	err := midi.MergeTracks()
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("independent sequences of a format 2 file")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
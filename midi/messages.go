@@ -0,0 +1,145 @@
+package midi
+
+/*
+This file contains typed representations of the channel voice messages. Each
+holds the channel and the data bytes of one message, already split into their
+fields.
+*/
+
+/*
+ChannelMessage is implemented by the typed channel voice messages. Bytes
+returns the message as it is stored in TrackEvent.Data: the status byte,
+carrying the channel, followed by the data bytes.
+*/
+type ChannelMessage interface {
+	Bytes() []byte
+}
+
+// NoteOffMessage releases Key on Channel with the given release Velocity.
+type NoteOffMessage struct {
+	Channel  uint8
+	Key      uint8
+	Velocity uint8
+}
+
+func (m NoteOffMessage) Bytes() []byte {
+	return []byte{NoteOffEvent | m.Channel&lowOrderMasl, m.Key, m.Velocity}
+}
+
+/*
+NoteOnMessage strikes Key on Channel. A Velocity of 0 releases the key instead,
+as the MIDI spec requires.
+*/
+type NoteOnMessage struct {
+	Channel  uint8
+	Key      uint8
+	Velocity uint8
+}
+
+func (m NoteOnMessage) Bytes() []byte {
+	return []byte{NoteOnEvent | m.Channel&lowOrderMasl, m.Key, m.Velocity}
+}
+
+// KeyPressureMessage is the aftertouch Value of a single held Key.
+type KeyPressureMessage struct {
+	Channel uint8
+	Key     uint8
+	Value   uint8
+}
+
+func (m KeyPressureMessage) Bytes() []byte {
+	return []byte{
+		PolyphonicKeyPressure | m.Channel&lowOrderMasl, m.Key, m.Value}
+}
+
+// ControlChangeMessage sets Controller on Channel to Value.
+type ControlChangeMessage struct {
+	Channel    uint8
+	Controller uint8
+	Value      uint8
+}
+
+func (m ControlChangeMessage) Bytes() []byte {
+	return []byte{ControlChange | m.Channel&lowOrderMasl, m.Controller, m.Value}
+}
+
+// ProgramChangeMessage selects the instrument Program for Channel.
+type ProgramChangeMessage struct {
+	Channel uint8
+	Program uint8
+}
+
+func (m ProgramChangeMessage) Bytes() []byte {
+	return []byte{ProgramChange | m.Channel&lowOrderMasl, m.Program}
+}
+
+// ChannelPressureMessage is the aftertouch Value applied to a whole Channel.
+type ChannelPressureMessage struct {
+	Channel uint8
+	Value   uint8
+}
+
+func (m ChannelPressureMessage) Bytes() []byte {
+	return []byte{ChannelPressure | m.Channel&lowOrderMasl, m.Value}
+}
+
+/*
+PitchWheelMessage moves the pitch wheel of Channel. Pitch is the signed 14-bit
+wheel position, from -8192 to 8191, with 0 at the centre.
+*/
+type PitchWheelMessage struct {
+	Channel uint8
+	Pitch   int16
+}
+
+func (m PitchWheelMessage) Bytes() []byte {
+	value := uint16(int(m.Pitch)+pitchWheelCenter) & 0x3FFF
+	return []byte{PitchWheelChange | m.Channel&lowOrderMasl,
+		byte(value & sevenBitMask), byte(value >> 7)}
+}
+
+/*
+Bend returns the position of the wheel as a fraction of the bend range, from
+-1 to just under 1, which is multiplied by the pitch bend sensitivity of the
+channel to give the bend in semitones.
+*/
+func (m PitchWheelMessage) Bend() float64 {
+	return float64(m.Pitch) / pitchWheelCenter
+}
+
+/*
+ChannelMessage returns the typed form of a channel voice message, or nil when
+the event is not one or is missing data bytes.
+*/
+func (e *TrackEvent) ChannelMessage() ChannelMessage {
+	return parseChannelMessage(e.Data)
+}
+
+/*
+parseChannelMessage splits data, a status byte followed by its data bytes, into
+the matching ChannelMessage. It returns nil if data is too short or its status
+is not a channel voice message.
+*/
+func parseChannelMessage(data []byte) ChannelMessage {
+	if len(data) == 0 || data[0] < NoteOffEvent || data[0] >= SysExEvent ||
+		len(data) < channelEventSize(data[0])+1 {
+		return nil
+	}
+	channel := data[0] & lowOrderMasl
+	switch data[0] & highOrderMask {
+	case NoteOffEvent:
+		return NoteOffMessage{channel, data[1], data[2]}
+	case NoteOnEvent:
+		return NoteOnMessage{channel, data[1], data[2]}
+	case PolyphonicKeyPressure:
+		return KeyPressureMessage{channel, data[1], data[2]}
+	case ControlChange:
+		return ControlChangeMessage{channel, data[1], data[2]}
+	case ProgramChange:
+		return ProgramChangeMessage{channel, data[1]}
+	case ChannelPressure:
+		return ChannelPressureMessage{channel, data[1]}
+	}
+	value := int(data[1]&sevenBitMask) | int(data[2]&sevenBitMask)<<7
+	return PitchWheelMessage{channel, int16(value - pitchWheelCenter)}
+}
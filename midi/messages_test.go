@@ -0,0 +1,55 @@
+package midi_test
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelMessages(t *testing.T) {
+	for _, test := range []struct {
+		data    []byte
+		message ChannelMessage
+	}{
+		{[]byte{0x81, 0x3C, 0x20}, NoteOffMessage{1, 0x3C, 0x20}},
+		{[]byte{0x92, 0x3C, 0x40}, NoteOnMessage{2, 0x3C, 0x40}},
+		{[]byte{0xA3, 0x3C, 0x10}, KeyPressureMessage{3, 0x3C, 0x10}},
+		{[]byte{0xB4, 0x07, 0x64}, ControlChangeMessage{4, 0x07, 0x64}},
+		{[]byte{0xC5, 0x19}, ProgramChangeMessage{5, 0x19}},
+		{[]byte{0xD6, 0x30}, ChannelPressureMessage{6, 0x30}},
+		{[]byte{0xE7, 0x00, 0x40}, PitchWheelMessage{7, 0}},
+		{[]byte{0xEF, 0x00, 0x00}, PitchWheelMessage{15, -8192}},
+		{[]byte{0xE0, 0x7F, 0x7F}, PitchWheelMessage{0, 8191}},
+	} {
+		event := TrackEvent{0, test.data}
+		assert.Equal(t, test.message, event.ChannelMessage())
+		assert.Equal(t, test.data, test.message.Bytes())
+	}
+
+	for _, data := range [][]byte{
+		nil, {0x90, 0x3C}, {0xC0}, {0xFF, 0x2F}, {0xF0, 0x7E},
+	} {
+		event := TrackEvent{0, data}
+		assert.Nil(t, event.ChannelMessage())
+	}
+}
+
+func TestMidiEventProcessor(t *testing.T) {
+	parser := NewMidiParser()
+	processor := parser.Factory(0xB2).ConstructProcessor(0xB2)
+	midiProcessor, ok := processor.(*MidiEventProcessor)
+	assert.True(t, ok)
+	assert.Nil(t, midiProcessor.Message())
+
+	err := processor.Process(bytes.NewReader([]byte{0x40, 0x7F, 0x90}))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0xB2, 0x40, 0x7F}, processor.Event().Data)
+	assert.Equal(t, ControlChangeMessage{2, 0x40, 0x7F},
+		midiProcessor.Message())
+
+	processor = parser.Factory(0xC0).ConstructProcessor(0xC0)
+	assert.NotNil(t, processor.Process(bytes.NewReader(nil)))
+	assert.Nil(t, processor.(*MidiEventProcessor).Message())
+}
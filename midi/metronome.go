@@ -0,0 +1,233 @@
+package midi
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/midi/gm"
+)
+
+const (
+	// accentKey and beatKey are the General MIDI percussion keys of metronome
+	// clicks on the first and the other beats of a bar, the Hi and Low Wood
+	// Blocks.
+	accentKey = 76
+	beatKey   = 77
+	// clickDuration is the length of a rendered metronome click.
+	clickDuration = 20 * time.Millisecond
+)
+
+var (
+	// ErrSMPTEMetronome is returned when asked for the beats of a Midi whose
+	// division counts SMPTE frames, which has no quarter notes.
+	ErrSMPTEMetronome = errors.New(
+		"metronome requires a ticks per quarter note division")
+	// ErrMultiSequenceMetronome is returned when adding a metronome track to
+	// a format 2 file, whose tracks do not share a timeline.
+	ErrMultiSequenceMetronome = errors.New(
+		"cannot add a metronome track to a format 2 file")
+)
+
+/*
+A TimeSignature records a Time Signature meta event: Numerator beats to the bar,
+each a 1/Denominator note, from Tick on.
+*/
+type TimeSignature struct {
+	Tick        uint64
+	Numerator   uint8
+	Denominator uint16
+}
+
+/*
+TimeSignatures returns the Time Signature events of m in tick order, beginning
+with the 4/4 in effect when no event occurs at tick 0. A later event at the same
+tick replaces an earlier one, and events with malformed payloads are ignored. As
+with TempoMap, only the first track is read for a format 2 file.
+*/
+func (m *Midi) TimeSignatures() []TimeSignature {
+	events := m.mergedEvents()
+	if m.isMultiSequence() && len(m.TrackChunks) > 0 {
+		events = m.trackEvents(0)
+	}
+	signatures := []TimeSignature{{0, 4, 4}}
+	for _, timed := range events {
+		event := &timed.event
+		if !event.IsMeta(MetaTimeSignature) || len(event.Data) < 5 ||
+			event.Data[2] < 2 || event.Data[3] == 0 || event.Data[4] > 15 {
+			continue
+		}
+		signature := TimeSignature{
+			Tick:        timed.tick,
+			Numerator:   event.Data[3],
+			Denominator: 1 << event.Data[4],
+		}
+		if last := &signatures[len(signatures)-1]; last.Tick == timed.tick {
+			*last = signature
+		} else {
+			signatures = append(signatures, signature)
+		}
+	}
+	return signatures
+}
+
+/*
+A Beat is a metronome beat: beat Beat, counted from 1, of bar Bar, also counted
+from 1, at Tick and Time.
+*/
+type Beat struct {
+	Tick uint64
+	Time time.Duration
+	Bar  int
+	Beat int
+}
+
+/*
+Beats returns the beats of m from tick 0 up to, but not including, the end of
+its longest track, following its time signatures and timed by its tempo map. A
+beat is the note value of the signature's denominator, so 6/8 has six eighth
+note beats to the bar. A time signature that changes part way through a bar
+starts a new bar. ErrSMPTEMetronome is returned for an SMPTE division, and
+ErrMissingHeader for a Midi without a HeaderChunk.
+*/
+func (m *Midi) Beats() ([]Beat, error) {
+	if m.HeaderChunk == nil {
+		return nil, ErrMissingHeader
+	}
+	quarter, ok := m.TicksPerQuarterNote()
+	if !ok {
+		return nil, ErrSMPTEMetronome
+	}
+	tempoMap := m.TempoMap()
+	var end uint64
+	for i := range m.TrackChunks {
+		ticks := m.TrackChunks[i].AbsoluteTicks()
+		if len(ticks) > 0 && ticks[len(ticks)-1] > end {
+			end = ticks[len(ticks)-1]
+		}
+	}
+	signatures := m.TimeSignatures()
+	var beats []Beat
+	bar := 0
+	for i, signature := range signatures {
+		limit := end
+		if i+1 < len(signatures) && signatures[i+1].Tick < end {
+			limit = signatures[i+1].Tick
+		}
+		length := uint64(quarter) * 4 / uint64(signature.Denominator)
+		if length == 0 {
+			length = 1
+		}
+		for count, tick := 0, signature.Tick; tick < limit; tick += length {
+			if count%int(signature.Numerator) == 0 {
+				bar++
+			}
+			beats = append(beats, Beat{
+				Tick: tick,
+				Time: tempoMap.Time(tick),
+				Bar:  bar,
+				Beat: count%int(signature.Numerator) + 1,
+			})
+			count++
+		}
+	}
+	return beats, nil
+}
+
+/*
+MetronomeTrack returns a track with a click on every beat of m, a Hi Wood Block
+on the first beat of each bar and a Low Wood Block on the others, played on the
+General MIDI percussion channel. Each click lasts a sixteenth note.
+*/
+func (m *Midi) MetronomeTrack() (TrackChunk, error) {
+	beats, err := m.Beats()
+	if err != nil {
+		return TrackChunk{}, err
+	}
+	track := TrackChunk{Chunk: &Chunk{Type: trackChunk}}
+	var ticks []uint64
+	for i, beat := range beats {
+		key, velocity := byte(beatKey), byte(90)
+		if beat.Beat == 1 {
+			key, velocity = accentKey, 127
+		}
+		end := beat.Tick + uint64(m.Division)/4
+		if i+1 < len(beats) && beats[i+1].Tick < end {
+			end = beats[i+1].Tick
+		}
+		track.TrackEvents = append(track.TrackEvents,
+			TrackEvent{Data: []byte{
+				NoteOnEvent | gm.PercussionChannel, key, velocity}},
+			TrackEvent{Data: []byte{
+				NoteOffEvent | gm.PercussionChannel, key, 0}})
+		ticks = append(ticks, beat.Tick, end)
+	}
+	track.TrackEvents = append(
+		track.TrackEvents, TrackEvent{Data: []byte{MetaEvent, MetaEndOfTrack}})
+	if len(ticks) > 0 {
+		ticks = append(ticks, ticks[len(ticks)-1])
+	} else {
+		ticks = append(ticks, 0)
+	}
+	track.setAbsoluteTicks(ticks)
+	track.Length = track.dataLength()
+	return track, nil
+}
+
+/*
+AddMetronomeTrack appends MetronomeTrack to m, converting a format 0 file to
+format 1 so that it can hold the extra track. ErrMultiSequenceMetronome is
+returned for a format 2 file.
+*/
+func (m *Midi) AddMetronomeTrack() error {
+	if m.isMultiSequence() {
+		return ErrMultiSequenceMetronome
+	}
+	track, err := m.MetronomeTrack()
+	if err != nil {
+		return err
+	}
+	m.TrackChunks = append(m.TrackChunks, track)
+	m.Format = 1
+	m.Ntrks = uint16(len(m.TrackChunks))
+	m.Reindex()
+	return nil
+}
+
+/*
+RenderMetronome renders the beats of m as clicks in a Buffer of the given
+format, to align recordings with the file. Each click is a 20 ms decaying sine
+starting on the frame nearest its beat, at 1760 Hz and 0.8 of full scale on the
+first beat of each bar and at 880 Hz and 0.5 on the others. The Buffer lasts
+until the end of the last click.
+*/
+func (m *Midi) RenderMetronome(format audio.Format) (*audio.Buffer, error) {
+	beats, err := m.Beats()
+	if err != nil {
+		return nil, err
+	}
+	rate := float64(format.SampleRate)
+	length := int(clickDuration.Seconds() * rate)
+	var frames int
+	if len(beats) > 0 {
+		frames = int(math.Round(beats[len(beats)-1].Time.Seconds()*rate)) +
+			length
+	}
+	buffer := audio.NewBuffer(format, frames)
+	for _, beat := range beats {
+		start := int(math.Round(beat.Time.Seconds() * rate))
+		frequency, level := 880.0, 0.5
+		if beat.Beat == 1 {
+			frequency, level = 1760, 0.8
+		}
+		for i := 0; i < length && start+i < frames; i++ {
+			value := level * math.Exp(-5*float64(i)/float64(length)) *
+				math.Sin(2*math.Pi*frequency*float64(i)/rate)
+			for channel := range buffer.Frame(start + i) {
+				buffer.Frame(start + i)[channel] = value
+			}
+		}
+	}
+	return buffer, nil
+}
@@ -0,0 +1,107 @@
+package midi_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/husafan/audio"
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+// newMeterMidi returns a Midi with two bars of 3/4 followed by a bar of 6/8.
+func newMeterMidi() *Midi {
+	return newTrackMidi(
+		TrackEvent{0, []byte{0xFF, 0x58, 0x04, 3, 2, 24, 8}},
+		TrackEvent{0, []byte{0x90, 60, 100}},
+		TrackEvent{576, []byte{0x80, 60, 0}},
+		TrackEvent{0, []byte{0xFF, 0x58, 0x04, 6, 3, 36, 8}},
+		TrackEvent{288, []byte{0xFF, 0x2F}},
+	)
+}
+
+func TestTimeSignatures(t *testing.T) {
+	assert.Equal(t, []TimeSignature{{0, 4, 4}},
+		newTrackMidi(TrackEvent{0, []byte{0xFF, 0x2F}}).TimeSignatures())
+	assert.Equal(t, []TimeSignature{{0, 3, 4}, {576, 6, 8}},
+		newMeterMidi().TimeSignatures())
+}
+
+func TestBeats(t *testing.T) {
+	beats, err := newMeterMidi().Beats()
+	assert.Nil(t, err)
+	assert.Equal(t, 12, len(beats))
+	for i, beat := range beats[:6] {
+		assert.Equal(t, uint64(i*96), beat.Tick)
+		assert.Equal(t, time.Duration(i)*500*time.Millisecond, beat.Time)
+		assert.Equal(t, i/3+1, beat.Bar)
+		assert.Equal(t, i%3+1, beat.Beat)
+	}
+	for i, beat := range beats[6:] {
+		assert.Equal(t, uint64(576+i*48), beat.Tick)
+		assert.Equal(t, 3, beat.Bar)
+		assert.Equal(t, i+1, beat.Beat)
+	}
+
+	smpte := newTrackMidi()
+	smpte.Division = 0xE728
+	_, err = smpte.Beats()
+	assert.Equal(t, ErrSMPTEMetronome, err)
+	_, err = (&Midi{}).Beats()
+	assert.Equal(t, ErrMissingHeader, err)
+}
+
+func TestAddMetronomeTrack(t *testing.T) {
+	midi := newMeterMidi()
+	assert.Nil(t, midi.AddMetronomeTrack())
+	assert.Equal(t, uint16(1), midi.Format)
+	assert.Equal(t, uint16(2), midi.Ntrks)
+
+	data, err := midi.MarshalBinary()
+	assert.Nil(t, err)
+	parsed, err := ParseMidi(data, nil)
+	assert.Nil(t, err)
+	var clicks []Note
+	for _, note := range parsed.Notes() {
+		if note.Track == 1 {
+			clicks = append(clicks, note)
+		}
+	}
+	assert.Equal(t, 12, len(clicks))
+	for i, click := range clicks {
+		assert.Equal(t, uint8(9), click.Channel)
+		assert.Equal(t, uint64(24), click.DurationTicks)
+		if i == 0 || i == 3 || i == 6 {
+			assert.Equal(t, uint8(76), click.Key)
+			assert.Equal(t, uint8(127), click.Velocity)
+		} else {
+			assert.Equal(t, uint8(77), click.Key)
+		}
+	}
+
+	format2 := newSequenceMidi(2)
+	assert.Equal(t, ErrMultiSequenceMetronome, format2.AddMetronomeTrack())
+}
+
+func TestRenderMetronome(t *testing.T) {
+	format := audio.Format{SampleRate: 1000, Channels: 2}
+	buffer, err := newMeterMidi().RenderMetronome(format)
+	assert.Nil(t, err)
+	// The last beat is at 4.25 seconds and its click lasts 20 ms.
+	assert.Equal(t, 4270, buffer.Frames())
+	peak := func(start int) float64 {
+		var level float64
+		for i := start; i < start+20; i++ {
+			level = math.Max(level, math.Abs(buffer.Frame(i)[0]))
+			assert.Equal(t, buffer.Frame(i)[0], buffer.Frame(i)[1])
+		}
+		return level
+	}
+	assert.True(t, peak(0) > 0.5)
+	assert.True(t, peak(500) > 0.3 && peak(500) <= 0.5)
+	assert.True(t, peak(1500) > 0.5)
+	assert.Equal(t, 0.0, peak(250))
+	assert.Equal(t, 0.0, buffer.Frame(0)[0])
+	assert.NotEqual(t, 0.0, buffer.Frame(1501)[0])
+}
@@ -19,6 +19,22 @@ const (
 	ProgramChange         = 0xC0
 	ChannelPressure       = 0xD0
 	PitchWheelChange      = 0xE0
+
+	// The following status bytes introduce the non-channel events that can
+	// appear in a TrackEvent.
+	SysExEvent  = 0xF0
+	SysExEscape = 0xF7
+	MetaEvent   = 0xFF
+
+	// The following byte constants are the meta event types that the
+	// package interprets.
+	MetaCopyright     = 0x02
+	MetaTrackName     = 0x03
+	MetaMarker        = 0x06
+	MetaEndOfTrack    = 0x2F
+	MetaSetTempo      = 0x51
+	MetaTimeSignature = 0x58
+	MetaKeySignature  = 0x59
 )
 
 var (
@@ -33,6 +49,8 @@ http://goo.gl/rlEN0H
 type Midi struct {
 	*HeaderChunk
 	TrackChunks []TrackChunk
+
+	index *metaIndex
 }
 
 /*
@@ -80,6 +98,11 @@ The syntax of an MTrk event is very simple:
     <TrackEvent> = <delta-time><MidiEvent>
 <delta-time> is stored as a variable-length quantity. It represents the amount
 of time before the following event. Delta-times are always present, even when 0.
+
+Data always begins with the event's status byte, even when the file relied on
+running status. Meta events are stored as 0xFF, the meta type and the payload,
+and system exclusive events as 0xF0 (or 0xF7) followed by the payload; the
+length prefixes found in the file are not kept.
 */
 type TrackEvent struct {
 	DeltaTime int
@@ -10,23 +10,111 @@ package midi
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 )
 
 const (
-	HeaderSizeError = "expected a header length of 16 but found a length of %v"
+	HeaderSizeError        = "expected a header length of at least 6 but found a length of %v"
+	ChunkSizeError         = "chunk %s has a length of %v but only %v bytes remain"
+	EventSizeError         = "event of length %v exceeds the %v bytes remaining in the track"
+	InvalidStatusError     = "invalid status byte %#x"
+	RunningStatusError     = "data byte %#x found without a preceding status byte"
+	TooManyTracksError     = "file contains %v tracks; at most %v are allowed"
+	TooManyEventsError     = "file contains more than %v events"
+	TooManyTrackEventError = "track %v contains more than %v events"
+	ChunkTooLargeError     = "chunk %s has a length of %v; at most %v is allowed"
+
+	// headerDataSize is the number of bytes in the data section of a header
+	// chunk defined by the current MIDI specification.
+	headerDataSize = 6
 )
 
+/*
+ParseOptions bounds the amount of data ParseMidi is willing to accept. Services
+that parse untrusted MIDI files should set every limit, since a small file can
+otherwise declare an enormous number of tracks or events. MaxChunkSize bounds
+the length of each chunk following the header. A limit of 0 is not enforced.
+Parser parses the events of each track; when nil, a parser with only the
+built-in factories is used.
+*/
+type ParseOptions struct {
+	MaxTracks         int
+	MaxEventsPerTrack int
+	MaxEvents         int
+	MaxChunkSize      int
+	Parser            *MidiParser
+}
+
+/*
+ErrHeaderSize is returned when the header chunk's length is too small to hold
+its fields. Offset is the byte offset of the length in the file.
+*/
+type ErrHeaderSize struct {
+	Got    uint32
+	Offset int64
+}
+
+func (e ErrHeaderSize) Error() string {
+	return fmt.Sprintf(HeaderSizeError+" (at byte %v)", e.Got, e.Offset)
+}
+
+// ErrTooManyTracks is returned when a file exceeds ParseOptions.MaxTracks.
+type ErrTooManyTracks struct {
+	Max, Got int
+}
+
+func (e ErrTooManyTracks) Error() string {
+	return fmt.Sprintf(TooManyTracksError, e.Got, e.Max)
+}
+
+/*
+ErrTooManyTrackEvents is returned when a single track exceeds
+ParseOptions.MaxEventsPerTrack. Track is the index of the offending track.
+*/
+type ErrTooManyTrackEvents struct {
+	Track, Max int
+}
+
+func (e ErrTooManyTrackEvents) Error() string {
+	return fmt.Sprintf(TooManyTrackEventError, e.Track, e.Max)
+}
+
+// ErrChunkTooLarge is returned when a chunk exceeds ParseOptions.MaxChunkSize.
+type ErrChunkTooLarge struct {
+	Type     string
+	Max, Got int
+}
+
+func (e ErrChunkTooLarge) Error() string {
+	return fmt.Sprintf(ChunkTooLargeError, e.Type, e.Got, e.Max)
+}
+
+// ErrTooManyEvents is returned when a file exceeds ParseOptions.MaxEvents.
+type ErrTooManyEvents struct {
+	Max int
+}
+
+func (e ErrTooManyEvents) Error() string {
+	return fmt.Sprintf(TooManyEventsError, e.Max)
+}
+
 /*
 The EventProcessor interface provides an API for parsing bytes out of a MIDI
 file to construct a TrackEvent. At its core, each EventProcessor should be able
 to create a fully constructed TrackEvent type. Because EventProcessors are
 created by factories that have "claimed" the current event, if there is a
 failure in parsing, the EventProcessor should return a non-nil error.
+
+Process is handed a reader positioned after the status byte, which has already
+been consumed; under running status it was never present. Once Process returns
+nil, Event returns the event with its status byte first in Data. The MidiParser
+fills in the DeltaTime.
 */
 type EventProcessor interface {
 	Process(reader io.ByteReader) error
+	Event() TrackEvent
 }
 
 /*
@@ -53,48 +141,216 @@ func isLastByte(b *byte) bool {
 	return *b&msbMask != msbMask
 }
 
+/*
+MaxVariableLengthQuantity is the largest value a variable length quantity may
+hold. The MIDI file spec limits quantities to 4 bytes of 7 bits each.
+*/
+const MaxVariableLengthQuantity = 0x0FFFFFFF
+
+/*
+ErrQuantityTooLong is returned for a variable length quantity longer than 4
+bytes, or a value too large to be written as one.
+*/
+var ErrQuantityTooLong = errors.New(
+	"variable length quantity is longer than 4 bytes")
+
 /*
 ReadVariableLengthQuantity consumes bytes from a io.Reader according to the
 variable length quantity format, where each byte in the sequence, except the
-last, has a 1 in the most significant bit. It returns a uint64 containing the
-value of the sequence.
-*/
-func ReadVariableLengthQuantity(reader io.ByteReader) uint64 {
-	value := uint64(0)
-	current, err := reader.ReadByte()
-	for i := 0; i < 8 && err == nil; i++ {
-		value = value<<7 + uint64(current&sevenBitMask)
+last, has a 1 in the most significant bit. It returns the value of the sequence
+and the number of bytes consumed. io.EOF is returned if reader is empty, and
+io.ErrUnexpectedEOF if it ends within the sequence. Sequences longer than 4
+bytes return ErrQuantityTooLong after consuming 4 bytes.
+*/
+func ReadVariableLengthQuantity(reader io.ByteReader) (uint64, int, error) {
+	var value uint64
+	for n := 1; n <= 4; n++ {
+		current, err := reader.ReadByte()
+		if err == io.EOF && n > 1 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return value, n - 1, err
+		}
+		value = value<<7 | uint64(current&sevenBitMask)
 		if isLastByte(&current) {
-			break
+			return value, n, nil
 		}
-		current, err = reader.ReadByte()
 	}
-	return value
+	return value, 4, ErrQuantityTooLong
 }
 
 /*
 UnmarshalBinary reads in bytes from data and populates the Midi receiver. This
-method satisfies the encoder.BinaryUnmarshaler interface.
+method satisfies the encoder.BinaryUnmarshaler interface. No limits are placed
+on the size of the file; use ParseMidi when the data is untrusted.
 */
 func (m *Midi) UnmarshalBinary(data []byte) error {
-	buffer := bytes.NewBuffer(data)
-	if err := m.unmarshalHeaderChunk(buffer); err != nil {
+	return m.unmarshal(data, new(ParseOptions))
+}
+
+/*
+ParseMidi parses data into a new Midi, enforcing the limits in options. A nil
+options places no limits on the file. When a limit is exceeded, the returned
+error is one of ErrTooManyTracks, ErrTooManyTrackEvents, ErrTooManyEvents or
+ErrChunkTooLarge.
+*/
+func ParseMidi(data []byte, options *ParseOptions) (*Midi, error) {
+	if options == nil {
+		options = new(ParseOptions)
+	}
+	m := new(Midi)
+	if err := m.unmarshal(data, options); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+/*
+unmarshal parses the header chunk followed by every track chunk in data. Chunks
+with an unrecognized type are skipped, as required by the MIDI file spec.
+*/
+func (m *Midi) unmarshal(data []byte, options *ParseOptions) error {
+	reader := bytes.NewReader(data)
+	if err := m.unmarshalHeaderChunk(reader); err != nil {
 		return err
 	}
+	if exceeds(int(m.Ntrks), options.MaxTracks) {
+		return ErrTooManyTracks{Max: options.MaxTracks, Got: int(m.Ntrks)}
+	}
+	parser := options.Parser
+	if parser == nil {
+		parser = defaultParser
+	}
+
+	m.TrackChunks = nil
+	var events int
+	for reader.Len() > 0 {
+		var chunk Chunk
+		if err := binary.Read(reader, binary.BigEndian, &chunk); err != nil {
+			return err
+		}
+		if int64(chunk.Length) > int64(reader.Len()) {
+			return fmt.Errorf(
+				ChunkSizeError, chunk.Type[:], chunk.Length, reader.Len())
+		}
+		if exceeds(int(chunk.Length), options.MaxChunkSize) {
+			return ErrChunkTooLarge{Type: string(chunk.Type[:]),
+				Max: options.MaxChunkSize, Got: int(chunk.Length)}
+		}
+		chunkData := make([]byte, chunk.Length)
+		reader.Read(chunkData)
+		if chunk.Type != trackChunk {
+			continue
+		}
+		if exceeds(len(m.TrackChunks)+1, options.MaxTracks) {
+			return ErrTooManyTracks{
+				Max: options.MaxTracks, Got: len(m.TrackChunks) + 1}
+		}
+
+		// A track may hold no more than MaxEventsPerTrack events, nor more
+		// than remain of the MaxEvents budget for the whole file.
+		limit, totalBound := -1, false
+		if options.MaxEventsPerTrack > 0 {
+			limit = options.MaxEventsPerTrack
+		}
+		if options.MaxEvents > 0 &&
+			(limit < 0 || options.MaxEvents-events < limit) {
+			limit, totalBound = options.MaxEvents-events, true
+		}
+		track, err := parser.parseTrack(chunkData, limit)
+		if err == errEventLimit && totalBound {
+			return ErrTooManyEvents{Max: options.MaxEvents}
+		} else if err == errEventLimit {
+			return ErrTooManyTrackEvents{
+				Track: len(m.TrackChunks),
+				Max:   options.MaxEventsPerTrack,
+			}
+		} else if err != nil {
+			return err
+		}
+		events += len(track)
+		chunkCopy := chunk
+		m.TrackChunks = append(
+			m.TrackChunks, TrackChunk{&chunkCopy, track})
+	}
+	m.Reindex()
 	return nil
 }
 
+// exceeds reports whether value is over limit, where a limit of 0 is unbounded.
+func exceeds(value, limit int) bool {
+	return limit > 0 && value > limit
+}
+
+// errEventLimit signals that parseTrack stopped at its event limit.
+var errEventLimit = errors.New("event limit reached")
+
+/*
+readEventPayload reads a variable length quantity followed by that many bytes,
+as found in meta and system exclusive events.
+*/
+func readEventPayload(reader io.ByteReader) ([]byte, error) {
+	length, _, err := ReadVariableLengthQuantity(reader)
+	if err != nil {
+		return nil, err
+	}
+	return readEventData(reader, length)
+}
+
+/*
+readEventData reads length bytes of event data. Readers that report how many
+bytes remain, like bytes.Reader, have length checked before anything is
+allocated; other readers fail with EventSizeError when they run out.
+*/
+func readEventData(reader io.ByteReader, length uint64) ([]byte, error) {
+	var data []byte
+	if sized, ok := reader.(interface{ Len() int }); ok {
+		if length > uint64(sized.Len()) {
+			return nil, fmt.Errorf(EventSizeError, length, sized.Len())
+		}
+		data = make([]byte, 0, length)
+	}
+	for uint64(len(data)) < length {
+		current, err := reader.ReadByte()
+		if err == io.EOF {
+			return nil, fmt.Errorf(EventSizeError, length, len(data))
+		} else if err != nil {
+			return nil, err
+		}
+		data = append(data, current)
+	}
+	return data, nil
+}
+
+/*
+channelEventSize returns the number of data bytes following a channel message
+status byte. Program Change and Channel Pressure carry one data byte; every
+other channel voice message carries two.
+*/
+func channelEventSize(status byte) int {
+	switch status & highOrderMask {
+	case ProgramChange, ChannelPressure:
+		return 1
+	}
+	return 2
+}
+
 /*
 The unmarshalHeaderChunk method parses out a Midi header chunk. If there is
-an error parsing out a valid header chunk, a non-nil error is returned.
+an error parsing out a valid header chunk, a non-nil error is returned. Header
+chunks longer than 6 bytes are accepted and their extra data ignored, as the
+spec reserves them for future extensions. An SMPTE division must name one of
+the four SMPTE frame rates.
 */
-func (m *Midi) unmarshalHeaderChunk(reader io.Reader) error {
+func (m *Midi) unmarshalHeaderChunk(reader *bytes.Reader) error {
 	var chunk Chunk
 	if err := binary.Read(reader, binary.BigEndian, &chunk); err != nil {
 		return err
 	}
-	if chunk.Length != uint32(16) {
-		return fmt.Errorf(HeaderSizeError, chunk.Length)
+	if chunk.Length < uint32(headerDataSize) {
+		offset := reader.Size() - int64(reader.Len()) - 4
+		return ErrHeaderSize{Got: chunk.Length, Offset: offset}
 	}
 	var format, ntrks, division uint16
 	if err := binary.Read(reader, binary.BigEndian, &format); err != nil {
@@ -106,13 +362,18 @@ func (m *Midi) unmarshalHeaderChunk(reader io.Reader) error {
 	if err := binary.Read(reader, binary.BigEndian, &division); err != nil {
 		return err
 	}
+	extra := int64(chunk.Length) - headerDataSize
+	if extra > int64(reader.Len()) {
+		return io.ErrUnexpectedEOF
+	}
+	reader.Seek(extra, io.SeekCurrent)
 	m.HeaderChunk = &HeaderChunk{
 		Chunk:    &chunk,
 		Format:   format,
 		Ntrks:    ntrks,
 		Division: division,
 	}
-	return nil
+	return m.validateDivision()
 }
 
 /*
@@ -120,19 +381,43 @@ MidiEventFactory creates MidiEventProcessors when channel voice messages are
 encountered. Given a byte, this factory will inspect the 4 high-order bits to
 determine whether they match any of the known channel voice message events.
 */
-type midiEventFactory struct {
-	last EventProcessor
-}
+type midiEventFactory struct{}
 
 func (*midiEventFactory) ConstructProcessor(midiByte byte) EventProcessor {
 	switch midiByte & highOrderMask {
-	case NoteOffEvent:
-	case NoteOnEvent:
-	case PolyphonicKeyPressure:
-	case ControlChange:
-	case ProgramChange:
-	case ChannelPressure:
-	case PitchWheelChange:
+	case NoteOffEvent, NoteOnEvent, PolyphonicKeyPressure, ControlChange,
+		ProgramChange, ChannelPressure, PitchWheelChange:
+		return &MidiEventProcessor{status: midiByte}
+	}
+	return nil
+}
+
+/*
+MidiEventProcessor reads the data bytes of a channel voice message. Once Process
+succeeds, Message returns the message as one of the typed ChannelMessage
+structs, such as NoteOnMessage or ControlChangeMessage.
+*/
+type MidiEventProcessor struct {
+	status  byte
+	data    []byte
+	message ChannelMessage
+}
+
+func (p *MidiEventProcessor) Process(reader io.ByteReader) error {
+	data, err := readEventData(reader, uint64(channelEventSize(p.status)))
+	if err != nil {
+		return err
 	}
+	p.data = append([]byte{p.status}, data...)
+	p.message = parseChannelMessage(p.data)
 	return nil
 }
+
+func (p *MidiEventProcessor) Event() TrackEvent {
+	return TrackEvent{Data: p.data}
+}
+
+// Message returns the processed message, or nil before Process succeeds.
+func (p *MidiEventProcessor) Message() ChannelMessage {
+	return p.message
+}
@@ -0,0 +1,154 @@
+/*
+The midi2 package reads and writes Universal MIDI Packets (UMP), the transport
+of MIDI 2.0. A packet is one to four 32-bit words whose first word names its
+message type and group. Packets carrying MIDI 1.0 messages, MIDI 2.0 channel
+voice messages, system messages and 7-bit system exclusive data can be
+translated to the midi package's TrackEvents, so existing tools work with
+either protocol. More information on the format can be found here:
+
+https://www.midi.org/specifications/universal-midi-packet-ump-and-midi-2-0-protocol-specification
+*/
+package midi2
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	EncodeStatusError = "cannot encode an event with status byte %#x as a packet"
+	EncodeSizeError   = "status byte %#x needs %v data bytes but the event has %v"
+	GroupError        = "group %v is out of range 0-15"
+	PacketSizeError   = "message type %#x needs %v words but %v were given"
+	PacketTrailError  = "%v bytes remain after the last packet; packets are 4 byte words"
+
+	// The following message types are those translated to and from MIDI
+	// 1.0 events.
+	UtilityMessage       = 0x0
+	SystemMessage        = 0x1
+	MIDI1ChannelVoice    = 0x2
+	SysEx7Message        = 0x3
+	MIDI2ChannelVoice    = 0x4
+	SysEx8Message        = 0x5
+	FlexDataMessage      = 0xD
+	StreamMessage        = 0xF
+	groupCount           = 16
+	sysEx7BytesPerPacket = 6
+
+	// The following are the status values of 7-bit system exclusive
+	// packets, which split a message across packets.
+	sysExComplete = 0x0
+	sysExStart    = 0x1
+	sysExContinue = 0x2
+	sysExEnd      = 0x3
+)
+
+/*
+packetWords holds the number of 32-bit words in a packet of each message type,
+as fixed by the UMP specification for reserved types too.
+*/
+var packetWords = [16]int{1, 1, 1, 2, 2, 4, 1, 1, 2, 2, 2, 3, 3, 4, 4, 4}
+
+/*
+A Packet is a single Universal MIDI Packet of one to four words, the length
+being fixed by its message type.
+*/
+type Packet []uint32
+
+// PacketWords returns the number of words in packets of messageType.
+func PacketWords(messageType uint8) int {
+	return packetWords[messageType&0x0F]
+}
+
+// MessageType returns the message type in the top 4 bits of the packet.
+func (p Packet) MessageType() uint8 {
+	return uint8(p[0] >> 28)
+}
+
+// Group returns the group, 0-15, the packet is addressed to.
+func (p Packet) Group() uint8 {
+	return uint8(p[0]>>24) & 0x0F
+}
+
+// status returns the third nibble of the packet, the status of most types.
+func (p Packet) status() uint8 {
+	return uint8(p[0]>>20) & 0x0F
+}
+
+// byteAt returns byte index of the packet, counting from the most significant.
+func (p Packet) byteAt(index int) byte {
+	return byte(p[index/4] >> (24 - 8*uint(index%4)))
+}
+
+/*
+Validate returns an error if the packet's length does not match its message
+type.
+*/
+func (p Packet) Validate() error {
+	if len(p) == 0 {
+		return fmt.Errorf(PacketSizeError, 0, 1, 0)
+	}
+	if words := PacketWords(p.MessageType()); len(p) != words {
+		return fmt.Errorf(PacketSizeError, p.MessageType(), words, len(p))
+	}
+	return nil
+}
+
+/*
+ReadPacket reads a packet of big-endian words from reader, reading the first
+word to learn how many follow. It returns io.EOF if reader has no more data and
+io.ErrUnexpectedEOF if it ends within a packet.
+*/
+func ReadPacket(reader io.Reader) (Packet, error) {
+	var first uint32
+	if err := binary.Read(reader, binary.BigEndian, &first); err != nil {
+		return nil, err
+	}
+	packet := make(Packet, PacketWords(uint8(first>>28)))
+	packet[0] = first
+	if len(packet) > 1 {
+		err := binary.Read(reader, binary.BigEndian, packet[1:])
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return packet, nil
+}
+
+// ParsePackets splits data, a sequence of big-endian words, into packets.
+func ParsePackets(data []byte) ([]Packet, error) {
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf(PacketTrailError, len(data)%4)
+	}
+	var packets []Packet
+	for offset := 0; offset < len(data); {
+		words := PacketWords(data[offset] >> 4)
+		if offset+4*words > len(data) {
+			return nil, fmt.Errorf(PacketSizeError,
+				data[offset]>>4, words, (len(data)-offset)/4)
+		}
+		packet := make(Packet, words)
+		for i := range packet {
+			packet[i] = binary.BigEndian.Uint32(data[offset+4*i:])
+		}
+		packets = append(packets, packet)
+		offset += 4 * words
+	}
+	return packets, nil
+}
+
+// MarshalBinary returns the packet as big-endian words.
+func (p Packet) MarshalBinary() ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	data := make([]byte, 4*len(p))
+	for i, word := range p {
+		binary.BigEndian.PutUint32(data[4*i:], word)
+	}
+	return data, nil
+}
@@ -0,0 +1,154 @@
+package midi2_test
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"testing"
+
+	"github.com/husafan/audio/midi"
+	. "github.com/husafan/audio/midi/midi2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePackets(t *testing.T) {
+	data := []byte{
+		0x20, 0x90, 0x3C, 0x40,
+		0x41, 0x91, 0x3C, 0x00, 0x80, 0x00, 0x00, 0x00,
+		0x10, 0xF8, 0x00, 0x00,
+	}
+	packets, err := ParsePackets(data)
+	assert.Nil(t, err)
+	assert.Equal(t, []Packet{
+		{0x20903C40}, {0x41913C00, 0x80000000}, {0x10F80000},
+	}, packets)
+	assert.Equal(t, uint8(MIDI2ChannelVoice), packets[1].MessageType())
+	assert.Equal(t, uint8(1), packets[1].Group())
+
+	reader := bytes.NewReader(data)
+	for _, expected := range packets {
+		packet, err := ReadPacket(reader)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, packet)
+		encoded, err := packet.MarshalBinary()
+		assert.Nil(t, err)
+		assert.Equal(t, 4*len(packet), len(encoded))
+	}
+	_, err = ReadPacket(reader)
+	assert.Equal(t, io.EOF, err)
+	_, err = ReadPacket(bytes.NewReader(data[4:8]))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	_, err = ParsePackets(data[:10])
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("2 bytes remain after the last packet")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+	_, err = ParsePackets(data[:8])
+	re = regexp.MustCompile("message type 0x4 needs 2 words but 1 were given")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+	err = Packet{0x40000000}.Validate()
+	assert.NotNil(t, err)
+}
+
+func TestTranslateMIDI2ChannelVoice(t *testing.T) {
+	var translator Translator
+	for _, test := range []struct {
+		packet Packet
+		events [][]byte
+	}{
+		// A velocity of 0 still sounds the note in MIDI 2.0.
+		{Packet{0x40913C00, 0x00000000}, [][]byte{{0x91, 0x3C, 1}}},
+		{Packet{0x40913C00, 0xFFFF0000}, [][]byte{{0x91, 0x3C, 0x7F}}},
+		{Packet{0x40823C00, 0x80000000}, [][]byte{{0x82, 0x3C, 0x40}}},
+		{Packet{0x40A03C00, 0x40000000}, [][]byte{{0xA0, 0x3C, 0x20}}},
+		{Packet{0x40B50700, 0xFE000000}, [][]byte{{0xB5, 0x07, 0x7F}}},
+		{Packet{0x40D00000, 0x02000000}, [][]byte{{0xD0, 0x01}}},
+		{Packet{0x40E00000, 0x80000000}, [][]byte{{0xE0, 0x00, 0x40}}},
+		{Packet{0x40E00000, 0x00000000}, [][]byte{{0xE0, 0x00, 0x00}}},
+		{Packet{0x40C00000, 0x05000000}, [][]byte{{0xC0, 0x05}}},
+		{Packet{0x40C00001, 0x05000102}, [][]byte{
+			{0xB0, 0, 1}, {0xB0, 32, 2}, {0xC0, 0x05}}},
+		// RPN 0, a pitch bend range of 12 semitones.
+		{Packet{0x40200000, 0x18000000}, [][]byte{
+			{0xB0, 101, 0}, {0xB0, 100, 0}, {0xB0, 6, 12}, {0xB0, 38, 0}}},
+		{Packet{0x40310203, 0x00040000}, [][]byte{
+			{0xB1, 99, 2}, {0xB1, 98, 3}, {0xB1, 6, 0}, {0xB1, 38, 1}}},
+		// Per-note pitch bend has no MIDI 1.0 equivalent.
+		{Packet{0x40603C00, 0x80000000}, nil},
+	} {
+		events, err := translator.Translate(test.packet)
+		assert.Nil(t, err)
+		var data [][]byte
+		for _, event := range events {
+			data = append(data, event.Data)
+		}
+		assert.Equal(t, test.events, data, "packet %#x", test.packet)
+	}
+}
+
+func TestTranslateSysEx7(t *testing.T) {
+	var translator Translator
+	payload := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13}
+	event := midi.TrackEvent{Data: append(
+		append([]byte{0xF0}, payload...), 0xF7)}
+	packets, err := Encode(3, event)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(packets))
+	assert.Equal(t, Packet{0x33160102, 0x03040506}, packets[0])
+	assert.Equal(t, Packet{0x33310D00, 0x00000000}, packets[2])
+
+	// Another group's message does not disturb the partial message.
+	for _, packet := range packets[:2] {
+		events, err := translator.Translate(packet)
+		assert.Nil(t, err)
+		assert.Nil(t, events)
+	}
+	events, err := translator.Translate(Packet{0x30020102, 0})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0xF0, 1, 2, 0xF7}, events[0].Data)
+	events, err = translator.Translate(packets[2])
+	assert.Nil(t, err)
+	assert.Equal(t, []midi.TrackEvent{event}, events)
+
+	// An end without a start is dropped.
+	events, err = translator.Translate(packets[2])
+	assert.Nil(t, err)
+	assert.Nil(t, events)
+
+	packets, err = Encode(0, midi.TrackEvent{Data: []byte{0xF0, 0xF7}})
+	assert.Nil(t, err)
+	assert.Equal(t, []Packet{{0x30000000, 0}}, packets)
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	var translator Translator
+	for _, data := range [][]byte{
+		{0x90, 0x3C, 0x40},
+		{0xC3, 0x05},
+		{0xE0, 0x00, 0x40},
+		{0xF2, 0x10, 0x01},
+		{0xF8},
+		{0xFF},
+	} {
+		event := midi.TrackEvent{Data: data}
+		packets, err := Encode(5, event)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(packets))
+		assert.Equal(t, uint8(5), packets[0].Group())
+		events, err := translator.Translate(packets[0])
+		assert.Nil(t, err)
+		assert.Equal(t, []midi.TrackEvent{event}, events)
+	}
+	packets, _ := Encode(0, midi.TrackEvent{Data: []byte{0x90, 0x3C, 0x40}})
+	assert.Equal(t, []Packet{{0x20903C40}}, packets)
+
+	_, err := Encode(16, midi.TrackEvent{Data: []byte{0xF8}})
+	re := regexp.MustCompile("group 16 is out of range 0-15")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+	_, err = Encode(0, midi.TrackEvent{Data: []byte{0xFF, 0x2F}})
+	re = regexp.MustCompile("cannot encode an event with status byte 0xff")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+	_, err = Encode(0, midi.TrackEvent{Data: []byte{0x90, 0x3C}})
+	re = regexp.MustCompile("status byte 0x90 needs 2 data bytes")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
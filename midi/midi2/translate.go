@@ -0,0 +1,296 @@
+package midi2
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/husafan/audio/midi"
+)
+
+// The following are the opcodes of MIDI 2.0 channel voice messages.
+const (
+	registeredController = 0x2
+	assignableController = 0x3
+	noteOff              = 0x8
+	noteOn               = 0x9
+	polyPressure         = 0xA
+	controlChange        = 0xB
+	programChange        = 0xC
+	channelPressure      = 0xD
+	pitchBend            = 0xE
+
+	// bankValid is the flag of a MIDI 2.0 Program Change that carries a
+	// bank.
+	bankValid = 0x01
+)
+
+/*
+systemMessageSize returns the number of data bytes of a system common or
+real-time message, and false for status bytes that are not one.
+*/
+func systemMessageSize(status byte) (int, bool) {
+	switch status {
+	case 0xF1, 0xF3:
+		return 1, true
+	case midi.SongPositionPointer:
+		return 2, true
+	case 0xF6, midi.TimingClock, midi.StartMessage, midi.ContinueMessage,
+		midi.StopMessage, 0xFE, 0xFF:
+		return 0, true
+	}
+	return 0, false
+}
+
+/*
+channelMessageSize returns the number of data bytes of a channel voice message.
+*/
+func channelMessageSize(status byte) int {
+	switch status & 0xF0 {
+	case midi.ProgramChange, midi.ChannelPressure:
+		return 1
+	}
+	return 2
+}
+
+/*
+Translator converts packets to MIDI 1.0 TrackEvents with delta-times of 0.
+System exclusive data may be split across several packets, so a Translator
+holds the partial message of each group until its last packet arrives. The zero
+value is ready to use.
+*/
+type Translator struct {
+	sysEx [groupCount][]byte
+}
+
+/*
+Translate returns the events carried by packet. MIDI 1.0 channel voice and
+system packets map to one event each. MIDI 2.0 channel voice messages have
+their values scaled down to 7 or 14 bits; a Program Change with a bank becomes
+Bank Select MSB and LSB Control Changes followed by the Program Change, and a
+registered or assignable controller becomes the four Control Changes of an RPN
+or NRPN. A 7-bit system exclusive message is returned once its last packet is
+translated. Packets with no MIDI 1.0 equivalent, such as utility messages,
+per-note controllers and 8-bit system exclusive data, yield no events.
+*/
+func (t *Translator) Translate(packet Packet) ([]midi.TrackEvent, error) {
+	if err := packet.Validate(); err != nil {
+		return nil, err
+	}
+	var data []byte
+	switch packet.MessageType() {
+	case SystemMessage:
+		status := packet.byteAt(1)
+		size, ok := systemMessageSize(status)
+		if !ok {
+			return nil, nil
+		}
+		data = []byte{status, packet.byteAt(2) & 0x7F,
+			packet.byteAt(3) & 0x7F}[:1+size]
+	case MIDI1ChannelVoice:
+		status := packet.byteAt(1)
+		if status < midi.NoteOffEvent || status >= midi.SysExEvent {
+			return nil, nil
+		}
+		data = []byte{status, packet.byteAt(2) & 0x7F,
+			packet.byteAt(3) & 0x7F}[:1+channelMessageSize(status)]
+	case SysEx7Message:
+		data = t.sysEx7(packet)
+	case MIDI2ChannelVoice:
+		var events []midi.TrackEvent
+		for _, message := range channelMessages(packet) {
+			events = append(events, midi.TrackEvent{Data: message.Bytes()})
+		}
+		return events, nil
+	}
+	if data == nil {
+		return nil, nil
+	}
+	return []midi.TrackEvent{{Data: data}}, nil
+}
+
+/*
+sysEx7 adds a 7-bit system exclusive packet to the partial message of its group,
+returning the whole message, from 0xF0 to 0xF7, once it is complete.
+*/
+func (t *Translator) sysEx7(packet Packet) []byte {
+	group := packet.Group()
+	count := int(packet.byteAt(1) & 0x0F)
+	if count > sysEx7BytesPerPacket {
+		count = sysEx7BytesPerPacket
+	}
+	var payload []byte
+	for i := 0; i < count; i++ {
+		payload = append(payload, packet.byteAt(2+i)&0x7F)
+	}
+	switch packet.status() {
+	case sysExComplete:
+		t.sysEx[group] = nil
+		return append(append([]byte{midi.SysExEvent}, payload...),
+			midi.SysExEscape)
+	case sysExStart:
+		t.sysEx[group] = append([]byte{midi.SysExEvent}, payload...)
+	case sysExContinue:
+		if t.sysEx[group] != nil {
+			t.sysEx[group] = append(t.sysEx[group], payload...)
+		}
+	case sysExEnd:
+		message := t.sysEx[group]
+		t.sysEx[group] = nil
+		if message != nil {
+			return append(append(message, payload...), midi.SysExEscape)
+		}
+	}
+	return nil
+}
+
+/*
+channelMessages translates a MIDI 2.0 channel voice packet to the MIDI 1.0
+messages with the same effect.
+*/
+func channelMessages(packet Packet) []midi.ChannelMessage {
+	channel := uint8(packet[0]>>16) & 0x0F
+	index := packet.byteAt(2) & 0x7F
+	value := packet[1]
+	// scale7 reduces a 32-bit value to 7 bits.
+	scale7 := uint8(value >> 25)
+	switch packet.status() {
+	case noteOff:
+		return []midi.ChannelMessage{
+			midi.NoteOffMessage{
+				Channel: channel, Key: index, Velocity: uint8(value >> 25)}}
+	case noteOn:
+		// A MIDI 2.0 Note On with a velocity of 0 still sounds, where a
+		// MIDI 1.0 velocity of 0 would release the note.
+		velocity := uint8(value >> 25)
+		if velocity == 0 {
+			velocity = 1
+		}
+		return []midi.ChannelMessage{
+			midi.NoteOnMessage{
+				Channel: channel, Key: index, Velocity: velocity}}
+	case polyPressure:
+		return []midi.ChannelMessage{
+			midi.KeyPressureMessage{
+				Channel: channel, Key: index, Value: scale7}}
+	case controlChange:
+		return []midi.ChannelMessage{
+			midi.ControlChangeMessage{
+				Channel: channel, Controller: index, Value: scale7}}
+	case channelPressure:
+		return []midi.ChannelMessage{
+			midi.ChannelPressureMessage{Channel: channel, Value: scale7}}
+	case pitchBend:
+		return []midi.ChannelMessage{
+			midi.PitchWheelMessage{
+				Channel: channel, Pitch: int16(int(value>>18) - 0x2000)}}
+	case programChange:
+		var messages []midi.ChannelMessage
+		if packet.byteAt(3)&bankValid != 0 {
+			messages = append(messages,
+				control(channel, 0, packet.byteAt(6)),
+				control(channel, 32, packet.byteAt(7)))
+		}
+		return append(messages, midi.ProgramChangeMessage{
+			Channel: channel, Program: packet.byteAt(4) & 0x7F})
+	case registeredController, assignableController:
+		msb, lsb := byte(midi.RPNMSB), byte(midi.RPNLSB)
+		if packet.status() == assignableController {
+			msb, lsb = midi.NRPNMSB, midi.NRPNLSB
+		}
+		value14 := value >> 18
+		return []midi.ChannelMessage{
+			control(channel, msb, index),
+			control(channel, lsb, packet.byteAt(3)),
+			control(channel, midi.DataEntryMSB, byte(value14>>7)),
+			control(channel, midi.DataEntryLSB, byte(value14)),
+		}
+	}
+	return nil
+}
+
+// control returns a Control Change with a 7-bit value.
+func control(channel, controller, value byte) midi.ControlChangeMessage {
+	return midi.ControlChangeMessage{
+		Channel: channel, Controller: controller, Value: value & 0x7F}
+}
+
+/*
+Encode returns the packets, addressed to group, that carry event using the MIDI
+1.0 protocol within UMP: channel voice messages as MIDI 1.0 channel voice
+packets, system common and real-time messages as system packets, and system
+exclusive messages as one or more 7-bit system exclusive packets. Meta events
+and system exclusive escapes have no UMP form and return an error.
+*/
+func Encode(group uint8, event midi.TrackEvent) ([]Packet, error) {
+	if group >= groupCount {
+		return nil, fmt.Errorf(GroupError, group)
+	}
+	status := event.Status()
+	header := uint32(group) << 24
+	var messageType uint32
+	var size int
+	switch {
+	case status == midi.SysExEvent:
+		return encodeSysEx7(header, event.Data[1:]), nil
+	case event.IsChannelEvent():
+		messageType, size = MIDI1ChannelVoice, channelMessageSize(status)
+	default:
+		var ok bool
+		if size, ok = systemMessageSize(status); !ok ||
+			(status == midi.MetaEvent && len(event.Data) > 1) {
+			return nil, fmt.Errorf(EncodeStatusError, status)
+		}
+		messageType = SystemMessage
+	}
+	if len(event.Data)-1 != size {
+		return nil, fmt.Errorf(
+			EncodeSizeError, status, size, len(event.Data)-1)
+	}
+	word := messageType<<28 | header | uint32(status)<<16
+	for i, value := range event.Data[1:] {
+		word |= uint32(value&0x7F) << (8 - 8*uint(i))
+	}
+	return []Packet{{word}}, nil
+}
+
+/*
+encodeSysEx7 splits the payload of a system exclusive message, without its
+closing 0xF7, into 7-bit system exclusive packets.
+*/
+func encodeSysEx7(header uint32, payload []byte) []Packet {
+	if n := len(payload); n > 0 && payload[n-1] == midi.SysExEscape {
+		payload = payload[:n-1]
+	}
+	var packets []Packet
+	for start := 0; ; {
+		end := start + sysEx7BytesPerPacket
+		if end > len(payload) {
+			end = len(payload)
+		}
+		var status uint32
+		switch {
+		case start == 0 && end == len(payload):
+			status = sysExComplete
+		case start == 0:
+			status = sysExStart
+		case end == len(payload):
+			status = sysExEnd
+		default:
+			status = sysExContinue
+		}
+		var data [8]byte
+		data[1] = byte(status<<4) | byte(end-start)
+		for i, value := range payload[start:end] {
+			data[2+i] = value & 0x7F
+		}
+		packets = append(packets, Packet{
+			SysEx7Message<<28 | header | binary.BigEndian.Uint32(data[:4]),
+			binary.BigEndian.Uint32(data[4:]),
+		})
+		if end == len(payload) {
+			break
+		}
+		start = end
+	}
+	return packets
+}
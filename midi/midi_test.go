@@ -2,6 +2,9 @@ package midi_test
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
 	"regexp"
 	"testing"
 
@@ -10,33 +13,71 @@ import (
 )
 
 func TestVariableLengthQuantity(t *testing.T) {
-	value := ReadVariableLengthQuantity(bytes.NewBuffer([]byte{0x7F}))
+	value, n, err := ReadVariableLengthQuantity(bytes.NewBuffer([]byte{0x7F}))
+	assert.Nil(t, err)
 	assert.Equal(t, uint64(127), value)
+	assert.Equal(t, 1, n)
 
-	value = ReadVariableLengthQuantity(bytes.NewBuffer([]byte{0x81, 0x48}))
+	value, n, err = ReadVariableLengthQuantity(
+		bytes.NewBuffer([]byte{0x81, 0x48}))
 	assert.Equal(t, uint64(200), value)
+	assert.Equal(t, 2, n)
 
-	value = ReadVariableLengthQuantity(bytes.NewBuffer([]byte{0xFF, 0xFF, 0x7F}))
+	value, n, err = ReadVariableLengthQuantity(
+		bytes.NewBuffer([]byte{0xFF, 0xFF, 0x7F}))
 	assert.Equal(t, uint64(2097151), value)
+	assert.Equal(t, 3, n)
 
-	value = ReadVariableLengthQuantity(bytes.NewBuffer([]byte{0x81, 0x80, 0x80, 0x00}))
+	value, n, err = ReadVariableLengthQuantity(
+		bytes.NewBuffer([]byte{0x81, 0x80, 0x80, 0x00}))
 	assert.Equal(t, uint64(2097152), value)
+	assert.Equal(t, 4, n)
 
-	value = ReadVariableLengthQuantity(bytes.NewBuffer([]byte{0xC0, 0x80, 0x80, 0x00}))
+	value, _, err = ReadVariableLengthQuantity(
+		bytes.NewBuffer([]byte{0xC0, 0x80, 0x80, 0x00}))
+	assert.Nil(t, err)
 	assert.Equal(t, uint64(134217728), value)
 }
 
+func TestVariableLengthQuantityErrors(t *testing.T) {
+	_, n, err := ReadVariableLengthQuantity(bytes.NewBuffer(nil))
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 0, n)
+
+	_, n, err = ReadVariableLengthQuantity(bytes.NewBuffer([]byte{0x81, 0x80}))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Equal(t, 2, n)
+
+	// The spec limits quantities to 4 bytes.
+	reader := bytes.NewBuffer([]byte{0x81, 0x80, 0x80, 0x80, 0x00})
+	_, n, err = ReadVariableLengthQuantity(reader)
+	assert.Equal(t, ErrQuantityTooLong, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, 1, reader.Len())
+
+	// A track whose delta time is too long fails to parse.
+	var buffer bytes.Buffer
+	writeHeader(&buffer, 0, 1, 96)
+	writeTrack(&buffer, []byte{0x81, 0x80, 0x80, 0x80, 0x00, 0xFF, 0x2F, 0x00})
+	_, err = ParseMidi(buffer.Bytes(), nil)
+	assert.Equal(t, ErrQuantityTooLong, err)
+}
+
 func TestMidiHeaderIncorrectSize(t *testing.T) {
 	var buffer bytes.Buffer
 	buffer.WriteString("MThd")
-	// Write the header length. Should always be 16, but is 5 here.
+	// Write the header length. Should be at least 6, but is 5 here.
 	buffer.Write([]byte{0, 0, 0, 5})
 
 	midi := new(Midi)
 	err := midi.UnmarshalBinary(buffer.Bytes())
 	assert.NotNil(t, err)
-	re := regexp.MustCompile("expected a header length of 16 but found a length of 5")
+	re := regexp.MustCompile(
+		"expected a header length of at least 6 but found a length of 5")
 	assert.NotEqual(t, "", re.FindString(err.Error()))
+	var sizeErr ErrHeaderSize
+	assert.True(t, errors.As(err, &sizeErr))
+	assert.Equal(t, ErrHeaderSize{Got: 5, Offset: 4}, sizeErr)
 }
 
 func TestMidiHeaderChunkTooSmall(t *testing.T) {
@@ -51,7 +92,7 @@ func TestMidiHeaderChunkTooSmall(t *testing.T) {
 
 	buffer.Reset()
 	buffer.WriteString("MThd")
-	buffer.Write([]byte{0, 0, 0, 16})
+	buffer.Write([]byte{0, 0, 0, 6})
 
 	midi = new(Midi)
 	err = midi.UnmarshalBinary(buffer.Bytes())
@@ -59,6 +100,163 @@ func TestMidiHeaderChunkTooSmall(t *testing.T) {
 	assert.NotEqual(t, "", re.FindString(err.Error()))
 }
 
+// writeHeader writes a header chunk declaring ntrks tracks to buffer.
+func writeHeader(buffer *bytes.Buffer, format, ntrks, division uint16) {
+	buffer.WriteString("MThd")
+	binary.Write(buffer, binary.BigEndian, uint32(6))
+	binary.Write(buffer, binary.BigEndian, format)
+	binary.Write(buffer, binary.BigEndian, ntrks)
+	binary.Write(buffer, binary.BigEndian, division)
+}
+
+// writeTrack writes a track chunk containing the raw event bytes to buffer.
+func writeTrack(buffer *bytes.Buffer, events []byte) {
+	buffer.WriteString("MTrk")
+	binary.Write(buffer, binary.BigEndian, uint32(len(events)))
+	buffer.Write(events)
+}
+
+// noteTrack contains a note on and off (using running status) and an end of
+// track meta event.
+var noteTrack = []byte{
+	0x00, 0x90, 0x3C, 0x40,
+	0x60, 0x3C, 0x00,
+	0x00, 0xFF, 0x2F, 0x00,
+}
+
 func TestMidiHeaderChunkParsed(t *testing.T) {
+	var buffer bytes.Buffer
+	writeHeader(&buffer, 1, 2, 96)
+
+	midi := new(Midi)
+	err := midi.UnmarshalBinary(buffer.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(1), midi.Format)
+	assert.Equal(t, uint16(2), midi.Ntrks)
+	assert.Equal(t, uint16(96), midi.Division)
+}
+
+func TestMidiHeaderChunkExtraDataSkipped(t *testing.T) {
+	var buffer bytes.Buffer
+	buffer.WriteString("MThd")
+	buffer.Write([]byte{0, 0, 0, 8, 0, 0, 0, 1, 0, 96, 0xAA, 0xBB})
+	writeTrack(&buffer, noteTrack)
+
+	midi := new(Midi)
+	err := midi.UnmarshalBinary(buffer.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(96), midi.Division)
+	assert.Equal(t, 1, len(midi.TrackChunks))
+}
+
+func TestMidiTrackEventsParsed(t *testing.T) {
+	var buffer bytes.Buffer
+	writeHeader(&buffer, 0, 1, 96)
+	// Unknown chunks must be skipped.
+	buffer.WriteString("XFIH")
+	buffer.Write([]byte{0, 0, 0, 2, 1, 2})
+	writeTrack(&buffer, noteTrack)
+
+	midi := new(Midi)
+	err := midi.UnmarshalBinary(buffer.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(midi.TrackChunks))
+	events := midi.TrackChunks[0].TrackEvents
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, TrackEvent{0, []byte{0x90, 0x3C, 0x40}}, events[0])
+	// Running status is expanded.
+	assert.Equal(t, TrackEvent{0x60, []byte{0x90, 0x3C, 0x00}}, events[1])
+	assert.Equal(t, TrackEvent{0, []byte{0xFF, 0x2F}}, events[2])
+}
+
+func TestMidiTrackErrors(t *testing.T) {
+	var buffer bytes.Buffer
+	writeHeader(&buffer, 0, 1, 96)
+	writeTrack(&buffer, []byte{0x00, 0x3C, 0x40})
+	_, err := ParseMidi(buffer.Bytes(), nil)
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("without a preceding status byte")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+
+	// A meta event claiming more data than the track contains.
+	buffer.Reset()
+	writeHeader(&buffer, 0, 1, 96)
+	writeTrack(&buffer, []byte{0x00, 0xFF, 0x01, 0x7F, 'a'})
+	_, err = ParseMidi(buffer.Bytes(), nil)
+	assert.NotNil(t, err)
+	re = regexp.MustCompile("exceeds the 1 bytes remaining")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+
+	// A chunk claiming more data than the file contains.
+	buffer.Reset()
+	writeHeader(&buffer, 0, 1, 96)
+	buffer.WriteString("MTrk")
+	buffer.Write([]byte{0x7F, 0, 0, 0, 0})
+	_, err = ParseMidi(buffer.Bytes(), nil)
+	assert.NotNil(t, err)
+	re = regexp.MustCompile("chunk MTrk has a length of 2130706432")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
+
+func TestParseMidiLimits(t *testing.T) {
+	var buffer bytes.Buffer
+	writeHeader(&buffer, 1, 2, 96)
+	writeTrack(&buffer, noteTrack)
+	writeTrack(&buffer, noteTrack)
+	data := buffer.Bytes()
+
+	midi, err := ParseMidi(data, &ParseOptions{
+		MaxTracks: 2, MaxEventsPerTrack: 3, MaxEvents: 6})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(midi.TrackChunks))
+
+	midi, err = ParseMidi(data, &ParseOptions{MaxTracks: 1})
+	assert.Nil(t, midi)
+	var tracksErr ErrTooManyTracks
+	assert.True(t, errors.As(err, &tracksErr))
+	assert.Equal(t, ErrTooManyTracks{Max: 1, Got: 2}, tracksErr)
+
+	_, err = ParseMidi(data, &ParseOptions{MaxEventsPerTrack: 2})
+	var trackEventsErr ErrTooManyTrackEvents
+	assert.True(t, errors.As(err, &trackEventsErr))
+	assert.Equal(t, ErrTooManyTrackEvents{Track: 0, Max: 2}, trackEventsErr)
+
+	_, err = ParseMidi(data, &ParseOptions{MaxEventsPerTrack: 3, MaxEvents: 5})
+	var eventsErr ErrTooManyEvents
+	assert.True(t, errors.As(err, &eventsErr))
+	assert.Equal(t, ErrTooManyEvents{Max: 5}, eventsErr)
+	re := regexp.MustCompile("more than 5 events")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+
+	// The MaxEvents budget is exhausted exactly by the first track.
+	_, err = ParseMidi(data, &ParseOptions{MaxEvents: 3})
+	assert.True(t, errors.As(err, &eventsErr))
+
+	_, err = ParseMidi(data, &ParseOptions{MaxChunkSize: len(noteTrack) - 1})
+	var chunkErr ErrChunkTooLarge
+	assert.True(t, errors.As(err, &chunkErr))
+	assert.Equal(t, ErrChunkTooLarge{
+		Type: "MTrk", Max: len(noteTrack) - 1, Got: len(noteTrack)}, chunkErr)
+}
+
+/*
+FuzzParseMidi checks that arbitrary data never panics the parser or the
+validator, and that whatever parses can be marshalled again.
+*/
+func FuzzParseMidi(f *testing.F) {
+	var buffer bytes.Buffer
+	writeHeader(&buffer, 1, 1, 96)
+	writeTrack(&buffer, noteTrack)
+	f.Add(buffer.Bytes())
+	f.Add([]byte("MThd\x00\x00\x00\x06"))
 
+	f.Fuzz(func(t *testing.T, data []byte) {
+		Validate(data)
+		midi, err := ParseMidi(data, &ParseOptions{
+			MaxTracks: 16, MaxEvents: 1 << 16, MaxChunkSize: 1 << 20})
+		if err != nil {
+			return
+		}
+		midi.MarshalBinary()
+	})
 }
@@ -0,0 +1,96 @@
+package midi
+
+import (
+	"sort"
+	"time"
+)
+
+/*
+A Note is a NoteOn event paired with the NoteOff that ends it, the piano-roll
+view of a MIDI file. Start and duration are given both in ticks and as times
+computed from the file's tempo map.
+*/
+type Note struct {
+	Track         int
+	Channel       uint8
+	Key           uint8
+	Velocity      uint8
+	StartTick     uint64
+	DurationTicks uint64
+	StartTime     time.Duration
+	Duration      time.Duration
+}
+
+/*
+Notes pairs the NoteOn and NoteOff events of every track into Notes, sorted by
+start tick and then by track, channel and key. Overlapping notes of the same key
+on the same channel are paired first in, first out. A note that is never
+released ends at the last event of its track. The notes of each track of a
+format 2 file are timed by that track's own tempo map.
+*/
+func (m *Midi) Notes() []Note {
+	tempoMaps := m.trackTempoMaps()
+	var notes []Note
+	for index := range m.TrackChunks {
+		track := &m.TrackChunks[index]
+		ticks := track.AbsoluteTicks()
+		sounding := make(map[noteKey][]Note)
+		var open int
+		for i := range track.TrackEvents {
+			event := &track.TrackEvents[i]
+			switch {
+			case event.IsNoteOn():
+				key := noteKey{event.Channel(), event.Data[1]}
+				sounding[key] = append(sounding[key], Note{
+					Track:     index,
+					Channel:   key.channel,
+					Key:       key.key,
+					Velocity:  event.Data[2],
+					StartTick: ticks[i],
+				})
+				open++
+			case event.IsNoteOff():
+				key := noteKey{event.Channel(), event.Data[1]}
+				if started := sounding[key]; len(started) > 0 {
+					note := started[0]
+					sounding[key] = started[1:]
+					note.DurationTicks = ticks[i] - note.StartTick
+					notes = append(notes, note)
+					open--
+				}
+			}
+		}
+		if open == 0 {
+			continue
+		}
+		end := ticks[len(ticks)-1]
+		for _, started := range sounding {
+			for _, note := range started {
+				note.DurationTicks = end - note.StartTick
+				notes = append(notes, note)
+			}
+		}
+	}
+
+	sort.Slice(notes, func(a, b int) bool {
+		first, second := &notes[a], &notes[b]
+		if first.StartTick != second.StartTick {
+			return first.StartTick < second.StartTick
+		}
+		if first.Track != second.Track {
+			return first.Track < second.Track
+		}
+		if first.Channel != second.Channel {
+			return first.Channel < second.Channel
+		}
+		return first.Key < second.Key
+	})
+	for i := range notes {
+		note := &notes[i]
+		tempoMap := tempoMaps[note.Track]
+		note.StartTime = tempoMap.Time(note.StartTick)
+		note.Duration =
+			tempoMap.Time(note.StartTick+note.DurationTicks) - note.StartTime
+	}
+	return notes
+}
@@ -0,0 +1,59 @@
+package midi_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotes(t *testing.T) {
+	var buffer bytes.Buffer
+	writeHeader(&buffer, 1, 2, 96)
+	// 1,000,000 microseconds per quarter note from tick 96.
+	writeTrack(&buffer, []byte{
+		0x60, 0xFF, 0x51, 0x03, 0x0F, 0x42, 0x40,
+		0x00, 0xFF, 0x2F, 0x00,
+	})
+	writeTrack(&buffer, []byte{
+		0x00, 0x90, 0x3C, 0x40,
+		0x00, 0x91, 0x40, 0x50,
+		// A second overlapping C on channel 0.
+		0x30, 0x90, 0x3C, 0x60,
+		0x30, 0x80, 0x3C, 0x00,
+		0x60, 0x90, 0x3C, 0x00,
+		// A note that is never released.
+		0x00, 0x91, 0x43, 0x10,
+		0x30, 0xFF, 0x2F, 0x00,
+	})
+	midi, err := ParseMidi(buffer.Bytes(), nil)
+	assert.Nil(t, err)
+
+	notes := midi.Notes()
+	assert.Equal(t, []Note{
+		{
+			Track: 1, Channel: 0, Key: 0x3C, Velocity: 0x40,
+			StartTick: 0, DurationTicks: 96,
+			StartTime: 0, Duration: 500 * time.Millisecond,
+		},
+		{
+			Track: 1, Channel: 1, Key: 0x40, Velocity: 0x50,
+			StartTick: 0, DurationTicks: 240,
+			StartTime: 0, Duration: 2 * time.Second,
+		},
+		{
+			Track: 1, Channel: 0, Key: 0x3C, Velocity: 0x60,
+			StartTick: 48, DurationTicks: 144,
+			StartTime: 250 * time.Millisecond,
+			Duration:  1250 * time.Millisecond,
+		},
+		{
+			Track: 1, Channel: 1, Key: 0x43, Velocity: 0x10,
+			StartTick: 192, DurationTicks: 48,
+			StartTime: 1500 * time.Millisecond,
+			Duration:  500 * time.Millisecond,
+		},
+	}, notes)
+}
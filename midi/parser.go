@@ -0,0 +1,211 @@
+package midi
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+const (
+	FactoryConflictError = "status byte %#x is already claimed by another EventFactory"
+	ProcessorEventError  = "processor for status byte %#x returned event %#x"
+)
+
+/*
+MidiParser dispatches the events of a track chunk to the EventFactory instances
+registered with it. Each status byte may be claimed by at most one factory, so
+the dispatch table is built once, at registration, rather than asking every
+factory about every event. NewMidiParser returns a parser with the built-in
+factories registered; the zero value has none.
+*/
+type MidiParser struct {
+	factories [256]EventFactory
+}
+
+// defaultParser parses tracks when ParseOptions does not name a parser.
+var defaultParser = NewMidiParser()
+
+/*
+NewMidiParser returns a MidiParser that understands channel voice, meta and
+system exclusive events. Further factories may claim the status bytes left
+unclaimed, such as the system common messages, via RegisterFactory.
+*/
+func NewMidiParser() *MidiParser {
+	parser := new(MidiParser)
+	for _, factory := range []EventFactory{
+		new(midiEventFactory),
+		metaEventFactory{},
+		sysExEventFactory{},
+	} {
+		if err := parser.RegisterFactory(factory); err != nil {
+			panic(err)
+		}
+	}
+	return parser
+}
+
+/*
+RegisterFactory offers every status byte to factory and records those for
+which it constructs an EventProcessor. If any of them is already claimed, an
+error naming the first such byte is returned and nothing is registered.
+*/
+func (p *MidiParser) RegisterFactory(factory EventFactory) error {
+	var claimed []byte
+	for status := 0x80; status <= 0xFF; status++ {
+		if factory.ConstructProcessor(byte(status)) == nil {
+			continue
+		}
+		if p.factories[status] != nil {
+			return fmt.Errorf(FactoryConflictError, status)
+		}
+		claimed = append(claimed, byte(status))
+	}
+	for _, status := range claimed {
+		p.factories[status] = factory
+	}
+	return nil
+}
+
+/*
+Factory returns the EventFactory that claimed status, or nil when no registered
+factory handles it.
+*/
+func (p *MidiParser) Factory(status byte) EventFactory {
+	return p.factories[status]
+}
+
+/*
+ParseTrack parses the data section of a track chunk into a slice of
+TrackEvents, dispatching each event to the factory that claimed its status
+byte. Running status is expanded so that the Data of every returned event
+begins with its status byte; only channel messages establish running status.
+*/
+func (p *MidiParser) ParseTrack(data []byte) ([]TrackEvent, error) {
+	return p.parseTrack(data, -1)
+}
+
+/*
+parseTrack implements ParseTrack. If limit is not negative and the track holds
+more than limit events, errEventLimit is returned.
+*/
+func (p *MidiParser) parseTrack(data []byte, limit int) ([]TrackEvent, error) {
+	reader := bytes.NewReader(data)
+	events := make([]TrackEvent, 0)
+	var status byte
+	for reader.Len() > 0 {
+		if limit >= 0 && len(events) == limit {
+			return nil, errEventLimit
+		}
+		deltaTime, _, err := ReadVariableLengthQuantity(reader)
+		if err != nil {
+			return nil, err
+		}
+		current, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if current&msbMask == msbMask {
+			status = current
+		} else if status == 0 {
+			return nil, fmt.Errorf(RunningStatusError, current)
+		} else {
+			reader.UnreadByte()
+		}
+
+		event, err := p.parseEvent(status, reader)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, TrackEvent{int(deltaTime), event.Data})
+		// Meta and system exclusive events cancel running status.
+		if status >= SysExEvent {
+			status = 0
+		}
+	}
+	return events, nil
+}
+
+/*
+parseEvent constructs a processor for status from its factory and runs it over
+the bytes following the status byte.
+*/
+func (p *MidiParser) parseEvent(
+	status byte, reader io.ByteReader) (TrackEvent, error) {
+	factory := p.factories[status]
+	if factory == nil {
+		return TrackEvent{}, fmt.Errorf(InvalidStatusError, status)
+	}
+	processor := factory.ConstructProcessor(status)
+	if processor == nil {
+		return TrackEvent{}, fmt.Errorf(InvalidStatusError, status)
+	}
+	if err := processor.Process(reader); err != nil {
+		return TrackEvent{}, err
+	}
+	event := processor.Event()
+	if len(event.Data) == 0 || event.Data[0] != status {
+		return TrackEvent{}, fmt.Errorf(ProcessorEventError, status, event.Data)
+	}
+	return event, nil
+}
+
+/*
+rawEventProcessor is the EventProcessor used by the built-in factories. It
+copies the event's bytes, status byte first, into a TrackEvent.
+*/
+type rawEventProcessor struct {
+	data []byte
+	read func(io.ByteReader) ([]byte, error)
+}
+
+func (p *rawEventProcessor) Process(reader io.ByteReader) error {
+	payload, err := p.read(reader)
+	if err != nil {
+		return err
+	}
+	p.data = append(p.data, payload...)
+	return nil
+}
+
+func (p *rawEventProcessor) Event() TrackEvent {
+	return TrackEvent{Data: p.data}
+}
+
+/*
+metaEventFactory claims the meta event status byte. Meta events carry a type
+byte followed by a length prefixed payload.
+*/
+type metaEventFactory struct{}
+
+func (metaEventFactory) ConstructProcessor(status byte) EventProcessor {
+	if status != MetaEvent {
+		return nil
+	}
+	return &rawEventProcessor{
+		data: []byte{status},
+		read: func(reader io.ByteReader) ([]byte, error) {
+			metaType, err := reader.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			payload, err := readEventPayload(reader)
+			if err != nil {
+				return nil, err
+			}
+			return append([]byte{metaType}, payload...), nil
+		},
+	}
+}
+
+/*
+sysExEventFactory claims both forms of system exclusive event, each of which
+carries a length prefixed payload.
+*/
+type sysExEventFactory struct{}
+
+func (sysExEventFactory) ConstructProcessor(status byte) EventProcessor {
+	if status != SysExEvent && status != SysExEscape {
+		return nil
+	}
+	return &rawEventProcessor{data: []byte{status}, read: readEventPayload}
+}
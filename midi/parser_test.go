@@ -0,0 +1,140 @@
+package midi_test
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+// songPositionFactory claims the Song Position Pointer system common message,
+// which carries two data bytes.
+type songPositionFactory struct{}
+
+func (songPositionFactory) ConstructProcessor(status byte) EventProcessor {
+	if status != 0xF2 {
+		return nil
+	}
+	return &songPositionProcessor{data: []byte{status}}
+}
+
+type songPositionProcessor struct {
+	data []byte
+}
+
+func (p *songPositionProcessor) Process(reader io.ByteReader) error {
+	for i := 0; i < 2; i++ {
+		current, err := reader.ReadByte()
+		if err != nil {
+			return err
+		}
+		p.data = append(p.data, current)
+	}
+	return nil
+}
+
+func (p *songPositionProcessor) Event() TrackEvent {
+	return TrackEvent{Data: p.data}
+}
+
+// noteOnFactory claims the Note On status bytes, which the built-in factories
+// already handle.
+type noteOnFactory struct{}
+
+func (noteOnFactory) ConstructProcessor(status byte) EventProcessor {
+	if status&0xF0 != NoteOnEvent {
+		return nil
+	}
+	return new(songPositionProcessor)
+}
+
+func TestMidiParserDefaultFactories(t *testing.T) {
+	parser := NewMidiParser()
+	events, err := parser.ParseTrack(noteTrack)
+	assert.Nil(t, err)
+	assert.Equal(t, []TrackEvent{
+		{0, []byte{0x90, 0x3C, 0x40}},
+		{0x60, []byte{0x90, 0x3C, 0x00}},
+		{0, []byte{0xFF, 0x2F}},
+	}, events)
+
+	assert.NotNil(t, parser.Factory(0x80))
+	assert.NotNil(t, parser.Factory(0xEF))
+	assert.NotNil(t, parser.Factory(SysExEvent))
+	assert.NotNil(t, parser.Factory(SysExEscape))
+	assert.NotNil(t, parser.Factory(MetaEvent))
+	assert.Nil(t, parser.Factory(0xF2))
+	assert.Nil(t, new(MidiParser).Factory(0x90))
+
+	_, err = parser.ParseTrack([]byte{0x00, 0xF2, 0x01, 0x02})
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("invalid status byte 0xf2")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+
+	_, err = parser.ParseTrack([]byte{0x00, 0x90, 0x3C})
+	assert.NotNil(t, err)
+	re = regexp.MustCompile("event of length 2 exceeds the 1 bytes remaining")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
+
+func TestMidiParserRegisterFactory(t *testing.T) {
+	parser := NewMidiParser()
+	assert.Nil(t, parser.RegisterFactory(songPositionFactory{}))
+	assert.Equal(t, songPositionFactory{}, parser.Factory(0xF2))
+
+	err := parser.RegisterFactory(songPositionFactory{})
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("status byte 0xf2 is already claimed")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+
+	// A conflicting factory registers none of its status bytes.
+	empty := new(MidiParser)
+	assert.Nil(t, empty.RegisterFactory(songPositionFactory{}))
+	err = parser.RegisterFactory(noteOnFactory{})
+	assert.NotNil(t, err)
+	re = regexp.MustCompile("status byte 0x90 is already claimed")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+	assert.Nil(t, empty.RegisterFactory(noteOnFactory{}))
+	assert.Equal(t, noteOnFactory{}, empty.Factory(0x9F))
+	assert.Nil(t, empty.Factory(0x80))
+
+	// System common messages cancel running status.
+	events, err := parser.ParseTrack([]byte{
+		0x00, 0x90, 0x3C, 0x40,
+		0x10, 0xF2, 0x01, 0x02,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, TrackEvent{0x10, []byte{0xF2, 0x01, 0x02}}, events[1])
+	_, err = parser.ParseTrack([]byte{
+		0x00, 0xF2, 0x01, 0x02,
+		0x00, 0x3C, 0x40,
+	})
+	assert.NotNil(t, err)
+}
+
+func TestParseMidiWithParser(t *testing.T) {
+	var buffer bytes.Buffer
+	writeHeader(&buffer, 0, 1, 96)
+	writeTrack(&buffer, []byte{0x00, 0xF2, 0x01, 0x02, 0x00, 0xFF, 0x2F, 0x00})
+
+	_, err := ParseMidi(buffer.Bytes(), nil)
+	assert.NotNil(t, err)
+
+	parser := NewMidiParser()
+	assert.Nil(t, parser.RegisterFactory(songPositionFactory{}))
+	midi, err := ParseMidi(buffer.Bytes(), &ParseOptions{Parser: parser})
+	assert.Nil(t, err)
+	events := midi.TrackChunks[0].TrackEvents
+	assert.Equal(t, TrackEvent{0, []byte{0xF2, 0x01, 0x02}}, events[0])
+
+	// A processor must return an event that begins with its status byte.
+	parser = new(MidiParser)
+	assert.Nil(t, parser.RegisterFactory(noteOnFactory{}))
+	_, err = parser.ParseTrack([]byte{0x00, 0x90, 0x3C, 0x40})
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("processor for status byte 0x90 returned event")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
@@ -0,0 +1,155 @@
+package midi
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	DataValueError = "%s of %v is out of range 0-127"
+
+	// DefaultPitchBendRange is the pitch bend sensitivity of a channel, in
+	// semitones, before it is changed by RPN 0.
+	DefaultPitchBendRange = 2.0
+
+	// The following controller numbers select and set registered and
+	// non-registered parameters.
+	DataEntryMSB = 6
+	DataEntryLSB = 38
+	NRPNLSB      = 98
+	NRPNMSB      = 99
+	RPNLSB       = 100
+	RPNMSB       = 101
+
+	// rpnNull is the value of both RPN controllers that deselects the
+	// current parameter.
+	rpnNull = 0x7F
+
+	// pitchWheelCenter is the 14-bit pitch wheel value for no bend.
+	pitchWheelCenter = 0x2000
+)
+
+/*
+A PitchBend is a Pitch Wheel Change event along with the pitch bend sensitivity
+of its channel at the time. Value is the signed 14-bit wheel position, from
+-8192 to 8191, and Semitones the resulting bend given the channel's range.
+*/
+type PitchBend struct {
+	Track     int
+	Channel   uint8
+	Tick      uint64
+	Time      time.Duration
+	Value     int16
+	Range     float64
+	Semitones float64
+}
+
+/*
+rpnState tracks the registered parameter selected on a channel and the pitch
+bend sensitivity set through it.
+*/
+type rpnState struct {
+	msb, lsb   uint8
+	registered bool
+	bendRange  float64
+	semitones  uint8
+}
+
+// selected returns true when RPN 0, pitch bend sensitivity, is selected.
+func (s *rpnState) selected() bool {
+	return s.registered && s.msb == 0 && s.lsb == 0
+}
+
+/*
+PitchBends returns every Pitch Wheel Change event in m in time order, with the
+bend in semitones computed from the pitch bend sensitivity of its channel. The
+sensitivity starts at DefaultPitchBendRange and follows RPN 0 messages: RPN MSB
+and LSB of 0 followed by Data Entry MSB (semitones) and optionally Data Entry
+LSB (cents). Channel state is shared between tracks, as it is on a device.
+*/
+func (m *Midi) PitchBends() []PitchBend {
+	tempoMap := m.TempoMap()
+	var states [channelCount]rpnState
+	for i := range states {
+		states[i] = rpnState{msb: rpnNull, lsb: rpnNull,
+			bendRange: DefaultPitchBendRange, semitones: 2}
+	}
+
+	var bends []PitchBend
+	for _, timed := range m.mergedEvents() {
+		event := &timed.event
+		if !event.IsChannelEvent() || len(event.Data) < 3 {
+			continue
+		}
+		state := &states[event.Channel()]
+		switch event.Status() & highOrderMask {
+		case ControlChange:
+			state.control(event.Data[1], event.Data[2])
+		case PitchWheelChange:
+			value := int16(uint16(event.Data[2])<<7|uint16(event.Data[1])) -
+				pitchWheelCenter
+			bend := PitchWheelMessage{Pitch: value}.Bend()
+			bends = append(bends, PitchBend{
+				Track:     timed.track,
+				Channel:   event.Channel(),
+				Tick:      timed.tick,
+				Time:      tempoMap.Time(timed.tick),
+				Value:     value,
+				Range:     state.bendRange,
+				Semitones: bend * state.bendRange,
+			})
+		}
+	}
+	return bends
+}
+
+// control updates the channel's parameter state for a Control Change.
+func (s *rpnState) control(controller, value uint8) {
+	switch controller {
+	case RPNMSB:
+		s.msb, s.registered = value, true
+	case RPNLSB:
+		s.lsb, s.registered = value, true
+	case NRPNMSB, NRPNLSB:
+		s.registered = false
+	case DataEntryMSB:
+		if s.selected() {
+			s.semitones = value
+			s.bendRange = float64(value)
+		}
+	case DataEntryLSB:
+		if s.selected() {
+			s.bendRange = float64(s.semitones) + float64(value)/100
+		}
+	}
+}
+
+/*
+PitchBendRangeEvents returns the Control Change events that set the pitch bend
+sensitivity of channel to semitones plus cents: select RPN 0, set the range with
+Data Entry MSB and LSB, then deselect the parameter so later Data Entry messages
+do not change it. The events have delta-times of 0 and can be inserted into a
+track with TrackChunk.InsertAt.
+*/
+func PitchBendRangeEvents(channel, semitones, cents uint8) ([]TrackEvent, error) {
+	if channel >= channelCount {
+		return nil, fmt.Errorf(ChannelRangeError, channel)
+	}
+	if semitones&msbMask != 0 {
+		return nil, fmt.Errorf(DataValueError, "semitones", semitones)
+	}
+	if cents&msbMask != 0 {
+		return nil, fmt.Errorf(DataValueError, "cents", cents)
+	}
+	status := ControlChange | channel
+	events := make([]TrackEvent, 0, 6)
+	for _, control := range [][2]uint8{
+		{RPNMSB, 0}, {RPNLSB, 0},
+		{DataEntryMSB, semitones}, {DataEntryLSB, cents},
+		{RPNMSB, rpnNull}, {RPNLSB, rpnNull},
+	} {
+		events = append(events,
+			TrackEvent{0, []byte{status, control[0], control[1]}})
+	}
+	return events, nil
+}
@@ -0,0 +1,66 @@
+package midi_test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPitchBendRangeEvents(t *testing.T) {
+	events, err := PitchBendRangeEvents(3, 12, 50)
+	assert.Nil(t, err)
+	assert.Equal(t, []TrackEvent{
+		{0, []byte{0xB3, 101, 0}},
+		{0, []byte{0xB3, 100, 0}},
+		{0, []byte{0xB3, 6, 12}},
+		{0, []byte{0xB3, 38, 50}},
+		{0, []byte{0xB3, 101, 127}},
+		{0, []byte{0xB3, 100, 127}},
+	}, events)
+
+	_, err = PitchBendRangeEvents(16, 2, 0)
+	assert.NotNil(t, err)
+	_, err = PitchBendRangeEvents(0, 128, 0)
+	re := regexp.MustCompile("semitones of 128 is out of range 0-127")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
+
+func TestPitchBends(t *testing.T) {
+	midi := newTrackMidi(
+		// Full upward bend with the default range of 2 semitones.
+		TrackEvent{0, []byte{0xE0, 0x7F, 0x7F}},
+		// Data entry without RPN 0 selected is ignored.
+		TrackEvent{0, []byte{0xB0, 6, 24}},
+	)
+	events, _ := PitchBendRangeEvents(0, 12, 50)
+	for _, event := range events {
+		midi.TrackChunks[0].InsertAt(96, event)
+	}
+	// Full downward bend on channel 0 and centered on channel 1.
+	midi.TrackChunks[0].InsertAt(192, TrackEvent{Data: []byte{0xE0, 0, 0}})
+	midi.TrackChunks[0].InsertAt(192, TrackEvent{Data: []byte{0xE1, 0, 0x40}})
+	// An NRPN deselects RPN 0, so data entry is ignored again.
+	midi.TrackChunks[0].InsertAt(288, TrackEvent{Data: []byte{0xB0, 101, 0}})
+	midi.TrackChunks[0].InsertAt(288, TrackEvent{Data: []byte{0xB0, 100, 0}})
+	midi.TrackChunks[0].InsertAt(288, TrackEvent{Data: []byte{0xB0, 99, 1}})
+	midi.TrackChunks[0].InsertAt(288, TrackEvent{Data: []byte{0xB0, 6, 1}})
+	midi.TrackChunks[0].InsertAt(288, TrackEvent{Data: []byte{0xE0, 0, 0x60}})
+
+	bends := midi.PitchBends()
+	assert.Equal(t, 4, len(bends))
+	assert.Equal(t, PitchBend{
+		Channel: 0, Tick: 0, Time: 0, Value: 8191, Range: 2,
+		Semitones: 8191.0 / 8192 * 2,
+	}, bends[0])
+	assert.Equal(t, int16(-8192), bends[1].Value)
+	assert.Equal(t, 12.5, bends[1].Range)
+	assert.Equal(t, -12.5, bends[1].Semitones)
+	assert.Equal(t, time.Second, bends[1].Time)
+	assert.Equal(t, uint8(1), bends[2].Channel)
+	assert.Equal(t, 0.0, bends[2].Semitones)
+	assert.Equal(t, 2.0, bends[2].Range)
+	assert.Equal(t, 6.25, bends[3].Semitones)
+}
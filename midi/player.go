@@ -0,0 +1,279 @@
+package midi
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	TempoScaleError = "tempo scale must be a positive number; found %v"
+)
+
+/*
+A PlayerEvent is an event delivered by a Player, along with the track it came
+from and its position in the file.
+*/
+type PlayerEvent struct {
+	Track int
+	Tick  uint64
+	Time  time.Duration
+	Event TrackEvent
+}
+
+/*
+Player delivers the events of a Midi to a handler at the wall-clock times given
+by the file's tempo map. Events of every track are merged in time order, as by
+MergeTracks. Playback can be paused, resumed, moved with Seek and sped up or
+slowed down with SetTempoScale, from any goroutine, including the handler. With
+SetClock, the player also acts as a MIDI clock master.
+*/
+type Player struct {
+	events   []PlayerEvent
+	handler  func(PlayerEvent) error
+	tempoMap *TempoMap
+
+	lock      sync.Mutex
+	next      int
+	position  time.Duration
+	started   time.Time
+	scale     float64
+	paused    bool
+	changed   chan struct{}
+	clock     bool
+	nextClock uint64
+	transport []PlayerEvent
+}
+
+/*
+NewPlayer returns a Player for m that passes each event to handler. The player
+starts at the beginning of the file, at the file's own tempo. The events are
+copied, so m may be changed once NewPlayer returns.
+*/
+func NewPlayer(m *Midi, handler func(PlayerEvent) error) *Player {
+	tempoMap := m.TempoMap()
+	merged := m.mergedEvents()
+	events := make([]PlayerEvent, len(merged))
+	for i, timed := range merged {
+		data := append([]byte(nil), timed.event.Data...)
+		events[i] = PlayerEvent{
+			Track: timed.track,
+			Tick:  timed.tick,
+			Time:  tempoMap.Time(timed.tick),
+			Event: TrackEvent{timed.event.DeltaTime, data},
+		}
+	}
+	return &Player{events: events, handler: handler, tempoMap: tempoMap,
+		scale: 1, changed: make(chan struct{})}
+}
+
+/*
+Play delivers events until the end of the file, returning nil. It returns early
+with the handler's error if the handler fails, or with the context's error if
+ctx is done first. Time spent paused does not count, and Play can be called
+again to continue from where it stopped.
+*/
+func (p *Player) Play(ctx context.Context) error {
+	p.lock.Lock()
+	p.transport = nil
+	if p.clock {
+		p.queueStart()
+	}
+	p.started = time.Now()
+	p.lock.Unlock()
+	for {
+		p.lock.Lock()
+		if len(p.transport) > 0 {
+			event := p.transport[0]
+			p.transport = p.transport[1:]
+			p.lock.Unlock()
+			if err := p.handler(event); err != nil {
+				return p.finish(err)
+			}
+			continue
+		}
+		if p.next >= len(p.events) {
+			p.lock.Unlock()
+			return p.finish(nil)
+		}
+		var timer *time.Timer
+		var wait <-chan time.Time
+		if !p.paused {
+			event, isClock := p.upcoming()
+			delay := time.Duration(
+				float64(event.Time-p.now()) / p.scale)
+			if delay <= 0 {
+				if isClock {
+					p.nextClock++
+				} else {
+					p.next++
+				}
+				p.lock.Unlock()
+				if err := p.handler(event); err != nil {
+					return p.finish(err)
+				}
+				continue
+			}
+			timer = time.NewTimer(delay)
+			wait = timer.C
+		}
+		changed := p.changed
+		p.lock.Unlock()
+
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return p.finish(ctx.Err())
+		case <-changed:
+		case <-wait:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+/*
+finish stops playback on behalf of Play, which returns err. A clock master
+sends Stop first; its failure is only returned when err is nil.
+*/
+func (p *Player) finish(err error) error {
+	p.lock.Lock()
+	p.stop()
+	p.transport = nil
+	clock := p.clock
+	stop := p.transportEvent(StopMessage)
+	p.lock.Unlock()
+	if clock {
+		if stopErr := p.handler(stop); err == nil {
+			err = stopErr
+		}
+	}
+	return err
+}
+
+/*
+now returns the position of playback in the file. The lock must be held.
+*/
+func (p *Player) now() time.Duration {
+	if p.paused || p.started.IsZero() {
+		return p.position
+	}
+	elapsed := time.Since(p.started)
+	return p.position + time.Duration(float64(elapsed)*p.scale)
+}
+
+/*
+stop records the current position so that playback can continue from it, and
+marks the player as not playing. The lock must be held.
+*/
+func (p *Player) stop() {
+	p.position = p.now()
+	p.started = time.Time{}
+}
+
+/*
+update applies change to the player with the position fixed at its current
+value, then wakes Play so it can reschedule.
+*/
+func (p *Player) update(change func()) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.position = p.now()
+	if !p.started.IsZero() {
+		p.started = time.Now()
+	}
+	change()
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+/*
+Pause stops the clock until Resume is called. Play keeps waiting meanwhile. A
+clock master sends Stop.
+*/
+func (p *Player) Pause() {
+	p.update(func() {
+		if p.clock && !p.paused && !p.started.IsZero() {
+			p.queue(StopMessage)
+		}
+		p.paused = true
+	})
+}
+
+// Resume restarts the clock after Pause. A clock master sends Continue.
+func (p *Player) Resume() {
+	p.update(func() {
+		if p.clock && p.paused && !p.started.IsZero() {
+			p.queue(ContinueMessage)
+		}
+		p.paused = false
+	})
+}
+
+// Paused returns true when playback is paused.
+func (p *Player) Paused() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.paused
+}
+
+/*
+Seek moves playback to position, the time from the start of the file. The next
+event delivered is the first at or after position; the events skipped over are
+not delivered. A clock master sends a Song Position Pointer, surrounded by Stop
+and Continue while playing.
+*/
+func (p *Player) Seek(position time.Duration) {
+	if position < 0 {
+		position = 0
+	}
+	p.update(func() {
+		p.position = position
+		p.next = sort.Search(len(p.events), func(i int) bool {
+			return p.events[i].Time >= position
+		})
+		p.nextClock = p.clockIndex(position)
+		if !p.clock || p.started.IsZero() {
+			return
+		}
+		if p.paused {
+			p.queueSongPosition()
+		} else {
+			p.queue(StopMessage)
+			p.queueSongPosition()
+			p.queue(ContinueMessage)
+		}
+	})
+}
+
+// Position returns the current position of playback in the file.
+func (p *Player) Position() time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.now()
+}
+
+// TempoScale returns the scale set by SetTempoScale, 1 by default.
+func (p *Player) TempoScale() float64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.scale
+}
+
+/*
+SetTempoScale plays the file scale times as fast as its tempo map specifies: 2
+doubles the speed and 0.5 halves it. The scale applies from the current
+position onward.
+*/
+func (p *Player) SetTempoScale(scale float64) error {
+	if !(scale > 0) {
+		return fmt.Errorf(TempoScaleError, scale)
+	}
+	p.update(func() { p.scale = scale })
+	return nil
+}
@@ -0,0 +1,112 @@
+package midi_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+// newPlayerMidi returns a two track file with events at 0, 0.5 and 1 second.
+func newPlayerMidi() *Midi {
+	return &Midi{
+		HeaderChunk: &HeaderChunk{Format: 1, Ntrks: 2, Division: 96},
+		TrackChunks: []TrackChunk{
+			{TrackEvents: []TrackEvent{
+				{0, []byte{0x90, 60, 100}},
+				{192, []byte{0x80, 60, 0}},
+			}},
+			{TrackEvents: []TrackEvent{
+				{96, []byte{0x91, 64, 100}},
+			}},
+		},
+	}
+}
+
+func TestPlayerPlay(t *testing.T) {
+	var events []PlayerEvent
+	player := NewPlayer(newPlayerMidi(), func(event PlayerEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	assert.Nil(t, player.SetTempoScale(50))
+	start := time.Now()
+	assert.Nil(t, player.Play(context.Background()))
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, PlayerEvent{
+		Track: 1, Tick: 96, Time: 500 * time.Millisecond,
+		Event: TrackEvent{96, []byte{0x91, 64, 100}},
+	}, events[1])
+	assert.Equal(t, uint64(192), events[2].Tick)
+	assert.True(t, player.Position() >= time.Second)
+
+	// Playing again at the end of the file returns immediately.
+	assert.Nil(t, player.Play(context.Background()))
+	assert.Equal(t, 3, len(events))
+}
+
+func TestPlayerSeek(t *testing.T) {
+	var ticks []uint64
+	player := NewPlayer(newPlayerMidi(), func(event PlayerEvent) error {
+		ticks = append(ticks, event.Tick)
+		return nil
+	})
+	player.SetTempoScale(50)
+	player.Seek(500 * time.Millisecond)
+	assert.Equal(t, 500*time.Millisecond, player.Position())
+	assert.Nil(t, player.Play(context.Background()))
+	assert.Equal(t, []uint64{96, 192}, ticks)
+
+	player.Seek(-time.Second)
+	assert.Equal(t, time.Duration(0), player.Position())
+	assert.Nil(t, player.Play(context.Background()))
+	assert.Equal(t, []uint64{96, 192, 0, 96, 192}, ticks)
+}
+
+func TestPlayerPause(t *testing.T) {
+	var player *Player
+	player = NewPlayer(newPlayerMidi(), func(event PlayerEvent) error {
+		if event.Tick == 0 {
+			player.Pause()
+			time.AfterFunc(30*time.Millisecond, player.Resume)
+		}
+		return nil
+	})
+	player.SetTempoScale(100)
+	start := time.Now()
+	assert.Nil(t, player.Play(context.Background()))
+	assert.True(t, time.Since(start) >= 40*time.Millisecond)
+	assert.False(t, player.Paused())
+}
+
+func TestPlayerErrors(t *testing.T) {
+	failure := errors.New("port closed")
+	var count int
+	player := NewPlayer(newPlayerMidi(), func(event PlayerEvent) error {
+		count++
+		return failure
+	})
+	assert.Equal(t, failure, player.Play(context.Background()))
+	assert.Equal(t, 1, count)
+
+	// The context ends playback while waiting for the next event.
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 10*time.Millisecond)
+	defer cancel()
+	player = NewPlayer(newPlayerMidi(), func(PlayerEvent) error { return nil })
+	assert.Equal(t, context.DeadlineExceeded, player.Play(ctx))
+	position := player.Position()
+	assert.True(t, position >= 10*time.Millisecond)
+	assert.True(t, position < 500*time.Millisecond)
+
+	err := player.SetTempoScale(0)
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("tempo scale must be a positive number")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
@@ -0,0 +1,49 @@
+package midi
+
+import (
+	"io"
+
+	"github.com/husafan/audio"
+)
+
+func init() {
+	audio.RegisterFormat("midi", "MThd", probe)
+}
+
+/*
+probe summarizes a standard MIDI file for audio.Probe. The duration runs to the
+last event of the longest track. The first track name is reported as the title,
+as it names the sequence in format 0 and 1 files.
+*/
+func probe(reader io.Reader) (*audio.Info, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ParseMidi(data, nil)
+	if err != nil {
+		return nil, err
+	}
+	info := &audio.Info{
+		Format:   "midi",
+		Codec:    "smf",
+		Metadata: make(map[string]string),
+	}
+	events := m.mergedEvents()
+	info.Duration = m.TempoMap().Time(lastTick(events))
+
+	for _, timed := range events {
+		event := &timed.event
+		switch {
+		case event.IsMeta(MetaTrackName) && timed.track == 0:
+			if _, ok := info.Metadata["title"]; !ok {
+				info.Metadata["title"] = string(event.Data[2:])
+			}
+		case event.IsMeta(MetaCopyright):
+			if _, ok := info.Metadata["copyright"]; !ok {
+				info.Metadata["copyright"] = string(event.Data[2:])
+			}
+		}
+	}
+	return info, nil
+}
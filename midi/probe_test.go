@@ -0,0 +1,33 @@
+package midi_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/husafan/audio"
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbeMidi(t *testing.T) {
+	m := newTrackMidi(
+		TrackEvent{0, []byte{MetaEvent, MetaTrackName, 'S', 'o', 'n', 'g'}},
+		TrackEvent{0, []byte{MetaEvent, MetaCopyright, '(', 'c', ')'}},
+		TrackEvent{0, []byte{NoteOnEvent, 60, 100}},
+		TrackEvent{192, []byte{NoteOffEvent, 60, 0}},
+		TrackEvent{0, []byte{MetaEvent, MetaEndOfTrack}},
+	)
+	data, err := m.MarshalBinary()
+	assert.Nil(t, err)
+
+	info, err := audio.Probe(bytes.NewReader(data))
+	assert.Nil(t, err)
+	// 192 ticks at 96 ticks per quarter note and 120 beats per minute.
+	assert.Equal(t, &audio.Info{
+		Format:   "midi",
+		Codec:    "smf",
+		Duration: time.Second,
+		Metadata: map[string]string{"title": "Song", "copyright": "(c)"},
+	}, info)
+}
@@ -0,0 +1,130 @@
+package midi
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	GridError     = "grid must be a positive number of ticks; found %v"
+	StrengthError = "quantize strength must be between 0 and 1; found %v"
+	SwingError    = "swing must be between 0 and 1; found %v"
+)
+
+/*
+Quantize moves the NoteOn and NoteOff events of every track in m towards the
+nearest line of a grid spaced gridTicks apart. A strength of 1 snaps events
+onto the grid, while smaller values move them proportionally closer, keeping
+some of the original feel. Swing delays every second grid line by a fraction of
+gridTicks: 0 leaves the grid straight, and 1/3 gives a triplet feel.
+
+Events are re-sorted by their new positions and every delta-time in the track is
+recomputed, so other events keep their absolute positions. A note whose NoteOff
+would land on or before its NoteOn keeps its original duration instead, and the
+End of Track event is moved if a note now ends after it.
+*/
+func Quantize(m *Midi, gridTicks int, strength, swing float64) error {
+	if gridTicks <= 0 {
+		return fmt.Errorf(GridError, gridTicks)
+	}
+	if strength < 0 || strength > 1 {
+		return fmt.Errorf(StrengthError, strength)
+	}
+	if swing < 0 || swing > 1 {
+		return fmt.Errorf(SwingError, swing)
+	}
+	for i := range m.TrackChunks {
+		quantizeTrack(&m.TrackChunks[i], gridTicks, strength, swing)
+	}
+	return nil
+}
+
+/*
+quantizeTrack applies Quantize to a single track. NoteOffs are matched to the
+most recent unmatched NoteOn with the same channel and key.
+*/
+func quantizeTrack(track *TrackChunk, grid int, strength, swing float64) {
+	original := track.AbsoluteTicks()
+	ticks := make([]uint64, len(original))
+	copy(ticks, original)
+
+	sounding := make(map[noteKey][]int)
+	var last uint64
+	for i := range track.TrackEvents {
+		event := &track.TrackEvents[i]
+		switch {
+		case event.IsNoteOn():
+			ticks[i] = quantizeTick(original[i], grid, strength, swing)
+			key := noteKey{event.Channel(), event.Data[1]}
+			sounding[key] = append(sounding[key], i)
+		case event.IsNoteOff():
+			ticks[i] = quantizeTick(original[i], grid, strength, swing)
+			key := noteKey{event.Channel(), event.Data[1]}
+			if on := sounding[key]; len(on) > 0 {
+				start := on[len(on)-1]
+				sounding[key] = on[:len(on)-1]
+				if ticks[i] <= ticks[start] && original[i] > original[start] {
+					ticks[i] = ticks[start] + original[i] - original[start]
+				}
+			}
+		}
+		if ticks[i] > last {
+			last = ticks[i]
+		}
+	}
+	// The End of Track event may precede a NoteOff that moved later.
+	for i := range track.TrackEvents {
+		if track.TrackEvents[i].IsMeta(MetaEndOfTrack) {
+			ticks[i] = last
+		}
+	}
+
+	order := make([]int, len(ticks))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if ticks[order[a]] != ticks[order[b]] {
+			return ticks[order[a]] < ticks[order[b]]
+		}
+		// End of Track must remain the final event in the track.
+		return track.TrackEvents[order[b]].IsMeta(MetaEndOfTrack) &&
+			!track.TrackEvents[order[a]].IsMeta(MetaEndOfTrack)
+	})
+	events := make([]TrackEvent, len(order))
+	sorted := make([]uint64, len(order))
+	for i, index := range order {
+		events[i] = track.TrackEvents[index]
+		sorted[i] = ticks[index]
+	}
+	track.TrackEvents = events
+	track.setAbsoluteTicks(sorted)
+}
+
+/*
+quantizeTick returns tick moved by strength towards the nearest line of a grid
+spaced grid ticks apart, where odd grid lines are delayed by swing * grid.
+*/
+func quantizeTick(tick uint64, grid int, strength, swing float64) uint64 {
+	line := func(n int64) float64 {
+		position := float64(n * int64(grid))
+		if n%2 != 0 {
+			position += swing * float64(grid)
+		}
+		return position
+	}
+	n := int64(tick) / int64(grid)
+	target := line(n)
+	for _, candidate := range []int64{n - 1, n + 1} {
+		if candidate < 0 {
+			continue
+		}
+		if math.Abs(line(candidate)-float64(tick)) <
+			math.Abs(target-float64(tick)) {
+			target = line(candidate)
+		}
+	}
+	moved := float64(tick) + strength*(target-float64(tick))
+	return uint64(math.Round(moved))
+}
@@ -0,0 +1,102 @@
+package midi_test
+
+import (
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTrackMidi returns a Midi with a single track holding events.
+func newTrackMidi(events ...TrackEvent) *Midi {
+	return &Midi{
+		HeaderChunk: &HeaderChunk{Format: 0, Ntrks: 1, Division: 96},
+		TrackChunks: []TrackChunk{{TrackEvents: events}},
+	}
+}
+
+func TestQuantizeInvalidArguments(t *testing.T) {
+	midi := newTrackMidi()
+	err := Quantize(midi, 0, 1, 0)
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("positive number of ticks; found 0")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+
+	err = Quantize(midi, 24, 1.5, 0)
+	assert.NotNil(t, err)
+	re = regexp.MustCompile("strength must be between 0 and 1")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+
+	err = Quantize(midi, 24, 1, -1)
+	assert.NotNil(t, err)
+}
+
+func TestQuantizeFullStrength(t *testing.T) {
+	midi := newTrackMidi(
+		TrackEvent{5, []byte{0x90, 60, 100}},
+		TrackEvent{40, []byte{0x80, 60, 0}},
+		TrackEvent{3, []byte{0xB0, 7, 100}},
+		TrackEvent{0, []byte{0xFF, 0x2F}},
+	)
+	assert.Nil(t, Quantize(midi, 24, 1, 0))
+	track := midi.TrackChunks[0]
+	// Note on 5 -> 0, note off 45 -> 48, control change stays at 48, end
+	// of track stays at 48.
+	assert.Equal(t, []uint64{0, 48, 48, 48}, track.AbsoluteTicks())
+	assert.Equal(t, byte(0x90), track.TrackEvents[0].Status())
+	assert.Equal(t, byte(0x80), track.TrackEvents[1].Status())
+	assert.Equal(t, byte(0xB0), track.TrackEvents[2].Status())
+	assert.True(t, track.TrackEvents[3].IsMeta(MetaEndOfTrack))
+}
+
+func TestQuantizePartialStrengthAndReorder(t *testing.T) {
+	midi := newTrackMidi(
+		TrackEvent{20, []byte{0xB0, 7, 100}},
+		TrackEvent{2, []byte{0x90, 60, 100}},
+		TrackEvent{10, []byte{0x90, 60, 0}},
+	)
+	assert.Nil(t, Quantize(midi, 24, 0.5, 0))
+	track := midi.TrackChunks[0]
+	// Note on at 22 moves half way to 24, note off at 32 half way to 24.
+	assert.Equal(t, []uint64{20, 23, 28}, track.AbsoluteTicks())
+
+	midi = newTrackMidi(
+		TrackEvent{30, []byte{0xB0, 7, 100}},
+		TrackEvent{10, []byte{0x90, 60, 100}},
+	)
+	assert.Nil(t, Quantize(midi, 24, 1, 0))
+	track = midi.TrackChunks[0]
+	// The note on at 40 snaps to 48 and stays after the controller.
+	assert.Equal(t, []uint64{30, 48}, track.AbsoluteTicks())
+	assert.Equal(t, []int{30, 18}, []int{
+		track.TrackEvents[0].DeltaTime, track.TrackEvents[1].DeltaTime})
+}
+
+func TestQuantizeKeepsCollapsedNotes(t *testing.T) {
+	midi := newTrackMidi(
+		TrackEvent{2, []byte{0x90, 60, 100}},
+		TrackEvent{6, []byte{0x80, 60, 0}},
+		TrackEvent{0, []byte{0xFF, 0x2F}},
+	)
+	assert.Nil(t, Quantize(midi, 24, 1, 0))
+	// The note would collapse to 0 ticks, so it keeps its 6 tick length.
+	// End of track is never moved earlier.
+	assert.Equal(t, []uint64{0, 6, 8}, midi.TrackChunks[0].AbsoluteTicks())
+}
+
+func TestQuantizeSwingAndEndOfTrack(t *testing.T) {
+	midi := newTrackMidi(
+		TrackEvent{0, []byte{0x90, 60, 100}},
+		TrackEvent{23, []byte{0x80, 60, 0}},
+		TrackEvent{1, []byte{0x90, 62, 100}},
+		TrackEvent{0, []byte{0xFF, 0x2F}},
+		TrackEvent{0, []byte{0x80, 62, 0}},
+	)
+	assert.Nil(t, Quantize(midi, 24, 1, 1.0/3))
+	track := midi.TrackChunks[0]
+	// The second grid line is swung from 24 to 32, and the end of track
+	// event follows the last note off.
+	assert.Equal(t, []uint64{0, 32, 32, 32, 32}, track.AbsoluteTicks())
+	assert.True(t, track.TrackEvents[4].IsMeta(MetaEndOfTrack))
+}
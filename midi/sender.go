@@ -0,0 +1,97 @@
+package midi
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	SendDataError   = "status byte %#x needs %v data bytes but the event has %v"
+	SendStatusError = "cannot send an event with status byte %#x"
+)
+
+/*
+A Sender is a MIDI output port: anything that can pass events on to a device, a
+pipe or another program. Only the Data of the event is sent; delta-times are
+for the caller, such as a Player, to honor.
+*/
+type Sender interface {
+	Send(event TrackEvent) error
+}
+
+/*
+StreamSender is a Sender that writes events to an io.Writer as the raw bytes of
+the MIDI wire protocol, as expected by serial ports and device files such as
+/dev/midi. Channel messages are written with their status byte unless
+RunningStatus is set, in which case it is left out when it repeats. System
+exclusive events are written as stored, and escape (0xF7) events as their
+payload alone. Meta events only exist in files, so they are skipped; a lone
+0xFF is sent as a System Reset. System common and real-time messages, such as
+those sent by a Player acting as a clock master, are written as they are.
+*/
+type StreamSender struct {
+	RunningStatus bool
+
+	writer io.Writer
+	status byte
+}
+
+// NewStreamSender returns a StreamSender that writes to writer.
+func NewStreamSender(writer io.Writer) *StreamSender {
+	return &StreamSender{writer: writer}
+}
+
+/*
+Send writes event to the underlying writer in a single Write call. Channel
+messages with the wrong number of data bytes and events with an invalid status
+byte are rejected before anything is written.
+*/
+func (s *StreamSender) Send(event TrackEvent) error {
+	status := event.Status()
+	data := event.Data
+	switch {
+	case status == MetaEvent && len(data) > 1:
+		return nil
+	case status == SysExEvent:
+		s.status = 0
+	case status == SysExEscape:
+		s.status = 0
+		data = data[1:]
+	case event.IsChannelEvent():
+		if size := channelEventSize(status); len(data)-1 != size {
+			return fmt.Errorf(SendDataError, status, size, len(data)-1)
+		}
+		if s.RunningStatus && status == s.status {
+			data = data[1:]
+		}
+		s.status = status
+	default:
+		size, ok := systemMessageSize(status)
+		if !ok {
+			return fmt.Errorf(SendStatusError, status)
+		}
+		if len(data)-1 != size {
+			return fmt.Errorf(SendDataError, status, size, len(data)-1)
+		}
+		// Real-time messages may be sent between the bytes of any other
+		// message, so they leave running status in place.
+		if !isRealTime(status) {
+			s.status = 0
+		}
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	_, err := s.writer.Write(data)
+	return err
+}
+
+/*
+SendTo returns a Player handler that sends every event to sender, so that a
+Player can drive an output port.
+*/
+func SendTo(sender Sender) func(PlayerEvent) error {
+	return func(event PlayerEvent) error {
+		return sender.Send(event.Event)
+	}
+}
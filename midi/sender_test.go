@@ -0,0 +1,75 @@
+package midi_test
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamSender(t *testing.T) {
+	var buffer bytes.Buffer
+	sender := NewStreamSender(&buffer)
+	for _, event := range []TrackEvent{
+		{0, []byte{0x90, 60, 100}},
+		{0, []byte{0x90, 64, 100}},
+		{0, []byte{0xFF, 0x2F}},
+		{0, []byte{0xF0, 0x7E, 0x09, 0xF7}},
+		{0, []byte{0xF7, 0xF8}},
+		{0, []byte{0xC1, 5}},
+	} {
+		assert.Nil(t, sender.Send(event))
+	}
+	assert.Equal(t, []byte{
+		0x90, 60, 100,
+		0x90, 64, 100,
+		0xF0, 0x7E, 0x09, 0xF7,
+		0xF8,
+		0xC1, 5,
+	}, buffer.Bytes())
+
+	err := sender.Send(TrackEvent{0, []byte{0x90, 60}})
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("status byte 0x90 needs 2 data bytes")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+	err = sender.Send(TrackEvent{0, []byte{0x3C}})
+	assert.NotNil(t, err)
+	re = regexp.MustCompile("cannot send an event with status byte 0x3c")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+	assert.NotNil(t, sender.Send(TrackEvent{}))
+	assert.Equal(t, 13, buffer.Len())
+}
+
+func TestStreamSenderRunningStatus(t *testing.T) {
+	var buffer bytes.Buffer
+	sender := NewStreamSender(&buffer)
+	sender.RunningStatus = true
+	for _, event := range []TrackEvent{
+		{0, []byte{0x90, 60, 100}},
+		{0, []byte{0x90, 64, 100}},
+		// System exclusive messages cancel running status.
+		{0, []byte{0xF0, 0x01, 0xF7}},
+		{0, []byte{0x90, 60, 0}},
+		{0, []byte{0x80, 64, 0}},
+	} {
+		assert.Nil(t, sender.Send(event))
+	}
+	assert.Equal(t, []byte{
+		0x90, 60, 100, 64, 100,
+		0xF0, 0x01, 0xF7,
+		0x90, 60, 0,
+		0x80, 64, 0,
+	}, buffer.Bytes())
+}
+
+func TestPlayerSendTo(t *testing.T) {
+	var buffer bytes.Buffer
+	player := NewPlayer(newPlayerMidi(), SendTo(NewStreamSender(&buffer)))
+	player.SetTempoScale(100)
+	assert.Nil(t, player.Play(context.Background()))
+	assert.Equal(t, []byte{0x90, 60, 100, 0x91, 64, 100, 0x80, 60, 0},
+		buffer.Bytes())
+}
@@ -0,0 +1,57 @@
+package midi
+
+/*
+isMultiSequence returns true for format 2 files, whose tracks are independent
+sequences rather than parts of one.
+*/
+func (m *Midi) isMultiSequence() bool {
+	return m.HeaderChunk != nil && m.Format == 2
+}
+
+/*
+trackTempoMaps returns the TempoMap that times each track of m: the file's own
+or, for a format 2 file, that of the track's sequence.
+*/
+func (m *Midi) trackTempoMaps() []*TempoMap {
+	tempoMaps := make([]*TempoMap, len(m.TrackChunks))
+	for i := range tempoMaps {
+		switch {
+		case m.isMultiSequence():
+			tempoMaps[i] = m.SequenceTempoMap(i)
+		case i == 0:
+			tempoMaps[i] = m.TempoMap()
+		default:
+			tempoMaps[i] = tempoMaps[0]
+		}
+	}
+	return tempoMaps
+}
+
+/*
+Sequences returns the sequences held by m. A format 2 file holds one per track,
+each with its own timing, and each is returned as a format 0 Midi with the same
+division whose TempoMap, Notes and other methods see that sequence alone. The
+tracks share their events with m. Format 0 and 1 files hold a single sequence,
+so they are returned as is. Tools that treat a file as one timeline, such as
+Player and MergeTracks, should be given one sequence at a time.
+*/
+func (m *Midi) Sequences() []*Midi {
+	if !m.isMultiSequence() {
+		return []*Midi{m}
+	}
+	sequences := make([]*Midi, len(m.TrackChunks))
+	for i, track := range m.TrackChunks {
+		sequence := &Midi{
+			HeaderChunk: &HeaderChunk{
+				Chunk:    &Chunk{Type: headerChunk, Length: headerDataSize},
+				Format:   0,
+				Ntrks:    1,
+				Division: m.Division,
+			},
+			TrackChunks: []TrackChunk{track},
+		}
+		sequence.Reindex()
+		sequences[i] = sequence
+	}
+	return sequences
+}
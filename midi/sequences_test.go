@@ -0,0 +1,65 @@
+package midi_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+// newSequenceMidi returns a file of the given format whose first track sets a
+// tempo of 60 beats per minute and whose tracks each play a note at tick 96.
+func newSequenceMidi(format uint16) *Midi {
+	return &Midi{
+		HeaderChunk: &HeaderChunk{Format: format, Ntrks: 2, Division: 96},
+		TrackChunks: []TrackChunk{
+			{TrackEvents: []TrackEvent{
+				{0, []byte{0xFF, 0x51, 0x0F, 0x42, 0x40}},
+				{96, []byte{0x90, 60, 100}},
+				{96, []byte{0x80, 60, 0}},
+			}},
+			{TrackEvents: []TrackEvent{
+				{96, []byte{0x91, 64, 100}},
+				{96, []byte{0x81, 64, 0}},
+			}},
+		},
+	}
+}
+
+func TestSequences(t *testing.T) {
+	midi := newSequenceMidi(2)
+	sequences := midi.Sequences()
+	assert.Equal(t, 2, len(sequences))
+	for i, sequence := range sequences {
+		assert.Equal(t, uint16(0), sequence.Format)
+		assert.Equal(t, uint16(1), sequence.Ntrks)
+		assert.Equal(t, uint16(96), sequence.Division)
+		assert.Equal(t, midi.TrackChunks[i].TrackEvents,
+			sequence.TrackChunks[0].TrackEvents)
+	}
+	assert.Equal(t, time.Second, sequences[0].TempoMap().Time(96))
+	assert.Equal(t, 500*time.Millisecond, sequences[1].TempoMap().Time(96))
+
+	// Format 0 and 1 files are a single sequence.
+	midi = newSequenceMidi(1)
+	assert.Equal(t, []*Midi{midi}, midi.Sequences())
+}
+
+func TestFormat2TempoMaps(t *testing.T) {
+	midi := newSequenceMidi(2)
+	assert.Equal(t, time.Second, midi.TempoMap().Time(96))
+	assert.Equal(t, time.Second, midi.SequenceTempoMap(0).Time(96))
+	assert.Equal(t, 500*time.Millisecond, midi.SequenceTempoMap(1).Time(96))
+
+	notes := midi.Notes()
+	assert.Equal(t, 2, len(notes))
+	assert.Equal(t, time.Second, notes[0].StartTime)
+	assert.Equal(t, time.Second, notes[0].Duration)
+	assert.Equal(t, 500*time.Millisecond, notes[1].StartTime)
+	assert.Equal(t, 500*time.Millisecond, notes[1].Duration)
+
+	// In a format 1 file the tempo of the first track applies to all.
+	notes = newSequenceMidi(1).Notes()
+	assert.Equal(t, time.Second, notes[1].StartTime)
+}
@@ -0,0 +1,275 @@
+package midi
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	SMPTEDivisionError = "SMPTE division has an unsupported rate of %v frames per second"
+
+	// MTCQuarterFrame is the status byte of an MTC Quarter Frame message.
+	MTCQuarterFrame = 0xF1
+
+	// The following frame counts are those of 29.97 drop frame timecode,
+	// which skips frame numbers 0 and 1 at the start of every minute except
+	// every tenth.
+	dropFramesPerMinute    = 30*60 - 2
+	dropFramesPer10Minutes = 10*dropFramesPerMinute + 2
+)
+
+/*
+FrameRate is one of the four SMPTE frame rates used by MIDI. The values match
+the rate bits of MIDI Time Code.
+*/
+type FrameRate uint8
+
+const (
+	FrameRate24 FrameRate = iota
+	FrameRate25
+	FrameRate2997Drop
+	FrameRate30
+)
+
+// smpteRates maps the frames per second of an SMPTE division to a FrameRate.
+var smpteRates = map[int8]FrameRate{
+	24: FrameRate24, 25: FrameRate25, 29: FrameRate2997Drop, 30: FrameRate30,
+}
+
+/*
+FramesPerSecond returns the number of frames in a second at the rate, 29.97 for
+drop frame timecode.
+*/
+func (r FrameRate) FramesPerSecond() float64 {
+	switch r {
+	case FrameRate24:
+		return 24
+	case FrameRate25:
+		return 25
+	case FrameRate2997Drop:
+		return 30000.0 / 1001
+	}
+	return 30
+}
+
+/*
+nominalFrames returns the number of frame numbers in each timecode second: 30
+for drop frame timecode, whose seconds are labels rather than real seconds.
+*/
+func (r FrameRate) nominalFrames() uint64 {
+	switch r {
+	case FrameRate24:
+		return 24
+	case FrameRate25:
+		return 25
+	}
+	return 30
+}
+
+/*
+SMPTE returns the frame rate and ticks per frame of an SMPTE division, where
+the high byte holds the negated frames per second. It returns false when the
+division counts ticks per quarter note, or names an unsupported rate.
+*/
+func (h *HeaderChunk) SMPTE() (FrameRate, uint8, bool) {
+	if h.Division&smpteMask == 0 {
+		return 0, 0, false
+	}
+	rate, ok := smpteRates[-int8(h.Division>>8)]
+	return rate, uint8(h.Division), ok
+}
+
+/*
+TicksPerQuarterNote returns the number of ticks in a quarter note, or false for
+an SMPTE division, whose ticks are a fixed fraction of a second.
+*/
+func (h *HeaderChunk) TicksPerQuarterNote() (uint16, bool) {
+	if h.Division&smpteMask != 0 {
+		return 0, false
+	}
+	return h.Division, true
+}
+
+// validateDivision checks that an SMPTE division names a supported rate.
+func (h *HeaderChunk) validateDivision() error {
+	if h.Division&smpteMask == 0 {
+		return nil
+	}
+	if _, _, ok := h.SMPTE(); !ok {
+		return fmt.Errorf(SMPTEDivisionError, -int8(h.Division>>8))
+	}
+	return nil
+}
+
+/*
+A Timecode is an SMPTE time as carried by MIDI Time Code: hours, minutes,
+seconds and frames at a frame rate.
+*/
+type Timecode struct {
+	Hours   uint8
+	Minutes uint8
+	Seconds uint8
+	Frames  uint8
+	Rate    FrameRate
+}
+
+// String formats the timecode as hh:mm:ss:ff, or hh:mm:ss;ff for drop frame.
+func (c Timecode) String() string {
+	separator := ":"
+	if c.Rate == FrameRate2997Drop {
+		separator = ";"
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d",
+		c.Hours, c.Minutes, c.Seconds, separator, c.Frames)
+}
+
+/*
+frameNumber returns the number of frames from 00:00:00:00 to the timecode,
+skipping the frame numbers dropped by drop frame timecode.
+*/
+func (c Timecode) frameNumber() uint64 {
+	minutes := uint64(c.Hours)*60 + uint64(c.Minutes)
+	frames := (minutes*60+uint64(c.Seconds))*c.Rate.nominalFrames() +
+		uint64(c.Frames)
+	if c.Rate == FrameRate2997Drop {
+		frames -= 2 * (minutes - minutes/10)
+	}
+	return frames
+}
+
+/*
+frameDuration returns the length of numerator / denominator frames at the rate,
+in nanoseconds.
+*/
+func (r FrameRate) frameDuration() (uint64, uint64) {
+	if r == FrameRate2997Drop {
+		return 1001 * uint64(time.Second), 30000
+	}
+	return uint64(time.Second), r.nominalFrames()
+}
+
+/*
+Duration returns the time from 00:00:00:00 to the start of the timecode's frame,
+rounded up to a whole nanosecond so that TimecodeAt returns the same timecode.
+*/
+func (c Timecode) Duration() time.Duration {
+	frames := c.frameNumber()
+	numerator, denominator := c.Rate.frameDuration()
+	d := scale(frames, numerator, denominator)
+	if scale(d, denominator, numerator) < frames {
+		d++
+	}
+	return time.Duration(d)
+}
+
+/*
+TimecodeAt returns the timecode of the frame showing at d, rounding down to a
+whole frame. Hours wrap at 24, as they do in MIDI Time Code.
+*/
+func TimecodeAt(d time.Duration, rate FrameRate) Timecode {
+	if d < 0 {
+		d = 0
+	}
+	numerator, denominator := rate.frameDuration()
+	frames := scale(uint64(d), denominator, numerator)
+	if rate == FrameRate2997Drop {
+		tens, remainder := frames/dropFramesPer10Minutes,
+			frames%dropFramesPer10Minutes
+		frames += 18 * tens
+		if remainder > 1 {
+			frames += 2 * ((remainder - 2) / dropFramesPerMinute)
+		}
+	}
+	perSecond := rate.nominalFrames()
+	seconds := frames / perSecond
+	return Timecode{
+		Hours:   uint8(seconds / 3600 % 24),
+		Minutes: uint8(seconds / 60 % 60),
+		Seconds: uint8(seconds % 60),
+		Frames:  uint8(frames % perSecond),
+		Rate:    rate,
+	}
+}
+
+/*
+Timecode returns the timecode at tick, with the frame rate of an SMPTE division
+or, for other divisions, rate.
+*/
+func (t *TempoMap) Timecode(tick uint64, rate FrameRate) Timecode {
+	header := HeaderChunk{Division: t.division}
+	if smpte, _, ok := header.SMPTE(); ok {
+		rate = smpte
+	}
+	return TimecodeAt(t.Time(tick), rate)
+}
+
+/*
+QuarterFrames returns the eight MTC Quarter Frame messages that carry the
+timecode, in the order they are sent while playing forward.
+*/
+func (c Timecode) QuarterFrames() []TrackEvent {
+	values := [8]uint8{
+		c.Frames & 0x0F, c.Frames >> 4 & 0x01,
+		c.Seconds & 0x0F, c.Seconds >> 4 & 0x03,
+		c.Minutes & 0x0F, c.Minutes >> 4 & 0x03,
+		c.Hours & 0x0F, c.Hours>>4&0x01 | uint8(c.Rate&0x03)<<1,
+	}
+	events := make([]TrackEvent, len(values))
+	for piece, value := range values {
+		events[piece] = TrackEvent{
+			Data: []byte{MTCQuarterFrame, uint8(piece)<<4 | value}}
+	}
+	return events
+}
+
+/*
+MTCDecoder assembles the timecode sent by an MIDI Time Code master, either as
+eight Quarter Frame messages or as a single Full Frame system exclusive
+message. The zero value is ready to use.
+*/
+type MTCDecoder struct {
+	pieces   [8]uint8
+	received uint8
+}
+
+/*
+Decode adds an event received from the master, returning the timecode it
+completes, if any. A timecode assembled from quarter frames is returned when
+the last piece arrives having seen all eight; it is the time at which the first
+piece was sent, two frames earlier, as the MTC specification describes. Events
+other than Quarter Frame and Full Frame messages are ignored.
+*/
+func (d *MTCDecoder) Decode(event TrackEvent) (Timecode, bool) {
+	data := event.Data
+	switch {
+	case len(data) == 2 && data[0] == MTCQuarterFrame:
+		piece := data[1] >> 4 & 0x07
+		if piece == 0 {
+			d.received = 0
+		}
+		d.pieces[piece] = data[1] & 0x0F
+		d.received |= 1 << piece
+		if piece != 7 || d.received != 0xFF {
+			return Timecode{}, false
+		}
+		return Timecode{
+			Frames:  d.pieces[0] | d.pieces[1]&0x01<<4,
+			Seconds: d.pieces[2] | d.pieces[3]&0x03<<4,
+			Minutes: d.pieces[4] | d.pieces[5]&0x03<<4,
+			Hours:   d.pieces[6] | d.pieces[7]&0x01<<4,
+			Rate:    FrameRate(d.pieces[7] >> 1 & 0x03),
+		}, true
+	case len(data) == 10 && data[0] == SysExEvent && data[1] == 0x7F &&
+		data[3] == 0x01 && data[4] == 0x01:
+		// F0 7F <device> 01 01 hr mn sc fr F7
+		d.received = 0
+		return Timecode{
+			Hours:   data[5] & 0x1F,
+			Minutes: data[6] & 0x3F,
+			Seconds: data[7] & 0x3F,
+			Frames:  data[8] & 0x1F,
+			Rate:    FrameRate(data[5] >> 5 & 0x03),
+		}, true
+	}
+	return Timecode{}, false
+}
@@ -0,0 +1,110 @@
+package midi_test
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderSMPTE(t *testing.T) {
+	header := HeaderChunk{Division: 0xE728}
+	rate, perFrame, ok := header.SMPTE()
+	assert.True(t, ok)
+	assert.Equal(t, FrameRate25, rate)
+	assert.Equal(t, uint8(40), perFrame)
+	_, ok = header.TicksPerQuarterNote()
+	assert.False(t, ok)
+
+	header.Division = 0xE350
+	rate, _, _ = header.SMPTE()
+	assert.Equal(t, FrameRate2997Drop, rate)
+
+	header.Division = 480
+	_, _, ok = header.SMPTE()
+	assert.False(t, ok)
+	ticks, ok := header.TicksPerQuarterNote()
+	assert.True(t, ok)
+	assert.Equal(t, uint16(480), ticks)
+
+	// Only the four SMPTE rates are accepted when parsing.
+	var buffer bytes.Buffer
+	writeHeader(&buffer, 0, 1, 0xE628)
+	writeTrack(&buffer, noteTrack)
+	_, err := ParseMidi(buffer.Bytes(), nil)
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("unsupported rate of 26 frames per second")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
+
+func TestTimecode(t *testing.T) {
+	code := Timecode{1, 2, 3, 4, FrameRate25}
+	assert.Equal(t, "01:02:03:04", code.String())
+	assert.Equal(t, time.Hour+2*time.Minute+3*time.Second+160*time.Millisecond,
+		code.Duration())
+	assert.Equal(t, code, TimecodeAt(code.Duration(), FrameRate25))
+
+	// Drop frame timecode skips frames 0 and 1 of minute 1, but not of
+	// minute 10.
+	drop := TimecodeAt(60060*time.Millisecond, FrameRate2997Drop)
+	assert.Equal(t, "00:01:00;02", drop.String())
+	assert.Equal(t, Timecode{0, 0, 59, 29, FrameRate2997Drop},
+		TimecodeAt(Timecode{0, 1, 0, 2, FrameRate2997Drop}.Duration()-
+			time.Millisecond, FrameRate2997Drop))
+	for _, code := range []Timecode{
+		{0, 1, 0, 2, FrameRate2997Drop},
+		{0, 10, 0, 0, FrameRate2997Drop},
+		{1, 23, 45, 17, FrameRate2997Drop},
+		{23, 59, 59, 23, FrameRate24},
+		{12, 0, 0, 29, FrameRate30},
+	} {
+		assert.Equal(t, code, TimecodeAt(code.Duration(), code.Rate))
+	}
+	// Ten minutes of drop frame timecode is 17982 frames.
+	assert.Equal(t, time.Duration(17982*1001*int64(time.Second)/30000),
+		Timecode{0, 10, 0, 0, FrameRate2997Drop}.Duration())
+}
+
+func TestTempoMapTimecode(t *testing.T) {
+	midi := newTrackMidi()
+	midi.Division = 0xE728
+	// 25 frames per second of 40 ticks each.
+	code := midi.TempoMap().Timecode(1000*60+50, FrameRate30)
+	assert.Equal(t, Timecode{0, 1, 0, 1, FrameRate25}, code)
+
+	midi.Division = 96
+	code = midi.TempoMap().Timecode(192, FrameRate30)
+	assert.Equal(t, Timecode{0, 0, 1, 0, FrameRate30}, code)
+}
+
+func TestMTCDecoder(t *testing.T) {
+	code := Timecode{17, 42, 33, 28, FrameRate2997Drop}
+	frames := code.QuarterFrames()
+	assert.Equal(t, 8, len(frames))
+	assert.Equal(t, []byte{MTCQuarterFrame, 0x0C}, frames[0].Data)
+	assert.Equal(t, []byte{MTCQuarterFrame, 0x75}, frames[7].Data)
+
+	var decoder MTCDecoder
+	// Pieces before the first piece 0 cannot complete a timecode.
+	for _, frame := range frames[4:] {
+		_, ok := decoder.Decode(frame)
+		assert.False(t, ok)
+	}
+	for i, frame := range frames {
+		decoded, ok := decoder.Decode(frame)
+		assert.Equal(t, i == 7, ok)
+		if ok {
+			assert.Equal(t, code, decoded)
+		}
+	}
+
+	decoded, ok := decoder.Decode(TrackEvent{Data: []byte{
+		0xF0, 0x7F, 0x7F, 0x01, 0x01, 0x61, 0x02, 0x03, 0x04, 0xF7}})
+	assert.True(t, ok)
+	assert.Equal(t, Timecode{1, 2, 3, 4, FrameRate30}, decoded)
+	_, ok = decoder.Decode(TrackEvent{Data: []byte{0x90, 60, 100}})
+	assert.False(t, ok)
+}
@@ -0,0 +1,131 @@
+package midi
+
+import (
+	"sort"
+)
+
+/*
+isTempoMapEvent returns true for the meta events that determine how ticks map
+to time and bars: Set Tempo, Time Signature and Key Signature. These are copied
+into every Midi produced by a split so the parts play back identically.
+*/
+func isTempoMapEvent(event *TrackEvent) bool {
+	return event.IsMeta(MetaSetTempo) || event.IsMeta(MetaTimeSignature) ||
+		event.IsMeta(MetaKeySignature)
+}
+
+/*
+SplitByChannel splits m into one format 0 Midi per channel used by its channel
+voice messages, keyed by channel. Each output holds the events for its channel
+along with the tempo map of the original file. Other meta and system exclusive
+events are not channel specific and are dropped.
+*/
+func (m *Midi) SplitByChannel() map[uint8]*Midi {
+	var tempoMap []timedEvent
+	channels := make(map[uint8][]timedEvent)
+	events := m.mergedEvents()
+	for _, timed := range events {
+		if isTempoMapEvent(&timed.event) {
+			tempoMap = append(tempoMap, timed)
+		} else if timed.event.IsChannelEvent() {
+			channel := timed.event.Channel()
+			channels[channel] = append(channels[channel], timed)
+		}
+	}
+
+	outputs := make(map[uint8]*Midi)
+	for channel, channelEvents := range channels {
+		outputs[channel] = m.newSplitMidi(
+			mergeTimedEvents(tempoMap, channelEvents), lastTick(events))
+	}
+	return outputs
+}
+
+/*
+SplitByTrack splits m into one format 0 Midi per track, in track order. Each
+output holds every event of its track, plus the tempo map events found in the
+other tracks, which for a format 1 file live in the first track.
+*/
+func (m *Midi) SplitByTrack() []*Midi {
+	var tempoMap []timedEvent
+	tracks := make([][]timedEvent, len(m.TrackChunks))
+	events := m.mergedEvents()
+	for _, timed := range events {
+		if isTempoMapEvent(&timed.event) {
+			tempoMap = append(tempoMap, timed)
+		}
+		tracks[timed.track] = append(tracks[timed.track], timed)
+	}
+
+	outputs := make([]*Midi, len(m.TrackChunks))
+	for index, trackEvents := range tracks {
+		var borrowed []timedEvent
+		for _, timed := range tempoMap {
+			if timed.track != index {
+				borrowed = append(borrowed, timed)
+			}
+		}
+		outputs[index] = m.newSplitMidi(
+			mergeTimedEvents(borrowed, trackEvents), lastTick(events))
+	}
+	return outputs
+}
+
+/*
+mergeTimedEvents merges two slices of timedEvents sorted by tick into one. At
+the same tick, events from first come before those from second.
+*/
+func mergeTimedEvents(first, second []timedEvent) []timedEvent {
+	merged := append(append([]timedEvent{}, first...), second...)
+	sort.SliceStable(merged, func(a, b int) bool {
+		return merged[a].tick < merged[b].tick
+	})
+	return merged
+}
+
+// lastTick returns the largest tick among events, or 0 if there are none.
+func lastTick(events []timedEvent) uint64 {
+	var last uint64
+	for _, timed := range events {
+		if timed.tick > last {
+			last = timed.tick
+		}
+	}
+	return last
+}
+
+/*
+newSplitMidi builds a single track format 0 Midi sharing m's division from
+events sorted by tick. End of Track events in events are dropped and a single
+End of Track is placed at end.
+*/
+func (m *Midi) newSplitMidi(events []timedEvent, end uint64) *Midi {
+	track := TrackChunk{Chunk: &Chunk{Type: trackChunk}}
+	var ticks []uint64
+	for _, timed := range events {
+		if timed.event.IsMeta(MetaEndOfTrack) {
+			continue
+		}
+		event := timed.event
+		event.Data = append([]byte{}, event.Data...)
+		track.TrackEvents = append(track.TrackEvents, event)
+		ticks = append(ticks, timed.tick)
+	}
+	track.TrackEvents = append(
+		track.TrackEvents, TrackEvent{Data: []byte{MetaEvent, MetaEndOfTrack}})
+	ticks = append(ticks, end)
+	track.setAbsoluteTicks(ticks)
+	track.Length = track.dataLength()
+
+	split := &Midi{
+		HeaderChunk: &HeaderChunk{
+			Chunk:    &Chunk{Type: headerChunk, Length: headerDataSize},
+			Format:   0,
+			Ntrks:    1,
+			Division: m.Division,
+		},
+		TrackChunks: []TrackChunk{track},
+	}
+	split.Reindex()
+	return split
+}
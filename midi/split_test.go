@@ -0,0 +1,83 @@
+package midi_test
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+// splitSource returns a format 1 file with a tempo track and a track playing
+// on channels 0 and 9.
+func splitSource(t *testing.T) *Midi {
+	var buffer bytes.Buffer
+	writeHeader(&buffer, 1, 2, 96)
+	writeTrack(&buffer, []byte{
+		0x00, 0xFF, 0x51, 0x03, 0x07, 0xA1, 0x20,
+		0x00, 0xFF, 0x58, 0x04, 0x04, 0x02, 0x18, 0x08,
+		0x60, 0xFF, 0x51, 0x03, 0x0F, 0x42, 0x40,
+		0x00, 0xFF, 0x2F, 0x00,
+	})
+	writeTrack(&buffer, []byte{
+		0x00, 0xFF, 0x03, 0x03, 'M', 'i', 'x',
+		0x00, 0x90, 0x3C, 0x40,
+		0x30, 0x99, 0x24, 0x64,
+		0x30, 0x80, 0x3C, 0x00,
+		0x30, 0x89, 0x24, 0x00,
+		0x00, 0xFF, 0x2F, 0x00,
+	})
+	midi, err := ParseMidi(buffer.Bytes(), nil)
+	assert.Nil(t, err)
+	return midi
+}
+
+func TestSplitByChannel(t *testing.T) {
+	outputs := splitSource(t).SplitByChannel()
+	assert.Equal(t, 2, len(outputs))
+
+	piano := outputs[0]
+	assert.Equal(t, uint16(0), piano.Format)
+	assert.Equal(t, uint16(96), piano.Division)
+	assert.Equal(t, 1, len(piano.TrackChunks))
+	events := piano.TrackChunks[0].TrackEvents
+	assert.Equal(t, 6, len(events))
+	assert.True(t, events[0].IsMeta(MetaSetTempo))
+	assert.True(t, events[1].IsMeta(MetaTimeSignature))
+	assert.True(t, events[2].IsNoteOn())
+	assert.True(t, events[3].IsMeta(MetaSetTempo))
+	assert.True(t, events[4].IsNoteOff())
+	assert.True(t, events[5].IsMeta(MetaEndOfTrack))
+	// Every output ends where the source ends.
+	assert.Equal(t, []uint64{0, 0, 0, 96, 96, 144},
+		piano.TrackChunks[0].AbsoluteTicks())
+
+	drums := outputs[9].TrackChunks[0]
+	assert.Equal(t, []uint64{0, 0, 48, 96, 144, 144}, drums.AbsoluteTicks())
+	assert.Equal(t, uint8(9), drums.TrackEvents[2].Channel())
+}
+
+func TestSplitByTrack(t *testing.T) {
+	source := splitSource(t)
+	outputs := source.SplitByTrack()
+	assert.Equal(t, 2, len(outputs))
+
+	// The tempo track is not given a second copy of its own events.
+	tempo := outputs[0].TrackChunks[0]
+	assert.Equal(t, 4, len(tempo.TrackEvents))
+	assert.Equal(t, []uint64{0, 0, 96, 144}, tempo.AbsoluteTicks())
+
+	mix := outputs[1]
+	assert.Equal(t, []int{0}, mix.TrackIndices("Mix"))
+	events := mix.TrackChunks[0].TrackEvents
+	assert.Equal(t, 9, len(events))
+	assert.True(t, events[0].IsMeta(MetaSetTempo))
+	assert.True(t, events[1].IsMeta(MetaTimeSignature))
+	assert.True(t, events[2].IsMeta(MetaTrackName))
+	assert.Equal(t, []uint64{0, 0, 0, 0, 48, 96, 96, 144, 144},
+		mix.TrackChunks[0].AbsoluteTicks())
+
+	// Outputs do not share event data with the source.
+	events[3].Data[1] = 0x40
+	assert.Equal(t, byte(0x3C), source.TrackChunks[1].TrackEvents[1].Data[1])
+}
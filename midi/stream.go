@@ -0,0 +1,115 @@
+package midi
+
+import "io"
+
+/*
+systemMessageSize returns the number of data bytes following the status byte of
+a system common or real-time message, and false for the undefined status bytes
+and for system exclusive messages, which have no fixed size.
+*/
+func systemMessageSize(status byte) (int, bool) {
+	switch status {
+	case 0xF1, 0xF3:
+		return 1, true
+	case SongPositionPointer:
+		return 2, true
+	case 0xF6, TimingClock, StartMessage, ContinueMessage, StopMessage,
+		0xFE, 0xFF:
+		return 0, true
+	}
+	return 0, false
+}
+
+// isRealTime returns true for the status bytes of system real-time messages.
+func isRealTime(status byte) bool {
+	return status >= TimingClock
+}
+
+/*
+StreamParser reads MIDI messages from a live byte stream, such as the input of
+a device, as opposed to a file. It expands running status, returns real-time
+messages as soon as they arrive, even in the middle of another message, and
+collects system exclusive messages up to their closing 0xF7. Each message is
+returned as a TrackEvent with a DeltaTime of 0. Stray data bytes and messages
+cut short by a new status byte are dropped, as a receiver is expected to do.
+Note that 0xFF is a System Reset on the wire, not a meta event.
+*/
+type StreamParser struct {
+	reader  io.ByteReader
+	status  byte
+	message []byte
+	size    int
+	sysEx   bool
+}
+
+// NewStreamParser returns a StreamParser that reads from reader.
+func NewStreamParser(reader io.ByteReader) *StreamParser {
+	return &StreamParser{reader: reader}
+}
+
+/*
+Next returns the next complete message in the stream. It returns io.EOF when the
+stream ends, dropping any incomplete message.
+*/
+func (p *StreamParser) Next() (TrackEvent, error) {
+	for {
+		current, err := p.reader.ReadByte()
+		if err != nil {
+			return TrackEvent{}, err
+		}
+		if message, ok := p.add(current); ok {
+			return TrackEvent{Data: message}, nil
+		}
+	}
+}
+
+/*
+add adds a byte read from the stream, returning the message it completes, if
+any.
+*/
+func (p *StreamParser) add(current byte) ([]byte, bool) {
+	switch {
+	case isRealTime(current):
+		if _, ok := systemMessageSize(current); ok {
+			return []byte{current}, true
+		}
+		return nil, false
+	case current == SysExEscape:
+		if !p.sysEx {
+			return nil, false
+		}
+		message := append(p.message, current)
+		p.message, p.sysEx = nil, false
+		return message, true
+	case current&msbMask != 0:
+		p.message, p.sysEx = []byte{current}, current == SysExEvent
+		if current < SysExEvent {
+			p.status, p.size = current, channelEventSize(current)
+			return nil, false
+		}
+		// System common messages cancel running status.
+		p.status = 0
+		size, ok := systemMessageSize(current)
+		if !ok && !p.sysEx {
+			p.message = nil
+			return nil, false
+		}
+		p.size = size
+	case p.sysEx:
+		p.message = append(p.message, current)
+		return nil, false
+	case len(p.message) > 0:
+		p.message = append(p.message, current)
+	case p.status != 0:
+		p.message = []byte{p.status, current}
+		p.size = channelEventSize(p.status)
+	default:
+		return nil, false
+	}
+	if p.sysEx || len(p.message) != p.size+1 {
+		return nil, false
+	}
+	message := p.message
+	p.message = nil
+	return message, true
+}
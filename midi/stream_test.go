@@ -0,0 +1,72 @@
+package midi_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamParser(t *testing.T) {
+	parser := NewStreamParser(bytes.NewReader([]byte{
+		0x90, 60, 100, 64, 100,
+		// Real-time messages may interrupt other messages.
+		0x80, 60, 0xF8, 0,
+		0xF0, 0x01, 0xFA, 0x02, 0xF7,
+		// System common messages cancel running status, so 0x3C is dropped.
+		0xF2, 0x01, 0x02, 0x3C,
+		0xF6, 0xF4, 0xF9,
+		// A message cut short is dropped.
+		0x90, 60, 0xC0, 5,
+		0xFF, 0xB0, 7,
+	}))
+	var events [][]byte
+	for {
+		event, err := parser.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, 0, event.DeltaTime)
+		events = append(events, event.Data)
+	}
+	assert.Equal(t, [][]byte{
+		{0x90, 60, 100},
+		{0x90, 64, 100},
+		{0xF8},
+		{0x80, 60, 0},
+		{0xFA},
+		{0xF0, 0x01, 0x02, 0xF7},
+		{0xF2, 0x01, 0x02},
+		{0xF6},
+		{0xC0, 5},
+		{0xFF},
+	}, events)
+}
+
+func TestStreamParserRoundTrip(t *testing.T) {
+	var buffer bytes.Buffer
+	sender := NewStreamSender(&buffer)
+	sender.RunningStatus = true
+	sent := []TrackEvent{
+		{0, []byte{0xB0, 7, 100}},
+		{0, []byte{0xF8}},
+		{0, []byte{0xB0, 10, 64}},
+		{0, []byte{0xF2, 0x10, 0x00}},
+		{0, []byte{0xE0, 0x00, 0x40}},
+		{0, []byte{0xFF}},
+	}
+	for _, event := range sent {
+		assert.Nil(t, sender.Send(event))
+	}
+	parser := NewStreamParser(&buffer)
+	for _, event := range sent {
+		received, err := parser.Next()
+		assert.Nil(t, err)
+		assert.Equal(t, event, received)
+	}
+	_, err := parser.Next()
+	assert.Equal(t, io.EOF, err)
+}
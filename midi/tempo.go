@@ -0,0 +1,181 @@
+package midi
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultTempo is the tempo of a file before any Set Tempo event, in
+	// microseconds per quarter note (120 beats per minute).
+	DefaultTempo = 500000
+
+	// smpteMask selects the bit of the division that indicates SMPTE timing.
+	smpteMask = 0x8000
+)
+
+/*
+tempoChange records a Set Tempo event along with the time at which it occurs,
+so conversions do not need to sum every earlier segment.
+*/
+type tempoChange struct {
+	tick  uint64
+	tempo uint32
+	time  time.Duration
+}
+
+/*
+A TempoMap converts between ticks and elapsed time for a Midi. When the division
+counts ticks per quarter note, the length of a tick depends on the Set Tempo
+events in effect. When it is an SMPTE division, ticks are a fixed fraction of a
+second and tempo events do not affect timing.
+*/
+type TempoMap struct {
+	division uint16
+	changes  []tempoChange
+}
+
+/*
+TempoMap builds a TempoMap from the Set Tempo events in every track of m. Events
+with a malformed payload or a tempo of 0 are ignored. The tracks of a format 2
+file are independent sequences, so only the first track's tempo events are used
+for it; SequenceTempoMap gives the map of each of the others.
+*/
+func (m *Midi) TempoMap() *TempoMap {
+	if m.isMultiSequence() && len(m.TrackChunks) > 0 {
+		return m.SequenceTempoMap(0)
+	}
+	return m.newTempoMap(m.mergedEvents())
+}
+
+/*
+SequenceTempoMap builds a TempoMap from the Set Tempo events of track alone, the
+timing of that track's sequence in a format 2 file.
+*/
+func (m *Midi) SequenceTempoMap(track int) *TempoMap {
+	return m.newTempoMap(m.trackEvents(track))
+}
+
+// trackEvents returns the events of a single track with their absolute ticks.
+func (m *Midi) trackEvents(track int) []timedEvent {
+	chunk := &m.TrackChunks[track]
+	events := make([]timedEvent, len(chunk.TrackEvents))
+	for i, tick := range chunk.AbsoluteTicks() {
+		events[i] = timedEvent{track, tick, chunk.TrackEvents[i]}
+	}
+	return events
+}
+
+// newTempoMap builds a TempoMap from events in time order.
+func (m *Midi) newTempoMap(events []timedEvent) *TempoMap {
+	var division uint16
+	if m.HeaderChunk != nil {
+		division = m.Division
+	}
+	tempoMap := &TempoMap{
+		division: division,
+		changes:  []tempoChange{{0, DefaultTempo, 0}},
+	}
+	for _, timed := range events {
+		event := &timed.event
+		if !event.IsMeta(MetaSetTempo) || len(event.Data) != 5 {
+			continue
+		}
+		tempo := uint32(event.Data[2])<<16 | uint32(event.Data[3])<<8 |
+			uint32(event.Data[4])
+		if tempo == 0 {
+			continue
+		}
+		change := tempoChange{tick: timed.tick, tempo: tempo}
+		change.time = tempoMap.Time(timed.tick)
+		if last := &tempoMap.changes[len(tempoMap.changes)-1]; last.tick ==
+			timed.tick {
+			*last = change
+		} else {
+			tempoMap.changes = append(tempoMap.changes, change)
+		}
+	}
+	return tempoMap
+}
+
+/*
+changeAt returns the last tempo change at or before tick.
+*/
+func (t *TempoMap) changeAt(tick uint64) tempoChange {
+	index := sort.Search(len(t.changes), func(i int) bool {
+		return t.changes[i].tick > tick
+	})
+	return t.changes[index-1]
+}
+
+/*
+Tempo returns the tempo in effect at tick, in microseconds per quarter note.
+*/
+func (t *TempoMap) Tempo(tick uint64) uint32 {
+	return t.changeAt(tick).tempo
+}
+
+/*
+Time returns the time elapsed from the start of the file until tick.
+*/
+func (t *TempoMap) Time(tick uint64) time.Duration {
+	if t.division&smpteMask != 0 {
+		return time.Duration(
+			scale(tick, 100*uint64(time.Second), t.ticksPer100Seconds()))
+	}
+	if t.division == 0 {
+		return 0
+	}
+	change := t.changeAt(tick)
+	return change.time + time.Duration(scale(tick-change.tick,
+		uint64(change.tempo)*uint64(time.Microsecond), uint64(t.division)))
+}
+
+/*
+Tick returns the last tick at or before the elapsed time d, the inverse of Time.
+*/
+func (t *TempoMap) Tick(d time.Duration) uint64 {
+	if d <= 0 {
+		return 0
+	}
+	if t.division&smpteMask != 0 {
+		return scale(
+			uint64(d), t.ticksPer100Seconds(), 100*uint64(time.Second))
+	}
+	if t.division == 0 {
+		return 0
+	}
+	index := sort.Search(len(t.changes), func(i int) bool {
+		return t.changes[i].time > d
+	})
+	change := t.changes[index-1]
+	return change.tick + scale(uint64(d-change.time),
+		uint64(t.division), uint64(change.tempo)*uint64(time.Microsecond))
+}
+
+/*
+ticksPer100Seconds returns the number of ticks in 100 seconds for an SMPTE
+division, where the high byte holds the negated frames per second and the low
+byte the ticks per frame. A rate of 29 denotes 29.97 frame per second drop frame
+timing, which is why the count is not per second.
+*/
+func (t *TempoMap) ticksPer100Seconds() uint64 {
+	fps := uint64(-int8(t.division >> 8))
+	perFrame := uint64(t.division & 0xFF)
+	if fps == 29 {
+		return 2997 * perFrame
+	}
+	return 100 * fps * perFrame
+}
+
+/*
+scale returns value * numerator / denominator, avoiding overflow of the
+intermediate product for the values found in MIDI files.
+*/
+func scale(value, numerator, denominator uint64) uint64 {
+	if denominator == 0 {
+		return 0
+	}
+	whole, remainder := value/denominator, value%denominator
+	return whole*numerator + remainder*numerator/denominator
+}
@@ -0,0 +1,57 @@
+package midi_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTempoMapDefaultTempo(t *testing.T) {
+	tempoMap := newTrackMidi().TempoMap()
+	assert.Equal(t, uint32(DefaultTempo), tempoMap.Tempo(1000))
+	// 96 ticks per quarter note at 120 beats per minute.
+	assert.Equal(t, 500*time.Millisecond, tempoMap.Time(96))
+	assert.Equal(t, 250*time.Millisecond, tempoMap.Time(48))
+	assert.Equal(t, uint64(96), tempoMap.Tick(500*time.Millisecond))
+}
+
+func TestTempoMapChanges(t *testing.T) {
+	midi := newTrackMidi(
+		// 1,000,000 microseconds per quarter note from tick 96.
+		TrackEvent{96, []byte{0xFF, 0x51, 0x0F, 0x42, 0x40}},
+		// A malformed and a zero tempo are ignored.
+		TrackEvent{0, []byte{0xFF, 0x51, 0x0F}},
+		TrackEvent{96, []byte{0xFF, 0x51, 0x00, 0x00, 0x00}},
+		// 250,000 microseconds per quarter note from tick 288.
+		TrackEvent{96, []byte{0xFF, 0x51, 0x03, 0xD0, 0x90}},
+	)
+	tempoMap := midi.TempoMap()
+	assert.Equal(t, uint32(500000), tempoMap.Tempo(95))
+	assert.Equal(t, uint32(1000000), tempoMap.Tempo(96))
+	assert.Equal(t, uint32(250000), tempoMap.Tempo(300))
+
+	assert.Equal(t, 500*time.Millisecond, tempoMap.Time(96))
+	assert.Equal(t, 2500*time.Millisecond, tempoMap.Time(288))
+	assert.Equal(t, 2750*time.Millisecond, tempoMap.Time(384))
+
+	assert.Equal(t, uint64(192), tempoMap.Tick(1500*time.Millisecond))
+	assert.Equal(t, uint64(384), tempoMap.Tick(2750*time.Millisecond))
+	assert.Equal(t, uint64(0), tempoMap.Tick(-time.Second))
+}
+
+func TestTempoMapSMPTEDivision(t *testing.T) {
+	midi := newTrackMidi(TrackEvent{0, []byte{0xFF, 0x51, 0x0F, 0x42, 0x40}})
+	// 25 frames per second with 40 ticks per frame: one tick per
+	// millisecond, regardless of tempo.
+	midi.Division = 0xE728
+	tempoMap := midi.TempoMap()
+	assert.Equal(t, time.Second, tempoMap.Time(1000))
+	assert.Equal(t, uint64(1500), tempoMap.Tick(1500*time.Millisecond))
+
+	// 29.97 drop frame with 100 ticks per frame.
+	midi.Division = 0xE364
+	tempoMap = midi.TempoMap()
+	assert.Equal(t, time.Second, tempoMap.Time(2997))
+}
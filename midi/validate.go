@@ -0,0 +1,264 @@
+package midi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// DiagnosticKind identifies the type of problem a Diagnostic reports.
+type DiagnosticKind int
+
+const (
+	// InvalidHeader reports a missing or malformed header chunk. No further
+	// checks are made.
+	InvalidHeader DiagnosticKind = iota
+	// ChunkLengthMismatch reports a chunk whose length runs past the end of
+	// the file, or a track whose last event runs past the end of its chunk.
+	ChunkLengthMismatch
+	// TrackCountMismatch reports a header whose track count differs from
+	// the number of track chunks in the file.
+	TrackCountMismatch
+	// MalformedEvent reports an event that cannot be parsed. The rest of
+	// its track is not checked.
+	MalformedEvent
+	// MissingEndOfTrack reports a track that does not end with an End of
+	// Track event.
+	MissingEndOfTrack
+	// EventAfterEndOfTrack reports an event following End of Track.
+	EventAfterEndOfTrack
+	// OrphanNoteOn reports a NoteOn that is never released.
+	OrphanNoteOn
+	// OrphanNoteOff reports a NoteOff for a note that is not sounding.
+	OrphanNoteOff
+	// OverlappingNotes reports a NoteOn for a key that is already sounding
+	// on the same channel.
+	OverlappingNotes
+)
+
+var diagnosticKindNames = map[DiagnosticKind]string{
+	InvalidHeader:        "invalid header",
+	ChunkLengthMismatch:  "chunk length mismatch",
+	TrackCountMismatch:   "track count mismatch",
+	MalformedEvent:       "malformed event",
+	MissingEndOfTrack:    "missing end of track",
+	EventAfterEndOfTrack: "event after end of track",
+	OrphanNoteOn:         "orphan note on",
+	OrphanNoteOff:        "orphan note off",
+	OverlappingNotes:     "overlapping notes",
+}
+
+func (k DiagnosticKind) String() string {
+	if name, ok := diagnosticKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("DiagnosticKind(%d)", int(k))
+}
+
+/*
+A Diagnostic describes a single problem found by Validate. Offset is the byte
+offset in the file of the chunk or event at fault, and Track the index of the
+track chunk it belongs to, or -1 for problems with the file as a whole.
+*/
+type Diagnostic struct {
+	Kind    DiagnosticKind
+	Offset  int64
+	Track   int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	if d.Track < 0 {
+		return fmt.Sprintf("offset %v: %v: %s", d.Offset, d.Kind, d.Message)
+	}
+	return fmt.Sprintf(
+		"offset %v: track %v: %v: %s", d.Offset, d.Track, d.Kind, d.Message)
+}
+
+/*
+Validate checks the MIDI file in data and reports every problem it finds,
+ordered by offset, rather than stopping at the first. A file that parses
+successfully can still produce diagnostics, such as notes that are never
+released. A nil slice is returned for a file with no problems.
+*/
+func Validate(data []byte) []Diagnostic {
+	var diagnostics []Diagnostic
+	report := func(kind DiagnosticKind, offset int64, track int,
+		format string, args ...interface{}) {
+		diagnostics = append(diagnostics, Diagnostic{
+			kind, offset, track, fmt.Sprintf(format, args...)})
+	}
+
+	reader := bytes.NewReader(data)
+	var header Chunk
+	if err := binary.Read(reader, binary.BigEndian, &header); err != nil {
+		report(InvalidHeader, 0, -1, "file too short for a header: %v", err)
+		return diagnostics
+	}
+	if header.Type != headerChunk {
+		report(InvalidHeader, 0, -1,
+			"expected MThd but found %q", header.Type[:])
+		return diagnostics
+	}
+	if header.Length < headerDataSize ||
+		int64(header.Length) > int64(reader.Len()) {
+		report(InvalidHeader, 4, -1, HeaderSizeError, header.Length)
+		return diagnostics
+	}
+	var fields [3]uint16
+	binary.Read(reader, binary.BigEndian, &fields)
+	reader.Seek(int64(header.Length)-headerDataSize, io.SeekCurrent)
+
+	tracks := 0
+	for reader.Len() > 0 {
+		offset := reader.Size() - int64(reader.Len())
+		var chunk Chunk
+		if err := binary.Read(reader, binary.BigEndian, &chunk); err != nil {
+			report(ChunkLengthMismatch, offset, -1,
+				"%v trailing bytes do not form a chunk", len(data)-int(offset))
+			break
+		}
+		length := int64(chunk.Length)
+		if length > int64(reader.Len()) {
+			report(ChunkLengthMismatch, offset, -1,
+				ChunkSizeError, chunk.Type[:], chunk.Length, reader.Len())
+			length = int64(reader.Len())
+		}
+		start := reader.Size() - int64(reader.Len())
+		if chunk.Type == trackChunk {
+			diagnostics = append(diagnostics, validateTrack(
+				data[start:start+length], start, tracks)...)
+			tracks++
+		}
+		reader.Seek(length, io.SeekCurrent)
+	}
+	if tracks != int(fields[1]) {
+		report(TrackCountMismatch, 10, -1,
+			"header declares %v tracks but the file contains %v",
+			fields[1], tracks)
+	}
+
+	sort.SliceStable(diagnostics, func(a, b int) bool {
+		return diagnostics[a].Offset < diagnostics[b].Offset
+	})
+	return diagnostics
+}
+
+/*
+validateTrack checks the events in the data section of a track chunk. base is
+the offset of data within the file and track the index of the track chunk.
+*/
+func validateTrack(data []byte, base int64, track int) []Diagnostic {
+	var diagnostics []Diagnostic
+	report := func(kind DiagnosticKind, offset int64,
+		format string, args ...interface{}) {
+		diagnostics = append(diagnostics, Diagnostic{
+			kind, base + offset, track, fmt.Sprintf(format, args...)})
+	}
+
+	reader := bytes.NewReader(data)
+	sounding := make(map[noteKey][]int64)
+	var status byte
+	var ended bool
+	for reader.Len() > 0 {
+		offset := reader.Size() - int64(reader.Len())
+		_, _, err := ReadVariableLengthQuantity(reader)
+		if err == ErrQuantityTooLong {
+			report(MalformedEvent, offset, "delta time %v", err)
+			return diagnostics
+		}
+		var current byte
+		if err == nil {
+			current, err = reader.ReadByte()
+		}
+		if err != nil {
+			report(ChunkLengthMismatch, offset,
+				"event runs past the end of the chunk")
+			return diagnostics
+		}
+		if current&msbMask == msbMask {
+			status = current
+		} else if status == 0 {
+			report(MalformedEvent, offset, RunningStatusError, current)
+			return diagnostics
+		} else {
+			reader.UnreadByte()
+		}
+
+		event := TrackEvent{Data: []byte{status}}
+		switch {
+		case status == MetaEvent:
+			metaType, err := reader.ReadByte()
+			if err == nil {
+				_, err = readEventPayload(reader)
+			}
+			if err != nil {
+				report(ChunkLengthMismatch, offset,
+					"meta event runs past the end of the chunk")
+				return diagnostics
+			}
+			event.Data = append(event.Data, metaType)
+			status = 0
+		case status == SysExEvent || status == SysExEscape:
+			if _, err := readEventPayload(reader); err != nil {
+				report(ChunkLengthMismatch, offset,
+					"system exclusive event runs past the end of the chunk")
+				return diagnostics
+			}
+			status = 0
+		case status > SysExEvent:
+			report(MalformedEvent, offset, InvalidStatusError, status)
+			return diagnostics
+		default:
+			size := channelEventSize(status)
+			if size > reader.Len() {
+				report(ChunkLengthMismatch, offset, EventSizeError,
+					size, reader.Len())
+				return diagnostics
+			}
+			payload := make([]byte, size)
+			reader.Read(payload)
+			event.Data = append(event.Data, payload...)
+		}
+
+		if ended {
+			report(EventAfterEndOfTrack, offset,
+				"event follows the End of Track event")
+		}
+		switch {
+		case event.IsMeta(MetaEndOfTrack):
+			ended = true
+		case event.IsNoteOn():
+			key := noteKey{event.Channel(), event.Data[1]}
+			if len(sounding[key]) > 0 {
+				report(OverlappingNotes, offset,
+					"key %v on channel %v is already sounding",
+					key.key, key.channel)
+			}
+			sounding[key] = append(sounding[key], offset)
+		case event.IsNoteOff():
+			key := noteKey{event.Channel(), event.Data[1]}
+			if len(sounding[key]) == 0 {
+				report(OrphanNoteOff, offset,
+					"key %v on channel %v is not sounding",
+					key.key, key.channel)
+			} else {
+				sounding[key] = sounding[key][1:]
+			}
+		}
+	}
+
+	if !ended {
+		report(MissingEndOfTrack, int64(len(data)),
+			"track does not end with an End of Track event")
+	}
+	for key, offsets := range sounding {
+		for _, offset := range offsets {
+			report(OrphanNoteOn, offset, "key %v on channel %v is never released",
+				key.key, key.channel)
+		}
+	}
+	return diagnostics
+}
@@ -0,0 +1,102 @@
+package midi_test
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+// kinds returns the kind of every diagnostic.
+func kinds(diagnostics []Diagnostic) []DiagnosticKind {
+	var result []DiagnosticKind
+	for _, diagnostic := range diagnostics {
+		result = append(result, diagnostic.Kind)
+	}
+	return result
+}
+
+func TestValidateCleanFile(t *testing.T) {
+	var buffer bytes.Buffer
+	writeHeader(&buffer, 0, 1, 96)
+	writeTrack(&buffer, noteTrack)
+	assert.Nil(t, Validate(buffer.Bytes()))
+}
+
+func TestValidateHeader(t *testing.T) {
+	diagnostics := Validate([]byte("MTh"))
+	assert.Equal(t, []DiagnosticKind{InvalidHeader}, kinds(diagnostics))
+
+	diagnostics = Validate([]byte{'R', 'I', 'F', 'F', 0, 0, 0, 6, 0, 0, 0, 1, 0, 96})
+	assert.Equal(t, []DiagnosticKind{InvalidHeader}, kinds(diagnostics))
+	assert.Equal(t,
+		"offset 0: invalid header: expected MThd but found \"RIFF\"",
+		diagnostics[0].String())
+}
+
+func TestValidateNotes(t *testing.T) {
+	var buffer bytes.Buffer
+	writeHeader(&buffer, 0, 1, 96)
+	writeTrack(&buffer, []byte{
+		0x00, 0x90, 0x3C, 0x40, // offset 22
+		0x10, 0x90, 0x3C, 0x40, // offset 26: overlaps
+		0x10, 0x80, 0x3C, 0x00, // offset 30
+		0x10, 0x80, 0x3E, 0x00, // offset 34: nothing sounding
+		0x10, 0x90, 0x40, 0x40, // offset 38: never released
+		0x00, 0xFF, 0x2F, 0x00, // offset 42
+	})
+	diagnostics := Validate(buffer.Bytes())
+	assert.Equal(t, []Diagnostic{
+		{OverlappingNotes, 26, 0, "key 60 on channel 0 is already sounding"},
+		{OrphanNoteOn, 26, 0, "key 60 on channel 0 is never released"},
+		{OrphanNoteOff, 34, 0, "key 62 on channel 0 is not sounding"},
+		{OrphanNoteOn, 38, 0, "key 64 on channel 0 is never released"},
+	}, diagnostics)
+}
+
+func TestValidateStructure(t *testing.T) {
+	var buffer bytes.Buffer
+	// Declares 3 tracks but only contains 2.
+	writeHeader(&buffer, 1, 3, 96)
+	// No end of track, and a note off without running status at the end.
+	writeTrack(&buffer, []byte{0x00, 0xC0, 0x01})
+	// An event after end of track, then a chunk length that is too long.
+	buffer.WriteString("MTrk")
+	buffer.Write([]byte{0, 0, 0, 20})
+	buffer.Write([]byte{0x00, 0xFF, 0x2F, 0x00, 0x00, 0xC0, 0x02})
+	diagnostics := Validate(buffer.Bytes())
+	assert.Equal(t, []DiagnosticKind{
+		TrackCountMismatch, MissingEndOfTrack, ChunkLengthMismatch,
+		EventAfterEndOfTrack,
+	}, kinds(diagnostics))
+	assert.Equal(t, int64(10), diagnostics[0].Offset)
+	assert.Equal(t, -1, diagnostics[0].Track)
+	assert.Equal(t, int64(25), diagnostics[1].Offset)
+	assert.Equal(t, int64(25), diagnostics[2].Offset)
+	assert.Equal(t, int64(37), diagnostics[3].Offset)
+	assert.Equal(t, 1, diagnostics[3].Track)
+}
+
+func TestValidateMalformedEvents(t *testing.T) {
+	var buffer bytes.Buffer
+	writeHeader(&buffer, 0, 3, 96)
+	writeTrack(&buffer, []byte{0x00, 0x3C, 0x40})
+	writeTrack(&buffer, []byte{0x00, 0xF2, 0x00, 0x00})
+	writeTrack(&buffer, []byte{0x00, 0x90, 0x3C})
+	diagnostics := Validate(buffer.Bytes())
+	assert.Equal(t, []DiagnosticKind{
+		MalformedEvent, MalformedEvent, ChunkLengthMismatch,
+	}, kinds(diagnostics))
+	assert.Equal(t, "data byte 0x3c found without a preceding status byte",
+		diagnostics[0].Message)
+	assert.Equal(t, "invalid status byte 0xf2", diagnostics[1].Message)
+
+	// The parser rejects system common messages too.
+	buffer.Reset()
+	writeHeader(&buffer, 0, 1, 96)
+	writeTrack(&buffer, []byte{0x00, 0xF2, 0x00, 0x00})
+	_, err := ParseMidi(buffer.Bytes(), nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, "invalid status byte 0xf2", err.Error())
+}
@@ -0,0 +1,152 @@
+package midi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	DataByteError   = "track %v, event %v: %s of %v is out of range 0-127"
+	DataCountError  = "track %v, event %v: expected %v data bytes after status %#x but found %v"
+	DeltaTimeError  = "track %v, event %v: delta time of %v is out of range 0-%v"
+	MetaEventError  = "track %v, event %v: meta event is missing its type"
+	MetaTypeError   = "track %v, event %v: meta event type %#x is out of range 0-127"
+	StatusByteError = "track %v, event %v: invalid status byte %#x"
+)
+
+// ErrMissingHeader is returned when marshaling a Midi without a HeaderChunk.
+var ErrMissingHeader = errors.New("midi has no header chunk")
+
+/*
+dataByteNames holds descriptive names for the data bytes of each channel voice
+message, used to explain which value was out of range.
+*/
+var dataByteNames = map[byte][]string{
+	NoteOffEvent:          {"key", "velocity"},
+	NoteOnEvent:           {"key", "velocity"},
+	PolyphonicKeyPressure: {"key", "pressure"},
+	ControlChange:         {"controller", "value"},
+	ProgramChange:         {"program"},
+	ChannelPressure:       {"pressure"},
+	PitchWheelChange:      {"pitch LSB", "pitch MSB"},
+}
+
+/*
+WriteVariableLengthQuantity writes value to writer in the variable length
+quantity format read by ReadVariableLengthQuantity. ErrQuantityTooLong is
+returned, and nothing written, if value is over MaxVariableLengthQuantity.
+*/
+func WriteVariableLengthQuantity(writer io.ByteWriter, value uint64) error {
+	if value > MaxVariableLengthQuantity {
+		return ErrQuantityTooLong
+	}
+	size := variableLengthQuantitySize(value)
+	for i := size - 1; i >= 0; i-- {
+		current := byte(value>>(7*uint(i))) & sevenBitMask
+		if i > 0 {
+			current |= msbMask
+		}
+		if err := writer.WriteByte(current); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+MarshalBinary encodes the Midi as a standard MIDI file. This method satisfies
+the encoding.BinaryMarshaler interface. Every event is validated before anything
+is encoded, and a descriptive error is returned for events that would produce a
+corrupt file, such as a velocity above 127 or a missing status byte. The track
+count and chunk lengths are computed from TrackChunks rather than taken from the
+existing header and chunk values. Running status is not used.
+*/
+func (m *Midi) MarshalBinary() ([]byte, error) {
+	if m.HeaderChunk == nil {
+		return nil, ErrMissingHeader
+	}
+	for i := range m.TrackChunks {
+		if err := m.TrackChunks[i].validate(i); err != nil {
+			return nil, err
+		}
+	}
+
+	buffer := new(bytes.Buffer)
+	binary.Write(buffer, binary.BigEndian, Chunk{headerChunk, headerDataSize})
+	binary.Write(buffer, binary.BigEndian, m.Format)
+	binary.Write(buffer, binary.BigEndian, uint16(len(m.TrackChunks)))
+	binary.Write(buffer, binary.BigEndian, m.Division)
+	for i := range m.TrackChunks {
+		track := &m.TrackChunks[i]
+		binary.Write(
+			buffer, binary.BigEndian, Chunk{trackChunk, track.dataLength()})
+		for j := range track.TrackEvents {
+			writeTrackEvent(buffer, &track.TrackEvents[j])
+		}
+	}
+	return buffer.Bytes(), nil
+}
+
+/*
+writeTrackEvent encodes a single event, restoring the length prefixes of meta
+and system exclusive events that TrackEvent does not store.
+*/
+func writeTrackEvent(buffer *bytes.Buffer, event *TrackEvent) {
+	WriteVariableLengthQuantity(buffer, uint64(event.DeltaTime))
+	switch status := event.Status(); status {
+	case MetaEvent:
+		buffer.Write(event.Data[:2])
+		WriteVariableLengthQuantity(buffer, uint64(len(event.Data)-2))
+		buffer.Write(event.Data[2:])
+	case SysExEvent, SysExEscape:
+		buffer.WriteByte(status)
+		WriteVariableLengthQuantity(buffer, uint64(len(event.Data)-1))
+		buffer.Write(event.Data[1:])
+	default:
+		buffer.Write(event.Data)
+	}
+}
+
+/*
+validate checks that every event in the track can be encoded. The index of the
+track is only used to describe the problem in the returned error.
+*/
+func (t *TrackChunk) validate(track int) error {
+	for i := range t.TrackEvents {
+		event := &t.TrackEvents[i]
+		if event.DeltaTime < 0 ||
+			event.DeltaTime > MaxVariableLengthQuantity {
+			return fmt.Errorf(DeltaTimeError,
+				track, i, event.DeltaTime, MaxVariableLengthQuantity)
+		}
+		status := event.Status()
+		switch {
+		case status == MetaEvent:
+			if len(event.Data) < 2 {
+				return fmt.Errorf(MetaEventError, track, i)
+			}
+			if event.Data[1]&msbMask != 0 {
+				return fmt.Errorf(MetaTypeError, track, i, event.Data[1])
+			}
+		case status == SysExEvent || status == SysExEscape:
+		case event.IsChannelEvent():
+			names := dataByteNames[status&highOrderMask]
+			if len(event.Data)-1 != len(names) {
+				return fmt.Errorf(DataCountError,
+					track, i, len(names), status, len(event.Data)-1)
+			}
+			for j, value := range event.Data[1:] {
+				if value&msbMask != 0 {
+					return fmt.Errorf(
+						DataByteError, track, i, names[j], value)
+				}
+			}
+		default:
+			return fmt.Errorf(StatusByteError, track, i, status)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package midi_test
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/midi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteVariableLengthQuantity(t *testing.T) {
+	for _, value := range []uint64{0, 127, 128, 200, 2097151, 134217728} {
+		var buffer bytes.Buffer
+		assert.Nil(t, WriteVariableLengthQuantity(&buffer, value))
+		read, _, err := ReadVariableLengthQuantity(&buffer)
+		assert.Nil(t, err)
+		assert.Equal(t, value, read)
+	}
+	var buffer bytes.Buffer
+	WriteVariableLengthQuantity(&buffer, 200)
+	assert.Equal(t, []byte{0x81, 0x48}, buffer.Bytes())
+
+	buffer.Reset()
+	err := WriteVariableLengthQuantity(
+		&buffer, MaxVariableLengthQuantity+1)
+	assert.Equal(t, ErrQuantityTooLong, err)
+	assert.Equal(t, 0, buffer.Len())
+}
+
+func TestMarshalBinaryRoundTrip(t *testing.T) {
+	var buffer bytes.Buffer
+	writeHeader(&buffer, 1, 1, 480)
+	writeTrack(&buffer, []byte{
+		0x00, 0xFF, 0x03, 0x04, 'L', 'e', 'a', 'd',
+		0x00, 0xF0, 0x03, 0x7E, 0x09, 0xF7,
+		0x00, 0xC1, 0x05,
+		0x00, 0x91, 0x3C, 0x40,
+		0x83, 0x60, 0x3C, 0x00,
+		0x00, 0xFF, 0x2F, 0x00,
+	})
+	original := buffer.Bytes()
+	midi, err := ParseMidi(original, nil)
+	assert.Nil(t, err)
+
+	data, err := midi.MarshalBinary()
+	assert.Nil(t, err)
+	// Running status is expanded, adding one byte to the track.
+	assert.Equal(t, len(original)+1, len(data))
+
+	parsed, err := ParseMidi(data, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, midi.Division, parsed.Division)
+	assert.Equal(t, midi.TrackChunks[0].TrackEvents,
+		parsed.TrackChunks[0].TrackEvents)
+	assert.Equal(t, uint32(len(data)-22), parsed.TrackChunks[0].Length)
+}
+
+func TestMarshalBinaryValidation(t *testing.T) {
+	tests := []struct {
+		data  []byte
+		error string
+	}{
+		{[]byte{0x90, 60, 200}, "track 0, event 1: velocity of 200 is out of range 0-127"},
+		{[]byte{0xB3, 128, 1}, "controller of 128 is out of range"},
+		{[]byte{0xC0, 1, 2}, "expected 1 data bytes after status 0xc0 but found 2"},
+		{[]byte{0x3C, 60, 100}, "invalid status byte 0x3c"},
+		{[]byte{0xF1, 1}, "invalid status byte 0xf1"},
+		{[]byte{0xFF}, "meta event is missing its type"},
+		{[]byte{0xFF, 0x80}, "meta event type 0x80 is out of range"},
+	}
+	for _, test := range tests {
+		midi := newTrackMidi(
+			TrackEvent{0, []byte{0x90, 60, 100}}, TrackEvent{0, test.data})
+		data, err := midi.MarshalBinary()
+		assert.Nil(t, data)
+		assert.NotNil(t, err)
+		re := regexp.MustCompile(regexp.QuoteMeta(test.error))
+		assert.NotEqual(t, "", re.FindString(err.Error()), err.Error())
+	}
+
+	midi := newTrackMidi(TrackEvent{-1, []byte{0x90, 60, 100}})
+	_, err := midi.MarshalBinary()
+	re := regexp.MustCompile("delta time of -1 is out of range")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+
+	_, err = new(Midi).MarshalBinary()
+	assert.Equal(t, ErrMissingHeader, err)
+}
@@ -0,0 +1,20 @@
+package audio
+
+/*
+ProgressFunc is called by long running operations as they work, with the number
+of sample frames done so far and the total they expect to do, or -1 for the
+total when it is not known in advance. Operations take one in their options,
+such as wav.ContextOptions, and call it from the goroutine running the
+operation after each block of a few thousand frames.
+*/
+type ProgressFunc func(framesDone, framesTotal int64)
+
+/*
+Report calls the ProgressFunc, if it is not nil, so that operations can report
+to an optional ProgressFunc without checking for one.
+*/
+func (p ProgressFunc) Report(framesDone, framesTotal int64) {
+	if p != nil {
+		p(framesDone, framesTotal)
+	}
+}
@@ -0,0 +1,22 @@
+package audio_test
+
+import (
+	"testing"
+
+	. "github.com/husafan/audio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportProgress(t *testing.T) {
+	// A nil ProgressFunc is ignored.
+	var progress ProgressFunc
+	progress.Report(1, 2)
+
+	var reports [][2]int64
+	progress = func(done, total int64) {
+		reports = append(reports, [2]int64{done, total})
+	}
+	progress.Report(1, 4)
+	progress.Report(4, -1)
+	assert.Equal(t, [][2]int64{{1, 4}, {4, -1}}, reports)
+}
@@ -0,0 +1,95 @@
+package raw
+
+const (
+	// muLawBias is added to linear samples before mu-law companding, so
+	// that every segment starts at a power of two.
+	muLawBias = 0x84
+	// muLawClip is the largest magnitude mu-law encodes, in 14 bits.
+	muLawClip = 8159
+)
+
+/*
+segment returns the index of the first of the eight segment ends that value
+does not exceed, or 8 if it exceeds them all. The segments of G.711 double in
+width, so the ends are 2^(first+i) - 1.
+*/
+func segment(value int, first uint) int {
+	for i := 0; i < 8; i++ {
+		if value <= 1<<(first+uint(i))-1 {
+			return i
+		}
+	}
+	return 8
+}
+
+/*
+linearToMuLaw compands a 16 bit linear sample to mu-law, as in the reference
+implementation of G.711.
+*/
+func linearToMuLaw(sample int16) byte {
+	value := int(sample) >> 2
+	mask := byte(0xFF)
+	if value < 0 {
+		value, mask = -value, 0x7F
+	}
+	if value > muLawClip {
+		value = muLawClip
+	}
+	value += muLawBias >> 2
+	seg := segment(value, 6)
+	if seg >= 8 {
+		return 0x7F ^ mask
+	}
+	return (byte(seg<<4) | byte(value>>(seg+1)&0x0F)) ^ mask
+}
+
+// muLawToLinear expands a mu-law sample to 16 bit linear.
+func muLawToLinear(sample byte) int16 {
+	sample = ^sample
+	value := (int(sample&0x0F)<<3 + muLawBias) << (sample & 0x70 >> 4)
+	if sample&0x80 != 0 {
+		return int16(muLawBias - value)
+	}
+	return int16(value - muLawBias)
+}
+
+/*
+linearToALaw compands a 16 bit linear sample to A-law, as in the reference
+implementation of G.711.
+*/
+func linearToALaw(sample int16) byte {
+	value := int(sample) >> 3
+	mask := byte(0xD5)
+	if value < 0 {
+		value, mask = -value-1, 0x55
+	}
+	seg := segment(value, 5)
+	if seg >= 8 {
+		return 0x7F ^ mask
+	}
+	companded := byte(seg << 4)
+	if seg < 2 {
+		companded |= byte(value >> 1 & 0x0F)
+	} else {
+		companded |= byte(value >> seg & 0x0F)
+	}
+	return companded ^ mask
+}
+
+// aLawToLinear expands an A-law sample to 16 bit linear.
+func aLawToLinear(sample byte) int16 {
+	sample ^= 0x55
+	value := int(sample&0x0F) << 4
+	switch seg := sample & 0x70 >> 4; seg {
+	case 0:
+		value += 8
+	case 1:
+		value += 0x108
+	default:
+		value = (value + 0x108) << (seg - 1)
+	}
+	if sample&0x80 != 0 {
+		return int16(value)
+	}
+	return int16(-value)
+}
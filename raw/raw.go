@@ -0,0 +1,470 @@
+/*
+The raw package reads and writes headerless PCM, the bare interleaved samples
+that embedded devices, telephony systems and tools such as sox and ffmpeg
+exchange without a container. Nothing in the stream says how it is encoded, so
+a Format must be given for it, either directly or parsed from a description
+such as "s16le:8000:1" with ParseFormat.
+*/
+package raw
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/husafan/audio"
+)
+
+const (
+	BitDepthError = "cannot encode %v bit %v samples"
+	ChannelError  = "expected %v channels; found %v"
+	EncodingError = "unknown encoding %q; use a name such as s16le, f32be or mulaw"
+	SpecError     = "raw format %q is not encoding:rate:channels"
+
+	// copyFrames is the number of frames ReadAll decodes at a time.
+	copyFrames = 4096
+)
+
+// Encoding is the way each sample of raw audio is stored.
+type Encoding int
+
+const (
+	// Signed samples are two's complement integers, the zero value.
+	Signed Encoding = iota
+	// Unsigned samples are offset binary integers, silent at half scale.
+	Unsigned
+	// Float samples are IEEE floats, between -1 and 1 at full scale.
+	Float
+	// MuLaw samples are 8 bit G.711 mu-law, as on North American phones.
+	MuLaw
+	// ALaw samples are 8 bit G.711 A-law, as on European phones.
+	ALaw
+)
+
+// String returns the name of the Encoding.
+func (e Encoding) String() string {
+	switch e {
+	case Signed:
+		return "signed"
+	case Unsigned:
+		return "unsigned"
+	case Float:
+		return "float"
+	case MuLaw:
+		return "mu-law"
+	case ALaw:
+		return "A-law"
+	}
+	return fmt.Sprintf("Encoding(%d)", int(e))
+}
+
+/*
+Format describes raw audio: SampleRate frames per second of Channels
+interleaved samples, each stored with Encoding in BitDepth bits. Integers may
+be 8 to 32 bits wide in whole bytes and floats 32 or 64, while G.711 samples
+are always 8 bits, so a BitDepth of 0 means 8 for them. BigEndian orders the
+bytes of each sample from the most significant, rather than the least as WAV
+files do.
+*/
+type Format struct {
+	SampleRate uint32
+	Channels   int
+	Encoding   Encoding
+	BitDepth   int
+	BigEndian  bool
+}
+
+/*
+ParseFormat parses a description of raw audio as encoding:rate:channels, in
+the style of ffmpeg's sample formats. The encoding is s, u or f for signed,
+unsigned or float samples, their bit depth, and le or be for their byte order,
+which 8 bit samples leave out, as in s16le, u8 or f32be, or else mulaw or
+alaw. So "mulaw:8000:1" describes a telephone call and "f32le:48000:2" floating
+point stereo. A non-nil error is returned if the description is malformed or
+describes samples that cannot be encoded.
+*/
+func ParseFormat(spec string) (Format, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) != 3 {
+		return Format{}, fmt.Errorf(SpecError, spec)
+	}
+	format, err := parseEncoding(parts[0])
+	if err != nil {
+		return Format{}, err
+	}
+	rate, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return Format{}, fmt.Errorf(SpecError, spec)
+	}
+	channels, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return Format{}, fmt.Errorf(SpecError, spec)
+	}
+	format.SampleRate, format.Channels = uint32(rate), channels
+	return format, format.check()
+}
+
+// parseEncoding returns a Format with the encoding given by name.
+func parseEncoding(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "mulaw", "ulaw":
+		return Format{Encoding: MuLaw, BitDepth: 8}, nil
+	case "alaw":
+		return Format{Encoding: ALaw, BitDepth: 8}, nil
+	}
+	var format Format
+	rest := strings.ToLower(name)
+	if strings.HasSuffix(rest, "le") {
+		rest = strings.TrimSuffix(rest, "le")
+	} else if strings.HasSuffix(rest, "be") {
+		rest, format.BigEndian = strings.TrimSuffix(rest, "be"), true
+	}
+	if len(rest) < 2 {
+		return Format{}, fmt.Errorf(EncodingError, name)
+	}
+	switch rest[0] {
+	case 's':
+		format.Encoding = Signed
+	case 'u':
+		format.Encoding = Unsigned
+	case 'f':
+		format.Encoding = Float
+	default:
+		return Format{}, fmt.Errorf(EncodingError, name)
+	}
+	bits, err := strconv.Atoi(rest[1:])
+	if err != nil || bits <= 0 {
+		return Format{}, fmt.Errorf(EncodingError, name)
+	}
+	// Only single byte samples may leave out their byte order.
+	if bits > 8 && rest == strings.ToLower(name) {
+		return Format{}, fmt.Errorf(EncodingError, name)
+	}
+	format.BitDepth = bits
+	return format, nil
+}
+
+/*
+String returns the description of f that ParseFormat accepts, such as
+"s16le:44100:2".
+*/
+func (f Format) String() string {
+	var name string
+	switch f.Encoding {
+	case MuLaw:
+		name = "mulaw"
+	case ALaw:
+		name = "alaw"
+	case Signed, Unsigned, Float:
+		name = fmt.Sprintf("%c%d", "suf"[f.Encoding], f.BitDepth)
+		if f.BitDepth > 8 && f.BigEndian {
+			name += "be"
+		} else if f.BitDepth > 8 {
+			name += "le"
+		}
+	default:
+		name = f.Encoding.String()
+	}
+	return fmt.Sprintf("%s:%d:%d", name, f.SampleRate, f.Channels)
+}
+
+// Format returns the in-memory format of the samples described by f.
+func (f Format) Format() audio.Format {
+	return audio.Format{SampleRate: f.SampleRate, Channels: f.Channels}
+}
+
+// FrameSize returns the number of bytes in a frame of f.
+func (f Format) FrameSize() int {
+	return f.sampleSize() * f.Channels
+}
+
+// sampleSize returns the number of bytes in a sample of f.
+func (f Format) sampleSize() int {
+	if f.Encoding == MuLaw || f.Encoding == ALaw {
+		return 1
+	}
+	return f.BitDepth / 8
+}
+
+// check returns a non-nil error unless samples of f can be encoded.
+func (f Format) check() error {
+	if f.Channels <= 0 {
+		return fmt.Errorf(ChannelError, "at least 1", f.Channels)
+	}
+	bits := f.BitDepth
+	switch f.Encoding {
+	case Signed, Unsigned:
+		if bits >= 8 && bits <= 32 && bits%8 == 0 {
+			return nil
+		}
+	case Float:
+		if bits == 32 || bits == 64 {
+			return nil
+		}
+	case MuLaw, ALaw:
+		if bits == 0 || bits == 8 {
+			return nil
+		}
+	}
+	return fmt.Errorf(BitDepthError, bits, f.Encoding)
+}
+
+// order returns the byte order of the samples of f.
+func (f Format) order() binary.ByteOrder {
+	if f.BigEndian {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+/*
+decode returns the value between -1 and 1 of the sample of f held by data,
+which is sampleSize bytes long.
+*/
+func (f Format) decode(data []byte) float64 {
+	switch f.Encoding {
+	case MuLaw:
+		return float64(muLawToLinear(data[0])) / 32768
+	case ALaw:
+		return float64(aLawToLinear(data[0])) / 32768
+	case Float:
+		if len(data) == 4 {
+			return float64(math.Float32frombits(f.order().Uint32(data)))
+		}
+		return math.Float64frombits(f.order().Uint64(data))
+	}
+	var value int64
+	for i := range data {
+		b := data[i]
+		if !f.BigEndian {
+			b = data[len(data)-1-i]
+		}
+		value = value<<8 | int64(b)
+	}
+	width := uint(8 * len(data))
+	if f.Encoding == Unsigned {
+		value -= 1 << (width - 1)
+	} else {
+		// Sign extend from the width of the sample.
+		value = value << (64 - width) >> (64 - width)
+	}
+	return float64(value) / float64(int64(1)<<(width-1))
+}
+
+/*
+encode stores value in data as a sample of f, clipping it to the range -1 to
+1 unless f holds floats.
+*/
+func (f Format) encode(data []byte, value float64) {
+	switch f.Encoding {
+	case MuLaw:
+		data[0] = linearToMuLaw(linear16(value))
+		return
+	case ALaw:
+		data[0] = linearToALaw(linear16(value))
+		return
+	case Float:
+		if len(data) == 4 {
+			f.order().PutUint32(data, math.Float32bits(float32(value)))
+		} else {
+			f.order().PutUint64(data, math.Float64bits(value))
+		}
+		return
+	}
+	width := uint(8 * len(data))
+	scale := float64(int64(1) << (width - 1))
+	scaled := math.Max(-scale, math.Min(scale-1, math.Round(value*scale)))
+	integer := int64(scaled)
+	if f.Encoding == Unsigned {
+		integer += 1 << (width - 1)
+	}
+	for i := range data {
+		shift := uint(8 * i)
+		if f.BigEndian {
+			shift = width - 8 - shift
+		}
+		data[i] = byte(integer >> shift)
+	}
+}
+
+// linear16 returns value as a clipped 16 bit linear sample.
+func linear16(value float64) int16 {
+	return int16(math.Max(-32768, math.Min(32767, math.Round(value*32768))))
+}
+
+/*
+Reader decodes frames of raw audio in its Format from an underlying reader,
+with the same methods for reading them as wav.WavReader.
+*/
+type Reader struct {
+	Format  Format
+	reader  io.Reader
+	scratch []byte
+}
+
+/*
+NewReader returns a Reader of r, which holds raw audio in format from its
+current position to its end. A non-nil error is returned if format describes
+samples that cannot be decoded.
+*/
+func NewReader(r io.Reader, format Format) (*Reader, error) {
+	if err := format.check(); err != nil {
+		return nil, err
+	}
+	return &Reader{Format: format, reader: r}, nil
+}
+
+/*
+readRawFrames reads up to frames whole frames into the reader's scratch
+space, returning them and the number of frames read. io.EOF is returned at the
+end of the stream, or io.ErrUnexpectedEOF if it ends partway through a frame.
+*/
+func (r *Reader) readRawFrames(frames int) ([]byte, int, error) {
+	frameSize := r.Format.FrameSize()
+	if len(r.scratch) < frames*frameSize {
+		r.scratch = make([]byte, frames*frameSize)
+	}
+	n, err := io.ReadFull(r.reader, r.scratch[:frames*frameSize])
+	if err == io.ErrUnexpectedEOF && n%frameSize == 0 {
+		err = io.EOF
+	}
+	return r.scratch[:n], n / frameSize, err
+}
+
+/*
+ReadFrames decodes frames into frames, which are filled in order with one
+value between -1 and 1 per channel. Each entry of frames must hold at least
+one value per channel. The number of frames decoded is returned along with
+io.EOF once the stream is exhausted, or io.ErrUnexpectedEOF if it ends with
+part of a frame, which is dropped.
+*/
+func (r *Reader) ReadFrames(frames [][]float64) (int, error) {
+	channels := r.Format.Channels
+	for _, frame := range frames {
+		if len(frame) < channels {
+			return 0, fmt.Errorf(ChannelError, channels, len(frame))
+		}
+	}
+	data, count, err := r.readRawFrames(len(frames))
+	size := r.Format.sampleSize()
+	for i := 0; i < count; i++ {
+		for channel := 0; channel < channels; channel++ {
+			offset := (i*channels + channel) * size
+			frames[i][channel] = r.Format.decode(data[offset : offset+size])
+		}
+	}
+	return count, err
+}
+
+/*
+ReadBuffer decodes frames into buffer, filling it from the start, and returns
+their number as ReadFrames does. A non-nil error is returned if the buffer
+does not have the Format's channel count.
+*/
+func (r *Reader) ReadBuffer(buffer *audio.Buffer) (int, error) {
+	if buffer.Format.Channels != r.Format.Channels {
+		return 0, fmt.Errorf(
+			ChannelError, r.Format.Channels, buffer.Format.Channels)
+	}
+	data, count, err := r.readRawFrames(buffer.Frames())
+	size := r.Format.sampleSize()
+	for i := 0; i < count*r.Format.Channels; i++ {
+		buffer.Data[i] = r.Format.decode(data[i*size : (i+1)*size])
+	}
+	return count, err
+}
+
+/*
+ReadAll decodes every remaining frame into a new Buffer. A stream ending
+partway through a frame is not an error: the partial frame is dropped.
+*/
+func (r *Reader) ReadAll() (*audio.Buffer, error) {
+	format := r.Format.Format()
+	buffer := &audio.Buffer{Format: format}
+	block := audio.NewBuffer(format, copyFrames)
+	for {
+		count, err := r.ReadBuffer(block)
+		buffer.Data = append(buffer.Data, block.Data[:count*format.Channels]...)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return buffer, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+/*
+Writer encodes frames of audio in its Format to an underlying writer. It
+writes nothing but samples, so there is nothing to finish once the last frame
+is written.
+*/
+type Writer struct {
+	Format  Format
+	writer  io.Writer
+	scratch []byte
+}
+
+/*
+NewWriter returns a Writer of raw audio in format to w. A non-nil error is
+returned if format describes samples that cannot be encoded.
+*/
+func NewWriter(w io.Writer, format Format) (*Writer, error) {
+	if err := format.check(); err != nil {
+		return nil, err
+	}
+	return &Writer{Format: format, writer: w}, nil
+}
+
+/*
+WriteFrames encodes frames, each holding at least one value per channel, and
+writes them. Integer samples are clipped to the range -1 to 1.
+*/
+func (w *Writer) WriteFrames(frames [][]float64) error {
+	channels := w.Format.Channels
+	for _, frame := range frames {
+		if len(frame) < channels {
+			return fmt.Errorf(ChannelError, channels, len(frame))
+		}
+	}
+	size := w.Format.sampleSize()
+	data := w.buffer(len(frames) * channels * size)
+	for i, frame := range frames {
+		for channel, value := range frame[:channels] {
+			offset := (i*channels + channel) * size
+			w.Format.encode(data[offset:offset+size], value)
+		}
+	}
+	_, err := w.writer.Write(data)
+	return err
+}
+
+/*
+WriteBuffer encodes the frames of buffer and writes them, as WriteFrames does.
+A non-nil error is returned if the buffer does not have the Format's channel
+count.
+*/
+func (w *Writer) WriteBuffer(buffer *audio.Buffer) error {
+	if buffer.Format.Channels != w.Format.Channels {
+		return fmt.Errorf(
+			ChannelError, w.Format.Channels, buffer.Format.Channels)
+	}
+	size := w.Format.sampleSize()
+	values := buffer.Data[:buffer.Frames()*w.Format.Channels]
+	data := w.buffer(len(values) * size)
+	for i, value := range values {
+		w.Format.encode(data[i*size:(i+1)*size], value)
+	}
+	_, err := w.writer.Write(data)
+	return err
+}
+
+// buffer returns size bytes of the writer's scratch space.
+func (w *Writer) buffer(size int) []byte {
+	if len(w.scratch) < size {
+		w.scratch = make([]byte, size)
+	}
+	return w.scratch[:size]
+}
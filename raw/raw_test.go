@@ -0,0 +1,201 @@
+package raw_test
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"regexp"
+	"testing"
+
+	"github.com/husafan/audio"
+	. "github.com/husafan/audio/raw"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFormat(t *testing.T) {
+	for spec, expected := range map[string]Format{
+		"s16le:44100:2": {SampleRate: 44100, Channels: 2, BitDepth: 16},
+		"u8:8000:1": {SampleRate: 8000, Channels: 1, Encoding: Unsigned,
+			BitDepth: 8},
+		"f32be:48000:6": {SampleRate: 48000, Channels: 6, Encoding: Float,
+			BitDepth: 32, BigEndian: true},
+		"s24be:96000:2": {SampleRate: 96000, Channels: 2, BitDepth: 24,
+			BigEndian: true},
+		"mulaw:8000:1": {SampleRate: 8000, Channels: 1, Encoding: MuLaw,
+			BitDepth: 8},
+		"alaw:8000:1": {SampleRate: 8000, Channels: 1, Encoding: ALaw,
+			BitDepth: 8},
+	} {
+		format, err := ParseFormat(spec)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, format)
+		assert.Equal(t, spec, format.String())
+	}
+	format, err := ParseFormat("ULAW:8000:1")
+	assert.Nil(t, err)
+	assert.Equal(t, MuLaw, format.Encoding)
+	assert.Equal(t, 1, format.FrameSize())
+	assert.Equal(t, audio.Format{SampleRate: 8000, Channels: 1},
+		format.Format())
+
+	for spec, message := range map[string]string{
+		"s16le:44100":    "is not encoding:rate:channels",
+		"s16le:fast:2":   "is not encoding:rate:channels",
+		"s16le:44100:x":  "is not encoding:rate:channels",
+		"s16:44100:2":    `unknown encoding "s16"`,
+		"x16le:44100:2":  `unknown encoding "x16le"`,
+		"le:44100:2":     `unknown encoding "le"`,
+		"s12le:44100:2":  "cannot encode 12 bit signed samples",
+		"f16le:44100:2":  "cannot encode 16 bit float samples",
+		"s16le:44100:0":  "expected at least 1 channels; found 0",
+		"s16le:44100:-1": "found -1",
+	} {
+		_, err := ParseFormat(spec)
+		assert.NotEqual(t, "",
+			regexp.MustCompile(message).FindString(err.Error()), spec)
+	}
+}
+
+func TestReader(t *testing.T) {
+	for _, test := range []struct {
+		spec   string
+		data   []byte
+		values []float64
+	}{
+		{"s16le:8000:2", []byte{0x00, 0x40, 0x00, 0xC0},
+			[]float64{0.5, -0.5}},
+		{"s16be:8000:2", []byte{0x40, 0x00, 0xC0, 0x00},
+			[]float64{0.5, -0.5}},
+		{"u8:8000:2", []byte{0xC0, 0x00}, []float64{0.5, -1}},
+		{"u16be:8000:1", []byte{0x80, 0x00}, []float64{0}},
+		{"s24be:8000:1", []byte{0xE0, 0x00, 0x00}, []float64{-0.25}},
+		{"f32be:8000:1", []byte{0x3F, 0x00, 0x00, 0x00}, []float64{0.5}},
+		{"f64le:8000:1", []byte{0, 0, 0, 0, 0, 0, 0xE0, 0xBF},
+			[]float64{-0.5}},
+		{"mulaw:8000:2", []byte{0xFF, 0x00}, []float64{0, -32124.0 / 32768}},
+		{"alaw:8000:2", []byte{0xD5, 0xAA}, []float64{8.0 / 32768, 32256.0 / 32768}},
+	} {
+		format, err := ParseFormat(test.spec)
+		assert.Nil(t, err)
+		reader, err := NewReader(bytes.NewReader(test.data), format)
+		assert.Nil(t, err)
+		buffer, err := reader.ReadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, format.Format(), buffer.Format)
+		assert.Equal(t, test.values, buffer.Data, test.spec)
+	}
+}
+
+func TestReaderPartialFrame(t *testing.T) {
+	format := Format{SampleRate: 8000, Channels: 2, BitDepth: 8}
+	reader, err := NewReader(bytes.NewReader([]byte{0x40, 0xC0, 0x20}), format)
+	assert.Nil(t, err)
+	frames := [][]float64{make([]float64, 2), make([]float64, 2)}
+	n, err := reader.ReadFrames(frames)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Equal(t, []float64{0.5, -0.5}, frames[0])
+
+	reader, err = NewReader(bytes.NewReader([]byte{0x40, 0xC0}), format)
+	assert.Nil(t, err)
+	n, err = reader.ReadFrames(frames)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, io.EOF, err)
+	n, err = reader.ReadFrames(frames)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, io.EOF, err)
+
+	_, err = reader.ReadFrames([][]float64{{0}})
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"expected 2 channels; found 1").FindString(err.Error()))
+	_, err = reader.ReadBuffer(audio.NewBuffer(audio.Format{Channels: 1}, 1))
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"expected 2 channels; found 1").FindString(err.Error()))
+}
+
+func TestWriter(t *testing.T) {
+	values := []float64{0, 0.5, -0.5, 0.999, -1, 0.25, 2, -2}
+	for _, spec := range []string{"s8:8000:2", "u8:8000:2", "s16le:8000:2",
+		"s16be:8000:2", "u16le:8000:2", "s24le:8000:2", "s32be:8000:2",
+		"f32le:8000:2", "f64be:8000:2", "mulaw:8000:2", "alaw:8000:2"} {
+		format, err := ParseFormat(spec)
+		assert.Nil(t, err)
+		output := new(bytes.Buffer)
+		writer, err := NewWriter(output, format)
+		assert.Nil(t, err)
+		buffer := audio.NewBuffer(format.Format(), 2)
+		copy(buffer.Data, values[:4])
+		assert.Nil(t, writer.WriteBuffer(buffer))
+		assert.Nil(t, writer.WriteFrames([][]float64{values[4:6], values[6:]}))
+		assert.Equal(t, 4*format.FrameSize(), output.Len())
+
+		// Integers clip, and G.711 keeps about 12 bits of precision.
+		reader, err := NewReader(output, format)
+		assert.Nil(t, err)
+		decoded, err := reader.ReadAll()
+		assert.Nil(t, err)
+		expected := append([]float64(nil), values...)
+		delta := 1e-9
+		switch format.Encoding {
+		case Float:
+			delta = 1e-7
+		case MuLaw, ALaw:
+			expected[6], expected[7] = 1, -1
+			delta = 0.02
+		default:
+			expected[6], expected[7] = 1, -1
+			delta = 1.01 / float64(int64(1)<<(format.BitDepth-1))
+		}
+		assert.InDeltaSlice(t, expected, decoded.Data, delta, spec)
+	}
+}
+
+func TestG711(t *testing.T) {
+	// Every code survives decoding and encoding again, apart from
+	// mu-law's negative zero.
+	for code := 0; code < 256; code++ {
+		for _, spec := range []string{"mulaw:8000:1", "alaw:8000:1"} {
+			format, _ := ParseFormat(spec)
+			reader, _ := NewReader(bytes.NewReader([]byte{byte(code)}), format)
+			decoded, _ := reader.ReadAll()
+			output := new(bytes.Buffer)
+			writer, _ := NewWriter(output, format)
+			assert.Nil(t, writer.WriteBuffer(decoded))
+			if spec == "mulaw:8000:1" && code == 0x7F {
+				assert.Equal(t, []byte{0xFF}, output.Bytes())
+				continue
+			}
+			assert.Equal(t, []byte{byte(code)}, output.Bytes(), spec)
+		}
+	}
+	// Above its quietest segments, companding keeps the error in
+	// proportion to the level.
+	format := Format{SampleRate: 8000, Channels: 1, Encoding: ALaw}
+	for _, value := range []float64{0.01, 0.1, 0.9} {
+		output := new(bytes.Buffer)
+		writer, _ := NewWriter(output, format)
+		assert.Nil(t, writer.WriteFrames([][]float64{{value}}))
+		reader, _ := NewReader(output, format)
+		decoded, _ := reader.ReadAll()
+		assert.True(t, math.Abs(decoded.Data[0]-value) < value*0.04, value)
+	}
+}
+
+func TestNewWriterErrors(t *testing.T) {
+	_, err := NewWriter(io.Discard, Format{Channels: 1, BitDepth: 20})
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"cannot encode 20 bit signed samples").FindString(err.Error()))
+	_, err = NewReader(bytes.NewReader(nil),
+		Format{Channels: 1, Encoding: MuLaw, BitDepth: 16})
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"cannot encode 16 bit mu-law samples").FindString(err.Error()))
+	writer, err := NewWriter(io.Discard,
+		Format{Channels: 2, Encoding: Float, BitDepth: 32})
+	assert.Nil(t, err)
+	err = writer.WriteFrames([][]float64{{1}})
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"expected 2 channels; found 1").FindString(err.Error()))
+	err = writer.WriteBuffer(audio.NewBuffer(audio.Format{Channels: 1}, 1))
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"expected 2 channels; found 1").FindString(err.Error()))
+}
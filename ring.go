@@ -0,0 +1,173 @@
+package audio
+
+import "sync/atomic"
+
+/*
+cacheLine pads the positions of RingBuffer and Queue apart, so that the
+producer and consumer do not contend for the same cache line.
+*/
+type cacheLine [64]byte
+
+/*
+ringPositions holds the positions of a ring for a single producer and a single
+consumer. Each position counts every value that has passed it, so the ring
+holds write - read values. Only the producer stores write and only the
+consumer stores read, and each loads the other's position to see how far it
+may go, so neither needs a lock.
+*/
+type ringPositions struct {
+	_     cacheLine
+	write atomic.Uint64
+	_     cacheLine
+	read  atomic.Uint64
+	_     cacheLine
+}
+
+// ringSize returns capacity rounded up to a power of two, at least 1.
+func ringSize(capacity int) int {
+	size := 1
+	for size < capacity {
+		size <<= 1
+	}
+	return size
+}
+
+/*
+RingBuffer passes samples from one goroutine to another without locks or
+allocation, such as between an audio device callback and the goroutine that
+feeds or drains it. It is safe for one goroutine to Write while another
+Reads, but not for two goroutines to Write, or to Read, at once. Interleaved
+frames stay whole as long as both sides move multiples of the channel count.
+*/
+type RingBuffer struct {
+	positions ringPositions
+	data      []float64
+	mask      uint64
+}
+
+/*
+NewRingBuffer returns an empty RingBuffer holding at least capacity samples.
+The capacity is rounded up to a power of two.
+*/
+func NewRingBuffer(capacity int) *RingBuffer {
+	size := ringSize(capacity)
+	return &RingBuffer{data: make([]float64, size), mask: uint64(size - 1)}
+}
+
+// Cap returns the number of samples the RingBuffer holds when full.
+func (r *RingBuffer) Cap() int {
+	return len(r.data)
+}
+
+/*
+Len returns the number of samples waiting to be read. It is exact for the
+consumer, and a lower bound for the producer, as the consumer may read more
+at any time.
+*/
+func (r *RingBuffer) Len() int {
+	return int(r.positions.write.Load() - r.positions.read.Load())
+}
+
+/*
+Free returns the number of samples that can be written. It is exact for the
+producer, and a lower bound for the consumer.
+*/
+func (r *RingBuffer) Free() int {
+	return len(r.data) - r.Len()
+}
+
+/*
+Write appends as many samples of values as there is room for and returns
+that number, which is less than len(values) if the RingBuffer fills. Only one
+goroutine may write.
+*/
+func (r *RingBuffer) Write(values []float64) int {
+	write := r.positions.write.Load()
+	free := len(r.data) - int(write-r.positions.read.Load())
+	if len(values) > free {
+		values = values[:free]
+	}
+	start := int(write & r.mask)
+	copied := copy(r.data[start:], values)
+	copy(r.data, values[copied:])
+	r.positions.write.Store(write + uint64(len(values)))
+	return len(values)
+}
+
+/*
+Read removes as many samples as are waiting, up to len(values), into values
+and returns that number. Only one goroutine may read.
+*/
+func (r *RingBuffer) Read(values []float64) int {
+	read := r.positions.read.Load()
+	waiting := int(r.positions.write.Load() - read)
+	if len(values) > waiting {
+		values = values[:waiting]
+	}
+	start := int(read & r.mask)
+	copied := copy(values, r.data[start:])
+	copy(values[copied:], r.data)
+	r.positions.read.Store(read + uint64(len(values)))
+	return len(values)
+}
+
+/*
+Queue is a bounded first in, first out queue for a single producer and a
+single consumer, on the same terms as RingBuffer, for passing messages such as
+MIDI events or parameter changes to an audio thread without locks. Values
+stay in the Queue's storage until overwritten, so a consumer that must not
+keep references alive should use values without pointers.
+*/
+type Queue[T any] struct {
+	positions ringPositions
+	slots     []T
+	mask      uint64
+}
+
+/*
+NewQueue returns an empty Queue holding at least capacity values. The
+capacity is rounded up to a power of two.
+*/
+func NewQueue[T any](capacity int) *Queue[T] {
+	size := ringSize(capacity)
+	return &Queue[T]{slots: make([]T, size), mask: uint64(size - 1)}
+}
+
+// Cap returns the number of values the Queue holds when full.
+func (q *Queue[T]) Cap() int {
+	return len(q.slots)
+}
+
+// Len returns the number of values waiting, as RingBuffer.Len does.
+func (q *Queue[T]) Len() int {
+	return int(q.positions.write.Load() - q.positions.read.Load())
+}
+
+/*
+Push adds value to the back of the Queue and returns true, or returns false if
+the Queue is full. Only one goroutine may push.
+*/
+func (q *Queue[T]) Push(value T) bool {
+	write := q.positions.write.Load()
+	if int(write-q.positions.read.Load()) == len(q.slots) {
+		return false
+	}
+	q.slots[write&q.mask] = value
+	q.positions.write.Store(write + 1)
+	return true
+}
+
+/*
+Pop removes and returns the value at the front of the Queue, or returns false
+if the Queue is empty. Only one goroutine may pop.
+*/
+func (q *Queue[T]) Pop() (T, bool) {
+	read := q.positions.read.Load()
+	if read == q.positions.write.Load() {
+		var zero T
+		return zero, false
+	}
+	value := q.slots[read&q.mask]
+	q.positions.read.Store(read + 1)
+	return value, true
+}
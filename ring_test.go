@@ -0,0 +1,123 @@
+package audio_test
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	. "github.com/husafan/audio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRingBuffer(t *testing.T) {
+	ring := NewRingBuffer(5)
+	assert.Equal(t, 8, ring.Cap())
+	assert.Equal(t, 0, ring.Len())
+	assert.Equal(t, 8, ring.Free())
+
+	assert.Equal(t, 6, ring.Write([]float64{1, 2, 3, 4, 5, 6}))
+	values := make([]float64, 4)
+	assert.Equal(t, 4, ring.Read(values))
+	assert.Equal(t, []float64{1, 2, 3, 4}, values)
+	// Writing wraps around the end, and stops when full.
+	assert.Equal(t, 6, ring.Write([]float64{7, 8, 9, 10, 11, 12, 13}))
+	assert.Equal(t, 8, ring.Len())
+	assert.Equal(t, 0, ring.Write([]float64{14}))
+	values = make([]float64, 10)
+	assert.Equal(t, 8, ring.Read(values))
+	assert.Equal(t, []float64{5, 6, 7, 8, 9, 10, 11, 12}, values[:8])
+	assert.Equal(t, 0, ring.Read(values))
+}
+
+func TestRingBufferConcurrent(t *testing.T) {
+	const count = 100000
+	ring := NewRingBuffer(64)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		block := make([]float64, 7)
+		for next := 0; next < count; {
+			for i := range block {
+				block[i] = float64(next + i)
+			}
+			if next+len(block) > count {
+				block = block[:count-next]
+			}
+			n := ring.Write(block)
+			if n == 0 {
+				runtime.Gosched()
+			}
+			next += n
+		}
+	}()
+	block := make([]float64, 5)
+	for expected := 0; expected < count; {
+		n := ring.Read(block)
+		if n == 0 {
+			runtime.Gosched()
+		}
+		for _, value := range block[:n] {
+			if value != float64(expected) {
+				t.Fatalf("read %v, expected %v", value, expected)
+			}
+			expected++
+		}
+	}
+	wg.Wait()
+	assert.Equal(t, 0, ring.Len())
+}
+
+func TestQueue(t *testing.T) {
+	queue := NewQueue[string](2)
+	assert.Equal(t, 2, queue.Cap())
+	_, ok := queue.Pop()
+	assert.False(t, ok)
+	assert.True(t, queue.Push("a"))
+	assert.True(t, queue.Push("b"))
+	assert.False(t, queue.Push("c"))
+	assert.Equal(t, 2, queue.Len())
+	value, ok := queue.Pop()
+	assert.Equal(t, "a", value)
+	assert.True(t, ok)
+	assert.True(t, queue.Push("c"))
+	for _, expected := range []string{"b", "c"} {
+		value, _ = queue.Pop()
+		assert.Equal(t, expected, value)
+	}
+	assert.Equal(t, 0, queue.Len())
+}
+
+func TestQueueConcurrent(t *testing.T) {
+	const count = 100000
+	queue := NewQueue[int](16)
+	go func() {
+		for i := 0; i < count; {
+			if queue.Push(i) {
+				i++
+			} else {
+				runtime.Gosched()
+			}
+		}
+	}()
+	for expected := 0; expected < count; {
+		if value, ok := queue.Pop(); ok {
+			if value != expected {
+				t.Fatalf("popped %v, expected %v", value, expected)
+			}
+			expected++
+		} else {
+			runtime.Gosched()
+		}
+	}
+}
+
+func BenchmarkRingBuffer(b *testing.B) {
+	ring := NewRingBuffer(4096)
+	block := make([]float64, 256)
+	b.SetBytes(int64(len(block) * 8))
+	for i := 0; i < b.N; i++ {
+		ring.Write(block)
+		ring.Read(block)
+	}
+}
@@ -0,0 +1,130 @@
+package synth
+
+import (
+	"math"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/midi"
+)
+
+const (
+	// The following controllers shape the sound of a whole channel.
+	modulationWheel = 1
+	channelVolume   = 7
+	panController   = 10
+	expression      = 11
+)
+
+/*
+Channel holds the controllers of a MIDI channel that shape every voice on it:
+the pitch wheel, the modulation wheel, controller 1, Channel Volume and
+Expression, controllers 7 and 11, and Pan, controller 10. The bend range
+starts at the one given to NewChannel and follows Pitch Bend Sensitivity,
+registered parameter 0, as decoded by a midi.ControllerDecoder.
+*/
+type Channel struct {
+	bendRange  float64
+	bend       float64
+	modulation uint8
+	volume     uint8
+	expression uint8
+	pan        uint8
+	decoder    midi.ControllerDecoder
+}
+
+/*
+NewChannel returns a Channel with its controllers at their General MIDI
+defaults: the wheels centred and off, Channel Volume at 100, Expression at
+127 and Pan centred. A bendRange of 0 means the General MIDI range of 2
+semitones.
+*/
+func NewChannel(bendRange float64) *Channel {
+	if bendRange == 0 {
+		bendRange = midi.DefaultPitchBendRange
+	}
+	c := &Channel{bendRange: bendRange, volume: 100, pan: 64}
+	c.Reset()
+	return c
+}
+
+/*
+Reset returns the controllers to their defaults as Reset All Controllers,
+controller 121, does, which leaves Channel Volume, Pan and the bend range
+alone and deselects the registered and non-registered parameters.
+*/
+func (c *Channel) Reset() {
+	c.bend, c.modulation, c.expression = 0, 0, 127
+	c.decoder = midi.ControllerDecoder{}
+}
+
+/*
+Handle applies the pitch wheel and controller messages of the channel. Other
+messages are ignored.
+*/
+func (c *Channel) Handle(message midi.ChannelMessage) {
+	switch m := message.(type) {
+	case midi.PitchWheelMessage:
+		c.bend = m.Bend()
+	case midi.ControlChangeMessage:
+		c.controlChange(m)
+	}
+}
+
+// controlChange applies a Control Change message.
+func (c *Channel) controlChange(message midi.ControlChangeMessage) {
+	event, ok := c.decoder.Decode(message)
+	if ok && event.Kind == midi.RegisteredParameter &&
+		event.Number == midi.RPNPitchBendRange {
+		c.bendRange = event.PitchBendRange()
+	}
+	switch value := message.Value; message.Controller {
+	case modulationWheel:
+		c.modulation = value
+	case channelVolume:
+		c.volume = value
+	case panController:
+		c.pan = value
+	case expression:
+		c.expression = value
+	case resetControllers:
+		c.Reset()
+	}
+}
+
+// BendRange returns the bend of the pitch wheel at either end in semitones.
+func (c *Channel) BendRange() float64 {
+	return c.bendRange
+}
+
+// Bend returns the bend of the pitch wheel in semitones.
+func (c *Channel) Bend() float64 {
+	return c.bend * c.bendRange
+}
+
+// Modulation returns the position of the modulation wheel, from 0 to 1.
+func (c *Channel) Modulation() float64 {
+	return float64(c.modulation) / 127
+}
+
+/*
+Gain returns the level of the channel set by Channel Volume and Expression.
+Each follows the General MIDI curve of 40 log10(value/127) dB, and either at 0
+gives Silence.
+*/
+func (c *Channel) Gain() audio.Decibel {
+	gain := float64(c.volume) * float64(c.expression) / (127 * 127)
+	return audio.LinearToDecibel(gain * gain)
+}
+
+/*
+Pan returns the gains of the left and right outputs set by Pan, which keep
+the power of the channel the same wherever it is panned. Values 0 and 1 are
+both hard left, and 64 is the centre.
+*/
+func (c *Channel) Pan() (left, right float64) {
+	position := 0.0
+	if c.pan > 1 {
+		position = float64(c.pan-1) / 126
+	}
+	return math.Cos(math.Pi / 2 * position), math.Sin(math.Pi / 2 * position)
+}
@@ -0,0 +1,107 @@
+package synth_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/midi"
+	. "github.com/husafan/audio/synth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelDefaults(t *testing.T) {
+	channel := NewChannel(0)
+	assert.Equal(t, 2.0, channel.BendRange())
+	assert.Equal(t, 0.0, channel.Bend())
+	assert.Equal(t, 0.0, channel.Modulation())
+	assert.InDelta(t, 40*math.Log10(100.0/127), float64(channel.Gain()), 1e-9)
+	left, right := channel.Pan()
+	assert.InDelta(t, math.Sqrt(0.5), left, 1e-9)
+	assert.InDelta(t, math.Sqrt(0.5), right, 1e-9)
+}
+
+func TestChannelControllers(t *testing.T) {
+	channel := NewChannel(12)
+	channel.Handle(midi.PitchWheelMessage{Pitch: 8191})
+	assert.Equal(t, 12*8191.0/8192, channel.Bend())
+	channel.Handle(midi.PitchWheelMessage{Pitch: -4096})
+	assert.Equal(t, -6.0, channel.Bend())
+
+	for _, message := range []midi.ControlChangeMessage{
+		{Controller: 1, Value: 127},
+		{Controller: 7, Value: 127},
+		{Controller: 11, Value: 0},
+		{Controller: 10, Value: 127},
+	} {
+		channel.Handle(message)
+	}
+	assert.Equal(t, 1.0, channel.Modulation())
+	assert.Equal(t, audio.Silence, channel.Gain())
+	left, right := channel.Pan()
+	assert.InDelta(t, 0, left, 1e-9)
+	assert.InDelta(t, 1, right, 1e-9)
+
+	// Reset All Controllers leaves volume, pan and the bend range alone.
+	channel.Handle(midi.ControlChangeMessage{Controller: 121})
+	assert.Equal(t, 0.0, channel.Bend())
+	assert.Equal(t, 0.0, channel.Modulation())
+	assert.Equal(t, audio.Decibel(0), channel.Gain())
+	left, _ = channel.Pan()
+	assert.InDelta(t, 0, left, 1e-9)
+	assert.Equal(t, 12.0, channel.BendRange())
+}
+
+func TestChannelBendSensitivity(t *testing.T) {
+	channel := NewChannel(0)
+	// Data entry does nothing until the parameter is selected.
+	channel.Handle(midi.ControlChangeMessage{Controller: 6, Value: 7})
+	assert.Equal(t, 2.0, channel.BendRange())
+	for _, message := range []midi.ControlChangeMessage{
+		{Controller: 101, Value: 0},
+		{Controller: 100, Value: 0},
+		{Controller: 6, Value: 7},
+		{Controller: 38, Value: 50},
+		{Controller: 101, Value: 127},
+		{Controller: 100, Value: 127},
+		{Controller: 6, Value: 1},
+	} {
+		channel.Handle(message)
+	}
+	assert.InDelta(t, 7.5, channel.BendRange(), 1e-9)
+	channel.Handle(midi.PitchWheelMessage{Pitch: -8192})
+	assert.InDelta(t, -7.5, channel.Bend(), 1e-9)
+}
+
+func TestChannelParameterSelection(t *testing.T) {
+	channel := NewChannel(0)
+	// Data entry for a non-registered parameter 0 leaves the range alone.
+	for _, message := range []midi.ControlChangeMessage{
+		{Controller: 99, Value: 0},
+		{Controller: 98, Value: 0},
+		{Controller: 6, Value: 12},
+	} {
+		channel.Handle(message)
+	}
+	assert.Equal(t, 2.0, channel.BendRange())
+
+	// Nor does data entry after the null RPN deselects RPN 0.
+	for _, message := range []midi.ControlChangeMessage{
+		{Controller: 101, Value: 0},
+		{Controller: 100, Value: 0},
+		{Controller: 6, Value: 12},
+		{Controller: 101, Value: 127},
+		{Controller: 100, Value: 127},
+		{Controller: 6, Value: 24},
+	} {
+		channel.Handle(message)
+	}
+	assert.Equal(t, 12.0, channel.BendRange())
+
+	// Reset All Controllers deselects it too.
+	channel.Handle(midi.ControlChangeMessage{Controller: 101, Value: 0})
+	channel.Handle(midi.ControlChangeMessage{Controller: 100, Value: 0})
+	channel.Handle(midi.ControlChangeMessage{Controller: 121})
+	channel.Handle(midi.ControlChangeMessage{Controller: 6, Value: 24})
+	assert.Equal(t, 12.0, channel.BendRange())
+}
@@ -0,0 +1,283 @@
+package synth
+
+import (
+	"math"
+
+	"github.com/husafan/audio"
+)
+
+const (
+	// drumVoices is the number of hits a Drums plays at once. The oldest is
+	// cut when another starts.
+	drumVoices = 32
+	// chokeTime is the time in milliseconds over which a choked hit fades.
+	chokeTime = 5
+	// drumDecay is the level a hit falls to over its decay time, -60 dB, and
+	// drumFloor the level below which it is silent, -80 dB.
+	drumDecay = 1e-3
+	drumFloor = 1e-4
+)
+
+/*
+DrumSound describes a synthesized drum: a sine at Tone times full scale
+sweeping exponentially from Frequency to EndFrequency, with a time constant of
+Sweep milliseconds, mixed with white noise at Noise times full scale high pass
+filtered above Cutoff Hz, or unfiltered if Cutoff is 0. The mix then decays
+exponentially, falling by 60 dB over Decay milliseconds.
+*/
+type DrumSound struct {
+	Tone         float64 `json:"tone"`
+	Frequency    float64 `json:"frequency"`
+	EndFrequency float64 `json:"end_frequency"`
+	Sweep        float64 `json:"sweep"`
+	Noise        float64 `json:"noise"`
+	Cutoff       float64 `json:"cutoff"`
+	Decay        float64 `json:"decay"`
+}
+
+/*
+Kit maps General MIDI percussion keys to the sounds a Drums plays for them. A
+key in Samples plays its Buffer, mixed to mono and played at the Buffer's own
+sample rate, in preference to its DrumSound. Keys in neither are silent.
+*/
+type Kit struct {
+	Sounds  map[uint8]DrumSound     `json:"sounds"`
+	Samples map[uint8]*audio.Buffer `json:"-"`
+}
+
+/*
+generalMIDIKit holds a synthesized sound for each General MIDI Level 1
+percussion key. Fields are in the order Tone, Frequency, EndFrequency, Sweep,
+Noise, Cutoff and Decay.
+*/
+var generalMIDIKit = map[uint8]DrumSound{
+	35: {1, 120, 45, 40, 0.05, 0, 450},        // Acoustic Bass Drum
+	36: {1, 150, 50, 30, 0.05, 0, 400},        // Bass Drum 1
+	37: {0.6, 800, 600, 5, 0.4, 2000, 60},     // Side Stick
+	38: {0.5, 220, 180, 20, 0.7, 1500, 250},   // Acoustic Snare
+	39: {0, 0, 0, 0, 1, 1000, 180},            // Hand Clap
+	40: {0.5, 250, 200, 20, 0.8, 2000, 200},   // Electric Snare
+	41: {1, 90, 70, 60, 0.1, 0, 500},          // Low Floor Tom
+	42: {0, 0, 0, 0, 0.8, 7000, 60},           // Closed Hi Hat
+	43: {1, 105, 80, 60, 0.1, 0, 500},         // High Floor Tom
+	44: {0, 0, 0, 0, 0.7, 7000, 100},          // Pedal Hi-Hat
+	45: {1, 125, 95, 60, 0.1, 0, 450},         // Low Tom
+	46: {0, 0, 0, 0, 0.8, 7000, 600},          // Open Hi-Hat
+	47: {1, 145, 110, 60, 0.1, 0, 450},        // Low-Mid Tom
+	48: {1, 170, 130, 60, 0.1, 0, 400},        // Hi-Mid Tom
+	49: {0, 0, 0, 0, 0.8, 5000, 1800},         // Crash Cymbal 1
+	50: {1, 200, 150, 60, 0.1, 0, 400},        // High Tom
+	51: {0.2, 3000, 3000, 0, 0.5, 6000, 1200}, // Ride Cymbal 1
+	52: {0, 0, 0, 0, 0.8, 3000, 1500},         // Chinese Cymbal
+	53: {0.8, 2500, 2500, 0, 0.3, 6000, 900},  // Ride Bell
+	54: {0, 0, 0, 0, 0.6, 8000, 250},          // Tambourine
+	55: {0, 0, 0, 0, 0.7, 6000, 700},          // Splash Cymbal
+	56: {1, 560, 560, 0, 0, 0, 300},           // Cowbell
+	57: {0, 0, 0, 0, 0.8, 4500, 2000},         // Crash Cymbal 2
+	58: {0, 0, 0, 0, 0.6, 3000, 900},          // Vibraslap
+	59: {0.2, 2800, 2800, 0, 0.5, 5500, 1400}, // Ride Cymbal 2
+	60: {1, 400, 360, 10, 0.1, 0, 150},        // Hi Bongo
+	61: {1, 300, 270, 10, 0.1, 0, 200},        // Low Bongo
+	62: {1, 350, 315, 10, 0.1, 0, 80},         // Mute Hi Conga
+	63: {1, 330, 300, 10, 0.1, 0, 300},        // Open Hi Conga
+	64: {1, 220, 200, 10, 0.1, 0, 350},        // Low Conga
+	65: {0.8, 420, 400, 10, 0.3, 2000, 350},   // High Timbale
+	66: {0.8, 300, 285, 10, 0.3, 2000, 350},   // Low Timbale
+	67: {1, 900, 900, 0, 0, 0, 400},           // High Agogo
+	68: {1, 650, 650, 0, 0, 0, 400},           // Low Agogo
+	69: {0, 0, 0, 0, 0.5, 6000, 80},           // Cabasa
+	70: {0, 0, 0, 0, 0.5, 8000, 60},           // Maracas
+	71: {0.6, 2500, 2500, 0, 0, 0, 150},       // Short Whistle
+	72: {0.6, 2500, 2500, 0, 0, 0, 600},       // Long Whistle
+	73: {0, 0, 0, 0, 0.6, 3000, 100},          // Short Guiro
+	74: {0, 0, 0, 0, 0.6, 3000, 350},          // Long Guiro
+	75: {1, 2500, 2500, 0, 0, 0, 60},          // Claves
+	76: {1, 1200, 1200, 0, 0.1, 2000, 80},     // Hi Wood Block
+	77: {1, 800, 800, 0, 0.1, 2000, 80},       // Low Wood Block
+	78: {0.8, 700, 600, 20, 0, 0, 150},        // Mute Cuica
+	79: {0.8, 500, 900, 60, 0, 0, 400},        // Open Cuica
+	80: {0.5, 4500, 4500, 0, 0, 0, 150},       // Mute Triangle
+	81: {0.5, 4500, 4500, 0, 0, 0, 1500},      // Open Triangle
+}
+
+/*
+chokeGroups assigns keys to the groups of General MIDI percussion sounds that
+cut each other off, as a closed hi-hat silences an open one.
+*/
+var chokeGroups = map[uint8]int{
+	42: 1, 44: 1, 46: 1,
+	71: 2, 72: 2,
+	73: 3, 74: 3,
+	78: 4, 79: 4,
+	80: 5, 81: 5,
+}
+
+/*
+DefaultKit returns a Kit with a synthesized sound for every General MIDI Level
+1 percussion key, from 35 to 81, and no samples.
+*/
+func DefaultKit() Kit {
+	sounds := make(map[uint8]DrumSound, len(generalMIDIKit))
+	for key, sound := range generalMIDIKit {
+		sounds[key] = sound
+	}
+	return Kit{Sounds: sounds}
+}
+
+/*
+Drums plays a Kit for the General MIDI percussion channel. Hits are one-shot:
+they sound until they decay and ignore note offs, except that a hit cuts off
+earlier hits in its choke group, such as the open, closed and pedal hi-hats.
+*/
+type Drums struct {
+	kit        Kit
+	sampleRate float64
+	// samples holds the Samples of kit mixed to mono.
+	samples map[uint8][]float64
+	hits    []*drumHit
+}
+
+// NewDrums returns a silent Drums playing kit at the given sample rate.
+func NewDrums(kit Kit, sampleRate float64) *Drums {
+	d := &Drums{
+		kit:        kit,
+		sampleRate: sampleRate,
+		samples:    make(map[uint8][]float64),
+	}
+	for key, buffer := range kit.Samples {
+		mono := make([]float64, buffer.Frames())
+		for i := range mono {
+			for _, value := range buffer.Frame(i) {
+				mono[i] += value / float64(buffer.Format.Channels)
+			}
+		}
+		d.samples[key] = mono
+	}
+	return d
+}
+
+/*
+Hit starts the sound of key at velocity. Keys the kit has no sound for and a
+velocity of 0 are ignored.
+*/
+func (d *Drums) Hit(key, velocity uint8) {
+	if velocity == 0 {
+		return
+	}
+	hit := &drumHit{key: key, gain: float64(velocity) / 127, decay: 1}
+	if sample, ok := d.samples[key]; ok {
+		hit.sample = sample
+		hit.step = float64(d.kit.Samples[key].Format.SampleRate) / d.sampleRate
+	} else if sound, ok := d.kit.Sounds[key]; ok {
+		hit.sound = sound
+		hit.frequency = sound.Frequency
+		hit.decay = math.Pow(drumDecay, 1/d.frames(sound.Decay))
+		hit.sweep = math.Exp(-1 / d.frames(sound.Sweep))
+		hit.cutoff = 1 - math.Exp(-2*math.Pi*sound.Cutoff/d.sampleRate)
+		hit.seed = uint32(key)*2654435761 + 1
+	} else {
+		return
+	}
+	if group, ok := chokeGroups[key]; ok {
+		for _, other := range d.hits {
+			if chokeGroups[other.key] == group {
+				other.decay = math.Pow(drumFloor, 1/d.frames(chokeTime))
+			}
+		}
+	}
+	if len(d.hits) == drumVoices {
+		d.hits = d.hits[1:]
+	}
+	d.hits = append(d.hits, hit)
+}
+
+// frames returns the number of frames in milliseconds, at least 1.
+func (d *Drums) frames(milliseconds float64) float64 {
+	if frames := milliseconds * d.sampleRate / 1000; frames > 1 {
+		return frames
+	}
+	return 1
+}
+
+// Active reports whether any hit is sounding.
+func (d *Drums) Active() bool {
+	return len(d.hits) > 0
+}
+
+// Next returns the mix of the sounding hits for a frame and advances by it.
+func (d *Drums) Next() float64 {
+	var value float64
+	hits := d.hits[:0]
+	for _, hit := range d.hits {
+		value += hit.next(d.sampleRate)
+		if hit.active() {
+			hits = append(hits, hit)
+		}
+	}
+	for i := len(hits); i < len(d.hits); i++ {
+		d.hits[i] = nil
+	}
+	d.hits = hits
+	return value
+}
+
+/*
+drumHit is a sounding hit of a Drums, playing either sample or sound, at gain
+times full scale, which falls by the factor decay every frame.
+*/
+type drumHit struct {
+	key   uint8
+	gain  float64
+	decay float64
+	// sample is played from position, advancing by step frames a frame.
+	sample   []float64
+	position float64
+	step     float64
+	// sound is played with a sine at frequency, which approaches its end
+	// frequency by the factor sweep every frame, and noise from seed high
+	// passed by subtracting a one-pole low pass with coefficient cutoff.
+	sound     DrumSound
+	frequency float64
+	sweep     float64
+	phase     float64
+	cutoff    float64
+	lowpass   float64
+	seed      uint32
+}
+
+// active reports whether the hit is still audible.
+func (h *drumHit) active() bool {
+	if h.sample != nil {
+		return h.position < float64(len(h.sample)-1) && h.gain > drumFloor
+	}
+	return h.gain > drumFloor
+}
+
+// next returns the value of the current frame and advances by a frame.
+func (h *drumHit) next(sampleRate float64) float64 {
+	var value float64
+	if h.sample != nil {
+		whole := int(h.position)
+		if whole+1 < len(h.sample) {
+			fraction := h.position - float64(whole)
+			value = h.sample[whole] +
+				fraction*(h.sample[whole+1]-h.sample[whole])
+		}
+		h.position += h.step
+	} else {
+		value = h.sound.Tone * math.Sin(2*math.Pi*h.phase)
+		h.phase += h.frequency / sampleRate
+		h.phase -= math.Floor(h.phase)
+		h.frequency = h.sound.EndFrequency +
+			(h.frequency-h.sound.EndFrequency)*h.sweep
+		// A linear congruential generator is plenty for noise, and keeps
+		// renders repeatable.
+		h.seed = h.seed*1664525 + 1013904223
+		noise := float64(h.seed)/(1<<31) - 1
+		h.lowpass += h.cutoff * (noise - h.lowpass)
+		value += h.sound.Noise * (noise - h.lowpass)
+	}
+	value *= h.gain
+	h.gain *= h.decay
+	return value
+}
@@ -0,0 +1,94 @@
+package synth_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/husafan/audio"
+	. "github.com/husafan/audio/synth"
+	"github.com/stretchr/testify/assert"
+)
+
+// play runs drums for frames and returns their output.
+func play(drums *Drums, frames int) []float64 {
+	output := make([]float64, frames)
+	for i := range output {
+		output[i] = drums.Next()
+	}
+	return output
+}
+
+// peak returns the largest magnitude of values.
+func peak(values []float64) float64 {
+	var peak float64
+	for _, value := range values {
+		peak = math.Max(peak, math.Abs(value))
+	}
+	return peak
+}
+
+func TestDefaultKit(t *testing.T) {
+	kit := DefaultKit()
+	assert.Len(t, kit.Sounds, 47)
+	for key := uint8(35); key <= 81; key++ {
+		assert.Contains(t, kit.Sounds, key)
+	}
+	// Changing the Kit leaves the default alone.
+	delete(kit.Sounds, 36)
+	assert.Contains(t, DefaultKit().Sounds, uint8(36))
+}
+
+func TestDrums(t *testing.T) {
+	drums := NewDrums(DefaultKit(), 8000)
+	assert.False(t, drums.Active())
+	drums.Hit(36, 0)
+	drums.Hit(20, 100)
+	assert.False(t, drums.Active())
+
+	drums.Hit(36, 127)
+	assert.True(t, drums.Active())
+	attack := play(drums, 400)
+	assert.InDelta(t, 1, peak(attack), 0.1)
+	// The bass drum falls by 60 dB over 400 ms and is silent by 80 dB.
+	assert.InDelta(t, 1.2e-3, peak(play(drums, 2800)[2600:]), 4e-4)
+	play(drums, 1600)
+	assert.False(t, drums.Active())
+
+	// Hits are repeatable and follow velocity.
+	drums.Hit(38, 64)
+	soft := play(drums, 400)
+	drums = NewDrums(DefaultKit(), 8000)
+	drums.Hit(38, 127)
+	loud := play(drums, 400)
+	for i := range soft {
+		assert.InDelta(t, loud[i]*64/127, soft[i], 1e-9)
+	}
+}
+
+func TestDrumsChoke(t *testing.T) {
+	drums := NewDrums(DefaultKit(), 8000)
+	drums.Hit(46, 127)
+	drums.Hit(49, 127)
+	play(drums, 80)
+	// The closed hi-hat cuts the open one, but not the cymbal.
+	drums.Hit(42, 127)
+	play(drums, 800)
+	drums.Hit(42, 0)
+	cymbal := NewDrums(DefaultKit(), 8000)
+	cymbal.Hit(49, 127)
+	play(cymbal, 880)
+	assert.InDeltaSlice(t, play(cymbal, 100), play(drums, 100), 1e-9)
+}
+
+func TestDrumsSamples(t *testing.T) {
+	sample := audio.NewBuffer(audio.Format{SampleRate: 4000, Channels: 2}, 5)
+	copy(sample.Data, []float64{1, 0, 0.5, 0.5, 0, 1, -0.5, -0.5, -1, 0})
+	drums := NewDrums(Kit{Samples: map[uint8]*audio.Buffer{
+		36: sample}}, 8000)
+	drums.Hit(36, 127)
+	drums.Hit(38, 127)
+	// The sample is mixed to mono and played at its own rate.
+	assert.InDeltaSlice(t, []float64{0.5, 0.5, 0.5, 0.5, 0.5, 0, -0.5, -0.5},
+		play(drums, 8), 1e-9)
+	assert.False(t, drums.Active())
+}
@@ -0,0 +1,114 @@
+package synth
+
+// EnvelopeStage is the part of its cycle an Envelope is in.
+type EnvelopeStage int
+
+const (
+	// EnvelopeIdle is the stage of an Envelope that is silent until triggered.
+	EnvelopeIdle EnvelopeStage = iota
+	EnvelopeAttack
+	EnvelopeDecay
+	EnvelopeSustain
+	EnvelopeRelease
+)
+
+/*
+EnvelopeSettings configures an Envelope. Attack, Decay and Release are times in
+milliseconds and Sustain is a level between 0 and 1.
+*/
+type EnvelopeSettings struct {
+	Attack  float64 `json:"attack"`
+	Decay   float64 `json:"decay"`
+	Sustain float64 `json:"sustain"`
+	Release float64 `json:"release"`
+}
+
+/*
+Envelope is an ADSR envelope generator. Once triggered, its level rises to 1
+over the attack time, falls to the sustain level over the decay time and holds
+there until released, when it falls to 0 over the release time. Each segment
+is a straight line. Triggering an envelope that is still sounding restarts the
+attack from the current level rather than from 0, and releasing it part way
+through the attack or decay releases from the current level, so neither
+clicks.
+*/
+type Envelope struct {
+	settings   EnvelopeSettings
+	sampleRate float64
+	stage      EnvelopeStage
+	level      float64
+	step       float64
+}
+
+// NewEnvelope returns an idle Envelope for the given sample rate.
+func NewEnvelope(settings EnvelopeSettings, sampleRate float64) *Envelope {
+	return &Envelope{settings: settings, sampleRate: sampleRate}
+}
+
+/*
+frames returns the number of frames in milliseconds, at least 1 so that
+segments of no length finish on the next frame.
+*/
+func (e *Envelope) frames(milliseconds float64) float64 {
+	if frames := milliseconds * e.sampleRate / 1000; frames > 1 {
+		return frames
+	}
+	return 1
+}
+
+// Trigger starts the attack, as on a note on.
+func (e *Envelope) Trigger() {
+	e.stage = EnvelopeAttack
+	e.step = 1 / e.frames(e.settings.Attack)
+}
+
+// Release starts the release, as on a note off. An idle Envelope stays idle.
+func (e *Envelope) Release() {
+	if e.stage == EnvelopeIdle || e.stage == EnvelopeRelease {
+		return
+	}
+	e.stage = EnvelopeRelease
+	e.step = e.level / e.frames(e.settings.Release)
+}
+
+// Reset silences the Envelope at once, as when its voice is stolen.
+func (e *Envelope) Reset() {
+	e.stage, e.level = EnvelopeIdle, 0
+}
+
+// Stage returns the current stage.
+func (e *Envelope) Stage() EnvelopeStage {
+	return e.stage
+}
+
+// Active reports whether the Envelope is sounding.
+func (e *Envelope) Active() bool {
+	return e.stage != EnvelopeIdle
+}
+
+// Level returns the level of the last frame.
+func (e *Envelope) Level() float64 {
+	return e.level
+}
+
+// Next advances the Envelope by a frame and returns its level.
+func (e *Envelope) Next() float64 {
+	switch e.stage {
+	case EnvelopeAttack:
+		if e.level += e.step; e.level >= 1 {
+			e.level = 1
+			e.stage = EnvelopeDecay
+			e.step = (1 - e.settings.Sustain) / e.frames(e.settings.Decay)
+		}
+	case EnvelopeDecay:
+		if e.level -= e.step; e.level <= e.settings.Sustain {
+			e.level = e.settings.Sustain
+			e.stage = EnvelopeSustain
+		}
+	case EnvelopeRelease:
+		if e.level -= e.step; e.level <= 0 {
+			e.Reset()
+		}
+	}
+	return e.level
+}
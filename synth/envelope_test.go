@@ -0,0 +1,71 @@
+package synth_test
+
+import (
+	"testing"
+
+	. "github.com/husafan/audio/synth"
+	"github.com/stretchr/testify/assert"
+)
+
+// step advances envelope by frames and returns its last level.
+func step(envelope *Envelope, frames int) float64 {
+	var level float64
+	for i := 0; i < frames; i++ {
+		level = envelope.Next()
+	}
+	return level
+}
+
+func TestEnvelope(t *testing.T) {
+	envelope := NewEnvelope(EnvelopeSettings{
+		Attack: 4, Decay: 10, Sustain: 0.5, Release: 20}, 1000)
+	assert.False(t, envelope.Active())
+	assert.Equal(t, 0.0, envelope.Next())
+
+	envelope.Trigger()
+	assert.Equal(t, EnvelopeAttack, envelope.Stage())
+	assert.InDelta(t, 0.25, envelope.Next(), 1e-9)
+	assert.InDelta(t, 1, step(envelope, 3), 1e-9)
+	assert.Equal(t, EnvelopeDecay, envelope.Stage())
+	assert.InDelta(t, 0.75, step(envelope, 5), 1e-9)
+	assert.InDelta(t, 0.5, step(envelope, 5), 1e-9)
+	assert.Equal(t, EnvelopeSustain, envelope.Stage())
+	assert.InDelta(t, 0.5, step(envelope, 100), 1e-9)
+
+	envelope.Release()
+	assert.Equal(t, EnvelopeRelease, envelope.Stage())
+	assert.InDelta(t, 0.25, step(envelope, 10), 1e-9)
+	assert.InDelta(t, 0, step(envelope, 10), 1e-9)
+	assert.False(t, envelope.Active())
+}
+
+func TestEnvelopeRetrigger(t *testing.T) {
+	envelope := NewEnvelope(EnvelopeSettings{
+		Attack: 10, Decay: 10, Sustain: 1, Release: 10}, 1000)
+	envelope.Trigger()
+	step(envelope, 4)
+	// Releasing during the attack falls from the level reached.
+	envelope.Release()
+	assert.InDelta(t, 0.2, step(envelope, 5), 1e-9)
+	// Retriggering rises from there, at the attack's rate.
+	envelope.Trigger()
+	assert.InDelta(t, 0.3, envelope.Next(), 1e-9)
+	assert.InDelta(t, 1, step(envelope, 8), 1e-9)
+	step(envelope, 1)
+	assert.Equal(t, EnvelopeSustain, envelope.Stage())
+
+	envelope.Reset()
+	assert.Equal(t, 0.0, envelope.Level())
+	envelope.Release()
+	assert.Equal(t, EnvelopeIdle, envelope.Stage())
+}
+
+func TestEnvelopeWithoutTimes(t *testing.T) {
+	envelope := NewEnvelope(EnvelopeSettings{Sustain: 0.8}, 48000)
+	envelope.Trigger()
+	assert.Equal(t, 1.0, envelope.Next())
+	assert.InDelta(t, 0.8, envelope.Next(), 1e-9)
+	envelope.Release()
+	assert.Equal(t, 0.0, envelope.Next())
+	assert.False(t, envelope.Active())
+}
@@ -0,0 +1,80 @@
+package synth
+
+import "math"
+
+// Waveform is the shape of an oscillator's cycle.
+type Waveform int
+
+const (
+	Sine Waveform = iota
+	Triangle
+	Square
+	// Sawtooth rises from -1 to 1 over each cycle.
+	Sawtooth
+)
+
+/*
+Value returns the value of the waveform, between -1 and 1, at phase cycles
+from the start of a cycle. Every waveform starts a cycle at 0 and rising,
+except Sawtooth, which starts at -1, and Square, which starts at 1.
+*/
+func (w Waveform) Value(phase float64) float64 {
+	phase -= math.Floor(phase)
+	switch w {
+	case Triangle:
+		if phase < 0.25 {
+			return 4 * phase
+		} else if phase < 0.75 {
+			return 2 - 4*phase
+		}
+		return 4*phase - 4
+	case Square:
+		if phase < 0.5 {
+			return 1
+		}
+		return -1
+	case Sawtooth:
+		return 2*phase - 1
+	}
+	return math.Sin(2 * math.Pi * phase)
+}
+
+/*
+LFO is a low frequency oscillator for modulating parameters such as pitch for
+vibrato or level for tremolo. Its frequency can change while it runs without
+its output jumping.
+*/
+type LFO struct {
+	waveform   Waveform
+	sampleRate float64
+	step       float64
+	phase      float64
+}
+
+// NewLFO returns an LFO at frequency Hz for the given sample rate.
+func NewLFO(waveform Waveform, frequency, sampleRate float64) *LFO {
+	l := &LFO{waveform: waveform, sampleRate: sampleRate}
+	l.SetFrequency(frequency)
+	return l
+}
+
+// SetFrequency sets the frequency in Hz, keeping the current phase.
+func (l *LFO) SetFrequency(frequency float64) {
+	l.step = frequency / l.sampleRate
+}
+
+/*
+Retrigger restarts the cycle at phase cycles, from 0 to 1, as when a note on
+syncs the LFO of its voice.
+*/
+func (l *LFO) Retrigger(phase float64) {
+	l.phase = phase - math.Floor(phase)
+}
+
+// Next returns the value of the current frame and advances by a frame.
+func (l *LFO) Next() float64 {
+	value := l.waveform.Value(l.phase)
+	l.phase += l.step
+	l.phase -= math.Floor(l.phase)
+	return value
+}
@@ -0,0 +1,39 @@
+package synth_test
+
+import (
+	"testing"
+
+	. "github.com/husafan/audio/synth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaveformValue(t *testing.T) {
+	phases := []float64{0, 0.25, 0.5, 0.75, 1.25}
+	for waveform, expected := range map[Waveform][]float64{
+		Sine:     {0, 1, 0, -1, 1},
+		Triangle: {0, 1, 0, -1, 1},
+		Square:   {1, 1, -1, -1, 1},
+		Sawtooth: {-1, -0.5, 0, 0.5, -0.5},
+	} {
+		for i, phase := range phases {
+			assert.InDelta(t, expected[i], waveform.Value(phase), 1e-9)
+		}
+	}
+}
+
+func TestLFO(t *testing.T) {
+	lfo := NewLFO(Triangle, 250, 1000)
+	var values []float64
+	for i := 0; i < 5; i++ {
+		values = append(values, lfo.Next())
+	}
+	assert.InDeltaSlice(t, []float64{0, 1, 0, -1, 0}, values, 1e-9)
+
+	// Changing the frequency keeps the phase.
+	lfo.SetFrequency(125)
+	assert.InDelta(t, 1, lfo.Next(), 1e-9)
+	assert.InDelta(t, 0.5, lfo.Next(), 1e-9)
+
+	lfo.Retrigger(0.75)
+	assert.InDelta(t, -1, lfo.Next(), 1e-9)
+}
@@ -0,0 +1,149 @@
+package synth
+
+import (
+	"context"
+	"io"
+	"math"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/midi"
+)
+
+const (
+	// The following are used for RenderOptions Format fields left at 0.
+	defaultSampleRate = 44100
+	defaultChannels   = 2
+	// renderBlock is the number of frames Render reads at a time.
+	renderBlock = 1024
+)
+
+// scheduled is a channel message of a loaded sequence and the frame it is due.
+type scheduled struct {
+	frame   int64
+	message midi.ChannelMessage
+}
+
+/*
+Load schedules the channel messages of m to play from the current position of
+the Synth, each at the frame nearest its time in the file's tempo map,
+replacing any sequence loaded before. Messages passed to Handle still play
+alongside it. ErrMissingHeader is returned for a Midi without a HeaderChunk.
+*/
+func (s *Synth) Load(m *midi.Midi) error {
+	if m.HeaderChunk == nil {
+		return midi.ErrMissingHeader
+	}
+	s.sequence, s.due = s.sequence[:0], 0
+	s.end = s.frame
+	m.ForEachEvent(func(timed midi.TimedEvent) error {
+		frame := s.frame +
+			int64(math.Round(timed.Time.Seconds()*s.sampleRate))
+		if frame > s.end {
+			s.end = frame
+		}
+		if message := timed.Event.ChannelMessage(); message != nil {
+			s.sequence = append(s.sequence, scheduled{frame, message})
+		}
+		return nil
+	})
+	s.loaded = true
+	return nil
+}
+
+/*
+ReadFrames renders the next frames of the Synth into frames, in the manner of
+wav.WavReader.ReadFrames, playing the messages of the loaded sequence as they
+fall due. Each frame is filled as by Next. Once the sequence reaches the end
+of its longest track, every note still held is released, and when the last
+voice falls silent the number of frames rendered is returned with io.EOF.
+Without a loaded sequence, frames are always filled, so that a real time
+output can pull from a Synth driven by Handle.
+*/
+func (s *Synth) ReadFrames(frames [][]float64) (int, error) {
+	for i, frame := range frames {
+		for s.due < len(s.sequence) && s.sequence[s.due].frame <= s.frame {
+			s.Handle(s.sequence[s.due].message)
+			s.due++
+		}
+		if s.loaded && s.frame >= s.end && s.due == len(s.sequence) {
+			if s.frame == s.end {
+				s.releaseAll()
+			}
+			if !s.Active() {
+				s.loaded = false
+				return i, io.EOF
+			}
+		}
+		s.Next(frame)
+		s.frame++
+	}
+	return len(frames), nil
+}
+
+// releaseAll lifts the sustain pedal and releases the keys of every channel.
+func (s *Synth) releaseAll() {
+	for channel := uint8(0); channel < 16; channel++ {
+		s.allocator.ControlChange(channel, sustainPedal, 0)
+		s.allocator.ControlChange(channel, allNotesOff, 0)
+	}
+}
+
+/*
+RenderOptions configures Render: the Settings of its Synth, usually from
+DefaultSettings, and the Format of the Buffer it returns, whose zero SampleRate
+means 44100 Hz and zero Channels stereo. Progress, if not nil, is called as
+RenderContext works, with the frames up to the end of the file's longest track
+as the total, which the release of the last notes may run a little past.
+*/
+type RenderOptions struct {
+	Settings Settings           `json:"settings"`
+	Format   audio.Format       `json:"format"`
+	Progress audio.ProgressFunc `json:"-"`
+}
+
+/*
+Render plays m through a new Synth and returns the result, which lasts until
+the end of the file's longest track and the release of its last notes, such as
+for writing with wav.WavWriter.WriteBuffer. ErrMissingHeader is returned for
+a Midi without a HeaderChunk.
+*/
+func Render(m *midi.Midi, options RenderOptions) (*audio.Buffer, error) {
+	return RenderContext(context.Background(), m, options)
+}
+
+/*
+RenderContext is Render, stopping once ctx is done. The audio rendered until
+then is returned with ctx's error.
+*/
+func RenderContext(ctx context.Context,
+	m *midi.Midi, options RenderOptions) (*audio.Buffer, error) {
+	format := options.Format
+	if format.SampleRate == 0 {
+		format.SampleRate = defaultSampleRate
+	}
+	if format.Channels == 0 {
+		format.Channels = defaultChannels
+	}
+	synth := NewSynth(options.Settings, float64(format.SampleRate))
+	if err := synth.Load(m); err != nil {
+		return nil, err
+	}
+	buffer := &audio.Buffer{Format: format}
+	block := audio.NewBuffer(format, renderBlock)
+	frames := make([][]float64, renderBlock)
+	for i := range frames {
+		frames[i] = block.Frame(i)
+	}
+	total := synth.end - synth.frame
+	for {
+		if err := ctx.Err(); err != nil {
+			return buffer, err
+		}
+		count, err := synth.ReadFrames(frames)
+		buffer.Data = append(buffer.Data, block.Data[:count*format.Channels]...)
+		options.Progress.Report(int64(buffer.Frames()), total)
+		if err == io.EOF {
+			return buffer, nil
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package synth_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/midi"
+	. "github.com/husafan/audio/synth"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+newRenderMidi returns a format 0 Midi at 120 beats per minute and 480 ticks
+per quarter note, so that 960 ticks last a second, holding events and an End
+of Track.
+*/
+func newRenderMidi(events ...midi.TrackEvent) *midi.Midi {
+	track := midi.TrackChunk{Chunk: &midi.Chunk{}, TrackEvents: append(
+		events, midi.TrackEvent{
+			Data: []byte{midi.MetaEvent, midi.MetaEndOfTrack}})}
+	return &midi.Midi{
+		HeaderChunk: &midi.HeaderChunk{Chunk: &midi.Chunk{}, Division: 480},
+		TrackChunks: []midi.TrackChunk{track},
+	}
+}
+
+func TestRender(t *testing.T) {
+	m := newRenderMidi(
+		midi.TrackEvent{DeltaTime: 480,
+			Data: []byte{midi.NoteOnEvent, 69, 127}},
+		midi.TrackEvent{DeltaTime: 480,
+			Data: []byte{midi.NoteOffEvent, 69, 0}})
+	buffer, err := Render(m, RenderOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, audio.Format{SampleRate: 44100, Channels: 2},
+		buffer.Format)
+	// The note plays from half a second for half a second, then releases
+	// over 50 ms.
+	assert.Equal(t, 44100+2205, buffer.Frames())
+	assert.Equal(t, 0.0, buffer.Frame(22049)[0])
+	assert.NotEqual(t, 0.0, buffer.Frame(22051)[0])
+	assert.InDelta(t, buffer.Frame(30000)[0], buffer.Frame(30000)[1], 1e-12)
+
+	_, err = Render(&midi.Midi{}, RenderOptions{})
+	assert.Equal(t, midi.ErrMissingHeader, err)
+}
+
+func TestRenderContext(t *testing.T) {
+	m := newRenderMidi(
+		midi.TrackEvent{Data: []byte{midi.NoteOnEvent, 69, 127}},
+		midi.TrackEvent{DeltaTime: 960,
+			Data: []byte{midi.NoteOffEvent, 69, 0}})
+	ctx, cancel := context.WithCancel(context.Background())
+	var reports [][2]int64
+	// Cancel once the first two blocks have been rendered.
+	buffer, err := RenderContext(ctx, m, RenderOptions{
+		Format: audio.Format{SampleRate: 8000, Channels: 1},
+		Progress: func(done, total int64) {
+			reports = append(reports, [2]int64{done, total})
+			if len(reports) == 2 {
+				cancel()
+			}
+		},
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, [][2]int64{{1024, 8000}, {2048, 8000}}, reports)
+	assert.Equal(t, 2048, buffer.Frames())
+	assert.NotEqual(t, 0.0, buffer.Data[2047])
+
+	var last [2]int64
+	buffer, err = RenderContext(context.Background(), m, RenderOptions{
+		Format: audio.Format{SampleRate: 8000, Channels: 1},
+		Progress: func(done, total int64) {
+			last = [2]int64{done, total}
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, [2]int64{int64(buffer.Frames()), 8000}, last)
+}
+
+func TestRenderHangingNotes(t *testing.T) {
+	// A note without a note off, held by the pedal, still ends with the
+	// file.
+	m := newRenderMidi(
+		midi.TrackEvent{Data: []byte{midi.ControlChange, 64, 127}},
+		midi.TrackEvent{Data: []byte{midi.NoteOnEvent, 60, 100}},
+		midi.TrackEvent{DeltaTime: 960,
+			Data: []byte{midi.ControlChange, 7, 127}})
+	buffer, err := Render(m, RenderOptions{
+		Format: audio.Format{SampleRate: 8000, Channels: 1}})
+	assert.Nil(t, err)
+	assert.InDelta(t, 8000+400, buffer.Frames(), 1)
+}
+
+func TestReadFrames(t *testing.T) {
+	format := audio.Format{SampleRate: 8000, Channels: 1}
+	m := newRenderMidi(
+		midi.TrackEvent{Data: []byte{midi.NoteOnEvent, 69, 127}},
+		midi.TrackEvent{DeltaTime: 96,
+			Data: []byte{midi.NoteOffEvent, 69, 0}})
+	rendered, err := Render(m, RenderOptions{Format: format})
+	assert.Nil(t, err)
+
+	synth := NewSynth(Settings{}, 8000)
+	frames := make([][]float64, 300)
+	for i := range frames {
+		frames[i] = make([]float64, 1)
+	}
+	// Without a sequence, the Synth plays silence indefinitely.
+	count, err := synth.ReadFrames(frames)
+	assert.Equal(t, 300, count)
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{0}, frames[299])
+
+	// A loaded sequence plays from the current position, the same as
+	// Render.
+	assert.Nil(t, synth.Load(m))
+	var output []float64
+	for err == nil {
+		count, err = synth.ReadFrames(frames)
+		for _, frame := range frames[:count] {
+			output = append(output, frame[0])
+		}
+	}
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, rendered.Data, output)
+	count, err = synth.ReadFrames(frames)
+	assert.Equal(t, 300, count)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,213 @@
+/*
+The synth package generates audio for MIDI notes. It holds the building blocks
+of a synthesizer, such as envelopes and low frequency oscillators, which step
+one frame at a time at a given sample rate so that renderers and generators
+can share them, and a Synth that plays channel messages with them.
+*/
+package synth
+
+import (
+	"math"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/midi"
+	"github.com/husafan/audio/midi/gm"
+)
+
+const (
+	// The following are used for Settings fields left at 0.
+	defaultVoices      = 32
+	defaultVibratoRate = 5
+)
+
+// defaultEnvelope is used for a Settings Envelope left at its zero value.
+var defaultEnvelope = EnvelopeSettings{
+	Attack: 5, Decay: 200, Sustain: 0.5, Release: 50}
+
+/*
+KeyFrequency returns the frequency in Hz of a MIDI key in equal temperament,
+with A4, key 69, at 440 Hz. Fractional keys are between semitones, as when a
+pitch bend is applied.
+*/
+func KeyFrequency(key float64) float64 {
+	return 440 * math.Pow(2, (key-69)/12)
+}
+
+/*
+Settings configures a Synth. Voices, ChannelVoices and Steal configure its
+Allocator, with 0 Voices meaning 32, and every voice plays Waveform shaped by
+Envelope, whose zero value means a 5 ms attack, a 200 ms decay to half level
+and a 50 ms release. BendRange is the initial bend range in semitones, 0
+meaning 2. Vibrato is the depth in semitones of the vibrato at full
+modulation, 0 for none, and VibratoRate its rate in Hz, 0 meaning 5. Gain is
+the level of a voice at full velocity, and Kit the drums of the percussion
+channel, nil meaning DefaultKit. DefaultSettings gives a vibrato and a gain
+suited to playing a whole file.
+*/
+type Settings struct {
+	Voices        int              `json:"voices"`
+	ChannelVoices int              `json:"channel_voices"`
+	Steal         StealPolicy      `json:"steal"`
+	Envelope      EnvelopeSettings `json:"envelope"`
+	Waveform      Waveform         `json:"waveform"`
+	BendRange     float64          `json:"bend_range"`
+	Vibrato       float64          `json:"vibrato"`
+	VibratoRate   float64          `json:"vibrato_rate"`
+	Gain          audio.Decibel    `json:"gain"`
+	Kit           *Kit             `json:"kit,omitempty"`
+}
+
+/*
+Synth plays MIDI channel messages. Notes on the General MIDI percussion
+channel play its Kit, and notes on the other channels play voices from its
+Allocator, bent by the pitch wheel and given vibrato by the modulation wheel.
+Each channel is levelled by its Channel Volume and Expression and, in stereo,
+placed by its Pan. Messages come from Handle, as from a live input, or from a
+file given to Load, and the output is pulled a frame at a time with Next or in
+blocks with ReadFrames, so the same engine serves offline and real time use.
+*/
+type Synth struct {
+	settings   Settings
+	sampleRate float64
+	gain       float64
+	allocator  *Allocator
+	drums      *Drums
+	channels   [16]*Channel
+	vibrato    [16]*LFO
+	// frame counts the frames rendered. The loaded sequence plays
+	// sequence[due:] and ends at frame end.
+	frame    int64
+	loaded   bool
+	sequence []scheduled
+	due      int
+	end      int64
+}
+
+/*
+DefaultSettings returns the Settings of a General MIDI player: a vibrato of half
+a semitone, and a gain of -12 dB, which leaves room for several voices at full
+velocity before the mix clips. The other fields are left at their defaults.
+*/
+func DefaultSettings() Settings {
+	return Settings{Vibrato: 0.5, Gain: -12}
+}
+
+// NewSynth returns a silent Synth at the given sample rate.
+func NewSynth(settings Settings, sampleRate float64) *Synth {
+	if settings.Voices == 0 {
+		settings.Voices = defaultVoices
+	}
+	if settings.Envelope == (EnvelopeSettings{}) {
+		settings.Envelope = defaultEnvelope
+	}
+	if settings.VibratoRate == 0 {
+		settings.VibratoRate = defaultVibratoRate
+	}
+	kit := DefaultKit()
+	if settings.Kit != nil {
+		kit = *settings.Kit
+	}
+	s := &Synth{
+		settings:   settings,
+		sampleRate: sampleRate,
+		gain:       settings.Gain.Linear(),
+		allocator: NewAllocator(AllocatorSettings{
+			Voices:        settings.Voices,
+			ChannelVoices: settings.ChannelVoices,
+			Steal:         settings.Steal,
+			Envelope:      settings.Envelope,
+		}, sampleRate),
+		drums: NewDrums(kit, sampleRate),
+	}
+	for i := range s.channels {
+		s.channels[i] = NewChannel(settings.BendRange)
+		s.vibrato[i] = NewLFO(Sine, settings.VibratoRate, sampleRate)
+	}
+	return s
+}
+
+// Channel returns the controllers of channel, counted from 0.
+func (s *Synth) Channel(channel uint8) *Channel {
+	return s.channels[channel&0x0F]
+}
+
+// Handle plays a channel message.
+func (s *Synth) Handle(message midi.ChannelMessage) {
+	var channel uint8
+	switch m := message.(type) {
+	case midi.NoteOnMessage:
+		if m.Channel == gm.PercussionChannel {
+			s.drums.Hit(m.Key, m.Velocity)
+			return
+		}
+		channel = m.Channel
+	case midi.NoteOffMessage:
+		channel = m.Channel
+	case midi.ControlChangeMessage:
+		channel = m.Channel
+	case midi.PitchWheelMessage:
+		channel = m.Channel
+	default:
+		return
+	}
+	s.Channel(channel).Handle(message)
+	if channel != gm.PercussionChannel {
+		s.allocator.Handle(message)
+	}
+}
+
+// Active reports whether any voice or drum is sounding.
+func (s *Synth) Active() bool {
+	for i := range s.allocator.voices {
+		if s.allocator.voices[i].Active() {
+			return true
+		}
+	}
+	return s.drums.Active()
+}
+
+/*
+Next sets frame to the output of the Synth for a frame and advances by it. A
+mono frame holds the mix of every channel, and a frame of two or more
+channels holds the panned mix in its first two, with the others silent.
+*/
+func (s *Synth) Next(frame audio.Frame) {
+	var mixes [16]float64
+	var vibrato [16]float64
+	for i, lfo := range s.vibrato {
+		vibrato[i] = lfo.Next() * s.channels[i].Modulation() *
+			s.settings.Vibrato
+	}
+	for i := range s.allocator.voices {
+		voice := &s.allocator.voices[i]
+		if !voice.Active() {
+			continue
+		}
+		level := voice.Envelope.Next() * float64(voice.Velocity) / 127
+		channel := voice.Channel & 0x0F
+		mixes[channel] += level * s.settings.Waveform.Value(voice.phase)
+		key := float64(voice.Key) + s.channels[channel].Bend() +
+			vibrato[channel]
+		voice.phase += KeyFrequency(key) / s.sampleRate
+		voice.phase -= math.Floor(voice.phase)
+	}
+	if s.drums.Active() {
+		mixes[gm.PercussionChannel] += s.drums.Next()
+	}
+	for i := range frame {
+		frame[i] = 0
+	}
+	for i, mix := range mixes {
+		if mix == 0 || len(frame) == 0 {
+			continue
+		}
+		mix *= s.channels[i].Gain().Linear() * s.gain
+		if len(frame) == 1 {
+			frame[0] += mix
+			continue
+		}
+		left, right := s.channels[i].Pan()
+		frame[0] += left * mix
+		frame[1] += right * mix
+	}
+}
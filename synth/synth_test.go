@@ -0,0 +1,127 @@
+package synth_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/dsp"
+	"github.com/husafan/audio/midi"
+	. "github.com/husafan/audio/synth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyFrequency(t *testing.T) {
+	assert.Equal(t, 440.0, KeyFrequency(69))
+	assert.InDelta(t, 261.626, KeyFrequency(60), 1e-3)
+	assert.InDelta(t, 880, KeyFrequency(81), 1e-9)
+	assert.InDelta(t, 440*1.0293, KeyFrequency(69.5), 1e-2)
+}
+
+// render runs synth for frames of format and returns their output.
+func render(synth *Synth, format audio.Format, frames int) *audio.Buffer {
+	buffer := audio.NewBuffer(format, frames)
+	for i := 0; i < frames; i++ {
+		synth.Next(buffer.Frame(i))
+	}
+	return buffer
+}
+
+func TestSynthPitchWheel(t *testing.T) {
+	const rate = 8000
+	mono := audio.Format{SampleRate: rate, Channels: 1}
+	synth := NewSynth(Settings{}, rate)
+	synth.Handle(midi.NoteOnMessage{Channel: 0, Key: 69, Velocity: 127})
+	assert.InDelta(t, 440, dsp.DetectPitch(
+		render(synth, mono, 1024).Data, rate).Frequency, 2)
+	synth.Handle(midi.PitchWheelMessage{Channel: 0, Pitch: 8191})
+	assert.InDelta(t, 493.88, dsp.DetectPitch(
+		render(synth, mono, 1024).Data, rate).Frequency, 2)
+	// Only the wheel of the note's channel bends it.
+	synth.Handle(midi.PitchWheelMessage{Channel: 1, Pitch: -8192})
+	synth.Handle(midi.PitchWheelMessage{Channel: 0})
+	assert.InDelta(t, 440, dsp.DetectPitch(
+		render(synth, mono, 1024).Data, rate).Frequency, 2)
+
+	synth.Handle(midi.NoteOffMessage{Channel: 0, Key: 69})
+	render(synth, mono, 1000)
+	assert.False(t, synth.Active())
+}
+
+func TestSynthModulation(t *testing.T) {
+	const rate = 8000
+	mono := audio.Format{SampleRate: rate, Channels: 1}
+	settings := DefaultSettings()
+	settings.VibratoRate = 2
+	synth := NewSynth(settings, rate)
+	synth.Handle(midi.NoteOnMessage{Channel: 0, Key: 69, Velocity: 127})
+	synth.Handle(midi.ControlChangeMessage{
+		Channel: 0, Controller: 1, Value: 127})
+	// A quarter of the way through its cycle, the vibrato is at its peak,
+	// half a semitone up.
+	render(synth, mono, 500)
+	assert.InDelta(t, 452.89, dsp.DetectPitch(
+		render(synth, mono, 1000).Data, rate).Frequency, 4)
+}
+
+func TestSynthVolumeAndPan(t *testing.T) {
+	stereo := audio.Format{SampleRate: 8000, Channels: 2}
+	synth := NewSynth(Settings{Gain: -6}, 8000)
+	synth.Handle(midi.NoteOnMessage{Channel: 0, Key: 69, Velocity: 127})
+	synth.Handle(midi.NoteOnMessage{Channel: 1, Key: 57, Velocity: 127})
+	synth.Handle(midi.ControlChangeMessage{
+		Channel: 0, Controller: 10, Value: 0})
+	synth.Handle(midi.ControlChangeMessage{
+		Channel: 1, Controller: 10, Value: 127})
+	synth.Handle(midi.ControlChangeMessage{
+		Channel: 1, Controller: 7, Value: 127})
+	synth.Handle(midi.ControlChangeMessage{
+		Channel: 1, Controller: 11, Value: 0})
+	buffer := render(synth, stereo, 400)
+	// Channel 0 is hard left, and channel 1, hard right, is silenced by
+	// its expression.
+	var left, right float64
+	for i := 0; i < buffer.Frames(); i++ {
+		left = math.Max(left, buffer.Frame(i)[0])
+		right = math.Max(right, math.Abs(buffer.Frame(i)[1]))
+	}
+	assert.InDelta(t, audio.Decibel(-6).Linear()*math.Pow(100.0/127, 2),
+		left, 0.01)
+	assert.Equal(t, 0.0, right)
+}
+
+func TestSynthDrums(t *testing.T) {
+	mono := audio.Format{SampleRate: 8000, Channels: 1}
+	synth := NewSynth(Settings{}, 8000)
+	synth.Handle(midi.NoteOnMessage{Channel: 9, Key: 42, Velocity: 127})
+	assert.True(t, synth.Active())
+	synth.Handle(midi.NoteOffMessage{Channel: 9, Key: 42})
+	assert.NotEqual(t, 0.0, render(synth, mono, 100).Data[50])
+	// A muted percussion channel is silent.
+	synth.Handle(midi.ControlChangeMessage{
+		Channel: 9, Controller: 7, Value: 0})
+	synth.Handle(midi.NoteOnMessage{Channel: 9, Key: 42, Velocity: 127})
+	assert.Equal(t, 0.0, render(synth, mono, 100).Data[50])
+	render(synth, mono, 1000)
+	assert.False(t, synth.Active())
+}
+
+func TestSynthSettings(t *testing.T) {
+	// Without DefaultSettings, the gain is unity and there is no vibrato.
+	const rate = 8000
+	mono := audio.Format{SampleRate: rate, Channels: 1}
+	synth := NewSynth(Settings{VibratoRate: 2}, rate)
+	synth.Handle(midi.NoteOnMessage{Channel: 0, Key: 69, Velocity: 127})
+	synth.Handle(midi.ControlChangeMessage{
+		Channel: 0, Controller: 1, Value: 127})
+	// Once the envelope has decayed, the voice is at half level.
+	render(synth, mono, 4000)
+	buffer := render(synth, mono, 1000)
+	assert.InDelta(t, 440, dsp.DetectPitch(buffer.Data, rate).Frequency, 2)
+	var peak float64
+	for _, value := range buffer.Data {
+		peak = math.Max(peak, math.Abs(value))
+	}
+	assert.InDelta(t, math.Pow(100.0/127, 2)*0.5, peak, 0.01)
+	assert.Equal(t, audio.Decibel(-12), DefaultSettings().Gain)
+}
@@ -0,0 +1,273 @@
+package synth
+
+import "github.com/husafan/audio/midi"
+
+const (
+	// The following controllers change how held notes sound.
+	sustainPedal     = 64
+	allSoundOff      = 120
+	resetControllers = 121
+	allNotesOff      = 123
+	// pedalDown is the lowest value of a switch controller that turns it on.
+	pedalDown = 64
+)
+
+// StealPolicy chooses the voice an Allocator takes over when none are free.
+type StealPolicy int
+
+const (
+	// StealOldest takes the voice that started longest ago.
+	StealOldest StealPolicy = iota
+	// StealQuietest takes the voice with the lowest envelope level times
+	// velocity, which is usually a note already releasing.
+	StealQuietest
+	// StealNone drops new notes instead.
+	StealNone
+)
+
+/*
+Voice is a note sounding in an Allocator. Its Envelope is triggered when the
+note starts and released when it ends, and the voice is free for another note
+once the envelope is idle.
+*/
+type Voice struct {
+	Channel  uint8
+	Key      uint8
+	Velocity uint8
+	Envelope *Envelope
+	// held is true until the key is released and sustained while the
+	// sustain pedal keeps a released key sounding.
+	held      bool
+	sustained bool
+	started   uint64
+	// phase is the position of the voice's oscillator in its cycle.
+	phase float64
+}
+
+// Active reports whether the voice is sounding.
+func (v *Voice) Active() bool {
+	return v.Envelope.Active()
+}
+
+/*
+AllocatorSettings configures an Allocator. Voices is the total polyphony, and
+ChannelVoices, when positive, limits the voices of any one channel so that a
+busy part cannot starve the others. Steal is the policy for notes that arrive
+when no voice is free, and Envelope shapes every voice.
+*/
+type AllocatorSettings struct {
+	Voices        int              `json:"voices"`
+	ChannelVoices int              `json:"channel_voices"`
+	Steal         StealPolicy      `json:"steal"`
+	Envelope      EnvelopeSettings `json:"envelope"`
+}
+
+/*
+Allocator assigns MIDI notes to a fixed pool of voices. A repeated note on a
+key that is still sounding retriggers its voice rather than taking another,
+and the sustain pedal, controller 64, keeps released keys sounding until it is
+lifted. All Sound Off silences a channel at once, while All Notes Off releases
+its keys as if they were lifted. Stolen voices are cut at once.
+*/
+type Allocator struct {
+	settings AllocatorSettings
+	voices   []Voice
+	sustain  [16]bool
+	notes    uint64
+}
+
+// NewAllocator returns an Allocator with idle voices at the given sample rate.
+func NewAllocator(settings AllocatorSettings, sampleRate float64) *Allocator {
+	a := &Allocator{settings: settings, voices: make([]Voice, settings.Voices)}
+	for i := range a.voices {
+		a.voices[i].Envelope = NewEnvelope(settings.Envelope, sampleRate)
+	}
+	return a
+}
+
+/*
+Handle applies a channel message: note on and off, and the sustain pedal and
+channel mode controllers. Other messages are ignored.
+*/
+func (a *Allocator) Handle(message midi.ChannelMessage) {
+	switch m := message.(type) {
+	case midi.NoteOnMessage:
+		a.NoteOn(m.Channel, m.Key, m.Velocity)
+	case midi.NoteOffMessage:
+		a.NoteOff(m.Channel, m.Key)
+	case midi.ControlChangeMessage:
+		a.ControlChange(m.Channel, m.Controller, m.Value)
+	}
+}
+
+/*
+NoteOn starts key on channel and returns its voice, or nil if the note was
+dropped because no voice could be taken. A velocity of 0 is a note off.
+*/
+func (a *Allocator) NoteOn(channel, key, velocity uint8) *Voice {
+	if velocity == 0 {
+		a.NoteOff(channel, key)
+		return nil
+	}
+	voice := a.find(channel, key)
+	if voice == nil {
+		voice = a.free(channel)
+	}
+	if voice == nil {
+		return nil
+	}
+	a.notes++
+	*voice = Voice{
+		Channel:  channel,
+		Key:      key,
+		Velocity: velocity,
+		Envelope: voice.Envelope,
+		held:     true,
+		started:  a.notes,
+		phase:    voice.phase,
+	}
+	voice.Envelope.Trigger()
+	return voice
+}
+
+// find returns the voice sounding key on channel, or nil if there is none.
+func (a *Allocator) find(channel, key uint8) *Voice {
+	for i := range a.voices {
+		voice := &a.voices[i]
+		if voice.Active() && voice.Channel == channel && voice.Key == key {
+			return voice
+		}
+	}
+	return nil
+}
+
+/*
+free returns an idle voice for a new note on channel, stealing one if the pool
+or the channel's share of it is full, or nil if the policy forbids stealing.
+*/
+func (a *Allocator) free(channel uint8) *Voice {
+	var idle *Voice
+	var all, own []*Voice
+	for i := range a.voices {
+		voice := &a.voices[i]
+		if !voice.Active() {
+			if idle == nil {
+				idle = voice
+			}
+			continue
+		}
+		all = append(all, voice)
+		if voice.Channel == channel {
+			own = append(own, voice)
+		}
+	}
+	if limit := a.settings.ChannelVoices; limit > 0 && len(own) >= limit {
+		return a.steal(own)
+	}
+	if idle != nil {
+		return idle
+	}
+	return a.steal(all)
+}
+
+// steal cuts and returns a voice of candidates chosen by the policy.
+func (a *Allocator) steal(candidates []*Voice) *Voice {
+	if a.settings.Steal == StealNone || len(candidates) == 0 {
+		return nil
+	}
+	chosen := candidates[0]
+	for _, voice := range candidates[1:] {
+		switch a.settings.Steal {
+		case StealOldest:
+			if voice.started < chosen.started {
+				chosen = voice
+			}
+		case StealQuietest:
+			if voice.loudness() < chosen.loudness() {
+				chosen = voice
+			}
+		}
+	}
+	chosen.Envelope.Reset()
+	return chosen
+}
+
+// loudness estimates how much the voice contributes to the output.
+func (v *Voice) loudness() float64 {
+	return v.Envelope.Level() * float64(v.Velocity)
+}
+
+/*
+NoteOff releases key on channel, or marks it sustained while the channel's
+sustain pedal is down.
+*/
+func (a *Allocator) NoteOff(channel, key uint8) {
+	for i := range a.voices {
+		voice := &a.voices[i]
+		if !voice.held || voice.Channel != channel || voice.Key != key {
+			continue
+		}
+		voice.held = false
+		if a.sustain[channel&0x0F] {
+			voice.sustained = true
+		} else {
+			voice.Envelope.Release()
+		}
+	}
+}
+
+/*
+ControlChange applies the sustain pedal and the All Sound Off, Reset All
+Controllers and All Notes Off controllers to channel.
+*/
+func (a *Allocator) ControlChange(channel, controller, value uint8) {
+	channel &= 0x0F
+	switch controller {
+	case sustainPedal:
+		a.setSustain(channel, value >= pedalDown)
+	case resetControllers:
+		a.setSustain(channel, false)
+	case allNotesOff:
+		for i := range a.voices {
+			if voice := &a.voices[i]; voice.held && voice.Channel == channel {
+				a.NoteOff(channel, voice.Key)
+			}
+		}
+	case allSoundOff:
+		for i := range a.voices {
+			if voice := &a.voices[i]; voice.Channel == channel {
+				voice.held, voice.sustained = false, false
+				voice.Envelope.Reset()
+			}
+		}
+	}
+}
+
+// setSustain sets the sustain pedal of channel, releasing sustained voices.
+func (a *Allocator) setSustain(channel uint8, down bool) {
+	a.sustain[channel] = down
+	if down {
+		return
+	}
+	for i := range a.voices {
+		voice := &a.voices[i]
+		if voice.sustained && voice.Channel == channel {
+			voice.sustained = false
+			voice.Envelope.Release()
+		}
+	}
+}
+
+/*
+Voices returns the sounding voices, which are shared with the Allocator so that
+a renderer can step their envelopes.
+*/
+func (a *Allocator) Voices() []*Voice {
+	var voices []*Voice
+	for i := range a.voices {
+		if a.voices[i].Active() {
+			voices = append(voices, &a.voices[i])
+		}
+	}
+	return voices
+}
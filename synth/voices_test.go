@@ -0,0 +1,125 @@
+package synth_test
+
+import (
+	"testing"
+
+	"github.com/husafan/audio/midi"
+	. "github.com/husafan/audio/synth"
+	"github.com/stretchr/testify/assert"
+)
+
+// keys returns the keys of the sounding voices of allocator in pool order.
+func keys(allocator *Allocator) []uint8 {
+	var keys []uint8
+	for _, voice := range allocator.Voices() {
+		keys = append(keys, voice.Key)
+	}
+	return keys
+}
+
+var quick = EnvelopeSettings{Attack: 1, Decay: 1, Sustain: 1, Release: 10}
+
+func TestAllocatorNotes(t *testing.T) {
+	allocator := NewAllocator(AllocatorSettings{
+		Voices: 4, Envelope: quick}, 1000)
+	assert.Empty(t, allocator.Voices())
+
+	voice := allocator.NoteOn(0, 60, 100)
+	assert.Equal(t, uint8(60), voice.Key)
+	assert.Equal(t, uint8(100), voice.Velocity)
+	allocator.NoteOn(0, 64, 100)
+	assert.Equal(t, []uint8{60, 64}, keys(allocator))
+
+	// A repeated key retriggers its voice.
+	assert.Equal(t, voice, allocator.NoteOn(0, 60, 80))
+	assert.Equal(t, uint8(80), voice.Velocity)
+	assert.Len(t, allocator.Voices(), 2)
+
+	// A note on of velocity 0 releases the key, which sounds until its
+	// envelope finishes.
+	allocator.Handle(midi.NoteOnMessage{Channel: 0, Key: 60, Velocity: 0})
+	assert.Equal(t, EnvelopeRelease, voice.Envelope.Stage())
+	step(voice.Envelope, 20)
+	assert.Equal(t, []uint8{64}, keys(allocator))
+
+	allocator.Handle(midi.NoteOffMessage{Channel: 1, Key: 64})
+	assert.Equal(t, []uint8{64}, keys(allocator))
+}
+
+func TestAllocatorStealing(t *testing.T) {
+	for _, test := range []struct {
+		steal StealPolicy
+		want  []uint8
+	}{
+		{StealOldest, []uint8{67, 64, 62}},
+		{StealQuietest, []uint8{60, 67, 62}},
+		{StealNone, []uint8{60, 64, 62}},
+	} {
+		allocator := NewAllocator(AllocatorSettings{
+			Voices: 3, Steal: test.steal, Envelope: quick}, 1000)
+		allocator.NoteOn(0, 60, 100)
+		allocator.NoteOn(0, 64, 30)
+		allocator.NoteOn(0, 62, 100)
+		for _, voice := range allocator.Voices() {
+			step(voice.Envelope, 5)
+		}
+		stolen := allocator.NoteOn(0, 67, 100)
+		assert.Equal(t, test.want, keys(allocator), "policy %d", test.steal)
+		assert.Equal(t, test.steal == StealNone, stolen == nil)
+	}
+}
+
+func TestAllocatorChannelVoices(t *testing.T) {
+	allocator := NewAllocator(AllocatorSettings{
+		Voices: 4, ChannelVoices: 2, Envelope: quick}, 1000)
+	allocator.NoteOn(9, 36, 100)
+	allocator.NoteOn(9, 38, 100)
+	allocator.NoteOn(0, 60, 100)
+	// The drums are limited to two voices even with one free.
+	allocator.NoteOn(9, 42, 100)
+	assert.Equal(t, []uint8{42, 38, 60}, keys(allocator))
+}
+
+func TestAllocatorSustainPedal(t *testing.T) {
+	allocator := NewAllocator(AllocatorSettings{
+		Voices: 4, Envelope: quick}, 1000)
+	allocator.NoteOn(0, 60, 100)
+	allocator.Handle(midi.ControlChangeMessage{
+		Channel: 0, Controller: 64, Value: 127})
+	allocator.NoteOn(0, 64, 100)
+	allocator.NoteOff(0, 60)
+	allocator.NoteOff(0, 64)
+	// The pedal of another channel does not hold these notes.
+	allocator.NoteOn(1, 48, 100)
+	allocator.NoteOff(1, 48)
+	for _, voice := range allocator.Voices() {
+		step(voice.Envelope, 20)
+	}
+	assert.Equal(t, []uint8{60, 64}, keys(allocator))
+
+	allocator.ControlChange(0, 64, 0)
+	for _, voice := range allocator.Voices() {
+		assert.Equal(t, EnvelopeRelease, voice.Envelope.Stage())
+		step(voice.Envelope, 20)
+	}
+	assert.Empty(t, allocator.Voices())
+}
+
+func TestAllocatorChannelMode(t *testing.T) {
+	allocator := NewAllocator(AllocatorSettings{
+		Voices: 4, Envelope: quick}, 1000)
+	allocator.NoteOn(0, 60, 100)
+	allocator.NoteOn(0, 64, 100)
+	allocator.NoteOn(1, 48, 100)
+
+	// All Notes Off releases the keys, still sustained by the pedal.
+	allocator.ControlChange(0, 64, 127)
+	allocator.ControlChange(0, 123, 0)
+	assert.Len(t, allocator.Voices(), 3)
+	allocator.ControlChange(0, 121, 0)
+	assert.Equal(t, EnvelopeRelease, allocator.Voices()[0].Envelope.Stage())
+
+	// All Sound Off cuts the channel at once.
+	allocator.ControlChange(0, 120, 0)
+	assert.Equal(t, []uint8{48}, keys(allocator))
+}
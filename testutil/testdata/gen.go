@@ -0,0 +1,216 @@
+//go:build ignore
+
+/*
+gen writes the corpus of WAV and MIDI files used by the testutil tests. Most
+files are written by the library itself. Those named foreign_ are laid out by
+hand to imitate the way other tools write them, so they round trip without
+coming back byte for byte; they are not files from those tools. Run it from
+this directory with:
+
+	go run gen.go
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log"
+	"math"
+	"os"
+
+	"github.com/husafan/audio"
+	"github.com/husafan/audio/midi"
+	"github.com/husafan/audio/wav"
+)
+
+func main() {
+	for name, file := range map[string]func() ([]byte, error){
+		"pcm8_mono.wav":         pcmFile(1, 8, 8000, nil),
+		"pcm16_stereo_meta.wav": pcmFile(2, 16, 44100, metadata),
+		"pcm24_mono.wav":        pcmFile(1, 24, 48000, nil),
+		"float32_stereo.wav":    floatFile,
+		"foreign_junk_list.wav": foreignWav,
+		"format0.mid":           format0,
+		"foreign_running.mid":   foreignMidi,
+	} {
+		data, err := file()
+		if err == nil {
+			err = os.WriteFile(name, data, 0644)
+		}
+		if err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+	}
+}
+
+// sine returns 64 frames of a quiet sine wave in format.
+func sine(format audio.Format) *audio.Buffer {
+	buffer := audio.NewBuffer(format, 64)
+	for i := 0; i < buffer.Frames(); i++ {
+		for channel := range buffer.Frame(i) {
+			buffer.Frame(i)[channel] = 0.5 * math.Sin(
+				float64(i+channel)*2*math.Pi/16)
+		}
+	}
+	return buffer
+}
+
+/*
+metadata adds a cue point to a WavWriter. pcmFile also gives the files it is
+used for a title.
+*/
+func metadata(writer *wav.WavWriter) error {
+	return writer.AddCuePoint(wav.CuePoint{Id: 1, Position: 16,
+		Label: "Marker"})
+}
+
+// writeFile writes the sine wave to a file of the given fmt chunk.
+func writeFile(fmtChunk *wav.FmtChunk, extra func(*wav.WavWriter) error,
+	options ...wav.WriterOption) ([]byte, error) {
+	output := &memory{}
+	writer, err := wav.NewWavWriter(output, fmtChunk, options...)
+	if err != nil {
+		return nil, err
+	}
+	if err := writer.WriteBuffer(sine(fmtChunk.Format())); err != nil {
+		return nil, err
+	}
+	if extra != nil {
+		if err := extra(writer); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return output.data, nil
+}
+
+// pcmFile returns a generator of an integer PCM file.
+func pcmFile(channels, bits uint16, rate uint32,
+	extra func(*wav.WavWriter) error) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		fmtChunk := wav.NewDefaultFmtChunk()
+		fmtChunk.NumChannels = channels
+		fmtChunk.BitsPerSample = bits
+		fmtChunk.SampleRate = rate
+		fmtChunk.BlockAlign = channels * bits / 8
+		fmtChunk.ByteRate = rate * uint32(fmtChunk.BlockAlign)
+		var options []wav.WriterOption
+		if extra != nil {
+			options = append(options, wav.WithMetadata(
+				wav.Metadata{wav.InfoTitle: "Corpus"}))
+		}
+		return writeFile(fmtChunk, extra, options...)
+	}
+}
+
+func floatFile() ([]byte, error) {
+	fmtChunk := wav.NewDefaultFmtChunk()
+	fmtChunk.AudioFormat = wav.FormatIEEEFloat
+	fmtChunk.BitsPerSample = 32
+	fmtChunk.BlockAlign = 8
+	fmtChunk.ByteRate = 44100 * 8
+	return writeFile(fmtChunk, nil)
+}
+
+/*
+foreignWav lays out a 16 bit mono file with a JUNK chunk before the fmt chunk
+and an odd sized LIST chunk after the samples.
+*/
+func foreignWav() ([]byte, error) {
+	var body bytes.Buffer
+	body.WriteString("WAVE")
+	chunk(&body, "JUNK", make([]byte, 28))
+	fmtBody := new(bytes.Buffer)
+	binary.Write(fmtBody, binary.LittleEndian, []uint16{1, 1})
+	binary.Write(fmtBody, binary.LittleEndian, []uint32{22050, 44100})
+	binary.Write(fmtBody, binary.LittleEndian, []uint16{2, 16})
+	chunk(&body, "fmt ", fmtBody.Bytes())
+	samples := new(bytes.Buffer)
+	for i := 0; i < 32; i++ {
+		binary.Write(samples, binary.LittleEndian, int16(i*1000-16000))
+	}
+	chunk(&body, "data", samples.Bytes())
+	info := new(bytes.Buffer)
+	info.WriteString("INFO")
+	chunk(info, "INAM", []byte("Odd\x00"))
+	chunk(info, "ISFT", []byte("Tool\x00"))
+	chunk(&body, "LIST", info.Bytes())
+	var file bytes.Buffer
+	chunk(&file, "RIFF", body.Bytes())
+	return file.Bytes(), nil
+}
+
+// chunk writes a RIFF chunk and its pad byte to buffer.
+func chunk(buffer *bytes.Buffer, id string, data []byte) {
+	buffer.WriteString(id)
+	binary.Write(buffer, binary.LittleEndian, uint32(len(data)))
+	buffer.Write(data)
+	if len(data)%2 != 0 {
+		buffer.WriteByte(0)
+	}
+}
+
+// format0 marshals a single track with a tempo, a name and a few notes.
+func format0() ([]byte, error) {
+	track := midi.TrackChunk{Chunk: &midi.Chunk{}}
+	track.TrackEvents = append(track.TrackEvents,
+		midi.TrackEvent{Data: []byte{midi.MetaEvent, midi.MetaTrackName,
+			'C', 'o', 'r', 'p', 'u', 's'}},
+		midi.TrackEvent{Data: []byte{midi.MetaEvent, midi.MetaSetTempo,
+			0x07, 0xA1, 0x20}},
+		midi.TrackEvent{Data: []byte{midi.ProgramChange, 5}})
+	for _, key := range []byte{60, 62, 64, 65, 67} {
+		track.TrackEvents = append(track.TrackEvents,
+			midi.TrackEvent{Data: []byte{midi.NoteOnEvent, key, 90}},
+			midi.TrackEvent{DeltaTime: 240,
+				Data: []byte{midi.NoteOffEvent, key, 64}})
+	}
+	track.TrackEvents = append(track.TrackEvents, midi.TrackEvent{
+		Data: []byte{midi.MetaEvent, midi.MetaEndOfTrack}})
+	return (&midi.Midi{
+		HeaderChunk: &midi.HeaderChunk{Chunk: &midi.Chunk{}, Division: 480},
+		TrackChunks: []midi.TrackChunk{track},
+	}).MarshalBinary()
+}
+
+/*
+foreignMidi lays out a format 1 file with a tempo track and a note track that
+uses running status and Note On events with a velocity of 0 as note offs.
+*/
+func foreignMidi() ([]byte, error) {
+	var file bytes.Buffer
+	file.WriteString("MThd")
+	binary.Write(&file, binary.BigEndian, []uint32{6})
+	binary.Write(&file, binary.BigEndian, []uint16{1, 2, 96})
+	for _, track := range [][]byte{
+		{0x00, 0xFF, 0x51, 0x03, 0x09, 0x27, 0xC0,
+			0x00, 0xFF, 0x58, 0x04, 0x04, 0x02, 0x18, 0x08,
+			0x00, 0xFF, 0x2F, 0x00},
+		{0x00, 0x90, 0x3C, 0x50,
+			0x60, 0x3C, 0x00,
+			0x00, 0x40, 0x50,
+			0x60, 0x40, 0x00,
+			0x00, 0xB0, 0x40, 0x7F,
+			0x30, 0x40, 0x00,
+			0x00, 0xFF, 0x2F, 0x00},
+	} {
+		file.WriteString("MTrk")
+		binary.Write(&file, binary.BigEndian, uint32(len(track)))
+		file.Write(track)
+	}
+	return file.Bytes(), nil
+}
+
+// memory is an io.WriterAt that grows a byte slice as it is written.
+type memory struct {
+	data []byte
+}
+
+func (m *memory) WriteAt(p []byte, offset int64) (int, error) {
+	if end := int(offset) + len(p); end > len(m.data) {
+		m.data = append(m.data, make([]byte, end-len(m.data))...)
+	}
+	return copy(m.data[offset:], p), nil
+}
@@ -0,0 +1,195 @@
+/*
+The testutil package helps test code built on the format packages. LoadCorpus
+reads a directory of sample files, and RoundTripWav and RoundTripMidi decode a
+file, encode it again and check that nothing was lost. The package's testdata
+directory holds a small corpus of WAV and MIDI files covering common layouts,
+which its own tests round trip. Every file in it is synthesized by
+testdata/gen.go; those named foreign_ imitate how other tools lay files out,
+but none was written by another tool, so callers should round trip their own
+files too.
+*/
+package testutil
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/husafan/audio/midi"
+	"github.com/husafan/audio/wav"
+)
+
+// CorpusFile is a file loaded by LoadCorpus.
+type CorpusFile struct {
+	Name string
+	Data []byte
+}
+
+/*
+LoadCorpus reads the files matching pattern, as understood by filepath.Glob,
+sorted by name. tb fails if the pattern is malformed, matches nothing or a file
+cannot be read.
+*/
+func LoadCorpus(tb testing.TB, pattern string) []CorpusFile {
+	tb.Helper()
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if len(paths) == 0 {
+		tb.Fatalf("no files match %q", pattern)
+	}
+	files := make([]CorpusFile, len(paths))
+	for i, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		files[i] = CorpusFile{Name: filepath.Base(path), Data: data}
+	}
+	return files
+}
+
+/*
+RoundTripWav decodes the WAV file in data and encodes it again with the same
+format, metadata, bext and smpl chunks and cue points, returning the new file.
+tb fails if the file cannot be decoded or encoded, or if any of those or the
+raw sample bytes differ once the new file is decoded. Files written by this
+library come back byte for byte; compare the result with data to check that.
+*/
+func RoundTripWav(tb testing.TB, data []byte) []byte {
+	tb.Helper()
+	original, samples, err := decodeWav(data)
+	if err != nil {
+		tb.Fatalf("decoding: %v", err)
+	}
+	var options []wav.WriterOption
+	if len(original.Metadata) > 0 {
+		options = append(options, wav.WithMetadata(original.Metadata))
+	}
+	if original.Broadcast != nil {
+		options = append(options, wav.WithBroadcastExtension(original.Broadcast))
+	}
+	if original.Sampler != nil {
+		options = append(options, wav.WithSampler(original.Sampler))
+	}
+	fmtChunk := wav.NewDefaultFmtChunk()
+	fmtChunk.AudioFormat = original.Fmt.AudioFormat
+	fmtChunk.NumChannels = original.Fmt.NumChannels
+	fmtChunk.SampleRate = original.Fmt.SampleRate
+	fmtChunk.ByteRate = original.Fmt.ByteRate
+	fmtChunk.BlockAlign = original.Fmt.BlockAlign
+	fmtChunk.BitsPerSample = original.Fmt.BitsPerSample
+
+	output := &memoryWriterAt{}
+	writer, err := wav.NewWavWriter(output, fmtChunk, options...)
+	if err == nil {
+		_, err = writer.PCMWriter().Write(samples)
+	}
+	for _, cue := range original.CuePoints() {
+		if err == nil {
+			err = writer.AddCuePoint(cue)
+		}
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		tb.Fatalf("encoding: %v", err)
+	}
+
+	encoded := output.data
+	decoded, decodedSamples, err := decodeWav(encoded)
+	if err != nil {
+		tb.Fatalf("decoding the encoded file: %v", err)
+	}
+	for _, field := range []struct {
+		name          string
+		before, after interface{}
+	}{
+		{"format", original.Fmt.Format(), decoded.Fmt.Format()},
+		{"audio format", original.Fmt.AudioFormat, decoded.Fmt.AudioFormat},
+		{"bits per sample",
+			original.Fmt.BitsPerSample, decoded.Fmt.BitsPerSample},
+		{"metadata", original.Metadata, decoded.Metadata},
+		{"bext chunk", original.Broadcast, decoded.Broadcast},
+		{"smpl chunk", original.Sampler, decoded.Sampler},
+		{"cue points", original.CuePoints(), decoded.CuePoints()},
+	} {
+		if !reflect.DeepEqual(field.before, field.after) {
+			tb.Errorf("%s changed from %+v to %+v",
+				field.name, field.before, field.after)
+		}
+	}
+	if !bytes.Equal(samples, decodedSamples) {
+		tb.Errorf("samples changed: %v bytes before, %v after",
+			len(samples), len(decodedSamples))
+	}
+	return encoded
+}
+
+// decodeWav reads the header and raw sample bytes of a WAV file.
+func decodeWav(data []byte) (*wav.WavReader, []byte, error) {
+	reader, err := wav.NewWavReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	// Reading every sample also reads any chunks following them.
+	samples, err := io.ReadAll(reader.PCMReader())
+	return reader, samples, err
+}
+
+/*
+RoundTripMidi parses the MIDI file in data and marshals it again, returning the
+new file. tb fails if either step fails, or if the format, division or events of
+any track differ once the new file is parsed. Files without running status come
+back byte for byte; compare the result with data to check that.
+*/
+func RoundTripMidi(tb testing.TB, data []byte) []byte {
+	tb.Helper()
+	original, err := midi.ParseMidi(data, nil)
+	if err != nil {
+		tb.Fatalf("parsing: %v", err)
+	}
+	encoded, err := original.MarshalBinary()
+	if err != nil {
+		tb.Fatalf("marshaling: %v", err)
+	}
+	parsed, err := midi.ParseMidi(encoded, nil)
+	if err != nil {
+		tb.Fatalf("parsing the marshaled file: %v", err)
+	}
+	if original.Format != parsed.Format || original.Division != parsed.Division {
+		tb.Errorf("header changed from format %v, division %v "+
+			"to format %v, division %v", original.Format, original.Division,
+			parsed.Format, parsed.Division)
+	}
+	if len(original.TrackChunks) != len(parsed.TrackChunks) {
+		tb.Fatalf("track count changed from %v to %v",
+			len(original.TrackChunks), len(parsed.TrackChunks))
+	}
+	for i := range original.TrackChunks {
+		before := original.TrackChunks[i].TrackEvents
+		after := parsed.TrackChunks[i].TrackEvents
+		if !reflect.DeepEqual(before, after) {
+			tb.Errorf("events of track %v changed from %v to %v",
+				i, before, after)
+		}
+	}
+	return encoded
+}
+
+// memoryWriterAt is an io.WriterAt that grows a byte slice as it is written.
+type memoryWriterAt struct {
+	data []byte
+}
+
+func (m *memoryWriterAt) WriteAt(p []byte, offset int64) (int, error) {
+	if end := int(offset) + len(p); end > len(m.data) {
+		m.data = append(m.data, make([]byte, end-len(m.data))...)
+	}
+	return copy(m.data[offset:], p), nil
+}
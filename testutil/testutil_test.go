@@ -0,0 +1,40 @@
+package testutil_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/husafan/audio/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundTripWavCorpus(t *testing.T) {
+	for _, file := range LoadCorpus(t, "testdata/*.wav") {
+		t.Run(file.Name, func(t *testing.T) {
+			encoded := RoundTripWav(t, file.Data)
+			// Only files laid out by other tools may change.
+			if !strings.HasPrefix(file.Name, "foreign_") {
+				assert.True(t, bytes.Equal(file.Data, encoded))
+			}
+		})
+	}
+}
+
+func TestRoundTripMidiCorpus(t *testing.T) {
+	for _, file := range LoadCorpus(t, "testdata/*.mid") {
+		t.Run(file.Name, func(t *testing.T) {
+			encoded := RoundTripMidi(t, file.Data)
+			if !strings.HasPrefix(file.Name, "foreign_") {
+				assert.True(t, bytes.Equal(file.Data, encoded))
+			}
+		})
+	}
+}
+
+func TestLoadCorpus(t *testing.T) {
+	files := LoadCorpus(t, "testdata/*.mid")
+	assert.Equal(t, 2, len(files))
+	assert.Equal(t, "foreign_running.mid", files[0].Name)
+	assert.Equal(t, "MThd", string(files[0].Data[:4]))
+}
@@ -0,0 +1,44 @@
+package audio
+
+import (
+	"context"
+	"time"
+)
+
+/*
+Throttle slows down batch operations so they can run beside latency sensitive
+services without starving them. Pause is slept after each block an operation
+processes, and Workers caps the goroutines it spreads its work over. Zero values
+leave an operation unthrottled. Operations take one in their options, such as
+wav.ContextOptions.
+*/
+type Throttle struct {
+	Pause   time.Duration
+	Workers int
+}
+
+/*
+Wait sleeps for the Pause of the Throttle. It returns early with the context's
+error if ctx is done first.
+*/
+func (t Throttle) Wait(ctx context.Context) error {
+	if t.Pause <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(t.Pause)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Limit returns workers, capped to the Workers of the Throttle if it is set.
+func (t Throttle) Limit(workers int) int {
+	if t.Workers > 0 && t.Workers < workers {
+		return t.Workers
+	}
+	return workers
+}
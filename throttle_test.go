@@ -0,0 +1,31 @@
+package audio_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottleWait(t *testing.T) {
+	// The zero Throttle does not pause.
+	assert.Nil(t, Throttle{}.Wait(context.Background()))
+
+	throttle := Throttle{Pause: 20 * time.Millisecond}
+	start := time.Now()
+	assert.Nil(t, throttle.Wait(context.Background()))
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, Throttle{Pause: time.Hour}.Wait(ctx))
+}
+
+func TestThrottleLimit(t *testing.T) {
+	assert.Equal(t, 8, Throttle{}.Limit(8))
+	throttle := Throttle{Workers: 2}
+	assert.Equal(t, 2, throttle.Limit(8))
+	assert.Equal(t, 1, throttle.Limit(1))
+}
@@ -0,0 +1,124 @@
+package wav
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	AppendFormatError  = "cannot append to %s files"
+	AppendTrailerError = "cannot append to a file with a %s chunk after its data"
+)
+
+/*
+OpenForAppend returns a WavWriter that adds samples to the end of the WAV file
+in rw, so that a recorder can resume an interrupted recording. The headers are
+parsed from the start of rw, and the sizes are rewritten to be consistent before
+it returns.
+
+A data chunk with a size of 0, as left by a recorder that was interrupted, or
+one that runs past the end of rw, is taken to extend to the end of rw. A partial
+frame at the end of the data chunk is overwritten. Cue points following the data
+chunk are kept and written after the added samples by Flush and Close, but files
+with other chunks after the data chunk, such as a hash chain, are rejected.
+RF64 files, and files written WithRF64, can still grow past 4 GB.
+*/
+func OpenForAppend(rw io.ReadWriteSeeker) (*WavWriter, error) {
+	if _, err := rw.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	reader, err := NewWavReader(rw)
+	if err != nil {
+		return nil, err
+	}
+	if string(reader.Riff.Id[:]) == wave64Riff {
+		return nil, fmt.Errorf(AppendFormatError, "Wave64")
+	}
+	end, err := rw.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	dataSize := reader.dataSize()
+	available := uint64(end - reader.dataStart)
+	trailing := dataSize > 0 && dataSize < available
+	if dataSize == 0 || dataSize > available {
+		dataSize = available
+	}
+	if frameSize := uint64(reader.Fmt.BlockAlign); frameSize > 0 {
+		dataSize -= dataSize % frameSize
+	}
+
+	if trailing {
+		if err := reader.readTrailer(); err != nil {
+			return nil, err
+		}
+	}
+	reserved, err := hasReservedDs64(rw)
+	if err != nil {
+		return nil, err
+	}
+
+	var output io.WriterAt = seekWriterAt{rw}
+	if writerAt, ok := rw.(io.WriterAt); ok {
+		output = writerAt
+	}
+	reader.Data.Samples = nil
+	w := &WavWriter{
+		Wav:         reader.Wav,
+		buffer:      output,
+		dataOffset:  reader.dataStart,
+		reserveDs64: reserved || reader.Ds64 != nil,
+	}
+	w.cueChunks = w.encodeCuePoints()
+	w.setDataSize(dataSize)
+	return w, w.writeTrailer()
+}
+
+/*
+readTrailer reads the chunks following the data chunk, returning an error for
+chunks that a WavWriter could not write back after appended samples.
+*/
+func (w *WavReader) readTrailer() error {
+	metadata, id3 := len(w.Metadata), w.ID3
+	if err := w.seekData(int64(w.dataSize())); err != nil {
+		return err
+	}
+	w.finish()
+	switch {
+	case w.HashChain != nil:
+		return fmt.Errorf(AppendTrailerError, HashChain)
+	case len(w.Metadata) != metadata:
+		return fmt.Errorf(AppendTrailerError, List)
+	case w.ID3 != id3:
+		return fmt.Errorf(AppendTrailerError, "id3")
+	}
+	return nil
+}
+
+/*
+hasReservedDs64 reports whether the file in reader starts with the JUNK chunk
+reserved for a ds64 chunk by WithRF64.
+*/
+func hasReservedDs64(reader io.ReadSeeker) (bool, error) {
+	if _, err := reader.Seek(ds64Offset, io.SeekStart); err != nil {
+		return false, err
+	}
+	var header SubChunk
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return false, err
+	}
+	return string(header.Id[:]) == Junk && header.Size == ds64Size, nil
+}
+
+// seekWriterAt implements io.WriterAt by seeking an io.WriteSeeker.
+type seekWriterAt struct {
+	io.WriteSeeker
+}
+
+func (s seekWriterAt) WriteAt(p []byte, offset int64) (int, error) {
+	if _, err := s.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return s.Write(p)
+}
@@ -0,0 +1,118 @@
+package wav_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryFile is an io.ReadWriteSeeker that is not an io.WriterAt.
+type memoryFile struct {
+	data   []byte
+	offset int64
+}
+
+func (m *memoryFile) Read(p []byte) (int, error) {
+	if m.offset >= int64(len(m.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, m.data[m.offset:])
+	m.offset += int64(n)
+	return n, nil
+}
+
+func (m *memoryFile) Write(p []byte) (int, error) {
+	if grow := m.offset + int64(len(p)) - int64(len(m.data)); grow > 0 {
+		m.data = append(m.data, make([]byte, grow)...)
+	}
+	n := copy(m.data[m.offset:], p)
+	m.offset += int64(n)
+	return n, nil
+}
+
+func (m *memoryFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += m.offset
+	case io.SeekEnd:
+		offset += int64(len(m.data))
+	}
+	m.offset = offset
+	return offset, nil
+}
+
+func TestOpenForAppend(t *testing.T) {
+	file := &memoryFile{data: newWavFile(t, nil, Sample{{1, 2}, {3, 4}})}
+	wavWriter, err := OpenForAppend(file)
+	assert.Nil(t, err)
+	assert.Nil(t, wavWriter.AddSample(Sample{{5, 6}, {7, 8}}))
+	assert.Nil(t, wavWriter.Close())
+	assert.Equal(t, uint32(8), wavWriter.Data.Size)
+	assert.Equal(t, []Sample{{{1, 2}, {3, 4}}, {{5, 6}, {7, 8}}},
+		readAllSamples(t, file.data[:wavWriter.Riff.Size+8]))
+}
+
+func TestOpenForAppendInterrupted(t *testing.T) {
+	// Buffered samples are written without the sizes, as if the recorder
+	// stopped before Flush, and a partial frame is left at the end.
+	writer := &mockWriterAtCloser{make([]byte, 100)}
+	wavWriter, err := NewWavWriter(writer, nil, WithBufferSize(4))
+	assert.Nil(t, err)
+	assert.Nil(t, wavWriter.AddSample(Sample{{1, 2}, {3, 4}}))
+	file := &memoryFile{data: append(writer.data[:48], 9, 9)}
+
+	wavWriter, err = OpenForAppend(file)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(4), wavWriter.Data.Size)
+	assert.Nil(t, wavWriter.AddSample(Sample{{5, 6}, {7, 8}}))
+	assert.Equal(t, []Sample{{{1, 2}, {3, 4}}, {{5, 6}, {7, 8}}},
+		readAllSamples(t, file.data))
+}
+
+func TestOpenForAppendCuePoints(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cues.wav")
+	writer, err := NewAtomicWavWriter(path, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.AddSample(Sample{{1, 2}, {3, 4}}))
+	assert.Nil(t, writer.AddCuePoint(CuePoint{Id: 1, Label: "Take 1"}))
+	assert.Nil(t, writer.Finalize())
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	assert.Nil(t, err)
+	wavWriter, err := OpenForAppend(file)
+	assert.Nil(t, err)
+	assert.Nil(t, wavWriter.AddSample(Sample{{5, 6}, {7, 8}}))
+	assert.Nil(t, wavWriter.AddCuePoint(
+		CuePoint{Id: 2, Position: 1, Label: "Take 2"}))
+	assert.Nil(t, wavWriter.Close())
+	assert.Nil(t, file.Close())
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []Sample{{{1, 2}, {3, 4}}, {{5, 6}, {7, 8}}},
+		readAllSamples(t, data))
+	reader, err := NewWavReader(bytes.NewReader(data))
+	assert.Nil(t, err)
+	_, err = io.Copy(io.Discard, reader.PCMReader())
+	assert.Nil(t, err)
+	assert.Equal(t, []CuePoint{{Id: 1, Label: "Take 1"},
+		{Id: 2, Position: 1, Label: "Take 2"}}, reader.CuePoints())
+}
+
+func TestOpenForAppendHashChain(t *testing.T) {
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := NewWavWriter(writer, nil, WithHashChain(1))
+	assert.Nil(t, err)
+	assert.Nil(t, wavWriter.AddSample(Sample{{1, 2}, {3, 4}}))
+	assert.Nil(t, wavWriter.Flush())
+	_, err = OpenForAppend(
+		&memoryFile{data: writer.data[:wavWriter.Riff.Size+8]})
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"cannot append to a file with a hash chunk").FindString(err.Error()))
+}
@@ -0,0 +1,77 @@
+package wav
+
+import (
+	"os"
+	"path/filepath"
+)
+
+/*
+AtomicWavWriter is a WavWriter that writes to a temporary file in the directory
+of its destination, and only moves it into place when Finalize is called. An
+interrupted export therefore never leaves a half-written file at the destination
+for players to pick up.
+*/
+type AtomicWavWriter struct {
+	*WavWriter
+	file *os.File
+	path string
+}
+
+/*
+NewAtomicWavWriter returns an AtomicWavWriter that will save a WAV file to path.
+The temporary file is created alongside path, so the final rename never crosses
+file systems. The fmt chunk and options are passed to NewWavWriter.
+*/
+func NewAtomicWavWriter(
+	path string, fmt *FmtChunk, options ...WriterOption) (*AtomicWavWriter, error) {
+	file, err := os.CreateTemp(
+		filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	wavWriter, err := NewWavWriter(file, fmt, options...)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return &AtomicWavWriter{WavWriter: wavWriter, file: file, path: path}, nil
+}
+
+/*
+Finalize closes the WavWriter, unless it has already been closed, flushes the
+temporary file to disk and renames it to the destination, replacing any existing
+file. If anything fails, the temporary file is removed and the destination is
+left untouched.
+*/
+func (a *AtomicWavWriter) Finalize() error {
+	var err error
+	if !a.closed {
+		err = a.Close()
+	}
+	if err == nil {
+		err = a.file.Sync()
+	}
+	if err == nil {
+		err = a.file.Chmod(0644)
+	}
+	if closeErr := a.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(a.file.Name(), a.path)
+	}
+	if err != nil {
+		os.Remove(a.file.Name())
+	}
+	return err
+}
+
+/*
+Abort discards the temporary file without touching the destination. It should
+be called when the export fails before Finalize.
+*/
+func (a *AtomicWavWriter) Abort() error {
+	a.file.Close()
+	return os.Remove(a.file.Name())
+}
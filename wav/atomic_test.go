@@ -0,0 +1,54 @@
+package wav_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtomicWavWriterFinalize(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "out.wav")
+	writer, err := NewAtomicWavWriter(path, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.AddSample(Sample{{1, 2}, {3, 4}}))
+
+	// Nothing appears at the destination until the writer is finalized.
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	assert.Nil(t, writer.Finalize())
+
+	file, err := os.Open(path)
+	assert.Nil(t, err)
+	defer file.Close()
+	reader, err := NewWavReader(file)
+	assert.Nil(t, err)
+	sample, err := reader.GetSample()
+	assert.Nil(t, err)
+	assert.Equal(t, Sample{{1, 2}, {3, 4}}, sample)
+
+	entries, err := os.ReadDir(directory)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+}
+
+func TestAtomicWavWriterAbort(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "out.wav")
+	assert.Nil(t, os.WriteFile(path, []byte("previous"), 0644))
+
+	writer, err := NewAtomicWavWriter(path, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.AddSample(Sample{{1, 2}, {3, 4}}))
+	assert.Nil(t, writer.Abort())
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "previous", string(data))
+	entries, err := os.ReadDir(directory)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+}
@@ -0,0 +1,185 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	Bext          = "bext"
+	BextSizeError = "bext chunk of %v bytes is smaller than the %v byte minimum"
+	BextTextError = "bext %s of %v bytes exceeds its %v byte field"
+)
+
+/*
+bextFields is the fixed size portion of a bext chunk as laid out in the file by
+EBU Tech 3285, allowing it to be read and written with a single binary call.
+*/
+type bextFields struct {
+	Description          [256]byte
+	Originator           [32]byte
+	OriginatorReference  [32]byte
+	OriginationDate      [10]byte
+	OriginationTime      [8]byte
+	TimeReferenceLow     uint32
+	TimeReferenceHigh    uint32
+	Version              uint16
+	UMID                 [64]byte
+	LoudnessValue        int16
+	LoudnessRange        int16
+	MaxTruePeakLevel     int16
+	MaxMomentaryLoudness int16
+	MaxShortTermLoudness int16
+	Reserved             [180]byte
+}
+
+/*
+BextChunk holds the Broadcast Wave Format extension chunk, which carries
+metadata used by broadcasters and pro-audio tools:
+
+	Description:         free text description of the sound, up to 256 bytes.
+	Originator:          name of the originator, up to 32 bytes.
+	OriginatorReference: unique reference assigned by the originator.
+	OriginationDate:     yyyy-mm-dd.
+	OriginationTime:     hh-mm-ss.
+	TimeReference:       first sample's position, in samples since midnight.
+	Version:             version of the bext chunk; 2 includes loudness.
+	UMID:                SMPTE unique material identifier.
+	CodingHistory:       lines describing each coding process applied.
+
+Loudness values are stored as 100 times their LUFS, LU or dB value.
+*/
+type BextChunk struct {
+	Description          string
+	Originator           string
+	OriginatorReference  string
+	OriginationDate      string
+	OriginationTime      string
+	TimeReference        uint64
+	Version              uint16
+	UMID                 [64]byte
+	LoudnessValue        int16
+	LoudnessRange        int16
+	MaxTruePeakLevel     int16
+	MaxMomentaryLoudness int16
+	MaxShortTermLoudness int16
+	CodingHistory        string
+}
+
+/*
+TimeReferenceDuration returns the TimeReference as a time since midnight, given
+the sample rate of the file.
+*/
+func (b *BextChunk) TimeReferenceDuration(sampleRate uint32) time.Duration {
+	if sampleRate == 0 {
+		return 0
+	}
+	seconds := b.TimeReference / uint64(sampleRate)
+	remainder := b.TimeReference % uint64(sampleRate)
+	return time.Duration(seconds)*time.Second +
+		time.Duration(remainder)*time.Second/time.Duration(sampleRate)
+}
+
+/*
+WithBroadcastExtension returns a WriterOption that writes bext as a bext chunk.
+A non-nil error is returned if a text field does not fit in the chunk.
+*/
+func WithBroadcastExtension(bext *BextChunk) WriterOption {
+	return func(w *WavWriter) error {
+		if _, err := bext.encode(); err != nil {
+			return err
+		}
+		w.Broadcast = bext
+		return nil
+	}
+}
+
+/*
+readBextChunk parses the body of a bext chunk of the given size into the Wav's
+Broadcast field.
+*/
+func (w *Wav) readBextChunk(reader io.Reader, size uint32) error {
+	var fields bextFields
+	if minimum := binary.Size(fields); int(size) < minimum {
+		return fmt.Errorf(BextSizeError, size, minimum)
+	}
+	if err := binary.Read(reader, binary.LittleEndian, &fields); err != nil {
+		return err
+	}
+	history, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	w.Broadcast = &BextChunk{
+		Description:         fixedString(fields.Description[:]),
+		Originator:          fixedString(fields.Originator[:]),
+		OriginatorReference: fixedString(fields.OriginatorReference[:]),
+		OriginationDate:     fixedString(fields.OriginationDate[:]),
+		OriginationTime:     fixedString(fields.OriginationTime[:]),
+		TimeReference: uint64(fields.TimeReferenceHigh)<<32 |
+			uint64(fields.TimeReferenceLow),
+		Version:              fields.Version,
+		UMID:                 fields.UMID,
+		LoudnessValue:        fields.LoudnessValue,
+		LoudnessRange:        fields.LoudnessRange,
+		MaxTruePeakLevel:     fields.MaxTruePeakLevel,
+		MaxMomentaryLoudness: fields.MaxMomentaryLoudness,
+		MaxShortTermLoudness: fields.MaxShortTermLoudness,
+		CodingHistory:        fixedString(history),
+	}
+	return nil
+}
+
+/*
+encode returns the body of a bext chunk. A non-nil error is returned if a text
+field is longer than the space the chunk reserves for it.
+*/
+func (b *BextChunk) encode() ([]byte, error) {
+	fields := bextFields{
+		TimeReferenceLow:     uint32(b.TimeReference),
+		TimeReferenceHigh:    uint32(b.TimeReference >> 32),
+		Version:              b.Version,
+		UMID:                 b.UMID,
+		LoudnessValue:        b.LoudnessValue,
+		LoudnessRange:        b.LoudnessRange,
+		MaxTruePeakLevel:     b.MaxTruePeakLevel,
+		MaxMomentaryLoudness: b.MaxMomentaryLoudness,
+		MaxShortTermLoudness: b.MaxShortTermLoudness,
+	}
+	for _, field := range []struct {
+		name  string
+		value string
+		into  []byte
+	}{
+		{"description", b.Description, fields.Description[:]},
+		{"originator", b.Originator, fields.Originator[:]},
+		{"originator reference", b.OriginatorReference,
+			fields.OriginatorReference[:]},
+		{"origination date", b.OriginationDate, fields.OriginationDate[:]},
+		{"origination time", b.OriginationTime, fields.OriginationTime[:]},
+	} {
+		if len(field.value) > len(field.into) {
+			return nil, fmt.Errorf(
+				BextTextError, field.name, len(field.value), len(field.into))
+		}
+		copy(field.into, field.value)
+	}
+	buffer := new(bytes.Buffer)
+	binary.Write(buffer, binary.LittleEndian, &fields)
+	buffer.WriteString(b.CodingHistory)
+	return buffer.Bytes(), nil
+}
+
+/*
+fixedString returns the text held in a fixed size field, which is padded with
+NUL bytes when the text is shorter than the field.
+*/
+func fixedString(field []byte) string {
+	if end := bytes.IndexByte(field, 0); end >= 0 {
+		field = field[:end]
+	}
+	return string(field)
+}
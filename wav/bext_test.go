@@ -0,0 +1,76 @@
+package wav_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroadcastExtensionRoundTrip(t *testing.T) {
+	bext := &BextChunk{
+		Description:         "Interview",
+		Originator:          "Station",
+		OriginatorReference: "REF-0001",
+		OriginationDate:     "2014-06-01",
+		OriginationTime:     "12-30-00",
+		// One hour past midnight at 44.1kHz, beyond 32 bits of samples
+		// with the high word set.
+		TimeReference:    1<<32 + 158760000,
+		Version:          2,
+		LoudnessValue:    -2300,
+		MaxTruePeakLevel: -100,
+		CodingHistory:    "A=PCM,F=44100,W=16,M=stereo,T=original\r\n",
+	}
+	bext.UMID[0] = 0x06
+
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := NewWavWriter(
+		writer, nil, WithBroadcastExtension(bext))
+	assert.Nil(t, err)
+	assert.Equal(t, "bext", string(writer.data[36:40]))
+	var bextSize uint32
+	binary.Read(
+		bytes.NewBuffer(writer.data[40:44]), binary.LittleEndian, &bextSize)
+	assert.Equal(t, uint32(602+len(bext.CodingHistory)), bextSize)
+
+	reader, err := NewWavReader(
+		bytes.NewReader(writer.data[:wavWriter.Riff.Size+8]))
+	assert.Nil(t, err)
+	assert.Equal(t, bext, reader.Broadcast)
+}
+
+func TestBroadcastExtensionTextTooLong(t *testing.T) {
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	_, err := NewWavWriter(writer, nil, WithBroadcastExtension(
+		&BextChunk{Originator: strings.Repeat("a", 33)}))
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("bext originator of 33 bytes exceeds its 32 byte field")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
+
+func TestBroadcastExtensionTooSmall(t *testing.T) {
+	var size4Bytes = make([]byte, 4)
+	buffer := getValidHeaderAndFmtChunk()
+	buffer.WriteString("bext")
+	binary.LittleEndian.PutUint32(size4Bytes, 10)
+	buffer.Write(size4Bytes)
+	buffer.Write(make([]byte, 10))
+
+	_, err := NewWavReader(buffer)
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("smaller than the 602 byte minimum")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
+
+func TestTimeReferenceDuration(t *testing.T) {
+	bext := &BextChunk{TimeReference: 44100*3600 + 22050}
+	assert.Equal(t, time.Hour+500*time.Millisecond,
+		bext.TimeReferenceDuration(44100))
+	assert.Equal(t, time.Duration(0), bext.TimeReferenceDuration(0))
+}
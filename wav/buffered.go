@@ -0,0 +1,65 @@
+package wav
+
+import "fmt"
+
+const (
+	BufferSizeError = "buffer size of %v bytes is negative"
+)
+
+/*
+WithBufferSize returns a WriterOption that holds up to size bytes of samples in
+memory before writing them to the file, and defers updating the sizes in its
+header until Flush or Close is called. This replaces the several small writes
+made for every AddSample with one write per buffer, at the cost of the file only
+being valid once flushed. A size of 0 writes through, which is the default.
+*/
+func WithBufferSize(size int) WriterOption {
+	return func(w *WavWriter) error {
+		if size < 0 {
+			return fmt.Errorf(BufferSizeError, size)
+		}
+		w.bufferSize = size
+		w.pending = make([]byte, 0, size)
+		return nil
+	}
+}
+
+/*
+Flush writes any buffered samples, the chunks following the data chunk, such as
+cue points and the hash chain, and the current RIFF and data chunk sizes to the
+file, leaving it valid. Unlike Close, the WavWriter can still be used
+afterwards.
+*/
+func (w *WavWriter) Flush() error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+	return w.writeTrailer()
+}
+
+/*
+commit brings the file up to date after samples are added, unless the writer
+buffers them until Flush.
+*/
+func (w *WavWriter) commit() error {
+	if w.bufferSize > 0 {
+		return nil
+	}
+	return w.writeSizes()
+}
+
+/*
+writePending writes the buffered samples, which end a data chunk of dataSize
+bytes.
+*/
+func (w *WavWriter) writePending(dataSize uint64) error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+	offset := w.dataOffset + int64(dataSize) - int64(len(w.pending))
+	if _, err := w.buffer.WriteAt(w.pending, offset); err != nil {
+		return err
+	}
+	w.pending = w.pending[:0]
+	return nil
+}
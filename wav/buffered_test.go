@@ -0,0 +1,89 @@
+package wav_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingWriterAt counts the writes made to a mockWriterAtCloser.
+type countingWriterAt struct {
+	mockWriterAtCloser
+	writes int
+}
+
+func (c *countingWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	c.writes++
+	return c.mockWriterAtCloser.WriteAt(p, off)
+}
+
+func TestWithBufferSize(t *testing.T) {
+	writer := &countingWriterAt{mockWriterAtCloser: mockWriterAtCloser{
+		make([]byte, 1000)}}
+	wavWriter, err := NewWavWriter(writer, nil, WithBufferSize(64))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, writer.writes)
+	for i := 0; i < 20; i++ {
+		assert.Nil(t, wavWriter.AddSample(Sample{{byte(i), 0}, {0, byte(i)}}))
+	}
+	assert.Nil(t, wavWriter.AddCuePoint(CuePoint{Id: 1, Position: 5}))
+	for i := 20; i < 40; i++ {
+		assert.Nil(t, wavWriter.AddSample(Sample{{byte(i), 0}, {0, byte(i)}}))
+	}
+	// Each write holds 16 frames, and the cue point flushed the first 20.
+	writes := writer.writes
+	assert.True(t, writes < 10, "%v writes", writes)
+	assert.Equal(t, uint32(160), wavWriter.Data.Size)
+
+	assert.Nil(t, wavWriter.Flush())
+	reader, err := NewWavReader(
+		bytes.NewReader(writer.data[:wavWriter.Riff.Size+8]))
+	assert.Nil(t, err)
+	for i := 0; i < 40; i++ {
+		sample, err := reader.GetSample()
+		assert.Nil(t, err)
+		assert.Equal(t, Sample{{byte(i), 0}, {0, byte(i)}}, sample)
+	}
+	_, err = reader.GetSample()
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, wavWriter.CuePoints(), reader.CuePoints())
+}
+
+func TestWithBufferSizeNegative(t *testing.T) {
+	_, err := NewWavWriter(&mockWriterAtCloser{make([]byte, 100)}, nil,
+		WithBufferSize(-1))
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"buffer size of -1 bytes is negative").FindString(err.Error()))
+}
+
+// benchmarkAddSample adds samples to a file written with the given options.
+func benchmarkAddSample(b *testing.B, options ...WriterOption) {
+	file, err := os.Create(filepath.Join(b.TempDir(), "bench.wav"))
+	assert.Nil(b, err)
+	defer file.Close()
+	wavWriter, err := NewWavWriter(file, nil, options...)
+	assert.Nil(b, err)
+	sample := Sample{{1, 2}, {3, 4}}
+	b.SetBytes(4)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := wavWriter.AddSample(sample); err != nil {
+			b.Fatal(err)
+		}
+	}
+	assert.Nil(b, wavWriter.Flush())
+}
+
+func BenchmarkAddSample(b *testing.B) {
+	benchmarkAddSample(b)
+}
+
+func BenchmarkAddSampleBuffered(b *testing.B) {
+	benchmarkAddSample(b, WithBufferSize(64<<10))
+}
@@ -0,0 +1,50 @@
+package wav
+
+import "errors"
+
+// ErrWriterClosed is returned when a WavWriter is used after Close.
+var ErrWriterClosed = errors.New("wav writer is closed")
+
+/*
+WithPadding returns a WriterOption that makes Close follow a data chunk of odd
+size with a zero pad byte, as the RIFF spec requires, and count it in the RIFF
+size. It is off by default as some readers mistake the pad byte for part of the
+last sample. Files with chunks following the data chunk, such as cue points, are
+always padded.
+*/
+func WithPadding() WriterOption {
+	return func(w *WavWriter) error {
+		w.padding = true
+		return nil
+	}
+}
+
+/*
+Close finishes the file: it writes any buffered samples and the chunks following
+the data chunk, pads the data chunk if the writer was created WithPadding, and
+writes the final RIFF and data chunk sizes. The WavWriter cannot be used
+afterwards; its methods return ErrWriterClosed. Close does not close the
+underlying io.WriterAt.
+*/
+func (w *WavWriter) Close() error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+	if err := w.writeTrailer(); err != nil {
+		return err
+	}
+	if dataSize := w.dataSize(); w.padding && len(w.trailer) == 0 &&
+		dataSize%2 != 0 {
+		_, err := w.buffer.WriteAt([]byte{0}, w.trailerOffset(dataSize)-1)
+		if err != nil {
+			return err
+		}
+		w.padded = true
+		w.setDataSize(dataSize)
+		if err := w.writeSizes(); err != nil {
+			return err
+		}
+	}
+	w.closed = true
+	return nil
+}
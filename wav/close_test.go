@@ -0,0 +1,71 @@
+package wav_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// newByteWriter returns a WavWriter of mono 8 bit samples, for odd sizes.
+func newByteWriter(t *testing.T, writer io.WriterAt,
+	options ...WriterOption) *WavWriter {
+	fmtChunk := NewDefaultFmtChunk()
+	fmtChunk.NumChannels = 1
+	fmtChunk.BitsPerSample = 8
+	fmtChunk.BlockAlign = 1
+	fmtChunk.ByteRate = fmtChunk.SampleRate
+	wavWriter, err := NewWavWriter(writer, fmtChunk, options...)
+	assert.Nil(t, err)
+	return wavWriter
+}
+
+func TestClose(t *testing.T) {
+	for _, test := range []struct {
+		options  []WriterOption
+		riffSize uint32
+	}{
+		{nil, 39},
+		{[]WriterOption{WithPadding()}, 40},
+		{[]WriterOption{WithPadding(), WithBufferSize(1024)}, 40},
+	} {
+		writer := &mockWriterAtCloser{make([]byte, 100)}
+		for i := range writer.data {
+			writer.data[i] = 0xFF
+		}
+		wavWriter := newByteWriter(t, writer, test.options...)
+		for i := byte(1); i <= 3; i++ {
+			assert.Nil(t, wavWriter.AddSample(Sample{{i}}))
+		}
+		assert.Nil(t, wavWriter.Close())
+		assert.Equal(t, test.riffSize, wavWriter.Riff.Size)
+		if test.riffSize%2 == 0 {
+			assert.Equal(t, byte(0), writer.data[47])
+		}
+
+		reader, err := NewWavReader(
+			bytes.NewReader(writer.data[:wavWriter.Riff.Size+8]))
+		assert.Nil(t, err)
+		assert.Equal(t, uint32(3), reader.Data.Size)
+		for i := byte(1); i <= 3; i++ {
+			sample, err := reader.GetSample()
+			assert.Nil(t, err)
+			assert.Equal(t, Sample{{i}}, sample)
+		}
+		_, err = reader.GetSample()
+		assert.Equal(t, io.EOF, err)
+	}
+}
+
+func TestWriterClosed(t *testing.T) {
+	wavWriter := newByteWriter(t, &mockWriterAtCloser{make([]byte, 100)})
+	assert.Nil(t, wavWriter.Close())
+	assert.Equal(t, ErrWriterClosed, wavWriter.AddSample(Sample{{1}}))
+	_, err := wavWriter.PCMWriter().Write([]byte{1})
+	assert.Equal(t, ErrWriterClosed, err)
+	assert.Equal(t, ErrWriterClosed, wavWriter.AddCuePoint(CuePoint{Id: 1}))
+	assert.Equal(t, ErrWriterClosed, wavWriter.Flush())
+	assert.Equal(t, ErrWriterClosed, wavWriter.Close())
+}
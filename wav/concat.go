@@ -0,0 +1,145 @@
+package wav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	FormatMismatchError = "input %v is %s but the first input is %s"
+
+	// copyFrames is the number of sample frames copied at a time when data
+	// is streamed between files.
+	copyFrames = 4096
+)
+
+// ErrNoInputs is returned by Concat when it is given nothing to concatenate.
+var ErrNoInputs = errors.New("concat requires at least one input")
+
+/*
+Concat writes the samples of every input, in order, to a new WAV file written to
+output, and returns the WavWriter used to write it. Every input must have the
+same audio format, channel count, sample rate and bit depth as the first, as no
+conversion is made. The data chunks are streamed rather than held in memory, and
+the header sizes are written once at the end. Chunks other than fmt and data are
+not copied.
+*/
+func Concat(output io.WriterAt, inputs ...io.Reader) (*WavWriter, error) {
+	return ConcatContext(
+		context.Background(), output, ContextOptions{}, inputs...)
+}
+
+/*
+ConcatContext is Concat, stopping once ctx is done. The samples copied until
+then are left as a valid file, and its WavWriter is returned with ctx's error.
+*/
+func ConcatContext(ctx context.Context, output io.WriterAt,
+	options ContextOptions, inputs ...io.Reader) (*WavWriter, error) {
+	if len(inputs) == 0 {
+		return nil, ErrNoInputs
+	}
+	// Every header is read first, so the total length is known for progress
+	// reports and mismatched formats are found before anything is copied.
+	readers := make([]*WavReader, len(inputs))
+	tracker := &progress{ctx: ctx, report: options.Progress,
+		throttle: options.Throttle}
+	for index, input := range inputs {
+		reader, err := NewWavReader(input)
+		if err != nil {
+			return nil, err
+		}
+		if index > 0 && !sameFormat(readers[0].Fmt, reader.Fmt) {
+			return nil, fmt.Errorf(FormatMismatchError, index,
+				describeFormat(reader.Fmt), describeFormat(readers[0].Fmt))
+		}
+		readers[index] = reader
+		if frames := reader.RemainingFrames(); frames < 0 || tracker.total < 0 {
+			tracker.total = -1
+		} else {
+			tracker.total += frames
+		}
+	}
+	wavWriter, err := NewWavWriter(output, copyFmtChunk(readers[0].Fmt))
+	if err != nil {
+		return nil, err
+	}
+	for _, reader := range readers {
+		if err := wavWriter.copyData(ctx, reader, tracker); err != nil {
+			if ctx.Err() != nil {
+				return wavWriter.partial(ctx)
+			}
+			return nil, err
+		}
+	}
+	return wavWriter, wavWriter.writeSizes()
+}
+
+/*
+copyData appends the remaining samples of reader to the WavWriter, counting the
+frames copied in tracker and returning ctx's error if it is done first. A
+partial sample frame at the end of the reader's data chunk is dropped so the
+frames of later data stay aligned.
+*/
+func (w *WavWriter) copyData(
+	ctx context.Context, reader *WavReader, tracker *progress) error {
+	frameSize := int(reader.Fmt.BitsPerSample/8) * int(reader.Fmt.NumChannels)
+	if frameSize == 0 {
+		return nil
+	}
+	buffer := make([]byte, frameSize*copyFrames)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := reader.readData(buffer)
+		if n -= n % frameSize; n > 0 {
+			if err := w.appendData(buffer[:n]); err != nil {
+				return err
+			}
+			tracker.add(int64(n / frameSize))
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+/*
+partial finishes the file written by the WavWriter when ctx is done part way
+through an operation. The sizes of the samples written so far are written, so
+they form a valid file, and the WavWriter is returned with ctx's error.
+*/
+func (w *WavWriter) partial(ctx context.Context) (*WavWriter, error) {
+	if err := w.writeSizes(); err != nil {
+		return nil, err
+	}
+	return w, ctx.Err()
+}
+
+/*
+copyFmtChunk returns a copy of a fmt chunk holding only the fields the package
+writes, so that extra bytes declared by the original are not claimed.
+*/
+func copyFmtChunk(original *FmtChunk) *FmtChunk {
+	fmtChunk := NewDefaultFmtChunk()
+	*fmtChunk.fmtChunk = *original.fmtChunk
+	return fmtChunk
+}
+
+// sameFormat returns true if samples in both formats can be mixed freely.
+func sameFormat(first, second *FmtChunk) bool {
+	return first.AudioFormat == second.AudioFormat &&
+		first.NumChannels == second.NumChannels &&
+		first.SampleRate == second.SampleRate &&
+		first.BitsPerSample == second.BitsPerSample
+}
+
+// describeFormat returns a description of a format for error messages.
+func describeFormat(f *FmtChunk) string {
+	return fmt.Sprintf("format %v with %v channels at %v Hz and %v bits",
+		f.AudioFormat, f.NumChannels, f.SampleRate, f.BitsPerSample)
+}
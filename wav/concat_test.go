@@ -0,0 +1,86 @@
+package wav_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// newWavFile returns a complete WAV file holding samples.
+func newWavFile(t *testing.T, fmtChunk *FmtChunk, samples ...Sample) []byte {
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := NewWavWriter(writer, fmtChunk)
+	assert.Nil(t, err)
+	for _, sample := range samples {
+		assert.Nil(t, wavWriter.AddSample(sample))
+	}
+	return writer.data[:wavWriter.Riff.Size+8]
+}
+
+// readAllSamples returns every sample of a WAV file.
+func readAllSamples(t *testing.T, data []byte) []Sample {
+	reader, err := NewWavReader(bytes.NewReader(data))
+	assert.Nil(t, err)
+	var samples []Sample
+	for {
+		sample, err := reader.GetSample()
+		if err == io.EOF {
+			return samples
+		}
+		assert.Nil(t, err)
+		samples = append(samples, sample)
+	}
+}
+
+func TestConcat(t *testing.T) {
+	first := newWavFile(t, nil, Sample{{1, 2}, {3, 4}})
+	second := newWavFile(t, nil, Sample{{5, 6}, {7, 8}}, Sample{{9, 0}, {1, 2}})
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := Concat(writer,
+		bytes.NewReader(first), bytes.NewReader(second))
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(12), wavWriter.Data.Size)
+
+	assert.Equal(t, []Sample{
+		{{1, 2}, {3, 4}}, {{5, 6}, {7, 8}}, {{9, 0}, {1, 2}},
+	}, readAllSamples(t, writer.data[:wavWriter.Riff.Size+8]))
+}
+
+func TestConcatFormatMismatch(t *testing.T) {
+	mono := NewDefaultFmtChunk()
+	mono.NumChannels = 1
+	first := newWavFile(t, nil, Sample{{1, 2}, {3, 4}})
+	second := newWavFile(t, mono, Sample{{5, 6}})
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	_, err := Concat(writer, bytes.NewReader(first), bytes.NewReader(second))
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("input 1 is format 1 with 1 channels")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+
+	_, err = Concat(writer)
+	assert.Equal(t, ErrNoInputs, err)
+}
+
+func TestConcatContext(t *testing.T) {
+	first := newWavFile(t, nil, Sample{{1, 2}, {3, 4}})
+	second := newWavFile(t, nil, Sample{{5, 6}, {7, 8}}, Sample{{9, 0}, {1, 2}})
+	ctx, cancel := context.WithCancel(context.Background())
+	var reports [][2]int64
+	// Cancel once the first input has been copied.
+	options := ContextOptions{Progress: func(done, total int64) {
+		reports = append(reports, [2]int64{done, total})
+		cancel()
+	}}
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := ConcatContext(ctx, writer, options,
+		bytes.NewReader(first), bytes.NewReader(second))
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, [][2]int64{{1, 3}}, reports)
+	assert.Equal(t, []Sample{{{1, 2}, {3, 4}}},
+		readAllSamples(t, writer.data[:wavWriter.Riff.Size+8]))
+}
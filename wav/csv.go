@@ -0,0 +1,120 @@
+package wav
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/husafan/audio"
+)
+
+const (
+	CSVValueError = "row %v, column %v: invalid sample value %q"
+)
+
+// csvBlockFrames is the number of frames converted at a time.
+const csvBlockFrames = 4096
+
+/*
+ExportCSV writes the remaining samples of reader to writer as CSV, one sample
+frame per row with a column for each channel, after a header row naming the
+channels "channel 1", "channel 2" and so on. Values are decoded as by
+ReadBuffer, between -1 and 1, and written with the fewest digits that read back
+exactly, so ImportCSV can rebuild the file.
+*/
+func ExportCSV(writer io.Writer, reader *WavReader) error {
+	channels := int(reader.Fmt.NumChannels)
+	output := csv.NewWriter(writer)
+	record := make([]string, channels)
+	for channel := range record {
+		record[channel] = fmt.Sprintf("channel %v", channel+1)
+	}
+	if err := output.Write(record); err != nil {
+		return err
+	}
+	buffer := audio.NewBuffer(reader.Fmt.Format(), csvBlockFrames)
+	for {
+		n, err := reader.ReadBuffer(buffer)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			for channel, value := range buffer.Frame(i) {
+				record[channel] = strconv.FormatFloat(value, 'g', -1, 64)
+			}
+			if err := output.Write(record); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	output.Flush()
+	return output.Error()
+}
+
+/*
+ImportCSV builds a WAV file in output from CSV in the form written by ExportCSV,
+encoding the samples as described by fmt and clipping integer samples to the
+range -1 to 1. The header row is optional: a first row that does not hold
+numbers is skipped. Every row must have one column per channel. The WavWriter
+is returned closed.
+*/
+func ImportCSV(output io.WriterAt, input io.Reader,
+	fmt *FmtChunk) (*WavWriter, error) {
+	if fmt == nil {
+		fmt = NewDefaultFmtChunk()
+	}
+	if err := checkDecodable(fmt); err != nil {
+		return nil, err
+	}
+	wavWriter, err := NewWavWriter(output, fmt)
+	if err != nil {
+		return nil, err
+	}
+	records := csv.NewReader(input)
+	records.FieldsPerRecord = int(wavWriter.Fmt.NumChannels)
+	records.ReuseRecord = true
+	buffer := audio.NewBuffer(wavWriter.Fmt.Format(), csvBlockFrames)
+	frames := 0
+	for row := 1; ; row++ {
+		record, err := records.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		frame := buffer.Frame(frames)
+		if err := parseCSVFrame(record, frame, row); err != nil {
+			if row == 1 {
+				continue
+			}
+			return nil, err
+		}
+		if frames++; frames == csvBlockFrames {
+			if err := wavWriter.WriteBuffer(buffer); err != nil {
+				return nil, err
+			}
+			frames = 0
+		}
+	}
+	buffer.Data = buffer.Data[:frames*buffer.Format.Channels]
+	if err := wavWriter.WriteBuffer(buffer); err != nil {
+		return nil, err
+	}
+	return wavWriter, wavWriter.Close()
+}
+
+// parseCSVFrame parses the values of row of a CSV file into frame.
+func parseCSVFrame(record []string, frame []float64, row int) error {
+	for column, field := range record {
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return fmt.Errorf(CSVValueError, row, column+1, field)
+		}
+		frame[column] = value
+	}
+	return nil
+}
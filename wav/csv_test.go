@@ -0,0 +1,60 @@
+package wav_test
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportCSV(t *testing.T) {
+	reader, err := NewWavReader(bytes.NewReader(newWavFile(t, nil,
+		Sample{{0x00, 0x40}, {0x00, 0xC0}},
+		Sample{{0x01, 0x00}, {0x00, 0x80}})))
+	assert.Nil(t, err)
+	var csv bytes.Buffer
+	assert.Nil(t, ExportCSV(&csv, reader))
+	assert.Equal(t, "channel 1,channel 2\n"+
+		"0.5,-0.5\n"+
+		"3.0517578125e-05,-1\n", csv.String())
+
+	// Importing the CSV rebuilds the same samples.
+	writer := &mockWriterAtCloser{make([]byte, 100)}
+	wavWriter, err := ImportCSV(writer, &csv, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []Sample{
+		{{0x00, 0x40}, {0x00, 0xC0}},
+		{{0x01, 0x00}, {0x00, 0x80}},
+	}, readAllSamples(t, writer.data[:wavWriter.Riff.Size+8]))
+}
+
+func TestImportCSV(t *testing.T) {
+	// The header row is optional and values are clipped.
+	writer := &mockWriterAtCloser{make([]byte, 100)}
+	wavWriter, err := ImportCSV(writer,
+		strings.NewReader("0.5,2\n0,-0.5\n"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []Sample{
+		{{0x00, 0x40}, {0xFF, 0x7F}},
+		{{0x00, 0x00}, {0x00, 0xC0}},
+	}, readAllSamples(t, writer.data[:wavWriter.Riff.Size+8]))
+	assert.Equal(t, ErrWriterClosed, wavWriter.AddSample(Sample{{0, 0}, {0, 0}}))
+}
+
+func TestImportCSVErrors(t *testing.T) {
+	for _, test := range []struct {
+		csv string
+		err string
+	}{
+		{"left,right\n0.5,loud\n", `row 2, column 2: invalid sample value "loud"`},
+		{"0.5,0.5\n0.5\n", "wrong number of fields"},
+	} {
+		_, err := ImportCSV(&mockWriterAtCloser{make([]byte, 100)},
+			strings.NewReader(test.csv), nil)
+		assert.NotEqual(t, "",
+			regexp.MustCompile(test.err).FindString(err.Error()), test.err)
+	}
+}
@@ -0,0 +1,233 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	Adtl          = "adtl"
+	Cue           = "cue "
+	CueIdError    = "cue point ID %v is already in use"
+	Label         = "labl"
+	LabeledText   = "ltxt"
+	Note          = "note"
+	RegionPurpose = "rgn "
+)
+
+/*
+cuePointFields is a single entry of a cue chunk as laid out in the file. For
+uncompressed files ChunkStart and BlockStart are 0 and SampleOffset is the frame
+at which the cue point occurs.
+*/
+type cuePointFields struct {
+	Id           uint32
+	Position     uint32
+	DataChunkId  [4]byte
+	ChunkStart   uint32
+	BlockStart   uint32
+	SampleOffset uint32
+}
+
+/*
+ltxtFields is the fixed size portion of an ltxt entry of a LIST adtl chunk,
+which gives a cue point a length and so turns it into a region.
+*/
+type ltxtFields struct {
+	Id           uint32
+	SampleLength uint32
+	Purpose      [4]byte
+	Country      uint16
+	Language     uint16
+	Dialect      uint16
+	CodePage     uint16
+}
+
+/*
+CuePoint marks a position in the data chunk, measured in sample frames from the
+first sample. A CuePoint with a non-zero Length is a labeled region covering
+that many frames. Label, Note and Text are taken from the labl, note and ltxt
+entries of the LIST adtl chunk that refer to the cue point's ID.
+*/
+type CuePoint struct {
+	Id       uint32
+	Position uint32
+	Length   uint32
+	Label    string
+	Note     string
+	Text     string
+}
+
+/*
+CuePoints returns a copy of the file's cue points sorted by position. Cue points
+stored after the data chunk are only available once every sample has been read.
+*/
+func (w *Wav) CuePoints() []CuePoint {
+	cues := append([]CuePoint{}, w.cues...)
+	sort.SliceStable(cues, func(a, b int) bool {
+		return cues[a].Position < cues[b].Position
+	})
+	return cues
+}
+
+/*
+cue returns the cue point with the given ID, adding an empty one if there is
+none. The cue and adtl chunks may appear in either order, so either can be the
+first to mention a cue point.
+*/
+func (w *Wav) cue(id uint32) *CuePoint {
+	for i := range w.cues {
+		if w.cues[i].Id == id {
+			return &w.cues[i]
+		}
+	}
+	w.cues = append(w.cues, CuePoint{Id: id})
+	return &w.cues[len(w.cues)-1]
+}
+
+/*
+AddCuePoint adds cue to the file. Cue points are written in cue and LIST adtl
+chunks following the data chunk by Flush and Close. A non-nil error is returned
+if another cue point already uses the same ID.
+*/
+func (w *WavWriter) AddCuePoint(cue CuePoint) error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+	for i := range w.cues {
+		if w.cues[i].Id == cue.Id {
+			return fmt.Errorf(CueIdError, cue.Id)
+		}
+	}
+	w.cues = append(w.cues, cue)
+	w.cueChunks = w.encodeCuePoints()
+	return nil
+}
+
+/*
+readCueChunk parses the body of a cue chunk of the given size into the Wav's cue
+points.
+*/
+func (w *Wav) readCueChunk(reader io.Reader, size uint32) error {
+	var count uint32
+	if err := binary.Read(reader, binary.LittleEndian, &count); err != nil {
+		return err
+	}
+	if uint64(size) < 4+uint64(count)*uint64(binary.Size(cuePointFields{})) {
+		return fmt.Errorf(ChunkSizeError, Cue, size)
+	}
+	for i := uint32(0); i < count; i++ {
+		var fields cuePointFields
+		if err := binary.Read(
+			reader, binary.LittleEndian, &fields); err != nil {
+			return err
+		}
+		w.cue(fields.Id).Position = fields.SampleOffset
+	}
+	return nil
+}
+
+/*
+readAdtlList parses the entries of a LIST adtl chunk, whose list type has
+already been read, adding their text and lengths to the Wav's cue points.
+Entries of other types are skipped.
+*/
+func (w *Wav) readAdtlList(reader io.Reader) error {
+	for {
+		subChunk, err := readSubChunk(&reader)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		body, err := readBody(reader, uint64(subChunk.Size))
+		if err != nil {
+			return err
+		}
+		if subChunk.Size%2 != 0 {
+			io.CopyN(io.Discard, reader, 1)
+		}
+
+		id := string(subChunk.Id[:])
+		switch id {
+		case Label, Note:
+			if len(body) < 4 {
+				return fmt.Errorf(ChunkSizeError, id, len(body))
+			}
+			cue := w.cue(binary.LittleEndian.Uint32(body))
+			if id == Label {
+				cue.Label = fixedString(body[4:])
+			} else {
+				cue.Note = fixedString(body[4:])
+			}
+		case LabeledText:
+			var fields ltxtFields
+			size := binary.Size(fields)
+			if len(body) < size {
+				return fmt.Errorf(ChunkSizeError, id, len(body))
+			}
+			binary.Read(bytes.NewReader(body), binary.LittleEndian, &fields)
+			cue := w.cue(fields.Id)
+			cue.Length = fields.SampleLength
+			cue.Text = fixedString(body[size:])
+		}
+	}
+}
+
+/*
+encodeCuePoints returns the cue chunk and LIST adtl chunk, including their
+headers, that describe the writer's cue points. The adtl chunk is omitted when
+no cue point has a label, note, length or text.
+*/
+func (w *WavWriter) encodeCuePoints() []byte {
+	if len(w.cues) == 0 {
+		return nil
+	}
+
+	cues := new(bytes.Buffer)
+	binary.Write(cues, binary.LittleEndian, uint32(len(w.cues)))
+	adtl := bytes.NewBufferString(Adtl)
+	for _, cue := range w.cues {
+		binary.Write(cues, binary.LittleEndian, &cuePointFields{
+			Id:           cue.Id,
+			Position:     cue.Position,
+			DataChunkId:  w.Data.Id,
+			SampleOffset: cue.Position,
+		})
+
+		if cue.Label != "" {
+			writeChunk(adtl, Label, cueText(cue.Id, cue.Label))
+		}
+		if cue.Note != "" {
+			writeChunk(adtl, Note, cueText(cue.Id, cue.Note))
+		}
+		if cue.Length != 0 || cue.Text != "" {
+			text := new(bytes.Buffer)
+			fields := ltxtFields{Id: cue.Id, SampleLength: cue.Length}
+			copy(fields.Purpose[:], RegionPurpose)
+			binary.Write(text, binary.LittleEndian, &fields)
+			if cue.Text != "" {
+				text.WriteString(cue.Text)
+				text.WriteByte(0)
+			}
+			writeChunk(adtl, LabeledText, text.Bytes())
+		}
+	}
+
+	buffer := new(bytes.Buffer)
+	writeChunk(buffer, Cue, cues.Bytes())
+	if adtl.Len() > len(Adtl) {
+		writeChunk(buffer, List, adtl.Bytes())
+	}
+	return buffer.Bytes()
+}
+
+// cueText returns the body of a labl or note entry for the cue point id.
+func cueText(id uint32, text string) []byte {
+	body := make([]byte, 4, 4+len(text)+1)
+	binary.LittleEndian.PutUint32(body, id)
+	return append(append(body, text...), 0)
+}
@@ -0,0 +1,78 @@
+package wav_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCuePointsRoundTrip(t *testing.T) {
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := NewWavWriter(writer, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, wavWriter.AddSample(Sample{{1, 2}, {3, 4}}))
+	assert.Nil(t, wavWriter.AddCuePoint(
+		CuePoint{Id: 2, Position: 3, Length: 2, Label: "Chorus",
+			Text: "Region"}))
+	assert.Nil(t, wavWriter.AddCuePoint(
+		CuePoint{Id: 1, Position: 1, Note: "Downbeat"}))
+	// Samples added after the cue points overwrite the chunks that hold
+	// them, which Flush writes again after the samples.
+	for i := 0; i < 4; i++ {
+		assert.Nil(t, wavWriter.AddSample(Sample{{5, 6}, {7, 8}}))
+	}
+	assert.Equal(t, uint32(56), wavWriter.Riff.Size)
+	assert.Nil(t, wavWriter.Flush())
+	assert.Equal(t, []CuePoint{
+		{Id: 1, Position: 1, Note: "Downbeat"},
+		{Id: 2, Position: 3, Length: 2, Label: "Chorus", Text: "Region"},
+	}, wavWriter.CuePoints())
+
+	assert.Equal(t, "cue ", string(writer.data[64:68]))
+	reader, err := NewWavReader(
+		bytes.NewReader(writer.data[:wavWriter.Riff.Size+8]))
+	assert.Nil(t, err)
+	samples := 0
+	for {
+		if _, err := reader.GetSample(); err == io.EOF {
+			break
+		} else {
+			assert.Nil(t, err)
+		}
+		samples++
+	}
+	assert.Equal(t, 5, samples)
+	assert.Equal(t, wavWriter.CuePoints(), reader.CuePoints())
+}
+
+func TestAddCuePointDuplicateId(t *testing.T) {
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := NewWavWriter(writer, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, wavWriter.AddCuePoint(CuePoint{Id: 7}))
+	err = wavWriter.AddCuePoint(CuePoint{Id: 7, Position: 10})
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("cue point ID 7 is already in use")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
+
+func TestReadCueChunkTooSmall(t *testing.T) {
+	var size4Bytes = make([]byte, 4)
+	buffer := getValidHeaderAndFmtChunk()
+	buffer.WriteString("cue ")
+	binary.LittleEndian.PutUint32(size4Bytes, 4)
+	buffer.Write(size4Bytes)
+	// The chunk declares two cue points but holds none.
+	binary.LittleEndian.PutUint32(size4Bytes, 2)
+	buffer.Write(size4Bytes)
+
+	_, err := NewWavReader(buffer)
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("cue  chunk of 4 bytes is too small")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
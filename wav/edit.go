@@ -0,0 +1,172 @@
+package wav
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/husafan/audio"
+)
+
+const (
+	EditOverlapError = "edit at frame %v overlaps the edit at frame %v"
+	EditRangeError   = "edit at frame %v runs past the last of the %v frames"
+)
+
+/*
+Edit is a change to a region of a WAV file, applied by ApplyEdits. The region
+starts at sample frame Start. When Samples is non-nil the region's frames are
+replaced by Samples, and Length is ignored. Otherwise the Length frames of the
+region are scaled by Gain, so a Gain of audio.Silence mutes them.
+*/
+type Edit struct {
+	Start   uint64
+	Length  uint64
+	Gain    audio.Decibel
+	Samples []Sample
+}
+
+// length returns the number of frames the edit covers.
+func (e *Edit) length() uint64 {
+	if e.Samples != nil {
+		return uint64(len(e.Samples))
+	}
+	return e.Length
+}
+
+/*
+ApplyEdits copies the WAV file in input to output, applying every edit in a
+single streaming pass, and returns the WavWriter used to write it. Edits may be
+given in any order but must not overlap. Replacement samples must match the
+file's format, and gain changes require a PCM or IEEE float format. An edit
+that runs past the end of the input is reported once the copy is complete.
+*/
+func ApplyEdits(
+	output io.WriterAt, input io.Reader, edits []Edit) (*WavWriter, error) {
+	return ApplyEditsContext(
+		context.Background(), output, input, edits, ContextOptions{})
+}
+
+/*
+ApplyEditsContext is ApplyEdits, stopping once ctx is done. The samples copied
+until then are left as a valid file, and its WavWriter is returned with ctx's
+error.
+*/
+func ApplyEditsContext(ctx context.Context, output io.WriterAt,
+	input io.Reader, edits []Edit, options ContextOptions) (*WavWriter, error) {
+	edits = append([]Edit{}, edits...)
+	sort.SliceStable(edits, func(a, b int) bool {
+		return edits[a].Start < edits[b].Start
+	})
+	for i := 1; i < len(edits); i++ {
+		if previous := &edits[i-1]; previous.Start+previous.length() >
+			edits[i].Start {
+			return nil, fmt.Errorf(
+				EditOverlapError, edits[i].Start, previous.Start)
+		}
+	}
+
+	reader, err := NewWavReader(input)
+	if err != nil {
+		return nil, err
+	}
+	f := reader.Fmt
+	for i := range edits {
+		if edits[i].Samples == nil {
+			if err := checkDecodable(f); err != nil {
+				return nil, err
+			}
+		}
+		for _, sample := range edits[i].Samples {
+			if err := checkSample(f, sample); err != nil {
+				return nil, err
+			}
+		}
+	}
+	wavWriter, err := NewWavWriter(output, copyFmtChunk(f))
+	if err != nil {
+		return nil, err
+	}
+
+	bytesPerSample := int(f.BitsPerSample / 8)
+	frameSize := bytesPerSample * int(f.NumChannels)
+	buffer := make([]byte, frameSize*copyFrames)
+	tracker := &progress{ctx: ctx, report: options.Progress,
+		throttle: options.Throttle, total: reader.RemainingFrames()}
+	var frame uint64
+	for frameSize > 0 {
+		if ctx.Err() != nil {
+			return wavWriter.partial(ctx)
+		}
+		n, readErr := reader.readData(buffer)
+		n -= n % frameSize
+		for offset := 0; offset < n; offset += frameSize {
+			for len(edits) > 0 && frame >= edits[0].Start+edits[0].length() {
+				edits = edits[1:]
+			}
+			if len(edits) > 0 && frame >= edits[0].Start {
+				edits[0].apply(buffer[offset:offset+frameSize],
+					frame-edits[0].Start, f.AudioFormat, bytesPerSample)
+			}
+			frame++
+		}
+		if n > 0 {
+			if err := wavWriter.appendData(buffer[:n]); err != nil {
+				return nil, err
+			}
+			tracker.add(int64(n / frameSize))
+		}
+		if readErr == io.EOF {
+			break
+		} else if readErr != nil {
+			return nil, readErr
+		}
+	}
+	if err := wavWriter.writeSizes(); err != nil {
+		return nil, err
+	}
+	for _, edit := range edits {
+		if edit.Start+edit.length() > frame {
+			return wavWriter, fmt.Errorf(EditRangeError, edit.Start, frame)
+		}
+	}
+	return wavWriter, nil
+}
+
+/*
+apply applies the edit to a single sample frame, which is the index'th frame of
+the edit's region.
+*/
+func (e *Edit) apply(
+	frame []byte, index uint64, audioFormat uint16, bytesPerSample int) {
+	if e.Samples != nil {
+		offset := 0
+		for _, channel := range e.Samples[index] {
+			offset += copy(frame[offset:], channel)
+		}
+		return
+	}
+	gain := e.Gain.Linear()
+	for offset := 0; offset < len(frame); offset += bytesPerSample {
+		channel := frame[offset : offset+bytesPerSample]
+		setPCMValue(channel, audioFormat, pcmValue(channel, audioFormat)*gain)
+	}
+}
+
+/*
+checkSample returns a non-nil error if sample does not hold one channel sample
+of the right size for each channel of f.
+*/
+func checkSample(f *FmtChunk, sample Sample) error {
+	if samples := len(sample); samples != int(f.NumChannels) {
+		return fmt.Errorf(ChannelError, f.NumChannels, samples)
+	}
+	for index, channel := range sample {
+		if len(channel) != int(f.BitsPerSample/8) {
+			return ErrSampleSize{Channel: index,
+				Want: int(f.BitsPerSample / 8), Got: len(channel)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package wav_test
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/husafan/audio"
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyEdits(t *testing.T) {
+	input := newWavFile(t, nil,
+		Sample{{0x00, 0x40}, {0x00, 0xC0}},
+		Sample{{0x00, 0x40}, {0x00, 0xC0}},
+		Sample{{0x00, 0x40}, {0x00, 0xC0}},
+		Sample{{0x00, 0x40}, {0x00, 0xC0}},
+		Sample{{0xFF, 0x7F}, {0x00, 0x80}})
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := ApplyEdits(writer, bytes.NewReader(input), []Edit{
+		{Start: 3, Samples: []Sample{{{1, 2}, {3, 4}}}},
+		{Start: 1, Length: 2, Gain: audio.LinearToDecibel(0.5)},
+		// Gain is clipped to the range of the samples.
+		{Start: 4, Length: 1, Gain: audio.LinearToDecibel(2)},
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, []Sample{
+		{{0x00, 0x40}, {0x00, 0xC0}},
+		{{0x00, 0x20}, {0x00, 0xE0}},
+		{{0x00, 0x20}, {0x00, 0xE0}},
+		{{1, 2}, {3, 4}},
+		{{0xFF, 0x7F}, {0x00, 0x80}},
+	}, readAllSamples(t, writer.data[:wavWriter.Riff.Size+8]))
+}
+
+func TestApplyEditsErrors(t *testing.T) {
+	input := newWavFile(t, nil, Sample{{1, 2}, {3, 4}}, Sample{{1, 2}, {3, 4}})
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+
+	_, err := ApplyEdits(writer, bytes.NewReader(input), []Edit{
+		{Start: 0, Length: 2, Gain: audio.LinearToDecibel(0.5)},
+		{Start: 1, Length: 1}})
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("edit at frame 1 overlaps the edit at frame 0")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+
+	_, err = ApplyEdits(writer, bytes.NewReader(input), []Edit{
+		{Start: 1, Length: 5}})
+	assert.NotNil(t, err)
+	re = regexp.MustCompile("edit at frame 1 runs past the last of the 2 frames")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+
+	_, err = ApplyEdits(writer, bytes.NewReader(input), []Edit{
+		{Start: 0, Samples: []Sample{{{1}, {2}}}}})
+	assert.NotNil(t, err)
+	re = regexp.MustCompile("expected 2 bytes per sample but only found 1")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+
+	adpcm := NewDefaultFmtChunk()
+	adpcm.AudioFormat = 2
+	input = newWavFile(t, adpcm, Sample{{1, 2}, {3, 4}})
+	_, err = ApplyEdits(writer, bytes.NewReader(input), []Edit{
+		{Start: 0, Length: 1, Gain: audio.LinearToDecibel(0.5)}})
+	assert.NotNil(t, err)
+	re = regexp.MustCompile("audio format 2 with 16 bits per sample is not")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
+
+func TestApplyEditsContext(t *testing.T) {
+	input := newWavFile(t, nil, Sample{{1, 2}, {3, 4}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := ApplyEditsContext(ctx, writer, bytes.NewReader(input),
+		[]Edit{{Start: 0, Length: 1, Gain: audio.LinearToDecibel(0.5)}},
+		ContextOptions{})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, uint32(0), wavWriter.Data.Size)
+}
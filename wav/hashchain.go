@@ -0,0 +1,254 @@
+package wav
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+const (
+	HashChain           = "hash"
+	HashBlockError      = "block %v, frames %v to %v, does not match its recorded hash"
+	HashCountError      = "recording has %v blocks but the hash chain records %v"
+	HashIntervalError   = "hash chain interval must be a positive number of frames"
+	hashChainHeaderSize = 8
+)
+
+// ErrNoHashChain is returned by VerifyHashChain for a file without a hash chain.
+var ErrNoHashChain = errors.New("file has no hash chain")
+
+/*
+HashChainChunk holds a chain of SHA-256 hashes over consecutive blocks of
+Interval sample frames of the data chunk. Each hash covers the previous hash
+followed by the bytes of its block, with 32 zero bytes standing in before the
+first block, so no block can be changed, removed or reordered without breaking
+every later hash. The last hash covers a final partial block, if any. The hashes
+are HMACs when the chain was written WithKeyedHashChain.
+*/
+type HashChainChunk struct {
+	Interval uint32
+	Hashes   [][sha256.Size]byte
+}
+
+/*
+hashChainWriter maintains the hash chain of a WavWriter as data is appended.
+hash has been fed the previous hash and the blockBytes of the current block,
+and is an HMAC when key is set.
+*/
+type hashChainWriter struct {
+	blockSize  uint64
+	blockBytes uint64
+	key        []byte
+	hash       hash.Hash
+	chunk      HashChainChunk
+}
+
+/*
+WithHashChain returns a WriterOption that records a hash chain over every
+interval sample frames of the data. The chain is kept in memory as samples are
+added and written in a hash chunk following the data chunk by Flush and Close.
+Recordings are checked with VerifyHashChain.
+
+The chain alone detects corruption, but not deliberate edits: whoever changes
+the samples can compute a new chain to match. Keeping the last hash, from
+HashChainChunk.Head, somewhere the file's editor cannot change, such as a
+signed log, makes any edit evident. WithKeyedHashChain does so with a secret
+key instead.
+*/
+func WithHashChain(interval uint32) WriterOption {
+	return WithKeyedHashChain(interval, nil)
+}
+
+/*
+WithKeyedHashChain returns a WriterOption like WithHashChain that records
+HMAC-SHA256 hashes under key, so that a matching chain cannot be computed for
+changed samples without the key. Recordings are checked with
+VerifyKeyedHashChain and the same key. A nil key records a plain chain.
+*/
+func WithKeyedHashChain(interval uint32, key []byte) WriterOption {
+	return func(w *WavWriter) error {
+		if interval == 0 {
+			return errors.New(HashIntervalError)
+		}
+		frameSize := uint64(w.Fmt.BitsPerSample/8) * uint64(w.Fmt.NumChannels)
+		w.hashChain = &hashChainWriter{
+			blockSize: uint64(interval) * frameSize,
+			key:       key,
+			chunk:     HashChainChunk{Interval: interval},
+		}
+		w.Wav.HashChain = &w.hashChain.chunk
+		return nil
+	}
+}
+
+// newHash returns the hash of a block, an HMAC when key is not nil.
+func newHash(key []byte) hash.Hash {
+	if key == nil {
+		return sha256.New()
+	}
+	return hmac.New(sha256.New, key)
+}
+
+/*
+Head returns the last hash of the chain, which depends on every sample, or 32
+zero bytes for an empty chain.
+*/
+func (c *HashChainChunk) Head() [sha256.Size]byte {
+	if len(c.Hashes) == 0 {
+		return [sha256.Size]byte{}
+	}
+	return c.Hashes[len(c.Hashes)-1]
+}
+
+/*
+write adds data appended to the data chunk to the chain, completing a block
+each time blockSize bytes have been hashed.
+*/
+func (h *hashChainWriter) write(data []byte) {
+	for len(data) > 0 && h.blockSize > 0 {
+		if h.hash == nil {
+			h.hash = newHash(h.key)
+			var previous [sha256.Size]byte
+			if count := len(h.chunk.Hashes); count > 0 {
+				previous = h.chunk.Hashes[count-1]
+			}
+			h.hash.Write(previous[:])
+		}
+		size := uint64(len(data))
+		if remaining := h.blockSize - h.blockBytes; size > remaining {
+			size = remaining
+		}
+		h.hash.Write(data[:size])
+		h.blockBytes += size
+		data = data[size:]
+
+		var sum [sha256.Size]byte
+		copy(sum[:], h.hash.Sum(nil))
+		// The hash of a partial block is replaced as the block grows.
+		if h.blockBytes == size {
+			h.chunk.Hashes = append(h.chunk.Hashes, sum)
+		} else {
+			h.chunk.Hashes[len(h.chunk.Hashes)-1] = sum
+		}
+		if h.blockBytes == h.blockSize {
+			h.hash, h.blockBytes = nil, 0
+		}
+	}
+}
+
+// encode returns the body of a hash chunk.
+func (c *HashChainChunk) encode() []byte {
+	buffer := new(bytes.Buffer)
+	binary.Write(buffer, binary.LittleEndian, c.Interval)
+	binary.Write(buffer, binary.LittleEndian, uint32(len(c.Hashes)))
+	for _, hash := range c.Hashes {
+		buffer.Write(hash[:])
+	}
+	return buffer.Bytes()
+}
+
+/*
+readHashChainChunk parses the body of a hash chunk of the given size into the
+Wav's HashChain field.
+*/
+func (w *Wav) readHashChainChunk(reader io.Reader, size uint32) error {
+	var header [2]uint32
+	if size < hashChainHeaderSize {
+		return fmt.Errorf(ChunkSizeError, HashChain, size)
+	}
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	if uint64(size) < hashChainHeaderSize+uint64(header[1])*sha256.Size {
+		return fmt.Errorf(ChunkSizeError, HashChain, size)
+	}
+	chunk := &HashChainChunk{
+		Interval: header[0],
+		Hashes:   make([][sha256.Size]byte, header[1]),
+	}
+	if err := binary.Read(
+		reader, binary.LittleEndian, chunk.Hashes); err != nil {
+		return err
+	}
+	w.HashChain = chunk
+	return nil
+}
+
+/*
+VerifyHashChain reads the WAV file in reader and checks its samples against the
+hash chain recorded by a WavWriter created with WithHashChain. A nil error means
+the samples are exactly those that were recorded. Otherwise the error names the
+first block that differs, or is ErrNoHashChain for a file without a chain. As
+the hash chunk follows the data, the file is read twice: once to find the chain
+and once to check the samples.
+*/
+func VerifyHashChain(reader io.ReadSeeker) error {
+	return VerifyKeyedHashChain(reader, nil)
+}
+
+/*
+VerifyKeyedHashChain is VerifyHashChain for a chain recorded
+WithKeyedHashChain, which is checked with the same key.
+*/
+func VerifyKeyedHashChain(reader io.ReadSeeker, key []byte) error {
+	chain, err := readHashChain(reader)
+	if err != nil {
+		return err
+	}
+	if _, err := reader.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	wavReader, err := NewWavReader(reader)
+	if err != nil {
+		return err
+	}
+	frameSize := uint64(wavReader.Fmt.BitsPerSample/8) *
+		uint64(wavReader.Fmt.NumChannels)
+	blockSize := uint64(chain.Interval) * frameSize
+	if blockSize == 0 {
+		return errors.New(HashIntervalError)
+	}
+	blocks := (wavReader.dataSize() + blockSize - 1) / blockSize
+	if blocks != uint64(len(chain.Hashes)) {
+		return fmt.Errorf(HashCountError, blocks, len(chain.Hashes))
+	}
+
+	block := make([]byte, sha256.Size+blockSize)
+	var sum [sha256.Size]byte
+	for index, expected := range chain.Hashes {
+		n, err := wavReader.readData(block[sha256.Size:])
+		if err != nil && err != io.EOF {
+			return err
+		}
+		hash := newHash(key)
+		hash.Write(block[:sha256.Size+n])
+		copy(sum[:], hash.Sum(nil))
+		if n == 0 || sum != expected {
+			first := uint64(index) * uint64(chain.Interval)
+			last := first + uint64(chain.Interval) - 1
+			return fmt.Errorf(HashBlockError, index, first, last)
+		}
+		copy(block, expected[:])
+	}
+	return nil
+}
+
+/*
+readHashChain reads the WAV file in reader to the end and returns the hash
+chain found in it.
+*/
+func readHashChain(reader io.Reader) (*HashChainChunk, error) {
+	wav, err := readToEnd(reader)
+	if err != nil {
+		return nil, err
+	}
+	if wav.HashChain == nil {
+		return nil, ErrNoHashChain
+	}
+	return wav.HashChain, nil
+}
@@ -0,0 +1,82 @@
+package wav_test
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// newHashChainFile returns a WAV file of five frames with a hash chain.
+func newHashChainFile(t *testing.T) []byte {
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := NewWavWriter(writer, nil, WithHashChain(2))
+	assert.Nil(t, err)
+	for i := byte(0); i < 5; i++ {
+		assert.Nil(t, wavWriter.AddSample(Sample{{i, 1}, {i, 2}}))
+		// Cue points share the space after the data chunk.
+		if i == 2 {
+			assert.Nil(t, wavWriter.AddCuePoint(CuePoint{Id: 1, Position: 2}))
+		}
+	}
+	assert.Equal(t, uint32(2), wavWriter.HashChain.Interval)
+	assert.Equal(t, 3, len(wavWriter.HashChain.Hashes))
+	assert.Nil(t, wavWriter.Close())
+	return writer.data[:wavWriter.Riff.Size+8]
+}
+
+func TestVerifyHashChain(t *testing.T) {
+	data := newHashChainFile(t)
+	assert.Nil(t, VerifyHashChain(bytes.NewReader(data)))
+
+	reader, err := NewWavReader(bytes.NewReader(data))
+	assert.Nil(t, err)
+	for {
+		if _, err := reader.GetSample(); err != nil {
+			break
+		}
+	}
+	assert.Equal(t, 3, len(reader.HashChain.Hashes))
+	assert.Equal(t, []CuePoint{{Id: 1, Position: 2}}, reader.CuePoints())
+}
+
+func TestVerifyHashChainDetectsTampering(t *testing.T) {
+	data := newHashChainFile(t)
+	// Change the first byte of the fourth frame.
+	data[44+12]++
+	err := VerifyHashChain(bytes.NewReader(data))
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("block 1, frames 2 to 3, does not match")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
+
+func TestVerifyHashChainMissing(t *testing.T) {
+	data := newWavFile(t, nil, Sample{{1, 2}, {3, 4}})
+	assert.Equal(t, ErrNoHashChain, VerifyHashChain(bytes.NewReader(data)))
+
+	_, err := NewWavWriter(
+		&mockWriterAtCloser{make([]byte, 1000)}, nil, WithHashChain(0))
+	assert.NotNil(t, err)
+}
+
+func TestKeyedHashChain(t *testing.T) {
+	key := []byte("secret")
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := NewWavWriter(writer, nil, WithKeyedHashChain(2, key))
+	assert.Nil(t, err)
+	for i := byte(0); i < 3; i++ {
+		assert.Nil(t, wavWriter.AddSample(Sample{{i, 1}, {i, 2}}))
+	}
+	assert.Nil(t, wavWriter.Close())
+	data := writer.data[:wavWriter.Riff.Size+8]
+	head := wavWriter.HashChain.Head()
+	assert.Equal(t, wavWriter.HashChain.Hashes[1], head)
+
+	assert.Nil(t, VerifyKeyedHashChain(bytes.NewReader(data), key))
+	// Without the key, or with another, the chain does not match.
+	assert.NotNil(t, VerifyHashChain(bytes.NewReader(data)))
+	assert.NotNil(t, VerifyKeyedHashChain(bytes.NewReader(data), []byte("x")))
+	assert.Equal(t, [32]byte{}, (&HashChainChunk{}).Head())
+}
@@ -0,0 +1,173 @@
+package wav
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/husafan/audio"
+)
+
+const (
+	LoopCountError     = "loop count of %v must be at least 1"
+	LoopCrossfadeError = "crossfade of %v frames is longer than half the loop of %v frames"
+	LoopRangeError     = "loop from frame %v to %v does not fit in the %v frames of data"
+)
+
+/*
+LoopOptions controls Loop. When Duration is positive the output is exactly that
+long, and the loop repeats for as long as needed to fill it. Otherwise the loop
+plays Count times. Crossfade is the length of the blend between the end of each
+repetition and the start of the next, which hides clicks at seams that do not
+line up exactly. Each seam shortens the output by the length of the crossfade.
+Progress, if not nil, is called as LoopContext works, and Throttle slows it
+down.
+*/
+type LoopOptions struct {
+	Count     int
+	Duration  time.Duration
+	Crossfade time.Duration
+	Progress  audio.ProgressFunc
+	Throttle  audio.Throttle
+}
+
+/*
+Loop writes the WAV file in input to output with its loop repeated, and returns
+the WavWriter used to write it. The loop is the first loop of the smpl chunk
+when there is one, played forward whatever its type, and the whole file
+otherwise. Samples before the loop are written once before it, and when Count
+is used, samples after the loop are written once after the last repetition. The
+input's data chunk is held in memory. Chunks other than fmt and data are not
+copied, and crossfades require a PCM or IEEE float format.
+*/
+func Loop(
+	output io.WriterAt, input io.Reader, options LoopOptions) (*WavWriter, error) {
+	return LoopContext(context.Background(), output, input, options)
+}
+
+/*
+LoopContext is Loop, stopping once ctx is done. The samples written until then
+are left as a valid file, and its WavWriter is returned with ctx's error.
+*/
+func LoopContext(ctx context.Context, output io.WriterAt,
+	input io.Reader, options LoopOptions) (*WavWriter, error) {
+	if options.Duration <= 0 && options.Count < 1 {
+		return nil, fmt.Errorf(LoopCountError, options.Count)
+	}
+	reader, err := NewWavReader(input)
+	if err != nil {
+		return nil, err
+	}
+	f := reader.Fmt
+	bytesPerSample := int(f.BitsPerSample / 8)
+	frameSize := bytesPerSample * int(f.NumChannels)
+	data := new(bytes.Buffer)
+	buffer := make([]byte, frameSize*copyFrames)
+	for frameSize > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		n, err := reader.readData(buffer)
+		data.Write(buffer[:n-n%frameSize])
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	// Sampler loops include their End frame.
+	var frames, start, end int
+	if frameSize > 0 {
+		frames = data.Len() / frameSize
+	}
+	start, end = 0, frames
+	if reader.Sampler != nil && len(reader.Sampler.Loops) > 0 {
+		loop := reader.Sampler.Loops[0]
+		if loop.Start > loop.End || uint64(loop.End) >= uint64(frames) {
+			return nil, fmt.Errorf(LoopRangeError, loop.Start, loop.End, frames)
+		}
+		start, end = int(loop.Start), int(loop.End)+1
+	}
+	if start >= end {
+		return nil, fmt.Errorf(LoopRangeError, start, end-1, frames)
+	}
+	crossfade := int(reader.frameAt(options.Crossfade))
+	if crossfade > 0 {
+		if err := checkDecodable(f); err != nil {
+			return nil, err
+		}
+		if 2*crossfade > end-start {
+			return nil, fmt.Errorf(LoopCrossfadeError, crossfade, end-start)
+		}
+	}
+
+	wavWriter, err := NewWavWriter(output, copyFmtChunk(f))
+	if err != nil {
+		return nil, err
+	}
+	limit := int64(-1)
+	tracker := &progress{ctx: ctx, report: options.Progress,
+		throttle: options.Throttle,
+		total:    int64(frames + (options.Count-1)*(end-start-crossfade))}
+	if options.Duration > 0 {
+		limit = reader.frameAt(options.Duration) * int64(frameSize)
+		tracker.total = limit / int64(frameSize)
+	}
+	// write appends data until the output reaches its limit, returning
+	// false once it has.
+	write := func(data []byte) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		if limit >= 0 && int64(len(data)) >= limit {
+			data, limit = data[:limit], 0
+		} else if limit >= 0 {
+			limit -= int64(len(data))
+		}
+		if len(data) > 0 {
+			if err := wavWriter.appendData(data); err != nil {
+				return false, err
+			}
+			tracker.add(int64(len(data) / frameSize))
+		}
+		return limit != 0, nil
+	}
+
+	samples := data.Bytes()
+	body := samples[start*frameSize : end*frameSize]
+	seamSize := crossfade * frameSize
+	seam := make([]byte, seamSize)
+	for i := 0; i < seamSize; i += bytesPerSample {
+		tail := body[len(body)-seamSize+i : len(body)-seamSize+i+bytesPerSample]
+		head := body[i : i+bytesPerSample]
+		gain := float64(i/frameSize+1) / float64(crossfade+1)
+		setPCMValue(seam[i:i+bytesPerSample], f.AudioFormat,
+			pcmValue(tail, f.AudioFormat)*(1-gain)+
+				pcmValue(head, f.AudioFormat)*gain)
+	}
+
+	more, err := write(samples[:start*frameSize])
+	if more && err == nil {
+		more, err = write(body[:len(body)-seamSize])
+	}
+	for repetition := 1; more && err == nil; repetition++ {
+		if limit < 0 && repetition == options.Count {
+			if more, err = write(body[len(body)-seamSize:]); more && err == nil {
+				_, err = write(samples[end*frameSize:])
+			}
+			break
+		}
+		if more, err = write(seam); more && err == nil {
+			more, err = write(body[seamSize : len(body)-seamSize])
+		}
+	}
+	if ctx.Err() != nil {
+		return wavWriter.partial(ctx)
+	} else if err != nil {
+		return nil, err
+	}
+	return wavWriter, wavWriter.writeSizes()
+}
@@ -0,0 +1,92 @@
+package wav_test
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// loopedFile returns a file of four frames whose second and third frames loop.
+func loopedFile(t *testing.T) []byte {
+	sampler := &SamplerChunk{Loops: []SampleLoop{{Start: 1, End: 2}}}
+	return newFloatFile(t, []WriterOption{WithSampler(sampler)},
+		0.125, 0.25, 0.5, 0.75)
+}
+
+func TestLoop(t *testing.T) {
+	for _, test := range []struct {
+		input    []byte
+		options  LoopOptions
+		expected []float64
+	}{
+		{newFloatFile(t, nil, 0.125, 0.25), LoopOptions{Count: 3},
+			[]float64{0.125, 0.25, 0.125, 0.25, 0.125, 0.25}},
+		{loopedFile(t), LoopOptions{Count: 1},
+			[]float64{0.125, 0.25, 0.5, 0.75}},
+		{loopedFile(t), LoopOptions{Count: 2},
+			[]float64{0.125, 0.25, 0.5, 0.25, 0.5, 0.75}},
+		{loopedFile(t), LoopOptions{Duration: 6 * time.Millisecond},
+			[]float64{0.125, 0.25, 0.5, 0.25, 0.5, 0.25}},
+		{newFloatFile(t, nil, 0.125, 0.25, 0.5, 0.75), LoopOptions{
+			Count: 2, Crossfade: time.Millisecond}, []float64{
+			0.125, 0.25, 0.5, 0.4375, 0.25, 0.5, 0.75}},
+	} {
+		writer := &mockWriterAtCloser{make([]byte, 1000)}
+		wavWriter, err := Loop(writer, bytes.NewReader(test.input), test.options)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected,
+			readMix(t, writer.data[:wavWriter.Riff.Size+8]))
+	}
+}
+
+func TestLoopErrors(t *testing.T) {
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	_, err := Loop(writer, bytes.NewReader(loopedFile(t)), LoopOptions{})
+	assert.NotNil(t, err)
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"loop count of 0 must be at least 1").FindString(err.Error()))
+
+	_, err = Loop(writer, bytes.NewReader(loopedFile(t)), LoopOptions{
+		Count: 2, Crossfade: 2 * time.Millisecond})
+	assert.NotNil(t, err)
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"crossfade of 2 frames is longer than half the loop of 2 frames").
+		FindString(err.Error()))
+
+	sampler := &SamplerChunk{Loops: []SampleLoop{{Start: 1, End: 4}}}
+	input := newFloatFile(t, []WriterOption{WithSampler(sampler)}, 0.5, 0.5)
+	_, err = Loop(writer, bytes.NewReader(input), LoopOptions{Count: 2})
+	assert.NotNil(t, err)
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"loop from frame 1 to 4 does not fit in the 2 frames").
+		FindString(err.Error()))
+}
+
+func TestLoopContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	_, err := LoopContext(ctx, writer, bytes.NewReader(loopedFile(t)),
+		LoopOptions{Duration: time.Hour})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestLoopProgress(t *testing.T) {
+	var done, total int64
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	_, err := LoopContext(context.Background(), writer,
+		bytes.NewReader(loopedFile(t)), LoopOptions{
+			Count: 3, Crossfade: time.Millisecond,
+			Progress: func(d, t int64) {
+				done, total = d, t
+			},
+		})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(6), done)
+	assert.Equal(t, int64(6), total)
+}
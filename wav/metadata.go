@@ -0,0 +1,109 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	Info        = "INFO"
+	InfoIdError = "invalid INFO ID of %q; IDs must be 4 characters"
+
+	// The following IDs are the commonly used entries of a LIST INFO chunk.
+	InfoArtist       = "IART"
+	InfoComment      = "ICMT"
+	InfoCopyright    = "ICOP"
+	InfoCreationDate = "ICRD"
+	InfoEngineer     = "IENG"
+	InfoGenre        = "IGNR"
+	InfoKeywords     = "IKEY"
+	InfoProduct      = "IPRD"
+	InfoSoftware     = "ISFT"
+	InfoSubject      = "ISBJ"
+	InfoTitle        = "INAM"
+	InfoTrackNumber  = "ITRK"
+)
+
+/*
+Metadata holds the text entries of a LIST INFO chunk, keyed by their four
+character ID, such as InfoArtist ("IART") or InfoTitle ("INAM"). Entries with
+IDs the package has no constant for are kept as well.
+*/
+type Metadata map[string]string
+
+/*
+WithMetadata returns a WriterOption that writes metadata as a LIST INFO chunk.
+Every key must be a four character INFO ID.
+*/
+func WithMetadata(metadata Metadata) WriterOption {
+	return func(w *WavWriter) error {
+		for id := range metadata {
+			if len(id) != 4 {
+				return fmt.Errorf(InfoIdError, id)
+			}
+		}
+		w.Metadata = metadata
+		return nil
+	}
+}
+
+/*
+readListChunk parses the body of a LIST chunk. INFO lists are added to the
+Wav's Metadata and adtl lists to its cue points, while other list types are left
+for the caller to skip.
+*/
+func (w *Wav) readListChunk(reader io.Reader) error {
+	var listType [4]byte
+	if err := binary.Read(reader, binary.BigEndian, &listType); err != nil {
+		return err
+	}
+	if string(listType[:]) == Adtl {
+		return w.readAdtlList(reader)
+	}
+	if string(listType[:]) != Info {
+		return nil
+	}
+	if w.Metadata == nil {
+		w.Metadata = make(Metadata)
+	}
+	for {
+		subChunk, err := readSubChunk(&reader)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		text, err := readBody(reader, uint64(subChunk.Size))
+		if err != nil {
+			return err
+		}
+		// Values are NUL terminated and padded to an even length, although
+		// some writers omit the final pad byte.
+		if subChunk.Size%2 != 0 {
+			io.CopyN(io.Discard, reader, 1)
+		}
+		w.Metadata[string(subChunk.Id[:])] =
+			string(bytes.TrimRight(text, "\x00"))
+	}
+}
+
+/*
+encode returns the body of a LIST INFO chunk holding the metadata, with entries
+sorted by ID so output is deterministic.
+*/
+func (m Metadata) encode() []byte {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	buffer := bytes.NewBufferString(Info)
+	for _, id := range ids {
+		writeChunk(buffer, id, append([]byte(m[id]), 0))
+	}
+	return buffer.Bytes()
+}
@@ -0,0 +1,177 @@
+package wav_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeListInfo writes a LIST INFO chunk holding a single entry to buffer.
+func writeListInfo(buffer *bytes.Buffer, id, text string) {
+	var size4Bytes = make([]byte, 4)
+	buffer.WriteString("LIST")
+	binary.LittleEndian.PutUint32(
+		size4Bytes, uint32(4+8+len(text)+len(text)%2))
+	buffer.Write(size4Bytes)
+	buffer.WriteString("INFO")
+	buffer.WriteString(id)
+	binary.LittleEndian.PutUint32(size4Bytes, uint32(len(text)))
+	buffer.Write(size4Bytes)
+	buffer.WriteString(text)
+	if len(text)%2 != 0 {
+		buffer.WriteByte(0)
+	}
+}
+
+func TestReadMetadataBeforeData(t *testing.T) {
+	var size4Bytes = make([]byte, 4)
+	buffer := getValidHeaderAndFmtChunk()
+	// An unknown chunk with an odd size and its pad byte is skipped.
+	buffer.WriteString("junk")
+	binary.LittleEndian.PutUint32(size4Bytes, 3)
+	buffer.Write(size4Bytes)
+	buffer.Write([]byte{1, 2, 3, 0})
+	writeListInfo(buffer, "IART", "Artist\x00")
+	// Other list types are skipped.
+	buffer.WriteString("LIST")
+	binary.LittleEndian.PutUint32(size4Bytes, 4)
+	buffer.Write(size4Bytes)
+	buffer.WriteString("exif")
+	buffer.WriteString("data")
+	binary.LittleEndian.PutUint32(size4Bytes, 0)
+	buffer.Write(size4Bytes)
+
+	reader, err := NewWavReader(buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, Metadata{InfoArtist: "Artist"}, reader.Metadata)
+}
+
+func TestReadMetadataAfterData(t *testing.T) {
+	var size4Bytes = make([]byte, 4)
+	buffer := getValidHeaderAndFmtChunk()
+	buffer.WriteString("data")
+	binary.LittleEndian.PutUint32(size4Bytes, 4)
+	buffer.Write(size4Bytes)
+	buffer.Write([]byte{1, 2, 3, 4})
+	// A LIST chunk omitting the final pad byte.
+	buffer.WriteString("LIST")
+	binary.LittleEndian.PutUint32(size4Bytes, 4+8+5)
+	buffer.Write(size4Bytes)
+	buffer.WriteString("INFOINAM")
+	binary.LittleEndian.PutUint32(size4Bytes, 5)
+	buffer.Write(size4Bytes)
+	buffer.WriteString("Title")
+
+	reader, err := NewWavReader(buffer)
+	assert.Nil(t, err)
+	assert.Nil(t, reader.Metadata)
+
+	sample, err := reader.GetSample()
+	assert.Nil(t, err)
+	assert.Equal(t, Sample{{1, 2}, {3, 4}}, sample)
+	// The LIST chunk is not mistaken for samples.
+	sample, err = reader.GetSample()
+	assert.Nil(t, sample)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, Metadata{InfoTitle: "Title"}, reader.Metadata)
+
+	_, err = reader.GetSample()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestWriteMetadata(t *testing.T) {
+	writer := &mockWriterAtCloser{make([]byte, 200)}
+	metadata := Metadata{
+		InfoArtist:       "An Artist",
+		InfoCreationDate: "2014-01-01",
+	}
+	wavWriter, err := NewWavWriter(writer, nil, WithMetadata(metadata))
+	assert.Nil(t, err)
+	assert.Nil(t, wavWriter.AddSample(Sample{{1, 2}, {3, 4}}))
+
+	// RIFF header, fmt chunk, a LIST chunk of 4 + 18 + 20 bytes and the
+	// data chunk holding a single sample.
+	size := 12 + 24 + 50 + 12
+	var riffSize uint32
+	binary.Read(bytes.NewBuffer(writer.data[4:8]), binary.LittleEndian, &riffSize)
+	assert.Equal(t, uint32(size-8), riffSize)
+	assert.Equal(t, "LIST", string(writer.data[36:40]))
+
+	reader, err := NewWavReader(bytes.NewReader(writer.data[:size]))
+	assert.Nil(t, err)
+	assert.Equal(t, metadata, reader.Metadata)
+	assert.Equal(t, uint32(4), reader.Data.Size)
+	sample, err := reader.GetSample()
+	assert.Nil(t, err)
+	assert.Equal(t, Sample{{1, 2}, {3, 4}}, sample)
+}
+
+func TestWriteMetadataInvalidId(t *testing.T) {
+	writer := &mockWriterAtCloser{make([]byte, 200)}
+	wavWriter, err := NewWavWriter(
+		writer, nil, WithMetadata(Metadata{"ARTIST": "Someone"}))
+	assert.Nil(t, wavWriter)
+	re := regexp.MustCompile("invalid INFO ID of \"ARTIST\"")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
+
+// id3File returns a WAV file with an id3 chunk holding tag and no samples.
+func id3File(tag []byte) []byte {
+	var size4Bytes = make([]byte, 4)
+	buffer := getValidHeaderAndFmtChunk()
+	buffer.WriteString("id3 ")
+	binary.LittleEndian.PutUint32(size4Bytes, uint32(len(tag)))
+	buffer.Write(size4Bytes)
+	buffer.Write(tag)
+	if len(tag)%2 != 0 {
+		buffer.WriteByte(0)
+	}
+	buffer.WriteString("data")
+	binary.LittleEndian.PutUint32(size4Bytes, 0)
+	buffer.Write(size4Bytes)
+	return buffer.Bytes()
+}
+
+func TestReadID3Chunk(t *testing.T) {
+	tag := []byte("ID3\x03\x00\x00\x00\x00\x00\x0FTIT2\x00\x00\x00\x05\x00\x00\x00Song")
+	reader, err := NewWavReader(bytes.NewReader(id3File(tag)))
+	assert.Nil(t, err)
+	assert.Equal(t, "Song", reader.ID3.Title())
+}
+
+// compressedID3Tag returns a tag whose one frame inflates to a megabyte.
+func compressedID3Tag() []byte {
+	compressed := new(bytes.Buffer)
+	writer := zlib.NewWriter(compressed)
+	writer.Write(make([]byte, 1<<20))
+	writer.Close()
+	frame := bytes.NewBufferString("PRIV")
+	binary.Write(frame, binary.BigEndian, uint32(4+compressed.Len()))
+	binary.Write(frame, binary.BigEndian, uint16(0x0080))
+	binary.Write(frame, binary.BigEndian, uint32(1<<20))
+	frame.Write(compressed.Bytes())
+
+	size := frame.Len()
+	tag := []byte{'I', 'D', '3', 3, 0, 0, byte(size >> 21 & 0x7F),
+		byte(size >> 14 & 0x7F), byte(size >> 7 & 0x7F), byte(size & 0x7F)}
+	return append(tag, frame.Bytes()...)
+}
+
+func TestReadID3ChunkLimits(t *testing.T) {
+	// The tag fits in the chunk limit, but its frame inflates past it.
+	file := id3File(compressedID3Tag())
+	_, err := NewWavReaderWithOptions(
+		bytes.NewReader(file), &ReaderOptions{MaxChunkSize: 1 << 16})
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"frame PRIV inflates to more than 65536 bytes").FindString(err.Error()))
+
+	reader, err := NewWavReader(bytes.NewReader(file))
+	assert.Nil(t, err)
+	assert.Equal(t, 1<<20, len(reader.ID3.Frame("PRIV").Data))
+}
@@ -0,0 +1,326 @@
+package wav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/husafan/audio"
+)
+
+const (
+	FadeLengthError = "track %v cannot fade out as the size of its data chunk is unknown"
+	PanError        = "track %v has a pan of %v, outside the range -1 to 1"
+
+	// clipKnee is the level above which Mix starts to soft clip its output.
+	clipKnee = 0.9
+)
+
+/*
+PanLaw is the way a Track's Pan shares its level between the left and right
+channels, and so how loud a centred track is compared to one panned hard to one
+side. When the channels are summed to mono, a centred track comes out 6 dB
+louder than the original with BalancePan, 3 dB louder with ConstantPowerPan,
+1.5 dB louder with CompromisePan and at its original level with LinearPan.
+*/
+type PanLaw int
+
+const (
+	// BalancePan leaves a centred track unchanged and turns down the
+	// channel opposite the pan, like the balance control of a stereo.
+	BalancePan PanLaw = iota
+	// ConstantPowerPan turns a centred track down by 3 dB, keeping its
+	// power constant as it moves.
+	ConstantPowerPan
+	// CompromisePan turns a centred track down by 4.5 dB, halfway between
+	// ConstantPowerPan and LinearPan.
+	CompromisePan
+	// LinearPan turns a centred track down by 6 dB, keeping the sum of the
+	// channels constant as it moves.
+	LinearPan
+)
+
+/*
+gains returns the gains of the left and right channels for a pan between -1,
+hard left, and 1, hard right.
+*/
+func (l PanLaw) gains(pan float64) (float64, float64) {
+	angle := (pan + 1) * math.Pi / 4
+	switch l {
+	case ConstantPowerPan:
+		return math.Cos(angle), math.Sin(angle)
+	case CompromisePan:
+		return math.Sqrt(math.Cos(angle) * (1 - pan) / 2),
+			math.Sqrt(math.Sin(angle) * (1 + pan) / 2)
+	case LinearPan:
+		return (1 - pan) / 2, (1 + pan) / 2
+	}
+	return math.Min(1, 1-pan), math.Min(1, 1+pan)
+}
+
+// ErrNoTracks is returned by Mix when it is given nothing to mix.
+var ErrNoTracks = errors.New("mix requires at least one track")
+
+/*
+Track is a source mixed by Mix. Its samples are scaled by Gain, so the zero
+value leaves them unchanged, and start Offset after the start of the mix.
+FadeIn and FadeOut are the lengths of linear fades at the start and end of the
+track. When the fade out of one track overlaps the fade in of the next by the
+same length, the two crossfade at a constant combined gain. Stereo tracks are
+placed by Pan, from -1 for hard left through 0 for centre to 1 for hard right,
+following PanLaw. Pan is ignored for other channel counts.
+*/
+type Track struct {
+	Reader  *WavReader
+	Gain    audio.Decibel
+	Offset  time.Duration
+	FadeIn  time.Duration
+	FadeOut time.Duration
+	Pan     float64
+	PanLaw  PanLaw
+}
+
+// mixTrack holds the state of a Track while it is being mixed.
+type mixTrack struct {
+	*Track
+	gains   []float64
+	start   int64
+	length  int64
+	fadeIn  int64
+	fadeOut int64
+	read    int64
+	frames  [][]float64
+	done    bool
+
+	// block holds the track's interleaved contribution to the block being
+	// mixed, which starts first frames into the block and lasts n frames.
+	// active is set if the track was read for the block, and err holds the
+	// error that reading it returned.
+	block  []float64
+	first  int
+	n      int
+	active bool
+	err    error
+}
+
+/*
+Mix sums the remaining samples of every track into a new WAV file written to
+output, and returns the WavWriter used to write it. Every track must have the
+same audio format, channel count, sample rate and bit depth, which must be
+integer PCM or IEEE float, and the mix uses the same format. The mix lasts until
+the last track ends. Levels above 0.9 are soft clipped so that summed peaks
+bend smoothly towards full scale rather than being cut off.
+
+Tracks are decoded in parallel on up to GOMAXPROCS goroutines, so the readers of
+different tracks must not share an io.Reader. Their sources may share an
+io.ReaderAt, such as an *os.File read through io.NewSectionReader.
+*/
+func Mix(output io.WriterAt, tracks ...Track) (*WavWriter, error) {
+	return MixContext(context.Background(), output, ContextOptions{}, tracks...)
+}
+
+/*
+MixContext is Mix, stopping once ctx is done. The samples mixed until then are
+left as a valid file, and its WavWriter is returned with ctx's error. The
+Throttle of options also limits the goroutines tracks are decoded on.
+*/
+func MixContext(ctx context.Context, output io.WriterAt,
+	options ContextOptions, tracks ...Track) (*WavWriter, error) {
+	if len(tracks) == 0 {
+		return nil, ErrNoTracks
+	}
+	f := tracks[0].Reader.Fmt
+	if err := checkDecodable(f); err != nil {
+		return nil, err
+	}
+	channels := int(f.NumChannels)
+	bytesPerSample := int(f.BitsPerSample / 8)
+	frameSize := int64(bytesPerSample * channels)
+	mixTracks := make([]*mixTrack, len(tracks))
+	tracker := &progress{ctx: ctx, report: options.Progress,
+		throttle: options.Throttle}
+	workers := options.Throttle.Limit(runtime.GOMAXPROCS(0))
+	for index := range tracks {
+		track := &tracks[index]
+		reader := track.Reader
+		if !sameFormat(f, reader.Fmt) {
+			return nil, fmt.Errorf(FormatMismatchError, index,
+				describeFormat(reader.Fmt), describeFormat(f))
+		}
+		current := &mixTrack{
+			Track:   track,
+			gains:   make([]float64, channels),
+			start:   reader.frameAt(track.Offset),
+			length:  reader.RemainingFrames(),
+			fadeIn:  reader.frameAt(track.FadeIn),
+			fadeOut: reader.frameAt(track.FadeOut),
+			frames:  make([][]float64, copyFrames),
+			block:   make([]float64, copyFrames*channels),
+		}
+		if track.Pan < -1 || track.Pan > 1 {
+			return nil, fmt.Errorf(PanError, index, track.Pan)
+		}
+		for channel := range current.gains {
+			current.gains[channel] = track.Gain.Linear()
+		}
+		if channels == 2 {
+			left, right := track.PanLaw.gains(track.Pan)
+			current.gains[0] *= left
+			current.gains[1] *= right
+		}
+		if current.length < 0 && current.fadeOut > 0 {
+			return nil, fmt.Errorf(FadeLengthError, index)
+		}
+		if end := current.start + current.length; current.length < 0 ||
+			tracker.total < 0 {
+			tracker.total = -1
+		} else if end > tracker.total {
+			tracker.total = end
+		}
+		for i := range current.frames {
+			current.frames[i] = make([]float64, channels)
+		}
+		mixTracks[index] = current
+	}
+
+	wavWriter, err := NewWavWriter(output, copyFmtChunk(f))
+	if err != nil {
+		return nil, err
+	}
+	mix := make([]float64, copyFrames*channels)
+	buffer := make([]byte, copyFrames*int(frameSize))
+	for frame := int64(0); frameSize > 0; {
+		if ctx.Err() != nil {
+			return wavWriter.partial(ctx)
+		}
+		for i := range mix {
+			mix[i] = 0
+		}
+		var frames int
+		renderTracks(mixTracks, frame, channels, workers)
+		for _, track := range mixTracks {
+			if !track.active {
+				continue
+			}
+			// A track that starts after this block keeps the mix going
+			// with silence until it does.
+			if track.first >= copyFrames {
+				frames = copyFrames
+				continue
+			}
+			// The tracks are summed in order, so the mix does not depend
+			// on which finished rendering first.
+			offset := track.first * channels
+			for i, value := range track.block[:track.n*channels] {
+				mix[offset+i] += value
+			}
+			if track.first+track.n > frames {
+				frames = track.first + track.n
+			}
+			if track.err == io.EOF {
+				track.done = true
+			} else if track.err != nil {
+				return nil, track.err
+			}
+		}
+		if frames == 0 {
+			break
+		}
+		for i, value := range mix[:frames*channels] {
+			offset := i * bytesPerSample
+			setPCMValue(buffer[offset:offset+bytesPerSample],
+				f.AudioFormat, softClip(value))
+		}
+		if err := wavWriter.appendData(
+			buffer[:int64(frames)*frameSize]); err != nil {
+			return nil, err
+		}
+		frame += int64(frames)
+		tracker.add(int64(frames))
+	}
+	return wavWriter, wavWriter.writeSizes()
+}
+
+/*
+renderTracks renders the block of the mix starting at frame for every track,
+spreading the tracks over up to workers goroutines.
+*/
+func renderTracks(tracks []*mixTrack, frame int64, channels, workers int) {
+	if len(tracks) == 1 {
+		tracks[0].render(frame, channels)
+		return
+	}
+	limit := make(chan struct{}, workers)
+	var wait sync.WaitGroup
+	for _, track := range tracks {
+		wait.Add(1)
+		limit <- struct{}{}
+		go func(track *mixTrack) {
+			defer wait.Done()
+			track.render(frame, channels)
+			<-limit
+		}(track)
+	}
+	wait.Wait()
+}
+
+/*
+render reads the part of the track that falls in the block of the mix starting
+at frame, and scales it by the track's gains and envelope into its block.
+*/
+func (t *mixTrack) render(frame int64, channels int) {
+	t.active, t.n, t.err = !t.done, 0, nil
+	if t.done {
+		return
+	}
+	t.first = int(t.start - frame)
+	if t.first >= copyFrames {
+		return
+	} else if t.first < 0 {
+		t.first = 0
+	}
+	n, err := t.Reader.ReadFrames(t.frames[:copyFrames-t.first])
+	for i := 0; i < n; i++ {
+		envelope := t.envelope(t.read + int64(i))
+		for channel, value := range t.frames[i][:channels] {
+			t.block[i*channels+channel] = value * t.gains[channel] * envelope
+		}
+	}
+	t.read += int64(n)
+	t.n, t.err = n, err
+}
+
+/*
+envelope returns the gain of the track's fades at its index'th frame. Fades
+never quite reach silence or full level, so that a fade out and a fade in of
+the same length sum to exactly 1 at every frame.
+*/
+func (t *mixTrack) envelope(index int64) float64 {
+	gain := 1.0
+	if index < t.fadeIn {
+		gain = float64(index+1) / float64(t.fadeIn+1)
+	}
+	if t.length >= 0 && t.fadeOut > 0 && index >= t.length-t.fadeOut {
+		gain *= float64(t.length-index) / float64(t.fadeOut+1)
+	}
+	return gain
+}
+
+/*
+softClip passes value through unchanged up to clipKnee and above it compresses
+the level smoothly so that it approaches, but never reaches, full scale.
+*/
+func softClip(value float64) float64 {
+	level := math.Abs(value)
+	if level <= clipKnee {
+		return value
+	}
+	headroom := 1 - clipKnee
+	level = clipKnee + headroom*math.Tanh((level-clipKnee)/headroom)
+	return math.Copysign(level, value)
+}
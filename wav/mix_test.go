@@ -0,0 +1,240 @@
+package wav_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/husafan/audio"
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+newFloatFile returns a mono 32 bit float WAV file at 1000 Hz, so each
+millisecond is one sample frame, written with the given options.
+*/
+func newFloatFile(
+	t testing.TB, options []WriterOption, values ...float32) []byte {
+	fmtChunk := NewDefaultFmtChunk()
+	fmtChunk.AudioFormat = FormatIEEEFloat
+	fmtChunk.NumChannels = 1
+	fmtChunk.SampleRate = 1000
+	fmtChunk.BitsPerSample = 32
+	fmtChunk.BlockAlign = 4
+	writer := &mockWriterAtCloser{make([]byte, 1000+4*len(values))}
+	wavWriter, err := NewWavWriter(writer, fmtChunk, options...)
+	assert.Nil(t, err)
+	for _, value := range values {
+		data := make([]byte, 4)
+		binary.LittleEndian.PutUint32(data, math.Float32bits(value))
+		assert.Nil(t, wavWriter.AddSample(Sample{data}))
+	}
+	return writer.data[:wavWriter.Riff.Size+8]
+}
+
+// newFloatReader returns a WavReader for a file made by newFloatFile.
+func newFloatReader(t *testing.T, values ...float32) *WavReader {
+	reader, err := NewWavReader(bytes.NewReader(
+		newFloatFile(t, nil, values...)))
+	assert.Nil(t, err)
+	return reader
+}
+
+// readMix returns the values of the mono file written by Mix.
+func readMix(t *testing.T, data []byte) []float64 {
+	reader, err := NewWavReader(bytes.NewReader(data))
+	assert.Nil(t, err)
+	frames := make([][]float64, 100)
+	for i := range frames {
+		frames[i] = make([]float64, 1)
+	}
+	n, err := reader.ReadFrames(frames)
+	assert.Equal(t, io.EOF, err)
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = frames[i][0]
+	}
+	return values
+}
+
+func TestMix(t *testing.T) {
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := Mix(writer,
+		Track{Reader: newFloatReader(t, 0.25, 0.25, 0.25)},
+		Track{Reader: newFloatReader(t, 0.5, 0.5),
+			Gain: audio.LinearToDecibel(0.5), Offset: 2 * time.Millisecond},
+		Track{Reader: newFloatReader(t, 0.125),
+			Offset: 5 * time.Millisecond})
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(24), wavWriter.Data.Size)
+	assert.Equal(t, []float64{0.25, 0.25, 0.5, 0.25, 0, 0.125},
+		readMix(t, writer.data[:wavWriter.Riff.Size+8]))
+}
+
+func TestMixManyTracks(t *testing.T) {
+	// Enough long tracks to be rendered in parallel over several blocks.
+	const tracks, length = 8, 5000
+	mixTracks := make([]Track, tracks)
+	for i := range mixTracks {
+		values := make([]float32, length)
+		for j := range values {
+			values[j] = float32(i+1) / 64
+		}
+		mixTracks[i] = Track{
+			Reader: newFloatReader(t, values...),
+			Offset: time.Duration(i) * 700 * time.Millisecond,
+		}
+	}
+	total := (tracks-1)*700 + length
+	writer := &mockWriterAtCloser{make([]byte, 1000+4*total)}
+	wavWriter, err := Mix(writer, mixTracks...)
+	assert.Nil(t, err)
+
+	reader, err := NewWavReader(
+		bytes.NewReader(writer.data[:wavWriter.Riff.Size+8]))
+	assert.Nil(t, err)
+	mix := audio.NewBuffer(reader.Fmt.Format(), total)
+	n, err := reader.ReadBuffer(mix)
+	assert.Equal(t, total, n)
+	for frame, value := range mix.Data {
+		var expected float64
+		for i := 0; i < tracks; i++ {
+			if start := i * 700; frame >= start && frame < start+length {
+				expected += float64(i+1) / 64
+			}
+		}
+		if math.Abs(value-expected) > 1e-6 {
+			t.Fatalf("frame %v: expected %v but found %v",
+				frame, expected, value)
+		}
+	}
+}
+
+func TestMixCrossfade(t *testing.T) {
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := Mix(writer,
+		Track{Reader: newFloatReader(t, 0.5, 0.5, 0.5, 0.5),
+			FadeOut: 3 * time.Millisecond},
+		Track{Reader: newFloatReader(t, 0.5, 0.5, 0.5, 0.5),
+			Offset: time.Millisecond, FadeIn: 3 * time.Millisecond})
+	assert.Nil(t, err)
+	values := readMix(t, writer.data[:wavWriter.Riff.Size+8])
+	assert.Equal(t, 5, len(values))
+	for _, value := range values {
+		assert.InDelta(t, 0.5, value, 1e-6)
+	}
+}
+
+func TestMixSoftClip(t *testing.T) {
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := Mix(writer,
+		Track{Reader: newFloatReader(t, 0.8, -0.8, 0.4)},
+		Track{Reader: newFloatReader(t, 0.8, -0.8, 0.4)})
+	assert.Nil(t, err)
+	values := readMix(t, writer.data[:wavWriter.Riff.Size+8])
+	assert.True(t, values[0] > 0.9 && values[0] < 1)
+	assert.InDelta(t, -values[0], values[1], 1e-6)
+	assert.InDelta(t, 0.8, values[2], 1e-6)
+}
+
+func TestMixErrors(t *testing.T) {
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	_, err := Mix(writer)
+	assert.Equal(t, ErrNoTracks, err)
+
+	stereo, err := NewWavReader(bytes.NewReader(
+		newWavFile(t, nil, Sample{{1, 2}, {3, 4}})))
+	assert.Nil(t, err)
+	_, err = Mix(writer, Track{Reader: newFloatReader(t, 0.5)},
+		Track{Reader: stereo})
+	assert.NotNil(t, err)
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"input 1 is .* but the first input is").FindString(err.Error()))
+}
+
+func TestMixContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := MixContext(ctx, writer, ContextOptions{},
+		Track{Reader: newFloatReader(t, 0.25, 0.25)})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, uint32(0), wavWriter.Data.Size)
+	assert.Equal(t, []float64{},
+		readMix(t, writer.data[:wavWriter.Riff.Size+8]))
+}
+
+func TestMixProgress(t *testing.T) {
+	var reports [][2]int64
+	options := ContextOptions{Progress: func(done, total int64) {
+		reports = append(reports, [2]int64{done, total})
+	}}
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	_, err := MixContext(context.Background(), writer, options,
+		Track{Reader: newFloatReader(t, 0.25, 0.25)},
+		Track{Reader: newFloatReader(t, 0.25), Offset: 3 * time.Millisecond})
+	assert.Nil(t, err)
+	assert.Equal(t, [][2]int64{{4, 4}}, reports)
+}
+
+func TestMixThrottle(t *testing.T) {
+	options := ContextOptions{
+		Throttle: audio.Throttle{Pause: 10 * time.Millisecond, Workers: 1}}
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	start := time.Now()
+	wavWriter, err := MixContext(context.Background(), writer, options,
+		Track{Reader: newFloatReader(t, 0.25, 0.25)},
+		Track{Reader: newFloatReader(t, 0.25), Offset: time.Millisecond})
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) >= 10*time.Millisecond)
+	assert.Equal(t, []float64{0.25, 0.5},
+		readMix(t, writer.data[:wavWriter.Riff.Size+8]))
+}
+
+func TestMixPan(t *testing.T) {
+	// Both channels of the input are at half scale.
+	input := newWavFile(t, nil, Sample{{0x00, 0x40}, {0x00, 0x40}})
+	for _, test := range []struct {
+		law         PanLaw
+		pan         float64
+		left, right float64
+	}{
+		{BalancePan, 0, 0.5, 0.5},
+		{BalancePan, 0.5, 0.25, 0.5},
+		{ConstantPowerPan, 0, 0.5 / math.Sqrt2, 0.5 / math.Sqrt2},
+		{ConstantPowerPan, -1, 0.5, 0},
+		{CompromisePan, 0,
+			0.5 * audio.Decibel(-4.5).Linear(), 0.5 * audio.Decibel(-4.5).Linear()},
+		{LinearPan, 0, 0.25, 0.25},
+		{LinearPan, 1, 0, 0.5},
+	} {
+		reader, err := NewWavReader(bytes.NewReader(input))
+		assert.Nil(t, err)
+		writer := &mockWriterAtCloser{make([]byte, 1000)}
+		wavWriter, err := Mix(writer,
+			Track{Reader: reader, Pan: test.pan, PanLaw: test.law})
+		assert.Nil(t, err)
+		output, err := NewWavReader(bytes.NewReader(
+			writer.data[:wavWriter.Riff.Size+8]))
+		assert.Nil(t, err)
+		frames := [][]float64{{0, 0}}
+		_, err = output.ReadFrames(frames)
+		assert.InDelta(t, test.left, frames[0][0], 1e-3)
+		assert.InDelta(t, test.right, frames[0][1], 1e-3)
+	}
+}
+
+func TestMixPanError(t *testing.T) {
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	_, err := Mix(writer, Track{Reader: newFloatReader(t, 0.5), Pan: 2})
+	assert.NotNil(t, err)
+	assert.NotEqual(t, "", regexp.MustCompile(
+		"track 0 has a pan of 2, outside the range -1 to 1").
+		FindString(err.Error()))
+}
@@ -0,0 +1,160 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"unsafe"
+)
+
+/*
+ErrSampleView is returned by the typed views of a MappedWav when its samples
+are not of the requested type, or cannot be aliased because they are big endian
+on this machine or misaligned in the file.
+*/
+var ErrSampleView = errors.New("samples cannot be viewed as the requested type")
+
+/*
+MappedWav is a WAV file opened with OpenMapped. PCM holds the bytes of its data
+chunk, taken straight from the mapped file without copying, so editors can
+access any part of it without reads or seeks. The mapping is read-only: writing
+to PCM faults. After Close, PCM and the typed views must not be used.
+*/
+type MappedWav struct {
+	*Wav
+	PCM []byte
+
+	// release unmaps the file.
+	release func() error
+}
+
+/*
+newMappedWav parses the WAV file held by mapping, which release frees. The data
+chunk runs to the end of mapping if its size is unknown or too large.
+*/
+func newMappedWav(mapping []byte, release func() error) (*MappedWav, error) {
+	reader, err := NewWavReader(bytes.NewReader(mapping))
+	if err != nil {
+		release()
+		return nil, err
+	}
+	end := int64(len(mapping))
+	if size := int64(reader.dataSize()); size > 0 &&
+		size <= end-reader.dataStart {
+		end = reader.dataStart + size
+	}
+	return &MappedWav{Wav: reader.Wav,
+		PCM: mapping[reader.dataStart:end:end], release: release}, nil
+}
+
+// Close unmaps the file.
+func (m *MappedWav) Close() error {
+	return m.release()
+}
+
+// Frames returns the number of whole sample frames in the data chunk.
+func (m *MappedWav) Frames() int64 {
+	frameSize := int64(m.Fmt.BitsPerSample/8) * int64(m.Fmt.NumChannels)
+	if frameSize == 0 {
+		return 0
+	}
+	return int64(len(m.PCM)) / frameSize
+}
+
+/*
+ReadFrame decodes the sample frame at index into frame, which must hold one
+value per channel, as ReadFrames does. io.EOF is returned for an index outside
+the data chunk.
+*/
+func (m *MappedWav) ReadFrame(index int64, frame []float64) error {
+	if err := checkDecodable(m.Fmt); err != nil {
+		return err
+	}
+	channels := int(m.Fmt.NumChannels)
+	if len(frame) < channels {
+		return fmt.Errorf(ChannelError, channels, len(frame))
+	}
+	if index < 0 || index >= m.Frames() {
+		return io.EOF
+	}
+	bytesPerSample := int64(m.Fmt.BitsPerSample / 8)
+	offset := index * bytesPerSample * int64(channels)
+	for channel := range frame[:channels] {
+		frame[channel] = pcmValue(
+			m.PCM[offset:offset+bytesPerSample], m.Fmt.AudioFormat)
+		offset += bytesPerSample
+	}
+	return nil
+}
+
+// Int16s returns the interleaved samples of 16 bit PCM files without copying.
+func (m *MappedWav) Int16s() ([]int16, error) {
+	pointer, n, err := m.view(FormatPCM, 16)
+	if err != nil {
+		return nil, err
+	}
+	return unsafe.Slice((*int16)(pointer), n), nil
+}
+
+// Int32s returns the interleaved samples of 32 bit PCM files without copying.
+func (m *MappedWav) Int32s() ([]int32, error) {
+	pointer, n, err := m.view(FormatPCM, 32)
+	if err != nil {
+		return nil, err
+	}
+	return unsafe.Slice((*int32)(pointer), n), nil
+}
+
+/*
+Float32s returns the interleaved samples of 32 bit IEEE float files without
+copying.
+*/
+func (m *MappedWav) Float32s() ([]float32, error) {
+	pointer, n, err := m.view(FormatIEEEFloat, 32)
+	if err != nil {
+		return nil, err
+	}
+	return unsafe.Slice((*float32)(pointer), n), nil
+}
+
+/*
+Float64s returns the interleaved samples of 64 bit IEEE float files without
+copying.
+*/
+func (m *MappedWav) Float64s() ([]float64, error) {
+	pointer, n, err := m.view(FormatIEEEFloat, 64)
+	if err != nil {
+		return nil, err
+	}
+	return unsafe.Slice((*float64)(pointer), n), nil
+}
+
+/*
+view returns a pointer to the first sample and the number of samples, if the
+file's samples are of the given format and size and can be aliased.
+*/
+func (m *MappedWav) view(
+	audioFormat, bits uint16) (unsafe.Pointer, int, error) {
+	if m.Fmt.AudioFormat != audioFormat || m.Fmt.BitsPerSample != bits {
+		return nil, 0, ErrSampleView
+	}
+	size := int(bits / 8)
+	n := len(m.PCM) / size
+	if n == 0 {
+		return nil, 0, nil
+	}
+	pointer := unsafe.Pointer(&m.PCM[0])
+	if !littleEndian() || uintptr(pointer)%uintptr(size) != 0 {
+		return nil, 0, ErrSampleView
+	}
+	return pointer, n, nil
+}
+
+// littleEndian reports whether this machine stores integers little endian.
+func littleEndian() bool {
+	var probe [2]byte
+	binary.NativeEndian.PutUint16(probe[:], 1)
+	return probe[0] == 1
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package wav
+
+import "os"
+
+/*
+OpenMapped reads the WAV file at path into memory, as this platform has no
+supported way to map it. The MappedWav behaves as on other platforms, except
+that PCM is writable and the whole file is read up front.
+*/
+func OpenMapped(path string) (*MappedWav, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newMappedWav(data, func() error { return nil })
+}
@@ -0,0 +1,59 @@
+package wav_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTempFile writes data to a file in a temporary directory.
+func writeTempFile(t *testing.T, data []byte) string {
+	path := filepath.Join(t.TempDir(), "test.wav")
+	assert.Nil(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+func TestOpenMapped(t *testing.T) {
+	mapped, err := OpenMapped(writeTempFile(t,
+		newFloatFile(t, nil, 0.5, -0.25, 1)))
+	assert.Nil(t, err)
+	defer mapped.Close()
+	assert.Equal(t, int64(3), mapped.Frames())
+	assert.Equal(t, 12, len(mapped.PCM))
+
+	samples, err := mapped.Float32s()
+	assert.Nil(t, err)
+	assert.Equal(t, []float32{0.5, -0.25, 1}, samples)
+
+	frame := make([]float64, 1)
+	assert.Nil(t, mapped.ReadFrame(1, frame))
+	assert.Equal(t, -0.25, frame[0])
+	assert.Equal(t, io.EOF, mapped.ReadFrame(3, frame))
+
+	_, err = mapped.Int16s()
+	assert.Equal(t, ErrSampleView, err)
+}
+
+func TestOpenMappedPCM(t *testing.T) {
+	mapped, err := OpenMapped(writeTempFile(t, newWavFile(t, nil,
+		Sample{{0x00, 0x40}, {0x00, 0xC0}},
+		Sample{{0xFF, 0x7F}, {0x00, 0x80}})))
+	assert.Nil(t, err)
+	defer mapped.Close()
+	samples, err := mapped.Int16s()
+	assert.Nil(t, err)
+	assert.Equal(t, []int16{0x4000, -0x4000, 0x7FFF, -0x8000}, samples)
+	_, err = mapped.Float32s()
+	assert.Equal(t, ErrSampleView, err)
+}
+
+func TestOpenMappedInvalid(t *testing.T) {
+	_, err := OpenMapped(writeTempFile(t, []byte("RIFX")))
+	assert.NotNil(t, err)
+	_, err = OpenMapped(writeTempFile(t, nil))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,37 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package wav
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+/*
+OpenMapped maps the WAV file at path into memory read-only, for fast random
+access to its samples. The file is closed once mapped; the mapping lasts until
+the MappedWav is closed.
+*/
+func OpenMapped(path string) (*MappedWav, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	mapping, err := syscall.Mmap(int(file.Fd()), 0, int(info.Size()),
+		syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	return newMappedWav(mapping, func() error {
+		return syscall.Munmap(mapping)
+	})
+}
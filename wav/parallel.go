@@ -0,0 +1,165 @@
+package wav
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"runtime"
+
+	"github.com/husafan/audio"
+)
+
+// defaultBlockFrames is the number of frames DecodeParallel decodes at a time.
+const defaultBlockFrames = 1 << 16
+
+/*
+ErrUnknownDataSize is returned by DecodeParallel for a file whose data chunk
+size was never filled in, as its blocks cannot be located without it.
+*/
+var ErrUnknownDataSize = errors.New("data chunk size is unknown")
+
+/*
+ParallelOptions configures DecodeParallel. Workers is the number of blocks that
+may be decoded at once and BlockFrames the number of frames in each block. Zero
+values use GOMAXPROCS workers and blocks of 65536 frames. Progress, if not nil,
+is called after each block is processed. Throttle pauses after each block and
+further limits the workers.
+*/
+type ParallelOptions struct {
+	Workers     int
+	BlockFrames int
+	Progress    audio.ProgressFunc
+	Throttle    audio.Throttle
+}
+
+// decodedBlock is a block decoded by DecodeParallel.
+type decodedBlock struct {
+	buffer *audio.Buffer
+	err    error
+}
+
+/*
+DecodeParallel decodes the data chunk of the WAV file in source on several
+goroutines, each reading its own byte range with ReadAt, and hands the blocks
+to process in file order. Blocks are only decoded a few ahead of process, so
+memory use stays bounded. process may keep the buffers it is given.
+
+Decoding stops at the first error from reading or from process, which is
+returned, or once ctx is done, returning its error. A file that ends before its
+data chunk does is decoded up to its last whole frame. The file must be integer
+PCM or IEEE float, and ErrUnknownDataSize is returned if its data chunk size is
+unknown.
+*/
+func DecodeParallel(ctx context.Context, source io.ReaderAt,
+	options *ParallelOptions, process func(*audio.Buffer) error) error {
+	reader, err := NewWavReader(
+		io.NewSectionReader(source, 0, math.MaxInt64))
+	if err != nil {
+		return err
+	}
+	if err := checkDecodable(reader.Fmt); err != nil {
+		return err
+	}
+	frames := reader.RemainingFrames()
+	if frames < 0 {
+		return ErrUnknownDataSize
+	}
+	workers, blockFrames := runtime.GOMAXPROCS(0), defaultBlockFrames
+	if options != nil && options.Workers > 0 {
+		workers = options.Workers
+	}
+	if options != nil && options.BlockFrames > 0 {
+		blockFrames = options.BlockFrames
+	}
+	frameSize := int64(reader.Fmt.BitsPerSample/8) *
+		int64(reader.Fmt.NumChannels)
+	tracker := &progress{ctx: ctx, total: frames}
+	if options != nil {
+		tracker.report, tracker.throttle = options.Progress, options.Throttle
+		workers = options.Throttle.Limit(workers)
+	}
+
+	// Each block gets its own channel, queued in file order, so blocks can
+	// finish in any order but are processed in sequence. The queue bounds how
+	// far decoding runs ahead of process, and slots bounds the decoders
+	// running at once, which the queue alone does not: the block process
+	// waits for has already left it.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	pending := make(chan chan decodedBlock, workers)
+	slots := make(chan struct{}, workers)
+	go func() {
+		defer close(pending)
+		for start := int64(0); start < frames; start += int64(blockFrames) {
+			result := make(chan decodedBlock, 1)
+			select {
+			case pending <- result:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			count := int64(blockFrames)
+			if remaining := frames - start; remaining < count {
+				count = remaining
+			}
+			go func(offset, count int64) {
+				buffer, err := decodeBlock(
+					source, offset, int(count), reader.Fmt)
+				<-slots
+				result <- decodedBlock{buffer, err}
+			}(reader.dataStart+start*frameSize, count)
+		}
+	}()
+	for result := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+		block := <-result
+		if block.err != nil && block.err != io.EOF {
+			return block.err
+		}
+		if block.buffer.Frames() > 0 {
+			if err := process(block.buffer); err != nil {
+				return err
+			}
+			tracker.add(int64(block.buffer.Frames()))
+		}
+		if block.err == io.EOF {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+/*
+decodeBlock reads and decodes frames sample frames of format f starting offset
+bytes into source. io.EOF is returned with the whole frames read if source ends
+first.
+*/
+func decodeBlock(source io.ReaderAt, offset int64, frames int,
+	f *FmtChunk) (*audio.Buffer, error) {
+	bytesPerSample := int(f.BitsPerSample / 8)
+	raw := make([]byte, frames*bytesPerSample*int(f.NumChannels))
+	n, err := source.ReadAt(raw, offset)
+	if n == len(raw) {
+		err = nil
+	} else if err == nil || err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	buffer := audio.NewBuffer(
+		f.Format(), n/(bytesPerSample*int(f.NumChannels)))
+	for i := range buffer.Data {
+		start := i * bytesPerSample
+		buffer.Data[i] = pcmValue(
+			raw[start:start+bytesPerSample], f.AudioFormat)
+	}
+	return buffer, err
+}
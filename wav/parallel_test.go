@@ -0,0 +1,137 @@
+package wav_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/husafan/audio"
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// decodeAll collects the values DecodeParallel decodes from data.
+func decodeAll(t *testing.T, ctx context.Context, data []byte,
+	options *ParallelOptions) ([]float64, error) {
+	var values []float64
+	err := DecodeParallel(ctx, bytes.NewReader(data), options,
+		func(buffer *audio.Buffer) error {
+			assert.Equal(t, 1, buffer.Format.Channels)
+			values = append(values, buffer.Data...)
+			return nil
+		})
+	return values, err
+}
+
+func TestDecodeParallel(t *testing.T) {
+	samples := make([]float32, 100)
+	expected := make([]float64, len(samples))
+	for i := range samples {
+		samples[i] = float32(i) / 128
+		expected[i] = float64(samples[i])
+	}
+	file := newFloatFile(t, nil, samples...)
+
+	// Blocks finish out of order but are processed in file order.
+	values, err := decodeAll(t, context.Background(), file,
+		&ParallelOptions{Workers: 4, BlockFrames: 7})
+	assert.Nil(t, err)
+	assert.Equal(t, expected, values)
+
+	values, err = decodeAll(t, context.Background(), file, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, values)
+
+	// A truncated file is decoded up to its last whole frame.
+	values, err = decodeAll(t, context.Background(), file[:len(file)-6],
+		&ParallelOptions{BlockFrames: 16})
+	assert.Nil(t, err)
+	assert.Equal(t, expected[:98], values)
+}
+
+/*
+concurrentReader is an io.ReaderAt that records the most ReadAt calls it has
+served at once, each of which takes a millisecond.
+*/
+type concurrentReader struct {
+	*bytes.Reader
+	lock    sync.Mutex
+	active  int
+	maximum int
+}
+
+func (r *concurrentReader) ReadAt(p []byte, offset int64) (int, error) {
+	r.lock.Lock()
+	r.active++
+	if r.active > r.maximum {
+		r.maximum = r.active
+	}
+	r.lock.Unlock()
+	time.Sleep(time.Millisecond)
+	r.lock.Lock()
+	r.active--
+	r.lock.Unlock()
+	return r.Reader.ReadAt(p, offset)
+}
+
+func TestDecodeParallelWorkers(t *testing.T) {
+	file := newFloatFile(t, nil, make([]float32, 40)...)
+	for _, options := range []ParallelOptions{
+		{Workers: 3, BlockFrames: 1},
+		{Workers: 8, BlockFrames: 1, Throttle: audio.Throttle{Workers: 2}},
+		{Workers: 1, BlockFrames: 1},
+	} {
+		source := &concurrentReader{Reader: bytes.NewReader(file)}
+		var blocks int
+		err := DecodeParallel(context.Background(), source, &options,
+			func(*audio.Buffer) error {
+				blocks++
+				return nil
+			})
+		assert.Nil(t, err)
+		assert.Equal(t, 40, blocks)
+		assert.True(t, source.maximum <= options.Throttle.Limit(options.Workers),
+			"%v decoders for %+v", source.maximum, options)
+	}
+}
+
+func TestDecodeParallelErrors(t *testing.T) {
+	file := newFloatFile(t, nil, 0.5, 0.25, 0.125)
+	stop := errors.New("stop")
+	var blocks int
+	err := DecodeParallel(context.Background(), bytes.NewReader(file),
+		&ParallelOptions{BlockFrames: 1}, func(*audio.Buffer) error {
+			blocks++
+			return stop
+		})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, blocks)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = decodeAll(t, ctx, file, nil)
+	assert.Equal(t, context.Canceled, err)
+
+	// Streamed files leave the data chunk size at 0.
+	unknown := append([]byte{}, file...)
+	data := bytes.Index(unknown, []byte(Data))
+	copy(unknown[data+4:], []byte{0, 0, 0, 0})
+	_, err = decodeAll(t, context.Background(), unknown, nil)
+	assert.Equal(t, ErrUnknownDataSize, err)
+}
+
+func TestDecodeParallelProgress(t *testing.T) {
+	file := newFloatFile(t, nil, 0.5, 0.25, 0.125)
+	var reports [][2]int64
+	_, err := decodeAll(t, context.Background(), file, &ParallelOptions{
+		BlockFrames: 2,
+		Progress: func(done, total int64) {
+			reports = append(reports, [2]int64{done, total})
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, [][2]int64{{2, 3}, {3, 3}}, reports)
+}
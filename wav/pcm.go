@@ -0,0 +1,270 @@
+package wav
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/husafan/audio"
+)
+
+const (
+	UnsupportedFormatError = "audio format %v with %v bits per sample is not supported"
+
+	// The following values are the audio formats of a fmt chunk that the
+	// package can decode.
+	FormatPCM       uint16 = 1
+	FormatIEEEFloat uint16 = 3
+)
+
+/*
+checkDecodable returns a non-nil error unless the samples of f can be converted
+to and from floating point values by pcmValue and setPCMValue.
+*/
+func checkDecodable(f *FmtChunk) error {
+	switch {
+	case f.AudioFormat == FormatPCM && f.BitsPerSample >= 8 &&
+		f.BitsPerSample <= 32 && f.BitsPerSample%8 == 0:
+		return nil
+	case f.AudioFormat == FormatIEEEFloat &&
+		(f.BitsPerSample == 32 || f.BitsPerSample == 64):
+		return nil
+	}
+	return fmt.Errorf(UnsupportedFormatError, f.AudioFormat, f.BitsPerSample)
+}
+
+/*
+pcmValue decodes a single little endian channel sample to a value between -1
+and 1. 8 bit PCM samples are unsigned, while wider ones are signed. The format
+must have been checked by checkDecodable.
+*/
+func pcmValue(data []byte, audioFormat uint16) float64 {
+	if audioFormat == FormatIEEEFloat {
+		if len(data) == 4 {
+			return float64(math.Float32frombits(
+				binary.LittleEndian.Uint32(data)))
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(data))
+	}
+	if len(data) == 1 {
+		return (float64(data[0]) - 128) / 128
+	}
+	var value int64
+	for i := len(data) - 1; i >= 0; i-- {
+		value = value<<8 | int64(data[i])
+	}
+	// Sign extend from the width of the sample.
+	shift := uint(64 - 8*len(data))
+	value = value << shift >> shift
+	return float64(value) / float64(int64(1)<<uint(8*len(data)-1))
+}
+
+/*
+setPCMValue encodes value, clipped to the range -1 to 1 for integer formats,
+into data as a little endian channel sample. The format must have been checked
+by checkDecodable.
+*/
+func setPCMValue(data []byte, audioFormat uint16, value float64) {
+	if audioFormat == FormatIEEEFloat {
+		if len(data) == 4 {
+			binary.LittleEndian.PutUint32(
+				data, math.Float32bits(float32(value)))
+		} else {
+			binary.LittleEndian.PutUint64(data, math.Float64bits(value))
+		}
+		return
+	}
+	scale := float64(int64(1) << uint(8*len(data)-1))
+	scaled := math.Round(value * scale)
+	scaled = math.Max(-scale, math.Min(scale-1, scaled))
+	if len(data) == 1 {
+		data[0] = byte(scaled + 128)
+		return
+	}
+	integer := int64(scaled)
+	for i := range data {
+		data[i] = byte(integer >> uint(8*i))
+	}
+}
+
+/*
+ReadFrames decodes sample frames from the data chunk into frames, which are
+filled in order with one value between -1 and 1 per channel. Each entry of
+frames must hold at least one value per channel. The number of frames decoded is
+returned along with io.EOF once the data chunk is exhausted. Unlike GetSample,
+the frames are not kept in the reader's DataChunk. A non-nil error is returned
+for formats other than integer PCM and IEEE float.
+*/
+func (w *WavReader) ReadFrames(frames [][]float64) (int, error) {
+	channels := int(w.Fmt.NumChannels)
+	for _, frame := range frames {
+		if len(frame) < channels {
+			return 0, fmt.Errorf(ChannelError, channels, len(frame))
+		}
+	}
+	raw, count, err := w.readRawFrames(len(frames))
+	bytesPerSample := int(w.Fmt.BitsPerSample / 8)
+	for i := 0; i < count; i++ {
+		for channel := 0; channel < channels; channel++ {
+			offset := (i*channels + channel) * bytesPerSample
+			frames[i][channel] = pcmValue(
+				raw[offset:offset+bytesPerSample], w.Fmt.AudioFormat)
+		}
+	}
+	return count, err
+}
+
+/*
+ReadBuffer decodes sample frames from the data chunk into buffer, filling it
+from the start. The number of frames decoded is returned along with io.EOF once
+the data chunk is exhausted. A non-nil error is returned if the buffer does not
+have the file's channel count, or for formats ReadFrames cannot decode.
+*/
+func (w *WavReader) ReadBuffer(buffer *audio.Buffer) (int, error) {
+	channels := int(w.Fmt.NumChannels)
+	if buffer.Format.Channels != channels {
+		return 0, fmt.Errorf(ChannelError, channels, buffer.Format.Channels)
+	}
+	raw, count, err := w.readRawFrames(buffer.Frames())
+	bytesPerSample := int(w.Fmt.BitsPerSample / 8)
+	for i := 0; i < count*channels; i++ {
+		offset := i * bytesPerSample
+		buffer.Data[i] = pcmValue(
+			raw[offset:offset+bytesPerSample], w.Fmt.AudioFormat)
+	}
+	return count, err
+}
+
+/*
+ReadAll decodes every remaining sample frame of the data chunk into a new Buffer
+in the file's format, such as an impulse response for dsp.NewConvolver. A
+non-nil error is returned for formats ReadFrames cannot decode.
+*/
+func (w *WavReader) ReadAll() (*audio.Buffer, error) {
+	format := w.Fmt.Format()
+	buffer := &audio.Buffer{Format: format}
+	block := audio.NewBuffer(format, copyFrames)
+	if remaining := w.RemainingFrames(); remaining > 0 {
+		buffer.Data = make([]float64, 0, int(remaining)*format.Channels)
+	}
+	for {
+		count, err := w.ReadBuffer(block)
+		buffer.Data = append(buffer.Data, block.Data[:count*format.Channels]...)
+		if err == io.EOF {
+			return buffer, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+/*
+readRawFrames reads up to frames whole sample frames of a decodable format into
+the reader's scratch space, returning them and the number of frames read.
+*/
+func (w *WavReader) readRawFrames(frames int) ([]byte, int, error) {
+	if err := checkDecodable(w.Fmt); err != nil {
+		return nil, 0, err
+	}
+	frameSize := int(w.Fmt.BitsPerSample/8) * int(w.Fmt.NumChannels)
+	if frameSize == 0 {
+		return nil, 0, io.EOF
+	}
+	if len(w.scratch) < frames*frameSize {
+		w.scratch = make([]byte, frames*frameSize)
+	}
+	n, err := w.readData(w.scratch[:frames*frameSize])
+	return w.scratch[:n], n / frameSize, err
+}
+
+/*
+WriteBuffer encodes the frames of buffer and appends them to the data chunk.
+Integer samples are clipped to the range -1 to 1. A non-nil error is returned if
+the buffer does not have the file's channel count, or for formats other than
+integer PCM and IEEE float.
+*/
+func (w *WavWriter) WriteBuffer(buffer *audio.Buffer) error {
+	if err := checkDecodable(w.Fmt); err != nil {
+		return err
+	}
+	channels := int(w.Fmt.NumChannels)
+	if buffer.Format.Channels != channels {
+		return fmt.Errorf(ChannelError, channels, buffer.Format.Channels)
+	}
+	bytesPerSample := int(w.Fmt.BitsPerSample / 8)
+	values := buffer.Data[:buffer.Frames()*channels]
+	data := make([]byte, len(values)*bytesPerSample)
+	for i, value := range values {
+		offset := i * bytesPerSample
+		setPCMValue(
+			data[offset:offset+bytesPerSample], w.Fmt.AudioFormat, value)
+	}
+	if err := w.appendData(data); err != nil {
+		return err
+	}
+	return w.commit()
+}
+
+// Format returns the in-memory format of the samples described by the chunk.
+func (f *FmtChunk) Format() audio.Format {
+	return audio.Format{
+		SampleRate: f.SampleRate, Channels: int(f.NumChannels)}
+}
+
+/*
+PCMReader returns an io.Reader of the raw bytes of the data chunk, such as
+interleaved little endian PCM, starting at the reader's current position. It
+shares that position with GetSample and ReadFrames, and returns io.EOF once the
+data chunk is exhausted, so it can feed tools that read raw audio, such as
+ffmpeg or sox.
+*/
+func (w *WavReader) PCMReader() io.Reader {
+	return pcmReader{w}
+}
+
+// pcmReader reads the data chunk of a WavReader.
+type pcmReader struct {
+	reader *WavReader
+}
+
+func (p pcmReader) Read(data []byte) (int, error) {
+	return p.reader.readData(data)
+}
+
+/*
+PCMWriter returns an io.Writer that appends raw interleaved samples, encoded as
+described by the fmt chunk, to the data chunk. Only whole frames are added to
+the file: the bytes of an incomplete frame are held until later writes complete
+it, and are dropped if they never are.
+*/
+func (w *WavWriter) PCMWriter() io.Writer {
+	return &pcmWriter{writer: w}
+}
+
+// pcmWriter writes to the data chunk of a WavWriter.
+type pcmWriter struct {
+	writer *WavWriter
+	// pending holds the bytes of an incomplete frame.
+	pending []byte
+}
+
+func (p *pcmWriter) Write(data []byte) (int, error) {
+	frameSize := int(p.writer.Fmt.BitsPerSample/8) *
+		int(p.writer.Fmt.NumChannels)
+	buffered := append(p.pending, data...)
+	whole := len(buffered)
+	if frameSize > 0 {
+		whole -= whole % frameSize
+	}
+	if whole > 0 {
+		if err := p.writer.appendData(buffered[:whole]); err != nil {
+			return 0, err
+		}
+		if err := p.writer.commit(); err != nil {
+			return 0, err
+		}
+	}
+	p.pending = append(p.pending[:0], buffered[whole:]...)
+	return len(data), nil
+}
@@ -0,0 +1,165 @@
+package wav_test
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"testing"
+
+	"github.com/husafan/audio"
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadFrames(t *testing.T) {
+	reader, err := NewWavReader(bytes.NewReader(newWavFile(t, nil,
+		Sample{{0x00, 0x40}, {0x00, 0xC0}},
+		Sample{{0xFF, 0x7F}, {0x00, 0x80}},
+		Sample{{0x00, 0x00}, {0x00, 0x00}})))
+	assert.Nil(t, err)
+
+	frames := [][]float64{make([]float64, 2), make([]float64, 2)}
+	n, err := reader.ReadFrames(frames)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, [][]float64{{0.5, -0.5}, {32767.0 / 32768, -1}}, frames)
+
+	n, err = reader.ReadFrames(frames)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []float64{0, 0}, frames[0])
+}
+
+func TestReadFramesFormats(t *testing.T) {
+	for _, test := range []struct {
+		audioFormat uint16
+		bits        uint16
+		sample      []byte
+		value       float64
+	}{
+		{1, 8, []byte{0x40}, -0.5},
+		{1, 24, []byte{0x00, 0x00, 0xC0}, -0.5},
+		{1, 32, []byte{0x00, 0x00, 0x00, 0x40}, 0.5},
+		{3, 32, []byte{0x00, 0x00, 0x40, 0x3F}, 0.75},
+		{3, 64, []byte{0, 0, 0, 0, 0, 0, 0xE8, 0xBF}, -0.75},
+	} {
+		fmtChunk := NewDefaultFmtChunk()
+		fmtChunk.NumChannels = 1
+		fmtChunk.AudioFormat = test.audioFormat
+		fmtChunk.BitsPerSample = test.bits
+		reader, err := NewWavReader(bytes.NewReader(
+			newWavFile(t, fmtChunk, Sample{test.sample})))
+		assert.Nil(t, err)
+		frames := [][]float64{{0}}
+		n, err := reader.ReadFrames(frames)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, test.value, frames[0][0])
+	}
+}
+
+func TestReadFramesErrors(t *testing.T) {
+	reader, err := NewWavReader(bytes.NewReader(
+		newWavFile(t, nil, Sample{{1, 2}, {3, 4}})))
+	assert.Nil(t, err)
+	_, err = reader.ReadFrames([][]float64{{0}})
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("expected 2 channels; found 1")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+
+	adpcm := NewDefaultFmtChunk()
+	adpcm.AudioFormat = 2
+	reader, err = NewWavReader(bytes.NewReader(
+		newWavFile(t, adpcm, Sample{{1, 2}, {3, 4}})))
+	assert.Nil(t, err)
+	_, err = reader.ReadFrames([][]float64{{0, 0}})
+	assert.NotNil(t, err)
+}
+
+func TestPCMReader(t *testing.T) {
+	reader, err := NewWavReader(bytes.NewReader(newWavFile(t, nil,
+		Sample{{1, 2}, {3, 4}}, Sample{{5, 6}, {7, 8}},
+		Sample{{9, 10}, {11, 12}})))
+	assert.Nil(t, err)
+	sample, err := reader.GetSample()
+	assert.Nil(t, err)
+	assert.Equal(t, Sample{{1, 2}, {3, 4}}, sample)
+
+	// The PCM reader continues from the samples already read.
+	data, err := io.ReadAll(reader.PCMReader())
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{5, 6, 7, 8, 9, 10, 11, 12}, data)
+	_, err = reader.GetSample()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestPCMWriter(t *testing.T) {
+	writer := &mockWriterAtCloser{make([]byte, 100)}
+	wavWriter, err := NewWavWriter(writer, nil)
+	assert.Nil(t, err)
+	pcm := wavWriter.PCMWriter()
+
+	// Bytes are only added to the file once they complete a frame.
+	n, err := pcm.Write([]byte{1, 2, 3, 4, 5, 6})
+	assert.Nil(t, err)
+	assert.Equal(t, 6, n)
+	assert.Equal(t, uint32(4), wavWriter.Data.Size)
+	copied, err := io.Copy(pcm, bytes.NewReader([]byte{7, 8, 9}))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), copied)
+	assert.Equal(t, uint32(8), wavWriter.Data.Size)
+
+	samples := readAllSamples(t, writer.data[:wavWriter.Riff.Size+8])
+	assert.Equal(t, []Sample{{{1, 2}, {3, 4}}, {{5, 6}, {7, 8}}}, samples)
+}
+
+func TestReadWriteBuffer(t *testing.T) {
+	writer := &mockWriterAtCloser{make([]byte, 100)}
+	wavWriter, err := NewWavWriter(writer, nil)
+	assert.Nil(t, err)
+	format := wavWriter.Fmt.Format()
+	assert.Equal(t, 2, format.Channels)
+	assert.Equal(t, wavWriter.Fmt.SampleRate, format.SampleRate)
+
+	buffer := audio.NewBuffer(format, 3)
+	copy(buffer.Data, []float64{0.5, -0.5, 1.5, -1, 0, 0.25})
+	assert.Nil(t, wavWriter.WriteBuffer(buffer))
+	err = wavWriter.WriteBuffer(audio.NewBuffer(audio.Format{Channels: 1}, 1))
+	re := regexp.MustCompile("expected 2 channels; found 1")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+
+	reader, err := NewWavReader(
+		bytes.NewReader(writer.data[:wavWriter.Riff.Size+8]))
+	assert.Nil(t, err)
+	read := audio.NewBuffer(reader.Fmt.Format(), 2)
+	n, err := reader.ReadBuffer(read)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	// Values beyond full scale are clipped.
+	assert.Equal(t, []float64{0.5, -0.5, 32767.0 / 32768, -1}, read.Data)
+	n, err = reader.ReadBuffer(read)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []float64{0, 0.25}, read.Data[:2])
+}
+
+func TestReadAll(t *testing.T) {
+	writer := &mockWriterAtCloser{make([]byte, 21000)}
+	wavWriter, err := NewWavWriter(writer, nil)
+	assert.Nil(t, err)
+	buffer := audio.NewBuffer(wavWriter.Fmt.Format(), 5000)
+	for i := range buffer.Data {
+		buffer.Data[i] = float64(i%100) / 128
+	}
+	assert.Nil(t, wavWriter.WriteBuffer(buffer))
+
+	reader, err := NewWavReader(
+		bytes.NewReader(writer.data[:wavWriter.Riff.Size+8]))
+	assert.Nil(t, err)
+	read, err := reader.ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, buffer.Format, read.Format)
+	assert.InDeltaSlice(t, buffer.Data, read.Data, 1e-4)
+	read, err = reader.ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, read.Frames())
+}
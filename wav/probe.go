@@ -0,0 +1,91 @@
+package wav
+
+import (
+	"io"
+	"time"
+
+	"github.com/husafan/audio"
+)
+
+/*
+codecNames holds the names reported by audio.Probe for the audio formats of
+the fmt chunk.
+*/
+var codecNames = map[uint16]string{
+	1:      "pcm",
+	3:      "float",
+	6:      "alaw",
+	7:      "mulaw",
+	0xFFFE: "extensible",
+}
+
+/*
+metadataNames holds the names reported by audio.Probe for LIST INFO entries.
+Entries with other IDs are reported under their ID.
+*/
+var metadataNames = map[string]string{
+	InfoArtist:       "artist",
+	InfoComment:      "comment",
+	InfoCopyright:    "copyright",
+	InfoCreationDate: "date",
+	InfoEngineer:     "engineer",
+	InfoGenre:        "genre",
+	InfoKeywords:     "keywords",
+	InfoProduct:      "album",
+	InfoSoftware:     "software",
+	InfoSubject:      "subject",
+	InfoTitle:        "title",
+	InfoTrackNumber:  "track",
+}
+
+func init() {
+	audio.RegisterFormat("wav", "RIFF????WAVE", probe)
+	audio.RegisterFormat("wav", "RF64????WAVE", probe)
+	audio.RegisterFormat("wav", wave64Riff, probe)
+}
+
+/*
+probe summarizes a WAV file for audio.Probe from its header. Only the chunks
+before the data chunk are read, so metadata stored after the samples is not
+reported.
+*/
+func probe(reader io.Reader) (*audio.Info, error) {
+	wavReader, err := NewWavReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	info := &audio.Info{
+		Format:     "wav",
+		Codec:      codecNames[wavReader.Fmt.AudioFormat],
+		SampleRate: wavReader.Fmt.SampleRate,
+		Channels:   int(wavReader.Fmt.NumChannels),
+		BitDepth:   int(wavReader.Fmt.BitsPerSample),
+		Metadata:   make(map[string]string),
+	}
+	if info.Codec == "" {
+		info.Codec = "unknown"
+	}
+	frameSize := uint64(info.BitDepth/8) * uint64(info.Channels)
+	if frameSize > 0 && info.SampleRate > 0 {
+		frames := wavReader.dataSize() / frameSize
+		info.Duration = time.Duration(frames) * time.Second /
+			time.Duration(info.SampleRate)
+	}
+
+	for id, value := range wavReader.Metadata {
+		if name, ok := metadataNames[id]; ok {
+			id = name
+		}
+		info.Metadata[id] = value
+	}
+	if tag := wavReader.ID3; tag != nil {
+		for name, value := range map[string]string{
+			"title": tag.Title(), "artist": tag.Artist(), "album": tag.Album(),
+		} {
+			if _, ok := info.Metadata[name]; !ok && value != "" {
+				info.Metadata[name] = value
+			}
+		}
+	}
+	return info, nil
+}
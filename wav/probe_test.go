@@ -0,0 +1,37 @@
+package wav_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/husafan/audio"
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbeWav(t *testing.T) {
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	fmtChunk := NewDefaultFmtChunk()
+	fmtChunk.SampleRate = 4
+	wavWriter, err := NewWavWriter(writer, fmtChunk, WithMetadata(
+		Metadata{InfoTitle: "Title", InfoProduct: "Album", "IXYZ": "Other"}))
+	assert.Nil(t, err)
+	for i := 0; i < 6; i++ {
+		assert.Nil(t, wavWriter.AddSample(Sample{{1, 2}, {3, 4}}))
+	}
+
+	info, err := audio.Probe(
+		bytes.NewReader(writer.data[:wavWriter.Riff.Size+8]))
+	assert.Nil(t, err)
+	assert.Equal(t, &audio.Info{
+		Format:     "wav",
+		Codec:      "pcm",
+		SampleRate: 4,
+		Channels:   2,
+		BitDepth:   16,
+		Duration:   1500 * time.Millisecond,
+		Metadata: map[string]string{
+			"title": "Title", "album": "Album", "IXYZ": "Other"},
+	}, info)
+}
@@ -0,0 +1,41 @@
+package wav
+
+import (
+	"context"
+
+	"github.com/husafan/audio"
+)
+
+/*
+ContextOptions configures the Context variants of the operations that take no
+other options: ConcatContext, ApplyEditsContext, MixContext and
+WavReader.ExtractRangeContext. Progress, if not nil, is called as they work,
+and Throttle slows them down.
+*/
+type ContextOptions struct {
+	Progress audio.ProgressFunc
+	Throttle audio.Throttle
+}
+
+/*
+progress counts the sample frames done by an operation and reports them to its
+ProgressFunc. A total of -1 means it is not known. As add is called once per
+block, it also pauses for the operation's audio.Throttle.
+*/
+type progress struct {
+	ctx      context.Context
+	report   audio.ProgressFunc
+	throttle audio.Throttle
+	done     int64
+	total    int64
+}
+
+/*
+add counts frames as done, reports the progress and pauses. Cancellation during
+the pause is left to the caller's next check of the context.
+*/
+func (p *progress) add(frames int64) {
+	p.done += frames
+	p.report.Report(p.done, p.total)
+	p.throttle.Wait(p.ctx)
+}
@@ -0,0 +1,105 @@
+package wav
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	DataSizeError = "data chunk of %v bytes exceeds the 4 GB limit of RIFF files"
+	Ds64          = "ds64"
+	Ds64Error     = "RF64 file starts with a %s chunk instead of ds64"
+	Junk          = "JUNK"
+	RF64          = "RF64"
+
+	// ds64Size is the size of a ds64 chunk body without a chunk size table.
+	ds64Size = 28
+	// ds64Offset is the offset of the ds64 chunk, which directly follows the
+	// RIFF header, or of the JUNK chunk reserving space for it.
+	ds64Offset = 12
+)
+
+/*
+Ds64Chunk holds the 64 bit sizes of an RF64 file, which replace the RIFF and
+data chunk sizes once those no longer fit in 32 bits. The 32 bit sizes are then
+set to 0xFFFFFFFF. SampleCount is the number of sample frames in the data chunk.
+*/
+type Ds64Chunk struct {
+	RiffSize    uint64
+	DataSize    uint64
+	SampleCount uint64
+}
+
+/*
+WithRF64 returns a WriterOption that lets the file grow past 4 GB. A JUNK chunk
+is reserved at the start of the file, and the writer switches to the RF64 format
+by replacing it with a ds64 chunk when the file size would overflow its 32 bit
+RIFF size. Files that stay smaller remain ordinary WAV files. Without this
+option, AddSample returns an error rather than overflow.
+*/
+func WithRF64() WriterOption {
+	return func(w *WavWriter) error {
+		w.reserveDs64 = true
+		return nil
+	}
+}
+
+/*
+dataSize returns the size of the data chunk, taken from the ds64 chunk when the
+file is an RF64 file.
+*/
+func (w *Wav) dataSize() uint64 {
+	if w.Ds64 != nil {
+		return w.Ds64.DataSize
+	}
+	return uint64(w.Data.Size)
+}
+
+/*
+readDs64Chunk reads the ds64 chunk that must follow the RIFF header of an RF64
+file. Any chunk size table is skipped.
+*/
+func readDs64Chunk(reader *io.Reader) (*Ds64Chunk, error) {
+	subChunk, err := readSubChunk(reader)
+	if err != nil {
+		return nil, err
+	}
+	if id := string(subChunk.Id[:]); id != Ds64 {
+		return nil, fmt.Errorf(Ds64Error, id)
+	}
+	if subChunk.Size < ds64Size {
+		return nil, fmt.Errorf(ChunkSizeError, Ds64, subChunk.Size)
+	}
+	body := io.LimitReader(*reader, int64(subChunk.Size))
+	ds64 := &Ds64Chunk{}
+	if err := binary.Read(body, binary.LittleEndian, ds64); err != nil {
+		return nil, err
+	}
+	return ds64, skip(*reader, int64(subChunk.Size)%2, body)
+}
+
+/*
+setDataSize updates the RIFF and data chunk sizes for a data chunk of size
+bytes, switching the file to RF64 if its size no longer fits in 32 bits.
+*/
+func (w *WavWriter) setDataSize(size uint64) {
+	riffSize := w.riffSize(size)
+	if w.Ds64 == nil && riffSize > math.MaxUint32 {
+		w.Ds64 = &Ds64Chunk{}
+		copy(w.Riff.Id[:], RF64)
+	}
+	if w.Ds64 == nil {
+		w.Riff.Size = uint32(riffSize)
+		w.Data.Size = uint32(size)
+		return
+	}
+	w.Ds64.RiffSize = riffSize
+	w.Ds64.DataSize = size
+	if w.Fmt.BlockAlign > 0 {
+		w.Ds64.SampleCount = size / uint64(w.Fmt.BlockAlign)
+	}
+	w.Riff.Size = math.MaxUint32
+	w.Data.Size = math.MaxUint32
+}
@@ -0,0 +1,138 @@
+package wav_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+sparseWriterAt keeps only the bytes written to the start of a file, so writers
+can be tested at offsets past 4 GB without storing the samples.
+*/
+type sparseWriterAt struct {
+	header []byte
+}
+
+func (s *sparseWriterAt) WriteAt(p []byte, off int64) (n int, err error) {
+	if off < int64(len(s.header)) {
+		copy(s.header[off:], p)
+	}
+	return len(p), nil
+}
+
+func TestAddSampleOverflowWithoutRF64(t *testing.T) {
+	writer := &sparseWriterAt{make([]byte, 100)}
+	wavWriter, err := NewWavWriter(writer, nil)
+	assert.Nil(t, err)
+	wavWriter.Data.Size = math.MaxUint32 - 4
+
+	err = wavWriter.AddSample(Sample{{1, 2}, {3, 4}})
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("exceeds the 4 GB limit of RIFF files")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
+
+func TestAddSampleUpgradesToRF64(t *testing.T) {
+	writer := &sparseWriterAt{make([]byte, 100)}
+	wavWriter, err := NewWavWriter(writer, nil, WithRF64())
+	assert.Nil(t, err)
+	assert.Equal(t, "RIFF", string(writer.header[:4]))
+	assert.Equal(t, "JUNK", string(writer.header[12:16]))
+	assert.Equal(t, "fmt ", string(writer.header[48:52]))
+	assert.Equal(t, "data", string(writer.header[72:76]))
+
+	wavWriter.Data.Size = math.MaxUint32 - 4
+	assert.Nil(t, wavWriter.AddSample(Sample{{1, 2}, {3, 4}}))
+	assert.Nil(t, wavWriter.AddSample(Sample{{1, 2}, {3, 4}}))
+
+	dataSize := uint64(math.MaxUint32) + 4
+	assert.Equal(t, &Ds64Chunk{
+		RiffSize:    dataSize + 72,
+		DataSize:    dataSize,
+		SampleCount: dataSize / 4,
+	}, wavWriter.Ds64)
+	assert.Equal(t, "RF64", string(writer.header[:4]))
+	assert.Equal(t, uint32(math.MaxUint32),
+		binary.LittleEndian.Uint32(writer.header[4:8]))
+	assert.Equal(t, "ds64", string(writer.header[12:16]))
+	assert.Equal(t, uint32(28), binary.LittleEndian.Uint32(writer.header[16:20]))
+	assert.Equal(t, dataSize+72, binary.LittleEndian.Uint64(writer.header[20:28]))
+	assert.Equal(t, dataSize, binary.LittleEndian.Uint64(writer.header[28:36]))
+	assert.Equal(t, dataSize/4, binary.LittleEndian.Uint64(writer.header[36:44]))
+	assert.Equal(t, uint32(math.MaxUint32),
+		binary.LittleEndian.Uint32(writer.header[76:80]))
+}
+
+func TestSmallRF64WriterIsReadable(t *testing.T) {
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := NewWavWriter(writer, nil, WithRF64())
+	assert.Nil(t, err)
+	assert.Nil(t, wavWriter.AddSample(Sample{{1, 2}, {3, 4}}))
+	assert.Nil(t, wavWriter.Ds64)
+
+	reader, err := NewWavReader(
+		bytes.NewReader(writer.data[:wavWriter.Riff.Size+8]))
+	assert.Nil(t, err)
+	sample, err := reader.GetSample()
+	assert.Nil(t, err)
+	assert.Equal(t, Sample{{1, 2}, {3, 4}}, sample)
+	_, err = reader.GetSample()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReadRF64(t *testing.T) {
+	var size4Bytes = make([]byte, 4)
+	var size8Bytes = make([]byte, 8)
+	valid := getValidHeaderAndFmtChunk().Bytes()
+	buffer := bytes.NewBufferString("RF64")
+	binary.LittleEndian.PutUint32(size4Bytes, math.MaxUint32)
+	buffer.Write(size4Bytes)
+	buffer.WriteString("WAVE")
+	buffer.WriteString("ds64")
+	binary.LittleEndian.PutUint32(size4Bytes, 28)
+	buffer.Write(size4Bytes)
+	for _, size := range []uint64{72, 8, 2} {
+		binary.LittleEndian.PutUint64(size8Bytes, size)
+		buffer.Write(size8Bytes)
+	}
+	binary.LittleEndian.PutUint32(size4Bytes, 0)
+	buffer.Write(size4Bytes)
+	// Copy the fmt chunk of a stereo 16 bit file.
+	buffer.Write(valid[12:])
+	buffer.WriteString("data")
+	binary.LittleEndian.PutUint32(size4Bytes, math.MaxUint32)
+	buffer.Write(size4Bytes)
+	buffer.Write([]byte{1, 2, 3, 4, 5, 6, 7, 8})
+	// The ds64 data size, rather than EOF, ends the samples.
+	buffer.Write([]byte{9, 9, 9, 9})
+
+	reader, err := NewWavReader(buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(8), reader.Ds64.DataSize)
+	samples := 0
+	for {
+		if _, err := reader.GetSample(); err == io.EOF {
+			break
+		} else {
+			assert.Nil(t, err)
+		}
+		samples++
+	}
+	assert.Equal(t, 2, samples)
+}
+
+func TestReadRF64MissingDs64(t *testing.T) {
+	buffer := getValidHeaderAndFmtChunk()
+	copy(buffer.Bytes(), "RF64")
+	_, err := NewWavReader(buffer)
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("RF64 file starts with a fmt  chunk instead of ds64")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
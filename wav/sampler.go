@@ -0,0 +1,134 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	Sampler = "smpl"
+
+	// The following values are the loop types of a SampleLoop.
+	LoopForward     uint32 = 0
+	LoopAlternating uint32 = 1
+	LoopBackward    uint32 = 2
+)
+
+/*
+samplerFields is the fixed size portion of a smpl chunk as laid out in the
+file. It is followed by NumSampleLoops loops and SamplerDataSize bytes of
+manufacturer specific data.
+*/
+type samplerFields struct {
+	Manufacturer      uint32
+	Product           uint32
+	SamplePeriod      uint32
+	MIDIUnityNote     uint32
+	MIDIPitchFraction uint32
+	SMPTEFormat       uint32
+	SMPTEOffset       uint32
+	NumSampleLoops    uint32
+	SamplerDataSize   uint32
+}
+
+/*
+SampleLoop describes a loop within the data chunk. Start and End are the first
+and last sample frames of the loop, and PlayCount is the number of times it
+plays, with 0 meaning forever. CuePointId optionally names the cue point that
+marks the loop.
+*/
+type SampleLoop struct {
+	CuePointId uint32
+	Type       uint32
+	Start      uint32
+	End        uint32
+	Fraction   uint32
+	PlayCount  uint32
+}
+
+/*
+SamplerChunk holds the smpl chunk, which tells samplers how to play the sound:
+which MIDI note plays it back unaltered and which parts of it loop.
+SamplePeriod is the length of a sample frame in nanoseconds and MIDIUnityNote
+the MIDI key of the original pitch.
+*/
+type SamplerChunk struct {
+	Manufacturer      uint32
+	Product           uint32
+	SamplePeriod      uint32
+	MIDIUnityNote     uint32
+	MIDIPitchFraction uint32
+	SMPTEFormat       uint32
+	SMPTEOffset       uint32
+	Loops             []SampleLoop
+	SamplerData       []byte
+}
+
+// WithSampler returns a WriterOption that writes sampler as a smpl chunk.
+func WithSampler(sampler *SamplerChunk) WriterOption {
+	return func(w *WavWriter) error {
+		w.Sampler = sampler
+		return nil
+	}
+}
+
+/*
+readSamplerChunk parses the body of a smpl chunk of the given size into the
+Wav's Sampler field.
+*/
+func (w *Wav) readSamplerChunk(reader io.Reader, size uint32) error {
+	var fields samplerFields
+	if int(size) < binary.Size(fields) {
+		return fmt.Errorf(ChunkSizeError, Sampler, size)
+	}
+	if err := binary.Read(reader, binary.LittleEndian, &fields); err != nil {
+		return err
+	}
+	loopsSize := uint64(fields.NumSampleLoops) *
+		uint64(binary.Size(SampleLoop{}))
+	if uint64(size) < uint64(binary.Size(fields))+loopsSize {
+		return fmt.Errorf(ChunkSizeError, Sampler, size)
+	}
+	loops := make([]SampleLoop, fields.NumSampleLoops)
+	if err := binary.Read(reader, binary.LittleEndian, loops); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(
+		reader, int64(fields.SamplerDataSize)))
+	if err != nil {
+		return err
+	}
+	w.Sampler = &SamplerChunk{
+		Manufacturer:      fields.Manufacturer,
+		Product:           fields.Product,
+		SamplePeriod:      fields.SamplePeriod,
+		MIDIUnityNote:     fields.MIDIUnityNote,
+		MIDIPitchFraction: fields.MIDIPitchFraction,
+		SMPTEFormat:       fields.SMPTEFormat,
+		SMPTEOffset:       fields.SMPTEOffset,
+		Loops:             loops,
+		SamplerData:       data,
+	}
+	return nil
+}
+
+// encode returns the body of a smpl chunk.
+func (s *SamplerChunk) encode() []byte {
+	buffer := new(bytes.Buffer)
+	binary.Write(buffer, binary.LittleEndian, &samplerFields{
+		Manufacturer:      s.Manufacturer,
+		Product:           s.Product,
+		SamplePeriod:      s.SamplePeriod,
+		MIDIUnityNote:     s.MIDIUnityNote,
+		MIDIPitchFraction: s.MIDIPitchFraction,
+		SMPTEFormat:       s.SMPTEFormat,
+		SMPTEOffset:       s.SMPTEOffset,
+		NumSampleLoops:    uint32(len(s.Loops)),
+		SamplerDataSize:   uint32(len(s.SamplerData)),
+	})
+	binary.Write(buffer, binary.LittleEndian, s.Loops)
+	buffer.Write(s.SamplerData)
+	return buffer.Bytes()
+}
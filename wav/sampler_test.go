@@ -0,0 +1,43 @@
+package wav_test
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSamplerRoundTrip(t *testing.T) {
+	sampler := &SamplerChunk{
+		SamplePeriod:  22676,
+		MIDIUnityNote: 60,
+		Loops: []SampleLoop{
+			{CuePointId: 1, Type: LoopForward, Start: 100, End: 199},
+			{CuePointId: 2, Type: LoopAlternating, Start: 300, End: 399,
+				PlayCount: 2},
+		},
+		SamplerData: []byte{1, 2, 3},
+	}
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := NewWavWriter(writer, nil, WithSampler(sampler))
+	assert.Nil(t, err)
+
+	reader, err := NewWavReader(
+		bytes.NewReader(writer.data[:wavWriter.Riff.Size+8]))
+	assert.Nil(t, err)
+	assert.Equal(t, sampler, reader.Sampler)
+}
+
+func TestSamplerWithoutLoops(t *testing.T) {
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := NewWavWriter(
+		writer, nil, WithSampler(&SamplerChunk{MIDIUnityNote: 69}))
+	assert.Nil(t, err)
+
+	reader, err := NewWavReader(
+		bytes.NewReader(writer.data[:wavWriter.Riff.Size+8]))
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(69), reader.Sampler.MIDIUnityNote)
+	assert.Empty(t, reader.Sampler.Loops)
+}
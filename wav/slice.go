@@ -0,0 +1,127 @@
+package wav
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	RangeError = "range start %v is after its end %v"
+	SeekError  = "cannot seek back to data offset %v in a reader that is not an io.Seeker"
+)
+
+/*
+ExtractRange copies the samples between start and end, measured from the first
+sample, to a new WAV file written to output, and returns the WavWriter used to
+write it. Times are rounded down to the nearest sample frame, and a range that
+runs past the end of the data is cut short. When the reader was created from an
+io.Seeker, it seeks straight to start, so clips can be taken from long
+recordings without reading everything before them. Otherwise the samples before
+start are read and discarded, and start must not be before the samples already
+read. The reader is left at the end of the range.
+*/
+func (w *WavReader) ExtractRange(
+	start, end time.Duration, output io.WriterAt) (*WavWriter, error) {
+	return w.ExtractRangeContext(
+		context.Background(), start, end, output, ContextOptions{})
+}
+
+/*
+ExtractRangeContext is ExtractRange, stopping once ctx is done. The samples
+copied until then are left as a valid file, and its WavWriter is returned with
+ctx's error.
+*/
+func (w *WavReader) ExtractRangeContext(ctx context.Context, start,
+	end time.Duration, output io.WriterAt,
+	options ContextOptions) (*WavWriter, error) {
+	if start > end {
+		return nil, fmt.Errorf(RangeError, start, end)
+	}
+	frameSize := int64(w.Fmt.BitsPerSample/8) * int64(w.Fmt.NumChannels)
+	startByte := w.frameAt(start) * frameSize
+	length := (w.frameAt(end) - w.frameAt(start)) * frameSize
+	if err := w.seekData(startByte); err != nil {
+		return nil, err
+	}
+
+	wavWriter, err := NewWavWriter(output, copyFmtChunk(w.Fmt))
+	if err != nil {
+		return nil, err
+	}
+	buffer := make([]byte, frameSize*copyFrames)
+	tracker := &progress{ctx: ctx, report: options.Progress,
+		throttle: options.Throttle}
+	if frameSize > 0 {
+		tracker.total = length / frameSize
+		if remaining := w.RemainingFrames(); remaining >= 0 &&
+			remaining < tracker.total {
+			tracker.total = remaining
+		}
+	}
+	for length > 0 && frameSize > 0 {
+		if ctx.Err() != nil {
+			return wavWriter.partial(ctx)
+		}
+		if int64(len(buffer)) > length {
+			buffer = buffer[:length]
+		}
+		n, readErr := w.readData(buffer)
+		n -= n % int(frameSize)
+		if err := wavWriter.appendData(buffer[:n]); err != nil {
+			return nil, err
+		}
+		length -= int64(n)
+		tracker.add(int64(n) / frameSize)
+		if readErr == io.EOF {
+			break
+		} else if readErr != nil {
+			return nil, readErr
+		}
+	}
+	return wavWriter, wavWriter.writeSizes()
+}
+
+// frameAt returns the index of the sample frame playing at time d.
+func (w *WavReader) frameAt(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	seconds := int64(d / time.Second)
+	remainder := int64(d % time.Second)
+	rate := int64(w.Fmt.SampleRate)
+	return seconds*rate + remainder*rate/int64(time.Second)
+}
+
+/*
+seekData moves the reader to offset bytes into the data chunk, seeking the
+source when possible and otherwise discarding the bytes in between.
+*/
+func (w *WavReader) seekData(offset int64) error {
+	if w.remaining >= 0 && offset > w.position+w.remaining {
+		offset = w.position + w.remaining
+	}
+	if w.dataStart >= 0 {
+		seeker := w.source.(io.Seeker)
+		if _, err := seeker.Seek(
+			w.dataStart+offset, io.SeekStart); err != nil {
+			return err
+		}
+		w.buffer = bufio.NewReader(w.source)
+	} else if offset < w.position {
+		return fmt.Errorf(SeekError, offset)
+	} else {
+		skipped, err := io.CopyN(io.Discard, w.buffer, offset-w.position)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		offset = w.position + skipped
+	}
+	if w.remaining >= 0 {
+		w.remaining -= offset - w.position
+	}
+	w.position = offset
+	return nil
+}
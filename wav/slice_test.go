@@ -0,0 +1,88 @@
+package wav_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"regexp"
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// extractFrames extracts a range from reader and returns the first byte of
+// every frame in it.
+func extractFrames(t *testing.T, reader *WavReader,
+	start, end time.Duration) ([]byte, error) {
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := reader.ExtractRange(start, end, writer)
+	if err != nil {
+		return nil, err
+	}
+	frames := []byte{}
+	for _, sample := range readAllSamples(
+		t, writer.data[:wavWriter.Riff.Size+8]) {
+		frames = append(frames, sample[0][0])
+	}
+	return frames, nil
+}
+
+func TestExtractRangeSeeks(t *testing.T) {
+	reader, err := NewWavReader(bytes.NewReader(newSplitInput(t)))
+	assert.Nil(t, err)
+
+	frames, err := extractFrames(
+		t, reader, 500*time.Millisecond, 1500*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{2, 3, 4, 5}, frames)
+
+	// Seekable readers can go back to earlier samples.
+	frames, err = extractFrames(t, reader, 0, 250*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0}, frames)
+
+	// Ranges are cut short at the end of the data.
+	frames, err = extractFrames(t, reader, 2*time.Second, time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{8, 9}, frames)
+	frames, err = extractFrames(t, reader, time.Hour, 2*time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{}, frames)
+}
+
+func TestExtractRangeWithoutSeeking(t *testing.T) {
+	reader, err := NewWavReader(
+		io.MultiReader(bytes.NewReader(newSplitInput(t))))
+	assert.Nil(t, err)
+	_, err = reader.GetSample()
+	assert.Nil(t, err)
+
+	frames, err := extractFrames(t, reader, 750*time.Millisecond, time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{3}, frames)
+
+	_, err = extractFrames(t, reader, 0, time.Second)
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("cannot seek back to data offset 0")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+
+	_, err = extractFrames(t, reader, time.Second, 0)
+	assert.NotNil(t, err)
+	re = regexp.MustCompile("range start 1s is after its end 0s")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
+
+func TestExtractRangeContext(t *testing.T) {
+	reader, err := NewWavReader(bytes.NewReader(newSplitInput(t)))
+	assert.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := reader.ExtractRangeContext(
+		ctx, 0, time.Second, writer, ContextOptions{})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, []Sample(nil),
+		readAllSamples(t, writer.data[:wavWriter.Riff.Size+8]))
+}
@@ -0,0 +1,179 @@
+package wav
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/husafan/audio"
+)
+
+// ErrNoSplitPoints is returned by Split when SplitOptions requests no splits.
+var ErrNoSplitPoints = errors.New("split requires a positive duration or cue points")
+
+/*
+SplitOptions controls where Split cuts a file. A new segment starts every
+Duration, when Duration is positive, and at every cue point, when AtCuePoints
+is set. Create is called with the index of each segment to obtain the output it
+is written to. Progress, if not nil, is called as SplitContext works, and
+Throttle slows it down.
+*/
+type SplitOptions struct {
+	Duration    time.Duration
+	AtCuePoints bool
+	Create      func(index int) (io.WriterAt, error)
+	Progress    audio.ProgressFunc
+	Throttle    audio.Throttle
+}
+
+/*
+Split cuts the WAV file in reader into segments, each written through its own
+WavWriter with the format of the input, and returns the writers in order. The
+data is streamed, so inputs of any length can be split. Cue points are usually
+stored after the data; when splitting at cue points and reader is an
+io.ReadSeeker, the file is read once to find them before it is split. Otherwise
+only cue points stored before the data are used.
+*/
+func Split(reader io.Reader, options SplitOptions) ([]*WavWriter, error) {
+	return SplitContext(context.Background(), reader, options)
+}
+
+/*
+SplitContext is Split, stopping once ctx is done. The segments written until
+then are returned with ctx's error, the last of them cut short but left as a
+valid file.
+*/
+func SplitContext(ctx context.Context,
+	reader io.Reader, options SplitOptions) ([]*WavWriter, error) {
+	if options.Duration <= 0 && !options.AtCuePoints {
+		return nil, ErrNoSplitPoints
+	}
+	var cues []CuePoint
+	if seeker, ok := reader.(io.ReadSeeker); ok && options.AtCuePoints {
+		wav, err := readToEnd(seeker)
+		if err != nil {
+			return nil, err
+		}
+		cues = wav.CuePoints()
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	wavReader, err := NewWavReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	if options.AtCuePoints && cues == nil {
+		cues = wavReader.CuePoints()
+	}
+
+	f := wavReader.Fmt
+	frameSize := uint64(f.BitsPerSample/8) * uint64(f.NumChannels)
+	var interval uint64
+	if options.Duration > 0 {
+		interval = uint64(options.Duration) * uint64(f.SampleRate) /
+			uint64(time.Second)
+	}
+	var splits []uint64
+	if options.AtCuePoints {
+		for _, cue := range cues {
+			splits = append(splits, uint64(cue.Position))
+		}
+		sort.Slice(splits, func(a, b int) bool { return splits[a] < splits[b] })
+	}
+
+	var writers []*WavWriter
+	tracker := &progress{ctx: ctx, report: options.Progress,
+		throttle: options.Throttle, total: wavReader.RemainingFrames()}
+	var frame, segmentEnd uint64
+	buffer := make([]byte, frameSize*copyFrames)
+	for frameSize > 0 {
+		if ctx.Err() != nil && len(writers) > 0 {
+			_, err := writers[len(writers)-1].partial(ctx)
+			return writers, err
+		} else if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		n, readErr := wavReader.readData(buffer)
+		data := buffer[:uint64(n)-uint64(n)%frameSize]
+		for len(data) > 0 {
+			if writers == nil || frame == segmentEnd {
+				output, err := options.Create(len(writers))
+				if err != nil {
+					return nil, err
+				}
+				wavWriter, err := NewWavWriter(output, copyFmtChunk(f))
+				if err != nil {
+					return nil, err
+				}
+				writers = append(writers, wavWriter)
+				segmentEnd = nextSplit(frame, interval, splits)
+			}
+			size := uint64(len(data))
+			if remaining := (segmentEnd - frame) * frameSize; size > remaining {
+				size = remaining
+			}
+			current := writers[len(writers)-1]
+			if err := current.appendData(data[:size]); err != nil {
+				return nil, err
+			}
+			frame += size / frameSize
+			tracker.add(int64(size / frameSize))
+			data = data[size:]
+			if frame == segmentEnd {
+				if err := current.writeSizes(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if readErr == io.EOF {
+			break
+		} else if readErr != nil {
+			return nil, readErr
+		}
+	}
+	if len(writers) > 0 {
+		return writers, writers[len(writers)-1].writeSizes()
+	}
+	return writers, nil
+}
+
+/*
+nextSplit returns the first frame after frame at which a new segment starts,
+given the interval between regular splits, or 0 for none, and the sorted frames
+of cue points.
+*/
+func nextSplit(frame, interval uint64, splits []uint64) uint64 {
+	next := ^uint64(0)
+	if interval > 0 {
+		next = (frame/interval + 1) * interval
+	}
+	index := sort.Search(len(splits), func(i int) bool {
+		return splits[i] > frame
+	})
+	if index < len(splits) && splits[index] < next {
+		next = splits[index]
+	}
+	return next
+}
+
+/*
+readToEnd reads the WAV file in reader to the end, so that the chunks following
+the data chunk are read too, and returns it.
+*/
+func readToEnd(reader io.Reader) (*Wav, error) {
+	wavReader, err := NewWavReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	buffer := make([]byte, 32*1024)
+	for {
+		if _, err := wavReader.readData(buffer); err == io.EOF {
+			return wavReader.Wav, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
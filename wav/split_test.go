@@ -0,0 +1,93 @@
+package wav_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// newSplitInput returns a WAV file at 4 frames per second of ten frames,
+// numbered from 0, with cue points at frames 3 and 7.
+func newSplitInput(t *testing.T) []byte {
+	fmtChunk := NewDefaultFmtChunk()
+	fmtChunk.SampleRate = 4
+	writer := &mockWriterAtCloser{make([]byte, 1000)}
+	wavWriter, err := NewWavWriter(writer, fmtChunk)
+	assert.Nil(t, err)
+	for i := byte(0); i < 10; i++ {
+		assert.Nil(t, wavWriter.AddSample(Sample{{i, 0}, {i, 0}}))
+	}
+	assert.Nil(t, wavWriter.AddCuePoint(CuePoint{Id: 1, Position: 7}))
+	assert.Nil(t, wavWriter.AddCuePoint(CuePoint{Id: 2, Position: 3}))
+	assert.Nil(t, wavWriter.Flush())
+	return writer.data[:wavWriter.Riff.Size+8]
+}
+
+// splitFrames splits input and returns the first byte of every frame of each
+// segment.
+func splitFrames(
+	t *testing.T, input io.Reader, options SplitOptions) [][]byte {
+	var outputs []*mockWriterAtCloser
+	options.Create = func(index int) (io.WriterAt, error) {
+		assert.Equal(t, len(outputs), index)
+		outputs = append(outputs, &mockWriterAtCloser{make([]byte, 1000)})
+		return outputs[index], nil
+	}
+	writers, err := Split(input, options)
+	assert.Nil(t, err)
+	assert.Equal(t, len(outputs), len(writers))
+
+	var segments [][]byte
+	for i, writer := range writers {
+		var frames []byte
+		data := outputs[i].data[:writer.Riff.Size+8]
+		for _, sample := range readAllSamples(t, data) {
+			frames = append(frames, sample[0][0])
+		}
+		segments = append(segments, frames)
+	}
+	return segments
+}
+
+func TestSplitByDuration(t *testing.T) {
+	input := newSplitInput(t)
+	assert.Equal(t, [][]byte{{0, 1, 2, 3}, {4, 5, 6, 7}, {8, 9}},
+		splitFrames(t, bytes.NewReader(input),
+			SplitOptions{Duration: time.Second}))
+}
+
+func TestSplitAtCuePoints(t *testing.T) {
+	input := newSplitInput(t)
+	assert.Equal(t, [][]byte{{0, 1, 2}, {3, 4, 5, 6}, {7, 8, 9}},
+		splitFrames(t, bytes.NewReader(input),
+			SplitOptions{AtCuePoints: true}))
+	assert.Equal(t, [][]byte{{0, 1, 2}, {3}, {4, 5, 6}, {7}, {8, 9}},
+		splitFrames(t, bytes.NewReader(input),
+			SplitOptions{Duration: time.Second, AtCuePoints: true}))
+
+	// Without seeking, the cue points following the data are not found.
+	assert.Equal(t, [][]byte{{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		splitFrames(t, io.MultiReader(bytes.NewReader(input)),
+			SplitOptions{AtCuePoints: true}))
+}
+
+func TestSplitWithoutSplitPoints(t *testing.T) {
+	_, err := Split(bytes.NewReader(newSplitInput(t)), SplitOptions{})
+	assert.Equal(t, ErrNoSplitPoints, err)
+}
+
+func TestSplitContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	writers, err := SplitContext(ctx, bytes.NewReader(newSplitInput(t)),
+		SplitOptions{Duration: time.Second, Create: func(int) (io.WriterAt, error) {
+			return &mockWriterAtCloser{make([]byte, 1000)}, nil
+		}})
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, writers)
+}
@@ -10,27 +10,95 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+
+	"github.com/husafan/audio/id3"
 )
 
 const (
-	ChannelError           = "expected %v channels; found %v."
-	Data                   = "data"
-	DataError              = "invalid data chunk ID of %s; should be 'data'"
-	Fmt                    = "fmt "
-	FmtError               = "invalid fmt chunk ID of %s; should be 'fmt '."
-	Riff                   = "RIFF"
-	RiffError              = "invalid initial chunk ID of %s; should be 'RIFF'"
-	SampleError            = "expected %v bytes per sample but only found %v"
-	Wave                   = "WAVE"
-	WaveError              = "invalid format of %s; should be 'WAVE'"
-	FormatChunkError       = "invalid format chunk: %s."
-	RiffSizeOffset   int64 = 4
-	DataSizeOffset   int64 = 40
-	DataOffset       int64 = 44
+	ChannelError             = "expected %v channels; found %v."
+	ChunkSizeError           = "%s chunk of %v bytes is too small for its contents"
+	ChunkTooLargeError       = "%s chunk of %v bytes at byte %v exceeds the limit of %v bytes"
+	ChunkBoundsError         = "%s chunk of %v bytes at byte %v extends past the end of the file at byte %v"
+	Data                     = "data"
+	DataError                = "invalid data chunk ID of %s; should be 'data'"
+	Fmt                      = "fmt "
+	FmtError                 = "invalid fmt chunk ID of %s; should be 'fmt '."
+	Riff                     = "RIFF"
+	RiffError                = "invalid initial chunk ID of %s; should be 'RIFF'"
+	SampleError              = "expected %v bytes per sample but only found %v"
+	Wave                     = "WAVE"
+	WaveError                = "invalid format of %s; should be 'WAVE'"
+	FormatChunkError         = "invalid format chunk: %s."
+	ID3                      = "id3 "
+	ID3Upper                 = "ID3 "
+	List                     = "LIST"
+	RiffSizeOffset     int64 = 4
+	DataSizeOffset     int64 = 40
+	DataOffset         int64 = 44
 )
 
+// ErrNotRiff is returned when a file starts with neither a RIFF nor RF64 chunk.
+var ErrNotRiff = errors.New("not a RIFF file")
+
+/*
+ErrBadFmtChunk is returned when the chunk where the fmt chunk is expected has a
+different ID. Got is the ID that was found and Offset is its byte offset in the
+file.
+*/
+type ErrBadFmtChunk struct {
+	Got    string
+	Offset int64
+}
+
+func (e ErrBadFmtChunk) Error() string {
+	return fmt.Sprintf(FmtError+" (at byte %v)", e.Got, e.Offset)
+}
+
+/*
+ErrSampleSize is returned when the sample of Channel, counted from 0, holds Got
+bytes rather than the Want bytes per sample of the fmt chunk.
+*/
+type ErrSampleSize struct {
+	Channel   int
+	Want, Got int
+}
+
+func (e ErrSampleSize) Error() string {
+	return fmt.Sprintf("channel %v: "+SampleError, e.Channel, e.Want, e.Got)
+}
+
+/*
+ErrChunkTooLarge is returned when a chunk is larger than
+ReaderOptions.MaxChunkSize. Offset is the byte offset of the chunk in the file.
+*/
+type ErrChunkTooLarge struct {
+	Chunk    string
+	Max, Got uint64
+	Offset   int64
+}
+
+func (e ErrChunkTooLarge) Error() string {
+	return fmt.Sprintf(ChunkTooLargeError, e.Chunk, e.Got, e.Offset, e.Max)
+}
+
+/*
+ErrChunkBounds is returned when ReaderOptions.CheckBounds is set and the chunk
+at Offset extends past End, the end of the file declared by its RIFF header.
+*/
+type ErrChunkBounds struct {
+	Chunk       string
+	Size        uint64
+	Offset, End int64
+}
+
+func (e ErrChunkBounds) Error() string {
+	return fmt.Sprintf(ChunkBoundsError, e.Chunk, e.Size, e.Offset, e.End)
+}
+
 /*
 defaultRiffHeader conains an ID of 'RIFF' and a format of 'WAVE'. It contains a
 default size of 36. Every time a sample is added, the size should be incremented
@@ -159,9 +227,18 @@ type Sample [][]byte
 
 // Wav stores a WAV file's format and data.
 type Wav struct {
-	Riff *RiffHeader
-	Fmt  *FmtChunk
-	Data *DataChunk
+	Riff      *RiffHeader
+	Fmt       *FmtChunk
+	Data      *DataChunk
+	Metadata  Metadata
+	Broadcast *BextChunk
+	Sampler   *SamplerChunk
+	Ds64      *Ds64Chunk
+	ID3       *id3.Tag
+	HashChain *HashChainChunk
+
+	// cues holds the cue points in the order they were read or added.
+	cues []CuePoint
 }
 
 // WavReader contains the wav content as well as an internal buffer for reading
@@ -169,6 +246,20 @@ type Wav struct {
 type WavReader struct {
 	*Wav
 	buffer io.Reader
+
+	// remaining is the number of data chunk bytes not yet read, or -1 when
+	// the data chunk size is unknown and samples are read until EOF.
+	remaining int64
+	// finished is set once the chunks following the data have been read.
+	finished bool
+	// position is the number of data chunk bytes read so far.
+	position int64
+	// source is the reader the file is read from, and dataStart the offset
+	// of the first sample within it, or -1 when source cannot seek.
+	source    io.Reader
+	dataStart int64
+	// scratch holds the raw bytes decoded by ReadFrames.
+	scratch []byte
 }
 
 // WavWriter contains the basic wav information as well as the buffer being
@@ -176,12 +267,111 @@ type WavReader struct {
 type WavWriter struct {
 	*Wav
 	buffer io.WriterAt
+
+	// dataOffset is the offset of the first sample, which follows any
+	// optional chunks written before the data chunk.
+	dataOffset int64
+	// trailer holds the chunks following the data chunk, such as cue
+	// points, as last written by Flush or Close. Samples added since then
+	// overwrite them, so it is cleared until they are written again.
+	trailer []byte
+	// reserveDs64 is set when space is reserved for switching to RF64.
+	reserveDs64 bool
+	// cueChunks holds the encoded cue and adtl chunks, which are encoded
+	// again only when a cue point is added.
+	cueChunks []byte
+	// hashChain is set when the writer records a hash chain.
+	hashChain *hashChainWriter
+	// pending holds samples not yet written, up to bufferSize bytes.
+	bufferSize int
+	pending    []byte
+	// padding is set when Close pads the data chunk, and padded once it has.
+	padding bool
+	padded  bool
+	closed  bool
+}
+
+/*
+WriterOption configures a WavWriter created by NewWavWriter, typically to add
+optional chunks to the file. A non-nil error rejects the option and the writer.
+*/
+type WriterOption func(*WavWriter) error
+
+/*
+ReaderOptions bounds the chunks NewWavReaderWithOptions is willing to accept.
+Services that read untrusted files should set every limit, since a small file
+can otherwise declare chunks of up to 4 GB. MaxChunkSize limits every chunk but
+the data chunk, whose samples are streamed rather than held in memory; a limit
+of 0 is not enforced. It also limits the size the compressed frames of an id3
+chunk inflate to, which is otherwise id3.DefaultMaxFrameSize. CheckBounds
+rejects chunks, including the data chunk, that extend past the end of the file
+declared by its RIFF header.
+*/
+type ReaderOptions struct {
+	MaxChunkSize uint64
+	CheckBounds  bool
+}
+
+/*
+chunkReader counts the bytes read through it, so that chunks can be located in
+the file, and checks each chunk header read against options. end is the end of
+the file declared by its RIFF header, or 0 when that is unknown.
+*/
+type chunkReader struct {
+	reader  io.Reader
+	count   int64
+	options ReaderOptions
+	end     int64
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.count += int64(n)
+	return n, err
+}
+
+/*
+check returns a non-nil error if the chunk of size bytes that starts at offset
+breaks the limits of c.options.
+*/
+func (c *chunkReader) check(id string, size uint64, offset int64) error {
+	limit := c.options.MaxChunkSize
+	if limit > 0 && id != Data && size > limit {
+		return ErrChunkTooLarge{
+			Chunk: id, Max: limit, Got: size, Offset: offset}
+	}
+	if !c.options.CheckBounds || c.end == 0 {
+		return nil
+	}
+	if remaining := c.end - c.count; remaining < 0 ||
+		size > uint64(remaining) {
+		return ErrChunkBounds{
+			Chunk: id, Size: size, Offset: offset, End: c.end}
+	}
+	return nil
+}
+
+/*
+readBody reads a chunk body of size bytes. The body grows as data arrives rather
+than being allocated up front, so a corrupt size cannot exhaust memory before
+the file runs out.
+*/
+func readBody(reader io.Reader, size uint64) ([]byte, error) {
+	limit := int64(math.MaxInt64)
+	if size < math.MaxInt64 {
+		limit = int64(size)
+	}
+	body, err := io.ReadAll(io.LimitReader(reader, limit))
+	if err == nil && uint64(len(body)) < size {
+		err = io.ErrUnexpectedEOF
+	}
+	return body, err
 }
 
 /*
 readSubChunk reads and returns a populated SubChunk given an *io.Reader to read
 from. An error is returned from this function if there was an error in reading
-the necessary bytes.
+the necessary bytes, or if the chunk breaks the limits of a *chunkReader.
 */
 func readSubChunk(reader *io.Reader) (*SubChunk, error) {
 	newSubChunk := &SubChunk{}
@@ -193,6 +383,17 @@ func readSubChunk(reader *io.Reader) (*SubChunk, error) {
 		*reader, binary.LittleEndian, &newSubChunk.Size); err != nil {
 		return nil, err
 	}
+	// The RIFF chunk holds the whole file, and the size of an RF64 data chunk
+	// is held by the ds64 chunk, so the data chunk is checked once that is
+	// known.
+	chunks, ok := (*reader).(*chunkReader)
+	if id := string(newSubChunk.Id[:]); ok && chunks.count > 8 && id != Data {
+		err := chunks.check(
+			id, uint64(newSubChunk.Size), chunks.count-8)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return newSubChunk, nil
 }
 
@@ -212,8 +413,9 @@ func readRiffHeader(reader *io.Reader) (*RiffHeader, error) {
 		return nil, err
 	}
 	// Validate the Riff header chunk ID.
-	if uintString := string(subChunk.Id[:]); uintString != Riff {
-		return nil, fmt.Errorf(RiffError, uintString)
+	if uintString := string(subChunk.Id[:]); uintString != Riff &&
+		uintString != RF64 {
+		return nil, fmt.Errorf("%w: "+RiffError, ErrNotRiff, uintString)
 	}
 	// Create a Riff header.
 	riffHeader := &RiffHeader{SubChunk: subChunk}
@@ -230,21 +432,31 @@ func readRiffHeader(reader *io.Reader) (*RiffHeader, error) {
 
 /*
 readFormatChunk reads and returns a populated FormatChunk given an *io.Reader to
-read from. Returns a non-nil error when a problem is encountered reading the
-data.
+read from, positioned at byte offset of the file. JUNK chunks preceding it,
+which reserve space for other chunks, are skipped. Returns a non-nil error when
+a problem is encountered reading the data.
 */
-func readFormatChunk(reader *io.Reader) (*FmtChunk, error) {
+func readFormatChunk(reader *io.Reader, offset int64) (*FmtChunk, error) {
 	var err error
 	var subChunk *SubChunk
 
 	// Read the SubChunk of the fmt chunk.
-	subChunk, err = readSubChunk(reader)
-	if err != nil {
-		return nil, err
+	for subChunk == nil || string(subChunk.Id[:]) == Junk {
+		subChunk, err = readSubChunk(reader)
+		if err != nil {
+			return nil, err
+		}
+		if string(subChunk.Id[:]) == Junk {
+			body := io.LimitReader(*reader, int64(subChunk.Size))
+			if err := skip(*reader, int64(subChunk.Size)%2, body); err != nil {
+				return nil, err
+			}
+			offset += 8 + int64(subChunk.Size) + int64(subChunk.Size)%2
+		}
 	}
 	// Validate that the ID is "fmt ".
 	if uintString := string(subChunk.Id[:]); uintString != Fmt {
-		return nil, fmt.Errorf(FmtError, uintString)
+		return nil, ErrBadFmtChunk{Got: uintString, Offset: offset}
 	}
 	newFmtChunk := &fmtChunk{}
 	if err := binary.Read(
@@ -255,24 +467,105 @@ func readFormatChunk(reader *io.Reader) (*FmtChunk, error) {
 }
 
 /*
-readDataChunk reads and returns a DataChunk. It also validates the "data" ID.
+readDataChunk reads chunks until it finds the "data" chunk, which it returns.
 It does not completely read in all of the actual sound data immediately. Rather,
-the sound data is returned by sequential calls to GetSample(). A non-nil error
-is returned if there is a problem reading the data or the chunk is invalid.
+the sound data is returned by sequential calls to GetSample(). Chunks found
+before the data chunk are handed to readChunk. A non-nil error is returned if
+there is a problem reading the data or the file ends before the data chunk.
 */
-func readDataChunk(reader *io.Reader) (*DataChunk, error) {
-	var err error
-	var subChunk *SubChunk
+func (w *Wav) readDataChunk(reader *io.Reader) (*DataChunk, error) {
+	for {
+		subChunk, err := readSubChunk(reader)
+		if err != nil {
+			return nil, err
+		}
+		if string(subChunk.Id[:]) == Data {
+			return &DataChunk{SubChunk: subChunk}, nil
+		}
+		if err := w.readChunk(reader, subChunk); err != nil {
+			return nil, err
+		}
+	}
+}
 
-	subChunk, err = readSubChunk(reader)
+/*
+readChunk consumes the body of an optional chunk whose header has already been
+read, along with its pad byte. Chunks the package understands populate the Wav,
+while all others are skipped.
+*/
+func (w *Wav) readChunk(reader *io.Reader, subChunk *SubChunk) error {
+	body := io.LimitReader(*reader, int64(subChunk.Size))
+	var err error
+	switch string(subChunk.Id[:]) {
+	case List:
+		err = w.readListChunk(body)
+	case Bext:
+		err = w.readBextChunk(body, subChunk.Size)
+	case Cue:
+		err = w.readCueChunk(body, subChunk.Size)
+	case Sampler:
+		err = w.readSamplerChunk(body, subChunk.Size)
+	case ID3, ID3Upper:
+		w.ID3, err = id3.ReadWithOptions(body, id3Options(*reader))
+	case HashChain:
+		err = w.readHashChainChunk(body, subChunk.Size)
+	}
 	if err != nil {
-		return nil, err
+		return err
+	}
+	return skip(*reader, int64(subChunk.Size)%2, body)
+}
+
+/*
+id3Options returns the limits for an id3 chunk read from reader. Compressed
+frames may inflate to no more than the MaxChunkSize of a *chunkReader, so that
+the limit holds for the tag's contents as well as for the chunk.
+*/
+func id3Options(reader io.Reader) id3.Options {
+	var options id3.Options
+	if chunks, ok := reader.(*chunkReader); ok {
+		if limit := chunks.options.MaxChunkSize; limit > 0 &&
+			limit <= math.MaxInt32 {
+			options.MaxFrameSize = int(limit)
+		}
+	}
+	return options
+}
+
+/*
+skip discards whatever remains of body, followed by padding bytes from reader.
+Chunks with an odd size are followed by a single pad byte in RIFF files.
+*/
+func skip(reader io.Reader, padding int64, body io.Reader) error {
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(io.Discard, reader, padding); err != nil {
+		return err
+	}
+	return nil
+}
+
+/*
+readTrailingChunks reads the optional chunks following the data chunk, such as
+a LIST chunk written after the samples. Problems reading them are ignored, as
+the samples have already been read successfully.
+*/
+func (w *WavReader) readTrailingChunks() {
+	if w.dataSize()%2 != 0 {
+		if _, err := io.CopyN(io.Discard, w.buffer, 1); err != nil {
+			return
+		}
 	}
-	// Validate that the ID is "data".
-	if uintString := string(subChunk.Id[:]); uintString != Data {
-		return nil, fmt.Errorf(DataError, uintString)
+	for {
+		subChunk, err := readSubChunk(&w.buffer)
+		if err != nil {
+			return
+		}
+		if err := w.readChunk(&w.buffer, subChunk); err != nil {
+			return
+		}
 	}
-	return &DataChunk{SubChunk: subChunk}, nil
 }
 
 /**
@@ -282,26 +575,90 @@ non-nil error. Also, if the wav file's standard "fmt" block does not exist or
 does not parse correctly, a non-nil error will be returned.
 */
 func NewWavReader(r io.Reader) (*WavReader, error) {
-	var riffHeader *RiffHeader
-	var fmtChunk *FmtChunk
-	var dataChunk *DataChunk
+	return NewWavReaderWithOptions(r, nil)
+}
+
+/*
+NewWavReaderWithOptions creates a WavReader like NewWavReader, enforcing the
+limits in options. A nil options places no limits on the file. When a limit is
+exceeded, the returned error is an ErrChunkTooLarge or ErrChunkBounds.
+*/
+func NewWavReaderWithOptions(
+	r io.Reader, options *ReaderOptions) (*WavReader, error) {
+	var err error
+	var wavReader *WavReader
+
+	if options == nil {
+		options = new(ReaderOptions)
+	}
+	buffered := bufio.NewReader(r)
+	if id, peekErr := buffered.Peek(4); peekErr == nil &&
+		string(id) == wave64Riff {
+		wavReader, err = newWave64Reader(buffered, *options)
+	} else {
+		wavReader, err = newRiffReader(buffered, *options)
+	}
+	if err != nil {
+		return nil, err
+	}
+	wavReader.source, wavReader.dataStart = r, -1
+	if seeker, ok := r.(io.Seeker); ok {
+		if offset, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			wavReader.dataStart = offset - int64(buffered.Buffered())
+		}
+	}
+	return wavReader, nil
+}
+
+/*
+newRiffReader reads the chunks of a RIFF or RF64 file up to the start of the
+data chunk's samples, enforcing the limits in options.
+*/
+func newRiffReader(
+	buffered *bufio.Reader, options ReaderOptions) (*WavReader, error) {
 	var err error
+	wav := new(Wav)
 
-	bufferedReader := io.Reader(bufio.NewReader(r))
-	riffHeader, err = readRiffHeader(&bufferedReader)
+	// The offset of each chunk is tracked to check it against the end of the
+	// file and to report where parsing failed.
+	counter := &chunkReader{reader: buffered, options: options}
+	bufferedReader := io.Reader(counter)
+	wav.Riff, err = readRiffHeader(&bufferedReader)
+	if err != nil {
+		return nil, err
+	}
+	// Sizes of 0 and 0xFFFFFFFF are left by writers that could not seek back.
+	if size := wav.Riff.Size; size != 0 && size != math.MaxUint32 {
+		counter.end = 8 + int64(size)
+	}
+	if string(wav.Riff.Id[:]) == RF64 {
+		wav.Ds64, err = readDs64Chunk(&bufferedReader)
+		if err != nil {
+			return nil, err
+		}
+		if size := wav.Ds64.RiffSize; size < math.MaxInt64-8 {
+			counter.end = 8 + int64(size)
+		}
+	}
+	wav.Fmt, err = readFormatChunk(&bufferedReader, counter.count)
 	if err != nil {
 		return nil, err
 	}
-	fmtChunk, err = readFormatChunk(&bufferedReader)
+	wav.Data, err = wav.readDataChunk(&bufferedReader)
 	if err != nil {
 		return nil, err
 	}
-	dataChunk, err = readDataChunk(&bufferedReader)
+	err = counter.check(Data, wav.dataSize(), counter.count-8)
 	if err != nil {
 		return nil, err
 	}
+	remaining := int64(wav.dataSize())
+	if remaining == 0 {
+		// Streamed files may not know their size until they are closed.
+		remaining = -1
+	}
 	return &WavReader{
-		&Wav{riffHeader, fmtChunk, dataChunk}, bufferedReader}, nil
+		Wav: wav, buffer: bufferedReader, remaining: remaining}, nil
 }
 
 /*
@@ -310,44 +667,128 @@ re appended to the WavReader's DataChunk. The number of slices of byte slices is
 determined by the number of channels defined in the WAV file's fmt header. The
 number of bytes in each slice is determined by the bits per sample defined in
 the WAV file's fmt header.
+
+io.EOF is returned once the data chunk is exhausted. Any chunks following the
+data chunk, such as LIST metadata, are read at that point. A data chunk with a
+size of 0 is assumed to have been left unfinished and is read until EOF.
 */
 func (w *WavReader) GetSample() (Sample, error) {
-	var channelSample []byte
 	bytesPerSample := int(w.Fmt.BitsPerSample) / 8
+	// The channels of a sample share a single allocation.
+	data := make([]byte, bytesPerSample*int(w.Fmt.NumChannels))
+	sample := make(Sample, w.Fmt.NumChannels)
+	for i := range sample {
+		sample[i] = data[i*bytesPerSample : (i+1)*bytesPerSample]
+	}
+	if err := w.ReadInto(sample); err != nil {
+		return nil, err
+	}
+	w.Data.Samples = append(w.Data.Samples, sample)
+	return sample, nil
+}
 
-	channels := make([][]byte, 0)
-	for i := 0; i < int(w.Fmt.NumChannels); i++ {
-		channelSample = make([]byte, bytesPerSample)
-		for j := 0; j < bytesPerSample; j++ {
-			if err := binary.Read(
-				w.buffer,
-				binary.LittleEndian,
-				&channelSample[j]); err != nil {
-				return nil, err
-			}
-		}
-		channels = append(channels, channelSample)
+/*
+ReadInto reads the next sample frame into sample, which must hold one slice of
+the fmt chunk's bytes per sample for each channel, like those returned by
+GetSample. Unlike GetSample, it allocates nothing once the reader is warmed up
+and does not keep the sample in the reader's DataChunk, so a sample can be
+reused to read a file of any length. io.EOF is returned once the data chunk is
+exhausted; a trailing partial frame is dropped.
+*/
+func (w *WavReader) ReadInto(sample Sample) error {
+	if err := checkSample(w.Fmt, sample); err != nil {
+		return err
+	}
+	frameSize := int(w.Fmt.BitsPerSample/8) * int(w.Fmt.NumChannels)
+	if frameSize == 0 {
+		return io.EOF
+	}
+	if len(w.scratch) < frameSize {
+		w.scratch = make([]byte, frameSize)
+	}
+	if n, err := w.readData(w.scratch[:frameSize]); n < frameSize {
+		return err
+	}
+	offset := 0
+	for _, channel := range sample {
+		offset += copy(channel, w.scratch[offset:])
 	}
-	newSample := Sample(channels)
-	w.Data.Samples = append(w.Data.Samples, newSample)
-	return Sample(channels), nil
+	return nil
+}
+
+/*
+readData reads raw sample bytes from the data chunk into p until it is full or
+the data chunk ends, never reading past the end of the chunk. The number of
+bytes read is returned along with io.EOF once the data chunk is exhausted.
+*/
+func (w *WavReader) readData(p []byte) (int, error) {
+	last := false
+	if w.remaining >= 0 && int64(len(p)) >= w.remaining {
+		p, last = p[:w.remaining], true
+	}
+	n, err := io.ReadFull(w.buffer, p)
+	if w.remaining > 0 {
+		w.remaining -= int64(n)
+	}
+	w.position += int64(n)
+	if err == io.ErrUnexpectedEOF || (err == nil && last) {
+		err = io.EOF
+	}
+	if err == io.EOF && w.remaining == 0 {
+		w.finish()
+	}
+	return n, err
+}
+
+/*
+finish reads the chunks following the data chunk the first time the end of the
+data chunk is reached.
+*/
+func (w *WavReader) finish() {
+	if !w.finished {
+		w.readTrailingChunks()
+		w.finished = true
+	}
+}
+
+/*
+RemainingFrames returns the number of sample frames left to read from the data
+chunk, or -1 if the size of the data chunk is unknown.
+*/
+func (w *WavReader) RemainingFrames() int64 {
+	frameSize := int64(w.Fmt.BitsPerSample/8) * int64(w.Fmt.NumChannels)
+	if w.remaining < 0 || frameSize == 0 {
+		return -1
+	}
+	return w.remaining / frameSize
 }
 
 /*
 NewWavWriter Returns a WavWriter that can be used to create a wav file. It
 requires a WriterAt so that information in the header can be updated as samples
 are added to the WAV file. The passed in FormatChunk will define whether or not
-samples passed to this writer are valid.
+samples passed to this writer are valid. Options add optional chunks, such as
+metadata, which are written between the fmt and data chunks.
 */
-func NewWavWriter(output io.WriterAt, fmt *FmtChunk) (*WavWriter, error) {
+func NewWavWriter(
+	output io.WriterAt, fmt *FmtChunk, options ...WriterOption) (*WavWriter, error) {
 	if fmt == nil {
 		fmt = NewDefaultFmtChunk()
 	}
-	wavWriter := &WavWriter{&Wav{
-		defaultRiffHeader,
-		fmt,
-		defaultDataChunk,
-	}, output}
+	riffHeader := *defaultRiffHeader
+	riffSubChunk := *defaultRiffHeader.SubChunk
+	riffHeader.SubChunk = &riffSubChunk
+	dataSubChunk := *defaultDataChunk.SubChunk
+	wavWriter := &WavWriter{Wav: &Wav{
+		Riff: &riffHeader,
+		Fmt:  fmt,
+		Data: &DataChunk{SubChunk: &dataSubChunk},
+	}, buffer: output}
+	for _, option := range options {
+		if err := option(wavWriter); err != nil {
+			return nil, err
+		}
+	}
 	if err := wavWriter.writeInitialData(); err != nil {
 		return nil, err
 	}
@@ -355,59 +796,170 @@ func NewWavWriter(output io.WriterAt, fmt *FmtChunk) (*WavWriter, error) {
 }
 
 /*
-writeInitialData writes the initial riff header, fmt chunk and data chunk to the
-WavWriter.
+writeInitialData writes the initial riff header, fmt chunk, any optional chunks
+and the data chunk header to the WavWriter, and records where samples begin.
 */
 func (w *WavWriter) writeInitialData() error {
 	var buffer = new(bytes.Buffer)
-	var err error
 
-	// Write the RIFF header.
-	buffer.Reset()
+	// Write the RIFF header. Its size is filled in below.
 	binary.Write(buffer, binary.BigEndian, w.Riff.Id)
-	if _, err = w.buffer.WriteAt(buffer.Bytes(), int64(0)); err != nil {
-		return err
-	}
-	buffer.Reset()
 	binary.Write(buffer, binary.LittleEndian, w.Riff.Size)
-	if _, err = w.buffer.WriteAt(buffer.Bytes(), int64(4)); err != nil {
-		return err
-	}
-	buffer.Reset()
 	binary.Write(buffer, binary.BigEndian, w.Riff.Format)
-	if _, err = w.buffer.WriteAt(buffer.Bytes(), int64(8)); err != nil {
-		return err
+
+	// Reserve space for a ds64 chunk.
+	if w.reserveDs64 {
+		writeChunk(buffer, Junk, make([]byte, ds64Size))
 	}
 
 	// Write the format chunk.
-	buffer.Reset()
 	binary.Write(buffer, binary.BigEndian, w.Fmt.Id)
-	if _, err = w.buffer.WriteAt(buffer.Bytes(), int64(12)); err != nil {
-		return err
-	}
-	buffer.Reset()
 	binary.Write(buffer, binary.LittleEndian, w.Fmt.Size)
-	if _, err = w.buffer.WriteAt(buffer.Bytes(), int64(16)); err != nil {
-		return err
-	}
-	buffer.Reset()
 	binary.Write(buffer, binary.LittleEndian, w.Fmt.fmtChunk)
-	if _, err = w.buffer.WriteAt(buffer.Bytes(), int64(20)); err != nil {
-		return err
+
+	// Write the optional chunks.
+	for _, chunk := range w.optionalChunks() {
+		writeChunk(buffer, chunk.id, chunk.data)
 	}
 
 	// Write the data chunk.
-	buffer.Reset()
 	binary.Write(buffer, binary.BigEndian, w.Data.Id)
-	if _, err = w.buffer.WriteAt(buffer.Bytes(), int64(36)); err != nil {
+	binary.Write(buffer, binary.LittleEndian, w.Data.Size)
+
+	w.dataOffset = int64(buffer.Len())
+	w.Riff.Size = uint32(w.riffSize(uint64(w.Data.Size)))
+	binary.LittleEndian.PutUint32(buffer.Bytes()[RiffSizeOffset:], w.Riff.Size)
+	_, err := w.buffer.WriteAt(buffer.Bytes(), 0)
+	return err
+}
+
+// rawChunk holds the ID and encoded body of an optional chunk.
+type rawChunk struct {
+	id   string
+	data []byte
+}
+
+/*
+optionalChunks returns the chunks configured by WriterOptions, in the order they
+are written to the file.
+*/
+func (w *WavWriter) optionalChunks() []rawChunk {
+	var chunks []rawChunk
+	if w.Broadcast != nil {
+		// The option has already checked that the chunk can be encoded.
+		bext, _ := w.Broadcast.encode()
+		chunks = append(chunks, rawChunk{Bext, bext})
+	}
+	if w.Sampler != nil {
+		chunks = append(chunks, rawChunk{Sampler, w.Sampler.encode()})
+	}
+	if len(w.Metadata) > 0 {
+		chunks = append(chunks, rawChunk{List, w.Metadata.encode()})
+	}
+	return chunks
+}
+
+/*
+encodeTrailer returns the chunks following the data chunk, including their
+headers.
+*/
+func (w *WavWriter) encodeTrailer() []byte {
+	trailer := append([]byte{}, w.cueChunks...)
+	if w.hashChain != nil {
+		buffer := bytes.NewBuffer(trailer)
+		writeChunk(buffer, HashChain, w.hashChain.chunk.encode())
+		trailer = buffer.Bytes()
+	}
+	return trailer
+}
+
+/*
+writeTrailer writes any buffered samples, then the chunks following the data
+chunk after the pad byte of an odd sized data chunk, and updates the sizes to
+include them. The chunks are encoded here rather than as samples are added.
+*/
+func (w *WavWriter) writeTrailer() error {
+	dataSize := w.dataSize()
+	if err := w.writePending(dataSize); err != nil {
+		return err
+	}
+	trailer := w.encodeTrailer()
+	if len(trailer) > 0 {
+		buffer := new(bytes.Buffer)
+		if dataSize%2 != 0 {
+			buffer.WriteByte(0)
+		}
+		buffer.Write(trailer)
+		offset := w.dataOffset + int64(dataSize)
+		if _, err := w.buffer.WriteAt(buffer.Bytes(), offset); err != nil {
+			return err
+		}
+	}
+	w.trailer = trailer
+	w.setDataSize(dataSize)
+	return w.writeSizes()
+}
+
+/*
+trailerOffset returns the offset of the chunks following a data chunk of the
+given size, which begin after the pad byte of an odd sized data chunk.
+*/
+func (w *WavWriter) trailerOffset(dataSize uint64) int64 {
+	return w.dataOffset + int64(dataSize) + int64(dataSize%2)
+}
+
+/*
+riffSize returns the size of the RIFF chunk for a data chunk of the given size:
+everything after its header up to the end of the samples, plus any chunks that
+follow them.
+*/
+func (w *WavWriter) riffSize(dataSize uint64) uint64 {
+	if len(w.trailer) == 0 && !w.padded {
+		return uint64(w.dataOffset) - 8 + dataSize
+	}
+	return uint64(w.trailerOffset(dataSize)) - 8 + uint64(len(w.trailer))
+}
+
+/*
+writeSizes writes any buffered samples, then the RIFF header and data chunk
+size, along with the ds64 chunk of an RF64 file, to reflect the current sizes.
+*/
+func (w *WavWriter) writeSizes() error {
+	if err := w.writePending(w.dataSize()); err != nil {
+		return err
+	}
+	var buffer = new(bytes.Buffer)
+	binary.Write(buffer, binary.BigEndian, w.Riff.Id)
+	binary.Write(buffer, binary.LittleEndian, w.Riff.Size)
+	if _, err := w.buffer.WriteAt(buffer.Bytes(), 0); err != nil {
 		return err
 	}
+	if w.Ds64 != nil {
+		buffer.Reset()
+		buffer.WriteString(Ds64)
+		binary.Write(buffer, binary.LittleEndian, uint32(ds64Size))
+		binary.Write(buffer, binary.LittleEndian, w.Ds64)
+		if _, err := w.buffer.WriteAt(buffer.Bytes(), ds64Offset); err != nil {
+			return err
+		}
+	}
 	buffer.Reset()
 	binary.Write(buffer, binary.LittleEndian, w.Data.Size)
-	if _, err = w.buffer.WriteAt(buffer.Bytes(), int64(40)); err != nil {
-		return err
+	_, err := w.buffer.WriteAt(buffer.Bytes(), w.dataOffset-4)
+	return err
+}
+
+/*
+writeChunk writes a chunk with the given ID and body to buffer, followed by a
+pad byte if the body has an odd length.
+*/
+func writeChunk(buffer *bytes.Buffer, id string, data []byte) {
+	buffer.WriteString(id)
+	binary.Write(buffer, binary.LittleEndian, uint32(len(data)))
+	buffer.Write(data)
+	if len(data)%2 != 0 {
+		buffer.WriteByte(0)
 	}
-	return nil
 }
 
 /*
@@ -416,54 +968,51 @@ information in the fmt header. A non-nil error is returned if there is a problem
 writing the Sample or if the sample is invalid.
 */
 func (w *WavWriter) AddSample(sample Sample) error {
-	if samples := len(sample); samples != int(w.Fmt.NumChannels) {
-		return fmt.Errorf(ChannelError, w.Fmt.NumChannels, samples)
-	}
-
-	expectedBytes := (w.Fmt.BitsPerSample / 8) * w.Fmt.NumChannels
-	var counted int
-	for index := range sample {
-		counted += len(sample[index])
-	}
-	if counted != int(expectedBytes) {
-		return fmt.Errorf(SampleError, expectedBytes, counted)
+	if err := checkSample(w.Fmt, sample); err != nil {
+		return err
 	}
 
-	// Write the new sizes and the new sample. The sample must be written
-	// before the data size gets updated so the correct data offset can be
-	// calculated.
 	var buffer = new(bytes.Buffer)
-	var err error
-
-	buffer.Reset()
 	for i := range sample {
 		for j := range sample[i] {
 			binary.Write(buffer, binary.LittleEndian, sample[i][j])
 		}
 	}
-	offset := DataOffset + int64(w.Data.Size)
-	_, err = w.buffer.WriteAt(buffer.Bytes(), int64(offset))
-	if err != nil {
+	if err := w.appendData(buffer.Bytes()); err != nil {
 		return err
 	}
-
-	// Add the data to the WavWriter and update the counts.
 	w.Data.Samples = append(w.Data.Samples, sample)
-	w.Riff.Size += uint32(counted)
-	w.Data.Size += uint32(counted)
+	return w.commit()
+}
 
-	buffer.Reset()
-	binary.Write(buffer, binary.LittleEndian, w.Riff.Size)
-	_, err = w.buffer.WriteAt(buffer.Bytes(), int64(RiffSizeOffset))
-	if err != nil {
-		return err
+/*
+appendData writes raw sample bytes at the end of the data chunk, or buffers
+them, and updates the sizes held by the WavWriter, but does not write the sizes
+to the file. The samples overwrite any chunks following the data chunk, which
+are left out of the RIFF size until Flush or Close writes them again.
+*/
+func (w *WavWriter) appendData(data []byte) error {
+	if w.closed {
+		return ErrWriterClosed
 	}
-	buffer.Reset()
-	binary.Write(buffer, binary.LittleEndian, w.Data.Size)
-	_, err = w.buffer.WriteAt(buffer.Bytes(), int64(DataSizeOffset))
-	if err != nil {
-		return err
+	// The file cannot grow past 4 GB unless space was reserved for the ds64
+	// chunk of an RF64 file.
+	dataSize := w.dataSize() + uint64(len(data))
+	if !w.reserveDs64 && w.riffSize(dataSize) > math.MaxUint32 {
+		return fmt.Errorf(DataSizeError, dataSize)
 	}
 
+	if w.hashChain != nil {
+		w.hashChain.write(data)
+	}
+	w.pending = append(w.pending, data...)
+	if len(w.pending) >= w.bufferSize {
+		if err := w.writePending(dataSize); err != nil {
+			w.pending = w.pending[:len(w.pending)-len(data)]
+			return err
+		}
+	}
+	w.trailer = nil
+	w.setDataSize(dataSize)
 	return nil
 }
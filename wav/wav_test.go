@@ -71,6 +71,7 @@ func TestErrorReadingRiffWrongId(t *testing.T) {
 	assert.NotNil(t, err)
 	re := regexp.MustCompile("should be 'RIFF'")
 	assert.NotEqual(t, "", re.FindString(err.Error()))
+	assert.True(t, errors.Is(err, ErrNotRiff))
 }
 
 func TestErrorReadingSizeNotEnoughData(t *testing.T) {
@@ -140,6 +141,28 @@ func TestInvalidFormatChunk(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestWrongFormatChunkId(t *testing.T) {
+	var buffer bytes.Buffer
+	buffer.WriteString("RIFF")
+	binary.Write(&buffer, binary.LittleEndian, uint32(123))
+	buffer.WriteString("WAVE")
+	// A JUNK chunk with an odd size, followed by its pad byte.
+	buffer.WriteString("JUNK")
+	binary.Write(&buffer, binary.LittleEndian, uint32(3))
+	buffer.Write([]byte{0, 0, 0, 0})
+	buffer.WriteString("fmt_")
+	binary.Write(&buffer, binary.LittleEndian, uint32(16))
+
+	reader, err := NewWavReader(&buffer)
+	assert.Nil(t, reader)
+	var fmtErr ErrBadFmtChunk
+	assert.True(t, errors.As(err, &fmtErr))
+	assert.Equal(t, "fmt_", fmtErr.Got)
+	assert.Equal(t, int64(24), fmtErr.Offset)
+	re := regexp.MustCompile("should be 'fmt '. \\(at byte 24\\)")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
+
 func TestInvalidDataChunkNotEnoughData(t *testing.T) {
 	buffer := getValidHeaderAndFmtChunk()
 
@@ -250,6 +273,157 @@ func (m *mockWriterAtCloser) WriteAt(p []byte, off int64) (n int, err error) {
 	return len(p), nil
 }
 
+func TestReaderOptions(t *testing.T) {
+	file := newFloatFile(t, []WriterOption{
+		WithMetadata(Metadata{"INAM": "A title"})}, 0.5, 0.25)
+	list := int64(bytes.Index(file, []byte(List)))
+	listSize := uint64(binary.LittleEndian.Uint32(file[list+4:]))
+
+	reader, err := NewWavReaderWithOptions(bytes.NewReader(file),
+		&ReaderOptions{MaxChunkSize: listSize, CheckBounds: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "A title", reader.Metadata["INAM"])
+
+	_, err = NewWavReaderWithOptions(bytes.NewReader(file),
+		&ReaderOptions{MaxChunkSize: listSize - 1})
+	var sizeErr ErrChunkTooLarge
+	assert.True(t, errors.As(err, &sizeErr))
+	assert.Equal(t, ErrChunkTooLarge{
+		Chunk: List, Max: listSize - 1, Got: listSize, Offset: list}, sizeErr)
+
+	// A data chunk claiming more samples than the file holds is only
+	// rejected when bounds are checked.
+	data := int64(bytes.Index(file, []byte(Data)))
+	binary.LittleEndian.PutUint32(file[data+4:], 1000)
+	_, err = NewWavReader(bytes.NewReader(file))
+	assert.Nil(t, err)
+	_, err = NewWavReaderWithOptions(
+		bytes.NewReader(file), &ReaderOptions{CheckBounds: true})
+	var boundsErr ErrChunkBounds
+	assert.True(t, errors.As(err, &boundsErr))
+	assert.Equal(t, ErrChunkBounds{Chunk: Data, Size: 1000, Offset: data,
+		End: int64(len(file))}, boundsErr)
+	re := regexp.MustCompile("extends past the end of the file")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}
+
+/*
+FuzzNewWavReader checks that arbitrary data never panics the reader, whether or
+not it is parsed with limits.
+*/
+func FuzzNewWavReader(f *testing.F) {
+	f.Add(newFloatFile(f, []WriterOption{
+		WithMetadata(Metadata{"INAM": "A title"})}, 0.5, 0.25))
+	f.Add([]byte("RIFF\x24\x00\x00\x00WAVEJUNK\x01\x00\x00\x00"))
+	f.Add(id3File(compressedID3Tag()))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, options := range []*ReaderOptions{
+			nil, {MaxChunkSize: 1 << 16, CheckBounds: true}} {
+			reader, err := NewWavReaderWithOptions(
+				bytes.NewReader(data), options)
+			if err != nil {
+				continue
+			}
+			for i := 0; i < len(data); i++ {
+				if _, err := reader.GetSample(); err != nil {
+					break
+				}
+			}
+		}
+	})
+}
+
+func TestReadInto(t *testing.T) {
+	reader, err := NewWavReader(bytes.NewReader(newWavFile(t, nil,
+		Sample{{1, 2}, {3, 4}}, Sample{{5, 6}, {7, 8}})))
+	assert.Nil(t, err)
+	sample := Sample{make([]byte, 2), make([]byte, 2)}
+	assert.Nil(t, reader.ReadInto(sample))
+	assert.Equal(t, Sample{{1, 2}, {3, 4}}, sample)
+	assert.Nil(t, reader.ReadInto(sample))
+	assert.Equal(t, Sample{{5, 6}, {7, 8}}, sample)
+	assert.Equal(t, io.EOF, reader.ReadInto(sample))
+	// Samples read into are not kept by the reader.
+	assert.Equal(t, 0, len(reader.Data.Samples))
+
+	err = reader.ReadInto(Sample{make([]byte, 2), make([]byte, 1)})
+	var sizeErr ErrSampleSize
+	assert.True(t, errors.As(err, &sizeErr))
+}
+
+// newLongWavReader returns a reader of a 16 bit stereo file of frames frames.
+func newLongWavReader(tb testing.TB, frames int) *WavReader {
+	writer := &mockWriterAtCloser{make([]byte, 100+4*frames)}
+	wavWriter, err := NewWavWriter(writer, nil)
+	assert.Nil(tb, err)
+	_, err = wavWriter.PCMWriter().Write(make([]byte, 4*frames))
+	assert.Nil(tb, err)
+	reader, err := NewWavReader(
+		bytes.NewReader(writer.data[:wavWriter.Riff.Size+8]))
+	assert.Nil(tb, err)
+	return reader
+}
+
+func TestReadIntoDoesNotAllocate(t *testing.T) {
+	reader := newLongWavReader(t, 1000)
+	sample := Sample{make([]byte, 2), make([]byte, 2)}
+	allocations := testing.AllocsPerRun(500, func() {
+		if err := reader.ReadInto(sample); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.Equal(t, 0.0, allocations)
+
+	frames := [][]float64{make([]float64, 2), make([]float64, 2)}
+	allocations = testing.AllocsPerRun(100, func() {
+		if _, err := reader.ReadFrames(frames); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.Equal(t, 0.0, allocations)
+}
+
+func BenchmarkGetSample(b *testing.B) {
+	reader := newLongWavReader(b, b.N)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := reader.GetSample(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadInto(b *testing.B) {
+	reader := newLongWavReader(b, b.N)
+	sample := Sample{make([]byte, 2), make([]byte, 2)}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := reader.ReadInto(sample); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadFrames(b *testing.B) {
+	reader := newLongWavReader(b, b.N)
+	frames := make([][]float64, 1024)
+	for i := range frames {
+		frames[i] = make([]float64, 2)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for read := 0; read < b.N; {
+		n, err := reader.ReadFrames(frames)
+		if err != nil && err != io.EOF {
+			b.Fatal(err)
+		}
+		read += n
+	}
+}
+
 func TestWavWriterErrorNotEnoughBuffer(t *testing.T) {
 	writer := &mockWriterAtCloser{make([]byte, 10)}
 	wavWriter, err := NewWavWriter(writer, nil)
@@ -323,12 +497,26 @@ func TestWrongSampleSize(t *testing.T) {
 	writer := &mockWriterAtCloser{make([]byte, 100)}
 	wavWriter, err := NewWavWriter(writer, nil)
 
-	// Default writer expects 4 bytes per sample.
+	// Default writer expects 2 bytes in each of 2 channels.
 	sample := Sample([][]byte{{1}, {2}})
 	err = wavWriter.AddSample(sample)
 	assert.NotNil(t, err)
-	re := regexp.MustCompile("per sample but only found 2")
+	re := regexp.MustCompile(
+		"channel 0: expected 2 bytes per sample but only found 1")
 	assert.NotEqual(t, "", re.FindString(err.Error()))
+	var sizeErr ErrSampleSize
+	assert.True(t, errors.As(err, &sizeErr))
+	assert.Equal(t, ErrSampleSize{Channel: 0, Want: 2, Got: 1}, sizeErr)
+
+	// The right number of bytes split unevenly between channels is wrong
+	// too.
+	err = wavWriter.AddSample(Sample{{1, 2, 3}, {4}})
+	assert.True(t, errors.As(err, &sizeErr))
+	assert.Equal(t, ErrSampleSize{Channel: 0, Want: 2, Got: 3}, sizeErr)
+	err = wavWriter.AddSample(Sample{{1, 2}, {3, 4, 5}})
+	assert.True(t, errors.As(err, &sizeErr))
+	assert.Equal(t, ErrSampleSize{Channel: 1, Want: 2, Got: 3}, sizeErr)
+	assert.Equal(t, uint32(0), wavWriter.Data.Size)
 }
 
 func TestAddSamples(t *testing.T) {
@@ -352,3 +540,16 @@ func TestAddSamples(t *testing.T) {
 	// Confirm the sample was written.
 	assert.Equal(t, writer.data[44:48], []byte{1, 2, 2, 3})
 }
+
+func TestWavWritersAreIndependent(t *testing.T) {
+	first := &mockWriterAtCloser{make([]byte, 100)}
+	second := &mockWriterAtCloser{make([]byte, 100)}
+	firstWriter, _ := NewWavWriter(first, nil)
+	secondWriter, _ := NewWavWriter(second, nil)
+
+	assert.Nil(t, firstWriter.AddSample(Sample([][]byte{{1, 2}, {2, 3}})))
+	assert.Equal(t, uint32(4), firstWriter.Data.Size)
+	assert.Equal(t, uint32(0), secondWriter.Data.Size)
+	assert.Equal(t, uint32(36), secondWriter.Riff.Size)
+	assert.Equal(t, 0, len(secondWriter.Data.Samples))
+}
@@ -0,0 +1,145 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	Wave64Error     = "invalid Wave64 %s GUID of % x"
+	Wave64SizeError = "Wave64 chunk size of %v is smaller than its header"
+
+	// wave64Riff is the start of the GUID of a Wave64 file's riff chunk,
+	// which tells it apart from a RIFF file.
+	wave64Riff = "riff"
+	// wave64HeaderSize is the size of a Wave64 chunk header: a 16 byte GUID
+	// followed by a 64 bit size that includes the header itself.
+	wave64HeaderSize = 24
+)
+
+/*
+wave64RiffGuid identifies the outermost chunk of a Sony Wave64 file. The GUIDs
+of the wave, fmt and data chunks share wave64Suffix, following their four
+character ID.
+*/
+var (
+	wave64RiffGuid = [16]byte{'r', 'i', 'f', 'f', 0x2E, 0x91, 0xCF, 0x11,
+		0xA5, 0xD6, 0x28, 0xDB, 0x04, 0xC1, 0x00, 0x00}
+	wave64Suffix = [12]byte{0xF3, 0xAC, 0xD3, 0x11, 0x8C, 0xD1, 0x00, 0xC0,
+		0x4F, 0x8E, 0xDB, 0x8A}
+)
+
+// wave64Chunk is the header of a Wave64 chunk.
+type wave64Chunk struct {
+	Guid [16]byte
+	Size uint64
+}
+
+// wave64Guid returns the GUID of the Wave64 chunk with the given ID.
+func wave64Guid(id string) [16]byte {
+	var guid [16]byte
+	copy(guid[:], id)
+	copy(guid[4:], wave64Suffix[:])
+	return guid
+}
+
+/*
+newWave64Reader creates a WavReader for a Sony Wave64 file, which uses GUIDs for
+chunk IDs and 64 bit chunk sizes so it can hold more than 4 GB of samples. The
+Riff, Fmt and Data chunks are translated to their RIFF equivalents, with sizes
+capped at 0xFFFFFFFF. Chunks other than fmt and data are skipped. Chunks are
+checked against the limits in options.
+*/
+func newWave64Reader(
+	source io.Reader, options ReaderOptions) (*WavReader, error) {
+	chunks := &chunkReader{reader: source, options: options}
+	reader := io.Reader(chunks)
+	var riff wave64Chunk
+	if err := binary.Read(reader, binary.LittleEndian, &riff); err != nil {
+		return nil, err
+	}
+	if riff.Size < math.MaxInt64 {
+		chunks.end = int64(riff.Size)
+	}
+	if riff.Guid != wave64RiffGuid {
+		return nil, fmt.Errorf(Wave64Error, "riff", riff.Guid[:])
+	}
+	if riff.Size < wave64HeaderSize {
+		return nil, fmt.Errorf(Wave64SizeError, riff.Size)
+	}
+	var format [16]byte
+	if _, err := io.ReadFull(reader, format[:]); err != nil {
+		return nil, err
+	}
+	if format != wave64Guid("wave") {
+		return nil, fmt.Errorf(Wave64Error, "wave", format[:])
+	}
+
+	wav := &Wav{Riff: &RiffHeader{
+		&SubChunk{Size: capSize(riff.Size - 8)}, [4]byte{}}}
+	copy(wav.Riff.Id[:], wave64Riff)
+	copy(wav.Riff.Format[:], "wave")
+	for {
+		var chunk wave64Chunk
+		if err := binary.Read(
+			reader, binary.LittleEndian, &chunk); err != nil {
+			return nil, err
+		}
+		if chunk.Size < wave64HeaderSize {
+			return nil, fmt.Errorf(Wave64SizeError, chunk.Size)
+		}
+		size := chunk.Size - wave64HeaderSize
+		subChunk := &SubChunk{Size: capSize(size)}
+		copy(subChunk.Id[:], chunk.Guid[:4])
+		err := chunks.check(string(subChunk.Id[:]), size,
+			chunks.count-wave64HeaderSize)
+		if err != nil {
+			return nil, err
+		}
+
+		switch chunk.Guid {
+		case wave64Guid(Data):
+			if wav.Fmt == nil {
+				return nil, fmt.Errorf(FmtError, Data)
+			}
+			wav.Data = &DataChunk{SubChunk: subChunk}
+			// Chunks following the data are not read, as they would
+			// need to be translated from Wave64 as well.
+			return &WavReader{Wav: wav, buffer: source,
+				remaining: int64(size), finished: true}, nil
+		case wave64Guid(Fmt):
+			body, err := readBody(reader, size)
+			if err != nil {
+				return nil, err
+			}
+			wav.Fmt = &FmtChunk{subChunk, &fmtChunk{}}
+			if err := binary.Read(bytes.NewReader(body),
+				binary.LittleEndian, wav.Fmt.fmtChunk); err != nil {
+				return nil, err
+			}
+		default:
+			if _, err := io.CopyN(
+				io.Discard, reader, int64(size)); err != nil {
+				return nil, err
+			}
+		}
+		// Chunks are aligned to 8 bytes.
+		if padding := chunk.Size % 8; padding != 0 {
+			if _, err := io.CopyN(
+				io.Discard, reader, int64(8-padding)); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// capSize returns size, or 0xFFFFFFFF if it does not fit in 32 bits.
+func capSize(size uint64) uint32 {
+	if size > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(size)
+}
@@ -0,0 +1,82 @@
+package wav_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"regexp"
+	"testing"
+
+	. "github.com/husafan/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+var wave64Suffix = []byte{
+	0xF3, 0xAC, 0xD3, 0x11, 0x8C, 0xD1, 0x00, 0xC0, 0x4F, 0x8E, 0xDB, 0x8A}
+
+// writeWave64Chunk writes a Wave64 chunk and its padding to buffer.
+func writeWave64Chunk(buffer *bytes.Buffer, id string, body []byte) {
+	var size8Bytes = make([]byte, 8)
+	buffer.WriteString(id)
+	buffer.Write(wave64Suffix)
+	binary.LittleEndian.PutUint64(size8Bytes, uint64(24+len(body)))
+	buffer.Write(size8Bytes)
+	buffer.Write(body)
+	if padding := (24 + len(body)) % 8; padding != 0 {
+		buffer.Write(make([]byte, 8-padding))
+	}
+}
+
+func getWave64File(samples []byte) *bytes.Buffer {
+	var size8Bytes = make([]byte, 8)
+	body := new(bytes.Buffer)
+	body.WriteString("wave")
+	body.Write(wave64Suffix)
+	// An unknown chunk with a size that is not a multiple of 8 is skipped.
+	writeWave64Chunk(body, "junk", []byte{1, 2, 3})
+	fmtChunk := NewDefaultFmtChunk()
+	fmtBody := new(bytes.Buffer)
+	for _, field := range []interface{}{
+		fmtChunk.AudioFormat, fmtChunk.NumChannels, fmtChunk.SampleRate,
+		fmtChunk.ByteRate, fmtChunk.BlockAlign, fmtChunk.BitsPerSample} {
+		binary.Write(fmtBody, binary.LittleEndian, field)
+	}
+	writeWave64Chunk(body, "fmt ", fmtBody.Bytes())
+	writeWave64Chunk(body, "data", samples)
+
+	buffer := bytes.NewBuffer([]byte{'r', 'i', 'f', 'f', 0x2E, 0x91, 0xCF,
+		0x11, 0xA5, 0xD6, 0x28, 0xDB, 0x04, 0xC1, 0x00, 0x00})
+	binary.LittleEndian.PutUint64(size8Bytes, uint64(24+body.Len()))
+	buffer.Write(size8Bytes)
+	buffer.Write(body.Bytes())
+	return buffer
+}
+
+func TestReadWave64(t *testing.T) {
+	reader, err := NewWavReader(getWave64File([]byte{1, 2, 3, 4, 5, 6, 7, 8}))
+	assert.Nil(t, err)
+	assert.Equal(t, "riff", string(reader.Riff.Id[:]))
+	assert.Equal(t, uint16(2), reader.Fmt.NumChannels)
+	assert.Equal(t, uint32(44100), reader.Fmt.SampleRate)
+	assert.Equal(t, uint32(8), reader.Data.Size)
+
+	var samples []Sample
+	for {
+		sample, err := reader.GetSample()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		samples = append(samples, sample)
+	}
+	assert.Equal(t, []Sample{{{1, 2}, {3, 4}}, {{5, 6}, {7, 8}}}, samples)
+}
+
+func TestReadWave64InvalidGuid(t *testing.T) {
+	buffer := getWave64File(nil)
+	buffer.Bytes()[30] = 0
+	_, err := NewWavReader(buffer)
+	assert.NotNil(t, err)
+	re := regexp.MustCompile("invalid Wave64 wave GUID")
+	assert.NotEqual(t, "", re.FindString(err.Error()))
+}